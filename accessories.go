@@ -0,0 +1,38 @@
+package deckconverter
+
+import (
+	"strconv"
+
+	"github.com/jeandeaual/tts-deckconverter/log"
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+// addAccessories adds the accessories of the plugin parsing target to the
+// first of decks, if it implements plugins.AccessoryProvider and the
+// plugins.AccessoriesOption option is enabled.
+func addAccessories(target, mode string, options map[string]string, decks []*plugins.Deck) {
+	if len(decks) == 0 {
+		return
+	}
+
+	value, found := options[plugins.AccessoriesOption]
+	if !found {
+		return
+	}
+	if enabled, err := strconv.ParseBool(value); err != nil || !enabled {
+		return
+	}
+
+	plugin, found := FindPlugin(target, mode)
+	if !found {
+		return
+	}
+
+	provider, ok := plugin.(plugins.AccessoryProvider)
+	if !ok {
+		log.Warnf("Plugin %s doesn't have any accessory", plugin.PluginID())
+		return
+	}
+
+	decks[0].Accessories = append(decks[0].Accessories, provider.Accessories()...)
+}
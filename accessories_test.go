@@ -0,0 +1,35 @@
+package deckconverter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+func TestAddAccessories(t *testing.T) {
+	newDecks := func() []*plugins.Deck {
+		return []*plugins.Deck{{Name: "Main"}, {Name: "Sideboard"}}
+	}
+
+	decks := newDecks()
+	addAccessories("deck.txt", "mtg", map[string]string{}, decks)
+	assert.Empty(t, decks[0].Accessories)
+
+	addAccessories("deck.txt", "mtg", map[string]string{plugins.AccessoriesOption: "false"}, decks)
+	assert.Empty(t, decks[0].Accessories)
+
+	addAccessories("deck.txt", "mtg", map[string]string{plugins.AccessoriesOption: "true"}, decks)
+	assert.Equal(t, []plugins.Accessory{{Name: "Life", Die: plugins.D20}}, decks[0].Accessories)
+	assert.Empty(t, decks[1].Accessories)
+
+	decks = newDecks()
+	addAccessories("deck.txt", "cfv", map[string]string{plugins.AccessoriesOption: "true"}, decks)
+	assert.Equal(t, []plugins.Accessory{{Name: "Damage", Die: plugins.D6}}, decks[0].Accessories)
+
+	// The plugins without accessories are ignored
+	decks = newDecks()
+	addAccessories("deck.txt", "custom", map[string]string{plugins.AccessoriesOption: "true"}, decks)
+	assert.Empty(t, decks[0].Accessories)
+}
@@ -0,0 +1,187 @@
+// Package cache manages the files cached on disk by tts-deckconverter: the
+// API responses, the bulk data files and the downloaded images.
+package cache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const appFolder = "tts-deckconverter"
+
+// Names of the caches, which are the names of their folder inside the cache
+// directory.
+const (
+	// HTTP contains the API responses.
+	HTTP = "http"
+	// Bulk contains the bulk data files (e.g. the Scryfall card database).
+	Bulk = "bulk"
+	// Images contains the downloaded card images.
+	Images = "images"
+)
+
+// Caches are the names of the caches, in the order they are displayed.
+var Caches = []string{HTTP, Bulk, Images}
+
+// Info describes the contents of a cache.
+type Info struct {
+	// Name of the cache.
+	Name string
+	// Path of the cache folder.
+	Path string
+	// Files is the number of files in the cache.
+	Files int
+	// Size is the total size of the files in bytes.
+	Size int64
+}
+
+// DefaultDir returns the location of the cache directory
+// (e.g. ~/.cache/tts-deckconverter on Linux).
+func DefaultDir() (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(cacheDir, appFolder), nil
+}
+
+// Dir returns the folder of the cache called name inside dir.
+func Dir(dir, name string) string {
+	return filepath.Join(dir, name)
+}
+
+// checkNames returns an error if one of names isn't a cache, and all the
+// caches if names is empty.
+func checkNames(names []string) ([]string, error) {
+	if len(names) == 0 {
+		return Caches, nil
+	}
+
+	for _, name := range names {
+		found := false
+		for _, cache := range Caches {
+			if name == cache {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("invalid cache: %s", name)
+		}
+	}
+
+	return names, nil
+}
+
+// walkFiles calls fn for each file of the cache called name inside dir. A
+// missing cache folder is considered empty.
+func walkFiles(dir, name string, fn func(path string, info os.FileInfo) error) error {
+	root := Dir(dir, name)
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if path == root && os.IsNotExist(err) {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		return fn(path, info)
+	})
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+// Stat returns the number of files and the size of the caches called names
+// (all of them if names is empty) inside dir.
+func Stat(dir string, names ...string) ([]Info, error) {
+	names, err := checkNames(names)
+	if err != nil {
+		return nil, err
+	}
+
+	infos := make([]Info, 0, len(names))
+
+	for _, name := range names {
+		info := Info{Name: name, Path: Dir(dir, name)}
+
+		err := walkFiles(dir, name, func(_ string, fileInfo os.FileInfo) error {
+			info.Files++
+			info.Size += fileInfo.Size()
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("couldn't read the %s cache: %w", name, err)
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+// Prune removes the files of the caches called names (all of them if names
+// is empty) inside dir which haven't been modified since olderThan, and
+// returns what was removed from each cache.
+func Prune(dir string, olderThan time.Duration, names ...string) ([]Info, error) {
+	names, err := checkNames(names)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := time.Now().Add(-olderThan)
+	removed := make([]Info, 0, len(names))
+
+	for _, name := range names {
+		info := Info{Name: name, Path: Dir(dir, name)}
+
+		err := walkFiles(dir, name, func(path string, fileInfo os.FileInfo) error {
+			if !fileInfo.ModTime().Before(limit) {
+				return nil
+			}
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+			info.Files++
+			info.Size += fileInfo.Size()
+			return nil
+		})
+		if err != nil {
+			return removed, fmt.Errorf("couldn't prune the %s cache: %w", name, err)
+		}
+
+		removed = append(removed, info)
+	}
+
+	return removed, nil
+}
+
+// Clear removes every file of the caches called names (all of them if names
+// is empty) inside dir, and returns what was removed from each cache.
+func Clear(dir string, names ...string) ([]Info, error) {
+	names, err := checkNames(names)
+	if err != nil {
+		return nil, err
+	}
+
+	removed, err := Stat(dir, names...)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, info := range removed {
+		if err := os.RemoveAll(info.Path); err != nil {
+			return nil, fmt.Errorf("couldn't clear the %s cache: %w", info.Name, err)
+		}
+	}
+
+	return removed, nil
+}
@@ -0,0 +1,58 @@
+package cache
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeCacheFile(t *testing.T, dir, name, file string, size int, modTime time.Time) {
+	path := filepath.Join(Dir(dir, name), file)
+	assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	assert.Nil(t, ioutil.WriteFile(path, make([]byte, size), 0o644))
+	assert.Nil(t, os.Chtimes(path, modTime, modTime))
+}
+
+func TestCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	old := time.Now().Add(-48 * time.Hour)
+	writeCacheFile(t, dir, HTTP, "api.scryfall.com/cards", 10, old)
+	writeCacheFile(t, dir, HTTP, "api.scryfall.com/sets", 5, time.Now())
+	writeCacheFile(t, dir, Images, "island.png", 100, old)
+
+	infos, err := Stat(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, []Info{
+		{Name: HTTP, Path: filepath.Join(dir, HTTP), Files: 2, Size: 15},
+		{Name: Bulk, Path: filepath.Join(dir, Bulk)},
+		{Name: Images, Path: filepath.Join(dir, Images), Files: 1, Size: 100},
+	}, infos)
+
+	_, err = Stat(dir, "thumbnails")
+	assert.NotNil(t, err)
+
+	removed, err := Prune(dir, 24*time.Hour, HTTP)
+	assert.Nil(t, err)
+	assert.Equal(t, []Info{{Name: HTTP, Path: filepath.Join(dir, HTTP), Files: 1, Size: 10}}, removed)
+
+	removed, err = Clear(dir)
+	assert.Nil(t, err)
+	assert.Len(t, removed, 3)
+	assert.Equal(t, 1, removed[0].Files)
+	assert.Equal(t, int64(100), removed[2].Size)
+
+	infos, err = Stat(dir)
+	assert.Nil(t, err)
+	for _, info := range infos {
+		assert.Zero(t, info.Files)
+	}
+}
@@ -1,11 +1,13 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"reflect"
 	"runtime"
@@ -57,13 +59,35 @@ func showErrorf(win fyne.Window, format string, args ...interface{}) {
 	dialog.ShowError(msg, win)
 }
 
-func newProgressBar(title string, win fyne.Window) dialog.Dialog {
+// progressDialog displays the progress of a conversion.
+// It implements the plugins.ProgressReporter interface.
+type progressDialog struct {
+	dialog.Dialog
+	status *widget.Label
+}
+
+func newProgressBar(title string, win fyne.Window) *progressDialog {
 	bar := widget.NewProgressBarInfinite()
 	bar.Resize(fyne.NewSize(200, bar.MinSize().Height))
 
-	progress := dialog.NewCustom(title, "Cancel", bar, win)
+	status := widget.NewLabel("")
+
+	return &progressDialog{
+		Dialog: dialog.NewCustom(title, "Cancel", container.NewVBox(bar, status), win),
+		status: status,
+	}
+}
+
+func (p *progressDialog) CardResolved(deckName string, cardName string, resolved int, total int) {
+	p.status.SetText(fmt.Sprintf("%s: retrieved %d / %d", deckName, resolved, total))
+}
 
-	return progress
+func (p *progressDialog) ImageDownloaded(url string, _ int64) {
+	p.status.SetText("Downloaded " + path.Base(url))
+}
+
+func (p *progressDialog) FileWritten(filePath string, _ int64) {
+	p.status.SetText("Generated " + filepath.Base(filePath))
 }
 
 func handleTarget(
@@ -79,11 +103,18 @@ func handleTarget(
 	options := convertOptions(optionWidgets)
 	log.Infof("Selected options: %v", options)
 
+	ctx, cancel := context.WithCancel(context.Background())
 	progress := newProgressBar("Generating…", win)
+	// Cancel the conversion if the user closes the progress bar
+	progress.SetOnClosed(cancel)
+	ctx = plugins.WithProgressReporter(ctx, progress)
 
 	go func() {
-		decks, err := dc.Parse(target, mode, options)
-		if err != nil {
+		decks, err := dc.Parse(ctx, target, mode, options)
+		if errors.Is(err, context.Canceled) {
+			log.Info("Conversion cancelled")
+			return
+		} else if err != nil {
 			progress.Hide()
 			showErrorf(win, "Couldn't parse deck(s): %w", err)
 			return
@@ -95,7 +126,7 @@ func handleTarget(
 		}
 
 		if uploader != nil {
-			errs := tts.GenerateTemplates([][]*plugins.Deck{decks}, outputFolder, *uploader)
+			errs := tts.GenerateTemplates(ctx, [][]*plugins.Deck{decks}, outputFolder, *uploader)
 			if len(errs) > 0 {
 				progress.Hide()
 				uploadSizeErrsOnly := true
@@ -117,7 +148,7 @@ func handleTarget(
 			}
 		}
 
-		errs := tts.Generate(decks, backURL, outputFolder, !compact)
+		errs := tts.Generate(ctx, decks, backURL, outputFolder, !compact)
 		if len(errs) > 0 {
 			progress.Hide()
 			msg := "Couldn't generate deck(s):\n"
@@ -157,11 +188,18 @@ func handleText(
 	options := convertOptions(optionWidgets)
 	log.Infof("Selected options: %v", options)
 
+	ctx, cancel := context.WithCancel(context.Background())
 	progress := newProgressBar("Generating…", win)
+	// Cancel the conversion if the user closes the progress bar
+	progress.SetOnClosed(cancel)
+	ctx = plugins.WithProgressReporter(ctx, progress)
 
 	go func() {
-		decks, err := handler(strings.NewReader(text), deckName, options)
-		if err != nil {
+		decks, err := handler(ctx, strings.NewReader(text), deckName, options)
+		if errors.Is(err, context.Canceled) {
+			log.Info("Conversion cancelled")
+			return
+		} else if err != nil {
 			progress.Hide()
 			showErrorf(win, "Couldn't parse deck: %w", err)
 			return
@@ -173,7 +211,7 @@ func handleText(
 		}
 
 		if uploader != nil {
-			errs := tts.GenerateTemplates([][]*plugins.Deck{decks}, outputFolder, *uploader)
+			errs := tts.GenerateTemplates(ctx, [][]*plugins.Deck{decks}, outputFolder, *uploader)
 			if len(errs) > 0 {
 				progress.Hide()
 				uploadSizeErrsOnly := true
@@ -195,7 +233,7 @@ func handleText(
 			}
 		}
 
-		errs := tts.Generate(decks, backURL, outputFolder, !compact)
+		errs := tts.Generate(ctx, decks, backURL, outputFolder, !compact)
 		if len(errs) > 0 {
 			progress.Hide()
 			msg := "Couldn't generate deck:\n"
@@ -675,6 +713,13 @@ func pluginScreen(win fyne.Window, folderEntry *widget.Entry, uploaderSelect *wi
 			entry.SetPlaceHolder(plugins.CapitalizeString(option.DefaultValue.(string)))
 			optionWidgets[name] = entry
 
+			widgetsVBox.Add(entry)
+		case plugins.OptionTypeString:
+			widgetsVBox.Add(widget.NewLabel(plugins.CapitalizeString(option.Description)))
+
+			entry := widget.NewEntry()
+			optionWidgets[name] = entry
+
 			widgetsVBox.Add(entry)
 		case plugins.OptionTypeBool:
 			check := widget.NewCheck(plugins.CapitalizeString(option.Description), nil)
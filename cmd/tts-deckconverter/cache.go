@@ -0,0 +1,127 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jeandeaual/tts-deckconverter/cache"
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+func cacheUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s cache info|clear|prune [FLAGS] [CACHE...]\n\nCaches: %s\n", filepath.Base(os.Args[0]), strings.Join(cache.Caches, ", "))
+}
+
+// formatSize returns size in a human readable format (e.g. "1.5 MiB").
+func formatSize(size int64) string {
+	const unit = 1024
+
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// printCacheInfos displays the number of files and the size of each cache,
+// followed by the total.
+func printCacheInfos(w io.Writer, infos []cache.Info) {
+	var (
+		files int
+		size  int64
+	)
+
+	for _, info := range infos {
+		fmt.Fprintf(w, "%-7s %6d file(s) %10s  %s\n", info.Name+":", info.Files, formatSize(info.Size), info.Path)
+		files += info.Files
+		size += info.Size
+	}
+
+	if len(infos) > 1 {
+		fmt.Fprintf(w, "%-7s %6d file(s) %10s\n", "Total:", files, formatSize(size))
+	}
+}
+
+func runCache(args []string) {
+	if len(args) == 0 {
+		cacheUsage()
+		os.Exit(1)
+	}
+
+	command := args[0]
+
+	var (
+		dir       string
+		olderThan time.Duration
+	)
+
+	flags := flag.NewFlagSet("cache "+command, flag.ExitOnError)
+	flags.Usage = func() {
+		cacheUsage()
+		fmt.Fprint(flags.Output(), "\nFlags:\n")
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&dir, "dir", defaultCacheDir(), "path of the cache directory")
+
+	switch command {
+	case "info", "clear":
+	case "prune":
+		flags.DurationVar(&olderThan, "older-than", 30*24*time.Hour, "remove the files which haven't been updated for this duration (e.g. \"72h\")")
+	default:
+		cacheUsage()
+		os.Exit(1)
+	}
+
+	// flag.ExitOnError is set, no need to check for errors
+	_ = flags.Parse(args[1:])
+
+	if len(dir) == 0 {
+		fmt.Fprint(os.Stderr, "Couldn't find the cache directory, set it with \"-dir\"\n")
+		os.Exit(1)
+	}
+
+	var (
+		infos []cache.Info
+		err   error
+	)
+
+	switch command {
+	case "info":
+		infos, err = cache.Stat(dir, flags.Args()...)
+	case "clear":
+		infos, err = cache.Clear(dir, flags.Args()...)
+		if err == nil {
+			fmt.Println("Removed:")
+		}
+	case "prune":
+		infos, err = cache.Prune(dir, olderThan, flags.Args()...)
+		if err == nil {
+			fmt.Printf("Removed the files older than %s:\n", olderThan)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n", plugins.CapitalizeString(err.Error()))
+		os.Exit(1)
+	}
+
+	printCacheInfos(os.Stdout, infos)
+}
+
+func defaultCacheDir() string {
+	dir, err := cache.DefaultDir()
+	if err != nil {
+		return ""
+	}
+	return dir
+}
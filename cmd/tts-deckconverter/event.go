@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	dc "github.com/jeandeaual/tts-deckconverter"
+	"github.com/jeandeaual/tts-deckconverter/log"
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+	"github.com/jeandeaual/tts-deckconverter/tts"
+)
+
+// eventEntry is a line of an event file.
+type eventEntry struct {
+	player string
+	target string
+}
+
+// parseEventFile reads the "PLAYER = DECK" lines of the event file at path,
+// where DECK is a URL or a file (relative to the folder of the event file).
+// The empty lines and the lines starting with "#" are ignored.
+func parseEventFile(path string) ([]eventEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []eventEntry
+
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Split on the first "=", the URLs can contain some
+		index := strings.Index(line, "=")
+		if index < 0 {
+			return nil, fmt.Errorf("invalid line %d: %s (expected \"PLAYER = DECK\")", lineNumber, line)
+		}
+
+		player := strings.TrimSpace(line[:index])
+		target := strings.TrimSpace(line[index+1:])
+		if len(player) == 0 || len(target) == 0 {
+			return nil, fmt.Errorf("invalid line %d: %s (expected \"PLAYER = DECK\")", lineNumber, line)
+		}
+
+		if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") && !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+
+		entries = append(entries, eventEntry{player: player, target: target})
+	}
+
+	return entries, scanner.Err()
+}
+
+// handleEvent converts the deck of each player of the event file
+// config.target, and generates the save file of the event, named after the
+// event file.
+func handleEvent(ctx context.Context, config appConfig) []error {
+	errs := []error{}
+
+	budget := plugins.NewRetryBudget(config.retries)
+	ctx = plugins.WithRetryBudget(ctx, budget)
+	ctx = plugins.WithProgressReporter(ctx, summary)
+	summary.reset(budget)
+	defer func() {
+		log.Infof("Summary for %s: %s", config.target, summary)
+	}()
+
+	entries, err := parseEventFile(config.target)
+	if err != nil {
+		return append(errs, fmt.Errorf("couldn't read the event file: %w", err))
+	}
+
+	var (
+		players  []tts.EventPlayer
+		allDecks []*plugins.Deck
+	)
+
+	for _, entry := range entries {
+		log.Infof("Processing the deck of %s (%s)", entry.player, entry.target)
+
+		decks, err := dc.Parse(ctx, entry.target, config.mode, config.options)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("couldn't parse the deck of %s: %w", entry.player, err))
+			continue
+		}
+
+		decks, err = plugins.ApplyTransforms(decks, config.transforms.transforms)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		decks, err = tts.AddReferenceDeck(ctx, decks, config.reference, config.outputFolder)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		players = append(players, tts.EventPlayer{Name: entry.player, Decks: decks})
+		allDecks = append(allDecks, decks...)
+	}
+	if len(errs) > 0 {
+		// Don't generate an event with missing decks
+		return errs
+	}
+
+	// The players can have decks with the same name
+	tts.DeduplicateFileNames(allDecks)
+
+	if config.uploader != nil {
+		templateErrs, ok := generateTemplates(ctx, config, [][]*plugins.Deck{allDecks})
+		errs = append(errs, templateErrs...)
+		if !ok {
+			return errs
+		}
+	}
+
+	config.backURLs.apply(allDecks)
+	checkImages(ctx, config, allDecks)
+
+	name := strings.TrimSuffix(filepath.Base(config.target), filepath.Ext(config.target))
+	if err := tts.GenerateEvent(ctx, name, players, config.outputFolder, !config.compact); err != nil {
+		errs = append(errs, fmt.Errorf("couldn't generate the event: %w", err))
+	}
+
+	return errs
+}
@@ -1,29 +1,38 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
+	"time"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
 	dc "github.com/jeandeaual/tts-deckconverter"
+	"github.com/jeandeaual/tts-deckconverter/config"
+	"github.com/jeandeaual/tts-deckconverter/export"
 	"github.com/jeandeaual/tts-deckconverter/log"
 	"github.com/jeandeaual/tts-deckconverter/plugins"
 	"github.com/jeandeaual/tts-deckconverter/tts"
 	"github.com/jeandeaual/tts-deckconverter/tts/upload"
 )
 
-func handleFolder(config appConfig) []error {
+func handleFolder(ctx context.Context, config appConfig) []error {
 	log.Infof("Processing directory %s", config.target)
 
 	files := []string{}
 	errs := []error{}
 
+	outputFolder, _ := filepath.Abs(config.outputFolder)
+
 	err := filepath.Walk(config.target, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -36,6 +45,11 @@ func handleFolder(config appConfig) []error {
 		}
 
 		if info.IsDir() {
+			// With "-mirror", process the subfolders, except the output
+			// folder if it is inside the target folder
+			if absPath, err := filepath.Abs(path); config.mirror && err == nil && absPath != outputFolder {
+				return nil
+			}
 			log.Infof("Ignoring directory %s", path)
 			// Do not process the files in the subfolder
 			return filepath.SkipDir
@@ -54,17 +68,126 @@ func handleFolder(config appConfig) []error {
 		return errs
 	}
 
+	targets := make([]appConfig, 0, len(files))
+
 	for _, file := range files {
 		fileConfig := config
 		fileConfig.target = file
-		targetErrs := handleTarget(fileConfig)
+
+		if config.mirror {
+			// Mirror the structure of the target folder in the output folder
+			rel, err := filepath.Rel(config.target, filepath.Dir(file))
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			fileConfig.outputFolder = filepath.Join(config.outputFolder, rel)
+			if err := checkCreateDir(fileConfig.outputFolder); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+		}
+
+		targets = append(targets, fileConfig)
+	}
+
+	if config.uploader != nil && !config.mirror && len(targets) > 1 {
+		return append(errs, handleBatch(ctx, config, targets)...)
+	}
+
+	for _, target := range targets {
+		if err := ctx.Err(); err != nil {
+			return append(errs, err)
+		}
+
+		targetErrs := handleTarget(ctx, target)
 		errs = append(errs, targetErrs...)
 	}
 
 	return errs
 }
 
-func handleTarget(config appConfig) []error {
+func handleTarget(ctx context.Context, config appConfig) []error {
+	budget := plugins.NewRetryBudget(config.retries)
+	ctx = plugins.WithRetryBudget(ctx, budget)
+	ctx = plugins.WithProgressReporter(ctx, summary)
+	summary.reset(budget)
+	defer func() {
+		log.Infof("Summary for %s: %s", config.target, summary)
+	}()
+
+	decks, errs := prepareTarget(ctx, config)
+	if decks == nil {
+		return errs
+	}
+
+	if config.uploader != nil {
+		templateErrs, ok := generateTemplates(ctx, config, [][]*plugins.Deck{decks})
+		errs = append(errs, templateErrs...)
+		if !ok {
+			return errs
+		}
+	}
+
+	return append(errs, outputDecks(ctx, config, decks)...)
+}
+
+// handleBatch converts the targets of a folder, sharing the template sheets
+// of their decks: the cards appearing in several decks are only uploaded
+// once.
+func handleBatch(ctx context.Context, config appConfig, targets []appConfig) []error {
+	errs := []error{}
+
+	budget := plugins.NewRetryBudget(config.retries)
+	ctx = plugins.WithRetryBudget(ctx, budget)
+	ctx = plugins.WithProgressReporter(ctx, summary)
+	summary.reset(budget)
+	defer func() {
+		log.Infof("Summary for %s: %s", config.target, summary)
+	}()
+
+	var (
+		prepared []appConfig
+		decks    [][]*plugins.Deck
+		allDecks []*plugins.Deck
+	)
+
+	for _, target := range targets {
+		if err := ctx.Err(); err != nil {
+			return append(errs, err)
+		}
+
+		targetDecks, targetErrs := prepareTarget(ctx, target)
+		errs = append(errs, targetErrs...)
+		if targetDecks == nil {
+			continue
+		}
+
+		prepared = append(prepared, target)
+		decks = append(decks, targetDecks)
+		allDecks = append(allDecks, targetDecks...)
+	}
+
+	// The files of the decks and of their templates are in the same folder
+	tts.DeduplicateFileNames(allDecks)
+
+	templateErrs, ok := generateTemplates(ctx, config, [][]*plugins.Deck{allDecks})
+	errs = append(errs, templateErrs...)
+	if !ok {
+		return errs
+	}
+
+	for i, target := range prepared {
+		errs = append(errs, outputDecks(ctx, target, decks[i])...)
+	}
+
+	return errs
+}
+
+// prepareTarget parses the decks of config.target and transforms them. The
+// returned decks are nil if there is nothing left to do (e.g. when the decks
+// are dumped with "-dump-decks").
+func prepareTarget(ctx context.Context, config appConfig) ([]*plugins.Deck, []error) {
 	errs := []error{}
 
 	var (
@@ -72,10 +195,12 @@ func handleTarget(config appConfig) []error {
 		err   error
 	)
 
-	if config.target != "-" {
+	if config.loadDecks {
+		decks, err = loadDecks(config.target)
+	} else if config.target != "-" {
 		log.Infof("Processing %s", config.target)
 
-		decks, err = dc.Parse(config.target, config.mode, config.options)
+		decks, err = dc.Parse(ctx, config.target, config.mode, config.options)
 	} else {
 		plugin, found := dc.Plugins[config.mode]
 		if !found {
@@ -91,35 +216,329 @@ func handleTarget(config appConfig) []error {
 
 		log.Info("Processing stdin")
 
-		decks, err = handler(os.Stdin, config.deckName, config.options)
+		decks, err = handler(ctx, os.Stdin, config.deckName, config.options)
 	}
 	if err != nil {
 		errs = append(errs, fmt.Errorf("couldn't parse target: %w", err))
-		return errs
+		return nil, errs
 	}
 
-	if config.uploader != nil {
-		templateErrs := tts.GenerateTemplates([][]*plugins.Deck{decks}, config.outputFolder, *config.uploader)
-		if len(templateErrs) > 0 {
-			uploadSizeErrsOnly := true
-			for _, err := range templateErrs {
-				if !errors.Is(err, upload.ErrUploadSize) {
-					uploadSizeErrsOnly = false
-				}
-			}
+	plugins.RenameDecks(decks, config.renameDeck, config.nameSuffix)
+	tts.DeduplicateFileNames(decks)
 
-			// If the only error we got was that the template was too big to be uploaded, continue
-			// The user will be able to upload the template manually later on
-			if !uploadSizeErrsOnly {
-				return templateErrs
-			}
+	decks, err = plugins.ApplyTransforms(decks, config.transforms.transforms)
+	if err != nil {
+		errs = append(errs, err)
+		return nil, errs
+	}
+
+	if config.validate {
+		err = validateDecks(config, decks)
+		if err != nil {
+			errs = append(errs, err)
+			return nil, errs
+		}
+	}
+
+	if len(config.dumpDecks) > 0 {
+		err = dumpDecks(decks, config.backURLs, config.dumpDecks)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("couldn't write the decks: %w", err))
+		}
+		return nil, errs
+	}
+
+	if config.deckBox && len(decks) > 0 {
+		decks[0].Boxed = true
+	}
+
+	decks, err = tts.AddReferenceDeck(ctx, decks, config.reference, config.outputFolder)
+	if err != nil {
+		errs = append(errs, err)
+		return nil, errs
+	}
+
+	return decks, errs
+}
+
+// generateTemplates generates the template sheets of decks, and reports
+// whether the conversion can go on.
+func generateTemplates(ctx context.Context, config appConfig, decks [][]*plugins.Deck) ([]error, bool) {
+	templateErrs := tts.GenerateTemplates(ctx, decks, config.outputFolder, *config.uploader)
+
+	// If the only error we got was that the template was too big to be uploaded, continue
+	// The user will be able to upload the template manually later on
+	for _, err := range templateErrs {
+		if !errors.Is(err, upload.ErrUploadSize) {
+			return templateErrs, false
+		}
+	}
+
+	return templateErrs, true
+}
+
+// outputDecks writes the files of decks in every selected format, and sends
+// them to Tabletop Simulator or to a webhook if requested.
+func outputDecks(ctx context.Context, config appConfig, decks []*plugins.Deck) []error {
+	errs := []error{}
+
+	config.backURLs.apply(decks)
+
+	checkImages(ctx, config, decks)
+
+	// The decks are only parsed once, whatever the number of formats
+	for _, exporter := range config.exporters {
+		exportErrs := export.Exporters[exporter].Export(ctx, decks, export.Options{
+			OutputFolder: config.outputFolder,
+			Indent:       !config.compact,
+		})
+		errs = append(errs, exportErrs...)
+	}
+
+	if len(config.tablePreset) > 0 {
+		if err := generateTable(ctx, config, decks); err != nil {
+			errs = append(errs, fmt.Errorf("couldn't generate the table: %w", err))
+		}
+	}
+
+	if config.spawn {
+		spawnErrs := tts.Spawn(ctx, decks, "", tts.ExternalEditorAddress)
+		errs = append(errs, spawnErrs...)
+	}
+
+	if len(config.webhook) > 0 {
+		if err := tts.PostWebhook(ctx, decks, "", config.webhook); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// checkImages warns about the card backs of decks which would look wrong in
+// TTS, and with "-check-urls", about the images which can't be retrieved.
+func checkImages(ctx context.Context, config appConfig, decks []*plugins.Deck) {
+	// The custom backs are checked even without "-check-urls", since a
+	// back with the wrong size is only noticed once loaded in TTS
+	if len(config.backURLs) > 0 || config.checkURLs {
+		for _, err := range tts.CheckBackURLs(ctx, decks, knownBackURLs()) {
+			log.Warn(plugins.CapitalizeString(err.Error()))
+		}
+	}
+
+	if config.checkURLs {
+		for _, err := range tts.CheckImageURLs(ctx, decks) {
+			log.Warn(plugins.CapitalizeString(err.Error()))
+		}
+	}
+}
+
+// knownBackURLs returns the URLs of the card backs provided by the plugins,
+// which don't need to be checked.
+func knownBackURLs() map[string]bool {
+	known := make(map[string]bool)
+
+	for _, pluginID := range dc.AvailablePlugins() {
+		for _, back := range dc.Plugins[pluginID].AvailableBacks() {
+			known[back.URL] = true
+		}
+	}
+
+	return known
+}
+
+// validateDecks checks that the decks follow the construction rules of their
+// game, and logs the rules they break.
+func validateDecks(config appConfig, decks []*plugins.Deck) error {
+	plugin, found := dc.FindPlugin(config.target, config.mode)
+	if !found {
+		return fmt.Errorf("couldn't find the mode of %s, set it with \"-mode\" to validate the decks", config.target)
+	}
+
+	violations, err := dc.Validate(plugin.PluginID(), decks, config.options)
+	if err != nil {
+		return err
+	}
+
+	if len(violations) == 0 {
+		log.Infof("The decks follow the rules of %s", plugin.PluginName())
+		return nil
+	}
+
+	for _, violation := range violations {
+		log.Warn(violation)
+	}
+
+	return fmt.Errorf("%d deck construction rule(s) of %s broken", len(violations), plugin.PluginName())
+}
+
+// generateTable writes the save file of the table preset of the game of
+// the decks, with a copy of the decks for each player.
+func generateTable(ctx context.Context, config appConfig, decks []*plugins.Deck) error {
+	plugin, found := dc.FindPlugin(config.target, config.mode)
+	if !found {
+		return fmt.Errorf("couldn't find the mode of %s, set it with \"-mode\" to generate a table", config.target)
+	}
+
+	preset, err := dc.FindTablePreset(plugin.PluginID(), config.tablePreset)
+	if err != nil {
+		return err
+	}
+
+	return tts.GenerateTable(ctx, decks, preset, config.tablePlayers, config.outputFolder, !config.compact)
+}
+
+// loadDecks reads the decks previously written with "-dump-decks" from path,
+// or from stdin if path is "-".
+func loadDecks(path string) ([]*plugins.Deck, error) {
+	if path == "-" {
+		log.Info("Loading decks from stdin")
+		return plugins.DecodeDecks(os.Stdin)
+	}
+
+	log.Infof("Loading decks from %s", path)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return plugins.DecodeDecks(file)
+}
+
+// dumpDecks writes the parsed decks to path, or to stdout if path is "-",
+// instead of generating the TTS files.
+func dumpDecks(decks []*plugins.Deck, backURLs sectionBacks, path string) error {
+	backURLs.apply(decks)
+
+	if path == "-" {
+		return plugins.EncodeDecks(os.Stdout, decks)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = plugins.EncodeDecks(file, decks)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Wrote decks to %s", path)
+
+	return nil
+}
+
+// newContext returns a context cancelled when the user interrupts the program
+// (e.g. with Ctrl-C) or it is terminated, or after timeout if it is greater
+// than 0. The files being written when the context is cancelled are removed,
+// so that no truncated saved object or template is left behind.
+func newContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-interrupt:
+			log.Warn("Interrupted, stopping the conversion (interrupt again to exit immediately)")
+			cancel()
+		case <-ctx.Done():
+		}
+		// Restore the default behavior, so that a second interrupt exits
+		signal.Stop(interrupt)
+	}()
+
+	return ctx, cancel
+}
+
+func uploadBackFile(ctx context.Context, backFile string, uploader upload.TemplateUploader) (string, error) {
+	if _, err := os.Stat(backFile); err != nil {
+		return "", fmt.Errorf("invalid back file %s: %w", backFile, err)
+	}
+
+	name := strings.TrimSuffix(filepath.Base(backFile), filepath.Ext(backFile))
+
+	log.Infof("Uploading card back %s", backFile)
+
+	url, err := upload.UploadContext(ctx, uploader, backFile, name, plugins.HTTPClient())
+	if err != nil {
+		return "", fmt.Errorf("couldn't upload card back %s: %w", backFile, err)
+	}
+
+	log.Infof("Using card back URL %s", url)
+
+	return url, nil
+}
+
+// registerCredentials registers the User-Agent, headers, cookies and proxies
+// set in the configuration file and on the command line.
+func registerCredentials(config appConfig) {
+	config.config.RegisterSites()
+
+	if len(config.userAgent) > 0 {
+		plugins.SetUserAgent(config.userAgent)
+	}
+
+	for _, header := range config.headers.values {
+		plugins.AddHostHeader(header.host, header.key, header.value)
+	}
+	for _, cookie := range config.cookies.values {
+		plugins.AddHostCookie(cookie.host, cookie.key, cookie.value)
+	}
+	for host, proxyURL := range config.proxies {
+		// The proxy URLs have been checked when parsing the flags
+		_ = plugins.SetHostProxy(host, proxyURL)
+	}
+}
+
+// registerRateLimits changes the rate limits of the plugins using the
+// configuration file and the command line.
+func registerRateLimits(config appConfig) error {
+	err := config.config.RegisterRateLimits()
+	if err != nil {
+		return err
+	}
+
+	return config.rateLimits.register()
+}
 
-			errs = append(errs, templateErrs...)
+// loadExternalPlugins loads the external plugins listed in the configuration
+// file.
+func loadExternalPlugins(config *config.Config) {
+	for _, path := range config.Plugins {
+		if err := dc.LoadExternalPlugin(path); err != nil {
+			fmt.Fprintf(os.Stderr, "Couldn't load plugin %s: %v\n\n", path, err)
+			os.Exit(1)
 		}
 	}
+}
+
+func defaultConfigPath() string {
+	path, err := config.DefaultPath()
+	if err != nil {
+		return ""
+	}
+	return path
+}
 
-	generateErrs := tts.Generate(decks, config.backURL, config.outputFolder, !config.compact)
-	return append(errs, generateErrs...)
+func loadConfig(path string) (*config.Config, error) {
+	return config.Load(path)
 }
 
 func checkCreateDir(path string) error {
@@ -152,53 +571,152 @@ func checkErrs(errs []error) {
 
 type appConfig struct {
 	target       string
-	backURL      string
-	back         string
+	backURLs     sectionBacks
+	backs        sectionBacks
+	backFile     string
 	debug        bool
 	mode         string
 	deckName     string
+	renameDeck   string
+	nameSuffix   string
+	fileNames    string
 	deckFormat   string
 	outputFolder string
+	stdout       bool
 	chest        string
+	mirror       bool
 	templateMode string
+	sizing       tts.TemplateSizing
+	workers      int
+	saturation   float64
+	descriptions tts.DescriptionLimit
+	watermark    tts.Watermark
+	budget       tts.DownloadBudget
+	reference    string
+	deckBox      bool
+	deckBoxMesh  string
+	positions    sectionVectors
+	rotations    sectionVectors
+	scales       sectionScales
 	uploader     *upload.TemplateUploader
 	compact      bool
+	combine      bool
+	split        bool
+	noCache      bool
 	options      options
+	configPath   string
+	config       *config.Config
+	headers      hostValues
+	cookies      hostValues
+	proxies      hostProxies
+	userAgent    string
+	rateLimits   rateLimits
+	timeout      time.Duration
+	retries      int
+	dumpDecks    string
+	loadDecks    bool
+	transforms   transforms
+	validate     bool
+	checkURLs    bool
+	spawn        bool
+	webhook      string
+	guidMap      string
+	tablePreset  string
+	event        bool
+	tablePlayers int
+	exporters    []string
 }
 
 func parseFlags() appConfig {
 	var (
 		config      appConfig
 		showVersion bool
+		exporters   string
 	)
 
 	availableModes := dc.AvailablePlugins()
 	availableOptions := getAvailableOptions(availableModes)
 	availableDeckFormats := getAvailableDeckFormats(availableModes)
 	availableBacks := getAvailableBacks(availableModes)
+	availableTablePresets := getAvailableTablePresets(availableModes)
 	availableUploaders := getAvailableUploaders()
 
 	config.options = make(options)
+	config.backs = make(sectionBacks)
+	config.backURLs = make(sectionBacks)
+	config.rateLimits = make(rateLimits)
+	config.positions = make(sectionVectors)
+	config.rotations = make(sectionVectors)
+	config.scales = make(sectionScales)
+	config.proxies = make(hostProxies)
+	config.headers.separator = ":"
+	config.cookies.separator = "="
 
 	flag.Usage = func() {
-		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s TARGET\n\nFlags:\n", filepath.Base(os.Args[0]))
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s TARGET\n       %s COMMAND [FLAGS] TARGET\n\nCommands:%s\n\nFlags:\n", filepath.Base(os.Args[0]), filepath.Base(os.Args[0]), getAvailableSubcommands())
 		flag.PrintDefaults()
 	}
 
-	flag.StringVar(&config.back, "back", "", "card back (cannot be used with \"-backURL\"). Choose from:"+availableBacks)
-	flag.StringVar(&config.backURL, "backURL", "", "custom URL for the card backs (cannot be used with \"-back\")")
+	flag.Var(&config.backs, "back", "card back, for every deck (e.g. \"planechase\") or for a single section (e.g. \"sideboard=planechase\") (can have multiple, cannot be used with \"-backURL\" for the same section). Choose from the backs defined in the configuration file, or:"+availableBacks)
+	flag.Var(&config.backURLs, "backURL", "custom URL for the card backs, for every deck or for a single section (e.g. \"tokens=https://example.com/back.png\") (can have multiple, cannot be used with \"-back\" for the same section)")
+	flag.StringVar(&config.backFile, "back-file", "", "local image used for the card backs, uploaded using the template uploader (requires \"-template\", cannot be used with \"-back\" or \"-backURL\")")
 	flag.StringVar(&config.mode, "mode", "", "available modes: "+strings.Join(availableModes, ", "))
 	flag.StringVar(&config.deckName, "name", "", "name of the deck (usually inferred from the input file name or URL, but required with stdin)")
+	flag.StringVar(&config.renameDeck, "deck-name", "", "replace the name of the deck inferred from the input file name or page title (e.g. to remove the name of the website)")
+	flag.StringVar(&config.nameSuffix, "suffix", "", "append this suffix to the name of the deck (e.g. \" (v2)\")")
+	flag.StringVar(&config.fileNames, "filenames", tts.FileNameStyleDefault, "style of the generated file names: \"default\" (only replace the characters which can't be used in a file name) or \"ascii\" (also transliterate them to ASCII)")
 	flag.StringVar(&config.deckFormat, "format", "", "format of the deck (usually inferred from the input file name or URL, but required with stdin)"+availableDeckFormats)
-	flag.StringVar(&config.outputFolder, "output", "", "destination folder (defaults to the current folder) (cannot be used with \"-chest\")")
+	flag.StringVar(&config.outputFolder, "output", "", "destination folder (defaults to the current folder), or \"-\" to write a single saved object containing every deck to stdout (cannot be used with \"-chest\")")
+	flag.StringVar(&config.outputFolder, "o", "", "shorthand for \"-output\"")
 	flag.StringVar(&config.chest, "chest", "", "save to the Tabletop Simulator chest folder (use \"/\" for the root folder) (cannot be used with \"-output\")")
+	flag.BoolVar(&config.mirror, "mirror", false, "when the target is a folder, also convert the files of its subfolders, and mirror its structure in the output folder (or chest folder)")
 	flag.StringVar(&config.templateMode, "template", "", "download each images and create a deck template instead of referring to each image individually. Choose from the following uploaders:"+availableUploaders)
+	flag.IntVar(&config.sizing.MaxSize, "template-max-size", 0, "maximum width and height of the template sheets in pixels (e.g. 4096, the largest texture size recommended by Tabletop Simulator), the larger sheets are scaled down (no maximum by default)")
+	flag.BoolVar(&config.sizing.PowerOfTwo, "template-pow2", false, "resize the template sheets to the nearest power of two dimensions (without exceeding \"-template-max-size\")")
+	flag.StringVar(&config.sizing.Filter, "template-filter", tts.DefaultTemplateFilter, "resampling filter used to resize the card images of the template sheets: "+strings.Join(tts.AvailableTemplateFilters(), ", "))
+	flag.IntVar(&config.workers, "template-workers", tts.DefaultTemplateWorkers, "number of card images downloaded concurrently to generate the template sheets (the downloads from the same server are still spaced out)")
+	flag.Float64Var(&config.saturation, "template-saturation", 1, "saturation of the card images of the template sheets, from 0 (grayscale) to 1 (unchanged), e.g. to save ink when printing the sheets (requires \"-template\")")
+	flag.Var((*byteSize)(&config.budget.Total), "max-download-size", "maximum size of the card images downloaded to generate the template sheets, e.g. \"500MB\" on a metered connection (lower-quality images are downloaded when available to stay under this size, no maximum by default)")
+	flag.Var((*byteSize)(&config.budget.Image), "max-image-size", "maximum size of each card image downloaded to generate the template sheets, e.g. \"800KB\" (a lower-quality image is downloaded instead when available, no maximum by default)")
+	flag.StringVar(&config.reference, "reference-card", "", "text file (e.g. the quick rules of the format or the notes of the deck) rendered on an extra card, generated as a separate object so that the table has a rules reference (requires \"-template\")")
+	flag.BoolVar(&config.deckBox, "deck-box", false, "put the main deck in a deck box whose texture shows the name of the deck and the art of its commander (or of its first card) (requires \"-template\")")
+	flag.StringVar(&config.deckBoxMesh, "deck-box-mesh", "", "URL of the OBJ model of the deck boxes, using the texture layout of the model written by \"-deck-box\" (by default, the model is written in the output folder and only works on this computer)")
+	flag.StringVar(&config.watermark.Text, "watermark", "", "text overlaid on each card of the template sheets, e.g. \"PROXY\" for the playgroups requiring it (requires \"-template\")")
+	flag.Float64Var(&config.watermark.Opacity, "watermark-opacity", tts.DefaultWatermarkOpacity, "opacity of the watermark, between 0 and 1")
+	flag.IntVar(&config.descriptions.MaxLength, "description-max-length", 0, "truncate the card descriptions longer than this number of characters, so that their tooltips fit on the screen (no limit by default)")
+	flag.StringVar(&config.descriptions.Ellipsis, "description-ellipsis", tts.DefaultEllipsis, "text appended to the truncated card descriptions")
+	flag.BoolVar(&config.descriptions.GMNotes, "description-gm-notes", false, "move the truncated part of the card descriptions to their GM notes instead of dropping it")
+	flag.Var(&config.positions, "position", "initial position of the generated objects on the table, e.g. where the scripts of a table expect them, for every deck or for a single deck section (format: \"X,Y,Z\" or \"SECTION=X,Y,Z\", e.g. \"Sideboard=-10,1,0\", can have multiple) (the other decks are placed next to each other from this position)")
+	flag.Var(&config.rotations, "rotation", "initial rotation of the generated objects in degrees, replacing the facing of the decks, for every deck or for a single deck section (format: \"X,Y,Z\" or \"SECTION=X,Y,Z\", e.g. \"0,180,180\" for the decks face down, can have multiple)")
+	flag.Var(&config.scales, "scale", "multiply the size of the generated objects and their cards by this factor, for every deck or for a single deck section (e.g. \"2\" or \"Tokens=0.8\", can have multiple) (default 1)")
 	flag.Var(&config.options, "option", "plugin specific option (can have multiple)"+availableOptions)
 	flag.BoolVar(&config.compact, "compact", false, "don't indent the resulting JSON file")
+	flag.BoolVar(&config.combine, "combine", false, "generate a single file containing every deck of the target (e.g. the main deck, the sideboard and the tokens) next to each other, named after the main deck (cannot be used with \"-split\")")
+	flag.BoolVar(&config.split, "split", false, "generate a file for each deck of the target, named after the deck (e.g. \"Deck - Sideboard.json\") (default, cannot be used with \"-combine\")")
+	flag.BoolVar(&config.noCache, "no-cache", false, "don't read or write the on-disk cache of the API responses and card images, sending every request (see the \"cache\" command)")
+	flag.StringVar(&exporters, "export", export.DefaultExporter, "format of the generated files, or comma-separated list of formats (e.g. \"tts,ttpg\"):"+getAvailableExporters())
+	flag.Var(&config.transforms, "transform", "transformation applied to the decks before generating the Tabletop Simulator files (can have multiple, applied in order)"+getAvailableTransforms())
+	flag.BoolVar(&config.validate, "validate", false, "check that the decks follow the construction rules of their game (deck size, copy limits, banned cards) before generating the Tabletop Simulator files")
+	flag.BoolVar(&config.checkURLs, "check-urls", false, "check that the card images and backs can be retrieved before generating the Tabletop Simulator files, and warn about the dead links")
+	flag.BoolVar(&config.spawn, "spawn", false, "also spawn the generated decks in a running Tabletop Simulator instance, through its External Editor API (a game needs to be loaded)")
+	flag.StringVar(&config.tablePreset, "table-preset", "", "also generate a save file with a seat for each player (hand zone, mat and counters) and a copy of the decks on each mat (format: \"NAME\" or \"NAME:PLAYERS\" for 2 to 4 players, e.g. \"commander:3\"), available presets:"+availableTablePresets)
+	flag.BoolVar(&config.event, "event", false, "the target is an event file with a \"PLAYER = DECK\" line for each player (DECK being a URL or a file), generate a single save file with the deck of each player at their seat, labeled with their name (up to 10 players)")
+	flag.StringVar(&config.guidMap, "guid-map", "", "JSON file mapping the name of each generated object (e.g. \"Deck - Sideboard\") to its GUID, so that Lua scripts can find the generated decks with getObjectFromGUID (updated when it already exists)")
+	flag.StringVar(&config.webhook, "webhook", "", "also send the generated decks to this webhook URL (Discord webhooks receive them as attachments, other webhooks as JSON)")
+	flag.StringVar(&config.dumpDecks, "dump-decks", "", "write the parsed decks to this JSON file (\"-\" for stdout) instead of generating the Tabletop Simulator files (cannot be used with \"-template\" or a folder)")
+	flag.BoolVar(&config.loadDecks, "load-decks", false, "the target is a JSON file written with \"-dump-decks\" (\"-\" for stdin) instead of a deck list")
+	flag.IntVar(&config.retries, "retries", 10, "maximum number of retries of the failed requests (network errors, rate limits and server errors) for each target, 0 to disable them")
+	flag.DurationVar(&config.timeout, "timeout", 0, "stop the conversion if it takes longer than this duration (e.g. \"5m\") (no timeout by default)")
 	if len(version) > 0 {
 		flag.BoolVar(&showVersion, "version", false, "display the version information")
 	}
 	flag.BoolVar(&config.debug, "debug", false, "enable debug logging")
+	flag.StringVar(&config.configPath, "config", defaultConfigPath(), "path of the configuration file")
+	flag.Var(&config.headers, "header", "header sent with each request to a website, e.g. to import private decks (format: \"HOST=NAME: VALUE\", can have multiple)")
+	flag.Var(&config.rateLimits, "rate-limit", "minimum interval between two API calls, for every plugin (e.g. \"200ms\") or for a single one (e.g. \"mtg=200ms\") (can have multiple)"+getAvailableRateLimits())
+	flag.Var(&config.cookies, "cookie", "cookie sent with each request to a website, e.g. to import private decks (format: \"HOST=NAME=VALUE\", can have multiple)")
+	flag.StringVar(&config.userAgent, "user-agent", "", "User-Agent header sent with each request, replacing the one of the configuration file (default \""+plugins.DefaultUserAgent+"\")")
+	flag.Var(&config.proxies, "proxy", "proxy used for the requests to a website and its subdomains, e.g. if it is blocked in your region (format: \"HOST=URL\", e.g. \"example.com=socks5://localhost:1080\", can have multiple)")
 
 	flag.Parse()
 
@@ -213,6 +731,24 @@ func parseFlags() appConfig {
 		os.Exit(1)
 	}
 
+	var err error
+
+	config.config, err = loadConfig(config.configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n\n", plugins.CapitalizeString(err.Error()))
+		os.Exit(1)
+	}
+
+	// External plugins need to be loaded before checking the mode
+	loadExternalPlugins(config.config)
+
+	// The uploaders of the configuration file need to be registered before
+	// checking the template mode
+	if err := config.config.RegisterUploaders(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n\n", plugins.CapitalizeString(err.Error()))
+		os.Exit(1)
+	}
+
 	plugin, found := dc.Plugins[config.mode]
 	if len(config.mode) > 0 && !found {
 		fmt.Fprintf(os.Stderr, "Invalid mode: %s\n\n", config.mode)
@@ -220,32 +756,221 @@ func parseFlags() appConfig {
 		os.Exit(1)
 	}
 
+	if err := tts.SetFileNameStyle(config.fileNames); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n\n", plugins.CapitalizeString(err.Error()))
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if err := tts.SetTemplateSizing(config.sizing); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n\n", plugins.CapitalizeString(err.Error()))
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if err := tts.SetDescriptionLimit(config.descriptions); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n\n", plugins.CapitalizeString(err.Error()))
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if err := tts.SetPlacement(buildPlacement(config.positions, config.rotations, config.scales)); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n\n", plugins.CapitalizeString(err.Error()))
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if err := tts.SetTemplateWorkers(config.workers); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n\n", plugins.CapitalizeString(err.Error()))
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if err := tts.SetTemplateSaturation(config.saturation); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n\n", plugins.CapitalizeString(err.Error()))
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if err := tts.SetWatermark(config.watermark); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n\n", plugins.CapitalizeString(err.Error()))
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	tts.SetDeckBoxMesh(config.deckBoxMesh)
+
+	if config.combine && config.split {
+		fmt.Fprint(os.Stderr, "\"-combine\" and \"-split\" cannot be used at the same time\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+	if config.combine {
+		tts.SetOutputLayout(tts.OutputCombined)
+	}
+
+	if err := tts.SetDownloadBudget(config.budget); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n\n", plugins.CapitalizeString(err.Error()))
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	tts.SetGUIDMapFile(config.guidMap)
+
+	if len(config.tablePreset) > 0 {
+		name, players, err := plugins.ParseTablePreset(config.tablePreset)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n\n", plugins.CapitalizeString(err.Error()))
+			flag.Usage()
+			os.Exit(1)
+		}
+		config.tablePreset = name
+		config.tablePlayers = players
+	}
+
 	if len(config.outputFolder) > 0 && len(config.chest) > 0 {
 		fmt.Fprint(os.Stderr, "\"-output\" and \"-chest\" cannot be used at the same time\n\n")
 		flag.Usage()
 		os.Exit(1)
 	}
 
-	if len(config.back) > 0 && len(config.backURL) > 0 {
-		fmt.Fprint(os.Stderr, "\"-back\" and \"-backURL\" cannot be used at the same time\n\n")
+	for section := range config.backs {
+		if _, found := config.backURLs[section]; found {
+			fmt.Fprint(os.Stderr, "\"-back\" and \"-backURL\" cannot be used at the same time for the same section\n\n")
+			flag.Usage()
+			os.Exit(1)
+		}
+	}
+
+	_, defaultBack := config.backs[""]
+	_, defaultBackURL := config.backURLs[""]
+	if len(config.backFile) > 0 && (defaultBack || defaultBackURL) {
+		fmt.Fprint(os.Stderr, "\"-back-file\" cannot be used with \"-back\" or \"-backURL\" without a section\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if len(config.reference) > 0 && len(config.templateMode) == 0 {
+		fmt.Fprint(os.Stderr, "You need to choose a template uploader in order to use \"-reference-card\"\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if len(config.reference) > 0 {
+		text, err := ioutil.ReadFile(config.reference)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Couldn't read the reference card: %v\n\n", err)
+			os.Exit(1)
+		}
+		config.reference = string(text)
+	}
+
+	if config.deckBox && len(config.templateMode) == 0 {
+		fmt.Fprint(os.Stderr, "You need to choose a template uploader in order to use \"-deck-box\"\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if len(config.watermark.Text) > 0 && len(config.templateMode) == 0 {
+		fmt.Fprint(os.Stderr, "You need to choose a template uploader in order to use \"-watermark\"\n\n")
 		flag.Usage()
 		os.Exit(1)
 	}
 
-	if len(config.back) > 0 && plugin == nil {
-		fmt.Fprint(os.Stderr, "You need to choose a mode in order to use \"-back\"\n\n")
+	if config.saturation != 1 && len(config.templateMode) == 0 {
+		fmt.Fprint(os.Stderr, "You need to choose a template uploader in order to use \"-template-saturation\"\n\n")
 		flag.Usage()
 		os.Exit(1)
 	}
 
-	if len(config.back) > 0 {
-		chosenBack, found := plugin.AvailableBacks()[config.back]
+	if (config.budget.Total > 0 || config.budget.Image > 0) && len(config.templateMode) == 0 {
+		fmt.Fprint(os.Stderr, "You need to choose a template uploader in order to use \"-max-download-size\" or \"-max-image-size\"\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if len(config.backFile) > 0 && len(config.templateMode) == 0 {
+		fmt.Fprint(os.Stderr, "You need to choose a template uploader in order to use \"-back-file\"\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	for section, back := range config.backs {
+		if customBack, found := config.config.Back(back); found {
+			config.backURLs[section] = customBack.URL
+			continue
+		}
+
+		if plugin == nil {
+			fmt.Fprint(os.Stderr, "You need to choose a mode in order to use \"-back\"\n\n")
+			flag.Usage()
+			os.Exit(1)
+		}
+
+		chosenBack, found := plugin.AvailableBacks()[back]
 		if !found {
-			fmt.Fprintf(os.Stderr, "Invalid back for %s: %s\n\n", config.mode, config.back)
+			fmt.Fprintf(os.Stderr, "Invalid back for %s: %s\n\n", config.mode, back)
+			flag.Usage()
+			os.Exit(1)
+		}
+		config.backURLs[section] = chosenBack.URL
+	}
+
+	if len(config.dumpDecks) > 0 && len(config.templateMode) > 0 {
+		fmt.Fprint(os.Stderr, "\"-dump-decks\" and \"-template\" cannot be used at the same time\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if len(config.dumpDecks) > 0 && config.spawn {
+		fmt.Fprint(os.Stderr, "\"-dump-decks\" and \"-spawn\" cannot be used at the same time\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if len(config.dumpDecks) > 0 && len(config.webhook) > 0 {
+		fmt.Fprint(os.Stderr, "\"-dump-decks\" and \"-webhook\" cannot be used at the same time\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if len(config.dumpDecks) > 0 && config.loadDecks {
+		fmt.Fprint(os.Stderr, "\"-dump-decks\" and \"-load-decks\" cannot be used at the same time\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if config.event && (len(config.dumpDecks) > 0 || config.loadDecks) {
+		fmt.Fprint(os.Stderr, "\"-event\" cannot be used with \"-dump-decks\" or \"-load-decks\"\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	selectedExporters := make(map[string]bool)
+	for _, id := range strings.Split(exporters, ",") {
+		id = strings.TrimSpace(id)
+		if len(id) == 0 || selectedExporters[id] {
+			continue
+		}
+		selectedExporters[id] = true
+
+		if exporter, found := export.Exporters[id]; !found {
+			fmt.Fprintf(os.Stderr, "Invalid export format: %s\n\n", id)
+			flag.Usage()
+			os.Exit(1)
+		} else if exporter.RequiresTemplates && len(config.templateMode) == 0 {
+			fmt.Fprintf(os.Stderr, "You need to choose a template uploader in order to use \"-export %s\"\n\n", id)
 			flag.Usage()
 			os.Exit(1)
 		}
-		config.backURL = chosenBack.URL
+
+		config.exporters = append(config.exporters, id)
+	}
+
+	if len(config.exporters) == 0 {
+		fmt.Fprint(os.Stderr, "\"-export\" requires at least one format\n\n")
+		flag.Usage()
+		os.Exit(1)
 	}
 
 	if len(config.templateMode) > 0 {
@@ -260,7 +985,32 @@ func parseFlags() appConfig {
 
 	config.target = flag.Args()[0]
 
-	if config.target == "-" {
+	if info, err := os.Stat(config.target); len(config.dumpDecks) > 0 && err == nil && info.IsDir() {
+		fmt.Fprint(os.Stderr, "\"-dump-decks\" cannot be used with a folder\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if info, err := os.Stat(config.target); len(config.renameDeck) > 0 && err == nil && info.IsDir() {
+		fmt.Fprint(os.Stderr, "\"-deck-name\" cannot be used with a folder, since every deck would have the same name (use \"-suffix\" instead)\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if config.outputFolder == "-" {
+		checkStdoutOutput(config)
+		config.stdout = true
+		config.outputFolder = ""
+		tts.SetOutputWriter(os.Stdout)
+	}
+
+	if config.loadDecks {
+		if len(config.deckName) > 0 {
+			fmt.Fprintln(os.Stderr, "You can't set the deck name when loading decks")
+			flag.Usage()
+			os.Exit(1)
+		}
+	} else if config.target == "-" {
 		if len(config.mode) == 0 {
 			fmt.Fprintln(os.Stderr, "-mode is required when parsing stdin")
 			flag.Usage()
@@ -281,12 +1031,43 @@ func parseFlags() appConfig {
 	return config
 }
 
-func main() {
-	config := parseFlags()
+// checkStdoutOutput exits if config can't write the saved object to stdout
+// (with "-output -"), since only the JSON document can be written to stdout.
+func checkStdoutOutput(config appConfig) {
+	var conflict string
 
+	if info, err := os.Stat(config.target); err == nil && info.IsDir() {
+		fmt.Fprint(os.Stderr, "\"-output -\" cannot be used with a folder\n\n")
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	switch {
+	case len(config.templateMode) > 0:
+		conflict = "-template"
+	case config.split:
+		conflict = "-split"
+	case len(config.dumpDecks) > 0:
+		conflict = "-dump-decks"
+	case len(config.tablePreset) > 0:
+		conflict = "-table-preset"
+	case config.event:
+		conflict = "-event"
+	case len(config.exporters) > 1 || config.exporters[0] != export.DefaultExporter:
+		conflict = "-export"
+	default:
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "\"-output -\" and \"%s\" cannot be used at the same time\n\n", conflict)
+	flag.Usage()
+	os.Exit(1)
+}
+
+func initLogger(debug bool) *zap.Logger {
 	var zapConf zap.Config
 
-	if config.debug {
+	if debug {
 		zapConf = zap.NewDevelopmentConfig()
 		zapConf.EncoderConfig.EncodeTime = zapcore.RFC3339TimeEncoder
 	} else {
@@ -303,6 +1084,23 @@ func main() {
 		fmt.Fprint(os.Stderr, err.Error())
 		os.Exit(1)
 	}
+
+	log.SetLogger(logger.Sugar())
+
+	return logger
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		if subcommand, found := subcommands[os.Args[1]]; found {
+			subcommand.run(os.Args[2:])
+			return
+		}
+	}
+
+	config := parseFlags()
+
+	logger := initLogger(config.debug)
 	defer func() {
 		// Don't check for errors since logger.Sync() can sometimes fail
 		// even if the logs were properly displayed
@@ -310,7 +1108,21 @@ func main() {
 		_ = logger.Sync()
 	}()
 
-	log.SetLogger(logger.Sugar())
+	registerCredentials(config)
+	plugins.SetMetrics(summary)
+
+	if !config.noCache {
+		plugins.SetHTTPCache(defaultCacheDir())
+	}
+
+	err := registerRateLimits(config)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := config.config.RegisterAliases(); err != nil {
+		log.Fatal(err)
+	}
 
 	if len(config.outputFolder) > 0 {
 		err = checkCreateDir(config.outputFolder)
@@ -336,14 +1148,33 @@ func main() {
 		}
 	}
 
-	log.Infof("Generated files will go in %s", config.outputFolder)
+	if config.stdout {
+		log.Info("The saved object will be written to stdout")
+	} else {
+		log.Infof("Generated files will go in %s", config.outputFolder)
+	}
 
-	if info, err := os.Stat(config.target); err == nil && info.IsDir() {
-		errs := handleFolder(config)
-		checkErrs(errs)
-		os.Exit(0)
+	ctx, cancel := newContext(config.timeout)
+	defer cancel()
+
+	if len(config.backFile) > 0 {
+		config.backURLs[""], err = uploadBackFile(ctx, config.backFile, *config.uploader)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var errs []error
+
+	if config.event {
+		errs = handleEvent(ctx, config)
+	} else if info, err := os.Stat(config.target); err == nil && info.IsDir() {
+		errs = handleFolder(ctx, config)
+	} else {
+		errs = handleTarget(ctx, config)
 	}
 
-	errs := handleTarget(config)
+	// Cancel before checkErrs, since os.Exit doesn't run the deferred calls
+	cancel()
 	checkErrs(errs)
 }
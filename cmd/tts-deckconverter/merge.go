@@ -0,0 +1,122 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+func getAvailableSplitKeys() string {
+	var sb strings.Builder
+
+	for _, name := range plugins.AvailableSplitKeys() {
+		sb.WriteString("\n\t")
+		sb.WriteString(name)
+		sb.WriteString(": ")
+		sb.WriteString(plugins.SplitKeys[name].Description)
+	}
+
+	return sb.String()
+}
+
+func runMerge(args []string) {
+	var (
+		name   string
+		output string
+	)
+
+	flags := flag.NewFlagSet("merge", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s merge [FLAGS] DECKS...\n\nMerge the decks written with \"-dump-decks\" into a single deck.\n\nFlags:\n", filepath.Base(os.Args[0]))
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&name, "name", "", "name of the merged deck (defaults to the name of the first deck)")
+	flags.StringVar(&output, "output", "-", "JSON file the merged deck is written to (\"-\" for stdout), to be converted with \"-load-decks\"")
+
+	// flag.ExitOnError is set, no need to check for errors
+	_ = flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		fmt.Fprint(os.Stderr, "At least one deck file is required\n\n")
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	var decks []*plugins.Deck
+
+	for _, path := range flags.Args() {
+		loaded, err := loadDecks(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Couldn't load %s: %v\n", path, err)
+			os.Exit(1)
+		}
+		decks = append(decks, loaded...)
+	}
+
+	if len(name) == 0 && len(decks) > 0 {
+		name = decks[0].Name
+	}
+
+	merged, err := plugins.MergeDecks(name, decks)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, plugins.CapitalizeString(err.Error()))
+		os.Exit(1)
+	}
+
+	if err := dumpDecks([]*plugins.Deck{merged}, nil, output); err != nil {
+		fmt.Fprintf(os.Stderr, "Couldn't write the merged deck: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func runSplit(args []string) {
+	var (
+		by     string
+		output string
+	)
+
+	flags := flag.NewFlagSet("split", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s split [FLAGS] DECKS\n\nSplit each deck written with \"-dump-decks\" into several decks.\n\nFlags:\n", filepath.Base(os.Args[0]))
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&by, "by", "", "how to split the decks:"+getAvailableSplitKeys())
+	flags.StringVar(&output, "output", "-", "JSON file the split decks are written to (\"-\" for stdout), to be converted with \"-load-decks\"")
+
+	// flag.ExitOnError is set, no need to check for errors
+	_ = flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		fmt.Fprint(os.Stderr, "A deck file is required\n\n")
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	splitKey, found := plugins.SplitKeys[by]
+	if !found {
+		fmt.Fprintf(os.Stderr, "Invalid split: %s\n\n", by)
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	decks, err := loadDecks(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Couldn't load %s: %v\n", flags.Arg(0), err)
+		os.Exit(1)
+	}
+
+	var split []*plugins.Deck
+
+	for _, deck := range decks {
+		split = append(split, plugins.SplitDeck(deck, splitKey.Key)...)
+	}
+
+	if err := dumpDecks(split, nil, output); err != nil {
+		fmt.Fprintf(os.Stderr, "Couldn't write the split decks: %v\n", err)
+		os.Exit(1)
+	}
+}
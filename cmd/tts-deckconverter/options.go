@@ -5,11 +5,17 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	dc "github.com/jeandeaual/tts-deckconverter"
+	"github.com/jeandeaual/tts-deckconverter/config"
+	"github.com/jeandeaual/tts-deckconverter/export"
 	"github.com/jeandeaual/tts-deckconverter/plugins"
+	"github.com/jeandeaual/tts-deckconverter/tts"
 	"github.com/jeandeaual/tts-deckconverter/tts/upload"
 )
 
@@ -40,6 +46,409 @@ func (o *options) Set(value string) error {
 	return nil
 }
 
+// sectionBackPattern matches the deck sections which can be given a card back
+// (e.g. "sideboard" or "hidden agendas"), to distinguish them from the URLs.
+var sectionBackPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z ]*$`)
+
+// sectionBacks maps a deck section (e.g. "sideboard" or "tokens"), in lower
+// case, to a card back. The empty key applies to every deck.
+type sectionBacks map[string]string
+
+func (b *sectionBacks) String() string {
+	backs := make([]string, 0, len(*b))
+
+	for k, v := range *b {
+		if len(k) == 0 {
+			backs = append(backs, v)
+		} else {
+			backs = append(backs, k+"="+v)
+		}
+	}
+
+	return strings.Join(backs, ",")
+}
+
+func (b *sectionBacks) Set(value string) error {
+	section, back := sectionSplit(value)
+
+	if len(back) == 0 {
+		return errors.New("invalid card back: " + value)
+	}
+
+	(*b)[section] = back
+
+	return nil
+}
+
+// apply sets the card back URL of the decks. The back of the section of a
+// deck (e.g. " - Sideboard") overrides the back applying to every deck.
+func (b sectionBacks) apply(decks []*plugins.Deck) {
+	for _, deck := range decks {
+		if backURL, found := b[""]; found {
+			deck.BackURL = backURL
+		}
+
+		name := strings.ToLower(deck.Name)
+		for section, backURL := range b {
+			if len(section) > 0 && strings.HasSuffix(name, " - "+section) {
+				deck.BackURL = backURL
+			}
+		}
+	}
+}
+
+type hostValue struct {
+	host  string
+	key   string
+	value string
+}
+
+// hostValues are flag values using the "HOST=KEY<separator>VALUE" format
+// (e.g. "moxfield.com=Authorization: Bearer token").
+type hostValues struct {
+	separator string
+	values    []hostValue
+}
+
+func (h *hostValues) String() string {
+	if h == nil {
+		return ""
+	}
+
+	values := make([]string, 0, len(h.values))
+
+	for _, v := range h.values {
+		values = append(values, v.host+"="+v.key+h.separator+v.value)
+	}
+
+	return strings.Join(values, ",")
+}
+
+func (h *hostValues) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || len(parts[0]) == 0 {
+		return errors.New("invalid value (expected HOST=KEY" + h.separator + "VALUE): " + value)
+	}
+
+	kv := strings.SplitN(parts[1], h.separator, 2)
+	if len(kv) != 2 || len(strings.TrimSpace(kv[0])) == 0 {
+		return errors.New("invalid value (expected HOST=KEY" + h.separator + "VALUE): " + value)
+	}
+
+	h.values = append(h.values, hostValue{
+		host:  parts[0],
+		key:   strings.TrimSpace(kv[0]),
+		value: strings.TrimSpace(kv[1]),
+	})
+
+	return nil
+}
+
+// hostProxies maps a host name to the URL of the proxy used for its
+// requests.
+type hostProxies map[string]string
+
+func (h *hostProxies) String() string {
+	proxies := make([]string, 0, len(*h))
+
+	for host, proxyURL := range *h {
+		proxies = append(proxies, host+"="+proxyURL)
+	}
+
+	return strings.Join(proxies, ",")
+}
+
+func (h *hostProxies) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || len(parts[0]) == 0 {
+		return errors.New("invalid value (expected HOST=URL): " + value)
+	}
+
+	if _, err := plugins.ParseProxyURL(parts[1]); err != nil {
+		return err
+	}
+
+	(*h)[parts[0]] = parts[1]
+
+	return nil
+}
+
+// sectionSplit splits value into a deck section (in lower case, e.g.
+// "sideboard") and its value, using the "SECTION=VALUE" format. The section
+// is empty if value applies to every deck.
+func sectionSplit(value string) (string, string) {
+	if kv := strings.SplitN(value, "=", 2); len(kv) == 2 && sectionBackPattern.MatchString(kv[0]) {
+		return strings.ToLower(strings.TrimSpace(kv[0])), kv[1]
+	}
+
+	return "", value
+}
+
+// sectionVectors maps a deck section (e.g. "sideboard"), in lower case, to a
+// "X,Y,Z" value, e.g. a position. The empty key applies to every deck.
+type sectionVectors map[string][3]float64
+
+func (v *sectionVectors) String() string {
+	vectors := make([]string, 0, len(*v))
+
+	for k, values := range *v {
+		vector := fmt.Sprintf("%g,%g,%g", values[0], values[1], values[2])
+		if len(k) == 0 {
+			vectors = append(vectors, vector)
+		} else {
+			vectors = append(vectors, k+"="+vector)
+		}
+	}
+
+	return strings.Join(vectors, ";")
+}
+
+func (v *sectionVectors) Set(value string) error {
+	section, vector := sectionSplit(value)
+
+	parts := strings.Split(vector, ",")
+	if len(parts) != 3 {
+		return errors.New("invalid value (expected X,Y,Z): " + value)
+	}
+
+	var values [3]float64
+	for i, part := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return errors.New("invalid value (expected X,Y,Z): " + value)
+		}
+		values[i] = f
+	}
+	(*v)[section] = values
+
+	return nil
+}
+
+// sectionScales maps a deck section (e.g. "tokens"), in lower case, to a
+// scale. The empty key applies to every deck.
+type sectionScales map[string]float64
+
+func (s *sectionScales) String() string {
+	scales := make([]string, 0, len(*s))
+
+	for k, scale := range *s {
+		if len(k) == 0 {
+			scales = append(scales, strconv.FormatFloat(scale, 'g', -1, 64))
+		} else {
+			scales = append(scales, k+"="+strconv.FormatFloat(scale, 'g', -1, 64))
+		}
+	}
+
+	return strings.Join(scales, ",")
+}
+
+func (s *sectionScales) Set(value string) error {
+	section, scale := sectionSplit(value)
+
+	f, err := strconv.ParseFloat(strings.TrimSpace(scale), 64)
+	if err != nil {
+		return errors.New("invalid scale: " + value)
+	}
+	(*s)[section] = f
+
+	return nil
+}
+
+// buildPlacement returns the placement of the generated objects. The values
+// of a deck section override the values applying to every deck.
+func buildPlacement(positions, rotations sectionVectors, scales sectionScales) tts.Placement {
+	placement := tts.Placement{Sections: make(map[string]tts.Placement)}
+
+	for name, position := range positions {
+		position := position
+		if len(name) == 0 {
+			placement.Position = &position
+			continue
+		}
+		sp := placement.Sections[name]
+		sp.Position = &position
+		placement.Sections[name] = sp
+	}
+	for name, rotation := range rotations {
+		rotation := rotation
+		if len(name) == 0 {
+			placement.Rotation = &rotation
+			continue
+		}
+		sp := placement.Sections[name]
+		sp.Rotation = &rotation
+		placement.Sections[name] = sp
+	}
+	for name, scale := range scales {
+		if len(name) == 0 {
+			placement.Scale = scale
+			continue
+		}
+		sp := placement.Sections[name]
+		sp.Scale = scale
+		placement.Sections[name] = sp
+	}
+
+	return placement
+}
+
+// byteSizeRegex matches a size in bytes, with an optional unit (e.g. "500",
+// "800KB", "1.5 GiB").
+var byteSizeRegex = regexp.MustCompile(`^(?i)(\d+(?:\.\d+)?)\s*([kmg]i?)?b?$`)
+
+// byteUnits maps the units supported by byteSize to their number of bytes.
+var byteUnits = map[string]float64{
+	"":   1,
+	"k":  1e3,
+	"m":  1e6,
+	"g":  1e9,
+	"ki": 1 << 10,
+	"mi": 1 << 20,
+	"gi": 1 << 30,
+}
+
+// byteSize is a size in bytes, e.g. a download limit.
+type byteSize int64
+
+func (b *byteSize) String() string {
+	return strconv.FormatInt(int64(*b), 10)
+}
+
+func (b *byteSize) Set(value string) error {
+	matches := byteSizeRegex.FindStringSubmatch(strings.TrimSpace(value))
+	if matches == nil {
+		return errors.New("invalid size (expected a number of bytes, e.g. 800KB or 1.5GB): " + value)
+	}
+
+	size, err := strconv.ParseFloat(matches[1], 64)
+	if err != nil {
+		return errors.New("invalid size (expected a number of bytes, e.g. 800KB or 1.5GB): " + value)
+	}
+	*b = byteSize(size * byteUnits[strings.ToLower(matches[2])])
+
+	return nil
+}
+
+// rateLimits maps a plugin ID to the interval between two API calls.
+// The config.DefaultRateLimitKey key applies to every plugin.
+type rateLimits map[string]time.Duration
+
+func (r *rateLimits) String() string {
+	limits := make([]string, 0, len(*r))
+
+	for k, v := range *r {
+		limits = append(limits, k+"="+v.String())
+	}
+
+	return strings.Join(limits, ",")
+}
+
+func (r *rateLimits) Set(value string) error {
+	pluginID := config.DefaultRateLimitKey
+	interval := value
+
+	if kv := strings.SplitN(value, "=", 2); len(kv) == 2 {
+		pluginID = kv[0]
+		interval = kv[1]
+	}
+
+	duration, err := time.ParseDuration(interval)
+	if err != nil || duration < 0 {
+		return errors.New("invalid rate limit: " + value)
+	}
+
+	(*r)[pluginID] = duration
+
+	return nil
+}
+
+// register changes the rate limits of the plugins. The default rate limit
+// is applied first, so it can be overridden for each plugin.
+func (r rateLimits) register() error {
+	if interval, found := r[config.DefaultRateLimitKey]; found {
+		if err := plugins.SetDefaultRateLimit(interval); err != nil {
+			return err
+		}
+	}
+
+	for pluginID, interval := range r {
+		if pluginID == config.DefaultRateLimitKey {
+			continue
+		}
+		if err := plugins.SetRateLimit(pluginID, interval); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func getAvailableRateLimits() string {
+	var sb strings.Builder
+
+	for _, pluginID := range plugins.RateLimitedPlugins() {
+		sb.WriteString("\n\t")
+		sb.WriteString(pluginID)
+		sb.WriteString(" (default: ")
+		sb.WriteString(plugins.RateLimitInterval(pluginID).String())
+		sb.WriteString(")")
+	}
+
+	return sb.String()
+}
+
+// transforms are the deck transformations set by the user, in order.
+type transforms struct {
+	specs      []string
+	transforms []plugins.Transform
+}
+
+func (t *transforms) String() string {
+	if t == nil {
+		return ""
+	}
+
+	return strings.Join(t.specs, ",")
+}
+
+func (t *transforms) Set(value string) error {
+	transform, err := plugins.NewTransform(value)
+	if err != nil {
+		return err
+	}
+
+	t.specs = append(t.specs, value)
+	t.transforms = append(t.transforms, transform)
+
+	return nil
+}
+
+func getAvailableTransforms() string {
+	var sb strings.Builder
+
+	for _, name := range plugins.AvailableTransforms() {
+		sb.WriteString("\n\t")
+		sb.WriteString(name)
+		sb.WriteString(": ")
+		sb.WriteString(plugins.Transforms[name].Description)
+	}
+
+	return sb.String()
+}
+
+func getAvailableExporters() string {
+	var sb strings.Builder
+
+	for _, id := range export.AvailableExporters() {
+		sb.WriteString("\n\t")
+		sb.WriteString(id)
+		sb.WriteString(": ")
+		sb.WriteString(export.Exporters[id].Description)
+	}
+
+	return sb.String()
+}
+
 func getAvailableOptions(pluginNames []string) string {
 	var sb strings.Builder
 
@@ -175,6 +584,45 @@ func getAvailableBacks(pluginNames []string) string {
 	return sb.String()
 }
 
+func getAvailableTablePresets(pluginNames []string) string {
+	var sb strings.Builder
+
+	for _, pluginName := range pluginNames {
+		plugin, found := dc.Plugins[pluginName]
+		if !found {
+			fmt.Fprintf(os.Stderr, "Invalid mode: %s\n", pluginName)
+			flag.Usage()
+			os.Exit(1)
+		}
+
+		provider, ok := plugin.(plugins.TablePresetProvider)
+		if !ok {
+			continue
+		}
+
+		sb.WriteString("\n")
+		sb.WriteString(pluginName)
+		sb.WriteString(":")
+
+		presets := provider.TablePresets()
+
+		names := make([]string, 0, len(presets))
+		for name := range presets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			sb.WriteString("\n\t")
+			sb.WriteString(name)
+			sb.WriteString(": ")
+			sb.WriteString(presets[name].Description)
+		}
+	}
+
+	return sb.String()
+}
+
 func getAvailableUploaders() string {
 	var sb strings.Builder
 
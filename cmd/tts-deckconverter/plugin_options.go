@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	dc "github.com/jeandeaual/tts-deckconverter"
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+func pluginsUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s plugins options [FLAGS] [MODE...]\n", filepath.Base(os.Args[0]))
+}
+
+func runPlugins(args []string) {
+	if len(args) == 0 || args[0] != "options" {
+		pluginsUsage()
+		os.Exit(1)
+	}
+
+	runPluginOptions(args[1:])
+}
+
+func runPluginOptions(args []string) {
+	var (
+		jsonOutput bool
+		configPath string
+	)
+
+	flags := flag.NewFlagSet("plugins options", flag.ExitOnError)
+	flags.Usage = func() {
+		pluginsUsage()
+		fmt.Fprint(flags.Output(), "\nFlags:\n")
+		flags.PrintDefaults()
+	}
+	flags.BoolVar(&jsonOutput, "json", false, "display the options of each mode as a JSON Schema")
+	flags.StringVar(&configPath, "config", defaultConfigPath(), "path of the configuration file")
+
+	// flag.ExitOnError is set, no need to check for errors
+	_ = flags.Parse(args)
+
+	config, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n\n", plugins.CapitalizeString(err.Error()))
+		os.Exit(1)
+	}
+	loadExternalPlugins(config)
+
+	modes := flags.Args()
+	if len(modes) == 0 {
+		modes = dc.AvailablePlugins()
+	}
+
+	for _, mode := range modes {
+		if _, found := dc.Plugins[mode]; !found {
+			fmt.Fprintf(os.Stderr, "Invalid mode: %s\n\n", mode)
+			flags.Usage()
+			os.Exit(1)
+		}
+	}
+
+	if !jsonOutput {
+		// Remove the leading newline
+		fmt.Println(getAvailableOptions(modes)[1:])
+		return
+	}
+
+	schemas := make(map[string]*plugins.JSONSchema, len(modes))
+	for _, mode := range modes {
+		schemas[mode] = plugins.OptionsSchema(dc.Plugins[mode])
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+
+	if err := encoder.Encode(schemas); err != nil {
+		fmt.Fprintln(os.Stderr, plugins.CapitalizeString(err.Error()))
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jeandeaual/tts-deckconverter/log"
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+	"github.com/jeandeaual/tts-deckconverter/server"
+	"github.com/jeandeaual/tts-deckconverter/tts"
+)
+
+func runServe(args []string) {
+	var (
+		addr       string
+		workDir    string
+		workers    int
+		jobTimeout time.Duration
+		configPath string
+		debug      bool
+		chest      string
+		origins    string
+		retention  time.Duration
+		queueSize  int
+		stopDelay  time.Duration
+	)
+
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s serve [FLAGS]\n\nRun an HTTP server converting the deck URLs submitted to POST /jobs.\n\nFlags:\n", filepath.Base(os.Args[0]))
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&addr, "addr", "localhost:8080", "address the server listens on")
+	flags.StringVar(&workDir, "work-dir", filepath.Join(os.TempDir(), "tts-deckconverter"), "folder where the files of each job are generated")
+	flags.IntVar(&workers, "workers", server.DefaultWorkers, "number of jobs run concurrently")
+	flags.IntVar(&queueSize, "queue-size", server.DefaultQueueSize, "maximum number of jobs waiting for a worker")
+	flags.DurationVar(&jobTimeout, "job-timeout", 0, "stop the jobs taking longer than this duration (e.g. \"5m\") (no timeout by default)")
+	flags.DurationVar(&retention, "retention", time.Hour, "remove the finished jobs and their files after this duration (\"0\" to keep them)")
+	flags.DurationVar(&stopDelay, "shutdown-timeout", time.Minute, "when stopping the server, maximum duration to wait for the queued and running jobs to finish")
+	flags.StringVar(&chest, "chest", "", "also save the generated files to this folder of the Tabletop Simulator chest (use \"/\" for the root folder), so that they appear in the saved objects")
+	flags.StringVar(&origins, "allow-origin", "", "comma-separated list of origins allowed to call the server from a browser, e.g. a companion extension (\"chrome-extension://<extension ID>\") (\"*\" to allow every origin)")
+	flags.StringVar(&configPath, "config", defaultConfigPath(), "path of the configuration file")
+	flags.BoolVar(&debug, "debug", false, "enable debug logging")
+
+	// flag.ExitOnError is set, no need to check for errors
+	_ = flags.Parse(args)
+
+	if flags.NArg() > 0 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	logger := initLogger(debug)
+	defer func() {
+		_ = logger.Sync()
+	}()
+
+	config, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n\n", plugins.CapitalizeString(err.Error()))
+		os.Exit(1)
+	}
+
+	loadExternalPlugins(config)
+	config.RegisterSites()
+	if err := config.RegisterRateLimits(); err != nil {
+		log.Fatal(err)
+	}
+	if err := config.RegisterUploaders(); err != nil {
+		log.Fatal(err)
+	}
+	if err := config.RegisterAliases(); err != nil {
+		log.Fatal(err)
+	}
+
+	options := server.Options{
+		WorkDir:    workDir,
+		Workers:    workers,
+		QueueSize:  queueSize,
+		JobTimeout: jobTimeout,
+		Retention:  retention,
+	}
+
+	if len(chest) > 0 {
+		chestPath, err := tts.FindChestPath()
+		if err != nil {
+			log.Fatal(err)
+		}
+		options.OutputDir = filepath.Join(chestPath, chest)
+		if err := checkCreateDir(options.OutputDir); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	for _, origin := range strings.Split(origins, ",") {
+		if origin = strings.TrimSpace(origin); len(origin) > 0 {
+			options.AllowedOrigins = append(options.AllowedOrigins, origin)
+		}
+	}
+
+	s, err := server.New(options)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer s.Close()
+
+	httpServer := &http.Server{
+		Addr:    addr,
+		Handler: s.Handler(),
+	}
+
+	stop := make(chan os.Signal, 1)
+	// SIGTERM is sent when stopping a Docker container
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		<-stop
+		// Restore the default behavior, so that a second interrupt exits
+		signal.Stop(stop)
+
+		log.Warn("Stopping, waiting for the jobs to finish (interrupt again to exit immediately)")
+
+		ctx, cancel := context.WithTimeout(context.Background(), stopDelay)
+		defer cancel()
+
+		// Stop accepting jobs first, while still answering the status
+		// requests of the running ones
+		if err := s.Shutdown(ctx); err != nil {
+			log.Error(err)
+		}
+		if err := httpServer.Shutdown(ctx); err != nil {
+			log.Error(err)
+		}
+	}()
+
+	log.Infof("Listening on %s, generated files will go in %s", addr, workDir)
+
+	if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
+
+	<-stopped
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	dc "github.com/jeandeaual/tts-deckconverter"
+	"github.com/jeandeaual/tts-deckconverter/log"
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+func printStats(w io.Writer, stats plugins.DeckStats) {
+	fmt.Fprintf(w, "%s: %d card(s), %d unique\n", stats.Name, stats.CardCount, stats.UniqueCount)
+
+	// Don't display the curve if no card has a cost
+	if _, onlyZero := stats.Curve[0]; len(stats.Curve) > 1 || (len(stats.Curve) == 1 && !onlyZero) {
+		fmt.Fprintln(w, "  Curve:")
+		for _, cost := range stats.CurveCosts() {
+			count := stats.Curve[cost]
+			fmt.Fprintf(w, "    %2d: %3d %s\n", cost, count, strings.Repeat("█", count))
+		}
+	}
+
+	if len(stats.Colors) > 0 {
+		fmt.Fprintln(w, "  Colors:")
+		for _, color := range stats.SortedColors() {
+			fmt.Fprintf(w, "    %s: %d\n", color, stats.Colors[color])
+		}
+		if stats.Colorless > 0 {
+			fmt.Fprintf(w, "    Colorless: %d\n", stats.Colorless)
+		}
+	}
+
+	fmt.Fprintf(w, "  Price: $%.2f", stats.Price)
+	if stats.MissingPrices > 0 {
+		fmt.Fprintf(w, " (no price found for %d card(s))", stats.MissingPrices)
+	}
+	fmt.Fprintln(w)
+}
+
+func runStats(args []string) {
+	var (
+		mode       string
+		debug      bool
+		configPath string
+		options    = make(options)
+	)
+
+	availableModes := dc.AvailablePlugins()
+
+	flags := flag.NewFlagSet("stats", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s stats [FLAGS] TARGET\n\nFlags:\n", filepath.Base(os.Args[0]))
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&mode, "mode", "", "available modes: "+strings.Join(availableModes, ", "))
+	flags.Var(&options, "option", "plugin specific option (can have multiple)"+getAvailableOptions(availableModes))
+	flags.BoolVar(&debug, "debug", false, "enable debug logging")
+	flags.StringVar(&configPath, "config", defaultConfigPath(), "path of the configuration file")
+
+	// flag.ExitOnError is set, no need to check for errors
+	_ = flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		fmt.Fprint(os.Stderr, "A target is required\n\n")
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	config, err := loadConfig(configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n\n", plugins.CapitalizeString(err.Error()))
+		os.Exit(1)
+	}
+	loadExternalPlugins(config)
+
+	if _, found := dc.Plugins[mode]; len(mode) > 0 && !found {
+		fmt.Fprintf(os.Stderr, "Invalid mode: %s\n\n", mode)
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	config.RegisterSites()
+	err = config.RegisterRateLimits()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n\n", plugins.CapitalizeString(err.Error()))
+		os.Exit(1)
+	}
+	if err := config.RegisterAliases(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s\n\n", plugins.CapitalizeString(err.Error()))
+		os.Exit(1)
+	}
+
+	logger := initLogger(debug)
+	defer func() {
+		_ = logger.Sync()
+	}()
+
+	ctx, cancel := newContext(0)
+	defer cancel()
+
+	decks, err := dc.Parse(ctx, flags.Arg(0), mode, options)
+	if err != nil {
+		log.Fatalf("Couldn't parse target: %v", err)
+	}
+
+	total := plugins.DeckStats{}
+
+	for _, deck := range decks {
+		stats := plugins.ComputeStats(deck)
+		printStats(os.Stdout, stats)
+
+		total.CardCount += stats.CardCount
+		total.Price += stats.Price
+		total.MissingPrices += stats.MissingPrices
+	}
+
+	if len(decks) > 1 {
+		fmt.Printf("Total: %d card(s), $%.2f\n", total.CardCount, total.Price)
+	}
+}
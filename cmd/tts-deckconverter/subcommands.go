@@ -0,0 +1,65 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+type subcommand struct {
+	// description of the subcommand, displayed in the usage
+	description string
+	// run the subcommand with the remaining command-line arguments
+	run func(args []string)
+}
+
+// subcommands is the list of commands which can be used instead of
+// converting a target directly.
+var subcommands map[string]subcommand
+
+func init() {
+	subcommands = map[string]subcommand{
+		"cache": {
+			description: "display the size of the caches, or remove their files (\"cache info|clear|prune [FLAGS] [CACHE...]\")",
+			run:         runCache,
+		},
+		"merge": {
+			description: "merge decks written with \"-dump-decks\" into a single deck",
+			run:         runMerge,
+		},
+		"split": {
+			description: "split decks written with \"-dump-decks\" by color, type or cost",
+			run:         runSplit,
+		},
+		"plugins": {
+			description: "display the options of each mode (\"plugins options [-json] [MODE...]\")",
+			run:         runPlugins,
+		},
+		"serve": {
+			description: "run an HTTP server converting decks asynchronously",
+			run:         runServe,
+		},
+		"stats": {
+			description: "display statistics about a deck without generating any file",
+			run:         runStats,
+		},
+	}
+}
+
+func getAvailableSubcommands() string {
+	var sb strings.Builder
+
+	names := make([]string, 0, len(subcommands))
+	for name := range subcommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		sb.WriteString("\n\t")
+		sb.WriteString(name)
+		sb.WriteString(": ")
+		sb.WriteString(subcommands[name].description)
+	}
+
+	return sb.String()
+}
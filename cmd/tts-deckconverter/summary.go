@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+// conversionSummary counts what happened during the conversion of a target,
+// to display it once the conversion is done.
+// It implements the plugins.Metrics and plugins.ProgressReporter interfaces.
+type conversionSummary struct {
+	mutex       sync.Mutex
+	start       time.Time
+	budget      *plugins.RetryBudget
+	resolved    int
+	apiCalls    int
+	failed      int
+	cacheHits   int
+	cacheMisses int
+	files       int
+}
+
+// summary is registered with plugins.SetMetrics, since the targets are
+// converted one at a time.
+var summary = &conversionSummary{}
+
+// reset starts the summary of a new conversion, whose retries are limited by
+// budget.
+func (s *conversionSummary) reset(budget *plugins.RetryBudget) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.start = time.Now()
+	s.budget = budget
+	s.resolved = 0
+	s.apiCalls = 0
+	s.failed = 0
+	s.cacheHits = 0
+	s.cacheMisses = 0
+	s.files = 0
+}
+
+// APICall implements plugins.Metrics.
+func (s *conversionSummary) APICall(_ string, _ time.Duration, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.apiCalls++
+	if err != nil {
+		s.failed++
+	}
+}
+
+// CacheLookup implements plugins.Metrics.
+func (s *conversionSummary) CacheLookup(_ string, hit bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if hit {
+		s.cacheHits++
+	} else {
+		s.cacheMisses++
+	}
+}
+
+// Downloaded implements plugins.Metrics.
+func (s *conversionSummary) Downloaded(int64) {}
+
+// Operation implements plugins.Metrics.
+func (s *conversionSummary) Operation(string, time.Duration, error) {}
+
+// CardResolved implements plugins.ProgressReporter.
+func (s *conversionSummary) CardResolved(string, string, int, int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.resolved++
+}
+
+// ImageDownloaded implements plugins.ProgressReporter.
+func (s *conversionSummary) ImageDownloaded(string, int64) {}
+
+// FileWritten implements plugins.ProgressReporter.
+func (s *conversionSummary) FileWritten(string, int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.files++
+}
+
+// String returns the summary displayed at the end of a conversion.
+func (s *conversionSummary) String() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	retried := 0
+	if s.budget != nil {
+		retried = s.budget.Retried()
+	}
+
+	parts := []string{
+		fmt.Sprintf("%d card(s) resolved", s.resolved),
+		fmt.Sprintf("%d API call(s) (%d failed, %d retried)", s.apiCalls, s.failed, retried),
+		fmt.Sprintf("%d cache hit(s) and %d miss(es)", s.cacheHits, s.cacheMisses),
+		fmt.Sprintf("%d file(s) written", s.files),
+	}
+
+	return strings.Join(parts, ", ") + " in " + time.Since(s.start).Round(100*time.Millisecond).String()
+}
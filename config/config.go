@@ -0,0 +1,244 @@
+// Package config handles the configuration file shared by the
+// tts-deckconverter applications.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+	"github.com/jeandeaual/tts-deckconverter/tts/upload"
+)
+
+const (
+	appFolder = "tts-deckconverter"
+	fileName  = "config.json"
+	// DefaultRateLimitKey is the key of the rate limit applied to every
+	// plugin.
+	DefaultRateLimitKey = "default"
+)
+
+// Back is a card back defined by the user.
+type Back struct {
+	// URL of the card back.
+	URL string `json:"url"`
+	// Description of the card back.
+	Description string `json:"description"`
+}
+
+// Site contains the credentials used when querying a website, e.g. to
+// access private decks.
+type Site struct {
+	// Headers sent with each request to the website.
+	Headers map[string]string `json:"headers"`
+	// Cookies sent with each request to the website.
+	Cookies map[string]string `json:"cookies"`
+	// Proxy is the URL of the proxy used for the requests to the website
+	// (e.g. "socks5://localhost:1080"), if it can't be accessed directly.
+	Proxy string `json:"proxy"`
+}
+
+// Uploader is a template uploader defined by the user, running an external
+// command (e.g. a script copying the templates to a web server).
+type Uploader struct {
+	// Command to run, with its arguments. "{path}" and "{name}" are replaced
+	// by the path and the name of the template. The command needs to print
+	// the URL of the uploaded template on the last line of its output.
+	Command []string `json:"command"`
+	// Description of the uploader.
+	Description string `json:"description"`
+}
+
+// Duration is a time.Duration read from a string such as "100ms" or "1.5s".
+type Duration time.Duration
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("invalid duration %s: %w", string(data), err)
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	if duration < 0 {
+		return fmt.Errorf("invalid duration %s", value)
+	}
+
+	*d = Duration(duration)
+
+	return nil
+}
+
+// Config is the content of the configuration file.
+type Config struct {
+	// Backs are the custom card backs, available for every plugin.
+	Backs map[string]Back `json:"backs"`
+	// Sites maps a host name (e.g. "moxfield.com") to the credentials used
+	// for this host and its subdomains.
+	Sites map[string]Site `json:"sites"`
+	// UserAgent replaces the User-Agent header sent with each request
+	// (plugins.DefaultUserAgent if empty). It can be overridden for a site
+	// with its headers.
+	UserAgent string `json:"userAgent"`
+	// RateLimits maps a plugin ID (e.g. "mtg") to the minimum interval
+	// between two calls to its API. The "default" key applies to every
+	// plugin.
+	RateLimits map[string]Duration `json:"rateLimits"`
+	// Plugins are the paths of the external plugins to load.
+	Plugins []string `json:"plugins"`
+	// Uploaders are the template uploaders running an external command,
+	// usable with "-template" like the built-in ones.
+	Uploaders map[string]Uploader `json:"uploaders"`
+	// Aliases maps the card names which can't be resolved (e.g. misspellings)
+	// to the names used by the APIs.
+	Aliases map[string]string `json:"aliases"`
+	// Ignore are the regular expressions matching the names of the deck list
+	// lines to skip, such as the non-card lines added by some websites.
+	Ignore []string `json:"ignore"`
+}
+
+// DefaultPath returns the location of the configuration file
+// (e.g. ~/.config/tts-deckconverter/config.json on Linux).
+func DefaultPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, appFolder, fileName), nil
+}
+
+// Load reads the configuration file located at path.
+// If the file doesn't exist, an empty configuration is returned.
+func Load(path string) (*Config, error) {
+	config := &Config{
+		Backs:      make(map[string]Back),
+		Sites:      make(map[string]Site),
+		RateLimits: make(map[string]Duration),
+		Uploaders:  make(map[string]Uploader),
+		Aliases:    make(map[string]string),
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("couldn't read configuration file %s: %w", path, err)
+	}
+
+	err = json.Unmarshal(data, config)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't parse configuration file %s: %w", path, err)
+	}
+
+	for name, back := range config.Backs {
+		if len(back.URL) == 0 {
+			return nil, fmt.Errorf("no URL set for card back %s in %s", name, path)
+		}
+	}
+
+	for host, site := range config.Sites {
+		if len(site.Proxy) == 0 {
+			continue
+		}
+		if _, err := plugins.ParseProxyURL(site.Proxy); err != nil {
+			return nil, fmt.Errorf("%w for site %s in %s", err, host, path)
+		}
+	}
+
+	for id, uploader := range config.Uploaders {
+		if len(uploader.Command) == 0 {
+			return nil, fmt.Errorf("no command set for uploader %s in %s", id, path)
+		}
+	}
+
+	return config, nil
+}
+
+// Back returns the custom card back registered under name.
+func (c *Config) Back(name string) (plugins.Back, bool) {
+	back, found := c.Backs[name]
+	if !found {
+		return plugins.Back{}, false
+	}
+
+	return plugins.Back{
+		URL:         back.URL,
+		Description: back.Description,
+	}, true
+}
+
+// RegisterSites registers the User-Agent, and the headers, cookies and proxy
+// of each site with the HTTP client used by the plugins.
+func (c *Config) RegisterSites() {
+	if len(c.UserAgent) > 0 {
+		plugins.SetUserAgent(c.UserAgent)
+	}
+
+	for host, site := range c.Sites {
+		if len(site.Proxy) > 0 {
+			// The proxy URLs have been checked by Load
+			_ = plugins.SetHostProxy(host, site.Proxy)
+		}
+		for key, value := range site.Headers {
+			plugins.AddHostHeader(host, key, value)
+		}
+		for name, value := range site.Cookies {
+			plugins.AddHostCookie(host, name, value)
+		}
+	}
+}
+
+// RegisterRateLimits changes the rate limits of the plugins. The default
+// rate limit is applied first, so it can be overridden for each plugin.
+func (c *Config) RegisterRateLimits() error {
+	if interval, found := c.RateLimits[DefaultRateLimitKey]; found {
+		if err := plugins.SetDefaultRateLimit(time.Duration(interval)); err != nil {
+			return err
+		}
+	}
+
+	for pluginID, interval := range c.RateLimits {
+		if pluginID == DefaultRateLimitKey {
+			continue
+		}
+		if err := plugins.SetRateLimit(pluginID, time.Duration(interval)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RegisterAliases registers the card aliases and the ignored card names
+// with the plugins.
+func (c *Config) RegisterAliases() error {
+	plugins.SetCardAliases(c.Aliases)
+	return plugins.SetIgnoredCards(c.Ignore)
+}
+
+// RegisterUploaders adds the uploaders of the configuration file to the
+// template uploaders.
+func (c *Config) RegisterUploaders() error {
+	for id, uploader := range c.Uploaders {
+		err := upload.RegisterTemplateUploader(upload.CommandUploader{
+			ID:          id,
+			Name:        id,
+			Description: uploader.Description,
+			Command:     uploader.Command,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+	"github.com/jeandeaual/tts-deckconverter/tts/upload"
+)
+
+func writeConfig(t *testing.T, contents string) string {
+	tmpFile, err := ioutil.TempFile(os.TempDir(), "config-")
+	assert.Nil(t, err)
+
+	_, err = tmpFile.WriteString(contents)
+	assert.Nil(t, err)
+	assert.Nil(t, tmpFile.Close())
+
+	return tmpFile.Name()
+}
+
+func TestLoadMissing(t *testing.T) {
+	config, err := Load(filepath.Join(os.TempDir(), "missing", fileName))
+	assert.Nil(t, err)
+	assert.Empty(t, config.Backs)
+}
+
+func TestLoadBacks(t *testing.T) {
+	path := writeConfig(t, `{
+	"backs": {
+		"playgroup": {
+			"url": "https://example.com/back.png",
+			"description": "our playgroup back"
+		}
+	}
+}`)
+	defer os.Remove(path)
+
+	config, err := Load(path)
+	assert.Nil(t, err)
+
+	back, found := config.Back("playgroup")
+	assert.True(t, found)
+	assert.Equal(t, "https://example.com/back.png", back.URL)
+	assert.Equal(t, "our playgroup back", back.Description)
+
+	_, found = config.Back("default")
+	assert.False(t, found)
+}
+
+func TestLoadSites(t *testing.T) {
+	path := writeConfig(t, `{
+	"sites": {
+		"moxfield.com": {
+			"headers": {"Authorization": "Bearer token"},
+			"cookies": {"session": "abc"},
+			"proxy": "socks5://localhost:1080"
+		}
+	}
+}`)
+	defer os.Remove(path)
+
+	config, err := Load(path)
+	assert.Nil(t, err)
+	assert.Empty(t, config.Backs)
+
+	site, found := config.Sites["moxfield.com"]
+	assert.True(t, found)
+	assert.Equal(t, map[string]string{"Authorization": "Bearer token"}, site.Headers)
+	assert.Equal(t, map[string]string{"session": "abc"}, site.Cookies)
+	assert.Equal(t, "socks5://localhost:1080", site.Proxy)
+}
+
+func TestLoadUserAgent(t *testing.T) {
+	path := writeConfig(t, `{"userAgent": "my-client/1.0"}`)
+	defer os.Remove(path)
+
+	config, err := Load(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "my-client/1.0", config.UserAgent)
+}
+
+func TestLoadRateLimits(t *testing.T) {
+	path := writeConfig(t, `{
+	"rateLimits": {
+		"default": "200ms",
+		"config-test": "1.5s"
+	}
+}`)
+	defer os.Remove(path)
+
+	config, err := Load(path)
+	assert.Nil(t, err)
+	assert.Equal(t, Duration(200*time.Millisecond), config.RateLimits[DefaultRateLimitKey])
+	assert.Equal(t, Duration(1500*time.Millisecond), config.RateLimits["config-test"])
+
+	plugins.NewRateLimiter("config-test", time.Second)
+	assert.Nil(t, config.RegisterRateLimits())
+	assert.Equal(t, 1500*time.Millisecond, plugins.RateLimitInterval("config-test"))
+}
+
+func TestLoadUploaders(t *testing.T) {
+	path := writeConfig(t, `{
+	"uploaders": {
+		"config-test": {
+			"command": ["web-upload", "{path}"],
+			"description": "Upload the template(s) to example.com."
+		}
+	}
+}`)
+	defer os.Remove(path)
+
+	config, err := Load(path)
+	assert.Nil(t, err)
+	assert.Nil(t, config.RegisterUploaders())
+	defer delete(upload.TemplateUploaders, "config-test")
+
+	uploader, found := upload.TemplateUploaders["config-test"]
+	assert.True(t, found)
+	assert.Equal(t, "Upload the template(s) to example.com.", (*uploader).UploaderDescription())
+}
+
+func TestLoadAliases(t *testing.T) {
+	path := writeConfig(t, `{
+	"aliases": {"Lim-Dul's Vault": "Lim-Dûl's Vault"},
+	"ignore": ["deck box"]
+}`)
+	defer os.Remove(path)
+
+	config, err := Load(path)
+	assert.Nil(t, err)
+	assert.Nil(t, config.RegisterAliases())
+	defer func() {
+		plugins.SetCardAliases(nil)
+		assert.Nil(t, plugins.SetIgnoredCards(nil))
+	}()
+
+	name, keep := plugins.ResolveCardName("Lim-Dul's Vault")
+	assert.True(t, keep)
+	assert.Equal(t, "Lim-Dûl's Vault", name)
+
+	_, keep = plugins.ResolveCardName("Deck Box")
+	assert.False(t, keep)
+
+	config.Ignore = []string{"["}
+	assert.NotNil(t, config.RegisterAliases())
+}
+
+func TestLoadInvalid(t *testing.T) {
+	for _, contents := range []string{`{"backs": {"empty": {}}}`, `{"uploaders": {"empty": {}}}`, `{`, `{"rateLimits": {"mtg": "fast"}}`, `{"rateLimits": {"mtg": "-1s"}}`, `{"sites": {"moxfield.com": {"proxy": "ftp://localhost"}}}`} {
+		path := writeConfig(t, contents)
+
+		_, err := Load(path)
+		assert.NotNil(t, err)
+
+		os.Remove(path)
+	}
+}
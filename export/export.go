@@ -0,0 +1,99 @@
+// Package export writes the parsed decks in the formats supported by
+// tts-deckconverter: Tabletop Simulator saved objects, and the formats of
+// other virtual tabletops.
+package export
+
+import (
+	"context"
+	"sort"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+	"github.com/jeandeaual/tts-deckconverter/tts"
+)
+
+// DefaultExporter is the ID of the exporter used when none is selected.
+const DefaultExporter = "tts"
+
+// Options are the settings shared by the exporters.
+type Options struct {
+	// OutputFolder is the folder where the files are written.
+	OutputFolder string
+	// Indent the generated JSON files.
+	Indent bool
+}
+
+// Exporter writes decks in a given format.
+type Exporter struct {
+	// Description of the exporter, displayed to the user.
+	Description string
+	// RequiresTemplates is true if the exporter uses the template sheets
+	// generated with tts.GenerateTemplates instead of the card images.
+	RequiresTemplates bool
+	// Export writes the decks inside options.OutputFolder.
+	Export func(ctx context.Context, decks []*plugins.Deck, options Options) []error
+}
+
+// Exporters maps an ID to an exporter.
+// New exporters can be added to this map.
+var Exporters = map[string]Exporter{
+	"tts": {
+		Description: "Tabletop Simulator saved objects",
+		Export:      exportTTS,
+	},
+	"ttpg": {
+		Description:       "Tabletop Playground card templates and textures (requires \"-template\")",
+		RequiresTemplates: true,
+		Export:            ExportTTPG,
+	},
+	"sheets": {
+		Description:       "template sheets and a JSON manifest for each deck, without any Tabletop Simulator file (requires \"-template\")",
+		RequiresTemplates: true,
+		Export:            ExportSheets,
+	},
+	"screentop": {
+		Description:       "ZIP archive for screentop.gg, with the card faces cut from the template sheets and the card data as CSV (requires \"-template\")",
+		RequiresTemplates: true,
+		Export:            packageExporter("screentop", true),
+	},
+	"tabletopia": {
+		Description:       "ZIP archive for Tabletopia, with an image for each card cut from the template sheets (requires \"-template\")",
+		RequiresTemplates: true,
+		Export:            packageExporter("tabletopia", false),
+	},
+	"text": {
+		Description: "decklist of every section of the decks (\"4 Lightning Bolt\")",
+		Export:      textExporter(decklistText, ".txt"),
+	},
+	"arena": {
+		Description: "decklist in the Magic: The Gathering Arena format",
+		Export:      textExporter(decklistArena, " - Arena.txt"),
+	},
+	"mtgo": {
+		Description: "decklist in the Magic: The Gathering Online format",
+		Export:      textExporter(decklistMTGO, " - MTGO.txt"),
+	},
+	"untap": {
+		Description: "decklist in the untap.in format",
+		Export:      textExporter(decklistUntap, " - untap.txt"),
+	},
+	"vassal": {
+		Description: "card images and a deck file to load in an existing Vassal module",
+		Export:      ExportVassal,
+	},
+}
+
+// AvailableExporters returns the IDs of the exporters in Exporters, sorted.
+func AvailableExporters() []string {
+	ids := make([]string, 0, len(Exporters))
+
+	for id := range Exporters {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+func exportTTS(ctx context.Context, decks []*plugins.Deck, options Options) []error {
+	return tts.Generate(ctx, decks, "", options.OutputFolder, options.Indent)
+}
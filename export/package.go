@@ -0,0 +1,202 @@
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/disintegration/imaging"
+
+	"github.com/jeandeaual/tts-deckconverter/log"
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+	"github.com/jeandeaual/tts-deckconverter/tts"
+)
+
+const (
+	// packageCardsFolder is the folder of the archives containing the card
+	// faces.
+	packageCardsFolder = "cards"
+	// packageCardsCSV is the card data of the screentop.gg archives.
+	packageCardsCSV = "cards.csv"
+)
+
+// packageExporter returns an exporter writing each deck as a ZIP archive
+// which can be imported in a web tabletop platform. Since these platforms
+// build the decks from one image per card face, the cards are cut from the
+// template sheets (see buildManifest), and the card back is added as
+// "back.<ext>".
+// If cardsCSV is true (screentop.gg), each card face is written once inside
+// the cards folder, with a CSV file listing a row per copy of each card (name,
+// description and image), to import as the card data of the deck. Otherwise
+// (Tabletopia), the face is written once per copy, since a card is created for
+// each uploaded image.
+func packageExporter(platform string, cardsCSV bool) func(ctx context.Context, decks []*plugins.Deck, options Options) []error {
+	return func(ctx context.Context, decks []*plugins.Deck, options Options) []error {
+		errs := []error{}
+
+		for _, deck := range decks {
+			if err := ctx.Err(); err != nil {
+				return append(errs, err)
+			}
+			if len(deck.Cards) == 0 {
+				log.Infof("Deck %s is empty, skipping", deck.Name)
+				continue
+			}
+
+			if err := exportPackage(ctx, deck, platform, cardsCSV, options); err != nil {
+				errs = append(errs, fmt.Errorf("couldn't export deck %s: %w", deck.Name, err))
+			}
+		}
+
+		return errs
+	}
+}
+
+func exportPackage(ctx context.Context, deck *plugins.Deck, platform string, cardsCSV bool, options Options) (err error) {
+	if deck.TemplateInfo == nil {
+		return errors.New("no template generated")
+	}
+
+	manifest, sources, err := buildManifest(deck)
+	if err != nil {
+		return err
+	}
+
+	tmpDir, err := ioutil.TempDir("", "tts-deckconverter-package")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Download the template sheets if they were uploaded
+	sheets := make([]image.Image, 0, len(manifest.Sheets))
+	for _, sheet := range manifest.Sheets {
+		path := filepath.Join(tmpDir, sheet.File)
+		if err := tts.SaveImage(ctx, sources[sheet.File], path); err != nil {
+			return fmt.Errorf("couldn't save template %s: %w", sources[sheet.File], err)
+		}
+		img, err := imaging.Open(path)
+		if err != nil {
+			return fmt.Errorf("couldn't read template %s: %w", sources[sheet.File], err)
+		}
+		sheets = append(sheets, img)
+	}
+
+	filename := filepath.Join(options.OutputFolder, tts.FileName(deck.Name)+" - "+platform+".zip")
+	log.Infof("Generating %s", filename)
+
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if cerr := file.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+		if err != nil {
+			// Don't leave an incomplete archive
+			os.Remove(filename)
+		} else if info, serr := os.Stat(filename); serr == nil {
+			plugins.ReportFileWritten(ctx, filename, info.Size())
+		}
+	}()
+
+	archive := zip.NewWriter(file)
+
+	if len(manifest.Back) > 0 {
+		path := filepath.Join(tmpDir, manifest.Back)
+		if err := tts.SaveImage(ctx, sources[manifest.Back], path); err != nil {
+			return fmt.Errorf("couldn't save the card back: %w", err)
+		}
+		if err := addFileToArchive(archive, manifest.Back, path); err != nil {
+			return err
+		}
+	}
+
+	records := [][]string{{"name", "description", "image"}}
+
+	for i, card := range manifest.Cards {
+		face := cutCard(sheets[card.Sheet], manifest.Sheets[card.Sheet], card.Index)
+		name := fmt.Sprintf("%03d - %s", i+1, tts.FileName(strings.ReplaceAll(card.Name, "\n", " ")))
+
+		if cardsCSV {
+			file := packageCardsFolder + "/" + name + ".jpg"
+			if err := addImageToArchive(archive, file, face); err != nil {
+				return err
+			}
+			for j := 0; j < card.Count; j++ {
+				records = append(records, []string{card.Name, card.Description, file})
+			}
+			continue
+		}
+
+		for j := 1; j <= card.Count; j++ {
+			file := packageCardsFolder + "/" + name
+			if j > 1 {
+				file += " (" + strconv.Itoa(j) + ")"
+			}
+			if err := addImageToArchive(archive, file+".jpg", face); err != nil {
+				return err
+			}
+		}
+	}
+
+	if cardsCSV {
+		writer, err := archive.Create(packageCardsCSV)
+		if err != nil {
+			return err
+		}
+		if err := csv.NewWriter(writer).WriteAll(records); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// cutCard returns the face of the card located at index in the template
+// sheet img, whose cards are laid out following sheet.
+func cutCard(img image.Image, sheet ManifestSheet, index int) image.Image {
+	bounds := img.Bounds()
+	width := bounds.Dx() / sheet.Columns
+	height := bounds.Dy() / sheet.Rows
+	x := bounds.Min.X + (index%sheet.Columns)*width
+	y := bounds.Min.Y + (index/sheet.Columns)*height
+
+	return imaging.Crop(img, image.Rect(x, y, x+width, y+height))
+}
+
+func addImageToArchive(archive *zip.Writer, name string, img image.Image) error {
+	writer, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+
+	return imaging.Encode(writer, img, imaging.JPEG)
+}
+
+func addFileToArchive(archive *zip.Writer, name, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(writer, file)
+
+	return err
+}
@@ -0,0 +1,133 @@
+package export
+
+import (
+	"archive/zip"
+	"context"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/disintegration/imaging"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+func readArchive(t *testing.T, path string) map[string][]byte {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archive.Close()
+
+	files := make(map[string][]byte)
+
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[file.Name] = data
+	}
+
+	return files
+}
+
+func TestExportPackage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "package")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The first sheet contains 2 cards: a red one and a blue one
+	sheet1 := imaging.New(40, 30, color.NRGBA{0xff, 0, 0, 0xff})
+	sheet1 = imaging.Paste(sheet1, imaging.New(20, 30, color.NRGBA{0, 0, 0xff, 0xff}), image.Pt(20, 0))
+	sheet1Path := filepath.Join(dir, "sheet1.png")
+	assert.Nil(t, imaging.Save(sheet1, sheet1Path))
+	sheet2Path := filepath.Join(dir, "sheet2.png")
+	assert.Nil(t, imaging.Save(imaging.New(20, 30, color.NRGBA{0, 0xff, 0, 0xff}), sheet2Path))
+	backPath := filepath.Join(dir, "back.png")
+	assert.Nil(t, ioutil.WriteFile(backPath, []byte("back"), 0o644))
+
+	deck := &plugins.Deck{
+		Name:    "Test",
+		BackURL: backPath,
+		Cards: []plugins.CardInfo{
+			{Name: "Lightning Bolt", Description: "Deals 3 damage", ImageURL: "https://example.com/1.jpg", Count: 2},
+			{Name: "Island", ImageURL: "https://example.com/2.jpg", Count: 1},
+			{Name: "Mountain", ImageURL: "https://example.com/3.jpg", Count: 1},
+		},
+		TemplateInfo: &plugins.TemplateInfo{
+			ImageURLCardIDMap: map[string]int{
+				"https://example.com/1.jpg": 100,
+				"https://example.com/2.jpg": 101,
+				"https://example.com/3.jpg": 200,
+			},
+			Templates: map[int]*plugins.Template{
+				1: {URL: sheet1Path, NumCols: 2, NumRows: 1},
+				2: {URL: sheet2Path, NumCols: 1, NumRows: 1},
+			},
+		},
+	}
+
+	errs := Exporters["screentop"].Export(context.Background(), []*plugins.Deck{deck}, Options{OutputFolder: dir})
+	assert.Empty(t, errs)
+
+	files := readArchive(t, filepath.Join(dir, "Test - screentop.zip"))
+	assert.Equal(t, "back", string(files["back.png"]))
+	assert.Equal(
+		t,
+		"name,description,image\n"+
+			"Lightning Bolt,Deals 3 damage,cards/001 - Lightning Bolt.jpg\n"+
+			"Lightning Bolt,Deals 3 damage,cards/001 - Lightning Bolt.jpg\n"+
+			"Island,,cards/002 - Island.jpg\n"+
+			"Mountain,,cards/003 - Mountain.jpg\n",
+		string(files["cards.csv"]),
+	)
+	assert.Len(t, files, 5)
+
+	// Each card is cut from its sheet
+	for name, expected := range map[string]color.NRGBA{
+		"cards/001 - Lightning Bolt.jpg": {0xff, 0, 0, 0xff},
+		"cards/002 - Island.jpg":         {0, 0, 0xff, 0xff},
+		"cards/003 - Mountain.jpg":       {0, 0xff, 0, 0xff},
+	} {
+		path := filepath.Join(dir, "card.jpg")
+		assert.Nil(t, ioutil.WriteFile(path, files[name], 0o644))
+		card, err := imaging.Open(path)
+		if !assert.Nil(t, err, name) {
+			continue
+		}
+		assert.Equal(t, image.Rect(0, 0, 20, 30), card.Bounds(), name)
+		r, g, b, _ := card.At(10, 15).RGBA()
+		er, eg, eb, _ := expected.RGBA()
+		assert.InDelta(t, er>>8, r>>8, 8, name)
+		assert.InDelta(t, eg>>8, g>>8, 8, name)
+		assert.InDelta(t, eb>>8, b>>8, 8, name)
+	}
+
+	errs = Exporters["tabletopia"].Export(context.Background(), []*plugins.Deck{deck}, Options{OutputFolder: dir})
+	assert.Empty(t, errs)
+
+	// Tabletopia creates a card for each image
+	files = readArchive(t, filepath.Join(dir, "Test - tabletopia.zip"))
+	assert.Contains(t, files, "back.png")
+	assert.Contains(t, files, "cards/001 - Lightning Bolt.jpg")
+	assert.Contains(t, files, "cards/001 - Lightning Bolt (2).jpg")
+	assert.Contains(t, files, "cards/003 - Mountain.jpg")
+	assert.NotContains(t, files, "cards.csv")
+	assert.Len(t, files, 5)
+
+	deck.TemplateInfo.Templates[2].URL = filepath.Join(dir, "missing.png")
+	errs = Exporters["tabletopia"].Export(context.Background(), []*plugins.Deck{deck}, Options{OutputFolder: dir})
+	assert.Len(t, errs, 1)
+}
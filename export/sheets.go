@@ -0,0 +1,225 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jeandeaual/tts-deckconverter/log"
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+	"github.com/jeandeaual/tts-deckconverter/tts"
+)
+
+// ManifestSheet is a template sheet of a deck manifest.
+type ManifestSheet struct {
+	// File is the name of the image, relative to the manifest.
+	File string `json:"file"`
+	// Columns is the number of cards in a row of the image.
+	Columns int `json:"columns"`
+	// Rows is the number of cards in a column of the image.
+	Rows int `json:"rows"`
+}
+
+// ManifestCard is a card of a deck manifest.
+type ManifestCard struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Count       int    `json:"count"`
+	// Sheet is the index of the template sheet containing the card image.
+	Sheet int `json:"sheet"`
+	// Index is the position of the card in the sheet, from left to right
+	// and top to bottom, starting at 0.
+	Index int `json:"index"`
+}
+
+// Manifest describes the template sheets of a deck written by ExportSheets,
+// i.e. how to cut the template sheets and how many copies of each card the
+// deck contains.
+type Manifest struct {
+	Name string `json:"name"`
+	// CardSize is "standard", "small", "mini", "square", "tarot" or
+	// "jumbo".
+	CardSize plugins.CardSize `json:"cardSize"`
+	// Back is the name of the card back image, if any.
+	Back   string          `json:"back,omitempty"`
+	Sheets []ManifestSheet `json:"sheets"`
+	Cards  []ManifestCard  `json:"cards"`
+}
+
+// ExportSheets writes the template sheets of the decks and a manifest for
+// each deck inside options.OutputFolder, for the tools which only need the
+// composite images (see Manifest), with the file names of the sheets relative
+// to options.OutputFolder.
+// The templates need to be generated first with tts.GenerateTemplates. The
+// sheets written by the manual uploader are used as is, the others are
+// downloaded.
+func ExportSheets(ctx context.Context, decks []*plugins.Deck, options Options) []error {
+	errs := []error{}
+	// Related decks can share the same sheet, so only save it once
+	saved := make(map[string]string)
+
+	for _, deck := range decks {
+		if err := ctx.Err(); err != nil {
+			return append(errs, err)
+		}
+		if len(deck.Cards) == 0 {
+			log.Infof("Deck %s is empty, skipping", deck.Name)
+			continue
+		}
+
+		if err := exportSheets(ctx, deck, saved, options); err != nil {
+			errs = append(errs, fmt.Errorf("couldn't export deck %s: %w", deck.Name, err))
+		}
+	}
+
+	return errs
+}
+
+func exportSheets(ctx context.Context, deck *plugins.Deck, saved map[string]string, options Options) error {
+	if deck.TemplateInfo == nil {
+		return errors.New("no template generated")
+	}
+
+	manifest, sources, err := buildManifest(deck)
+	if err != nil {
+		return err
+	}
+
+	deckName := tts.FileName(deck.Name)
+
+	for i := range manifest.Sheets {
+		source := sources[manifest.Sheets[i].File]
+
+		name := deckName + " - Template"
+		if i > 0 {
+			name += " " + strconv.Itoa(i+1)
+		}
+
+		file, err := saveSheetImage(ctx, source, name+".jpg", saved, options)
+		if err != nil {
+			return fmt.Errorf("couldn't save template %s: %w", source, err)
+		}
+		manifest.Sheets[i].File = file
+	}
+
+	if len(manifest.Back) > 0 {
+		source := sources[manifest.Back]
+
+		file, err := saveSheetImage(ctx, source, deckName+" - Back"+imageExt(source), saved, options)
+		if err != nil {
+			return fmt.Errorf("couldn't save the card back: %w", err)
+		}
+		manifest.Back = file
+	}
+
+	var data []byte
+
+	if options.Indent {
+		data, err = json.MarshalIndent(manifest, "", strings.Repeat(" ", 2))
+	} else {
+		data, err = json.Marshal(manifest)
+	}
+	if err != nil {
+		return fmt.Errorf("couldn't marshall data: %w", err)
+	}
+
+	filename := filepath.Join(options.OutputFolder, deckName+" - Manifest.json")
+	log.Infof("Generating %s", filename)
+
+	err = tts.WriteFile(filename, data)
+	if err != nil {
+		return fmt.Errorf("couldn't write file %s: %w", filename, err)
+	}
+
+	plugins.ReportFileWritten(ctx, filename, int64(len(data)))
+
+	return nil
+}
+
+// saveSheetImage saves the image located at source as name inside
+// options.OutputFolder, unless it has already been saved or is already
+// located there, and returns its file name relative to options.OutputFolder.
+func saveSheetImage(ctx context.Context, source, name string, saved map[string]string, options Options) (string, error) {
+	if file, found := saved[source]; found {
+		return file, nil
+	}
+
+	if outputFolder, err := filepath.Abs(options.OutputFolder); err == nil && filepath.Dir(source) == outputFolder {
+		// Generated by the manual uploader
+		saved[source] = filepath.Base(source)
+		return saved[source], nil
+	}
+
+	if err := tts.SaveImage(ctx, source, filepath.Join(options.OutputFolder, name)); err != nil {
+		return "", err
+	}
+	saved[source] = name
+
+	return name, nil
+}
+
+// buildManifest returns the manifest of deck, and the sources of its images
+// mapped by file name.
+func buildManifest(deck *plugins.Deck) (*Manifest, map[string]string, error) {
+	manifest := &Manifest{
+		Name:     deck.Name,
+		CardSize: deck.CardSize,
+		Cards:    []ManifestCard{},
+	}
+	sources := make(map[string]string)
+
+	if len(deck.BackURL) > 0 {
+		manifest.Back = "back" + imageExt(deck.BackURL)
+		sources[manifest.Back] = deck.BackURL
+	}
+
+	// Number the sheets in the order of their template IDs
+	templateIDs := make([]int, 0, len(deck.TemplateInfo.Templates))
+	for templateID := range deck.TemplateInfo.Templates {
+		templateIDs = append(templateIDs, templateID)
+	}
+	sort.Ints(templateIDs)
+
+	sheetIndexes := make(map[int]int)
+	for _, templateID := range templateIDs {
+		template := deck.TemplateInfo.Templates[templateID]
+		sheet := ManifestSheet{
+			File:    "sheet-" + strconv.Itoa(len(manifest.Sheets)+1) + ".jpg",
+			Columns: template.NumCols,
+			Rows:    template.NumRows,
+		}
+
+		sheetIndexes[templateID] = len(manifest.Sheets)
+		manifest.Sheets = append(manifest.Sheets, sheet)
+		sources[sheet.File] = template.URL
+	}
+
+	for _, card := range deck.Cards {
+		cardID, found := deck.TemplateInfo.ImageURLCardIDMap[card.ImageURL]
+		if !found {
+			return nil, nil, fmt.Errorf("card %s not found in the templates", card.Name)
+		}
+
+		_, templateID, err := deck.TemplateInfo.GetAssociatedTemplate(cardID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		manifest.Cards = append(manifest.Cards, ManifestCard{
+			Name:        card.Name,
+			Description: card.Description,
+			Count:       card.Count,
+			Sheet:       sheetIndexes[templateID],
+			// The card IDs start at 100 for the first template sheet, 200
+			// for the second, etc.
+			Index: cardID % 100,
+		})
+	}
+
+	return manifest, sources, nil
+}
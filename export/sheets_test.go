@@ -0,0 +1,83 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+func TestExportSheets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sheets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	outputDir := filepath.Join(dir, "output")
+	assert.Nil(t, os.Mkdir(outputDir, 0o755))
+
+	// Generated by the manual uploader
+	sheet1 := filepath.Join(outputDir, "Test - Template.jpg")
+	// Generated elsewhere
+	sheet2 := filepath.Join(dir, "sheet2.jpg")
+	back := filepath.Join(dir, "back.png")
+	assert.Nil(t, ioutil.WriteFile(sheet1, []byte("sheet1"), 0o644))
+	assert.Nil(t, ioutil.WriteFile(sheet2, []byte("sheet2"), 0o644))
+	assert.Nil(t, ioutil.WriteFile(back, []byte("back"), 0o644))
+
+	deck := &plugins.Deck{
+		Name:    "Test",
+		BackURL: back,
+		Cards: []plugins.CardInfo{
+			{Name: "Lightning Bolt", ImageURL: "https://example.com/1.jpg", Count: 4},
+			{Name: "Mountain", ImageURL: "https://example.com/2.jpg", Count: 16},
+		},
+		TemplateInfo: &plugins.TemplateInfo{
+			ImageURLCardIDMap: map[string]int{
+				"https://example.com/1.jpg": 100,
+				"https://example.com/2.jpg": 200,
+			},
+			Templates: map[int]*plugins.Template{
+				1: {URL: sheet1, NumCols: 10, NumRows: 7},
+				2: {URL: sheet2, NumCols: 2, NumRows: 1},
+			},
+		},
+	}
+
+	errs := Exporters["sheets"].Export(context.Background(), []*plugins.Deck{deck}, Options{OutputFolder: outputDir})
+	assert.Empty(t, errs)
+
+	data, err := ioutil.ReadFile(filepath.Join(outputDir, "Test - Manifest.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var manifest Manifest
+	assert.Nil(t, json.Unmarshal(data, &manifest))
+	assert.Equal(t, "Test - Back.png", manifest.Back)
+	assert.Equal(t, []ManifestSheet{
+		{File: "Test - Template.jpg", Columns: 10, Rows: 7},
+		{File: "Test - Template 2.jpg", Columns: 2, Rows: 1},
+	}, manifest.Sheets)
+	assert.Len(t, manifest.Cards, 2)
+
+	for file, content := range map[string]string{
+		"Test - Template.jpg":   "sheet1",
+		"Test - Template 2.jpg": "sheet2",
+		"Test - Back.png":       "back",
+	} {
+		data, err := ioutil.ReadFile(filepath.Join(outputDir, file))
+		assert.Nil(t, err)
+		assert.Equal(t, content, string(data))
+	}
+
+	_, err = os.Stat(filepath.Join(outputDir, "Test.json"))
+	assert.True(t, os.IsNotExist(err))
+}
@@ -0,0 +1,193 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jeandeaual/tts-deckconverter/log"
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+	"github.com/jeandeaual/tts-deckconverter/tts"
+)
+
+// decklistFormat is the format of a decklist written by the text exporters.
+type decklistFormat int
+
+const (
+	// decklistText lists every section of the decks, with the card names
+	// used by the plugins.
+	decklistText decklistFormat = iota
+	// decklistArena is the format imported by Magic: The Gathering Arena.
+	decklistArena
+	// decklistMTGO is the format imported by Magic: The Gathering Online.
+	decklistMTGO
+	// decklistUntap is the format imported by untap.in.
+	decklistUntap
+)
+
+// sideboardSections are the deck sections which are sideboards.
+var sideboardSections = []string{"Sideboard", "Side"}
+
+// otherSections are the deck sections only listed in the text format.
+var otherSections = []string{"Maybeboard", "Conspiracies", "Hidden Agendas", "Tokens", "Dungeons", "Stickers", "Contraptions", "Attractions", "Commander", "Extra", "G deck"}
+
+// decklistLine is a card in a decklist.
+type decklistLine struct {
+	name  string
+	count int
+}
+
+// decklistSection is a part of a decklist (main deck, sideboard...).
+type decklistSection struct {
+	// title is empty for the main deck
+	title string
+	lines []*decklistLine
+	// indexes maps the line text to its index in lines
+	indexes map[string]int
+}
+
+func (s *decklistSection) add(name string, count int) {
+	if index, found := s.indexes[name]; found {
+		s.lines[index].count += count
+		return
+	}
+
+	s.indexes[name] = len(s.lines)
+	s.lines = append(s.lines, &decklistLine{name: name, count: count})
+}
+
+// textExporter returns an exporter writing the decks parsed from a target as
+// a single decklist file, whose name ends with suffix.
+func textExporter(format decklistFormat, suffix string) func(ctx context.Context, decks []*plugins.Deck, options Options) []error {
+	return func(ctx context.Context, decks []*plugins.Deck, options Options) []error {
+		if len(decks) == 0 {
+			return nil
+		}
+
+		data := formatDecklist(decks, format)
+
+		filename := filepath.Join(options.OutputFolder, tts.FileName(decks[0].Name)+suffix)
+		log.Infof("Generating %s", filename)
+
+		if err := tts.WriteFile(filename, data); err != nil {
+			return []error{fmt.Errorf("couldn't write file %s: %w", filename, err)}
+		}
+
+		plugins.ReportFileWritten(ctx, filename, int64(len(data)))
+
+		return nil
+	}
+}
+
+// deckSection returns the section of deck (e.g. "Sideboard"), or an empty
+// string if it is a main deck.
+func deckSection(deck *plugins.Deck) string {
+	for _, sections := range [][]string{sideboardSections, otherSections} {
+		for _, section := range sections {
+			if plugins.IsDeckSection(deck, section) {
+				return section
+			}
+		}
+	}
+
+	return ""
+}
+
+func isSideboard(section string) bool {
+	for _, sideboard := range sideboardSections {
+		if section == sideboard {
+			return true
+		}
+	}
+
+	return false
+}
+
+// formatDecklist returns the cards of decks as a decklist. The copies of the
+// same card found in several decks of the same section are combined.
+func formatDecklist(decks []*plugins.Deck, format decklistFormat) []byte {
+	var sections []*decklistSection
+	sectionIndexes := make(map[string]int)
+
+	for i, deck := range decks {
+		section := deckSection(deck)
+
+		var title string
+
+		switch format {
+		case decklistText:
+			title = section
+			if len(section) == 0 && i > 0 {
+				// Other main decks, e.g. when a file contains several decks
+				title = deck.Name
+			}
+		case decklistArena, decklistMTGO, decklistUntap:
+			if isSideboard(section) {
+				title = "Sideboard"
+			} else if len(section) > 0 {
+				// Not supported by Arena, MTGO and untap.in
+				continue
+			}
+		}
+
+		index, found := sectionIndexes[title]
+		if !found {
+			index = len(sections)
+			sectionIndexes[title] = index
+			sections = append(sections, &decklistSection{
+				title:   title,
+				indexes: make(map[string]int),
+			})
+		}
+
+		for _, card := range deck.Cards {
+			sections[index].add(decklistCardName(card, format), card.Count)
+		}
+	}
+
+	var buf bytes.Buffer
+
+	for i, section := range sections {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+
+		switch {
+		case format == decklistArena && len(section.title) == 0:
+			buf.WriteString("Deck\n")
+		case format == decklistMTGO:
+			// MTGO only separates the sideboard with an empty line
+		case len(section.title) > 0:
+			buf.WriteString(section.title + "\n")
+		}
+
+		for _, line := range section.lines {
+			buf.WriteString(strconv.Itoa(line.count) + " " + line.name + "\n")
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// decklistCardName returns the name of card as written in a decklist.
+func decklistCardName(card plugins.CardInfo, format decklistFormat) string {
+	name := card.Name
+	if len(card.Metadata.Name) > 0 {
+		name = card.Metadata.Name
+	}
+
+	switch format {
+	case decklistArena:
+		if len(card.Metadata.Set) > 0 && len(card.Metadata.CollectorNumber) > 0 {
+			name += " (" + strings.ToUpper(card.Metadata.Set) + ") " + card.Metadata.CollectorNumber
+		}
+	case decklistMTGO:
+		// Split and double-faced cards are written "Fire/Ice"
+		name = strings.ReplaceAll(name, " // ", "/")
+	}
+
+	return name
+}
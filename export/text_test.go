@@ -0,0 +1,88 @@
+package export
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+func decklistTestDecks() []*plugins.Deck {
+	return []*plugins.Deck{
+		{
+			Name: "Test",
+			Cards: []plugins.CardInfo{
+				{
+					Name:     "Lightning Bolt\nInstant",
+					Count:    4,
+					Metadata: plugins.CardMetadata{Name: "Lightning Bolt", Set: "m10", CollectorNumber: "146"},
+				},
+				{
+					Name:     "Fire // Ice",
+					Count:    2,
+					Metadata: plugins.CardMetadata{Name: "Fire // Ice"},
+				},
+				{
+					Name:     "Lightning Bolt\nInstant",
+					Count:    1,
+					Metadata: plugins.CardMetadata{Name: "Lightning Bolt", Set: "m10", CollectorNumber: "146"},
+				},
+			},
+		},
+		{
+			Name: "Test - Sideboard",
+			Cards: []plugins.CardInfo{
+				{Name: "Pyroblast", Count: 2},
+			},
+		},
+		{
+			Name: "Test - Tokens",
+			Cards: []plugins.CardInfo{
+				{Name: "Goblin", Count: 1},
+			},
+		},
+	}
+}
+
+func TestFormatDecklist(t *testing.T) {
+	assert.Equal(
+		t,
+		"5 Lightning Bolt\n2 Fire // Ice\n\nSideboard\n2 Pyroblast\n\nTokens\n1 Goblin\n",
+		string(formatDecklist(decklistTestDecks(), decklistText)),
+	)
+	assert.Equal(
+		t,
+		"Deck\n5 Lightning Bolt (M10) 146\n2 Fire // Ice\n\nSideboard\n2 Pyroblast\n",
+		string(formatDecklist(decklistTestDecks(), decklistArena)),
+	)
+	assert.Equal(
+		t,
+		"5 Lightning Bolt\n2 Fire/Ice\n\n2 Pyroblast\n",
+		string(formatDecklist(decklistTestDecks(), decklistMTGO)),
+	)
+	assert.Equal(
+		t,
+		"5 Lightning Bolt\n2 Fire // Ice\n\nSideboard\n2 Pyroblast\n",
+		string(formatDecklist(decklistTestDecks(), decklistUntap)),
+	)
+}
+
+func TestTextExporter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "text")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	errs := Exporters["arena"].Export(context.Background(), decklistTestDecks(), Options{OutputFolder: dir})
+	assert.Empty(t, errs)
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "Test - Arena.txt"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), "Deck\n")
+}
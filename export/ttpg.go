@@ -0,0 +1,302 @@
+package export
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jeandeaual/tts-deckconverter/log"
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+	"github.com/jeandeaual/tts-deckconverter/tts"
+)
+
+const (
+	// ttpgTemplatesFolder is the folder of a Tabletop Playground package
+	// containing the object templates.
+	ttpgTemplatesFolder = "Templates"
+	// ttpgTexturesFolder is the folder of a Tabletop Playground package
+	// containing the images.
+	ttpgTexturesFolder = "Textures"
+	// ttpgSharedBackIndex means that the back texture is a single image used
+	// for every card.
+	ttpgSharedBackIndex = -1
+	// Size of the cards in centimeters
+	ttpgStandardWidth  = 6.3
+	ttpgStandardHeight = 8.8
+	ttpgSmallWidth     = 5.9
+	ttpgSmallHeight    = 8.6
+	ttpgCardThickness  = 0.05
+)
+
+// TTPGColor is a color in a Tabletop Playground template.
+type TTPGColor struct {
+	R int `json:"R"`
+	G int `json:"G"`
+	B int `json:"B"`
+}
+
+// TTPGCardTemplate is a Tabletop Playground card (or card stack) template,
+// stored in the Templates folder of a package.
+type TTPGCardTemplate struct {
+	Type           string    `json:"Type"`
+	GUID           string    `json:"GUID"`
+	Name           string    `json:"Name"`
+	Metadata       string    `json:"Metadata"`
+	CollisionType  string    `json:"CollisionType"`
+	Friction       float64   `json:"Friction"`
+	Restitution    float64   `json:"Restitution"`
+	Density        float64   `json:"Density"`
+	SurfaceType    string    `json:"SurfaceType"`
+	Roughness      float64   `json:"Roughness"`
+	Metallic       float64   `json:"Metallic"`
+	PrimaryColor   TTPGColor `json:"PrimaryColor"`
+	SecondaryColor TTPGColor `json:"SecondaryColor"`
+	Flippable      bool      `json:"Flippable"`
+	AutoStraighten bool      `json:"AutoStraighten"`
+	ShouldSnap     bool      `json:"ShouldSnap"`
+	// FrontTexture is the template sheet, relative to the Textures folder.
+	FrontTexture string `json:"FrontTexture"`
+	// BackTexture is the card back, relative to the Textures folder.
+	BackTexture   string  `json:"BackTexture"`
+	HiddenTexture string  `json:"HiddenTexture"`
+	BackIndex     int     `json:"BackIndex"`
+	HiddenIndex   int     `json:"HiddenIndex"`
+	NumHorizontal int     `json:"NumHorizontal"`
+	NumVertical   int     `json:"NumVertical"`
+	Width         float64 `json:"Width"`
+	Height        float64 `json:"Height"`
+	Thickness     float64 `json:"Thickness"`
+	HiddenCards   bool    `json:"HiddenCards"`
+	CanStack      bool    `json:"CanStack"`
+	// Indices are the positions of the cards of the stack in FrontTexture,
+	// from the bottom to the top of the stack.
+	Indices []int `json:"Indices"`
+	// CardNames maps a position in FrontTexture to the card name.
+	CardNames map[string]string `json:"CardNames"`
+	// CardMetadata maps a position in FrontTexture to the card description.
+	CardMetadata map[string]string `json:"CardMetadata"`
+}
+
+// ExportTTPG writes the decks as Tabletop Playground card templates, inside
+// the Templates and Textures folders of options.OutputFolder, which can be
+// copied into a Tabletop Playground package.
+// Tabletop Playground needs the images to be stored inside the package, so
+// the templates need to be generated first with tts.GenerateTemplates. A
+// template is written for each template sheet of a deck.
+func ExportTTPG(ctx context.Context, decks []*plugins.Deck, options Options) []error {
+	errs := []error{}
+
+	for _, folder := range []string{ttpgTemplatesFolder, ttpgTexturesFolder} {
+		if err := os.MkdirAll(filepath.Join(options.OutputFolder, folder), 0o755); err != nil {
+			return append(errs, err)
+		}
+	}
+
+	for _, deck := range decks {
+		if err := ctx.Err(); err != nil {
+			return append(errs, err)
+		}
+		if len(deck.Cards) == 0 {
+			log.Infof("Deck %s is empty, skipping", deck.Name)
+			continue
+		}
+
+		if err := exportTTPGDeck(ctx, deck, options); err != nil {
+			errs = append(errs, fmt.Errorf("couldn't export deck %s: %w", deck.Name, err))
+		}
+	}
+
+	return errs
+}
+
+func exportTTPGDeck(ctx context.Context, deck *plugins.Deck, options Options) error {
+	if deck.TemplateInfo == nil {
+		return errors.New("no template generated")
+	}
+
+	deckName := tts.FileName(deck.Name)
+
+	var backTexture string
+
+	if len(deck.BackURL) > 0 {
+		backTexture = deckName + " - Back" + imageExt(deck.BackURL)
+		err := tts.SaveImage(ctx, deck.BackURL, filepath.Join(options.OutputFolder, ttpgTexturesFolder, backTexture))
+		if err != nil {
+			return fmt.Errorf("couldn't save the card back: %w", err)
+		}
+	}
+
+	templates, err := ttpgTemplates(deck)
+	if err != nil {
+		return err
+	}
+
+	templateIDs := make([]int, 0, len(templates))
+	for templateID := range templates {
+		templateIDs = append(templateIDs, templateID)
+	}
+	sort.Ints(templateIDs)
+
+	for i, templateID := range templateIDs {
+		sheet := deck.TemplateInfo.Templates[templateID]
+		template := templates[templateID]
+
+		name := deckName
+		if i > 0 {
+			name = fmt.Sprintf("%s %d", deckName, i+1)
+		}
+
+		template.Name = name
+		template.GUID = ttpgGUID(name)
+		template.BackTexture = backTexture
+		template.FrontTexture = name + ".jpg"
+
+		err := tts.SaveImage(ctx, sheet.URL, filepath.Join(options.OutputFolder, ttpgTexturesFolder, template.FrontTexture))
+		if err != nil {
+			return fmt.Errorf("couldn't save template %s: %w", sheet.URL, err)
+		}
+
+		if err := writeTTPGTemplate(ctx, template, options); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ttpgTemplates returns a Tabletop Playground template for each template
+// sheet used by deck, mapped by template ID.
+func ttpgTemplates(deck *plugins.Deck) (map[int]*TTPGCardTemplate, error) {
+	templates := make(map[int]*TTPGCardTemplate)
+
+	var width, height float64
+	switch deck.CardSize {
+	case plugins.CardSizeStandard:
+		width, height = ttpgStandardWidth, ttpgStandardHeight
+	case plugins.CardSizeSmall:
+		width, height = ttpgSmallWidth, ttpgSmallHeight
+	default:
+		width, height = deck.CardSize.Dimensions()
+		width, height = width/10, height/10
+	}
+
+	for _, card := range deck.Cards {
+		cardID, found := deck.TemplateInfo.ImageURLCardIDMap[card.ImageURL]
+		if !found {
+			return nil, fmt.Errorf("card %s not found in the templates", card.Name)
+		}
+
+		sheet, templateID, err := deck.TemplateInfo.GetAssociatedTemplate(cardID)
+		if err != nil {
+			return nil, err
+		}
+
+		template, found := templates[templateID]
+		if !found {
+			template = newTTPGCardTemplate(sheet, width, height)
+			templates[templateID] = template
+		}
+
+		// The card IDs start at 100 for the first template sheet, 200 for
+		// the second, etc.
+		index := cardID % 100
+		key := strconv.Itoa(index)
+
+		template.CardNames[key] = card.Name
+		if len(card.Description) > 0 {
+			template.CardMetadata[key] = card.Description
+		}
+		for i := 0; i < card.Count; i++ {
+			template.Indices = append(template.Indices, index)
+		}
+	}
+
+	return templates, nil
+}
+
+func newTTPGCardTemplate(sheet *plugins.Template, width, height float64) *TTPGCardTemplate {
+	white := TTPGColor{R: 255, G: 255, B: 255}
+
+	return &TTPGCardTemplate{
+		Type:           "Card",
+		CollisionType:  "Regular",
+		Friction:       0.7,
+		Restitution:    0,
+		Density:        0.5,
+		SurfaceType:    "Cardboard",
+		Roughness:      1,
+		PrimaryColor:   white,
+		SecondaryColor: white,
+		Flippable:      true,
+		ShouldSnap:     true,
+		BackIndex:      ttpgSharedBackIndex,
+		NumHorizontal:  sheet.NumCols,
+		NumVertical:    sheet.NumRows,
+		Width:          width,
+		Height:         height,
+		Thickness:      ttpgCardThickness,
+		CanStack:       true,
+		Indices:        []int{},
+		CardNames:      make(map[string]string),
+		CardMetadata:   make(map[string]string),
+	}
+}
+
+// imageExt returns the extension of the image located at source (a URL or a
+// file path), or ".jpg" if it doesn't have any.
+func imageExt(source string) string {
+	ext := path.Ext(source)
+	if u, err := url.Parse(source); err == nil && len(u.Scheme) > 0 {
+		ext = path.Ext(u.Path)
+	}
+	if len(ext) == 0 {
+		return ".jpg"
+	}
+
+	return ext
+}
+
+// ttpgGUID returns a GUID derived from the template name, so that exporting
+// the same deck again replaces its template instead of creating a new one.
+func ttpgGUID(name string) string {
+	sum := md5.Sum([]byte(name))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func writeTTPGTemplate(ctx context.Context, template *TTPGCardTemplate, options Options) error {
+	var (
+		data []byte
+		err  error
+	)
+
+	if options.Indent {
+		data, err = json.MarshalIndent(template, "", strings.Repeat(" ", 2))
+	} else {
+		data, err = json.Marshal(template)
+	}
+	if err != nil {
+		return fmt.Errorf("couldn't marshall data: %w", err)
+	}
+
+	filename := filepath.Join(options.OutputFolder, ttpgTemplatesFolder, template.Name+".json")
+	log.Infof("Generating %s", filename)
+
+	err = tts.WriteFile(filename, data)
+	if err != nil {
+		return fmt.Errorf("couldn't write file %s: %w", filename, err)
+	}
+
+	plugins.ReportFileWritten(ctx, filename, int64(len(data)))
+
+	return nil
+}
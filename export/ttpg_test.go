@@ -0,0 +1,84 @@
+package export
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+func TestImageExt(t *testing.T) {
+	assert.Equal(t, ".png", imageExt("https://example.com/back.png?1234"))
+	assert.Equal(t, ".jpg", imageExt("https://example.com/back"))
+	assert.Equal(t, ".png", imageExt(filepath.Join("folder", "back.png")))
+}
+
+func TestExportTTPG(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ttpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	sheet := filepath.Join(dir, "Test - Template.jpg")
+	back := filepath.Join(dir, "back.png")
+	assert.Nil(t, ioutil.WriteFile(sheet, []byte("sheet"), 0o644))
+	assert.Nil(t, ioutil.WriteFile(back, []byte("back"), 0o644))
+
+	deck := &plugins.Deck{
+		Name:     "Test: Deck",
+		BackURL:  back,
+		CardSize: plugins.CardSizeSmall,
+		Cards: []plugins.CardInfo{
+			{Name: "Dark Magician", Description: "The ultimate wizard", ImageURL: "https://example.com/1.jpg", Count: 2},
+			{Name: "Pot of Greed", ImageURL: "https://example.com/2.jpg", Count: 1},
+		},
+		TemplateInfo: &plugins.TemplateInfo{
+			ImageURLCardIDMap: map[string]int{
+				"https://example.com/1.jpg": 100,
+				"https://example.com/2.jpg": 101,
+			},
+			Templates: map[int]*plugins.Template{
+				1: {URL: sheet, NumCols: 2, NumRows: 1},
+			},
+		},
+	}
+
+	output := filepath.Join(dir, "output")
+
+	errs := ExportTTPG(context.Background(), []*plugins.Deck{deck, {Name: "Empty"}}, Options{OutputFolder: output})
+	assert.Empty(t, errs)
+
+	data, err := ioutil.ReadFile(filepath.Join(output, "Templates", "Test- Deck.json"))
+	assert.Nil(t, err)
+
+	var template TTPGCardTemplate
+	assert.Nil(t, json.Unmarshal(data, &template))
+	assert.Equal(t, "Card", template.Type)
+	assert.Equal(t, "Test- Deck", template.Name)
+	assert.Len(t, template.GUID, 32)
+	assert.Equal(t, "Test- Deck.jpg", template.FrontTexture)
+	assert.Equal(t, "Test- Deck - Back.png", template.BackTexture)
+	assert.Equal(t, 2, template.NumHorizontal)
+	assert.Equal(t, 1, template.NumVertical)
+	assert.Equal(t, ttpgSmallWidth, template.Width)
+	assert.Equal(t, []int{0, 0, 1}, template.Indices)
+	assert.Equal(t, map[string]string{"0": "Dark Magician", "1": "Pot of Greed"}, template.CardNames)
+	assert.Equal(t, map[string]string{"0": "The ultimate wizard"}, template.CardMetadata)
+
+	texture, err := ioutil.ReadFile(filepath.Join(output, "Textures", "Test- Deck.jpg"))
+	assert.Nil(t, err)
+	assert.Equal(t, "sheet", string(texture))
+	_, err = os.Stat(filepath.Join(output, "Textures", "Test- Deck - Back.png"))
+	assert.Nil(t, err)
+
+	deck.TemplateInfo = nil
+	errs = ExportTTPG(context.Background(), []*plugins.Deck{deck}, Options{OutputFolder: output})
+	assert.Len(t, errs, 1)
+}
@@ -0,0 +1,156 @@
+package export
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/jeandeaual/tts-deckconverter/log"
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+	"github.com/jeandeaual/tts-deckconverter/tts"
+)
+
+const (
+	// vassalImagesFolder is the folder containing the card images, which
+	// have to be added to the Vassal module.
+	vassalImagesFolder = "images"
+	// vassalDeckFile is the deck file, loaded with the "Load Deck" command of
+	// a deck of the Vassal module.
+	vassalDeckFile = "deck.txt"
+	// vassalCommandSeparator separates the commands of a Vassal file.
+	vassalCommandSeparator = "\x1b"
+)
+
+// ExportVassal writes each deck as a folder containing the card images and a
+// deck file which can be loaded in an existing deck of a Vassal module.
+// Vassal pieces refer to the images of the module by name, so the images need
+// to be added to the module (with the module editor, or inside the images
+// folder of the module archive) before loading the deck.
+func ExportVassal(ctx context.Context, decks []*plugins.Deck, options Options) []error {
+	errs := []error{}
+
+	for _, deck := range decks {
+		if err := ctx.Err(); err != nil {
+			return append(errs, err)
+		}
+		if len(deck.Cards) == 0 {
+			log.Infof("Deck %s is empty, skipping", deck.Name)
+			continue
+		}
+
+		if err := exportVassalDeck(ctx, deck, options); err != nil {
+			errs = append(errs, fmt.Errorf("couldn't export deck %s: %w", deck.Name, err))
+		}
+	}
+
+	return errs
+}
+
+func exportVassalDeck(ctx context.Context, deck *plugins.Deck, options Options) error {
+	deckName := tts.FileName(deck.Name)
+	folder := filepath.Join(options.OutputFolder, deckName+" - Vassal")
+
+	if err := os.MkdirAll(filepath.Join(folder, vassalImagesFolder), 0o755); err != nil {
+		return err
+	}
+
+	// Map the image URLs to the name of the image files, since the same image
+	// can be used by several cards
+	images := make(map[string]string)
+	usedNames := make(map[string]bool)
+	commands := []string{}
+	// Prefix the piece IDs, so that they don't conflict with the pieces of
+	// another deck
+	sum := md5.Sum([]byte(deck.Name))
+	idPrefix := "tdc" + hex.EncodeToString(sum[:4]) + "-"
+
+	for _, card := range deck.Cards {
+		if len(card.ImageURL) == 0 {
+			return fmt.Errorf("card %s doesn't have an image", card.Name)
+		}
+
+		image, found := images[card.ImageURL]
+		if !found {
+			image = vassalImageName(card, usedNames)
+
+			if err := tts.SaveImage(ctx, card.ImageURL, filepath.Join(folder, vassalImagesFolder, image)); err != nil {
+				return fmt.Errorf("couldn't save the image of %s: %w", card.Name, err)
+			}
+
+			images[card.ImageURL] = image
+			usedNames[image] = true
+		}
+
+		for i := 0; i < card.Count; i++ {
+			commands = append(commands, vassalAddPiece(idPrefix+strconv.Itoa(len(commands)+1), card, image))
+		}
+	}
+
+	if len(commands) == 0 {
+		return errors.New("no card to export")
+	}
+
+	data := []byte(strings.Join(commands, vassalCommandSeparator))
+	filename := filepath.Join(folder, vassalDeckFile)
+	log.Infof("Generating %s", filename)
+
+	if err := tts.WriteFile(filename, data); err != nil {
+		return fmt.Errorf("couldn't write file %s: %w", filename, err)
+	}
+
+	plugins.ReportFileWritten(ctx, filename, int64(len(data)))
+
+	return nil
+}
+
+// vassalImageName returns a file name for the image of card which isn't in
+// usedNames.
+func vassalImageName(card plugins.CardInfo, usedNames map[string]bool) string {
+	name := card.Name
+	if len(card.Metadata.Name) > 0 {
+		name = card.Metadata.Name
+	}
+	name = tts.FileName(strings.ReplaceAll(name, "\n", " "))
+	ext := imageExt(card.ImageURL)
+
+	image := name + ext
+	for i := 2; usedNames[image]; i++ {
+		image = name + " " + strconv.Itoa(i) + ext
+	}
+
+	return image
+}
+
+// vassalAddPiece returns the Vassal command adding a basic piece showing image.
+func vassalAddPiece(id string, card plugins.CardInfo, image string) string {
+	name := card.Name
+	if len(card.Metadata.Name) > 0 {
+		name = card.Metadata.Name
+	}
+
+	// Type: "piece;<clone key>;<delete key>;<image>;<name>"
+	pieceType := vassalSequence(';', "piece", "", "", image, name)
+	// State: "<map>;<x>;<y>;<global piece ID>;<property count>"
+	pieceState := vassalSequence(';', "null", "0", "0", "", "0")
+
+	return "+/" + vassalSequence('/', id, pieceType, pieceState)
+}
+
+// vassalSequence joins tokens with delimiter, escaping the delimiter inside
+// the tokens with a backslash like the SequenceEncoder class of Vassal.
+func vassalSequence(delimiter rune, tokens ...string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, string(delimiter), `\`+string(delimiter))
+
+	escaped := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		escaped = append(escaped, replacer.Replace(token))
+	}
+
+	return strings.Join(escaped, string(delimiter))
+}
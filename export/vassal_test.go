@@ -0,0 +1,59 @@
+package export
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+func TestVassalSequence(t *testing.T) {
+	assert.Equal(t, `piece;;;Fire.jpg;Fire\;Ice`, vassalSequence(';', "piece", "", "", "Fire.jpg", "Fire;Ice"))
+	assert.Equal(t, "a\\/b/c\\\\", vassalSequence('/', "a/b", "c\\"))
+}
+
+func TestExportVassal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "vassal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	image1 := filepath.Join(dir, "1.png")
+	image2 := filepath.Join(dir, "2.png")
+	assert.Nil(t, ioutil.WriteFile(image1, []byte("image1"), 0o644))
+	assert.Nil(t, ioutil.WriteFile(image2, []byte("image2"), 0o644))
+
+	deck := &plugins.Deck{
+		Name: "Test",
+		Cards: []plugins.CardInfo{
+			{Name: "Island", ImageURL: image1, Count: 2},
+			{Name: "Island", ImageURL: image2, Count: 1},
+		},
+	}
+
+	errs := Exporters["vassal"].Export(context.Background(), []*plugins.Deck{deck}, Options{OutputFolder: dir})
+	assert.Empty(t, errs)
+
+	folder := filepath.Join(dir, "Test - Vassal")
+
+	data, err := ioutil.ReadFile(filepath.Join(folder, "images", "Island 2.png"))
+	assert.Nil(t, err)
+	assert.Equal(t, "image2", string(data))
+
+	data, err = ioutil.ReadFile(filepath.Join(folder, "deck.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commands := strings.Split(string(data), "\x1b")
+	assert.Len(t, commands, 3)
+	assert.Regexp(t, `^\+/tdc[0-9a-f]{8}-1/piece;;;Island\.png;Island/null;0;0;;0$`, commands[0])
+	assert.Contains(t, commands[2], "piece;;;Island 2.png;Island/")
+}
@@ -5,12 +5,14 @@ go 1.13
 require (
 	fyne.io/fyne/v2 v2.0.1
 	github.com/BlueMonday/go-scryfall v0.1.1-0.20200924044520-b1c60eed23b8
-	github.com/PokemonTCG/pokemon-tcg-sdk-go-v2 v0.1.0 // indirect
+	github.com/PokemonTCG/pokemon-tcg-sdk-go-v2 v0.1.0
 	github.com/antchfx/htmlquery v1.2.3
 	github.com/antchfx/xpath v1.1.11
 	github.com/disintegration/imaging v1.6.2
 	github.com/koffeinsource/go-imgur v0.3.0
 	github.com/stretchr/testify v1.7.0
 	go.uber.org/zap v1.16.0
+	golang.org/x/image v0.0.0-20200430140353-33d19683fad8
 	golang.org/x/net v0.0.0-20210119194325-5f4716e94777
+	golang.org/x/text v0.3.3
 )
@@ -0,0 +1,157 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// nopLogger discards all the messages.
+// Fatal and Panic messages still exit or panic, like with any other logger.
+type nopLogger struct{}
+
+func (nopLogger) Debug(args ...interface{})                       {}
+func (nopLogger) Info(args ...interface{})                        {}
+func (nopLogger) Warn(args ...interface{})                        {}
+func (nopLogger) Error(args ...interface{})                       {}
+func (nopLogger) Fatal(args ...interface{})                       { os.Exit(1) }
+func (nopLogger) Panic(args ...interface{})                       { panic(fmt.Sprint(args...)) }
+func (nopLogger) Debugf(format string, args ...interface{})       {}
+func (nopLogger) Infof(format string, args ...interface{})        {}
+func (nopLogger) Warnf(format string, args ...interface{})        {}
+func (nopLogger) Errorf(format string, args ...interface{})       {}
+func (nopLogger) Fatalf(format string, args ...interface{})       { os.Exit(1) }
+func (nopLogger) Panicf(format string, args ...interface{})       { panic(fmt.Sprintf(format, args...)) }
+func (nopLogger) Debugw(msg string, keysAndValues ...interface{}) {}
+func (nopLogger) Infow(msg string, keysAndValues ...interface{})  {}
+func (nopLogger) Warnw(msg string, keysAndValues ...interface{})  {}
+func (nopLogger) Errorw(msg string, keysAndValues ...interface{}) {}
+func (nopLogger) Fatalw(msg string, keysAndValues ...interface{}) { os.Exit(1) }
+func (nopLogger) Panicw(msg string, keysAndValues ...interface{}) { panic(msg) }
+
+// Printer is implemented by simple loggers such as the standard library's
+// *log.Logger.
+type Printer interface {
+	Printf(format string, args ...interface{})
+}
+
+// printerLogger is a Logger writing every message to a Printer, prefixed with
+// its level.
+type printerLogger struct {
+	printer Printer
+	debug   bool
+}
+
+// NewPrinterLogger creates a Logger from a Printer, so that the package can
+// be used without depending on a specific logging library.
+// Debug messages are only printed if debug is true.
+func NewPrinterLogger(printer Printer, debug bool) Logger {
+	return printerLogger{printer: printer, debug: debug}
+}
+
+func (l printerLogger) print(level string, msg string) {
+	l.printer.Printf("%s\t%s", level, msg)
+}
+
+func (l printerLogger) printw(level string, msg string, keysAndValues []interface{}) {
+	var sb strings.Builder
+
+	sb.WriteString(msg)
+
+	for i := 0; i < len(keysAndValues); i += 2 {
+		sb.WriteString("\t")
+		if i+1 < len(keysAndValues) {
+			sb.WriteString(fmt.Sprintf("%v=%v", keysAndValues[i], keysAndValues[i+1]))
+		} else {
+			sb.WriteString(fmt.Sprint(keysAndValues[i]))
+		}
+	}
+
+	l.print(level, sb.String())
+}
+
+func (l printerLogger) Debug(args ...interface{}) {
+	if l.debug {
+		l.print("DEBUG", fmt.Sprint(args...))
+	}
+}
+
+func (l printerLogger) Info(args ...interface{}) {
+	l.print("INFO", fmt.Sprint(args...))
+}
+
+func (l printerLogger) Warn(args ...interface{}) {
+	l.print("WARN", fmt.Sprint(args...))
+}
+
+func (l printerLogger) Error(args ...interface{}) {
+	l.print("ERROR", fmt.Sprint(args...))
+}
+
+func (l printerLogger) Fatal(args ...interface{}) {
+	l.print("FATAL", fmt.Sprint(args...))
+	os.Exit(1)
+}
+
+func (l printerLogger) Panic(args ...interface{}) {
+	msg := fmt.Sprint(args...)
+	l.print("PANIC", msg)
+	panic(msg)
+}
+
+func (l printerLogger) Debugf(format string, args ...interface{}) {
+	if l.debug {
+		l.print("DEBUG", fmt.Sprintf(format, args...))
+	}
+}
+
+func (l printerLogger) Infof(format string, args ...interface{}) {
+	l.print("INFO", fmt.Sprintf(format, args...))
+}
+
+func (l printerLogger) Warnf(format string, args ...interface{}) {
+	l.print("WARN", fmt.Sprintf(format, args...))
+}
+
+func (l printerLogger) Errorf(format string, args ...interface{}) {
+	l.print("ERROR", fmt.Sprintf(format, args...))
+}
+
+func (l printerLogger) Fatalf(format string, args ...interface{}) {
+	l.print("FATAL", fmt.Sprintf(format, args...))
+	os.Exit(1)
+}
+
+func (l printerLogger) Panicf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	l.print("PANIC", msg)
+	panic(msg)
+}
+
+func (l printerLogger) Debugw(msg string, keysAndValues ...interface{}) {
+	if l.debug {
+		l.printw("DEBUG", msg, keysAndValues)
+	}
+}
+
+func (l printerLogger) Infow(msg string, keysAndValues ...interface{}) {
+	l.printw("INFO", msg, keysAndValues)
+}
+
+func (l printerLogger) Warnw(msg string, keysAndValues ...interface{}) {
+	l.printw("WARN", msg, keysAndValues)
+}
+
+func (l printerLogger) Errorw(msg string, keysAndValues ...interface{}) {
+	l.printw("ERROR", msg, keysAndValues)
+}
+
+func (l printerLogger) Fatalw(msg string, keysAndValues ...interface{}) {
+	l.printw("FATAL", msg, keysAndValues)
+	os.Exit(1)
+}
+
+func (l printerLogger) Panicw(msg string, keysAndValues ...interface{}) {
+	l.printw("PANIC", msg, keysAndValues)
+	panic(msg)
+}
@@ -0,0 +1,32 @@
+package log
+
+import (
+	"bytes"
+	stdlog "log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNopLogger(t *testing.T) {
+	SetLogger(nil)
+	defer SetLogger(nil)
+
+	// Shouldn't panic
+	Info("message")
+	Debugw("message", "key", "value")
+	assert.Panics(t, func() { Panic("message") })
+}
+
+func TestPrinterLogger(t *testing.T) {
+	var buf bytes.Buffer
+
+	SetLogger(NewPrinterLogger(stdlog.New(&buf, "", 0), false))
+	defer SetLogger(nil)
+
+	Debug("hidden")
+	Infof("card %d", 1)
+	Warnw("missing", "name", "Island", "set")
+
+	assert.Equal(t, "INFO\tcard 1\nWARN\tmissing\tname=Island\tset\n", buf.String())
+}
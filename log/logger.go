@@ -1,7 +1,27 @@
 package log
 
+import "sync/atomic"
+
 // A global variable so that log functions can be directly accessed
-var log Logger
+// Nothing is logged until SetLogger is called.
+// An atomic.Value is used so that SetLogger can be called while other
+// goroutines are logging.
+var log atomic.Value
+
+// loggerHolder is stored in log, since an atomic.Value requires values of
+// the same concrete type.
+type loggerHolder struct {
+	Logger
+}
+
+func init() {
+	log.Store(loggerHolder{nopLogger{}})
+}
+
+// current returns the logger set with SetLogger.
+func current() Logger {
+	return log.Load().(loggerHolder).Logger
+}
 
 // Logger is a logger abstraction
 type Logger interface {
@@ -26,96 +46,100 @@ type Logger interface {
 }
 
 // SetLogger sets the logger instance used by the package.
+// A nil logger disables logging.
 func SetLogger(logger Logger) {
-	log = logger
+	if logger == nil {
+		logger = nopLogger{}
+	}
+	log.Store(loggerHolder{logger})
 }
 
 // Debug uses fmt.Sprint to construct and log a message.
 func Debug(args ...interface{}) {
-	log.Debug(args...)
+	current().Debug(args...)
 }
 
 // Info uses fmt.Sprint to construct and log a message.
 func Info(args ...interface{}) {
-	log.Info(args...)
+	current().Info(args...)
 }
 
 // Warn uses fmt.Sprint to construct and log a message.
 func Warn(args ...interface{}) {
-	log.Warn(args...)
+	current().Warn(args...)
 }
 
 // Error uses fmt.Sprint to construct and log a message.
 func Error(args ...interface{}) {
-	log.Error(args...)
+	current().Error(args...)
 }
 
 // Fatal uses fmt.Sprint to construct and log a message, then calls os.Exit.
 func Fatal(args ...interface{}) {
-	log.Fatal(args...)
+	current().Fatal(args...)
 }
 
 // Panic uses fmt.Sprint to construct and log a message, then panics.
 func Panic(args ...interface{}) {
-	log.Panic(args...)
+	current().Panic(args...)
 }
 
 // Debugf uses fmt.Sprintf to construct and log a message.
 func Debugf(format string, args ...interface{}) {
-	log.Debugf(format, args...)
+	current().Debugf(format, args...)
 }
 
 // Infof uses fmt.Sprintf to construct and log a message.
 func Infof(format string, args ...interface{}) {
-	log.Infof(format, args...)
+	current().Infof(format, args...)
 }
 
 // Warnf uses fmt.Sprintf to construct and log a message.
 func Warnf(format string, args ...interface{}) {
-	log.Warnf(format, args...)
+	current().Warnf(format, args...)
 }
 
 // Errorf uses fmt.Sprintf to construct and log a message.
 func Errorf(format string, args ...interface{}) {
-	log.Errorf(format, args...)
+	current().Errorf(format, args...)
 }
 
 // Fatalf uses fmt.Sprint to construct and log a message, then calls os.Exit.
 func Fatalf(format string, args ...interface{}) {
-	log.Fatalf(format, args...)
+	current().Fatalf(format, args...)
 }
 
 // Panicf uses fmt.Sprint to construct and log a message, then panics.
 func Panicf(format string, args ...interface{}) {
-	log.Panicf(format, args...)
+	current().Panicf(format, args...)
 }
 
 // Debugw logs a message with some additional context.
 func Debugw(msg string, keysAndValues ...interface{}) {
-	log.Debugw(msg, keysAndValues...)
+	current().Debugw(msg, keysAndValues...)
 }
 
 // Infow logs a message with some additional context.
 func Infow(msg string, keysAndValues ...interface{}) {
-	log.Infow(msg, keysAndValues...)
+	current().Infow(msg, keysAndValues...)
 }
 
 // Warnw logs a message with some additional context.
 func Warnw(msg string, keysAndValues ...interface{}) {
-	log.Warnw(msg, keysAndValues...)
+	current().Warnw(msg, keysAndValues...)
 }
 
 // Errorw logs a message with some additional context.
 func Errorw(msg string, keysAndValues ...interface{}) {
-	log.Errorw(msg, keysAndValues...)
+	current().Errorw(msg, keysAndValues...)
 }
 
 // Panicw logs a message with some additional context, then panics.
 func Panicw(msg string, keysAndValues ...interface{}) {
-	log.Panicw(msg, keysAndValues...)
+	current().Panicw(msg, keysAndValues...)
 }
 
 // Fatalw logs a message with some additional context, then calls os.Exit.
 func Fatalw(msg string, keysAndValues ...interface{}) {
-	log.Fatalw(msg, keysAndValues...)
+	current().Fatalw(msg, keysAndValues...)
 }
@@ -1,17 +1,19 @@
 package deckconverter
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/jeandeaual/tts-deckconverter/log"
 	"github.com/jeandeaual/tts-deckconverter/plugins"
 )
 
-func parseFileWithPlugin(target string, plugin plugins.Plugin, options map[string]string) ([]*plugins.Deck, error) {
+func parseFileWithPlugin(ctx context.Context, target string, plugin plugins.Plugin, options map[string]string) ([]*plugins.Deck, error) {
 	log.Infof("Parsing file %s", target)
 
 	var decks []*plugins.Deck
@@ -37,15 +39,15 @@ func parseFileWithPlugin(target string, plugin plugins.Plugin, options map[strin
 	log.Debugf("Base file name: %s", name)
 
 	if handler, ok := plugin.FileExtHandlers()[ext]; ok {
-		decks, err = handler(file, name, options)
+		decks, err = handler(ctx, file, name, options)
 		return decks, err
 	}
 
-	decks, err = plugin.GenericFileHandler().FileHandler(file, name, options)
+	decks, err = plugin.GenericFileHandler().FileHandler(ctx, file, name, options)
 	return decks, err
 }
 
-func parseFile(target string, options map[string]string) ([]*plugins.Deck, error) {
+func parseFile(ctx context.Context, target string, options map[string]string) ([]*plugins.Deck, error) {
 	if _, err := os.Stat(target); os.IsNotExist(err) {
 		return nil, err
 	}
@@ -53,7 +55,7 @@ func parseFile(target string, options map[string]string) ([]*plugins.Deck, error
 	// No mode selected, check the file extension handlers
 	ext := filepath.Ext(target)
 
-	fileExtHandler, found := FileExtHandlers[ext]
+	fileExtHandler, found := lookupFileExtHandler(ext)
 	if !found {
 		return nil, fmt.Errorf("no handler found for %s files", ext)
 	}
@@ -74,19 +76,42 @@ func parseFile(target string, options map[string]string) ([]*plugins.Deck, error
 
 	log.Debugf("Base file name: %s", name)
 
-	decks, err := fileExtHandler(file, name, options)
+	decks, err := fileExtHandler(ctx, file, name, options)
 
 	return decks, err
 }
 
 // Parse a URL or file and generate a list of decks from it.
-func Parse(target, mode string, options map[string]string) ([]*plugins.Deck, error) {
+// The requests sent while parsing are cancelled when ctx is done.
+// The accessories of the plugin are added to the first deck if the
+// plugins.AccessoriesOption option is enabled.
+// Parse can be called concurrently from multiple goroutines, with the same
+// options: they are copied before being passed to the handlers, which set the
+// default values of some options. options can be nil.
+func Parse(ctx context.Context, target, mode string, options map[string]string) (decks []*plugins.Deck, err error) {
+	options = copyOptions(options)
+
+	start := time.Now()
+	defer func() {
+		plugins.RecordOperation(plugins.OperationParse, start, err)
+	}()
+	defer func() {
+		if err == nil {
+			addAccessories(target, mode, options, decks)
+		}
+	}()
+
 	if u, err := url.Parse(target); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
 		// Check if the target is a supported URL
-		for _, handler := range URLHandlers {
+		for _, handler := range urlHandlers() {
 			if handler.Regex.MatchString(target) {
 				log.Debugf("Using handler %+v", handler)
-				decks, err := handler.Handler(target, options)
+				decks, err := handler.Handler(ctx, target, options)
+				for _, deck := range decks {
+					if len(deck.SourceURL) == 0 {
+						deck.SourceURL = target
+					}
+				}
 				return decks, err
 			}
 		}
@@ -94,7 +119,7 @@ func Parse(target, mode string, options map[string]string) ([]*plugins.Deck, err
 		return nil, fmt.Errorf("unsupported URL: %s", target)
 	}
 
-	_, err := os.Stat(target)
+	_, err = os.Stat(target)
 
 	if err != nil {
 		return nil, fmt.Errorf("file %s not found: %w", target, err)
@@ -103,7 +128,7 @@ func Parse(target, mode string, options map[string]string) ([]*plugins.Deck, err
 	var selectedPlugin *plugins.Plugin
 
 	if len(mode) > 0 {
-		plugin, found := Plugins[mode]
+		plugin, found := lookupPlugin(mode)
 		if !found {
 			return nil, fmt.Errorf("plugin %s not found", mode)
 		}
@@ -114,8 +139,19 @@ func Parse(target, mode string, options map[string]string) ([]*plugins.Deck, err
 	}
 
 	if selectedPlugin != nil {
-		return parseFileWithPlugin(target, *selectedPlugin, options)
+		return parseFileWithPlugin(ctx, target, *selectedPlugin, options)
+	}
+
+	return parseFile(ctx, target, options)
+}
+
+// copyOptions returns a copy of options, which is never nil.
+func copyOptions(options map[string]string) map[string]string {
+	copied := make(map[string]string, len(options))
+
+	for k, v := range options {
+		copied[k] = v
 	}
 
-	return parseFile(target, options)
+	return copied
 }
@@ -1,10 +1,14 @@
 package deckconverter
 
 import (
+	"errors"
+	"fmt"
 	"log"
+	"sync"
 
 	"github.com/jeandeaual/tts-deckconverter/plugins"
 	"github.com/jeandeaual/tts-deckconverter/plugins/custom"
+	"github.com/jeandeaual/tts-deckconverter/plugins/external"
 	"github.com/jeandeaual/tts-deckconverter/plugins/mtg"
 	"github.com/jeandeaual/tts-deckconverter/plugins/pkm"
 	"github.com/jeandeaual/tts-deckconverter/plugins/vanguard"
@@ -28,6 +32,8 @@ func init() {
 }
 
 // Plugins is the list of registered plugins.
+// It shouldn't be modified directly: use LoadExternalPlugin to register a
+// new plugin.
 var Plugins map[string]plugins.Plugin
 
 // pluginIDs is the ordered list of registered plugins.
@@ -36,9 +42,26 @@ var pluginIDs []string
 // URLHandlers are all the registered URL handlers.
 var URLHandlers []plugins.URLHandler
 
+// registeredURLHandler is a URL handler added to a plugin with
+// RegisterURLHandler.
+type registeredURLHandler struct {
+	pluginID string
+	handler  plugins.URLHandler
+}
+
+// registeredURLHandlers are the URL handlers added with RegisterURLHandler,
+// most recent first, so that FindPlugin can return the plugin they were
+// registered for.
+var registeredURLHandlers []registeredURLHandler
+
 // FileExtHandlers are all the registered file extension handlers.
 var FileExtHandlers map[string]plugins.FileHandler
 
+// registryMutex protects Plugins, pluginIDs, URLHandlers,
+// registeredURLHandlers and FileExtHandlers, so that plugins and handlers can be registered while
+// decks are being parsed.
+var registryMutex sync.RWMutex
+
 func registerPlugins(plugins ...plugins.Plugin) {
 	for _, plugin := range plugins {
 		Plugins[plugin.PluginID()] = plugin
@@ -52,6 +75,67 @@ func registerURLHandlers() {
 	}
 }
 
+// LoadExternalPlugin loads the plugin implemented by the executable located
+// at path, and registers it with its URL and file extension handlers.
+// See the external package for the protocol used to communicate with the
+// executable.
+func LoadExternalPlugin(path string) error {
+	plugin, err := external.Load(path)
+	if err != nil {
+		return err
+	}
+
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	if _, found := Plugins[plugin.PluginID()]; found {
+		return fmt.Errorf("plugin %s (%s) already exists", plugin.PluginID(), path)
+	}
+
+	for ext := range plugin.FileExtHandlers() {
+		if _, found := FileExtHandlers[ext]; found {
+			return fmt.Errorf(
+				"handler for file extension %s already exists, cannot register for %s",
+				ext,
+				plugin.PluginID(),
+			)
+		}
+	}
+
+	registerPlugins(plugin)
+	URLHandlers = append(URLHandlers, plugin.URLHandlers()...)
+	for ext, fileExtHandler := range plugin.FileExtHandlers() {
+		FileExtHandlers[ext] = fileExtHandler
+	}
+
+	return nil
+}
+
+// RegisterURLHandler adds a URL handler to an existing plugin at runtime, so
+// that decks from other websites can be parsed without modifying the plugin.
+// The handlers registered this way are checked before the built-in ones, and
+// can therefore override them.
+func RegisterURLHandler(pluginID string, handler plugins.URLHandler) error {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+
+	if _, found := Plugins[pluginID]; !found {
+		return fmt.Errorf("plugin %s not found", pluginID)
+	}
+
+	if handler.Regex == nil || handler.Handler == nil {
+		return errors.New("a URL handler requires a regex and a handler function")
+	}
+
+	URLHandlers = append([]plugins.URLHandler{handler}, URLHandlers...)
+	registeredURLHandlers = append(
+		[]registeredURLHandler{{pluginID: pluginID, handler: handler}},
+		registeredURLHandlers...,
+	)
+
+	return nil
+}
+
 func registerFileExtHandlers() {
 	for _, plugin := range Plugins {
 		for ext, fileExtHandler := range plugin.FileExtHandlers() {
@@ -72,5 +156,37 @@ func registerFileExtHandlers() {
 
 // AvailablePlugins lists the registered plugins, sorted.
 func AvailablePlugins() []string {
-	return pluginIDs
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	ids := make([]string, len(pluginIDs))
+	copy(ids, pluginIDs)
+
+	return ids
+}
+
+// lookupPlugin returns the registered plugin with the given ID.
+func lookupPlugin(pluginID string) (plugins.Plugin, bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	plugin, found := Plugins[pluginID]
+	return plugin, found
+}
+
+// lookupFileExtHandler returns the registered handler for a file extension.
+func lookupFileExtHandler(ext string) (plugins.FileHandler, bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	handler, found := FileExtHandlers[ext]
+	return handler, found
+}
+
+// urlHandlers returns the registered URL handlers.
+func urlHandlers() []plugins.URLHandler {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	return URLHandlers
 }
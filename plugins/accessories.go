@@ -0,0 +1,54 @@
+package plugins
+
+// AccessoriesOption is the name of the option of the plugins implementing
+// AccessoryProvider, which spawns their accessories next to the first deck
+// of each target.
+const AccessoriesOption = "accessories"
+
+// Die is the number of faces of a standard die.
+type Die int
+
+const (
+	// D4 is a four-sided die.
+	D4 Die = 4
+	// D6 is a six-sided die.
+	D6 Die = 6
+	// D8 is an eight-sided die.
+	D8 Die = 8
+	// D10 is a ten-sided die.
+	D10 Die = 10
+	// D12 is a twelve-sided die.
+	D12 Die = 12
+	// D20 is a twenty-sided die.
+	D20 Die = 20
+)
+
+// Accessory is an object spawned next to a deck, used to play the game
+// (e.g. a die keeping track of the damage).
+type Accessory struct {
+	// Name of the accessory (e.g. "Life").
+	Name string `json:"name"`
+	// Die is the kind of die of the accessory.
+	Die Die `json:"die"`
+	// Count is the number of copies of the accessory (1 if 0).
+	Count int `json:"count,omitempty"`
+}
+
+// AccessoryProvider can be implemented by a plugin to declare the
+// accessories used by its game, which are spawned next to the decks when
+// the AccessoriesOption option is enabled. The plugin needs to declare this
+// option (see NewAccessoriesOption).
+type AccessoryProvider interface {
+	// Accessories returns the accessories spawned next to the decks.
+	Accessories() []Accessory
+}
+
+// NewAccessoriesOption returns the AccessoriesOption option of a plugin
+// implementing AccessoryProvider.
+func NewAccessoriesOption() Option {
+	return Option{
+		Type:         OptionTypeBool,
+		Description:  "Spawn the dice used by the game next to the deck",
+		DefaultValue: false,
+	}
+}
@@ -0,0 +1,72 @@
+package plugins
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var (
+	// cardAliases maps the lowercase names found in the deck lists to the
+	// names used to query the APIs.
+	cardAliases = make(map[string]string)
+	// ignoredCards match the lines of the deck lists which aren't cards.
+	ignoredCards []*regexp.Regexp
+	aliasesMutex sync.RWMutex
+)
+
+// SetCardAliases replaces the card names found in the deck lists (e.g. a
+// misspelling like "Lim-Dul's Vault") with the names used by the APIs (e.g.
+// "Lim-Dûl's Vault"). The names are compared case-insensitively.
+func SetCardAliases(aliases map[string]string) {
+	aliasesMutex.Lock()
+	defer aliasesMutex.Unlock()
+
+	cardAliases = make(map[string]string, len(aliases))
+	for name, alias := range aliases {
+		cardAliases[strings.ToLower(NormalizeName(name))] = alias
+	}
+}
+
+// SetIgnoredCards skips the card names found in the deck lists which
+// entirely match one of patterns (case-insensitive regular expressions),
+// such as the non-card lines added by some websites.
+func SetIgnoredCards(patterns []string) error {
+	regexps := make([]*regexp.Regexp, 0, len(patterns))
+
+	for _, pattern := range patterns {
+		regex, err := regexp.Compile(`(?i)^(?:` + pattern + `)$`)
+		if err != nil {
+			return fmt.Errorf("invalid ignore pattern %s: %w", pattern, err)
+		}
+		regexps = append(regexps, regex)
+	}
+
+	aliasesMutex.Lock()
+	defer aliasesMutex.Unlock()
+
+	ignoredCards = regexps
+
+	return nil
+}
+
+// ResolveCardName returns the name to use for a card found in a deck list,
+// and false if the card should be ignored.
+// See SetCardAliases and SetIgnoredCards.
+func ResolveCardName(name string) (string, bool) {
+	aliasesMutex.RLock()
+	defer aliasesMutex.RUnlock()
+
+	for _, regex := range ignoredCards {
+		if regex.MatchString(name) {
+			return name, false
+		}
+	}
+
+	if alias, found := cardAliases[strings.ToLower(NormalizeName(name))]; found {
+		return alias, true
+	}
+
+	return name, true
+}
@@ -0,0 +1,33 @@
+package plugins
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveCardName(t *testing.T) {
+	SetCardAliases(map[string]string{"Lim-Dul’s Vault": "Lim-Dûl's Vault"})
+	assert.Nil(t, SetIgnoredCards([]string{"deck box", `\d+ sleeves`}))
+	defer func() {
+		SetCardAliases(nil)
+		assert.Nil(t, SetIgnoredCards(nil))
+	}()
+
+	name, keep := ResolveCardName("lim-dul's vault")
+	assert.True(t, keep)
+	assert.Equal(t, "Lim-Dûl's Vault", name)
+
+	name, keep = ResolveCardName("Lightning Bolt")
+	assert.True(t, keep)
+	assert.Equal(t, "Lightning Bolt", name)
+
+	_, keep = ResolveCardName("Deck Box")
+	assert.False(t, keep)
+	_, keep = ResolveCardName("100 Sleeves")
+	assert.False(t, keep)
+	_, keep = ResolveCardName("Deck Box Golem")
+	assert.True(t, keep)
+
+	assert.NotNil(t, SetIgnoredCards([]string{"("}))
+}
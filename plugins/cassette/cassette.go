@@ -0,0 +1,320 @@
+// Package cassette records the HTTP interactions of the plugins and replays
+// them, so that the parsers can be tested without sending any request to
+// the websites and APIs they use.
+//
+// A Recorder is used as the transport of the plugins' HTTP client:
+//
+//	recorder, err := cassette.New("testdata/deck.json", cassette.ModeFromEnv(), nil)
+//	plugins.SetHTTPTransport(recorder)
+//	// Parse a deck...
+//	err = recorder.Save()
+package cassette
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"unicode/utf8"
+)
+
+// Mode of a Recorder.
+type Mode int
+
+const (
+	// ModeReplay replays the recorded interactions, without sending any
+	// request.
+	ModeReplay Mode = iota
+	// ModeRecord sends the requests and records the interactions.
+	ModeRecord
+)
+
+// String representation of a Mode.
+func (m Mode) String() string {
+	switch m {
+	case ModeReplay:
+		return "replay"
+	case ModeRecord:
+		return "record"
+	default:
+		return "unknown"
+	}
+}
+
+// RecordEnv is the environment variable used to record the interactions
+// again (e.g. "TTS_DECKCONVERTER_RECORD=1 go test ./...").
+const RecordEnv = "TTS_DECKCONVERTER_RECORD"
+
+// ModeFromEnv returns ModeRecord if the RecordEnv environment variable is
+// set to a true value, and ModeReplay otherwise.
+func ModeFromEnv() Mode {
+	if record, err := strconv.ParseBool(os.Getenv(RecordEnv)); err == nil && record {
+		return ModeRecord
+	}
+	return ModeReplay
+}
+
+// ErrInteractionNotFound is returned when replaying a request which hasn't
+// been recorded.
+var ErrInteractionNotFound = errors.New("no recorded interaction matching the request")
+
+// ErrNotRecorded is returned when replaying a cassette which hasn't been
+// recorded yet. The tests using it can be skipped until it is recorded with
+// RecordEnv.
+var ErrNotRecorded = errors.New("cassette not recorded")
+
+// Request is a recorded HTTP request.
+// The request headers aren't recorded, since they can contain credentials.
+type Request struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body,omitempty"`
+}
+
+// Response is a recorded HTTP response.
+type Response struct {
+	StatusCode  int    `json:"status"`
+	ContentType string `json:"contentType,omitempty"`
+	// Body is set if the response body is valid UTF-8, BodyBase64 otherwise
+	// (e.g. for images).
+	Body       string `json:"body,omitempty"`
+	BodyBase64 string `json:"bodyBase64,omitempty"`
+}
+
+// Interaction is a recorded request and its response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+	// replayed is set once the interaction has been replayed, so that the
+	// same request sent several times gets the responses in order.
+	replayed bool
+}
+
+// cassetteFile is the JSON document written by Recorder.Save.
+type cassetteFile struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Recorder is an http.RoundTripper recording or replaying HTTP interactions.
+// It can be used concurrently.
+type Recorder struct {
+	path         string
+	mode         Mode
+	base         http.RoundTripper
+	mutex        sync.Mutex
+	interactions []*Interaction
+}
+
+// New creates a recorder using the cassette located at path.
+// In replay mode, the cassette is loaded and base is ignored. In record
+// mode, the requests are sent using base (http.DefaultTransport if nil), and
+// the cassette is only written when calling Save.
+func New(path string, mode Mode, base http.RoundTripper) (*Recorder, error) {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	recorder := &Recorder{
+		path: path,
+		mode: mode,
+		base: base,
+	}
+
+	if mode == ModeRecord {
+		return recorder, nil
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %s", ErrNotRecorded, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("couldn't load cassette %s: %w", path, err)
+	}
+
+	var file cassetteFile
+
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid cassette %s: %w", path, err)
+	}
+
+	recorder.interactions = file.Interactions
+
+	return recorder, nil
+}
+
+// NewForTest creates a recorder using the cassette located at path, in the
+// mode selected with RecordEnv and with http.DefaultTransport as base.
+// The test is skipped if the cassette hasn't been recorded yet, and fails if
+// it can't be loaded.
+func NewForTest(t testing.TB, path string) *Recorder {
+	t.Helper()
+
+	recorder, err := New(path, ModeFromEnv(), nil)
+	if errors.Is(err, ErrNotRecorded) {
+		t.Skipf("%v (record it with %s=1)", err, RecordEnv)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return recorder
+}
+
+// Mode returns the mode of the recorder.
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	request, err := newRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.mode == ModeRecord {
+		return r.record(req, request)
+	}
+
+	return r.replay(req, request)
+}
+
+func (r *Recorder) record(req *http.Request, request Request) (*http.Response, error) {
+	resp, err := r.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	cerr := resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if cerr != nil {
+		return nil, cerr
+	}
+
+	response := Response{
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+	}
+	if utf8.Valid(body) {
+		response.Body = string(body)
+	} else {
+		response.BodyBase64 = base64.StdEncoding.EncodeToString(body)
+	}
+
+	r.mutex.Lock()
+	r.interactions = append(r.interactions, &Interaction{
+		Request:  request,
+		Response: response,
+	})
+	r.mutex.Unlock()
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	return resp, nil
+}
+
+func (r *Recorder) replay(req *http.Request, request Request) (*http.Response, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, interaction := range r.interactions {
+		if interaction.replayed || interaction.Request != request {
+			continue
+		}
+
+		interaction.replayed = true
+
+		return interaction.Response.toHTTP(req)
+	}
+
+	return nil, fmt.Errorf("%w: %s %s", ErrInteractionNotFound, request.Method, request.URL)
+}
+
+// Save writes the recorded interactions to the cassette file.
+// It does nothing in replay mode.
+func (r *Recorder) Save() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+
+	r.mutex.Lock()
+	data, err := json.MarshalIndent(cassetteFile{Interactions: r.interactions}, "", "  ")
+	r.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(r.path, append(data, '\n'), 0o644)
+}
+
+func newRequest(req *http.Request) (Request, error) {
+	request := Request{
+		Method: req.Method,
+		URL:    req.URL.String(),
+	}
+
+	if req.Body == nil || req.Body == http.NoBody {
+		return request, nil
+	}
+
+	body, err := ioutil.ReadAll(req.Body)
+	cerr := req.Body.Close()
+	if err != nil {
+		return request, err
+	}
+	if cerr != nil {
+		return request, cerr
+	}
+
+	request.Body = string(body)
+	// The body has been consumed, replace it so that the request can still
+	// be sent when recording
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	return request, nil
+}
+
+func (r Response) toHTTP(req *http.Request) (*http.Response, error) {
+	body := []byte(r.Body)
+
+	if len(r.BodyBase64) > 0 {
+		var err error
+		body, err = base64.StdEncoding.DecodeString(r.BodyBase64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recorded body for %s: %w", req.URL, err)
+		}
+	}
+
+	header := make(http.Header)
+	if len(r.ContentType) > 0 {
+		header.Set("Content-Type", r.ContentType)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
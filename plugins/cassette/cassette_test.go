@@ -0,0 +1,97 @@
+package cassette
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func get(t *testing.T, client *http.Client, url string) (int, string) {
+	resp, err := client.Get(url)
+	assert.Nil(t, err)
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.Nil(t, err)
+
+	return resp.StatusCode, string(body)
+}
+
+func TestRecordReplay(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path == "/image" {
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte{0x89, 0x50, 0x4e, 0x47, 0xff})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"path":%q,"count":%d}`, r.URL.Path, requests)
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir(os.TempDir(), "cassette-")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "testdata", "cassette.json")
+
+	recorder, err := New(path, ModeRecord, nil)
+	assert.Nil(t, err)
+	client := &http.Client{Transport: recorder}
+
+	_, first := get(t, client, ts.URL+"/deck")
+	_, second := get(t, client, ts.URL+"/deck")
+	_, image := get(t, client, ts.URL+"/image")
+	assert.Nil(t, recorder.Save())
+	assert.Equal(t, 3, requests)
+
+	recorder, err = New(path, ModeReplay, nil)
+	assert.Nil(t, err)
+	client = &http.Client{Transport: recorder}
+
+	status, body := get(t, client, ts.URL+"/deck")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Equal(t, first, body)
+	_, body = get(t, client, ts.URL+"/deck")
+	assert.Equal(t, second, body)
+	_, body = get(t, client, ts.URL+"/image")
+	assert.Equal(t, image, body)
+	// Nothing should have been sent while replaying
+	assert.Equal(t, 3, requests)
+
+	_, err = client.Get(ts.URL + "/deck")
+	assert.True(t, errors.Is(err, ErrInteractionNotFound))
+	_, err = client.Post(ts.URL+"/deck", "text/plain", strings.NewReader("body"))
+	assert.True(t, errors.Is(err, ErrInteractionNotFound))
+}
+
+func TestNewMissingCassette(t *testing.T) {
+	_, err := New(filepath.Join("testdata", "missing.json"), ModeReplay, nil)
+	assert.True(t, errors.Is(err, ErrNotRecorded))
+}
+
+func TestNewForTest(t *testing.T) {
+	t.Run("missing", func(t *testing.T) {
+		NewForTest(t, filepath.Join("testdata", "missing.json"))
+		t.Error("the test should have been skipped")
+	})
+}
+
+func TestModeFromEnv(t *testing.T) {
+	defer os.Unsetenv(RecordEnv)
+
+	os.Setenv(RecordEnv, "1")
+	assert.Equal(t, ModeRecord, ModeFromEnv())
+	os.Setenv(RecordEnv, "")
+	assert.Equal(t, ModeReplay, ModeFromEnv())
+}
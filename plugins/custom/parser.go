@@ -2,6 +2,7 @@ package custom
 
 import (
 	"bufio"
+	"context"
 	"io"
 	"regexp"
 	"strconv"
@@ -95,6 +96,7 @@ func cardFilesToDeck(cards *CardFiles, name string, options map[string]interface
 		}
 		if cardInfo.Name != nil {
 			card.Name = *cardInfo.Name
+			card.Metadata.Name = *cardInfo.Name
 		}
 		deck.Cards = append(deck.Cards, card)
 	}
@@ -102,7 +104,7 @@ func cardFilesToDeck(cards *CardFiles, name string, options map[string]interface
 	return deck, nil
 }
 
-func fromList(file io.Reader, name string, options map[string]string) ([]*plugins.Deck, error) {
+func fromList(ctx context.Context, file io.Reader, name string, options map[string]string) ([]*plugins.Deck, error) {
 	// Check the options
 	validatedOptions, err := CustomPlugin.AvailableOptions().ValidateNormalize(options)
 	if err != nil {
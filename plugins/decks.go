@@ -0,0 +1,91 @@
+package plugins
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// DeckFileVersion is the version of the JSON representation of the decks
+// written by EncodeDecks. It is incremented when a backward incompatible
+// change is made to the format.
+const DeckFileVersion = 1
+
+// deckFile is the JSON document written by EncodeDecks.
+type deckFile struct {
+	Version int     `json:"version"`
+	Decks   []*Deck `json:"decks"`
+}
+
+// EncodeDecks writes the JSON representation of decks to w, so that they can
+// be loaded later on with DecodeDecks (e.g. to generate the TTS files
+// separately, or to let other tools build the decks).
+func EncodeDecks(w io.Writer, decks []*Deck) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(deckFile{
+		Version: DeckFileVersion,
+		Decks:   decks,
+	})
+}
+
+// DecodeDecks reads decks written by EncodeDecks from r.
+func DecodeDecks(r io.Reader) ([]*Deck, error) {
+	var file deckFile
+
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, fmt.Errorf("invalid deck file: %w", err)
+	}
+
+	if file.Version != DeckFileVersion {
+		return nil, fmt.Errorf("unsupported deck file version: %d (expected %d)", file.Version, DeckFileVersion)
+	}
+
+	for i, deck := range file.Decks {
+		if deck == nil {
+			return nil, fmt.Errorf("invalid deck file: deck %d is null", i)
+		}
+		if len(deck.Name) == 0 {
+			return nil, fmt.Errorf("invalid deck file: deck %d has no name", i)
+		}
+	}
+
+	return file.Decks, nil
+}
+
+// SetAuthor sets the author of each deck of decks, if author isn't empty.
+func SetAuthor(decks []*Deck, author string) {
+	if len(author) == 0 {
+		return
+	}
+
+	for _, deck := range decks {
+		deck.Author = author
+	}
+}
+
+// RenameDecks renames decks, the first one being the main deck and the others
+// its sections (e.g. "<main deck> - Sideboard"). The name of the main deck is
+// replaced by name if it isn't empty, and suffix is appended to it.
+func RenameDecks(decks []*Deck, name string, suffix string) {
+	if len(decks) == 0 || (len(name) == 0 && len(suffix) == 0) {
+		return
+	}
+
+	baseName := decks[0].Name
+	newName := baseName
+	if len(name) > 0 {
+		newName = name
+	}
+	newName += suffix
+
+	for _, deck := range decks {
+		if deck.Name == baseName {
+			deck.Name = newName
+		} else if strings.HasPrefix(deck.Name, baseName+" - ") {
+			deck.Name = newName + strings.TrimPrefix(deck.Name, baseName)
+		}
+	}
+}
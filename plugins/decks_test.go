@@ -0,0 +1,140 @@
+package plugins
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeDecodeDecks(t *testing.T) {
+	back := CardInfo{
+		Name:     "Insectile Aberration",
+		ImageURL: "https://example.com/back.png",
+		Count:    1,
+	}
+	decks := []*Deck{
+		{
+			Name: "Main",
+			Cards: []CardInfo{
+				{
+					Name:             "Delver of Secrets",
+					Description:      "Creature",
+					ImageURL:         "https://example.com/front.png",
+					Count:            4,
+					AlternativeState: &back,
+					Metadata: CardMetadata{
+						Name:   "Delver of Secrets",
+						Cost:   1,
+						Colors: []string{"U"},
+						Price:  0.5,
+					},
+				},
+			},
+			BackURL:   "https://example.com/card-back.png",
+			CardSize:  CardSizeSmall,
+			Rounded:   true,
+			Facing:    FacingUp,
+			Author:    "Someone",
+			Container: ContainerBag,
+			TemplateInfo: &TemplateInfo{
+				Templates: map[int]*Template{},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	assert.Nil(t, EncodeDecks(&buf, decks))
+	assert.Contains(t, buf.String(), `"cardSize": "small"`)
+	assert.Contains(t, buf.String(), `"facing": "up"`)
+	assert.Contains(t, buf.String(), `"container": "bag"`)
+	assert.NotContains(t, buf.String(), "TemplateInfo")
+
+	decoded, err := DecodeDecks(&buf)
+	assert.Nil(t, err)
+
+	// The template information isn't serialized
+	decks[0].TemplateInfo = nil
+	assert.Equal(t, decks, decoded)
+}
+
+func TestCardSize(t *testing.T) {
+	for _, size := range []CardSize{
+		CardSizeStandard,
+		CardSizeSmall,
+		CardSizeMini,
+		CardSizeSquare,
+		CardSizeTarot,
+		CardSizeJumbo,
+	} {
+		text, err := size.MarshalText()
+		assert.Nil(t, err)
+
+		var decoded CardSize
+		assert.Nil(t, decoded.UnmarshalText(text))
+		assert.Equal(t, size, decoded)
+	}
+
+	width, height := CardSizeTarot.Dimensions()
+	assert.Equal(t, 70.0, width)
+	assert.Equal(t, 120.0, height)
+
+	// Unknown sizes have the dimensions of the standard cards
+	width, height = CardSize(42).Dimensions()
+	assert.Equal(t, 63.5, width)
+	assert.Equal(t, 88.9, height)
+	_, err := CardSize(42).MarshalText()
+	assert.NotNil(t, err)
+}
+
+func TestDecodeDecksInvalid(t *testing.T) {
+	for _, contents := range []string{
+		``,
+		`{"decks": []}`,
+		`{"version": 2, "decks": []}`,
+		`{"version": 1, "decks": [null]}`,
+		`{"version": 1, "decks": [{"cards": []}]}`,
+		`{"version": 1, "decks": [{"name": "Test", "cardSize": "huge"}]}`,
+		`{"version": 1, "decks": [{"name": "Test", "facing": "sideways"}]}`,
+		`{"version": 1, "decks": [{"name": "Test", "container": "box"}]}`,
+	} {
+		_, err := DecodeDecks(strings.NewReader(contents))
+		assert.NotNil(t, err, contents)
+	}
+}
+
+func TestRenameDecks(t *testing.T) {
+	newDecks := func() []*Deck {
+		return []*Deck{
+			{Name: "Mono Red - Aggro | Site"},
+			{Name: "Mono Red - Aggro | Site - Sideboard"},
+			{Name: "Other"},
+		}
+	}
+	names := func(decks []*Deck) []string {
+		names := make([]string, 0, len(decks))
+		for _, deck := range decks {
+			names = append(names, deck.Name)
+		}
+		return names
+	}
+
+	decks := newDecks()
+	RenameDecks(decks, "", "")
+	assert.Equal(t, []string{"Mono Red - Aggro | Site", "Mono Red - Aggro | Site - Sideboard", "Other"}, names(decks))
+
+	decks = newDecks()
+	RenameDecks(decks, "Burn", "")
+	assert.Equal(t, []string{"Burn", "Burn - Sideboard", "Other"}, names(decks))
+
+	decks = newDecks()
+	RenameDecks(decks, "Burn", " (v2)")
+	assert.Equal(t, []string{"Burn (v2)", "Burn (v2) - Sideboard", "Other"}, names(decks))
+
+	decks = newDecks()
+	RenameDecks(decks, "", " (v2)")
+	assert.Equal(t, []string{"Mono Red - Aggro | Site (v2)", "Mono Red - Aggro | Site (v2) - Sideboard", "Other"}, names(decks))
+
+	RenameDecks(nil, "Burn", "")
+}
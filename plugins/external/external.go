@@ -0,0 +1,211 @@
+// Package external loads deck plugins from executables, so that new games or
+// websites can be supported without modifying tts-deckconverter.
+//
+// An external plugin is an executable implementing two commands:
+//
+//   - "describe" writes a Description (JSON) to the standard output.
+//   - "parse" reads a Request (JSON) from the standard input, and writes a
+//     Response (JSON) to the standard output.
+//
+// Anything written to the standard error is logged.
+package external
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/jeandeaual/tts-deckconverter/log"
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+type externalPlugin struct {
+	path        string
+	description Description
+	urlHandlers []plugins.URLHandler
+	options     plugins.Options
+	backs       map[string]plugins.Back
+}
+
+// run executes the plugin with the given argument, writing input to its
+// standard input and decoding its standard output to output.
+func run(ctx context.Context, path string, arg string, input interface{}, output interface{}) error {
+	cmd := exec.CommandContext(ctx, path, arg)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if input != nil {
+		data, err := json.Marshal(input)
+		if err != nil {
+			return err
+		}
+		cmd.Stdin = bytes.NewReader(data)
+	}
+
+	err := cmd.Run()
+	if stderr.Len() > 0 {
+		log.Debugf("%s %s: %s", path, arg, strings.TrimSpace(stderr.String()))
+	}
+	if err != nil {
+		return fmt.Errorf("couldn't run %s %s: %w", path, arg, err)
+	}
+
+	err = json.Unmarshal(stdout.Bytes(), output)
+	if err != nil {
+		return fmt.Errorf("invalid output from %s %s: %w", path, arg, err)
+	}
+
+	return nil
+}
+
+// Load runs the executable located at path to retrieve its description, and
+// returns the corresponding plugin.
+func Load(path string) (plugins.Plugin, error) {
+	p := &externalPlugin{
+		path:    path,
+		options: make(plugins.Options),
+		backs:   make(map[string]plugins.Back),
+	}
+
+	err := run(context.Background(), path, "describe", nil, &p.description)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(p.description.ID) == 0 {
+		return nil, fmt.Errorf("no plugin ID returned by %s", path)
+	}
+	if len(p.description.Name) == 0 {
+		p.description.Name = p.description.ID
+	}
+
+	for _, handler := range p.description.URLHandlers {
+		regex, err := regexp.Compile(handler.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid URL regex for plugin %s: %w", p.description.ID, err)
+		}
+
+		p.urlHandlers = append(p.urlHandlers, plugins.URLHandler{
+			BasePath: handler.BasePath,
+			Regex:    regex,
+			Handler:  p.parseURL,
+		})
+	}
+
+	for key, option := range p.description.Options {
+		p.options[key], err = option.toOption()
+		if err != nil {
+			return nil, fmt.Errorf("invalid option %s for plugin %s: %w", key, p.description.ID, err)
+		}
+	}
+
+	for key, back := range p.description.Backs {
+		p.backs[key] = plugins.Back{
+			URL:         back.URL,
+			Description: back.Description,
+		}
+	}
+
+	log.Debugf("Loaded plugin %s from %s", p.description.ID, path)
+
+	return p, nil
+}
+
+func (p *externalPlugin) parse(ctx context.Context, request Request) ([]*plugins.Deck, error) {
+	// Check the options
+	_, err := p.options.ValidateNormalize(request.Options)
+	if err != nil {
+		return nil, err
+	}
+
+	var response Response
+
+	err = run(ctx, p.path, "parse", request, &response)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(response.Error) > 0 {
+		return nil, fmt.Errorf("plugin %s: %s", p.description.ID, response.Error)
+	}
+
+	decks := make([]*plugins.Deck, 0, len(response.Decks))
+	for _, d := range response.Decks {
+		deck, err := d.toDeck(p.backs[plugins.DefaultBackKey].URL)
+		if err != nil {
+			return nil, err
+		}
+		decks = append(decks, deck)
+	}
+
+	return decks, nil
+}
+
+func (p *externalPlugin) parseURL(ctx context.Context, url string, options map[string]string) ([]*plugins.Deck, error) {
+	return p.parse(ctx, Request{
+		URL:     url,
+		Options: options,
+	})
+}
+
+func (p *externalPlugin) parseFile(ctx context.Context, file io.Reader, name string, options map[string]string) ([]*plugins.Deck, error) {
+	content, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.parse(ctx, Request{
+		Name:    name,
+		Content: string(content),
+		Options: options,
+	})
+}
+
+func (p *externalPlugin) PluginID() string {
+	return p.description.ID
+}
+
+func (p *externalPlugin) PluginName() string {
+	return p.description.Name
+}
+
+func (p *externalPlugin) URLHandlers() []plugins.URLHandler {
+	return p.urlHandlers
+}
+
+func (p *externalPlugin) FileExtHandlers() map[string]plugins.FileHandler {
+	handlers := make(map[string]plugins.FileHandler, len(p.description.FileExtensions))
+
+	for _, ext := range p.description.FileExtensions {
+		handlers[ext] = p.parseFile
+	}
+
+	return handlers
+}
+
+func (p *externalPlugin) DeckTypeHandlers() map[string]plugins.DeckType {
+	return map[string]plugins.DeckType{}
+}
+
+func (p *externalPlugin) GenericFileHandler() plugins.DeckType {
+	return plugins.DeckType{
+		FileHandler: p.parseFile,
+		Example:     p.description.Example,
+	}
+}
+
+func (p *externalPlugin) AvailableOptions() plugins.Options {
+	return p.options
+}
+
+func (p *externalPlugin) AvailableBacks() map[string]plugins.Back {
+	return p.backs
+}
@@ -0,0 +1,88 @@
+package external
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+const testPlugin = `#!/bin/sh
+case "$1" in
+describe)
+	cat <<'JSON'
+{"id": "test", "name": "Test", "fileExtensions": [".test"],
+	"urlHandlers": [{"basePath": "https://test.example.com", "regex": "^https://test\\.example\\.com/"}],
+	"options": {"foil": {"type": "bool", "description": "foil cards", "default": false}},
+	"backs": {"default": {"url": "https://example.com/back.png", "description": "default back"}}}
+JSON
+	;;
+parse)
+	if grep -q '"content":"error"' -; then
+		echo '{"error": "invalid deck"}'
+	else
+		echo '{"decks": [{"name": "Deck", "cardSize": "small", "container": "tiles", "cards": [{"name": "Card", "imageURL": "https://example.com/card.png", "count": 2}]}]}'
+	fi
+	;;
+esac
+`
+
+func writePlugin(t *testing.T) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts can't be executed on Windows")
+	}
+
+	dir, err := ioutil.TempDir("", "external")
+	assert.Nil(t, err)
+
+	path := filepath.Join(dir, "plugin")
+	assert.Nil(t, ioutil.WriteFile(path, []byte(testPlugin), 0o755))
+
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writePlugin(t)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	plugin, err := Load(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "test", plugin.PluginID())
+	assert.Equal(t, "Test", plugin.PluginName())
+	assert.Len(t, plugin.URLHandlers(), 1)
+	assert.True(t, plugin.URLHandlers()[0].Regex.MatchString("https://test.example.com/deck/1"))
+	assert.Contains(t, plugin.FileExtHandlers(), ".test")
+	assert.Equal(t, plugins.OptionTypeBool, plugin.AvailableOptions()["foil"].Type)
+
+	decks, err := plugin.GenericFileHandler().FileHandler(context.Background(), strings.NewReader("1 Card"), "Deck", nil)
+	assert.Nil(t, err)
+	assert.Len(t, decks, 1)
+	assert.Equal(t, "Deck", decks[0].Name)
+	assert.Equal(t, "https://example.com/back.png", decks[0].BackURL)
+	assert.Equal(t, plugins.CardSizeSmall, decks[0].CardSize)
+	assert.Equal(t, plugins.ContainerTiles, decks[0].Container)
+	assert.Equal(t, []plugins.CardInfo{{
+		Name:     "Card",
+		ImageURL: "https://example.com/card.png",
+		Count:    2,
+		Metadata: plugins.CardMetadata{Name: "Card"},
+	}}, decks[0].Cards)
+
+	_, err = plugin.GenericFileHandler().FileHandler(context.Background(), strings.NewReader("error"), "Deck", nil)
+	assert.NotNil(t, err)
+
+	_, err = plugin.GenericFileHandler().FileHandler(context.Background(), strings.NewReader("1 Card"), "Deck", map[string]string{"invalid": "1"})
+	assert.NotNil(t, err)
+}
+
+func TestLoadInvalid(t *testing.T) {
+	_, err := Load(filepath.Join(os.TempDir(), "missing-plugin"))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,189 @@
+package external
+
+import (
+	"fmt"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+// Description is written by an external plugin to its standard output when
+// called with the "describe" argument.
+type Description struct {
+	// ID of the plugin, used as the mode.
+	ID string `json:"id"`
+	// Name of the plugin.
+	Name string `json:"name"`
+	// URLHandlers are the websites supported by the plugin.
+	URLHandlers []URLHandler `json:"urlHandlers"`
+	// FileExtensions are the file extensions (e.g. ".dek") supported by the
+	// plugin.
+	FileExtensions []string `json:"fileExtensions"`
+	// Options available for the plugin.
+	Options map[string]Option `json:"options"`
+	// Backs are the card backs available for the plugin. The "default" key
+	// is used when no back is selected.
+	Backs map[string]Back `json:"backs"`
+	// Example of a deck list, displayed to the user.
+	Example string `json:"example"`
+}
+
+// URLHandler describes a website supported by an external plugin.
+type URLHandler struct {
+	// BasePath is the main page of the supported website.
+	BasePath string `json:"basePath"`
+	// Regex used to recognize supported URLs.
+	Regex string `json:"regex"`
+}
+
+// Option of an external plugin.
+type Option struct {
+	// Type of the option ("enum", "bool", "int" or "string").
+	Type string `json:"type"`
+	// Description of the option.
+	Description string `json:"description"`
+	// DefaultValue of the option.
+	DefaultValue interface{} `json:"default"`
+	// AllowedValues should only be set when Type is "enum".
+	AllowedValues []string `json:"allowedValues"`
+}
+
+// Back is a card back of an external plugin.
+type Back struct {
+	// URL of the card back.
+	URL string `json:"url"`
+	// Description of the card back.
+	Description string `json:"description"`
+}
+
+// Request is written to the standard input of an external plugin called
+// with the "parse" argument. Either URL or Content is set.
+type Request struct {
+	// URL of the deck to parse.
+	URL string `json:"url,omitempty"`
+	// Name of the deck, when parsing a file.
+	Name string `json:"name,omitempty"`
+	// Content of the deck file to parse.
+	Content string `json:"content,omitempty"`
+	// Options selected by the user.
+	Options map[string]string `json:"options"`
+}
+
+// Response is written by an external plugin to its standard output in
+// response to a Request.
+type Response struct {
+	// Decks parsed by the plugin.
+	Decks []Deck `json:"decks"`
+	// Error is set if the deck couldn't be parsed.
+	Error string `json:"error,omitempty"`
+}
+
+// Deck parsed by an external plugin.
+type Deck struct {
+	Name         string `json:"name"`
+	Cards        []Card `json:"cards"`
+	BackURL      string `json:"backURL"`
+	ThumbnailURL string `json:"thumbnailURL"`
+	// CardSize is either "standard" (the default), "small", "mini",
+	// "square", "tarot" or "jumbo".
+	CardSize string `json:"cardSize"`
+	Rounded  bool   `json:"rounded"`
+	// Container is either "deck" (the default), "states", "bag", "tiles"
+	// (e.g. for dungeons), "boards" (e.g. for playmats) or "figurines"
+	// (e.g. for tokens).
+	Container string `json:"container"`
+}
+
+// Card parsed by an external plugin.
+type Card struct {
+	Name             string   `json:"name"`
+	Description      string   `json:"description"`
+	ImageURL         string   `json:"imageURL"`
+	Count            int      `json:"count"`
+	AlternativeState *Card    `json:"alternativeState"`
+	Oversized        bool     `json:"oversized"`
+	Type             string   `json:"type"`
+	Cost             float64  `json:"cost"`
+	Colors           []string `json:"colors"`
+	Price            float64  `json:"price"`
+}
+
+func (o Option) toOption() (plugins.Option, error) {
+	option := plugins.Option{
+		Description:   o.Description,
+		DefaultValue:  o.DefaultValue,
+		AllowedValues: o.AllowedValues,
+	}
+
+	switch o.Type {
+	case "enum":
+		option.Type = plugins.OptionTypeEnum
+	case "bool":
+		option.Type = plugins.OptionTypeBool
+	case "int":
+		option.Type = plugins.OptionTypeInt
+		// JSON numbers are decoded as float64
+		if value, ok := o.DefaultValue.(float64); ok {
+			option.DefaultValue = int(value)
+		}
+	case "string":
+		option.Type = plugins.OptionTypeString
+	default:
+		return option, fmt.Errorf("invalid option type: %s", o.Type)
+	}
+
+	return option, nil
+}
+
+func (c Card) toCardInfo() plugins.CardInfo {
+	card := plugins.CardInfo{
+		Name:        c.Name,
+		Description: c.Description,
+		ImageURL:    c.ImageURL,
+		Count:       c.Count,
+		Oversized:   c.Oversized,
+		Metadata: plugins.CardMetadata{
+			Name:   c.Name,
+			Type:   c.Type,
+			Cost:   c.Cost,
+			Colors: c.Colors,
+			Price:  c.Price,
+		},
+	}
+
+	if card.Count == 0 {
+		card.Count = 1
+	}
+
+	if c.AlternativeState != nil {
+		alternativeState := c.AlternativeState.toCardInfo()
+		card.AlternativeState = &alternativeState
+	}
+
+	return card
+}
+
+func (d Deck) toDeck(defaultBackURL string) (*plugins.Deck, error) {
+	deck := &plugins.Deck{
+		Name:         d.Name,
+		BackURL:      d.BackURL,
+		ThumbnailURL: d.ThumbnailURL,
+		Rounded:      d.Rounded,
+	}
+
+	if len(deck.BackURL) == 0 {
+		deck.BackURL = defaultBackURL
+	}
+
+	if err := deck.CardSize.UnmarshalText([]byte(d.CardSize)); err != nil {
+		return nil, fmt.Errorf("invalid card size for deck %s: %s", d.Name, d.CardSize)
+	}
+	if err := deck.Container.UnmarshalText([]byte(d.Container)); err != nil {
+		return nil, fmt.Errorf("invalid container for deck %s: %s", d.Name, d.Container)
+	}
+
+	for _, card := range d.Cards {
+		deck.Cards = append(deck.Cards, card.toCardInfo())
+	}
+
+	return deck, nil
+}
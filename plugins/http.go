@@ -0,0 +1,204 @@
+package plugins
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// DefaultUserAgent is the User-Agent header sent with each request, unless
+// another one is set with SetUserAgent or registered for the host with
+// AddHostHeader. Some websites reject the requests which don't identify
+// their client.
+const DefaultUserAgent = "tts-deckconverter (+https://github.com/jeandeaual/tts-deckconverter)"
+
+var (
+	// hostHeaders maps a host name to the headers sent with each request to
+	// this host.
+	hostHeaders      = make(map[string]http.Header)
+	userAgent        = DefaultUserAgent
+	hostHeadersMutex sync.RWMutex
+)
+
+// SetUserAgent replaces the User-Agent header sent with each request
+// (DefaultUserAgent if userAgent is empty), including the requests sent by
+// the API clients setting their own.
+func SetUserAgent(ua string) {
+	hostHeadersMutex.Lock()
+	defer hostHeadersMutex.Unlock()
+
+	if len(ua) == 0 {
+		ua = DefaultUserAgent
+	}
+	userAgent = ua
+}
+
+// AddHostHeader registers a header sent with every request to host (and its
+// subdomains), e.g. an authorization header used to access a private deck.
+// It replaces the header of the same name set by the plugin, if any (e.g.
+// the User-Agent).
+func AddHostHeader(host, key, value string) {
+	hostHeadersMutex.Lock()
+	defer hostHeadersMutex.Unlock()
+
+	host = strings.ToLower(host)
+
+	header, found := hostHeaders[host]
+	if !found {
+		header = make(http.Header)
+		hostHeaders[host] = header
+	}
+	header.Add(key, value)
+}
+
+// AddHostCookie registers a cookie sent with every request to host (and its
+// subdomains).
+func AddHostCookie(host, name, value string) {
+	AddHostHeader(host, "Cookie", (&http.Cookie{Name: name, Value: value}).String())
+}
+
+// matchesHost checks if requestHost is host or one of its subdomains.
+func matchesHost(requestHost, host string) bool {
+	return requestHost == host || strings.HasSuffix(requestHost, "."+host)
+}
+
+// headersForHost returns the headers registered for requestHost, including
+// the User-Agent.
+func headersForHost(requestHost string) http.Header {
+	hostHeadersMutex.RLock()
+	defer hostHeadersMutex.RUnlock()
+
+	requestHost = strings.ToLower(requestHost)
+	headers := make(http.Header)
+	headers.Set("User-Agent", userAgent)
+
+	for host, header := range hostHeaders {
+		if !matchesHost(requestHost, host) {
+			continue
+		}
+		for key, values := range header {
+			if key == "User-Agent" {
+				// Replace the default User-Agent
+				headers.Del(key)
+			}
+			for _, value := range values {
+				headers.Add(key, value)
+			}
+		}
+	}
+
+	return headers
+}
+
+// hostHeaderTransport adds the User-Agent and the headers registered with
+// AddHostHeader to each request.
+type hostHeaderTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t hostHeaderTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	headers := headersForHost(req.URL.Hostname())
+
+	// RoundTrippers shouldn't modify the original request
+	req = req.Clone(req.Context())
+	for key, values := range headers {
+		if key == "Cookie" {
+			// Cookies need to be joined in a single header
+			cookies := values
+			if existing := req.Header.Get(key); len(existing) > 0 {
+				cookies = append([]string{existing}, cookies...)
+			}
+			req.Header.Set(key, strings.Join(cookies, "; "))
+			continue
+		}
+		req.Header.Del(key)
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+
+	return base.RoundTrip(req)
+}
+
+// currentHTTPClient contains the *http.Client returned by HTTPClient.
+// An atomic.Value is used so that SetHTTPClient can be called while requests
+// are being sent.
+var currentHTTPClient atomic.Value
+
+// defaultTransport is the transport of HTTPClient, unless another one is
+// set with SetHTTPClient. It uses the proxies registered with SetHostProxy.
+var defaultTransport = newDefaultTransport()
+
+func init() {
+	SetHTTPClient(nil)
+}
+
+// HTTPClient returns the HTTP client used by the plugins to query websites
+// and APIs, and to download the card images.
+// Use SetHTTPClient to replace it.
+func HTTPClient() *http.Client {
+	return currentHTTPClient.Load().(*http.Client)
+}
+
+// SetHTTPClient replaces the HTTP client used by the plugins and the image
+// downloads, e.g. to record and replay requests in tests, to use a proxy or
+// to collect metrics. The headers registered with AddHostHeader are still
+// added to each request sent through client, the failed requests are still
+// retried (see WithRetryBudget) and the responses are still cached (see
+// SetHTTPCache).
+// Setting a nil client restores the default one.
+// The Pokémon plugin is the only one not using it, since the Pokémon TCG SDK
+// creates its own client.
+func SetHTTPClient(client *http.Client) {
+	if client == nil {
+		client = &http.Client{}
+	}
+
+	base := client.Transport
+	if base == nil {
+		base = defaultTransport
+	}
+
+	wrapped := *client
+	wrapped.Transport = hostHeaderTransport{base: cacheTransport{base: retryTransport{base: base}}}
+	currentHTTPClient.Store(&wrapped)
+}
+
+// SetHTTPTransport replaces the transport of the HTTP client used by the
+// plugins and the image downloads. See SetHTTPClient.
+func SetHTTPTransport(transport http.RoundTripper) {
+	SetHTTPClient(&http.Client{Transport: transport})
+}
+
+// LoadURL retrieves and parses the HTML document located at url, using
+// HTTPClient.
+func LoadURL(ctx context.Context, url string) (*html.Node, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := HTTPClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	r, err := charset.NewReader(resp.Body, resp.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+
+	return html.Parse(r)
+}
@@ -0,0 +1,131 @@
+package plugins
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingTransport struct {
+	req *http.Request
+}
+
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.req = req
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       ioutil.NopCloser(strings.NewReader("<html><body></body></html>")),
+	}, nil
+}
+
+func TestMatchesHost(t *testing.T) {
+	assert.True(t, matchesHost("example.com", "example.com"))
+	assert.True(t, matchesHost("api.example.com", "example.com"))
+	assert.False(t, matchesHost("badexample.com", "example.com"))
+	assert.False(t, matchesHost("example.org", "example.com"))
+}
+
+func TestHostHeaderTransport(t *testing.T) {
+	AddHostHeader("private.example.com", "Authorization", "Bearer token")
+	AddHostCookie("private.example.com", "session", "abc")
+	AddHostCookie("private.example.com", "user", "def")
+
+	recorder := &recordingTransport{}
+	transport := hostHeaderTransport{base: recorder}
+
+	req, err := http.NewRequest("GET", "https://api.private.example.com/deck", nil)
+	assert.Nil(t, err)
+	_, err = transport.RoundTrip(req)
+	assert.Nil(t, err)
+	assert.Equal(t, "Bearer token", recorder.req.Header.Get("Authorization"))
+	assert.Equal(t, "session=abc; user=def", recorder.req.Header.Get("Cookie"))
+	// The original request must be left untouched
+	assert.Empty(t, req.Header.Get("Authorization"))
+
+	req, err = http.NewRequest("GET", "https://public.example.com/deck", nil)
+	assert.Nil(t, err)
+	_, err = transport.RoundTrip(req)
+	assert.Nil(t, err)
+	assert.Empty(t, recorder.req.Header.Get("Authorization"))
+	assert.Empty(t, recorder.req.Header.Get("Cookie"))
+}
+
+func TestLoadURLCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := LoadURL(ctx, "https://example.com")
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestSetHTTPTransport(t *testing.T) {
+	defer SetHTTPClient(nil)
+
+	AddHostHeader("replay.example.com", "X-Test", "value")
+
+	recorder := &recordingTransport{}
+	SetHTTPTransport(recorder)
+
+	_, err := LoadURL(context.Background(), "https://replay.example.com/deck")
+	assert.Nil(t, err)
+	assert.NotNil(t, recorder.req)
+	assert.Equal(t, "value", recorder.req.Header.Get("X-Test"))
+}
+
+func TestSetHTTPClientConcurrent(t *testing.T) {
+	defer SetHTTPClient(nil)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			SetHTTPTransport(&recordingTransport{})
+		}()
+		go func() {
+			defer wg.Done()
+			assert.NotNil(t, HTTPClient())
+		}()
+	}
+	wg.Wait()
+
+	// A nil client restores the default transport
+	SetHTTPClient(nil)
+	transport, ok := HTTPClient().Transport.(hostHeaderTransport)
+	if assert.True(t, ok) {
+		assert.Equal(t, cacheTransport{base: retryTransport{base: defaultTransport}}, transport.base)
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	AddHostHeader("strict.example.com", "User-Agent", "my-client/1.0")
+
+	recorder := &recordingTransport{}
+	transport := hostHeaderTransport{base: recorder}
+
+	req, err := http.NewRequest("GET", "https://example.com/deck", nil)
+	assert.Nil(t, err)
+	req.Header.Set("User-Agent", "go-scryfall")
+	_, err = transport.RoundTrip(req)
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultUserAgent, recorder.req.Header.Get("User-Agent"))
+
+	SetUserAgent("custom/2.0")
+	defer SetUserAgent("")
+
+	_, err = transport.RoundTrip(req)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"custom/2.0"}, recorder.req.Header.Values("User-Agent"))
+
+	req, err = http.NewRequest("GET", "https://www.strict.example.com/deck", nil)
+	assert.Nil(t, err)
+	_, err = transport.RoundTrip(req)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"my-client/1.0"}, recorder.req.Header.Values("User-Agent"))
+}
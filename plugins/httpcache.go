@@ -0,0 +1,405 @@
+package plugins
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jeandeaual/tts-deckconverter/cache"
+	"github.com/jeandeaual/tts-deckconverter/log"
+)
+
+// maxHeuristicFreshness is the maximum time a cached response without any
+// explicit expiration time (but with a Last-Modified header) is used
+// without being revalidated.
+const maxHeuristicFreshness = 24 * time.Hour
+
+var (
+	httpCacheDir      string
+	httpCacheDirMutex sync.RWMutex
+)
+
+// SetHTTPCache enables the on-disk cache of the responses to the GET
+// requests sent through HTTPClient (the API responses and the card images),
+// stored inside dir (see cache.DefaultDir). The cached responses are reused
+// while they are fresh, then revalidated with their ETag or Last-Modified
+// header. The cache is disabled if dir is empty.
+// The requests sending credentials (cookies or an Authorization header) are
+// never cached.
+func SetHTTPCache(dir string) {
+	httpCacheDirMutex.Lock()
+	defer httpCacheDirMutex.Unlock()
+
+	httpCacheDir = dir
+}
+
+func getHTTPCacheDir() string {
+	httpCacheDirMutex.RLock()
+	defer httpCacheDirMutex.RUnlock()
+
+	return httpCacheDir
+}
+
+// cacheTransport serves the responses cached by SetHTTPCache, and caches
+// the responses of base.
+type cacheTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	dir := getHTTPCacheDir()
+	if len(dir) == 0 || !cacheableRequest(req) {
+		return base.RoundTrip(req)
+	}
+
+	key := cacheKey(req)
+	cached, cachedName, storedAt := loadCachedResponse(dir, key, req)
+
+	if cached != nil {
+		if isFresh(cached.Header, storedAt) {
+			RecordCacheLookup(cachedName, true)
+			return cached, nil
+		}
+
+		// Revalidate the cached response
+		req = req.Clone(req.Context())
+		if etag := cached.Header.Get("ETag"); len(etag) > 0 {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if lastModified := cached.Header.Get("Last-Modified"); len(lastModified) > 0 {
+			req.Header.Set("If-Modified-Since", lastModified)
+		}
+	}
+
+	resp, err := base.RoundTrip(req)
+	if err != nil {
+		if cached != nil && req.Context().Err() == nil {
+			log.Debugf("Using the cached response of %s: %v", req.URL, err)
+			RecordCacheLookup(cachedName, true)
+			return cached, nil
+		}
+		return nil, err
+	}
+
+	if cached != nil && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		// Restart the freshness period of the cached response
+		now := time.Now()
+		_ = os.Chtimes(filepath.Join(cache.Dir(dir, cachedName), key), now, now)
+		RecordCacheLookup(cachedName, true)
+		return cached, nil
+	}
+
+	name := responseCacheName(resp)
+	RecordCacheLookup(name, false)
+
+	if resp.StatusCode != http.StatusOK || cacheControl(resp.Header)["no-store"] {
+		return resp, nil
+	}
+
+	staleName := ""
+	if cached != nil && cachedName != name {
+		staleName = cachedName
+	}
+	if err := cacheBody(dir, name, key, staleName, resp); err != nil {
+		log.Debugf("Couldn't cache the response of %s: %v", req.URL, err)
+	}
+
+	return resp, nil
+}
+
+// cacheableRequest checks if the response to req can be cached.
+func cacheableRequest(req *http.Request) bool {
+	if req.Method != http.MethodGet {
+		return false
+	}
+
+	for _, header := range []string{"Authorization", "Cookie", "Range"} {
+		if len(req.Header.Get(header)) > 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// cacheKey returns the name of the file caching the response to req.
+func cacheKey(req *http.Request) string {
+	sum := sha256.Sum256([]byte(req.URL.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// responseCacheName returns the name of the cache storing resp: the card
+// images are stored separately from the API responses.
+func responseCacheName(resp *http.Response) string {
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "image/") {
+		return cache.Images
+	}
+	return cache.HTTP
+}
+
+// cacheControl returns the set of directives of the Cache-Control header
+// (e.g. "no-store" or "max-age=60"), in lower case.
+func cacheControl(header http.Header) map[string]bool {
+	directives := make(map[string]bool)
+
+	for _, value := range header[http.CanonicalHeaderKey("Cache-Control")] {
+		for _, directive := range strings.Split(value, ",") {
+			directive = strings.ToLower(strings.TrimSpace(directive))
+			if len(directive) > 0 {
+				directives[directive] = true
+			}
+		}
+	}
+
+	return directives
+}
+
+// maxAge returns the max-age directive of the Cache-Control header, and false
+// if there is none.
+func maxAge(header http.Header) (time.Duration, bool) {
+	for directive := range cacheControl(header) {
+		if !strings.HasPrefix(directive, "max-age=") {
+			continue
+		}
+		seconds, err := strconv.Atoi(strings.Trim(strings.TrimPrefix(directive, "max-age="), `"`))
+		if err != nil {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	return 0, false
+}
+
+// isFresh checks if a response with header, cached at storedAt (or last
+// revalidated at storedAt), can be used without being revalidated.
+func isFresh(header http.Header, storedAt time.Time) bool {
+	if cacheControl(header)["no-cache"] {
+		return false
+	}
+
+	age := time.Since(storedAt)
+
+	if lifetime, found := maxAge(header); found {
+		return age < lifetime
+	}
+
+	date, err := http.ParseTime(header.Get("Date"))
+	if err != nil {
+		return false
+	}
+
+	if expires, err := http.ParseTime(header.Get("Expires")); err == nil {
+		return age < expires.Sub(date)
+	}
+
+	// Without an explicit expiration time, a resource which hasn't changed
+	// for a long time is unlikely to change soon
+	if lastModified, err := http.ParseTime(header.Get("Last-Modified")); err == nil {
+		lifetime := date.Sub(lastModified) / 10
+		if lifetime > maxHeuristicFreshness {
+			lifetime = maxHeuristicFreshness
+		}
+		return age < lifetime
+	}
+
+	return false
+}
+
+// loadCachedResponse returns the cached response to req stored under key,
+// the name of its cache and the time it was stored (or last revalidated).
+// The response is nil if it isn't cached.
+func loadCachedResponse(dir, key string, req *http.Request) (*http.Response, string, time.Time) {
+	for _, name := range []string{cache.HTTP, cache.Images} {
+		path := filepath.Join(cache.Dir(dir, name), key)
+
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+
+		resp, err := readCachedResponse(path, req)
+		if err != nil {
+			log.Debugf("Ignoring the invalid cached response of %s: %v", req.URL, err)
+			continue
+		}
+
+		return resp, name, info.ModTime()
+	}
+
+	return nil, "", time.Time{}
+}
+
+// readCachedResponse reads the response to req stored at path.
+func readCachedResponse(path string, req *http.Request) (*http.Response, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), req)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	return resp, nil
+}
+
+// maxCacheDrain is the maximum number of bytes of a response body read when
+// it is closed before its end (e.g. by a JSON decoder leaving the final new
+// line), so that the response can still be cached.
+const maxCacheDrain = 64 * 1024
+
+// cacheBody replaces the body of resp so that it is written to the cache
+// called name inside dir, under key, while the caller reads it. The body
+// isn't loaded in memory, and the response is only cached once its body has
+// been read until the end: a download stopped early (e.g. because of its
+// size) isn't cached. The response cached in the cache called staleName, if
+// any, is removed once resp is cached.
+func cacheBody(dir, name, key, staleName string, resp *http.Response) error {
+	folder := cache.Dir(dir, name)
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		return err
+	}
+
+	// Don't leave a truncated response if the download is interrupted
+	file, err := ioutil.TempFile(folder, key+".*.partial")
+	if err != nil {
+		return err
+	}
+
+	// The length of the body isn't always known, it is read until the end
+	// of the file when the response is loaded
+	header := resp.Header.Clone()
+	header.Del("Content-Length")
+	header.Del("Transfer-Encoding")
+
+	w := bufio.NewWriter(file)
+	_, err = fmt.Fprintf(w, "HTTP/%d.%d %s\r\n", resp.ProtoMajor, resp.ProtoMinor, resp.Status)
+	if err == nil {
+		err = header.Write(w)
+	}
+	if err == nil {
+		_, err = w.WriteString("\r\n")
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return err
+	}
+
+	resp.Body = &cachingBody{
+		body:   resp.Body,
+		file:   file,
+		writer: w,
+		path:   filepath.Join(folder, key),
+		stale:  staleName,
+		dir:    dir,
+		key:    key,
+	}
+
+	return nil
+}
+
+// cachingBody is a response body written to a cache file while it is read.
+type cachingBody struct {
+	body   io.ReadCloser
+	file   *os.File
+	writer *bufio.Writer
+	// path of the cache file, written once the whole body has been read.
+	path string
+	// stale is the name of the cache whose response is replaced, if any.
+	stale string
+	dir   string
+	key   string
+	// done is true once the cache file has been written or discarded.
+	done bool
+}
+
+// Read implements the io.Reader interface.
+func (b *cachingBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+
+	if !b.done {
+		if n > 0 {
+			if _, werr := b.writer.Write(p[:n]); werr != nil {
+				log.Debugf("Couldn't cache the response: %v", werr)
+				b.discard()
+			}
+		}
+		if err == io.EOF {
+			b.commit()
+		} else if err != nil {
+			b.discard()
+		}
+	}
+
+	return n, err
+}
+
+// Close implements the io.Closer interface.
+func (b *cachingBody) Close() error {
+	if !b.done {
+		// Read what is left of a body which is almost completely read
+		_, _ = io.Copy(ioutil.Discard, io.LimitReader(b, maxCacheDrain))
+		if !b.done {
+			b.discard()
+		}
+	}
+
+	return b.body.Close()
+}
+
+// commit moves the cache file to its final location.
+func (b *cachingBody) commit() {
+	b.done = true
+
+	err := b.writer.Flush()
+	if cerr := b.file.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(b.file.Name(), b.path)
+	}
+	if err != nil {
+		log.Debugf("Couldn't cache the response: %v", err)
+		os.Remove(b.file.Name())
+		return
+	}
+
+	if len(b.stale) > 0 {
+		_ = os.Remove(filepath.Join(cache.Dir(b.dir, b.stale), b.key))
+	}
+}
+
+// discard removes the incomplete cache file.
+func (b *cachingBody) discard() {
+	b.done = true
+	b.file.Close()
+	os.Remove(b.file.Name())
+}
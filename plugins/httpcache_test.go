@@ -0,0 +1,167 @@
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jeandeaual/tts-deckconverter/cache"
+)
+
+func TestIsFresh(t *testing.T) {
+	now := time.Now()
+	header := func(values ...string) http.Header {
+		h := make(http.Header)
+		for i := 0; i < len(values); i += 2 {
+			h.Set(values[i], values[i+1])
+		}
+		return h
+	}
+
+	assert.True(t, isFresh(header("Cache-Control", "public, max-age=60"), now))
+	assert.False(t, isFresh(header("Cache-Control", "max-age=60"), now.Add(-time.Minute)))
+	assert.False(t, isFresh(header("Cache-Control", "no-cache, max-age=60"), now))
+
+	date := now.UTC().Format(http.TimeFormat)
+	assert.True(t, isFresh(header("Date", date, "Expires", now.Add(time.Hour).UTC().Format(http.TimeFormat)), now))
+	assert.False(t, isFresh(header("Date", date, "Expires", date), now))
+
+	// Heuristic freshness, capped to a day
+	lastModified := now.Add(-100 * 24 * time.Hour).UTC().Format(http.TimeFormat)
+	assert.True(t, isFresh(header("Date", date, "Last-Modified", lastModified), now.Add(-time.Hour)))
+	assert.False(t, isFresh(header("Date", date, "Last-Modified", lastModified), now.Add(-25*time.Hour)))
+
+	assert.False(t, isFresh(header("ETag", `"a"`), now))
+}
+
+func TestCacheTransport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "httpcache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	SetHTTPCache(dir)
+	defer SetHTTPCache("")
+
+	requests := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+
+		switch r.URL.Path {
+		case "/fresh.json":
+			w.Header().Set("Cache-Control", "max-age=3600")
+			_, _ = w.Write([]byte(`{"fresh":true}`))
+		case "/etag.json":
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+			_, _ = w.Write([]byte(`{"etag":true}`))
+		case "/card.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Header().Set("Cache-Control", "max-age=3600")
+			_, _ = w.Write([]byte("png"))
+		case "/chunked.json":
+			// Sent without a Content-Length
+			w.Header().Set("Cache-Control", "max-age=3600")
+			_, _ = w.Write([]byte(`{"chunked":`))
+			w.(http.Flusher).Flush()
+			_, _ = w.Write([]byte(`true}`))
+		case "/large.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Header().Set("Cache-Control", "max-age=3600")
+			w.(http.Flusher).Flush()
+			_, _ = w.Write(bytes.Repeat([]byte("a"), 2*maxCacheDrain))
+		case "/private.json":
+			w.Header().Set("Cache-Control", "no-store")
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: cacheTransport{base: http.DefaultTransport}}
+	get := func(path string, header ...string) string {
+		req, err := http.NewRequestWithContext(context.Background(), "GET", server.URL+path, nil)
+		if !assert.Nil(t, err) {
+			return ""
+		}
+		if len(header) == 2 {
+			req.Header.Set(header[0], header[1])
+		}
+		resp, err := client.Do(req)
+		if !assert.Nil(t, err) {
+			return ""
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		assert.Nil(t, err)
+		return string(body)
+	}
+
+	for i := 0; i < 2; i++ {
+		assert.Equal(t, `{"fresh":true}`, get("/fresh.json"))
+		assert.Equal(t, `{"etag":true}`, get("/etag.json"))
+		assert.Equal(t, "png", get("/card.png"))
+		assert.Equal(t, `{"chunked":true}`, get("/chunked.json"))
+		assert.Equal(t, `{}`, get("/private.json"))
+		assert.Equal(t, `{}`, get("/private.json", "Cookie", "session=1"))
+	}
+
+	// The fresh responses are reused
+	assert.Equal(t, 1, requests["/fresh.json"])
+	assert.Equal(t, 1, requests["/card.png"])
+	assert.Equal(t, 1, requests["/chunked.json"])
+	// The other ones are revalidated
+	assert.Equal(t, 2, requests["/etag.json"])
+	assert.Equal(t, 4, requests["/private.json"])
+
+	infos, err := cache.Stat(dir)
+	if assert.Nil(t, err) {
+		assert.Equal(t, cache.HTTP, infos[0].Name)
+		assert.Equal(t, 3, infos[0].Files)
+		assert.Equal(t, cache.Images, infos[2].Name)
+		assert.Equal(t, 1, infos[2].Files)
+	}
+
+	// A response whose body isn't read until the end (e.g. a download
+	// exceeding the budget) isn't cached
+	for i := 0; i < 2; i++ {
+		req, err := http.NewRequestWithContext(context.Background(), "GET", server.URL+"/large.png", nil)
+		if !assert.Nil(t, err) {
+			break
+		}
+		resp, err := client.Do(req)
+		if !assert.Nil(t, err) {
+			break
+		}
+		_, err = resp.Body.Read(make([]byte, 10))
+		assert.Nil(t, err)
+		resp.Body.Close()
+	}
+	assert.Equal(t, 2, requests["/large.png"])
+	infos, err = cache.Stat(dir)
+	if assert.Nil(t, err) {
+		assert.Equal(t, 1, infos[2].Files)
+	}
+
+	// The requests aren't cached when the cache is disabled
+	SetHTTPCache("")
+	assert.Equal(t, `{"fresh":true}`, get("/fresh.json"))
+	assert.Equal(t, 2, requests["/fresh.json"])
+
+	// The cached response is used when the server can't be reached
+	SetHTTPCache(dir)
+	server.Close()
+	assert.Equal(t, `{"etag":true}`, get("/etag.json"))
+}
@@ -0,0 +1,203 @@
+package plugins
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MergeDecks merges decks into a single deck called name. The copies of the
+// same card (same name and image) found in several decks are combined.
+// The card back, size and corners of the first deck are used, and all the
+// decks need to have the same card size.
+func MergeDecks(name string, decks []*Deck) (*Deck, error) {
+	if len(decks) == 0 {
+		return nil, errors.New("no deck to merge")
+	}
+
+	merged := emptyCopy(decks[0], name)
+
+	indexes := make(map[string]int)
+
+	for _, deck := range decks {
+		if deck.CardSize != merged.CardSize {
+			return nil, fmt.Errorf(
+				"cannot merge deck %s (%s cards) with deck %s (%s cards)",
+				deck.Name,
+				deck.CardSize,
+				decks[0].Name,
+				merged.CardSize,
+			)
+		}
+
+		for _, card := range deck.Cards {
+			key := card.Name + "\x00" + card.ImageURL
+			if index, found := indexes[key]; found {
+				merged.Cards[index].Count += card.Count
+				continue
+			}
+
+			indexes[key] = len(merged.Cards)
+			merged.Cards = append(merged.Cards, card)
+		}
+	}
+
+	return merged, nil
+}
+
+// SplitKey returns the group of a card when splitting a deck.
+type SplitKey func(card CardInfo) string
+
+// SplitKeyDescription contains a split key and its description.
+type SplitKeyDescription struct {
+	// Description of the split key, displayed to the user.
+	Description string
+	// Key returns the group of a card.
+	Key SplitKey
+}
+
+// SplitKeys maps a name to a way of splitting decks.
+// New split keys can be added to this map.
+var SplitKeys = map[string]SplitKeyDescription{
+	"color": {
+		Description: "one deck per color, plus \"Multicolor\" and \"Colorless\"",
+		Key:         splitByColor,
+	},
+	"type": {
+		Description: "one deck per card type (e.g. \"Creature\" or \"Spell Card\")",
+		Key:         splitByType,
+	},
+	"cost": {
+		Description: "one deck per cost (mana value, level, grade...)",
+		Key:         splitByCost,
+	},
+	"category": {
+		Description: "one deck per category header of the deck list (e.g. \"Ramp\"), plus \"Uncategorized\"",
+		Key:         splitByCategory,
+	},
+}
+
+// AvailableSplitKeys returns the names of the split keys in SplitKeys,
+// sorted.
+func AvailableSplitKeys() []string {
+	names := make([]string, 0, len(SplitKeys))
+
+	for name := range SplitKeys {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// SplitDeck splits deck into one deck per group returned by key, named
+// "<deck name> - <group>" and sorted by group (numerically if the groups are
+// numbers). The cards keep their order inside each deck.
+func SplitDeck(deck *Deck, key SplitKey) []*Deck {
+	var groups []string
+	decks := make(map[string]*Deck)
+
+	for _, card := range deck.Cards {
+		group := key(card)
+
+		split, found := decks[group]
+		if !found {
+			split = emptyCopy(deck, deck.Name+" - "+group)
+			decks[group] = split
+			groups = append(groups, group)
+		}
+
+		split.Cards = append(split.Cards, card)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		return lessGroup(groups[i], groups[j])
+	})
+
+	result := make([]*Deck, 0, len(groups))
+	for _, group := range groups {
+		result = append(result, decks[group])
+	}
+
+	return result
+}
+
+// SplitDeckBy splits deck in two: the cards for which predicate returns
+// true, and the other ones. Each of the returned decks is nil if it doesn't
+// contain any card.
+func SplitDeckBy(deck *Deck, predicate func(card CardInfo) bool) (*Deck, *Deck) {
+	var matching, others *Deck
+
+	for _, card := range deck.Cards {
+		split := &others
+		if predicate(card) {
+			split = &matching
+		}
+
+		if *split == nil {
+			*split = emptyCopy(deck, deck.Name)
+		}
+		(*split).Cards = append((*split).Cards, card)
+	}
+
+	return matching, others
+}
+
+// emptyCopy returns a deck without any card, with the same back, size and
+// corners as deck.
+func emptyCopy(deck *Deck, name string) *Deck {
+	return &Deck{
+		Name:         name,
+		BackURL:      deck.BackURL,
+		CardSize:     deck.CardSize,
+		Rounded:      deck.Rounded,
+		ThumbnailURL: deck.ThumbnailURL,
+	}
+}
+
+// lessGroup sorts the groups numerically if they are numbers (e.g. costs),
+// and alphabetically otherwise.
+func lessGroup(a, b string) bool {
+	fa, aErr := strconv.ParseFloat(a, 64)
+	fb, bErr := strconv.ParseFloat(b, 64)
+	if aErr == nil && bErr == nil {
+		return fa < fb
+	}
+
+	return a < b
+}
+
+func splitByColor(card CardInfo) string {
+	switch len(card.Metadata.Colors) {
+	case 0:
+		return "Colorless"
+	case 1:
+		return card.Metadata.Colors[0]
+	default:
+		return "Multicolor"
+	}
+}
+
+func splitByType(card CardInfo) string {
+	// Remove the subtypes (e.g. "Creature — Elf")
+	cardType := strings.TrimSpace(strings.SplitN(card.Metadata.Type, "—", 2)[0])
+	if len(cardType) == 0 {
+		return "Unknown"
+	}
+
+	return cardType
+}
+
+func splitByCost(card CardInfo) string {
+	return strconv.FormatFloat(card.Metadata.Cost, 'f', -1, 64)
+}
+
+func splitByCategory(card CardInfo) string {
+	if len(card.Metadata.Category) == 0 {
+		return "Uncategorized"
+	}
+
+	return card.Metadata.Category
+}
@@ -0,0 +1,76 @@
+package plugins
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeDecks(t *testing.T) {
+	side := &Deck{
+		Name: "Test - Sideboard",
+		Cards: []CardInfo{
+			{Name: "Lightning Bolt", Count: 2},
+			{Name: "Pyroblast", Count: 1},
+		},
+	}
+
+	merged, err := MergeDecks("Merged", []*Deck{transformTestDeck(), side})
+	assert.Nil(t, err)
+	assert.Equal(t, "Merged", merged.Name)
+	assert.Len(t, merged.Cards, 4)
+	assert.Equal(t, "Lightning Bolt", merged.Cards[0].Name)
+	assert.Equal(t, 6, merged.Cards[0].Count)
+	assert.Equal(t, "Pyroblast", merged.Cards[3].Name)
+
+	side.CardSize = CardSizeSmall
+	_, err = MergeDecks("Merged", []*Deck{transformTestDeck(), side})
+	assert.NotNil(t, err)
+
+	_, err = MergeDecks("Merged", nil)
+	assert.NotNil(t, err)
+}
+
+func TestSplitDeck(t *testing.T) {
+	deck := transformTestDeck()
+	deck.Cards[0].Metadata.Cost = 1
+	deck.Cards[1].Metadata.Cost = 10
+
+	decks := SplitDeck(deck, SplitKeys["type"].Key)
+	assert.Len(t, decks, 3)
+	assert.Equal(t, "Test - Artifact", decks[0].Name)
+	assert.Equal(t, "Test - Basic Land", decks[1].Name)
+	assert.Equal(t, "Test - Instant", decks[2].Name)
+
+	decks = SplitDeck(deck, SplitKeys["cost"].Key)
+	assert.Len(t, decks, 3)
+	assert.Equal(t, "Test - 0", decks[0].Name)
+	assert.Equal(t, "Test - 1", decks[1].Name)
+	assert.Equal(t, "Test - 10", decks[2].Name)
+
+	decks = SplitDeck(deck, SplitKeys["color"].Key)
+	assert.Len(t, decks, 1)
+	assert.Equal(t, "Test - Colorless", decks[0].Name)
+
+	deck.Cards[0].Metadata.Category = "Removal"
+	deck.Cards[2].Metadata.Category = "Lands"
+	decks = SplitDeck(deck, SplitKeys["category"].Key)
+	assert.Len(t, decks, 3)
+	assert.Equal(t, "Test - Lands", decks[0].Name)
+	assert.Equal(t, "Test - Removal", decks[1].Name)
+	assert.Equal(t, "Test - Uncategorized", decks[2].Name)
+}
+
+func TestSplitDeckBy(t *testing.T) {
+	lands, others := SplitDeckBy(transformTestDeck(), func(card CardInfo) bool {
+		return isLand(card.Metadata.Type)
+	})
+	assert.Len(t, lands.Cards, 1)
+	assert.Equal(t, "Mountain", lands.Cards[0].Name)
+	assert.Len(t, others.Cards, 2)
+
+	_, others = SplitDeckBy(transformTestDeck(), func(card CardInfo) bool {
+		return true
+	})
+	assert.Nil(t, others)
+}
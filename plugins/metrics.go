@@ -0,0 +1,92 @@
+package plugins
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Names of the operations passed to Metrics.Operation.
+const (
+	// OperationParse is the parsing of a target into decks.
+	OperationParse = "parse"
+	// OperationGenerate is the generation of the TTS files of the decks.
+	OperationGenerate = "generate"
+	// OperationTemplates is the generation and upload of the deck
+	// templates.
+	OperationTemplates = "templates"
+)
+
+// Metrics receives measurements about the conversions, so that they can be
+// exported (e.g. with Prometheus or expvar) when running the converter as a
+// service.
+// Its methods can be called from several goroutines.
+type Metrics interface {
+	// APICall is called after each call to the API used by a plugin. err is
+	// the error returned by the API, if any.
+	APICall(pluginID string, duration time.Duration, err error)
+	// CacheLookup is called each time a value is looked up in one of the
+	// caches used by the plugins.
+	CacheLookup(cache string, hit bool)
+	// Downloaded is called each time an image has been downloaded.
+	Downloaded(bytes int64)
+	// Operation is called at the end of each conversion step (see the
+	// Operation constants).
+	Operation(name string, duration time.Duration, err error)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) APICall(string, time.Duration, error)   {}
+func (nopMetrics) CacheLookup(string, bool)               {}
+func (nopMetrics) Downloaded(int64)                       {}
+func (nopMetrics) Operation(string, time.Duration, error) {}
+
+// metricsHolder is stored in currentMetrics, since an atomic.Value requires
+// values of the same concrete type.
+type metricsHolder struct {
+	Metrics
+}
+
+// currentMetrics contains the Metrics set with SetMetrics.
+var currentMetrics atomic.Value
+
+func init() {
+	currentMetrics.Store(metricsHolder{nopMetrics{}})
+}
+
+// SetMetrics sets the Metrics receiving the measurements of every
+// conversion. Nothing is measured until it is called, and setting nil
+// disables the measurements.
+func SetMetrics(metrics Metrics) {
+	if metrics == nil {
+		metrics = nopMetrics{}
+	}
+	currentMetrics.Store(metricsHolder{metrics})
+}
+
+func metrics() Metrics {
+	return currentMetrics.Load().(metricsHolder).Metrics
+}
+
+// RecordAPICall notifies the Metrics that an API call of a plugin started at
+// start has ended.
+func RecordAPICall(pluginID string, start time.Time, err error) {
+	metrics().APICall(pluginID, time.Since(start), err)
+}
+
+// RecordCacheLookup notifies the Metrics that a value has been looked up in
+// cache.
+func RecordCacheLookup(cache string, hit bool) {
+	metrics().CacheLookup(cache, hit)
+}
+
+// RecordDownload notifies the Metrics that an image has been downloaded.
+func RecordDownload(bytes int64) {
+	metrics().Downloaded(bytes)
+}
+
+// RecordOperation notifies the Metrics that an operation started at start
+// has ended.
+func RecordOperation(name string, start time.Time, err error) {
+	metrics().Operation(name, time.Since(start), err)
+}
@@ -0,0 +1,80 @@
+package plugins
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingMetrics struct {
+	mutex      sync.Mutex
+	apiCalls   map[string]int
+	apiErrors  int
+	cacheHits  int
+	downloaded int64
+	operations []string
+}
+
+func (m *recordingMetrics) APICall(pluginID string, duration time.Duration, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.apiCalls[pluginID]++
+	if err != nil {
+		m.apiErrors++
+	}
+}
+
+func (m *recordingMetrics) CacheLookup(cache string, hit bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if hit {
+		m.cacheHits++
+	}
+}
+
+func (m *recordingMetrics) Downloaded(bytes int64) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.downloaded += bytes
+}
+
+func (m *recordingMetrics) Operation(name string, duration time.Duration, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.operations = append(m.operations, name)
+}
+
+func TestMetrics(t *testing.T) {
+	// No metrics set, nothing should happen
+	RecordDownload(10)
+
+	metrics := &recordingMetrics{apiCalls: make(map[string]int)}
+	SetMetrics(metrics)
+	defer SetMetrics(nil)
+
+	start := time.Now()
+	RecordAPICall("mtg", start, nil)
+	RecordAPICall("mtg", start, errors.New("not found"))
+	RecordCacheLookup("mtg.sets", false)
+	RecordCacheLookup("mtg.sets", true)
+	RecordDownload(10)
+	RecordDownload(5)
+	RecordOperation(OperationParse, start, nil)
+
+	assert.Equal(t, map[string]int{"mtg": 2}, metrics.apiCalls)
+	assert.Equal(t, 1, metrics.apiErrors)
+	assert.Equal(t, 1, metrics.cacheHits)
+	assert.Equal(t, int64(15), metrics.downloaded)
+	assert.Equal(t, []string{OperationParse}, metrics.operations)
+
+	SetMetrics(nil)
+	RecordDownload(10)
+	assert.Equal(t, int64(15), metrics.downloaded)
+}
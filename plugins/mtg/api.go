@@ -5,29 +5,73 @@ import (
 	"time"
 
 	scryfall "github.com/BlueMonday/go-scryfall"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
 )
 
 // See https://scryfall.com/docs/api#rate-limits-and-good-citizenship
-var rateLimiter = time.NewTicker(100 * time.Millisecond)
+var rateLimiter = plugins.NewRateLimiter(MagicPlugin.PluginID(), 100*time.Millisecond)
 
 func getCard(ctx context.Context, client *scryfall.Client, id string) (scryfall.Card, error) {
-	<-rateLimiter.C
-	return client.GetCard(ctx, id)
+	if err := rateLimiter.Wait(ctx); err != nil {
+		return scryfall.Card{}, err
+	}
+	start := time.Now()
+	card, err := client.GetCard(ctx, id)
+	plugins.RecordAPICall(MagicPlugin.PluginID(), start, err)
+	return card, err
 }
 
 func getCardByName(ctx context.Context, client *scryfall.Client, name string, opts scryfall.GetCardByNameOptions) (scryfall.Card, error) {
-	<-rateLimiter.C
+	if err := rateLimiter.Wait(ctx); err != nil {
+		return scryfall.Card{}, err
+	}
 	// Fuzzy search is required to match card names in languages other
 	// than English ("printed_name")
-	return client.GetCardByName(ctx, name, false, opts)
+	start := time.Now()
+	card, err := client.GetCardByName(ctx, name, false, opts)
+	plugins.RecordAPICall(MagicPlugin.PluginID(), start, err)
+	return card, err
+}
+
+func getCardsByIdentifiers(ctx context.Context, client *scryfall.Client, identifiers []scryfall.CardIdentifier) (scryfall.GetCardsByIdentifiersResponse, error) {
+	if err := rateLimiter.Wait(ctx); err != nil {
+		return scryfall.GetCardsByIdentifiersResponse{}, err
+	}
+	start := time.Now()
+	// The collection endpoint is queried with a POST request, but it doesn't
+	// have any side effect
+	result, err := client.GetCardsByIdentifiers(plugins.AllowRetry(ctx), identifiers)
+	plugins.RecordAPICall(MagicPlugin.PluginID(), start, err)
+	return result, err
+}
+
+func searchCards(ctx context.Context, client *scryfall.Client, query string, opts scryfall.SearchCardsOptions) (scryfall.CardListResponse, error) {
+	if err := rateLimiter.Wait(ctx); err != nil {
+		return scryfall.CardListResponse{}, err
+	}
+	start := time.Now()
+	result, err := client.SearchCards(ctx, query, opts)
+	plugins.RecordAPICall(MagicPlugin.PluginID(), start, err)
+	return result, err
 }
 
 func listSets(ctx context.Context, client *scryfall.Client) ([]scryfall.Set, error) {
-	<-rateLimiter.C
-	return client.ListSets(ctx)
+	if err := rateLimiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	sets, err := client.ListSets(ctx)
+	plugins.RecordAPICall(MagicPlugin.PluginID(), start, err)
+	return sets, err
 }
 
 func getRulings(ctx context.Context, client *scryfall.Client, cardID string) ([]scryfall.Ruling, error) {
-	<-rateLimiter.C
-	return client.GetRulings(ctx, cardID)
+	if err := rateLimiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	rulings, err := client.GetRulings(ctx, cardID)
+	plugins.RecordAPICall(MagicPlugin.PluginID(), start, err)
+	return rulings, err
 }
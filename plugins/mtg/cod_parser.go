@@ -1,6 +1,7 @@
 package mtg
 
 import (
+	"context"
 	"encoding/xml"
 	"io"
 	"io/ioutil"
@@ -32,13 +33,18 @@ type CockatriceCard struct {
 	Name    string   `xml:"name,attr"`
 }
 
-func fromCockatriceDeckFile(file io.Reader, name string, options map[string]string) ([]*plugins.Deck, error) {
+func fromCockatriceDeckFile(ctx context.Context, file io.Reader, name string, options map[string]string) ([]*plugins.Deck, error) {
 	// Check the options
 	validatedOptions, err := MagicPlugin.AvailableOptions().ValidateNormalize(options)
 	if err != nil {
 		return nil, err
 	}
 
+	boards, err := selectedBoards(validatedOptions)
+	if err != nil {
+		return nil, err
+	}
+
 	main, side, err := parseCockatriceDeckFile(file)
 	if err != nil {
 		return nil, err
@@ -49,18 +55,22 @@ func fromCockatriceDeckFile(file io.Reader, name string, options map[string]stri
 		tokenIDs []string
 	)
 
-	if main != nil {
-		mainDeck, mainTokenIDs, err := cardNamesToDeck(main, name, validatedOptions)
+	if main != nil && boards[mainBoard] {
+		mainDeck, mainTokenIDs, err := cardNamesToDeck(ctx, main, name, validatedOptions)
 		if err != nil {
 			return nil, err
 		}
 
 		decks = append(decks, mainDeck)
+
+		if split, found := validatedOptions["conspiracies"]; !found || split.(bool) {
+			decks = append(decks, splitConspiracies(mainDeck)...)
+		}
 		tokenIDs = append(tokenIDs, mainTokenIDs...)
 	}
 
-	if side != nil {
-		sideDeck, sideTokenIDs, err := cardNamesToDeck(side, name+" - Sideboard", validatedOptions)
+	if side != nil && boards[sideBoard] {
+		sideDeck, sideTokenIDs, err := cardNamesToDeck(ctx, side, name+" - Sideboard", validatedOptions)
 		if err != nil {
 			return nil, err
 		}
@@ -69,15 +79,29 @@ func fromCockatriceDeckFile(file io.Reader, name string, options map[string]stri
 		tokenIDs = append(tokenIDs, sideTokenIDs...)
 	}
 
+	decks = appendAuxiliaryDecks(ctx, decks, name, validatedOptions)
+
+	if generateDungeons, found := validatedOptions["dungeons"]; !found || generateDungeons.(bool) {
+		dungeonDeck, dungeonTokenIDs, err := dungeonsToDeck(ctx, decks, name+" - Dungeons", validatedOptions)
+		if err != nil {
+			log.Warnf("Couldn't retrieve the dungeons: %v", err)
+		} else if dungeonDeck != nil {
+			decks = append(decks, dungeonDeck)
+			tokenIDs = append(tokenIDs, dungeonTokenIDs...)
+		}
+	}
+
 	if generateTokens, found := validatedOptions["tokens"]; found && generateTokens.(bool) {
-		tokenDeck, err := tokenIDsToDeck(tokenIDs, name+" - Tokens", validatedOptions)
+		tokenDeck, err := tokenIDsToDeck(ctx, tokenIDs, name+" - Tokens", validatedOptions)
 		if err != nil {
 			return nil, err
 		}
 
-		decks = append(decks, tokenDeck)
+		decks = append(decks, splitTokenFigurines(tokenDeck, name+" - Token Figurines", validatedOptions)...)
 	}
 
+	warnCounts(decks, name, validatedOptions)
+
 	return decks, nil
 }
 
@@ -0,0 +1,156 @@
+package mtg
+
+import (
+	"context"
+	"strings"
+
+	scryfall "github.com/BlueMonday/go-scryfall"
+
+	"github.com/jeandeaual/tts-deckconverter/log"
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+// maxCollectionIdentifiers is the maximum number of cards queried by a
+// single request to the collection endpoint of Scryfall.
+// See https://scryfall.com/docs/api/cards/collection.
+const maxCollectionIdentifiers = 75
+
+// collectionIdentifier returns the identifier of cardInfo for the
+// collection endpoint of Scryfall, or false if the card needs to be queried
+// on its own (e.g. a name printed in another language, which requires a
+// fuzzy search).
+func collectionIdentifier(cardInfo CardInfo, sets map[string]scryfall.Set) (scryfall.CardIdentifier, bool) {
+	if cardInfo.ID != nil {
+		return scryfall.CardIdentifier{ID: *cardInfo.ID}, true
+	}
+
+	name, keep := plugins.ResolveCardName(cardInfo.Name)
+	if !keep || isForeignName(name) {
+		return scryfall.CardIdentifier{}, false
+	}
+
+	identifier := scryfall.CardIdentifier{Name: name}
+	if cardInfo.Set != nil {
+		identifier.Set = findSetCode(sets, *cardInfo.Set)
+		if len(identifier.Set) == 0 {
+			// The set is reported when querying the card on its own
+			return scryfall.CardIdentifier{}, false
+		}
+	}
+
+	return identifier, true
+}
+
+// matchesIdentifier checks if card was returned for identifier by the
+// collection endpoint. The names match the full name of the card, or the
+// name of one of its faces.
+func matchesIdentifier(card scryfall.Card, identifier scryfall.CardIdentifier) bool {
+	if len(identifier.ID) > 0 {
+		return card.ID == identifier.ID
+	}
+
+	if len(identifier.Set) > 0 && !strings.EqualFold(card.Set, identifier.Set) {
+		return false
+	}
+
+	if strings.EqualFold(card.Name, identifier.Name) {
+		return true
+	}
+	for _, face := range card.CardFaces {
+		if strings.EqualFold(face.Name, identifier.Name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getCardCollection queries the cards of identifiers, by batches of
+// maxCollectionIdentifiers. The cards are indexed by their identifier, and
+// the ones which weren't found are missing from the result.
+func getCardCollection(ctx context.Context, client *scryfall.Client, identifiers []scryfall.CardIdentifier) (map[scryfall.CardIdentifier]scryfall.Card, error) {
+	cards := make(map[scryfall.CardIdentifier]scryfall.Card, len(identifiers))
+
+	for start := 0; start < len(identifiers); start += maxCollectionIdentifiers {
+		end := start + maxCollectionIdentifiers
+		if end > len(identifiers) {
+			end = len(identifiers)
+		}
+		batch := identifiers[start:end]
+
+		log.Debugf("Querying a collection of %d card(s)", len(batch))
+
+		result, err := getCardsByIdentifiers(ctx, client, batch)
+		if err != nil {
+			return cards, err
+		}
+
+		// The cards which aren't found are missing from the data, so the
+		// cards can't be matched with their identifier by their index
+		for _, identifier := range batch {
+			for _, card := range result.Data {
+				if matchesIdentifier(card, identifier) {
+					cards[identifier] = card
+					break
+				}
+			}
+		}
+	}
+
+	return cards, nil
+}
+
+// prefetchCards queries the cards of names with the collection endpoint of
+// Scryfall, instead of sending a request for each card. The cards are
+// indexed by their key in names.Counts. The cards missing from the result
+// (e.g. misspelled names or names printed in another language) need to be
+// queried on their own.
+func prefetchCards(ctx context.Context, client *scryfall.Client, names *CardNames) map[string]scryfall.Card {
+	prefetched := make(map[string]scryfall.Card)
+
+	var sets map[string]scryfall.Set
+	for _, cardInfo := range names.Names {
+		if cardInfo.Set != nil && cardInfo.ID == nil {
+			var err error
+			sets, err = getSets(ctx, client)
+			if err != nil {
+				log.Warnf("Couldn't retrieve the sets: %v", err)
+				return prefetched
+			}
+			break
+		}
+	}
+
+	identifiers := []scryfall.CardIdentifier{}
+	keys := make(map[scryfall.CardIdentifier][]string)
+	for _, cardInfo := range names.Names {
+		identifier, ok := collectionIdentifier(cardInfo, sets)
+		if !ok {
+			continue
+		}
+		if _, found := keys[identifier]; !found {
+			identifiers = append(identifiers, identifier)
+		}
+		keys[identifier] = append(keys[identifier], cardInfo.key())
+	}
+
+	// Querying a single card on its own takes as long
+	if len(identifiers) < 2 {
+		return prefetched
+	}
+
+	cards, err := getCardCollection(ctx, client, identifiers)
+	if err != nil {
+		log.Warnf("Couldn't query the card collection, querying the cards one by one: %v", err)
+	}
+
+	for identifier, card := range cards {
+		for _, key := range keys[identifier] {
+			prefetched[key] = card
+		}
+	}
+
+	log.Debugf("Prefetched %d of %d card(s)", len(prefetched), len(names.Names))
+
+	return prefetched
+}
@@ -0,0 +1,93 @@
+package mtg
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	scryfall "github.com/BlueMonday/go-scryfall"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchesIdentifier(t *testing.T) {
+	delver := scryfall.Card{
+		ID:   "28059d09-2c7d-4c61-af55-8942107a7c1f",
+		Name: "Delver of Secrets // Insectile Aberration",
+		Set:  "isd",
+		CardFaces: []scryfall.CardFace{
+			{Name: "Delver of Secrets"},
+			{Name: "Insectile Aberration"},
+		},
+	}
+
+	assert.True(t, matchesIdentifier(delver, scryfall.CardIdentifier{ID: delver.ID}))
+	assert.False(t, matchesIdentifier(delver, scryfall.CardIdentifier{ID: "other"}))
+	assert.True(t, matchesIdentifier(delver, scryfall.CardIdentifier{Name: "delver of secrets"}))
+	assert.True(t, matchesIdentifier(delver, scryfall.CardIdentifier{Name: "Delver of Secrets // Insectile Aberration"}))
+	assert.True(t, matchesIdentifier(delver, scryfall.CardIdentifier{Name: "Delver of Secrets", Set: "ISD"}))
+	assert.False(t, matchesIdentifier(delver, scryfall.CardIdentifier{Name: "Delver of Secrets", Set: "mid"}))
+	assert.False(t, matchesIdentifier(delver, scryfall.CardIdentifier{Name: "Delver"}))
+}
+
+func TestPrefetchCards(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/cards/collection" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		requests++
+
+		var request scryfall.GetCardsByIdentifiersRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if len(request.Identifiers) > maxCollectionIdentifiers {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		// The cards are written by hand, since their release dates aren't
+		// marshalled in the format expected by go-scryfall
+		response := map[string][]interface{}{"data": {}, "not_found": {}}
+		for _, identifier := range request.Identifiers {
+			if strings.HasPrefix(identifier.Name, "Misspelled") {
+				response["not_found"] = append(response["not_found"], identifier)
+				continue
+			}
+			response["data"] = append(response["data"], map[string]string{
+				"id":   "id-" + identifier.Name,
+				"name": identifier.Name,
+			})
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	client, err := scryfall.NewClient(scryfall.WithBaseURL(server.URL + "/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := NewCardNames()
+	for i := 0; i < 100; i++ {
+		names.Insert("Card "+strings.Repeat("I", i+1), nil)
+	}
+	names.Insert("Misspelled Card", nil)
+	names.Insert("Лиллиана", nil)
+	names.InsertCardInfo(CardInfo{Name: "Card I", Finish: "Foil"}, 1)
+
+	prefetched := prefetchCards(context.Background(), client, names)
+
+	// 101 distinct names, the foreign name being queried on its own
+	assert.Equal(t, 2, requests)
+	assert.Len(t, prefetched, 101)
+	assert.Equal(t, "Card I", prefetched[CardInfo{Name: "Card I"}.key()].Name)
+	assert.Equal(t, "Card I", prefetched[CardInfo{Name: "Card I", Finish: "Foil"}.key()].Name)
+	assert.NotContains(t, prefetched, CardInfo{Name: "Misspelled Card"}.key())
+	assert.NotContains(t, prefetched, CardInfo{Name: "Лиллиана"}.key())
+}
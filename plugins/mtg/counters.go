@@ -0,0 +1,84 @@
+package mtg
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	scryfall "github.com/BlueMonday/go-scryfall"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+// counterRegex matches the named counters of the oracle texts, preceded by
+// their number (e.g. "a +1/+1 counter", "three shield counters" or "number of
+// charge counters"), so that the verb "to counter" ("may counter that spell")
+// and the counters without a name ("had counters") are left out.
+var counterRegex = regexp.MustCompile(`(?i)\b(?:a|an|one|two|three|four|five|six|seven|eight|nine|ten|x|many|more|additional|another|each|no|number of)\s+([+-]\d+/[+-]\d+|[a-z]+) counters?\b`)
+
+// notCounterNames are the words found between a number and "counter" which
+// aren't the name of a counter (e.g. "an additional counter").
+var notCounterNames = map[string]bool{
+	"a": true, "an": true, "the": true, "each": true, "any": true,
+	"that": true, "those": true, "these": true, "this": true, "more": true,
+	"no": true, "of": true, "additional": true, "another": true, "all": true,
+	"with": true, "its": true, "kind": true, "such": true, "x": true,
+	"one": true, "two": true, "three": true, "four": true, "five": true,
+	"many": true, "same": true, "which": true, "target": true, "other": true,
+	"and": true, "or": true,
+}
+
+// findCounters returns the lowercase names of the counters used by card
+// (e.g. "+1/+1", "loyalty", "energy" or "shield"), found in its oracle text.
+func findCounters(card scryfall.Card) []string {
+	texts := []string{card.OracleText}
+	loyalty := card.Loyalty != nil
+
+	for _, face := range card.CardFaces {
+		if face.OracleText != nil {
+			texts = append(texts, *face.OracleText)
+		}
+		if face.Loyalty != nil {
+			loyalty = true
+		}
+	}
+
+	found := make(map[string]bool)
+	if loyalty {
+		found["loyalty"] = true
+	}
+
+	for _, text := range texts {
+		for _, match := range counterRegex.FindAllStringSubmatch(text, -1) {
+			name := strings.ToLower(match[1])
+			if !notCounterNames[name] {
+				found[name] = true
+			}
+		}
+		if strings.Contains(text, "{E}") {
+			found["energy"] = true
+		}
+	}
+
+	counters := make([]string, 0, len(found))
+	for name := range found {
+		counters = append(counters, name)
+	}
+	sort.Strings(counters)
+
+	return counters
+}
+
+// addCounters adds the names of counters to the counters of deck, keeping
+// them sorted and without duplicates.
+func addCounters(deck *plugins.Deck, counters []string) {
+	for _, name := range counters {
+		index := sort.SearchStrings(deck.Counters, name)
+		if index < len(deck.Counters) && deck.Counters[index] == name {
+			continue
+		}
+		deck.Counters = append(deck.Counters, "")
+		copy(deck.Counters[index+1:], deck.Counters[index:])
+		deck.Counters[index] = name
+	}
+}
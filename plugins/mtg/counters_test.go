@@ -0,0 +1,58 @@
+package mtg
+
+import (
+	"testing"
+
+	scryfall "github.com/BlueMonday/go-scryfall"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+func TestFindCounters(t *testing.T) {
+	loyalty := "3"
+	backText := "Whenever you cast a spell, put a shield counter on target creature."
+
+	for _, tc := range []struct {
+		card     scryfall.Card
+		counters []string
+	}{
+		{
+			card:     scryfall.Card{OracleText: "Counter target spell."},
+			counters: []string{},
+		},
+		{
+			card:     scryfall.Card{OracleText: "You may counter that spell. If it had counters on it, creatures you control get counters equal to that number."},
+			counters: []string{},
+		},
+		{
+			card:     scryfall.Card{OracleText: "Put an additional +1/+1 counter on it for each charge counter on this artifact. Draw cards equal to the number of time counters removed this way."},
+			counters: []string{"+1/+1", "charge", "time"},
+		},
+		{
+			card:     scryfall.Card{OracleText: "Hardened Scales enters with a +1/+1 counter on it. Remove a counter from target permanent. This spell can't be countered."},
+			counters: []string{"+1/+1"},
+		},
+		{
+			card:     scryfall.Card{OracleText: "When this enters, you get {E}{E}. Put three -1/-1 counters on target creature.", Loyalty: &loyalty},
+			counters: []string{"-1/-1", "energy", "loyalty"},
+		},
+		{
+			card: scryfall.Card{CardFaces: []scryfall.CardFace{
+				{OracleText: &backText},
+				{Loyalty: &loyalty},
+			}},
+			counters: []string{"loyalty", "shield"},
+		},
+	} {
+		assert.Equal(t, tc.counters, findCounters(tc.card))
+	}
+}
+
+func TestAddCounters(t *testing.T) {
+	deck := &plugins.Deck{}
+
+	addCounters(deck, []string{"loyalty", "+1/+1"})
+	addCounters(deck, []string{"energy", "loyalty"})
+	assert.Equal(t, []string{"+1/+1", "energy", "loyalty"}, deck.Counters)
+}
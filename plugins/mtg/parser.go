@@ -13,9 +13,11 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"unicode"
 
 	scryfall "github.com/BlueMonday/go-scryfall"
 	"github.com/antchfx/htmlquery"
@@ -45,6 +47,23 @@ var cardLineRegexps = []*regexp.Regexp{
 	// TODO: Support .dck for CubeCobra
 }
 
+// cardAnnotationRegex matches an annotation at the end of a deck list line:
+// a finish (e.g. "[foil]") or a list of overrides (e.g. "{back=planechase}").
+var cardAnnotationRegex = regexp.MustCompile(`\s+(\[(?i:foil|etched)\]|\{[^{}]*\})\s*$`)
+
+// sectionHeaderRegex matches the lines starting a section of a deck list
+// (e.g. "Sideboard", "Commander:" or the "Companion" and "About" headers of
+// the Arena exports).
+var sectionHeaderRegex = regexp.MustCompile(`^(?i)(about|commanders?|companions?|deck|maybeboard|sideboard)\b[^a-z']*$`)
+
+// cardRoleRegex matches the commander and companion markers of a deck list
+// line, e.g. "*CMDR*", "[Commander]", "[Commander{top}]" or "!Companion".
+var cardRoleRegex = regexp.MustCompile(`\s+(\*CMDR\*|\[(?i:commander|companion)(?:\{[^{}\]]*\})*\]|!(?i:commander|companion))`)
+
+// categoryHeaderRegex matches the category headers of a deck list, e.g.
+// "Creatures", "Lands (24)", "Instants:" or "## Ramp".
+var categoryHeaderRegex = regexp.MustCompile(`^\s*(?:#+\s*)?([A-Za-z][A-Za-z0-9 '&/,+-]{0,39}?)\s*(?:\(\d+\))?\s*:?\s*$`)
+
 // DeckType is the type of a parsed deck.
 type DeckType int
 
@@ -55,6 +74,10 @@ const (
 	Sideboard
 	// Maybeboard cards
 	Maybeboard
+	// Commander cards, which are also part of the main deck
+	Commander
+	// Companion cards, which are also part of the sideboard
+	Companion
 )
 
 var (
@@ -66,6 +89,8 @@ func getSets(ctx context.Context, client *scryfall.Client) (map[string]scryfall.
 	setsMutex.Lock()
 	defer setsMutex.Unlock()
 
+	plugins.RecordCacheLookup("mtg.sets", sets != nil)
+
 	if sets == nil {
 		setList, err := listSets(ctx, client)
 		if err != nil {
@@ -80,12 +105,74 @@ func getSets(ctx context.Context, client *scryfall.Client) (map[string]scryfall.
 	return sets, nil
 }
 
+// findSetCode returns the Scryfall code of set (a Scryfall, MTGO or Arena
+// set code, or a deckstats.net set name), or an empty string if it isn't
+// found in sets.
+func findSetCode(sets map[string]scryfall.Set, set string) string {
+	setName := strings.ToLower(set)
+	// Manual fix for some deckstats.net set names which differ from Scryfall set names.
+	// See https://deckstats.net/sets/?lng=en and https://scryfall.com/sets
+	if setName == "frf_ugin" {
+		setName = "ugin"
+	} else if setName == "mps_akh" {
+		setName = "mp2"
+	} else if strings.Contains(setName, "_") {
+		setName = strings.Split(setName, "_")[0]
+	}
+
+	if _, found := sets[setName]; found {
+		return setName
+	}
+
+	for _, set := range sets {
+		if set.MTGOCode != nil && *set.MTGOCode == setName {
+			return set.Code
+		}
+		if set.ArenaCode != nil && *set.ArenaCode == setName {
+			return set.Code
+		}
+	}
+
+	return ""
+}
+
 // CardInfo contains the name of a card and its set.
 type CardInfo struct {
 	// Name of the card.
 	Name string
 	// Set of the card.
 	Set *string
+	// ID is the Scryfall ID of the exact printing of the card, if known.
+	ID *string
+	// Finish of the card (e.g. "Foil" or "Etched"), if known.
+	Finish string
+	// BackURL is the URL of the back of the card, if it differs from the
+	// back of the deck.
+	BackURL string
+	// Sideways cards are displayed in landscape orientation.
+	Sideways bool
+	// Category of the card in the main deck (e.g. "Lands" or "Ramp"), if
+	// the deck list has category headers. The copies of a card in several
+	// categories are merged in the first one.
+	Category string
+}
+
+// key returns the key of the card in CardNames.Counts.
+func (c CardInfo) key() string {
+	idx := c.Name
+	if c.Set != nil {
+		idx += *c.Set
+	}
+	if c.ID != nil {
+		idx += *c.ID
+	}
+
+	idx += c.Finish + c.BackURL
+	if c.Sideways {
+		idx += "sideways"
+	}
+
+	return idx
 }
 
 // CardNames contains the card names and their count.
@@ -109,16 +196,20 @@ func (c *CardNames) Insert(name string, set *string) {
 
 // InsertCount inserts several new cards in a CardNames struct.
 func (c *CardNames) InsertCount(name string, set *string, count int) {
-	idx := name
-	if set != nil {
-		idx += *set
-	}
+	c.InsertCardInfo(CardInfo{
+		Name: name,
+		Set:  set,
+	}, count)
+}
+
+// InsertCardInfo inserts several copies of a card in a CardNames struct.
+// The copies of a card are only merged if they have the same set, printing
+// and finish.
+func (c *CardNames) InsertCardInfo(cardInfo CardInfo, count int) {
+	idx := cardInfo.key()
 	_, found := c.Counts[idx]
 	if !found {
-		c.Names = append(c.Names, CardInfo{
-			Name: name,
-			Set:  set,
-		})
+		c.Names = append(c.Names, cardInfo)
 		c.Counts[idx] = count
 	} else {
 		c.Counts[idx] = c.Counts[idx] + count
@@ -127,11 +218,7 @@ func (c *CardNames) InsertCount(name string, set *string, count int) {
 
 // Count return the number of cards for a given name and set (optional).
 func (c *CardNames) Count(name string, set *string) int {
-	idx := name
-	if set != nil {
-		idx += *set
-	}
-	return c.Counts[idx]
+	return c.Counts[CardInfo{Name: name, Set: set}.key()]
 }
 
 // String representation of a CardNames struct.
@@ -139,7 +226,7 @@ func (c *CardNames) String() string {
 	var sb strings.Builder
 
 	for _, cardInfo := range c.Names {
-		count := c.Count(cardInfo.Name, cardInfo.Set)
+		count := c.Counts[cardInfo.key()]
 		sb.WriteString(strconv.Itoa(count))
 		sb.WriteString(" ")
 		sb.WriteString(cardInfo.Name)
@@ -153,55 +240,131 @@ func (c *CardNames) String() string {
 	return sb.String()
 }
 
+// getImageURL returns the URL of the image of a card in the first available
+// quality of qualities (see imageQualities).
 func getImageURL(
 	uris *scryfall.ImageURIs,
 	highResAvailable bool,
-	imageQuality string,
+	qualities []string,
 ) string {
 	if uris == nil {
 		log.Warn("No image data available")
 		return ""
 	}
 
-	var imageURL string
+	for i, quality := range qualities {
+		var imageURL string
 
-	switch imageQuality {
-	case string(small):
-		imageURL = uris.Small
-	case string(normal):
-		imageURL = uris.Normal
-	case string(large):
-		if highResAvailable {
-			imageURL = uris.Large
-		} else {
-			log.Warn("High-resolution image not available, using normal quality instead of large")
+		switch quality {
+		case string(small):
+			imageURL = uris.Small
+		case string(normal):
 			imageURL = uris.Normal
+		case string(large):
+			if highResAvailable {
+				imageURL = uris.Large
+			}
+		case string(png):
+			if highResAvailable {
+				imageURL = uris.PNG
+			}
 		}
-	case string(png):
-		if highResAvailable {
-			imageURL = uris.PNG
-		} else {
-			log.Warn("High-resolution image not available, using normal quality instead of png")
-			imageURL = uris.Normal
+
+		if len(imageURL) > 0 {
+			if i > 0 {
+				log.Debugf("Image quality %s not available, using %s instead", qualities[0], quality)
+			}
+			return imageURL
 		}
 	}
 
-	return imageURL
+	log.Warnf("No image available in the qualities %s", strings.Join(qualities, ", "))
+
+	return ""
 }
 
-func checkRulings(ctx context.Context, client *scryfall.Client, cardID string, options map[string]interface{}) ([]scryfall.Ruling, error) {
+// getFallbackImageURLs returns the URLs of the images of a card smaller than
+// imageURL (see getImageURL), from the largest to the smallest.
+func getFallbackImageURLs(uris *scryfall.ImageURIs, imageURL string) []string {
+	if uris == nil || len(imageURL) == 0 {
+		return nil
+	}
+
 	var (
-		rulings []scryfall.Ruling
-		err     error
+		urls  []string
+		found bool
 	)
 
-	// Check the options to see if we want the rulings
-	if showRulings, found := options["rulings"]; found && showRulings.(bool) {
-		log.Debugf("Querying rulings for card ID %s", cardID)
-		rulings, err = getRulings(ctx, client, cardID)
+	for _, u := range []string{uris.PNG, uris.Large, uris.Normal, uris.Small} {
+		switch {
+		case u == imageURL:
+			found = true
+		case found && len(u) > 0:
+			urls = append(urls, u)
+		}
+	}
+
+	return urls
+}
+
+// getArtURL returns the URL of the art crop of uris, if any.
+func getArtURL(uris *scryfall.ImageURIs) string {
+	if uris == nil {
+		return ""
+	}
+
+	return uris.ArtCrop
+}
+
+var (
+	// rulingsCache contains the rulings already retrieved, indexed by
+	// Oracle ID since they are shared by every printing of a card.
+	rulingsCache = make(map[string][]scryfall.Ruling)
+	rulingsMutex sync.Mutex
+)
+
+func getCachedRulings(ctx context.Context, client *scryfall.Client, card scryfall.Card) ([]scryfall.Ruling, error) {
+	key := card.OracleID
+	if len(key) == 0 {
+		key = card.ID
+	}
+
+	rulingsMutex.Lock()
+	rulings, found := rulingsCache[key]
+	rulingsMutex.Unlock()
+
+	plugins.RecordCacheLookup("mtg.rulings", found)
+
+	if found {
+		return rulings, nil
 	}
 
-	return rulings, err
+	log.Debugf("Querying rulings for card ID %s", card.ID)
+	rulings, err := getRulings(ctx, client, card.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	rulingsMutex.Lock()
+	rulingsCache[key] = rulings
+	rulingsMutex.Unlock()
+
+	return rulings, nil
+}
+
+// checkRulings returns the rulings of card matching filter, or nil if filter
+// is nil (i.e. the "rulings" option isn't set).
+func checkRulings(ctx context.Context, client *scryfall.Client, card scryfall.Card, filter *rulingsFilter) ([]scryfall.Ruling, error) {
+	if filter == nil {
+		return nil, nil
+	}
+
+	rulings, err := getCachedRulings(ctx, client, card)
+	if err != nil {
+		return nil, err
+	}
+
+	return filter.apply(rulings), nil
 }
 
 func parseRelatedTokenIDs(card scryfall.Card) []string {
@@ -223,7 +386,7 @@ func buildMeldCard(
 	client *scryfall.Client,
 	card scryfall.Card,
 	rulings []scryfall.Ruling,
-	imageQuality string,
+	imageQualities []string,
 	detailedDescription bool,
 	count int,
 	deck *plugins.Deck,
@@ -256,23 +419,28 @@ func buildMeldCard(
 		return plugins.CardInfo{}, fmt.Errorf("Scryfall client error: %v (card ID %s)", err, meldResultID)
 	}
 
-	imageURL := getImageURL(card.ImageURIs, card.HighresImage, imageQuality)
-	meldResultImageURL := getImageURL(meldResult.ImageURIs, meldResult.HighresImage, imageQuality)
+	imageURL := getImageURL(card.ImageURIs, card.HighresImage, imageQualities)
+	meldResultImageURL := getImageURL(meldResult.ImageURIs, meldResult.HighresImage, imageQualities)
 
 	if len(deck.ThumbnailURL) == 0 {
 		deck.ThumbnailURL = meldResult.ImageURIs.PNG
 	}
 
 	return plugins.CardInfo{
-		Name:        buildCardName(card),
-		Description: buildCardDescription(card, rulings, detailedDescription),
-		ImageURL:    imageURL,
-		Count:       count,
+		Name:              buildCardName(card),
+		Description:       buildCardDescription(card, rulings, detailedDescription),
+		ImageURL:          imageURL,
+		FallbackImageURLs: getFallbackImageURLs(card.ImageURIs, imageURL),
+		ArtURL:            getArtURL(card.ImageURIs),
+		Count:             count,
+		Metadata:          buildCardMetadata(card),
 		AlternativeState: &plugins.CardInfo{
-			Name:        meldResult.Name,
-			Description: buildCardDescription(meldResult, rulings, detailedDescription),
-			ImageURL:    meldResultImageURL,
-			Oversized:   true,
+			Name:              meldResult.Name,
+			Description:       buildCardDescription(meldResult, rulings, detailedDescription),
+			ImageURL:          meldResultImageURL,
+			FallbackImageURLs: getFallbackImageURLs(meldResult.ImageURIs, meldResultImageURL),
+			ArtURL:            getArtURL(meldResult.ImageURIs),
+			Oversized:         true,
 		},
 	}, nil
 }
@@ -280,7 +448,7 @@ func buildMeldCard(
 func buildDoubleFacedCard(
 	card scryfall.Card,
 	rulings []scryfall.Ruling,
-	imageQuality string,
+	imageQualities []string,
 	detailedDescription bool,
 	count int,
 	deck *plugins.Deck,
@@ -292,18 +460,27 @@ func buildDoubleFacedCard(
 	front := card.CardFaces[0]
 	back := card.CardFaces[1]
 
-	frontImageURL := getImageURL(&front.ImageURIs, card.HighresImage, imageQuality)
-	backImageURL := getImageURL(&back.ImageURIs, card.HighresImage, imageQuality)
+	frontImageURL := getImageURL(&front.ImageURIs, card.HighresImage, imageQualities)
+	backImageURL := getImageURL(&back.ImageURIs, card.HighresImage, imageQualities)
+
+	if len(deck.ThumbnailURL) == 0 {
+		deck.ThumbnailURL = front.ImageURIs.PNG
+	}
 
 	return plugins.CardInfo{
-		Name:        buildCardFaceName(front.Name, card.CMC, front.TypeLine),
-		Description: buildCardFaceDescription(front, rulings, detailedDescription),
-		ImageURL:    frontImageURL,
-		Count:       count,
+		Name:              buildCardFaceName(front.Name, card.CMC, front.TypeLine),
+		Description:       buildCardFaceDescription(front, rulings, detailedDescription),
+		ImageURL:          frontImageURL,
+		FallbackImageURLs: getFallbackImageURLs(&front.ImageURIs, frontImageURL),
+		ArtURL:            getArtURL(&front.ImageURIs),
+		Count:             count,
+		Metadata:          buildCardMetadata(card),
 		AlternativeState: &plugins.CardInfo{
-			Name:        buildCardFaceName(back.Name, card.CMC, back.TypeLine),
-			Description: buildCardFaceDescription(back, rulings, detailedDescription),
-			ImageURL:    backImageURL,
+			Name:              buildCardFaceName(back.Name, card.CMC, back.TypeLine),
+			Description:       buildCardFaceDescription(back, rulings, detailedDescription),
+			ImageURL:          backImageURL,
+			FallbackImageURLs: getFallbackImageURLs(&back.ImageURIs, backImageURL),
+			ArtURL:            getArtURL(&back.ImageURIs),
 		},
 	}, nil
 }
@@ -311,7 +488,7 @@ func buildDoubleFacedCard(
 func buildSingleFacedCard(
 	card scryfall.Card,
 	rulings []scryfall.Ruling,
-	imageQuality string,
+	imageQualities []string,
 	detailedDescription bool,
 	count int,
 	deck *plugins.Deck,
@@ -331,23 +508,25 @@ func buildSingleFacedCard(
 		description = buildCardDescription(card, rulings, detailedDescription)
 	}
 
-	imageURL := getImageURL(card.ImageURIs, card.HighresImage, imageQuality)
+	imageURL := getImageURL(card.ImageURIs, card.HighresImage, imageQualities)
 
 	if len(deck.ThumbnailURL) == 0 {
 		deck.ThumbnailURL = card.ImageURIs.PNG
 	}
 
 	return plugins.CardInfo{
-		Name:        name,
-		Description: description,
-		ImageURL:    imageURL,
-		Count:       count,
-		Oversized:   card.Oversized,
+		Name:              name,
+		Description:       description,
+		ImageURL:          imageURL,
+		FallbackImageURLs: getFallbackImageURLs(card.ImageURIs, imageURL),
+		ArtURL:            getArtURL(card.ImageURIs),
+		Count:             count,
+		Oversized:         card.Oversized,
+		Metadata:          buildCardMetadata(card),
 	}, nil
 }
 
-func cardNamesToDeck(cards *CardNames, name string, options map[string]interface{}) (*plugins.Deck, []string, error) {
-	ctx := context.Background()
+func cardNamesToDeck(ctx context.Context, cards *CardNames, name string, options map[string]interface{}) (*plugins.Deck, []string, error) {
 	deck := &plugins.Deck{
 		Name:     name,
 		BackURL:  MagicPlugin.AvailableBacks()[plugins.DefaultBackKey].URL,
@@ -355,14 +534,14 @@ func cardNamesToDeck(cards *CardNames, name string, options map[string]interface
 		Rounded:  true,
 	}
 	tokenIDs := []string{}
-	client, err := scryfall.NewClient()
+	client, err := scryfall.NewClient(scryfall.WithHTTPClient(plugins.HTTPClient()))
 	if err != nil {
 		return deck, tokenIDs, err
 	}
 
-	imageQuality := MagicPlugin.AvailableOptions()["quality"].DefaultValue.(string)
-	if quality, found := options["quality"]; found {
-		imageQuality = quality.(string)
+	imageQualities, err := selectedQualities(options)
+	if err != nil {
+		return deck, tokenIDs, err
 	}
 
 	detailedDescription := MagicPlugin.AvailableOptions()["detailed_description"].DefaultValue.(bool)
@@ -370,59 +549,116 @@ func cardNamesToDeck(cards *CardNames, name string, options map[string]interface
 		detailedDescription = description.(bool)
 	}
 
-	for _, cardInfo := range cards.Names {
-		count := cards.Count(cardInfo.Name, cardInfo.Set)
+	descriptionTmpl, err := descriptionTemplate(options)
+	if err != nil {
+		return deck, tokenIDs, err
+	}
 
-		opts := scryfall.GetCardByNameOptions{}
-		if cardInfo.Set != nil {
-			sets, err := getSets(ctx, client)
+	rulingFilter, err := parseRulingsFilter(options)
+	if err != nil {
+		return deck, tokenIDs, err
+	}
+
+	richText := false
+	if option, found := options["rich_text"]; found {
+		richText = option.(bool)
+	}
+
+	counters := MagicPlugin.AvailableOptions()["counters"].DefaultValue.(bool)
+	if option, found := options["counters"]; found {
+		counters = option.(bool)
+	}
+
+	filters := printingFilters(options)
+
+	// Resolve most of the cards with a few requests instead of one per card
+	prefetched := prefetchCards(ctx, client, cards)
+
+	for index, cardInfo := range cards.Names {
+		// Stop querying the cards if the conversion has been cancelled
+		if err := ctx.Err(); err != nil {
+			return deck, tokenIDs, err
+		}
+
+		key := cardInfo.key()
+		count := cards.Counts[key]
+		finish := cardInfo.Finish
+		category := cardInfo.Category
+		backURL := cardInfo.BackURL
+		sideways := cardInfo.Sideways
+
+		name, keep := plugins.ResolveCardName(cardInfo.Name)
+		if !keep {
+			log.Infof("Ignoring %s", cardInfo.Name)
+			continue
+		}
+		if name != cardInfo.Name {
+			log.Debugf("Using alias %s for %s", name, cardInfo.Name)
+			cardInfo.Name = name
+		}
+
+		var (
+			card          scryfall.Card
+			opts          scryfall.GetCardByNameOptions
+			exactPrinting bool
+			err           error
+		)
+
+		// Use the exact printing chosen in the deck list when it's known
+		if prefetchedCard, found := prefetched[key]; found && cardInfo.ID != nil {
+			card = prefetchedCard
+			exactPrinting = true
+		} else if cardInfo.ID != nil {
+			log.Debugf("Querying card %s (ID: %s)", cardInfo.Name, *cardInfo.ID)
+
+			card, err = getCard(ctx, client, *cardInfo.ID)
 			if err != nil {
-				return deck, tokenIDs, err
-			}
-			setName := strings.ToLower(*cardInfo.Set)
-			// Manual fix for some deckstats.net set names which differ from Scryfall set names.
-			// See https://deckstats.net/sets/?lng=en and https://scryfall.com/sets
-			if setName == "frf_ugin" {
-				setName = "ugin"
-			} else if setName == "mps_akh" {
-				setName = "mp2"
-			} else if strings.Contains(setName, "_") {
-				setName = strings.Split(setName, "_")[0]
-			}
-			if _, found := sets[setName]; found {
-				opts.Set = setName
+				log.Warnf("Couldn't retrieve printing %s of %s, searching by name instead: %v", *cardInfo.ID, cardInfo.Name, err)
 			} else {
-				for _, set := range sets {
-					if set.MTGOCode != nil && *set.MTGOCode == setName {
-						opts.Set = set.Code
-						break
-					}
-					if set.ArenaCode != nil && *set.ArenaCode == setName {
-						opts.Set = set.Code
-						break
-					}
+				exactPrinting = true
+			}
+		}
+
+		if !exactPrinting {
+			if cardInfo.Set != nil {
+				sets, err := getSets(ctx, client)
+				if err != nil {
+					return deck, tokenIDs, err
 				}
+				opts.Set = findSetCode(sets, *cardInfo.Set)
 				if len(opts.Set) == 0 {
 					log.Warnf("Set code \"%s\" not found", *cardInfo.Set)
 				}
 			}
-		}
 
-		log.Debugf("Querying card %s (set: %s)", cardInfo.Name, opts.Set)
+			if prefetchedCard, found := prefetched[key]; found && cardInfo.ID == nil {
+				card = prefetchedCard
+			} else {
+				log.Debugf("Querying card %s (set: %s)", cardInfo.Name, opts.Set)
+
+				card, err = getCardByNameAnyLanguage(ctx, client, cardInfo.Name, opts)
+				if err != nil {
+					log.Errorw(
+						"Scryfall client error",
+						"error", err,
+						"name", cardInfo.Name,
+						"options", opts,
+					)
+					return deck, tokenIDs, err
+				}
+			}
 
-		card, err := getCardByName(ctx, client, cardInfo.Name, opts)
-		if err != nil {
-			log.Errorw(
-				"Scryfall client error",
-				"error", err,
-				"name", cardInfo.Name,
-				"options", opts,
-			)
-			return deck, tokenIDs, err
+			// Look for the printing matching the filters, unless the set was
+			// chosen in the deck list
+			if len(filters) > 0 && len(opts.Set) == 0 {
+				card = findPrinting(ctx, client, card, filters)
+			}
 		}
 
 		log.Debugf("API response: %v", card)
 
+		plugins.ReportCardResolved(ctx, deck.Name, card.Name, index+1, len(cards.Names))
+
 		switch card.Layout {
 		case scryfall.LayoutToken, scryfall.LayoutDoubleFacedToken, scryfall.LayoutEmblem:
 			log.Debug("Card is a token, skipping for now")
@@ -433,7 +669,7 @@ func cardNamesToDeck(cards *CardNames, name string, options map[string]interface
 		// Retrieve the related tokens
 		tokenIDs = append(tokenIDs, parseRelatedTokenIDs(card)...)
 
-		rulings, err := checkRulings(ctx, client, card.ID, options)
+		rulings, err := checkRulings(ctx, client, card, rulingFilter)
 		if err != nil {
 			log.Errorw(
 				"Scryfall client error",
@@ -446,14 +682,22 @@ func cardNamesToDeck(cards *CardNames, name string, options map[string]interface
 
 		var cardInfo plugins.CardInfo
 
-		switch card.Layout {
-		case scryfall.LayoutMeld:
-			cardInfo, err = buildMeldCard(ctx, client, card, rulings, imageQuality, detailedDescription, count, deck)
-		case scryfall.LayoutTransform, scryfall.LayoutDoubleSided, scryfall.LayoutModalDFC:
+		switch {
+		case card.Layout == scryfall.LayoutMeld:
+			cardInfo, err = buildMeldCard(ctx, client, card, rulings, imageQualities, detailedDescription, count, deck)
+		case isDoubleFaced(card):
 			// For transform and other two-sided cards
-			cardInfo, err = buildDoubleFacedCard(card, rulings, imageQuality, detailedDescription, count, deck)
+			cardInfo, err = buildDoubleFacedCard(card, rulings, imageQualities, detailedDescription, count, deck)
 		default:
-			cardInfo, err = buildSingleFacedCard(card, rulings, imageQuality, detailedDescription, count, deck)
+			cardInfo, err = buildSingleFacedCard(card, rulings, imageQualities, detailedDescription, count, deck)
+		}
+
+		if err == nil {
+			err = applyDescriptionTemplate(descriptionTmpl, card, rulings, &cardInfo)
+		}
+
+		if err == nil && richText {
+			applyRichText(card, &cardInfo)
 		}
 
 		if err != nil {
@@ -461,6 +705,18 @@ func cardNamesToDeck(cards *CardNames, name string, options map[string]interface
 			continue
 		}
 
+		if len(finish) > 0 {
+			cardInfo.Description = appendFinish(cardInfo.Description, finish)
+		}
+
+		cardInfo.BackURL = backURL
+		cardInfo.Sideways = sideways
+		cardInfo.Metadata.Category = category
+
+		if counters {
+			addCounters(deck, findCounters(card))
+		}
+
 		deck.Cards = append(deck.Cards, cardInfo)
 
 		log.Infof("Retrieved %s", card.Name)
@@ -469,6 +725,100 @@ func cardNamesToDeck(cards *CardNames, name string, options map[string]interface
 	return deck, tokenIDs, nil
 }
 
+// findPrinting returns the most recent printing of card matching the Scryfall
+// search filters, or card if there isn't any.
+func findPrinting(ctx context.Context, client *scryfall.Client, card scryfall.Card, filters string) scryfall.Card {
+	query := `!"` + card.Name + `" ` + filters
+
+	log.Debugf("Searching printings: %s", query)
+
+	result, err := searchCards(ctx, client, query, scryfall.SearchCardsOptions{
+		Unique: scryfall.UniqueModePrints,
+		// Not defined by go-scryfall
+		Order: scryfall.Order("released"),
+		Dir:   scryfall.DirDesc,
+	})
+	if err != nil || len(result.Cards) == 0 {
+		// Scryfall returns a 404 error when no card matches the query
+		log.Infof("No printing of %s matching %s, using the default one", card.Name, filters)
+		return card
+	}
+
+	return result.Cards[0]
+}
+
+// isForeignName returns true if name contains letters of a script other
+// than Latin (e.g. Japanese or Russian), which are never used in the English
+// card names.
+func isForeignName(name string) bool {
+	for _, r := range name {
+		if unicode.IsLetter(r) && !unicode.Is(unicode.Latin, r) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// findEnglishName searches the card printed with name in any language, and
+// returns its English name.
+func findEnglishName(ctx context.Context, client *scryfall.Client, name string) (string, error) {
+	query := `"` + strings.ReplaceAll(name, `"`, "") + `" lang:any`
+
+	log.Debugf("Searching printed names: %s", query)
+
+	result, err := searchCards(ctx, client, query, scryfall.SearchCardsOptions{
+		Unique:              scryfall.UniqueModePrints,
+		IncludeMultilingual: true,
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(result.Cards) == 0 {
+		return "", fmt.Errorf("no card printed with the name %s", name)
+	}
+
+	// The search also matches the names containing these words, prefer the
+	// exact match
+	for _, card := range result.Cards {
+		if card.PrintedName != nil && strings.EqualFold(*card.PrintedName, name) {
+			return card.Name, nil
+		}
+	}
+
+	return result.Cards[0].Name, nil
+}
+
+// getCardByNameAnyLanguage queries a card by its name, which can be the
+// English name or the name printed on a card in another language.
+// The printed names are searched first when name isn't written in the Latin
+// alphabet, and when the English name isn't found otherwise (e.g. for a
+// French name).
+func getCardByNameAnyLanguage(ctx context.Context, client *scryfall.Client, name string, opts scryfall.GetCardByNameOptions) (scryfall.Card, error) {
+	if isForeignName(name) {
+		englishName, err := findEnglishName(ctx, client, name)
+		if err != nil {
+			return scryfall.Card{}, err
+		}
+		log.Infof("Found card %s from its printed name %s", englishName, name)
+		return getCardByName(ctx, client, englishName, opts)
+	}
+
+	card, err := getCardByName(ctx, client, name, opts)
+	if err == nil || ctx.Err() != nil {
+		return card, err
+	}
+
+	englishName, searchErr := findEnglishName(ctx, client, name)
+	if searchErr != nil || strings.EqualFold(englishName, name) {
+		// Return the original error
+		return card, err
+	}
+	log.Infof("Found card %s from its printed name %s", englishName, name)
+
+	return getCardByName(ctx, client, englishName, opts)
+}
+
 func removeDuplicates(s []string) []string {
 	seen := make(map[string]struct{}, len(s))
 	i := 0
@@ -483,8 +833,7 @@ func removeDuplicates(s []string) []string {
 	return s[:i]
 }
 
-func tokenIDsToDeck(tokenIDs []string, name string, options map[string]interface{}) (*plugins.Deck, error) {
-	ctx := context.Background()
+func tokenIDsToDeck(ctx context.Context, tokenIDs []string, name string, options map[string]interface{}) (*plugins.Deck, error) {
 	deck := &plugins.Deck{
 		Name:     name,
 		BackURL:  MagicPlugin.AvailableBacks()[plugins.DefaultBackKey].URL,
@@ -492,14 +841,14 @@ func tokenIDsToDeck(tokenIDs []string, name string, options map[string]interface
 		Rounded:  true,
 	}
 
-	client, err := scryfall.NewClient()
+	client, err := scryfall.NewClient(scryfall.WithHTTPClient(plugins.HTTPClient()))
 	if err != nil {
 		return deck, err
 	}
 
-	imageQuality := MagicPlugin.AvailableOptions()["quality"].DefaultValue.(string)
-	if quality, found := options["quality"]; found {
-		imageQuality = quality.(string)
+	imageQualities, err := selectedQualities(options)
+	if err != nil {
+		return deck, err
 	}
 
 	detailedDescription := MagicPlugin.AvailableOptions()["detailed_description"].DefaultValue.(bool)
@@ -507,9 +856,28 @@ func tokenIDsToDeck(tokenIDs []string, name string, options map[string]interface
 		detailedDescription = description.(bool)
 	}
 
+	descriptionTmpl, err := descriptionTemplate(options)
+	if err != nil {
+		return nil, err
+	}
+
+	rulingFilter, err := parseRulingsFilter(options)
+	if err != nil {
+		return nil, err
+	}
+
+	richText := false
+	if option, found := options["rich_text"]; found {
+		richText = option.(bool)
+	}
+
 	tokenIDs = removeDuplicates(tokenIDs)
 
-	for _, tokenID := range tokenIDs {
+	for index, tokenID := range tokenIDs {
+		if err := ctx.Err(); err != nil {
+			return deck, err
+		}
+
 		log.Debugf("Querying token ID %s", tokenID)
 
 		card, err := getCard(ctx, client, tokenID)
@@ -522,7 +890,9 @@ func tokenIDsToDeck(tokenIDs []string, name string, options map[string]interface
 			continue
 		}
 
-		rulings, err := checkRulings(ctx, client, card.ID, options)
+		plugins.ReportCardResolved(ctx, deck.Name, card.Name, index+1, len(tokenIDs))
+
+		rulings, err := checkRulings(ctx, client, card, rulingFilter)
 		if err != nil {
 			log.Errorw(
 				"Scryfall client error",
@@ -534,10 +904,19 @@ func tokenIDsToDeck(tokenIDs []string, name string, options map[string]interface
 
 		var cardInfo plugins.CardInfo
 
-		if card.Layout == scryfall.LayoutDoubleFacedToken {
-			cardInfo, err = buildDoubleFacedCard(card, rulings, imageQuality, detailedDescription, 1, deck)
+		if isDoubleFaced(card) {
+			// Day / night, transforming tokens, etc.
+			cardInfo, err = buildDoubleFacedCard(card, rulings, imageQualities, detailedDescription, 1, deck)
 		} else {
-			cardInfo, err = buildSingleFacedCard(card, rulings, imageQuality, detailedDescription, 1, deck)
+			cardInfo, err = buildSingleFacedCard(card, rulings, imageQualities, detailedDescription, 1, deck)
+		}
+
+		if err == nil {
+			err = applyDescriptionTemplate(descriptionTmpl, card, rulings, &cardInfo)
+		}
+
+		if err == nil && richText {
+			applyRichText(card, &cardInfo)
 		}
 
 		if err != nil {
@@ -551,45 +930,453 @@ func tokenIDsToDeck(tokenIDs []string, name string, options map[string]interface
 	return deck, nil
 }
 
-func fromDeckFile(file io.Reader, name string, options map[string]string) ([]*plugins.Deck, error) {
+// splitTokenFigurines returns the decks generated for the tokens of
+// tokenDeck: if the "token_figurines" option is set, the creature tokens are
+// moved to a separate deck called name, laid out as figurines. tokenDeck is
+// dropped if it only contained creature tokens.
+func splitTokenFigurines(tokenDeck *plugins.Deck, name string, options map[string]interface{}) []*plugins.Deck {
+	figurines := MagicPlugin.AvailableOptions()["token_figurines"].DefaultValue.(bool)
+	if option, found := options["token_figurines"]; found {
+		figurines = option.(bool)
+	}
+	if !figurines {
+		return []*plugins.Deck{tokenDeck}
+	}
+
+	figurineDeck := &plugins.Deck{
+		Name:      name,
+		BackURL:   tokenDeck.BackURL,
+		CardSize:  tokenDeck.CardSize,
+		Rounded:   tokenDeck.Rounded,
+		Container: plugins.ContainerFigurines,
+	}
+
+	cards := make([]plugins.CardInfo, 0, len(tokenDeck.Cards))
+	for _, card := range tokenDeck.Cards {
+		if strings.Contains(card.Metadata.Type, "Creature") {
+			figurineDeck.Cards = append(figurineDeck.Cards, card)
+		} else {
+			cards = append(cards, card)
+		}
+	}
+	tokenDeck.Cards = cards
+
+	switch {
+	case len(figurineDeck.Cards) == 0:
+		return []*plugins.Deck{tokenDeck}
+	case len(tokenDeck.Cards) == 0:
+		return []*plugins.Deck{figurineDeck}
+	default:
+		return []*plugins.Deck{tokenDeck, figurineDeck}
+	}
+}
+
+// dungeonsToDeck returns a deck containing the dungeons used by the cards of
+// decks, or nil if they don't use any, and the IDs of the tokens created by
+// the dungeons.
+func dungeonsToDeck(ctx context.Context, decks []*plugins.Deck, name string, options map[string]interface{}) (*plugins.Deck, []string, error) {
+	dungeons := requiredDungeons(decks)
+	if len(dungeons) == 0 {
+		return nil, nil, nil
+	}
+
+	cards := NewCardNames()
+	for _, dungeon := range dungeons {
+		cards.Insert(dungeon, nil)
+	}
+
+	deck, tokenIDs, err := cardNamesToDeck(ctx, cards, name, options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Make the dungeons easier to read on the table, and keep them out of
+	// the decks
+	for i := range deck.Cards {
+		deck.Cards[i].Oversized = true
+	}
+	deck.Container = plugins.ContainerTiles
+
+	return deck, tokenIDs, nil
+}
+
+// oversizedCommanderDeck returns a deck containing an oversized copy of each
+// commander, displayed face up next to the deck like the oversized commanders
+// of the preconstructed decks.
+func oversizedCommanderDeck(ctx context.Context, commanders *CardNames, name string, options map[string]interface{}) (*plugins.Deck, error) {
+	// The tokens of the commanders are already part of the main deck
+	deck, _, err := cardNamesToDeck(ctx, commanders, name, options)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range deck.Cards {
+		deck.Cards[i].Oversized = true
+	}
+	deck.Facing = plugins.FacingUp
+
+	return deck, nil
+}
+
+// commanderArtURL returns the art of the first commander of deck, or an
+// empty string if the commanders aren't known.
+func commanderArtURL(deck *plugins.Deck, commanders *CardNames) string {
+	if commanders == nil || len(commanders.Names) == 0 {
+		return ""
+	}
+
+	name, _ := plugins.ResolveCardName(commanders.Names[0].Name)
+	for _, card := range deck.Cards {
+		if strings.EqualFold(card.Metadata.Name, name) ||
+			strings.HasPrefix(strings.ToLower(card.Metadata.Name), strings.ToLower(name)+" // ") {
+			return card.ArtURL
+		}
+	}
+
+	return ""
+}
+
+// searchToDeck returns a deck containing one copy of each card returned by
+// the Scryfall search query.
+func searchToDeck(ctx context.Context, query string, name string, options map[string]interface{}) (*plugins.Deck, error) {
+	deck := &plugins.Deck{
+		Name:     name,
+		BackURL:  MagicPlugin.AvailableBacks()["m_filler"].URL,
+		CardSize: plugins.CardSizeStandard,
+		Rounded:  true,
+	}
+
+	err := appendSearchResults(ctx, deck, query, scryfall.SearchCardsOptions{
+		Unique:        scryfall.UniqueModeCards,
+		Order:         scryfall.OrderName,
+		IncludeExtras: true,
+	}, options)
+
+	return deck, err
+}
+
+// printsToDeck returns a deck containing one copy of each printing returned by
+// the Scryfall search query, in collector number order (e.g. the cards of a
+// set or of a Secret Lair drop), restricted to the rarity chosen in the
+// options. Every printing is kept unless the "variants" option is disabled.
+// The cards are placed face up, to be displayed.
+func printsToDeck(ctx context.Context, query string, name string, options map[string]string) ([]*plugins.Deck, error) {
+	// Check the options
+	validatedOptions, err := MagicPlugin.AvailableOptions().ValidateNormalize(options)
+	if err != nil {
+		return nil, err
+	}
+
+	deck := &plugins.Deck{
+		Name:     name,
+		BackURL:  MagicPlugin.AvailableBacks()[plugins.DefaultBackKey].URL,
+		CardSize: plugins.CardSizeStandard,
+		Rounded:  true,
+		Facing:   plugins.FacingUp,
+	}
+
+	if filter := rarityFilter(validatedOptions); len(filter) > 0 {
+		query = "(" + query + ") " + filter
+	}
+
+	searchOptions := scryfall.SearchCardsOptions{
+		Unique:            scryfall.UniqueModePrints,
+		Order:             scryfall.OrderSet,
+		IncludeExtras:     true,
+		IncludeVariations: true,
+	}
+	if variants, found := validatedOptions["variants"]; found && !variants.(bool) {
+		// One copy of each card, e.g. for a cube
+		searchOptions = scryfall.SearchCardsOptions{
+			Unique: scryfall.UniqueModeCards,
+			Order:  scryfall.OrderSet,
+		}
+	}
+
+	err = appendSearchResults(ctx, deck, query, searchOptions, validatedOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(deck.Cards) == 0 {
+		return nil, fmt.Errorf("no card found for %s", query)
+	}
+
+	return []*plugins.Deck{deck}, nil
+}
+
+// appendSearchResults appends one copy of each card returned by the Scryfall
+// search query to deck.
+func appendSearchResults(ctx context.Context, deck *plugins.Deck, query string, opts scryfall.SearchCardsOptions, options map[string]interface{}) error {
+	client, err := scryfall.NewClient(scryfall.WithHTTPClient(plugins.HTTPClient()))
+	if err != nil {
+		return err
+	}
+
+	imageQualities, err := selectedQualities(options)
+	if err != nil {
+		return err
+	}
+
+	detailedDescription := MagicPlugin.AvailableOptions()["detailed_description"].DefaultValue.(bool)
+	if description, found := options["detailed_description"]; found {
+		detailedDescription = description.(bool)
+	}
+
+	descriptionTmpl, err := descriptionTemplate(options)
+	if err != nil {
+		return err
+	}
+
+	rulingFilter, err := parseRulingsFilter(options)
+	if err != nil {
+		return err
+	}
+
+	richText := false
+	if option, found := options["rich_text"]; found {
+		richText = option.(bool)
+	}
+
+	opts.Page = 1
+
+	for {
+		log.Debugf("Searching cards: %s (page %d)", query, opts.Page)
+
+		result, err := searchCards(ctx, client, query, opts)
+		if err != nil {
+			return err
+		}
+
+		for _, card := range result.Cards {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			plugins.ReportCardResolved(ctx, deck.Name, card.Name, len(deck.Cards)+1, result.TotalCards)
+
+			rulings, err := checkRulings(ctx, client, card, rulingFilter)
+			if err != nil {
+				log.Errorw(
+					"Scryfall client error",
+					"error", err,
+					"id", card.ID,
+				)
+				continue
+			}
+
+			var cardInfo plugins.CardInfo
+
+			if isDoubleFaced(card) {
+				cardInfo, err = buildDoubleFacedCard(card, rulings, imageQualities, detailedDescription, 1, deck)
+			} else {
+				cardInfo, err = buildSingleFacedCard(card, rulings, imageQualities, detailedDescription, 1, deck)
+			}
+
+			if err == nil {
+				err = applyDescriptionTemplate(descriptionTmpl, card, rulings, &cardInfo)
+			}
+
+			if err == nil && richText {
+				applyRichText(card, &cardInfo)
+			}
+
+			if err != nil {
+				log.Warnf("Couldn't add card to deck: %v", err)
+				continue
+			}
+
+			deck.Cards = append(deck.Cards, cardInfo)
+		}
+
+		if !result.HasMore {
+			break
+		}
+		opts.Page++
+	}
+
+	return nil
+}
+
+// handleScryfallSetLink generates a deck containing every card of the set of
+// a Scryfall set page (e.g. https://scryfall.com/sets/sld).
+func handleScryfallSetLink(ctx context.Context, baseURL string, options map[string]string) ([]*plugins.Deck, error) {
+	parsedURL, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	code := strings.ToLower(path.Base(parsedURL.Path))
+	name := strings.ToUpper(code)
+
+	client, err := scryfall.NewClient(scryfall.WithHTTPClient(plugins.HTTPClient()))
+	if err != nil {
+		return nil, err
+	}
+
+	sets, err := getSets(ctx, client)
+	if err != nil {
+		log.Warnf("Couldn't retrieve the sets: %v", err)
+	} else if set, found := sets[code]; found {
+		name = set.Name
+	}
+
+	return printsToDeck(ctx, "e:"+code, name, options)
+}
+
+// handleScryfallSearchLink generates a deck containing every card returned by
+// a Scryfall search (e.g. the cards of a Secret Lair drop, with
+// https://scryfall.com/search?q=e%3Asld+cn%3E%3D1+cn%3C%3D5).
+func handleScryfallSearchLink(ctx context.Context, baseURL string, options map[string]string) ([]*plugins.Deck, error) {
+	parsedURL, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	query := strings.TrimSpace(parsedURL.Query().Get("q"))
+	if len(query) == 0 {
+		return nil, fmt.Errorf("no search query in %s", baseURL)
+	}
+
+	return printsToDeck(ctx, query, query, options)
+}
+
+// appendAuxiliaryDecks appends the auxiliary decks used by the cards of decks
+// (see auxiliaryDecks) to decks, unless they are disabled in the options.
+func appendAuxiliaryDecks(ctx context.Context, decks []*plugins.Deck, name string, options map[string]interface{}) []*plugins.Deck {
+	// Don't check the auxiliary decks added here
+	cardDecks := decks
+
+	for _, auxiliary := range auxiliaryDecks {
+		if generate, found := options[auxiliary.option]; found && !generate.(bool) {
+			continue
+		}
+		if !usesMechanic(cardDecks, auxiliary.regex) {
+			continue
+		}
+
+		deck, err := searchToDeck(ctx, auxiliary.query, name+" - "+auxiliary.section, options)
+		if err != nil {
+			log.Warnf("Couldn't retrieve the %s: %v", strings.ToLower(auxiliary.section), err)
+			continue
+		}
+
+		decks = append(decks, deck)
+	}
+
+	return decks
+}
+
+func fromDeckFile(ctx context.Context, file io.Reader, name string, options map[string]string) ([]*plugins.Deck, error) {
 	// Check the options
 	validatedOptions, err := MagicPlugin.AvailableOptions().ValidateNormalize(options)
 	if err != nil {
 		return nil, err
 	}
 
-	main, side, maybe, err := parseDeckFile(file)
-	if err != nil {
-		return nil, err
+	sideboardSplit := MagicPlugin.AvailableOptions()["sideboard_split"].DefaultValue.(string)
+	if split, found := validatedOptions["sideboard_split"]; found {
+		sideboardSplit = split.(string)
+	}
+
+	main, side, maybe, commanders, err := parseDeckFile(file, sideboardSplit)
+	if err != nil {
+		return nil, err
+	}
+
+	return cardNamesToDecks(ctx, main, side, maybe, commanders, name, validatedOptions)
+}
+
+// cardNamesToDecks returns the decks generated from the main deck, sideboard
+// and maybeboard (any of which can be nil), followed by the auxiliary decks
+// (commanders, dungeons, tokens, etc.). The commanders are also part of main.
+func cardNamesToDecks(
+	ctx context.Context,
+	main *CardNames,
+	side *CardNames,
+	maybe *CardNames,
+	commanders *CardNames,
+	name string,
+	validatedOptions map[string]interface{},
+) ([]*plugins.Deck, error) {
+	boards, err := selectedBoards(validatedOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	lifeTotal := 0
+	if life, found := validatedOptions["life_counter"]; found {
+		lifeTotal = life.(int)
+		if lifeTotal < 0 {
+			return nil, fmt.Errorf("invalid life_counter value: %d", lifeTotal)
+		}
 	}
 
+	checkSpelling(main, side, maybe)
+
 	var (
 		decks    []*plugins.Deck
 		tokenIDs []string
 	)
 
-	if main != nil {
-		mainDeck, mainTokenIDs, err := cardNamesToDeck(main, name, validatedOptions)
-		if err != nil {
-			return nil, err
+	if main != nil && boards[mainBoard] {
+		piles := []cardCategory{{cards: main}}
+		if categories, found := validatedOptions["categories"]; found && categories.(string) == pilesCategories {
+			piles = splitCategories(main)
 		}
 
-		decks = append(decks, mainDeck)
-		tokenIDs = append(tokenIDs, mainTokenIDs...)
+		for i, pile := range piles {
+			deckName := name
+			if len(pile.name) > 0 {
+				deckName = pileName(name, pile.name)
+			}
+
+			mainDeck, mainTokenIDs, err := cardNamesToDeck(ctx, pile.cards, deckName, validatedOptions)
+			if err != nil {
+				return nil, err
+			}
+
+			decks = append(decks, mainDeck)
+
+			if i == 0 {
+				mainDeck.LifeTotal = lifeTotal
+				mainDeck.FeaturedArtURL = commanderArtURL(mainDeck, commanders)
+
+				if oversized, found := validatedOptions["oversized_commander"]; found && oversized.(bool) && commanders != nil {
+					commanderDeck, err := oversizedCommanderDeck(ctx, commanders, name+" - Commander", validatedOptions)
+					if err != nil {
+						return nil, err
+					}
+
+					decks = append(decks, commanderDeck)
+				}
+			}
+
+			if split, found := validatedOptions["conspiracies"]; !found || split.(bool) {
+				decks = append(decks, splitConspiracies(mainDeck)...)
+			}
+			tokenIDs = append(tokenIDs, mainTokenIDs...)
+		}
 	}
 
-	if side != nil {
-		sideDeck, sideTokenIDs, err := cardNamesToDeck(side, name+" - Sideboard", validatedOptions)
+	if side != nil && boards[sideBoard] {
+		sideDeck, sideTokenIDs, err := cardNamesToDeck(ctx, side, name+" - Sideboard", validatedOptions)
 		if err != nil {
 			return nil, err
 		}
 
+		if container, found := validatedOptions["sideboard_container"]; found {
+			if err := sideDeck.Container.UnmarshalText([]byte(container.(string))); err != nil {
+				return nil, err
+			}
+		}
+
 		decks = append(decks, sideDeck)
 		tokenIDs = append(tokenIDs, sideTokenIDs...)
 	}
 
-	if maybe != nil {
-		maybeDeck, maybeTokenIDs, err := cardNamesToDeck(maybe, name+" - Maybeboard", validatedOptions)
+	if maybe != nil && boards[maybeBoard] {
+		maybeDeck, maybeTokenIDs, err := cardNamesToDeck(ctx, maybe, name+" - Maybeboard", validatedOptions)
 		if err != nil {
 			return nil, err
 		}
@@ -598,18 +1385,93 @@ func fromDeckFile(file io.Reader, name string, options map[string]string) ([]*pl
 		tokenIDs = append(tokenIDs, maybeTokenIDs...)
 	}
 
+	decks = appendAuxiliaryDecks(ctx, decks, name, validatedOptions)
+
+	if generateDungeons, found := validatedOptions["dungeons"]; !found || generateDungeons.(bool) {
+		dungeonDeck, dungeonTokenIDs, err := dungeonsToDeck(ctx, decks, name+" - Dungeons", validatedOptions)
+		if err != nil {
+			log.Warnf("Couldn't retrieve the dungeons: %v", err)
+		} else if dungeonDeck != nil {
+			decks = append(decks, dungeonDeck)
+			tokenIDs = append(tokenIDs, dungeonTokenIDs...)
+		}
+	}
+
 	if generateTokens, found := validatedOptions["tokens"]; (!found || generateTokens.(bool)) && len(tokenIDs) > 0 {
-		tokenDeck, err := tokenIDsToDeck(tokenIDs, name+" - Tokens", validatedOptions)
+		tokenDeck, err := tokenIDsToDeck(ctx, tokenIDs, name+" - Tokens", validatedOptions)
 		if err != nil {
 			return nil, err
 		}
 
-		decks = append(decks, tokenDeck)
+		decks = append(decks, splitTokenFigurines(tokenDeck, name+" - Token Figurines", validatedOptions)...)
 	}
 
+	warnCounts(decks, name, validatedOptions)
+
 	return decks, nil
 }
 
+// cardCategory contains the cards of a category of the main deck.
+type cardCategory struct {
+	// name of the category, empty for the cards found before the first
+	// category header.
+	name  string
+	cards *CardNames
+}
+
+// splitCategories returns the cards of main grouped by category, in the
+// order of the deck list. The cards without a category come first.
+func splitCategories(main *CardNames) []cardCategory {
+	var (
+		categories []cardCategory
+		indexes    = make(map[string]int)
+	)
+
+	for _, cardInfo := range main.Names {
+		index, found := indexes[cardInfo.Category]
+		if !found {
+			index = len(categories)
+			indexes[cardInfo.Category] = index
+			categories = append(categories, cardCategory{
+				name:  cardInfo.Category,
+				cards: NewCardNames(),
+			})
+		}
+		categories[index].cards.InsertCardInfo(cardInfo, main.Counts[cardInfo.key()])
+	}
+
+	if index, found := indexes[""]; found && index > 0 {
+		uncategorized := categories[index]
+		copy(categories[1:index+1], categories[:index])
+		categories[0] = uncategorized
+	}
+
+	return categories
+}
+
+// addCompanions adds the companions to the sideboard, unless they are
+// already part of it.
+func addCompanions(side *CardNames, companions *CardNames) *CardNames {
+	if side == nil {
+		side = NewCardNames()
+	}
+
+	for _, companion := range companions.Names {
+		found := false
+		for _, cardInfo := range side.Names {
+			if strings.EqualFold(cardInfo.Name, companion.Name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			side.InsertCardInfo(companion, companions.Counts[companion.key()])
+		}
+	}
+
+	return side
+}
+
 func parseDeckLine(
 	line string,
 	main *CardNames,
@@ -626,6 +1488,8 @@ func parseDeckLine(
 	bool,
 	int,
 ) {
+	line, annotations := parseCardAnnotations(line)
+
 	// Try to parse the line
 	for _, regex := range cardLineRegexps {
 		matches := regex.FindStringSubmatch(line)
@@ -654,14 +1518,7 @@ func parseDeckLine(
 				// already have cards in the sideboard
 				// That means we found an empty line beforehand,
 				// assuming this would be the sideboard separator
-				main.Names = append(main.Names, side.Names...)
-				for idx, count := range side.Counts {
-					if originalCount, found := main.Counts[idx]; found {
-						main.Counts[idx] = originalCount + count
-					} else {
-						main.Counts[idx] = count
-					}
-				}
+				main = moveToMain(main, side)
 				side = nil
 			}
 
@@ -685,7 +1542,7 @@ func parseDeckLine(
 			log.Errorf("Error when parsing count: %s", err)
 			continue
 		}
-		name := strings.TrimSpace(matches[nameIdx])
+		name := plugins.NormalizeName(strings.TrimSpace(matches[nameIdx]))
 
 		// Some formats use 3 slashes for split cards
 		// Since Scryfall uses 2 slashes, replace them
@@ -703,21 +1560,27 @@ func parseDeckLine(
 			"groupNames", groupNames,
 		)
 
+		cardInfo := annotations
+		cardInfo.Name = name
+		if cardInfo.Set == nil {
+			cardInfo.Set = set
+		}
+
 		if step == Main {
 			if main == nil {
 				main = NewCardNames()
 			}
-			main.InsertCount(name, set, count)
+			main.InsertCardInfo(cardInfo, count)
 		} else if step == Sideboard {
 			if side == nil {
 				side = NewCardNames()
 			}
-			side.InsertCount(name, set, count)
+			side.InsertCardInfo(cardInfo, count)
 		} else if step == Maybeboard {
 			if maybe == nil {
 				maybe = NewCardNames()
 			}
-			maybe.InsertCount(name, set, count)
+			maybe.InsertCardInfo(cardInfo, count)
 		} else {
 			log.Errorw(
 				"Found card info but deck not specified",
@@ -731,13 +1594,130 @@ func parseDeckLine(
 	return main, side, maybe, step, sbLineFound, emptyLineCount
 }
 
-func parseDeckFile(file io.Reader) (*CardNames, *CardNames, *CardNames, error) {
+// parseCardRole removes the commander or companion marker of a deck list
+// line, used by several exporters (e.g. "1x Atraxa, Praetors' Voice *CMDR*",
+// "1 Atraxa, Praetors' Voice [Commander]" or "1 Lurrus of the Dream-Den
+// !Companion"), and returns the line and Commander, Companion or Main if the
+// line has no marker.
+func parseCardRole(line string) (string, DeckType) {
+	role := Main
+
+	for _, match := range cardRoleRegex.FindAllStringSubmatch(line, -1) {
+		marker := strings.ToLower(match[1])
+		if strings.Contains(marker, "companion") {
+			role = Companion
+		} else {
+			role = Commander
+		}
+	}
+
+	if role != Main {
+		line = cardRoleRegex.ReplaceAllString(line, "")
+	}
+
+	return line, role
+}
+
+// parseCardAnnotations removes the annotations at the end of a deck list
+// line, and returns the line and the card overrides they contain:
+//
+//	1 Lightning Bolt (LEB) [foil] {back=planechase, sideways}
+//
+// The supported overrides are "set" (set code), "id" (Scryfall ID of the
+// printing), "finish", "back" (name of a card back or URL) and "sideways".
+func parseCardAnnotations(line string) (string, CardInfo) {
+	var (
+		cardInfo    CardInfo
+		annotations []string
+	)
+
+	for {
+		loc := cardAnnotationRegex.FindStringSubmatchIndex(line)
+		if loc == nil {
+			break
+		}
+		annotations = append([]string{line[loc[2]:loc[3]]}, annotations...)
+		line = line[:loc[0]]
+	}
+
+	for _, annotation := range annotations {
+		if strings.HasPrefix(annotation, "[") {
+			cardInfo.Finish = strings.Title(strings.ToLower(strings.Trim(annotation, "[]")))
+			continue
+		}
+
+		for _, override := range strings.Split(strings.Trim(annotation, "{}"), ",") {
+			override = strings.TrimSpace(override)
+			if len(override) == 0 {
+				continue
+			}
+
+			key, value := override, ""
+			if idx := strings.Index(override, "="); idx != -1 {
+				key = strings.TrimSpace(override[:idx])
+				value = strings.TrimSpace(override[idx+1:])
+			}
+			key = strings.ToLower(key)
+
+			switch key {
+			case "set":
+				set := strings.ToUpper(value)
+				cardInfo.Set = &set
+			case "id":
+				id := value
+				cardInfo.ID = &id
+			case "finish":
+				cardInfo.Finish = strings.Title(strings.ToLower(value))
+			case "back":
+				if back, found := MagicPlugin.AvailableBacks()[value]; found {
+					cardInfo.BackURL = back.URL
+				} else if u, err := url.Parse(value); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+					cardInfo.BackURL = value
+				} else {
+					log.Warnf("Invalid card back in annotation %s: %s", annotation, value)
+				}
+			case "sideways":
+				sideways, err := strconv.ParseBool(value)
+				cardInfo.Sideways = len(value) == 0 || (err == nil && sideways)
+			default:
+				log.Warnf("Unknown card annotation: %s", override)
+			}
+		}
+	}
+
+	return line, cardInfo
+}
+
+// moveToMain adds the cards of side to main, when the empty line assumed to
+// start the sideboard was only separating two categories of the main deck.
+func moveToMain(main *CardNames, side *CardNames) *CardNames {
+	if main == nil {
+		main = NewCardNames()
+	}
+
+	for _, cardInfo := range side.Names {
+		main.InsertCardInfo(cardInfo, side.Counts[cardInfo.key()])
+	}
+
+	return main
+}
+
+// parseDeckFile parses a deck list, returning its main deck, sideboard,
+// maybeboard and commanders. split is the strategy used to find the start of
+// the sideboard (see the "sideboard_split" option).
+func parseDeckFile(file io.Reader, split string) (*CardNames, *CardNames, *CardNames, *CardNames, error) {
 	var (
-		main  *CardNames
-		side  *CardNames
-		maybe *CardNames
+		main       *CardNames
+		side       *CardNames
+		maybe      *CardNames
+		commanders *CardNames
+		companions *CardNames
 	)
 	step := Main
+	// about is set while skipping the "About" section of the Arena exports
+	about := false
+	// category is the last category header found in the main deck
+	category := ""
 	scanner := bufio.NewScanner(file)
 	sbLineFound := false
 	emptyLineCount := 0
@@ -747,29 +1727,80 @@ func parseDeckFile(file io.Reader) (*CardNames, *CardNames, *CardNames, error) {
 
 		if len(line) == 0 {
 			// Empty line
-			// If we already found several main deck cards (two or less could be the commanders),
-			// this empty line means we switched to the sideboard
-			if main != nil && len(main.Names) > 2 {
-				if step == Main {
+			if about {
+				about = false
+				continue
+			}
+			// The commanders and companions are followed by the rest of the
+			// main deck
+			if step == Commander || step == Companion {
+				step = Main
+				log.Debug("Switched to main deck (found empty line after the commanders or companions)")
+				continue
+			}
+			switch split {
+			case heuristicSplit:
+				// If we already found several main deck cards (two or less could be the commanders),
+				// this empty line means we switched to the sideboard
+				if main != nil && len(main.Names) > 2 {
+					if step == Main {
+						step = Sideboard
+						log.Debug("Switched to sideboard (found empty line)")
+					}
+					emptyLineCount++
+				}
+			case blankLineSplit:
+				if main != nil && step == Main && !sbLineFound {
 					step = Sideboard
 					log.Debug("Switched to sideboard (found empty line)")
 				}
-				emptyLineCount++
 			}
 			continue
 		}
 
-		if strings.HasPrefix(line, "Sideboard") {
-			if step == Main {
-				step = Sideboard
-				log.Debug("Switched to sideboard (found comment)")
+		if match := sectionHeaderRegex.FindStringSubmatch(line); match != nil {
+			about = false
+			category = ""
+
+			switch strings.TrimSuffix(strings.ToLower(match[1]), "s") {
+			case "sideboard":
+				if step != Maybeboard {
+					if !sbLineFound && step == Sideboard && side != nil {
+						// The cards found after an empty line were another
+						// category of the main deck
+						main = moveToMain(main, side)
+						side = nil
+					}
+					step = Sideboard
+					sbLineFound = true
+					log.Debug("Switched to sideboard (found comment)")
+				}
+			case "maybeboard":
+				step = Maybeboard
+				log.Debug("Switched to maybeboard (found comment)")
+			case "commander":
+				if step == Main || step == Companion {
+					step = Commander
+					log.Debug("Switched to commanders (found comment)")
+				}
+			case "companion":
+				if step == Main || step == Commander {
+					step = Companion
+					log.Debug("Switched to companions (found comment)")
+				}
+			case "deck":
+				if step == Commander || step == Companion {
+					step = Main
+					log.Debug("Switched to main deck (found comment)")
+				}
+			case "about":
+				// Arena exports start with the name of the deck
+				about = true
 			}
 			continue
 		}
 
-		if strings.HasPrefix(line, "Maybeboard") {
-			step = Maybeboard
-			log.Debug("Switched to maybeboard (found comment)")
+		if about {
 			continue
 		}
 
@@ -778,6 +1809,56 @@ func parseDeckFile(file io.Reader) (*CardNames, *CardNames, *CardNames, error) {
 			continue
 		}
 
+		if match := categoryHeaderRegex.FindStringSubmatch(line); match != nil {
+			if step == Sideboard && !sbLineFound && split != blankLineSplit {
+				// The empty line before this header was separating two
+				// categories of the main deck
+				if side != nil {
+					main = moveToMain(main, side)
+					side = nil
+				}
+				step = Main
+				log.Debug("Switched back to main deck (found category header)")
+			}
+			if step == Main {
+				category = strings.TrimSpace(match[1])
+				log.Debugf("Found category %s", category)
+			}
+			continue
+		}
+
+		// The commanders and companions can also be marked on each line
+		line, role := parseCardRole(line)
+		if role == Main {
+			role = step
+		}
+
+		if role == Companion {
+			// The companions are added to the sideboard once parsed
+			companions, _, _, _, _, _ = parseDeckLine(line, companions, nil, nil, Main, true, 0)
+			continue
+		}
+
+		if role == Commander {
+			// The commanders are also added to the main deck below
+			commanders, _, _, _, _, _ = parseDeckLine(line, commanders, nil, nil, Main, true, 0)
+			main, side, maybe, _, sbLineFound, emptyLineCount = parseDeckLine(
+				line,
+				main,
+				side,
+				maybe,
+				Main,
+				sbLineFound,
+				emptyLineCount,
+			)
+			continue
+		}
+
+		mainCount := 0
+		if main != nil {
+			mainCount = len(main.Names)
+		}
+
 		main, side, maybe, step, sbLineFound, emptyLineCount = parseDeckLine(
 			line,
 			main,
@@ -787,22 +1868,26 @@ func parseDeckFile(file io.Reader) (*CardNames, *CardNames, *CardNames, error) {
 			sbLineFound,
 			emptyLineCount,
 		)
+
+		if main != nil && len(main.Names) > mainCount && len(category) > 0 {
+			// A new card was added to the main deck
+			main.Names[len(main.Names)-1].Category = category
+		}
 	}
 
-	if side != nil && !sbLineFound && emptyLineCount > 1 {
+	if side != nil && !sbLineFound && emptyLineCount > 1 && split == heuristicSplit {
 		// Multiple empty lines with no line starting with "SB:", that means
 		// there was no sideboard
-		main.Names = append(main.Names, side.Names...)
-		for idx, count := range side.Counts {
-			if originalCount, found := main.Counts[idx]; found {
-				main.Counts[idx] = originalCount + count
-			} else {
-				main.Counts[idx] = count
-			}
-		}
+		main = moveToMain(main, side)
 		side = nil
 	}
 
+	// The companions start the game outside of it, like the sideboard
+	// (Arena also lists them in the sideboard)
+	if companions != nil {
+		side = addCompanions(side, companions)
+	}
+
 	if main != nil {
 		log.Debugf("Main: %d different card(s)\n%v", len(main.Names), main)
 	} else {
@@ -814,27 +1899,31 @@ func parseDeckFile(file io.Reader) (*CardNames, *CardNames, *CardNames, error) {
 		log.Debug("Sideboard: 0 cards")
 	}
 	if maybe != nil {
-		log.Debugf("Maybeboard: %d different card(s)\n%v", len(maybe.Names), side)
+		log.Debugf("Maybeboard: %d different card(s)\n%v", len(maybe.Names), maybe)
 	} else {
 		log.Debug("Maybeboard: 0 cards")
 	}
 
+	if commanders != nil {
+		log.Debugf("Commanders: %d different card(s)\n%v", len(commanders.Names), commanders)
+	}
+
 	if err := scanner.Err(); err != nil {
 		log.Error(err)
-		return main, side, maybe, err
+		return main, side, maybe, commanders, err
 	}
 
-	return main, side, maybe, nil
+	return main, side, maybe, commanders, nil
 }
 
-func queryDeckFile(fileURL string, deckName string, options map[string]string) (decks []*plugins.Deck, err error) {
+func queryDeckFile(ctx context.Context, fileURL string, deckName string, options map[string]string) (decks []*plugins.Deck, err error) {
 	// Build the request
-	req, err := http.NewRequest("GET", fileURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", fileURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't create request for %s: %w", fileURL, err)
 	}
 
-	client := &http.Client{}
+	client := plugins.HTTPClient()
 
 	// Send the request
 	resp, err := client.Do(req)
@@ -847,12 +1936,12 @@ func queryDeckFile(fileURL string, deckName string, options map[string]string) (
 		}
 	}()
 
-	return fromDeckFile(resp.Body, deckName, options)
+	return fromDeckFile(ctx, resp.Body, deckName, options)
 }
 
-func handleLink(url, titleXPath, fileURL string, options map[string]string) (decks []*plugins.Deck, err error) {
+func handleLink(ctx context.Context, url, titleXPath, fileURL string, options map[string]string) (decks []*plugins.Deck, err error) {
 	log.Infof("Checking %s", url)
-	doc, err := htmlquery.LoadURL(url)
+	doc, err := plugins.LoadURL(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't query %s: %w", url, err)
 	}
@@ -865,13 +1954,13 @@ func handleLink(url, titleXPath, fileURL string, options map[string]string) (dec
 	deckName := strings.TrimSpace(htmlquery.InnerText(title))
 	log.Infof("Found title: %s", deckName)
 
-	return queryDeckFile(fileURL, deckName, options)
+	return queryDeckFile(ctx, fileURL, deckName, options)
 }
 
 // tappedout.net CSV format
-func handleCSVLink(url, titleXPath, fileURL string, options map[string]string) (decks []*plugins.Deck, err error) {
+func handleCSVLink(ctx context.Context, url, titleXPath, fileURL string, options map[string]string) (decks []*plugins.Deck, err error) {
 	log.Infof("Checking %s", url)
-	doc, err := htmlquery.LoadURL(url)
+	doc, err := plugins.LoadURL(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't query %s: %w", url, err)
 	}
@@ -885,12 +1974,12 @@ func handleCSVLink(url, titleXPath, fileURL string, options map[string]string) (
 	log.Infof("Found title: %s", deckName)
 
 	// Build the request
-	req, err := http.NewRequest("GET", fileURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", fileURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't create request for %s: %w", fileURL, err)
 	}
 
-	client := &http.Client{}
+	client := plugins.HTTPClient()
 
 	// Send the request
 	resp, err := client.Do(req)
@@ -988,7 +2077,11 @@ func handleCSVLink(url, titleXPath, fileURL string, options map[string]string) (
 			sb.WriteString("\n")
 		}
 	}
-	printCards(&sb, commanders)
+	if len(commanders) > 0 {
+		sb.WriteString("Commander\n")
+		printCards(&sb, commanders)
+		sb.WriteString("Deck\n")
+	}
 	printCards(&sb, main)
 	if len(sideboard) > 0 {
 		sb.WriteString("Sideboard\n")
@@ -999,13 +2092,13 @@ func handleCSVLink(url, titleXPath, fileURL string, options map[string]string) (
 	}
 	printCards(&sb, maybeboard)
 
-	return fromDeckFile(strings.NewReader(sb.String()), deckName, options)
+	return fromDeckFile(ctx, strings.NewReader(sb.String()), deckName, options)
 }
 
 // deckbox.org exports it's decks in HTML for some reason
-func handleHTMLLink(url, titleXPath, fileURL string, options map[string]string) ([]*plugins.Deck, error) {
+func handleHTMLLink(ctx context.Context, url, titleXPath, fileURL string, options map[string]string) ([]*plugins.Deck, error) {
 	log.Infof("Checking %s", url)
-	doc, err := htmlquery.LoadURL(url)
+	doc, err := plugins.LoadURL(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't query %s: %w", url, err)
 	}
@@ -1019,7 +2112,7 @@ func handleHTMLLink(url, titleXPath, fileURL string, options map[string]string)
 	log.Infof("Found title: %s", name)
 
 	// Retrieve the file
-	htmlFile, err := htmlquery.LoadURL(fileURL)
+	htmlFile, err := plugins.LoadURL(ctx, fileURL)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't query %s: %w", fileURL, err)
 	}
@@ -1059,12 +2152,12 @@ func handleHTMLLink(url, titleXPath, fileURL string, options map[string]string)
 
 	log.Debugf("Retrieved deck: %s", buffer.String())
 
-	return fromDeckFile(bytes.NewReader(buffer.Bytes()), name, options)
+	return fromDeckFile(ctx, bytes.NewReader(buffer.Bytes()), name, options)
 }
 
-func handleLinkWithDownloadLink(url, titleXPath, fileXPath, baseURL string, options map[string]string) (decks []*plugins.Deck, err error) {
+func handleLinkWithDownloadLink(ctx context.Context, url, titleXPath, fileXPath, baseURL string, options map[string]string) (decks []*plugins.Deck, err error) {
 	log.Infof("Checking %s", url)
-	doc, err := htmlquery.LoadURL(url)
+	doc, err := plugins.LoadURL(ctx, url)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't query %s: %w", url, err)
 	}
@@ -1085,7 +2178,7 @@ func handleLinkWithDownloadLink(url, titleXPath, fileXPath, baseURL string, opti
 	fileURL := baseURL + htmlquery.InnerText(a)
 	log.Infof("Found file URL: %s", fileURL)
 
-	return queryDeckFile(fileURL, deckName, options)
+	return queryDeckFile(ctx, fileURL, deckName, options)
 }
 
 type moxfieldDeck struct {
@@ -1100,6 +2193,15 @@ type moxfieldDeck struct {
 	Companions      map[string]moxfieldCard `json:"companions"`
 	CommandersCount int                     `json:"commandersCount"`
 	Commanders      map[string]moxfieldCard `json:"commanders"`
+	// Bonus boards, for the Unfinity and Unstable mechanics
+	Attractions   map[string]moxfieldCard `json:"attractions"`
+	Stickers      map[string]moxfieldCard `json:"stickers"`
+	Contraptions  map[string]moxfieldCard `json:"contraptions"`
+	CreatedByUser moxfieldUser            `json:"createdByUser"`
+}
+
+type moxfieldUser struct {
+	UserName string `json:"userName"`
 }
 
 type moxfieldCard struct {
@@ -1117,7 +2219,7 @@ type moxfieldCardInfo struct {
 	Name       string `json:"name"`
 }
 
-func handleMoxfieldLink(baseURL string, options map[string]string) (decks []*plugins.Deck, err error) {
+func handleMoxfieldLink(ctx context.Context, baseURL string, options map[string]string) (decks []*plugins.Deck, err error) {
 	parsedURL, err := url.Parse(baseURL)
 	if err != nil {
 		return nil, err
@@ -1127,12 +2229,12 @@ func handleMoxfieldLink(baseURL string, options map[string]string) (decks []*plu
 	deckInfoURL := "https://api.moxfield.com/v2/decks/all/" + deckID
 
 	// Build the request
-	req, err := http.NewRequest("GET", deckInfoURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", deckInfoURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't create request for %s: %w", deckInfoURL, err)
 	}
 
-	client := &http.Client{}
+	client := plugins.HTTPClient()
 
 	// Send the request
 	resp, err := client.Do(req)
@@ -1153,34 +2255,54 @@ func handleMoxfieldLink(baseURL string, options map[string]string) (decks []*plu
 	}
 	deckName := data.Name
 
-	var sb strings.Builder
+	validatedOptions, err := MagicPlugin.AvailableOptions().ValidateNormalize(options)
+	if err != nil {
+		return nil, err
+	}
 
-	printCards := func(sb *strings.Builder, cards map[string]moxfieldCard) {
-		for name, card := range cards {
-			sb.WriteString(strconv.Itoa(card.Quantity))
-			sb.WriteString(" ")
-			sb.WriteString(name)
-			if len(card.CardInfo.Set) > 0 {
-				sb.WriteString(" (")
-				sb.WriteString(strings.ToUpper(card.CardInfo.Set))
-				sb.WriteString(")")
-			}
-			sb.WriteString("\n")
-		}
+	// The commanders and companions are also part of the main deck
+	decks, err = cardNamesToDecks(
+		ctx,
+		moxfieldCardNames(data.Commanders, data.Companions, data.Mainboard),
+		moxfieldCardNames(data.Sideboard),
+		moxfieldCardNames(data.Maybeboard),
+		moxfieldCardNames(data.Commanders),
+		deckName,
+		validatedOptions,
+	)
+	if err != nil {
+		return decks, err
 	}
-	printCards(&sb, data.Commanders)
-	printCards(&sb, data.Companions)
-	printCards(&sb, data.Mainboard)
-	if data.SideboardCount > 0 {
-		sb.WriteString("Sideboard\n")
+
+	// The bonus boards replace the decks generated when a card uses their
+	// mechanic, since they contain the cards actually chosen by the player
+	bonusBoards := []struct {
+		section string
+		cards   map[string]moxfieldCard
+	}{
+		{"Attractions", data.Attractions},
+		{"Stickers", data.Stickers},
+		{"Contraptions", data.Contraptions},
 	}
-	printCards(&sb, data.Sideboard)
-	if data.MaybeboardCount > 0 {
-		sb.WriteString("Maybeboard\n")
+
+	for _, board := range bonusBoards {
+		cardNames := moxfieldCardNames(board.cards)
+		if cardNames == nil {
+			continue
+		}
+
+		deck, _, err := cardNamesToDeck(ctx, cardNames, deckName+" - "+board.section, validatedOptions)
+		if err != nil {
+			return decks, err
+		}
+		deck.BackURL = MagicPlugin.AvailableBacks()["m_filler"].URL
+
+		decks = replaceDeck(decks, deck)
 	}
-	printCards(&sb, data.Maybeboard)
 
-	return fromDeckFile(strings.NewReader(sb.String()), deckName, options)
+	plugins.SetAuthor(decks, data.CreatedByUser.UserName)
+
+	return decks, nil
 }
 
 type manaStackDeckOwner struct {
@@ -1201,6 +2323,7 @@ type manaStackCardInfo struct {
 
 type manaStackCard struct {
 	Card       manaStackCardInfo `json:"card"`
+	Count      int               `json:"count"`
 	Commander  bool              `json:"commander"`
 	Sideboard  bool              `json:"sideboard"`
 	Maybeboard bool              `json:"maybeboard"`
@@ -1212,7 +2335,7 @@ type manaStackDeck struct {
 	Owner manaStackDeckOwner `json:"owner"`
 }
 
-func handleManaStackLink(baseURL string, options map[string]string) (decks []*plugins.Deck, err error) {
+func handleManaStackLink(ctx context.Context, baseURL string, options map[string]string) (decks []*plugins.Deck, err error) {
 	log.Infof("Checking %s", baseURL)
 
 	parsedURL, err := url.Parse(baseURL)
@@ -1224,12 +2347,12 @@ func handleManaStackLink(baseURL string, options map[string]string) (decks []*pl
 	deckInfoURL := "https://manastack.com/api/deck?slug=" + slug
 
 	// Build the request
-	req, err := http.NewRequest("GET", deckInfoURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", deckInfoURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't create request for %s: %w", deckInfoURL, err)
 	}
 
-	client := &http.Client{}
+	client := plugins.HTTPClient()
 
 	// Send the request
 	resp, err := client.Do(req)
@@ -1250,44 +2373,41 @@ func handleManaStackLink(baseURL string, options map[string]string) (decks []*pl
 	}
 	deckName := data.Name
 
-	commanders := make([]string, 0, 2)
-	main := make([]string, 0, len(data.Cards))
-	sideboard := make([]string, 0, len(data.Cards))
-	maybeboard := make([]string, 0, len(data.Cards))
+	commanders := make([]manaStackCard, 0, 2)
+	main := make([]manaStackCard, 0, len(data.Cards))
+	sideboard := make([]manaStackCard, 0, len(data.Cards))
+	maybeboard := make([]manaStackCard, 0, len(data.Cards))
 
 	for _, card := range data.Cards {
 		if card.Commander {
-			commanders = append(commanders, card.Card.Name)
+			commanders = append(commanders, card)
 		} else if card.Sideboard {
-			sideboard = append(sideboard, card.Card.Name)
+			sideboard = append(sideboard, card)
 		} else if card.Maybeboard {
-			maybeboard = append(maybeboard, card.Card.Name)
+			maybeboard = append(maybeboard, card)
 		} else {
-			main = append(main, card.Card.Name)
+			main = append(main, card)
 		}
 	}
 
-	var sb strings.Builder
-
-	printCards := func(sb *strings.Builder, cards []string) {
-		for _, card := range cards {
-			sb.WriteString("1 ")
-			sb.WriteString(card)
-			sb.WriteString("\n")
-		}
-	}
-	printCards(&sb, commanders)
-	printCards(&sb, main)
-	if len(sideboard) > 0 {
-		sb.WriteString("Sideboard\n")
-	}
-	printCards(&sb, sideboard)
-	if len(maybeboard) > 0 {
-		sb.WriteString("Maybeboard\n")
+	validatedOptions, err := MagicPlugin.AvailableOptions().ValidateNormalize(options)
+	if err != nil {
+		return nil, err
 	}
-	printCards(&sb, maybeboard)
 
-	return fromDeckFile(strings.NewReader(sb.String()), deckName, options)
+	// The commanders are also part of the main deck
+	decks, err = cardNamesToDecks(
+		ctx,
+		manaStackCardNames(append(commanders, main...)),
+		manaStackCardNames(sideboard),
+		manaStackCardNames(maybeboard),
+		manaStackCardNames(commanders),
+		deckName,
+		validatedOptions,
+	)
+	plugins.SetAuthor(decks, data.Owner.Username)
+
+	return decks, err
 }
 
 type archidektOwner struct {
@@ -1328,7 +2448,7 @@ type archidektDeck struct {
 	Cards       []archidektCard `json:"cards"`
 }
 
-func handleArchidektLink(baseURL string, options map[string]string) (decks []*plugins.Deck, err error) {
+func handleArchidektLink(ctx context.Context, baseURL string, options map[string]string) (decks []*plugins.Deck, err error) {
 	log.Infof("Checking %s", baseURL)
 
 	parsedURL, err := url.Parse(baseURL)
@@ -1340,12 +2460,12 @@ func handleArchidektLink(baseURL string, options map[string]string) (decks []*pl
 	deckInfoURL := "https://archidekt.com/api/decks/" + id + "/small/"
 
 	// Build the request
-	req, err := http.NewRequest("GET", deckInfoURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", deckInfoURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't create request for %s: %w", deckInfoURL, err)
 	}
 
-	client := &http.Client{}
+	client := plugins.HTTPClient()
 
 	// Send the request
 	resp, err := client.Do(req)
@@ -1384,31 +2504,137 @@ func handleArchidektLink(baseURL string, options map[string]string) (decks []*pl
 		}
 	}
 
-	var sb strings.Builder
+	validatedOptions, err := MagicPlugin.AvailableOptions().ValidateNormalize(options)
+	if err != nil {
+		return nil, err
+	}
 
-	printCards := func(sb *strings.Builder, cards []archidektCard) {
-		for _, card := range cards {
-			sb.WriteString(strconv.Itoa(card.Quantity))
-			sb.WriteString(" ")
-			sb.WriteString(card.Card.OracleCard.Name)
-			sb.WriteString(" (")
-			sb.WriteString(strings.ToUpper(card.Card.Edition.Code))
-			sb.WriteString(")")
-			sb.WriteString("\n")
+	// The Scryfall ID of the chosen printing is used directly, instead of
+	// converting the deck to a deck list
+	// The commanders are also part of the main deck
+	decks, err = cardNamesToDecks(
+		ctx,
+		archidektCardNames(append(commanders, main...)),
+		archidektCardNames(sideboard),
+		archidektCardNames(maybeboard),
+		archidektCardNames(commanders),
+		deckName,
+		validatedOptions,
+	)
+	plugins.SetAuthor(decks, data.Owner.Username)
+
+	return decks, err
+}
+
+// manaStackCardNames returns the cards and their set, or nil if cards is
+// empty.
+func manaStackCardNames(cards []manaStackCard) *CardNames {
+	if len(cards) == 0 {
+		return nil
+	}
+
+	cardNames := NewCardNames()
+
+	for _, card := range cards {
+		var set *string
+		if len(card.Card.Set.Slug) > 0 {
+			slug := strings.ToUpper(card.Card.Set.Slug)
+			set = &slug
+		}
+
+		count := card.Count
+		if count <= 0 {
+			count = 1
 		}
+
+		cardNames.InsertCount(card.Card.Name, set, count)
 	}
-	printCards(&sb, commanders)
-	printCards(&sb, main)
-	if len(sideboard) > 0 {
-		sb.WriteString("Sideboard\n")
+
+	return cardNames
+}
+
+// moxfieldCardNames returns the exact printings and finishes of the cards of
+// boards, sorted by name inside each board, or nil if boards are empty.
+func moxfieldCardNames(boards ...map[string]moxfieldCard) *CardNames {
+	var cardNames *CardNames
+
+	for _, board := range boards {
+		names := make([]string, 0, len(board))
+		for name := range board {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			card := board[name]
+			cardInfo := CardInfo{
+				Name: name,
+			}
+			if len(card.CardInfo.Set) > 0 {
+				set := strings.ToUpper(card.CardInfo.Set)
+				cardInfo.Set = &set
+			}
+			if len(card.CardInfo.ScryfallID) > 0 {
+				id := card.CardInfo.ScryfallID
+				cardInfo.ID = &id
+			}
+			if card.IsFoil {
+				cardInfo.Finish = "Foil"
+			}
+
+			if cardNames == nil {
+				cardNames = NewCardNames()
+			}
+			cardNames.InsertCardInfo(cardInfo, card.Quantity)
+		}
 	}
-	printCards(&sb, sideboard)
-	if len(maybeboard) > 0 {
-		sb.WriteString("Maybeboard\n")
+
+	return cardNames
+}
+
+// replaceDeck replaces the deck of decks having the same name as deck, or
+// appends deck to decks if there isn't any.
+func replaceDeck(decks []*plugins.Deck, deck *plugins.Deck) []*plugins.Deck {
+	for i := range decks {
+		if decks[i].Name == deck.Name {
+			decks[i] = deck
+			return decks
+		}
 	}
-	printCards(&sb, maybeboard)
 
-	return fromDeckFile(strings.NewReader(sb.String()), deckName, options)
+	return append(decks, deck)
+}
+
+// archidektCardNames returns the exact printings and finishes of cards, or nil
+// if cards is empty.
+func archidektCardNames(cards []archidektCard) *CardNames {
+	if len(cards) == 0 {
+		return nil
+	}
+
+	cardNames := NewCardNames()
+
+	for _, card := range cards {
+		cardInfo := CardInfo{
+			Name: card.Card.OracleCard.Name,
+		}
+		if len(card.Card.Edition.Code) > 0 {
+			set := strings.ToUpper(card.Card.Edition.Code)
+			cardInfo.Set = &set
+		}
+		if len(card.Card.SkryfallID) > 0 {
+			id := card.Card.SkryfallID
+			cardInfo.ID = &id
+		}
+		// Normal, Foil or Etched
+		if len(card.Modifier) > 0 && card.Modifier != "Normal" {
+			cardInfo.Finish = card.Modifier
+		}
+
+		cardNames.InsertCardInfo(cardInfo, card.Quantity)
+	}
+
+	return cardNames
 }
 
 var (
@@ -1433,9 +2659,9 @@ func init() {
 	aetherHubCardNumberXPath = xpath.MustCompile(`/@data-card-number`)
 }
 
-func handleAetherHubLink(baseURL string, options map[string]string) (decks []*plugins.Deck, err error) {
+func handleAetherHubLink(ctx context.Context, baseURL string, options map[string]string) (decks []*plugins.Deck, err error) {
 	log.Infof("Checking %s", baseURL)
-	doc, err := htmlquery.LoadURL(baseURL)
+	doc, err := plugins.LoadURL(ctx, baseURL)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't query %s: %w", baseURL, err)
 	}
@@ -1563,7 +2789,11 @@ func handleAetherHubLink(baseURL string, options map[string]string) (decks []*pl
 			sb.WriteString(")\n")
 		}
 	}
-	printCards(&sb, commanders)
+	if len(commanders) > 0 {
+		sb.WriteString("Commander\n")
+		printCards(&sb, commanders)
+		sb.WriteString("Deck\n")
+	}
 	printCards(&sb, main)
 	if len(sideboard) > 0 {
 		sb.WriteString("Sideboard\n")
@@ -1574,7 +2804,7 @@ func handleAetherHubLink(baseURL string, options map[string]string) (decks []*pl
 	}
 	printCards(&sb, maybeboard)
 
-	return fromDeckFile(strings.NewReader(sb.String()), deckName, options)
+	return fromDeckFile(ctx, strings.NewReader(sb.String()), deckName, options)
 }
 
 type frogtownSubsets struct {
@@ -1596,11 +2826,11 @@ type frogtownData struct {
 	DeckDetails frogtownDeckDetails `json:"deckDetails"`
 }
 
-func handleFrogtownLink(baseURL string, options map[string]string) (decks []*plugins.Deck, err error) {
+func handleFrogtownLink(ctx context.Context, baseURL string, options map[string]string) (decks []*plugins.Deck, err error) {
 	scriptXPath := `//body/script[not(@src)]`
 
 	log.Infof("Checking %s", baseURL)
-	doc, err := htmlquery.LoadURL(baseURL)
+	doc, err := plugins.LoadURL(ctx, baseURL)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't query %s: %w", baseURL, err)
 	}
@@ -1664,12 +2894,12 @@ func handleFrogtownLink(baseURL string, options map[string]string) (decks []*plu
 	}
 	printCards(&sb, data.DeckDetails.Sideboard)
 
-	return fromDeckFile(strings.NewReader(sb.String()), deckName, options)
+	return fromDeckFile(ctx, strings.NewReader(sb.String()), deckName, options)
 }
 
 var cubeTutorSetRegex *regexp.Regexp = regexp.MustCompile(`^set\d_\d+$`)
 
-func handleCubeTutorLink(doc *html.Node, baseURL string, deckName string, cardSetXPath string, cardsXPath string, options map[string]string) (decks []*plugins.Deck, err error) {
+func handleCubeTutorLink(ctx context.Context, doc *html.Node, baseURL string, deckName string, cardSetXPath string, cardsXPath string, options map[string]string) (decks []*plugins.Deck, err error) {
 	cardSets := htmlquery.Find(doc, cardSetXPath)
 	main := make([]string, 0, 560)
 	sideboard := make([]string, 0, 30)
@@ -1731,10 +2961,10 @@ func handleCubeTutorLink(doc *html.Node, baseURL string, deckName string, cardSe
 	}
 	printCards(&sb, maybeboard)
 
-	return fromDeckFile(strings.NewReader(sb.String()), deckName, options)
+	return fromDeckFile(ctx, strings.NewReader(sb.String()), deckName, options)
 }
 
-func handleCubeCobraLink(baseURL string, options map[string]string) (decks []*plugins.Deck, err error) {
+func handleCubeCobraLink(ctx context.Context, baseURL string, options map[string]string) (decks []*plugins.Deck, err error) {
 	parsedURL, err := url.Parse(baseURL)
 	if err != nil {
 		return nil, err
@@ -1745,7 +2975,7 @@ func handleCubeCobraLink(baseURL string, options map[string]string) (decks []*pl
 	fileURL := "https://cubecobra.com/cube/download/mtgo/" + id
 
 	log.Infof("Checking %s", baseURL)
-	doc, err := htmlquery.LoadURL(baseURL)
+	doc, err := plugins.LoadURL(ctx, baseURL)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't query %s: %w", baseURL, err)
 	}
@@ -1760,5 +2990,5 @@ func handleCubeCobraLink(baseURL string, options map[string]string) (decks []*pl
 
 	log.Infof("Found title: %s", deckName)
 
-	return queryDeckFile(fileURL, deckName, options)
+	return queryDeckFile(ctx, fileURL, deckName, options)
 }
@@ -1,13 +1,18 @@
 package mtg
 
 import (
+	"context"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	scryfall "github.com/BlueMonday/go-scryfall"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 
 	"github.com/jeandeaual/tts-deckconverter/log"
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+	"github.com/jeandeaual/tts-deckconverter/plugins/cassette"
 )
 
 func init() {
@@ -15,11 +20,41 @@ func init() {
 	log.SetLogger(logger.Sugar())
 }
 
+func TestGetImageURL(t *testing.T) {
+	uris := &scryfall.ImageURIs{
+		Small:  "https://example.com/small.jpg",
+		Normal: "https://example.com/normal.jpg",
+		Large:  "https://example.com/large.jpg",
+		PNG:    "https://example.com/card.png",
+	}
+
+	assert.Equal(t, uris.PNG, getImageURL(uris, true, []string{"png", "large", "normal"}))
+	// The high-resolution images are skipped when they aren't available
+	assert.Equal(t, uris.Normal, getImageURL(uris, false, []string{"png", "large", "normal"}))
+	assert.Equal(t, uris.Small, getImageURL(&scryfall.ImageURIs{Small: uris.Small}, true, []string{"normal", "small"}))
+	assert.Equal(t, "", getImageURL(uris, false, []string{"large"}))
+	assert.Equal(t, "", getImageURL(nil, true, []string{"normal"}))
+}
+
+func TestGetFallbackImageURLs(t *testing.T) {
+	uris := &scryfall.ImageURIs{
+		Small:  "https://example.com/small.jpg",
+		Normal: "https://example.com/normal.jpg",
+		PNG:    "https://example.com/card.png",
+	}
+
+	assert.Equal(t, []string{uris.Normal, uris.Small}, getFallbackImageURLs(uris, uris.PNG))
+	assert.Equal(t, []string{uris.Small}, getFallbackImageURLs(uris, uris.Normal))
+	assert.Empty(t, getFallbackImageURLs(uris, uris.Small))
+	assert.Empty(t, getFallbackImageURLs(nil, uris.PNG))
+}
+
 func TestParseDeckFile(t *testing.T) {
-	main, side, maybe, err := parseDeckFile(strings.NewReader(""))
+	main, side, maybe, commanders, err := parseDeckFile(strings.NewReader(""), heuristicSplit)
 	assert.Nil(t, main)
 	assert.Nil(t, side)
 	assert.Nil(t, maybe)
+	assert.Nil(t, commanders)
 	assert.Nil(t, err)
 
 	setRNA := "RNA"
@@ -28,7 +63,7 @@ func TestParseDeckFile(t *testing.T) {
 	setGRN := "GRN"
 	setXLN := "XLN"
 	setEMN := "EMN"
-	main, side, maybe, err = parseDeckFile(
+	main, side, maybe, commanders, err = parseDeckFile(
 		strings.NewReader(`2 Blood Crypt (RNA) 245
 3 Carnival /// Carnage (RNA) 222
 3 Demon of Catastrophes (M19) 91
@@ -38,6 +73,7 @@ func TestParseDeckFile(t *testing.T) {
 4 Dragonskull Summit (XLN) 252
 2 Graf Rats (EMN) 91a
 2 Midnight Scavengers (EMN) 96a`),
+		heuristicSplit,
 	)
 	expected := &CardNames{
 		Names: []CardInfo{
@@ -94,5 +130,525 @@ func TestParseDeckFile(t *testing.T) {
 	assert.Equal(t, expected, main)
 	assert.Nil(t, side)
 	assert.Nil(t, maybe)
+	assert.Nil(t, commanders)
+	assert.Nil(t, err)
+}
+
+func TestFromDeckFileDefaultOptions(t *testing.T) {
+	// The options left out use their default value
+	decks, err := fromDeckFile(context.Background(), strings.NewReader(""), "Test", map[string]string{})
+	assert.Nil(t, err)
+	assert.Empty(t, decks)
+}
+
+func TestParseDeckFileCommanders(t *testing.T) {
+	main, side, maybe, commanders, err := parseDeckFile(
+		strings.NewReader(`Commander
+1 Atraxa, Praetors' Voice
+Deck
+1 Sol Ring
+1 Arcane Signet
+1 Command Tower
+Sideboard
+1 Swords to Plowshares`),
+		heuristicSplit,
+	)
+	assert.Nil(t, err)
+	assert.Nil(t, maybe)
+
+	assert.Equal(t, &CardNames{
+		Names:  []CardInfo{{Name: "Atraxa, Praetors' Voice"}},
+		Counts: map[string]int{"Atraxa, Praetors' Voice": 1},
+	}, commanders)
+
+	// The commanders are part of the main deck
+	if assert.NotNil(t, main) {
+		assert.Len(t, main.Names, 4)
+		assert.Equal(t, "Atraxa, Praetors' Voice", main.Names[0].Name)
+	}
+	if assert.NotNil(t, side) {
+		assert.Equal(t, 1, side.Counts["Swords to Plowshares"])
+	}
+
+	// The commanders can also be separated from the main deck by an empty line
+	main, _, _, commanders, err = parseDeckFile(
+		strings.NewReader("Commander\n1 Atraxa, Praetors' Voice\n\n1 Sol Ring\n"),
+		heuristicSplit,
+	)
+	assert.Nil(t, err)
+	if assert.NotNil(t, commanders) {
+		assert.Len(t, commanders.Names, 1)
+	}
+	if assert.NotNil(t, main) {
+		assert.Len(t, main.Names, 2)
+	}
+}
+
+func TestParseDeckFileArena(t *testing.T) {
+	setIKO := "IKO"
+	setTHB := "THB"
+	setELD := "ELD"
+	main, side, maybe, commanders, err := parseDeckFile(
+		strings.NewReader(`About
+Name Lurrus Weekly
+
+Companion
+1 Lurrus of the Dream-Den (IKO) 226
+
+Deck
+4 Giant Killer (ELD) 14
+4 Lurrus of the Dream-Den (IKO) 226
+4 Shepherd of the Flock (ELD) 28
+
+Sideboard
+2 Mystical Dispute (ELD) 58`),
+		heuristicSplit,
+	)
+	assert.Nil(t, err)
+	assert.Nil(t, maybe)
+	assert.Nil(t, commanders)
+
+	// The header lines aren't part of the main deck
+	if assert.NotNil(t, main) {
+		assert.Len(t, main.Names, 3)
+		assert.Equal(t, 4, main.Counts["Lurrus of the Dream-Den"+setIKO])
+	}
+	// The companion is added to the sideboard
+	if assert.NotNil(t, side) {
+		assert.Len(t, side.Names, 2)
+		assert.Equal(t, 2, side.Counts["Mystical Dispute"+setELD])
+		assert.Equal(t, 1, side.Counts["Lurrus of the Dream-Den"+setIKO])
+	}
+
+	// The companion isn't added twice if already listed in the sideboard
+	main, side, _, commanders, err = parseDeckFile(
+		strings.NewReader(`Commander
+1 Kenrith, the Returned King (ELD) 303
+
+Companion
+1 Lutri, the Spellchaser (IKO) 227
+
+Deck
+1 Sol Ring (C20) 252
+1 Thassa's Oracle (THB) 73
+
+Sideboard
+1 Lutri, the Spellchaser (IKO) 227`),
+		heuristicSplit,
+	)
+	assert.Nil(t, err)
+	if assert.NotNil(t, commanders) {
+		assert.Len(t, commanders.Names, 1)
+	}
+	if assert.NotNil(t, main) {
+		assert.Len(t, main.Names, 3)
+		assert.Equal(t, 1, main.Counts["Thassa's Oracle"+setTHB])
+	}
+	if assert.NotNil(t, side) {
+		assert.Len(t, side.Names, 1)
+		assert.Equal(t, 1, side.Counts["Lutri, the Spellchaser"+setIKO])
+	}
+}
+
+func TestParseCardRole(t *testing.T) {
+	line, role := parseCardRole("1 Sol Ring (C21) 263")
+	assert.Equal(t, "1 Sol Ring (C21) 263", line)
+	assert.Equal(t, Main, role)
+
+	line, role = parseCardRole("1x Atraxa, Praetors' Voice *CMDR*")
+	assert.Equal(t, "1x Atraxa, Praetors' Voice", line)
+	assert.Equal(t, Commander, role)
+
+	line, role = parseCardRole("1x Atraxa, Praetors' Voice (2XM) 190 [Commander{top}] [foil]")
+	assert.Equal(t, "1x Atraxa, Praetors' Voice (2XM) 190 [foil]", line)
+	assert.Equal(t, Commander, role)
+
+	line, role = parseCardRole("1 Lurrus of the Dream-Den !Companion")
+	assert.Equal(t, "1 Lurrus of the Dream-Den", line)
+	assert.Equal(t, Companion, role)
+}
+
+func TestParseDeckFileRoleMarkers(t *testing.T) {
+	main, side, _, commanders, err := parseDeckFile(
+		strings.NewReader(`1x Kenrith, the Returned King *CMDR*
+1x Lutri, the Spellchaser [Companion]
+1x Sol Ring
+1x Command Tower
+1x Arcane Signet`),
+		heuristicSplit,
+	)
+	assert.Nil(t, err)
+	if assert.NotNil(t, commanders) {
+		assert.Equal(t, []CardInfo{{Name: "Kenrith, the Returned King"}}, commanders.Names)
+	}
+	// The commanders are part of the main deck, the companions of the
+	// sideboard
+	if assert.NotNil(t, main) {
+		assert.Len(t, main.Names, 4)
+		assert.Equal(t, 1, main.Counts["Kenrith, the Returned King"])
+	}
+	if assert.NotNil(t, side) {
+		assert.Equal(t, []CardInfo{{Name: "Lutri, the Spellchaser"}}, side.Names)
+	}
+}
+
+func TestParseDeckFileSideboardSplit(t *testing.T) {
+	categories := `// Creatures
+4 Goblin Guide
+4 Monastery Swiftspear
+
+// Spells
+4 Lightning Bolt
+
+// Lands
+20 Mountain`
+
+	// The heuristic can't tell the categories from the sideboard
+	main, side, _, _, err := parseDeckFile(strings.NewReader(categories), heuristicSplit)
+	assert.Nil(t, err)
+	if assert.NotNil(t, main) && assert.NotNil(t, side) {
+		assert.Len(t, main.Names, 3)
+		assert.Equal(t, 20, side.Counts["Mountain"])
+	}
+
+	main, side, _, _, err = parseDeckFile(strings.NewReader(categories), headersSplit)
+	assert.Nil(t, err)
+	assert.Nil(t, side)
+	if assert.NotNil(t, main) {
+		assert.Len(t, main.Names, 4)
+		assert.Equal(t, 20, main.Counts["Mountain"])
+	}
+
+	main, side, _, _, err = parseDeckFile(strings.NewReader(categories), blankLineSplit)
+	assert.Nil(t, err)
+	if assert.NotNil(t, main) && assert.NotNil(t, side) {
+		assert.Len(t, main.Names, 2)
+		assert.Len(t, side.Names, 2)
+	}
+
+	// A sideboard header moves the categories back to the main deck
+	for _, split := range []string{heuristicSplit, blankLineSplit, headersSplit} {
+		main, side, _, _, err = parseDeckFile(strings.NewReader(categories+"\n\nSideboard\n2 Smash to Smithereens"), split)
+		assert.Nil(t, err)
+		if assert.NotNil(t, main, split) && assert.NotNil(t, side, split) {
+			assert.Len(t, main.Names, 4, split)
+			assert.Equal(t, 20, main.Counts["Mountain"], split)
+			assert.Len(t, side.Names, 1, split)
+			assert.Equal(t, 2, side.Counts["Smash to Smithereens"], split)
+		}
+	}
+
+	// A short main deck followed by a sideboard
+	main, side, _, _, err = parseDeckFile(strings.NewReader("4 Lightning Bolt\n20 Mountain\n\n2 Smash to Smithereens"), blankLineSplit)
+	assert.Nil(t, err)
+	if assert.NotNil(t, main) && assert.NotNil(t, side) {
+		assert.Len(t, main.Names, 2)
+		assert.Equal(t, 2, side.Counts["Smash to Smithereens"])
+	}
+}
+
+func TestParseDeckFileCategories(t *testing.T) {
+	main, side, _, _, err := parseDeckFile(
+		strings.NewReader(`1 Sol Ring
+Creatures (2):
+4 Llanowar Elves
+4 Elvish Mystic
+
+## Ramp
+4 Rampant Growth
+
+Lands
+20 Forest
+
+Sideboard
+2 Naturalize`),
+		heuristicSplit,
+	)
+	assert.Nil(t, err)
+	if assert.NotNil(t, side) {
+		assert.Len(t, side.Names, 1)
+	}
+	if assert.NotNil(t, main) {
+		assert.Equal(t, []CardInfo{
+			{Name: "Sol Ring"},
+			{Name: "Llanowar Elves", Category: "Creatures"},
+			{Name: "Elvish Mystic", Category: "Creatures"},
+			{Name: "Rampant Growth", Category: "Ramp"},
+			{Name: "Forest", Category: "Lands"},
+		}, main.Names)
+		assert.Equal(t, 20, main.Counts["Forest"])
+
+		categories := splitCategories(main)
+		if assert.Len(t, categories, 4) {
+			assert.Equal(t, "", categories[0].name)
+			assert.Equal(t, "Creatures", categories[1].name)
+			assert.Len(t, categories[1].cards.Names, 2)
+			assert.Equal(t, 4, categories[1].cards.Counts["Elvish Mystic"])
+			assert.Equal(t, "Ramp", categories[2].name)
+			assert.Equal(t, "Lands", categories[3].name)
+		}
+	}
+
+	// The cards without a category come first
+	main = NewCardNames()
+	main.InsertCardInfo(CardInfo{Name: "Forest", Category: "Lands"}, 20)
+	main.InsertCardInfo(CardInfo{Name: "Sol Ring"}, 1)
+	categories := splitCategories(main)
+	if assert.Len(t, categories, 2) {
+		assert.Equal(t, "", categories[0].name)
+		assert.Equal(t, 1, categories[0].cards.Counts["Sol Ring"])
+		assert.Equal(t, "Lands", categories[1].name)
+	}
+}
+
+func TestArchidektCardNames(t *testing.T) {
+	assert.Nil(t, archidektCardNames(nil))
+
+	newCard := func(id string, modifier string, quantity int) archidektCard {
+		return archidektCard{
+			Card: archidektCardInfo{
+				SkryfallID: id,
+				OracleCard: archidektOracleCard{Name: "Sol Ring"},
+				Edition:    archidektEdition{Code: "cmr"},
+			},
+			Quantity: quantity,
+			Modifier: modifier,
+		}
+	}
+
+	cardNames := archidektCardNames([]archidektCard{
+		newCard("id1", "Normal", 1),
+		newCard("id1", "Foil", 1),
+		newCard("id2", "Normal", 1),
+		newCard("id1", "Normal", 2),
+	})
+
+	// The printings and finishes are kept separate
+	if assert.Len(t, cardNames.Names, 3) {
+		assert.Equal(t, "id1", *cardNames.Names[0].ID)
+		assert.Equal(t, "CMR", *cardNames.Names[0].Set)
+		assert.Empty(t, cardNames.Names[0].Finish)
+		assert.Equal(t, 3, cardNames.Counts[cardNames.Names[0].key()])
+		assert.Equal(t, "Foil", cardNames.Names[1].Finish)
+		assert.Equal(t, 1, cardNames.Counts[cardNames.Names[1].key()])
+		assert.Equal(t, "id2", *cardNames.Names[2].ID)
+	}
+}
+
+func TestMoxfieldCardNames(t *testing.T) {
+	assert.Nil(t, moxfieldCardNames(nil, map[string]moxfieldCard{}))
+
+	cardNames := moxfieldCardNames(
+		map[string]moxfieldCard{
+			"Sol Ring": {Quantity: 1, IsFoil: true, CardInfo: moxfieldCardInfo{ScryfallID: "id1", Set: "cmr"}},
+		},
+		map[string]moxfieldCard{
+			"Island":        {Quantity: 10, CardInfo: moxfieldCardInfo{ScryfallID: "id3"}},
+			"Arcane Signet": {Quantity: 1, CardInfo: moxfieldCardInfo{ScryfallID: "id2", Set: "cmr"}},
+		},
+	)
+
+	if assert.NotNil(t, cardNames) && assert.Len(t, cardNames.Names, 3) {
+		assert.Equal(t, "Sol Ring", cardNames.Names[0].Name)
+		assert.Equal(t, "Foil", cardNames.Names[0].Finish)
+		assert.Equal(t, "CMR", *cardNames.Names[0].Set)
+		assert.Equal(t, "Arcane Signet", cardNames.Names[1].Name)
+		assert.Equal(t, "Island", cardNames.Names[2].Name)
+		assert.Nil(t, cardNames.Names[2].Set)
+		assert.Equal(t, "id3", *cardNames.Names[2].ID)
+		assert.Equal(t, 10, cardNames.Counts[cardNames.Names[2].key()])
+	}
+}
+
+func TestManaStackCardNames(t *testing.T) {
+	assert.Nil(t, manaStackCardNames(nil))
+
+	cardNames := manaStackCardNames([]manaStackCard{
+		{Card: manaStackCardInfo{Name: "Island", Set: manaStackSet{Slug: "m19"}}, Count: 4},
+		{Card: manaStackCardInfo{Name: "Opt"}},
+	})
+
+	setM19 := "M19"
+	assert.Equal(t, &CardNames{
+		Names: []CardInfo{
+			{Name: "Island", Set: &setM19},
+			{Name: "Opt"},
+		},
+		Counts: map[string]int{
+			"IslandM19": 4,
+			"Opt":       1,
+		},
+	}, cardNames)
+}
+
+func TestIsForeignName(t *testing.T) {
+	assert.False(t, isForeignName("Lightning Bolt"))
+	assert.False(t, isForeignName("Lim-Dûl's Vault"))
+	assert.False(t, isForeignName("Æther Vial"))
+	assert.False(t, isForeignName("Foudre"))
+	assert.True(t, isForeignName("稲妻"))
+	assert.True(t, isForeignName("Молния"))
+	assert.True(t, isForeignName("번개"))
+}
+
+func TestParseCardAnnotations(t *testing.T) {
+	line, cardInfo := parseCardAnnotations("1 Lightning Bolt (LEB)")
+	assert.Equal(t, "1 Lightning Bolt (LEB)", line)
+	assert.Equal(t, CardInfo{}, cardInfo)
+
+	line, cardInfo = parseCardAnnotations("1 Lightning Bolt (LEB) [foil] {back=planechase, sideways}")
+	assert.Equal(t, "1 Lightning Bolt (LEB)", line)
+	assert.Equal(t, CardInfo{Finish: "Foil", BackURL: planechaseBackURL, Sideways: true}, cardInfo)
+
+	line, cardInfo = parseCardAnnotations("4 Island {set=m19} {finish=etched, back=https://example.com/back.png}")
+	assert.Equal(t, "4 Island", line)
+	if assert.NotNil(t, cardInfo.Set) {
+		assert.Equal(t, "M19", *cardInfo.Set)
+	}
+	assert.Equal(t, "Etched", cardInfo.Finish)
+	assert.Equal(t, "https://example.com/back.png", cardInfo.BackURL)
+
+	line, cardInfo = parseCardAnnotations("1 Tibalt's Trickery {sideways=false, back=unknown}")
+	assert.Equal(t, "1 Tibalt's Trickery", line)
+	assert.Equal(t, CardInfo{}, cardInfo)
+}
+
+func TestParseDeckFileAnnotations(t *testing.T) {
+	main, _, _, _, err := parseDeckFile(strings.NewReader(`1 Lightning Bolt (LEB) [foil] {back=planechase}
+3 Lightning Bolt (LEB)
+2 Bazaar of Baghdad {sideways}`), heuristicSplit)
 	assert.Nil(t, err)
+
+	setLEB := "LEB"
+	expected := &CardNames{
+		Names: []CardInfo{
+			{Name: "Lightning Bolt", Set: &setLEB, Finish: "Foil", BackURL: planechaseBackURL},
+			{Name: "Lightning Bolt", Set: &setLEB},
+			{Name: "Bazaar of Baghdad", Sideways: true},
+		},
+		Counts: map[string]int{
+			"Lightning BoltLEBFoil" + planechaseBackURL: 1,
+			"Lightning BoltLEB":                         3,
+			"Bazaar of Baghdadsideways":                 2,
+		},
+	}
+	assert.Equal(t, expected, main)
+}
+
+func TestSplitTokenFigurines(t *testing.T) {
+	newTokenDeck := func() *plugins.Deck {
+		return &plugins.Deck{
+			Name:    "Test - Tokens",
+			BackURL: "https://example.com/back.jpg",
+			Cards: []plugins.CardInfo{
+				{Name: "Treasure", Metadata: plugins.CardMetadata{Type: "Token Artifact — Treasure"}},
+				{Name: "Goblin", ArtURL: "https://example.com/art.jpg", Metadata: plugins.CardMetadata{Type: "Token Creature — Goblin"}},
+			},
+		}
+	}
+
+	decks := splitTokenFigurines(newTokenDeck(), "Test - Token Figurines", map[string]interface{}{})
+	if assert.Len(t, decks, 1) {
+		assert.Len(t, decks[0].Cards, 2)
+	}
+
+	options := map[string]interface{}{"token_figurines": true}
+	decks = splitTokenFigurines(newTokenDeck(), "Test - Token Figurines", options)
+	if assert.Len(t, decks, 2) {
+		assert.Equal(t, "Test - Tokens", decks[0].Name)
+		assert.Equal(t, plugins.ContainerDeck, decks[0].Container)
+		if assert.Len(t, decks[0].Cards, 1) {
+			assert.Equal(t, "Treasure", decks[0].Cards[0].Name)
+		}
+		assert.Equal(t, "Test - Token Figurines", decks[1].Name)
+		assert.Equal(t, plugins.ContainerFigurines, decks[1].Container)
+		assert.Equal(t, "https://example.com/back.jpg", decks[1].BackURL)
+		if assert.Len(t, decks[1].Cards, 1) {
+			assert.Equal(t, "Goblin", decks[1].Cards[0].Name)
+		}
+	}
+
+	// The token deck is dropped when it only contains creatures
+	tokenDeck := newTokenDeck()
+	tokenDeck.Cards = tokenDeck.Cards[1:]
+	decks = splitTokenFigurines(tokenDeck, "Test - Token Figurines", options)
+	if assert.Len(t, decks, 1) {
+		assert.Equal(t, plugins.ContainerFigurines, decks[0].Container)
+	}
+}
+
+func TestCommanderArtURL(t *testing.T) {
+	deck := &plugins.Deck{
+		Cards: []plugins.CardInfo{
+			{Metadata: plugins.CardMetadata{Name: "Sol Ring"}, ArtURL: "https://example.com/sol-ring.jpg"},
+			{Metadata: plugins.CardMetadata{Name: "Esika, God of the Tree // The Prismatic Bridge"}, ArtURL: "https://example.com/esika.jpg"},
+		},
+	}
+
+	commanders := NewCardNames()
+	commanders.Insert("esika, god of the tree", nil)
+
+	assert.Equal(t, "https://example.com/esika.jpg", commanderArtURL(deck, commanders))
+	assert.Empty(t, commanderArtURL(deck, nil))
+	assert.Empty(t, commanderArtURL(deck, NewCardNames()))
+}
+
+// assertCassetteDecks checks the decks parsed from a recorded cassette. Only
+// their structure is checked, so that the cassettes can be recorded again
+// when the decks are updated.
+func assertCassetteDecks(t *testing.T, decks []*plugins.Deck, err error) {
+	t.Helper()
+
+	assert.Nil(t, err)
+	if !assert.NotEmpty(t, decks) {
+		return
+	}
+	for _, deck := range decks {
+		assert.NotEmpty(t, deck.Name)
+		assert.NotEmpty(t, deck.Cards, deck.Name)
+		for _, card := range deck.Cards {
+			assert.NotEmpty(t, card.Name, deck.Name)
+			assert.NotEmpty(t, card.ImageURL, card.Name)
+			assert.Greater(t, card.Count, 0, card.Name)
+		}
+	}
+}
+
+func TestHandleScryfallSearchLink(t *testing.T) {
+	recorder := cassette.NewForTest(t, filepath.Join("testdata", "scryfall_search.json"))
+
+	defer plugins.SetHTTPClient(nil)
+	plugins.SetHTTPTransport(recorder)
+
+	decks, err := handleScryfallSearchLink(context.Background(), "https://scryfall.com/search?q=e%3Asld+cn%3E%3D1+cn%3C%3D5", map[string]string{})
+	assert.Nil(t, recorder.Save())
+
+	assertCassetteDecks(t, decks, err)
+	if assert.NotEmpty(t, decks) {
+		assert.Equal(t, 5, plugins.CountCards(decks[0]))
+	}
+}
+
+func TestHandleMoxfieldLink(t *testing.T) {
+	recorder := cassette.NewForTest(t, filepath.Join("testdata", "moxfield.json"))
+
+	defer plugins.SetHTTPClient(nil)
+	plugins.SetHTTPTransport(recorder)
+
+	decks, err := handleMoxfieldLink(context.Background(), "https://www.moxfield.com/decks/yA6HKgjJS0O1J1X17skNqQ", map[string]string{})
+	assert.Nil(t, recorder.Save())
+
+	assertCassetteDecks(t, decks, err)
+}
+
+func TestHandleArchidektLink(t *testing.T) {
+	recorder := cassette.NewForTest(t, filepath.Join("testdata", "archidekt.json"))
+
+	defer plugins.SetHTTPClient(nil)
+	plugins.SetHTTPTransport(recorder)
+
+	decks, err := handleArchidektLink(context.Background(), "https://archidekt.com/decks/1", map[string]string{})
+	assert.Nil(t, recorder.Save())
+
+	assertCassetteDecks(t, decks, err)
 }
@@ -1,6 +1,7 @@
 package mtg
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"path"
@@ -22,6 +23,67 @@ const (
 	png    imageQuality = "png"
 )
 
+// Frames which can be selected with the "frame" option
+const (
+	anyFrame        = "any"
+	showcaseFrame   = "showcase"
+	borderlessFrame = "borderless"
+	extendedFrame   = "extended"
+)
+
+// Rarities which can be selected with the "rarity" option
+const (
+	anyRarity      = "any"
+	commonRarity   = "common"
+	uncommonRarity = "uncommon"
+	rareRarity     = "rare"
+	mythicRarity   = "mythic"
+	// Commons and uncommons, for peasant cubes
+	peasantRarity = "peasant"
+)
+
+// Boards which can be selected with the "boards" option
+const (
+	mainBoard  = "main"
+	sideBoard  = "side"
+	maybeBoard = "maybe"
+)
+
+// Formats which can be selected with the "deck_format" option
+const (
+	anyFormat         = "any"
+	constructedFormat = "constructed"
+	limitedFormat     = "limited"
+	commanderFormat   = "commander"
+	standardFormat    = "standard"
+	pioneerFormat     = "pioneer"
+	modernFormat      = "modern"
+	legacyFormat      = "legacy"
+	vintageFormat     = "vintage"
+	pauperFormat      = "pauper"
+)
+
+// Strategies which can be selected with the "sideboard_split" option, to
+// find where the sideboard starts in a deck file
+const (
+	// Explicit headers ("Sideboard") and "SB:" lines, or an empty line when
+	// the deck list doesn't seem to use empty lines between categories
+	heuristicSplit = "heuristic"
+	// The first empty line after the main deck cards
+	blankLineSplit = "blank_line"
+	// Explicit headers and "SB:" lines only
+	headersSplit = "headers"
+)
+
+// Values of the "categories" option, choosing what to do with the category
+// headers of a deck file (e.g. "Creatures" or "## Ramp")
+const (
+	// Keep the cards of every category in the main deck
+	mainCategories = "main"
+	// Generate each category of the main deck as a separate deck
+	pilesCategories = "piles"
+)
+
 type magicPlugin struct {
 	id   string
 	name string
@@ -37,6 +99,7 @@ func (p magicPlugin) PluginName() string {
 
 func (p magicPlugin) AvailableOptions() plugins.Options {
 	return plugins.Options{
+		plugins.AccessoriesOption: plugins.NewAccessoriesOption(),
 		"quality": plugins.Option{
 			Type:        plugins.OptionTypeEnum,
 			Description: "image quality",
@@ -48,11 +111,141 @@ func (p magicPlugin) AvailableOptions() plugins.Options {
 			},
 			DefaultValue: string(normal),
 		},
+		"quality_fallback": plugins.Option{
+			Type:         plugins.OptionTypeString,
+			Description:  "comma-separated chain of image qualities, from the highest to the lowest: when a card image isn't available in the selected quality, the next qualities of the chain are tried (empty to disable the fallback)",
+			DefaultValue: string(png) + "," + string(large) + "," + string(normal) + "," + string(small),
+		},
+		"artist": plugins.Option{
+			Type:         plugins.OptionTypeString,
+			Description:  "use the printings illustrated by this artist when available (e.g. \"Rebecca Guay\")",
+			DefaultValue: "",
+		},
+		"frame": plugins.Option{
+			Type:        plugins.OptionTypeEnum,
+			Description: "use the printings with this frame when available",
+			AllowedValues: []string{
+				anyFrame,
+				showcaseFrame,
+				borderlessFrame,
+				extendedFrame,
+			},
+			DefaultValue: anyFrame,
+		},
+		"boards": plugins.Option{
+			Type:         plugins.OptionTypeString,
+			Description:  "comma-separated list of the boards to generate, among \"main\", \"side\" and \"maybe\"",
+			DefaultValue: mainBoard + "," + sideBoard + "," + maybeBoard,
+		},
+		"rarity": plugins.Option{
+			Type:        plugins.OptionTypeEnum,
+			Description: "only keep the cards of this rarity when importing a Scryfall set or search (e.g. to build a peasant cube)",
+			AllowedValues: []string{
+				anyRarity,
+				commonRarity,
+				uncommonRarity,
+				rareRarity,
+				mythicRarity,
+				peasantRarity,
+			},
+			DefaultValue: anyRarity,
+		},
+		"variants": plugins.Option{
+			Type:         plugins.OptionTypeBool,
+			Description:  "keep every printing of a card (showcase, borderless, etc.) when importing a Scryfall set or search, instead of one copy of each card",
+			DefaultValue: true,
+		},
+		"deck_format": plugins.Option{
+			Type:        plugins.OptionTypeEnum,
+			Description: "warn when the deck size or the number of copies of a card doesn't fit this format, which usually means that the deck list wasn't parsed correctly, and check the rules of this format with \"-validate\" (including the banned cards of the tournament formats)",
+			AllowedValues: []string{
+				anyFormat,
+				constructedFormat,
+				limitedFormat,
+				commanderFormat,
+				standardFormat,
+				pioneerFormat,
+				modernFormat,
+				legacyFormat,
+				vintageFormat,
+				pauperFormat,
+			},
+			DefaultValue: anyFormat,
+		},
+		"sideboard_split": plugins.Option{
+			Type:        plugins.OptionTypeEnum,
+			Description: "how the sideboard of a deck file is found: \"heuristic\" (headers, \"SB:\" lines or an empty line, unless the deck list has several of them), \"blank_line\" (headers, \"SB:\" lines or the first empty line) or \"headers\" (headers and \"SB:\" lines only, for the deck lists with empty lines between categories)",
+			AllowedValues: []string{
+				heuristicSplit,
+				blankLineSplit,
+				headersSplit,
+			},
+			DefaultValue: heuristicSplit,
+		},
+		"categories": plugins.Option{
+			Type:        plugins.OptionTypeEnum,
+			Description: "what to do with the category headers of a deck file (e.g. \"Creatures\", \"Lands:\" or \"## Ramp\"): \"main\" keeps their cards in the main deck, \"piles\" generates each category as a separate deck (e.g. \"Deck - Ramp (pile)\")",
+			AllowedValues: []string{
+				mainCategories,
+				pilesCategories,
+			},
+			DefaultValue: mainCategories,
+		},
+		"sideboard_container": plugins.Option{
+			Type:        plugins.OptionTypeEnum,
+			Description: "object holding the sideboard: \"deck\", \"states\" (a holder card with each sideboard card as one of its states, as expected by some scripted mods) or \"bag\"",
+			AllowedValues: []string{
+				plugins.ContainerDeck.String(),
+				plugins.ContainerStates.String(),
+				plugins.ContainerBag.String(),
+			},
+			DefaultValue: plugins.ContainerDeck.String(),
+		},
 		"tokens": plugins.Option{
 			Type:         plugins.OptionTypeBool,
 			Description:  "generate a separate token deck",
 			DefaultValue: true,
 		},
+		"token_figurines": plugins.Option{
+			Type:         plugins.OptionTypeBool,
+			Description:  "generate the creature tokens as figurines (standees showing their art) in a separate object, instead of cards",
+			DefaultValue: false,
+		},
+		"dungeons": plugins.Option{
+			Type:         plugins.OptionTypeBool,
+			Description:  "generate a separate deck with the dungeons if a card ventures into the dungeon or takes the initiative",
+			DefaultValue: true,
+		},
+		"stickers": plugins.Option{
+			Type:         plugins.OptionTypeBool,
+			Description:  "generate a separate deck with the Unfinity sticker sheets if a card uses stickers",
+			DefaultValue: true,
+		},
+		"contraptions": plugins.Option{
+			Type:         plugins.OptionTypeBool,
+			Description:  "generate a separate deck with the Unstable Contraptions if a card assembles Contraptions",
+			DefaultValue: true,
+		},
+		"conspiracies": plugins.Option{
+			Type:         plugins.OptionTypeBool,
+			Description:  "move the Conspiracy cards of the main deck to a separate deck placed face up (face down for the cards with hidden agenda)",
+			DefaultValue: true,
+		},
+		"oversized_commander": plugins.Option{
+			Type:         plugins.OptionTypeBool,
+			Description:  "add an oversized copy of the commanders, placed face up next to the deck (when the deck list identifies them)",
+			DefaultValue: false,
+		},
+		"counters": plugins.Option{
+			Type:         plugins.OptionTypeBool,
+			Description:  "place a labeled counter next to the deck for each kind of counter used by its cards (e.g. +1/+1, loyalty, energy or shield)",
+			DefaultValue: false,
+		},
+		"life_counter": plugins.Option{
+			Type:         plugins.OptionTypeInt,
+			Description:  "place a scripted life counter next to the main deck, starting at this total (e.g. 20, 40 for Commander or 25 for Brawl), 0 for none",
+			DefaultValue: 0,
+		},
 		"detailed_description": plugins.Option{
 			Type:         plugins.OptionTypeBool,
 			Description:  "show all card info in the description of the card",
@@ -63,6 +256,26 @@ func (p magicPlugin) AvailableOptions() plugins.Options {
 			Description:  "add the rulings to each card description",
 			DefaultValue: false,
 		},
+		"max_rulings": plugins.Option{
+			Type:         plugins.OptionTypeInt,
+			Description:  "only add the most recent rulings, up to this number (0 for no limit)",
+			DefaultValue: 0,
+		},
+		"rulings_since": plugins.Option{
+			Type:         plugins.OptionTypeString,
+			Description:  "only add the rulings published since this date (format: YYYY-MM-DD)",
+			DefaultValue: "",
+		},
+		"rich_text": plugins.Option{
+			Type:         plugins.OptionTypeBool,
+			Description:  "color the mana symbols of the card descriptions and the type lines of the card names",
+			DefaultValue: false,
+		},
+		"description_template": plugins.Option{
+			Type:         plugins.OptionTypeString,
+			Description:  "Go template of the card descriptions, with the fields .Name, .Type, .ManaCost, .Text, .Rulings (requires \"rulings\"), .Price, .Set, .SetName and .Description (the default description) (e.g. \"{{.Type}}: {{.Text}}\")",
+			DefaultValue: "",
+		},
 	}
 }
 
@@ -71,7 +284,7 @@ func (p magicPlugin) URLHandlers() []plugins.URLHandler {
 		{
 			BasePath: "https://scryfall.com",
 			Regex:    regexp.MustCompile(`^https://scryfall\.com/@.+/decks/`),
-			Handler: func(baseURL string, options map[string]string) ([]*plugins.Deck, error) {
+			Handler: func(ctx context.Context, baseURL string, options map[string]string) ([]*plugins.Deck, error) {
 				parsedURL, err := url.Parse(baseURL)
 				if err != nil {
 					return nil, err
@@ -79,7 +292,7 @@ func (p magicPlugin) URLHandlers() []plugins.URLHandler {
 
 				uuid := path.Base(parsedURL.Path)
 
-				return handleLink(
+				return handleLink(ctx,
 					baseURL,
 					`//h1[contains(@class,'deck-details-title')]`,
 					"https://api.scryfall.com/decks/"+uuid+"/export/text",
@@ -87,10 +300,20 @@ func (p magicPlugin) URLHandlers() []plugins.URLHandler {
 				)
 			},
 		},
+		{
+			BasePath: "https://scryfall.com/sets",
+			Regex:    regexp.MustCompile(`^https://scryfall\.com/sets/[a-zA-Z0-9]+/?(?:\?.*)?$`),
+			Handler:  handleScryfallSetLink,
+		},
+		{
+			BasePath: "https://scryfall.com/search",
+			Regex:    regexp.MustCompile(`^https://scryfall\.com/search\?`),
+			Handler:  handleScryfallSearchLink,
+		},
 		{
 			BasePath: "https://deckstats.net",
 			Regex:    regexp.MustCompile(`^https://deckstats\.net/decks/`),
-			Handler: func(baseURL string, options map[string]string) ([]*plugins.Deck, error) {
+			Handler: func(ctx context.Context, baseURL string, options map[string]string) ([]*plugins.Deck, error) {
 				fileURL, err := url.Parse(baseURL)
 				if err != nil {
 					return nil, err
@@ -100,7 +323,7 @@ func (p magicPlugin) URLHandlers() []plugins.URLHandler {
 				q.Set("export_mtgarena", "1")
 				fileURL.RawQuery = q.Encode()
 
-				return handleLink(
+				return handleLink(ctx,
 					baseURL,
 					`//h2[@id='subtitle']`,
 					fileURL.String(),
@@ -111,7 +334,7 @@ func (p magicPlugin) URLHandlers() []plugins.URLHandler {
 		{
 			BasePath: "https://tappedout.net",
 			Regex:    regexp.MustCompile(`^https?://tappedout\.net/(?:mtg-decks|mtg-cube-drafts)/`),
-			Handler: func(baseURL string, options map[string]string) ([]*plugins.Deck, error) {
+			Handler: func(ctx context.Context, baseURL string, options map[string]string) ([]*plugins.Deck, error) {
 				fileURL, err := url.Parse(baseURL)
 				if err != nil {
 					return nil, err
@@ -129,7 +352,7 @@ func (p magicPlugin) URLHandlers() []plugins.URLHandler {
 					titleXPath = `//div[contains(@class,'well')]/h2`
 				}
 
-				return handleCSVLink(
+				return handleCSVLink(ctx,
 					baseURL,
 					titleXPath,
 					fileURL.String(),
@@ -140,7 +363,7 @@ func (p magicPlugin) URLHandlers() []plugins.URLHandler {
 		{
 			BasePath: "https://deckbox.org",
 			Regex:    regexp.MustCompile(`^https://deckbox\.org/sets/`),
-			Handler: func(baseURL string, options map[string]string) ([]*plugins.Deck, error) {
+			Handler: func(ctx context.Context, baseURL string, options map[string]string) ([]*plugins.Deck, error) {
 				var fileURL string
 
 				if strings.HasSuffix(baseURL, "/") {
@@ -149,7 +372,7 @@ func (p magicPlugin) URLHandlers() []plugins.URLHandler {
 					fileURL = baseURL + "/export"
 				}
 
-				return handleHTMLLink(
+				return handleHTMLLink(ctx,
 					baseURL,
 					`//div[contains(@class,'section_title')][1]/span[1]`,
 					fileURL,
@@ -160,8 +383,8 @@ func (p magicPlugin) URLHandlers() []plugins.URLHandler {
 		{
 			BasePath: "https://www.mtggoldfish.com",
 			Regex:    regexp.MustCompile(`^https://www\.mtggoldfish\.com/deck/`),
-			Handler: func(baseURL string, options map[string]string) ([]*plugins.Deck, error) {
-				return handleLinkWithDownloadLink(
+			Handler: func(ctx context.Context, baseURL string, options map[string]string) ([]*plugins.Deck, error) {
+				return handleLinkWithDownloadLink(ctx,
 					baseURL,
 					`//h1[contains(@class,'title')]/text()`,
 					`//a[contains(text(),'Download')]/@href`,
@@ -198,9 +421,10 @@ func (p magicPlugin) URLHandlers() []plugins.URLHandler {
 		{
 			BasePath: "https://www.cubetutor.com",
 			Regex:    regexp.MustCompile(`^https://www\.cubetutor\.com/(?:viewcube|cubedeck)/`),
-			Handler: func(baseURL string, options map[string]string) ([]*plugins.Deck, error) {
+			Handler: func(ctx context.Context, baseURL string, options map[string]string) ([]*plugins.Deck, error) {
 				var (
 					deckName     string
+					author       string
 					cardSetXPath string
 					cardsXPath   string
 				)
@@ -208,7 +432,7 @@ func (p magicPlugin) URLHandlers() []plugins.URLHandler {
 				titleXPath := `//div[@id='main']//h1`
 
 				log.Infof("Checking %s", baseURL)
-				doc, err := htmlquery.LoadURL(baseURL)
+				doc, err := plugins.LoadURL(ctx, baseURL)
 				if err != nil {
 					return nil, fmt.Errorf("couldn't query %s: %w", baseURL, err)
 				}
@@ -238,12 +462,15 @@ func (p magicPlugin) URLHandlers() []plugins.URLHandler {
 					// Parse the name
 					split := strings.Split(titleText, " by ")
 					deckName = strings.TrimSpace(split[0])
-					author := strings.TrimSpace(split[1])
+					author = strings.TrimSpace(split[1])
 
 					log.Infof("Found title: %s (created by %s)", deckName, author)
 				}
 
-				return handleCubeTutorLink(doc, baseURL, deckName, cardSetXPath, cardsXPath, options)
+				decks, err := handleCubeTutorLink(ctx, doc, baseURL, deckName, cardSetXPath, cardsXPath, options)
+				plugins.SetAuthor(decks, author)
+
+				return decks, err
 			},
 		},
 		{
@@ -254,7 +481,7 @@ func (p magicPlugin) URLHandlers() []plugins.URLHandler {
 		{
 			BasePath: "https://mtg.wtf/deck",
 			Regex:    regexp.MustCompile(`^https://mtg\.wtf/deck/`),
-			Handler: func(baseURL string, options map[string]string) ([]*plugins.Deck, error) {
+			Handler: func(ctx context.Context, baseURL string, options map[string]string) ([]*plugins.Deck, error) {
 				var fileURL string
 
 				if strings.HasSuffix(baseURL, "/") {
@@ -263,7 +490,7 @@ func (p magicPlugin) URLHandlers() []plugins.URLHandler {
 					fileURL = baseURL + "/download"
 				}
 
-				return handleHTMLLink(
+				return handleHTMLLink(ctx,
 					baseURL,
 					`//header/h4/text()`,
 					fileURL,
@@ -339,6 +566,52 @@ func (p magicPlugin) AvailableBacks() map[string]plugins.Back {
 	}
 }
 
+// Accessories returns a d20 keeping track of the life total.
+func (p magicPlugin) Accessories() []plugins.Accessory {
+	return []plugins.Accessory{
+		{Name: "Life", Die: plugins.D20},
+	}
+}
+
+// TablePresets returns the tables of the usual formats: a duel and a
+// Commander pod.
+func (p magicPlugin) TablePresets() map[string]plugins.TablePreset {
+	return map[string]plugins.TablePreset{
+		"duel": {
+			Description: "two players starting at 20 life",
+			Players:     2,
+			LifeTotal:   20,
+			Counters:    []string{"poison"},
+		},
+		"commander": {
+			Description: "four players starting at 40 life, with commander tax counters",
+			Players:     4,
+			LifeTotal:   40,
+			Counters:    []string{"commander tax", "poison"},
+		},
+	}
+}
+
+// ValidateDecks checks the rules of the format selected with the
+// "deck_format" option (see formats), or of the constructed formats if none
+// is: a main deck of at least 60 cards, a sideboard of up to 15 cards and up
+// to 4 copies of each card (except the basic lands and the cards allowing any
+// number of copies).
+func (p magicPlugin) ValidateDecks(decks []*plugins.Deck, options map[string]string) []plugins.RuleViolation {
+	rules := formats[constructedFormat]
+
+	// The options were already checked when parsing the decks
+	if validatedOptions, err := p.AvailableOptions().ValidateNormalize(options); err == nil {
+		if format, found := validatedOptions["deck_format"]; found {
+			if formatRules, found := formats[format.(string)]; found {
+				rules = formatRules
+			}
+		}
+	}
+
+	return checkFormat(decks, rules)
+}
+
 // MagicPlugin is the exported plugin for this package
 var MagicPlugin = magicPlugin{
 	id:   "mtg",
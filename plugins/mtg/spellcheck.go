@@ -0,0 +1,268 @@
+package mtg
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/jeandeaual/tts-deckconverter/cache"
+	"github.com/jeandeaual/tts-deckconverter/log"
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+// bulkFilePattern matches the Scryfall bulk data files containing cards
+// (e.g. oracle-cards-20210501090000.json), as downloaded from
+// https://scryfall.com/docs/api/bulk-data.
+const bulkFilePattern = "*cards*.json"
+
+// maxSuggestions is the maximum number of names suggested for a typo.
+const maxSuggestions = 3
+
+var (
+	// bulkNames maps the lowercase card names of the bulk data file to the
+	// card names, or is empty if there's no bulk data file.
+	bulkNames      map[string]string
+	bulkNamesMutex sync.Mutex
+)
+
+// typo is a card name which isn't in the bulk data file.
+type typo struct {
+	// Name found in the deck list.
+	Name string
+	// Suggestions are the closest card names, the closest first.
+	Suggestions []string
+}
+
+// findBulkFile returns the path of the most recent bulk data file inside
+// dir, or an empty string if there is none.
+func findBulkFile(dir string) string {
+	paths, err := filepath.Glob(filepath.Join(dir, bulkFilePattern))
+	if err != nil || len(paths) == 0 {
+		return ""
+	}
+
+	var (
+		latest  string
+		modTime int64
+	)
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		if latest == "" || info.ModTime().UnixNano() > modTime {
+			latest = path
+			modTime = info.ModTime().UnixNano()
+		}
+	}
+
+	return latest
+}
+
+// loadBulkNames reads the card names of a Scryfall bulk data file, including
+// the names of each face of the multi-faced cards. The file is decoded one
+// card at a time, since it's usually larger than 100 MB.
+func loadBulkNames(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+
+	if _, err := decoder.Token(); err != nil {
+		return nil, fmt.Errorf("invalid bulk data file %s: %w", path, err)
+	}
+
+	names := make(map[string]string)
+
+	for decoder.More() {
+		var card struct {
+			Name      string `json:"name"`
+			CardFaces []struct {
+				Name string `json:"name"`
+			} `json:"card_faces"`
+		}
+		if err := decoder.Decode(&card); err != nil {
+			return nil, fmt.Errorf("invalid bulk data file %s: %w", path, err)
+		}
+
+		names[strings.ToLower(card.Name)] = card.Name
+		for _, face := range card.CardFaces {
+			names[strings.ToLower(face.Name)] = face.Name
+		}
+	}
+
+	return names, nil
+}
+
+// getBulkNames returns the card names of the bulk data file found in the
+// bulk cache, loaded once, or nil if there's no bulk data file.
+func getBulkNames() map[string]string {
+	bulkNamesMutex.Lock()
+	defer bulkNamesMutex.Unlock()
+
+	plugins.RecordCacheLookup("mtg.bulk", bulkNames != nil)
+
+	if bulkNames != nil {
+		return bulkNames
+	}
+
+	bulkNames = make(map[string]string)
+
+	dir, err := cache.DefaultDir()
+	if err != nil {
+		return nil
+	}
+	path := findBulkFile(cache.Dir(dir, cache.Bulk))
+	if len(path) == 0 {
+		return nil
+	}
+
+	log.Debugf("Loading the card names of %s", path)
+
+	names, err := loadBulkNames(path)
+	if err != nil {
+		log.Warn(err)
+		return nil
+	}
+	bulkNames = names
+
+	return bulkNames
+}
+
+// spellCheck returns the names which aren't in known (the card names
+// returned by loadBulkNames), with the closest known names.
+func spellCheck(names []string, known map[string]string) []typo {
+	var typos []typo
+
+	for _, name := range names {
+		lower := strings.ToLower(name)
+		if _, found := known[lower]; found || isForeignName(name) {
+			continue
+		}
+
+		typos = append(typos, typo{
+			Name:        name,
+			Suggestions: suggestNames(lower, known),
+		})
+	}
+
+	return typos
+}
+
+// suggestNames returns the known names closest to name (lowercase), up to
+// maxSuggestions.
+func suggestNames(name string, known map[string]string) []string {
+	type candidate struct {
+		name     string
+		distance int
+	}
+
+	// Allow about one typo every four letters
+	maxDistance := len([]rune(name)) / 4
+	if maxDistance < 2 {
+		maxDistance = 2
+	}
+
+	var candidates []candidate
+
+	for lower, knownName := range known {
+		distance := levenshtein(name, lower)
+		if distance <= maxDistance {
+			candidates = append(candidates, candidate{name: knownName, distance: distance})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].name < candidates[j].name
+	})
+
+	suggestions := make([]string, 0, maxSuggestions)
+	for i := 0; i < len(candidates) && i < maxSuggestions; i++ {
+		suggestions = append(suggestions, candidates[i].name)
+	}
+
+	return suggestions
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(rb)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+// checkSpelling warns about the card names of the deck lists which aren't in
+// the Scryfall bulk data file, if one was downloaded to the bulk cache, before
+// querying the API. The cards with a known printing are skipped.
+func checkSpelling(cardNames ...*CardNames) {
+	known := getBulkNames()
+	if len(known) == 0 {
+		return
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+
+	for _, cards := range cardNames {
+		if cards == nil {
+			continue
+		}
+		for _, cardInfo := range cards.Names {
+			if cardInfo.ID != nil || seen[cardInfo.Name] {
+				continue
+			}
+			seen[cardInfo.Name] = true
+
+			if name, keep := plugins.ResolveCardName(cardInfo.Name); keep {
+				names = append(names, name)
+			}
+		}
+	}
+
+	for _, typo := range spellCheck(names, known) {
+		if len(typo.Suggestions) == 0 {
+			log.Warnf("Unknown card %s", typo.Name)
+		} else {
+			log.Warnf("Unknown card %s, did you mean %s?", typo.Name, strings.Join(typo.Suggestions, ", "))
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package mtg
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevenshtein(t *testing.T) {
+	assert.Equal(t, 0, levenshtein("opt", "opt"))
+	assert.Equal(t, 3, levenshtein("", "opt"))
+	assert.Equal(t, 1, levenshtein("lightning bolt", "lightnin bolt"))
+	assert.Equal(t, 2, levenshtein("lim-dûl's vault", "lim-dul's vaul"))
+}
+
+func TestSpellCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bulk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	assert.Empty(t, findBulkFile(dir))
+
+	path := filepath.Join(dir, "oracle-cards-20210501090000.json")
+	assert.Nil(t, ioutil.WriteFile(path, []byte(`[
+{"name": "Lightning Bolt"},
+{"name": "Lightning Helix"},
+{"name": "Fire // Ice", "card_faces": [{"name": "Fire"}, {"name": "Ice"}]},
+{"name": "Opt"}
+]`), 0o644))
+	assert.Equal(t, path, findBulkFile(dir))
+
+	known, err := loadBulkNames(path)
+	assert.Nil(t, err)
+	assert.Len(t, known, 6)
+
+	typos := spellCheck([]string{"lightning bolt", "Lightnig Bolt", "Fire", "Opt", "Counterspell", "稲妻"}, known)
+	assert.Equal(t, []typo{
+		{Name: "Lightnig Bolt", Suggestions: []string{"Lightning Bolt"}},
+		{Name: "Counterspell", Suggestions: []string{}},
+	}, typos)
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte(`{"name": "Opt"}`), 0o644))
+	_, err = loadBulkNames(path)
+	assert.NotNil(t, err)
+}
@@ -1,14 +1,72 @@
 package mtg
 
 import (
+	"fmt"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"text/template"
+	"time"
 
 	scryfall "github.com/BlueMonday/go-scryfall"
+
+	"github.com/jeandeaual/tts-deckconverter/log"
+	"github.com/jeandeaual/tts-deckconverter/plugins"
 )
 
 const dateFormat = "2006-01-02"
 
+// initiativeDungeon is the dungeon a player ventures into when taking the
+// initiative (Commander Legends: Battle for Baldur's Gate).
+const initiativeDungeon = "Undercity"
+
+// ventureDungeons are the dungeons a player can choose from when venturing
+// into the dungeon (Adventures in the Forgotten Realms).
+var ventureDungeons = []string{
+	"Lost Mine of Phandelver",
+	"Dungeon of the Mad Mage",
+	"Tomb of Annihilation",
+}
+
+var (
+	ventureRegex    = regexp.MustCompile(`(?i)ventures? into the dungeon|completed? a dungeon`)
+	initiativeRegex = regexp.MustCompile(`(?i)\bthe initiative\b`)
+)
+
+// auxiliaryDeck is a deck of cards which are not part of the deck but used by
+// some of its cards, e.g. the Contraptions assembled by the Unstable cards.
+type auxiliaryDeck struct {
+	// section is appended to the name of the deck.
+	section string
+	// option is the plugin option enabling the generation of the deck.
+	option string
+	// regex matches the Oracle text of the cards using the mechanic.
+	regex *regexp.Regexp
+	// query is the Scryfall search returning the cards of the deck.
+	query string
+}
+
+// auxiliaryDecks are the decks automatically generated when a card uses their
+// mechanic. They use the M filler card back, to be told apart from the main
+// deck.
+var auxiliaryDecks = []auxiliaryDeck{
+	{
+		// Unfinity sticker sheets, 3 of which are chosen at random
+		section: "Stickers",
+		option:  "stickers",
+		regex:   regexp.MustCompile(`(?i)\bstickers?\b`),
+		query:   "t:stickers",
+	},
+	{
+		// Unstable Contraptions, from which the Contraption deck is built
+		section: "Contraptions",
+		option:  "contraptions",
+		regex:   regexp.MustCompile(`(?i)\bcontraptions?\b`),
+		query:   "t:contraption",
+	},
+}
+
 func appendRulings(sb *strings.Builder, rulings []scryfall.Ruling) {
 	if sb == nil || rulings == nil || len(rulings) == 0 {
 		return
@@ -32,6 +90,69 @@ func appendRulings(sb *strings.Builder, rulings []scryfall.Ruling) {
 	}
 }
 
+// rulingsFilter selects the rulings added to the card descriptions, so that
+// the long lists of rulings don't exceed the length of the TTS descriptions.
+type rulingsFilter struct {
+	// since is the publication date of the oldest rulings to keep, if set.
+	since time.Time
+	// max is the maximum number of rulings, 0 for no limit.
+	max int
+}
+
+// parseRulingsFilter reads the "rulings_since" and "max_rulings" options,
+// and returns nil if the "rulings" option isn't set.
+func parseRulingsFilter(options map[string]interface{}) (*rulingsFilter, error) {
+	if showRulings, found := options["rulings"]; !found || !showRulings.(bool) {
+		return nil, nil
+	}
+
+	filter := &rulingsFilter{}
+
+	if since, found := options["rulings_since"]; found && len(since.(string)) > 0 {
+		date, err := time.Parse(dateFormat, since.(string))
+		if err != nil {
+			return nil, fmt.Errorf("invalid rulings_since date (expected YYYY-MM-DD): %s", since)
+		}
+		filter.since = date
+	}
+
+	if max, found := options["max_rulings"]; found {
+		if max.(int) < 0 {
+			return nil, fmt.Errorf("invalid max_rulings value: %d", max)
+		}
+		filter.max = max.(int)
+	}
+
+	return filter, nil
+}
+
+// apply returns the rulings matching the filter, keeping the most recent
+// ones when there are more than max.
+func (f rulingsFilter) apply(rulings []scryfall.Ruling) []scryfall.Ruling {
+	if !f.since.IsZero() {
+		filtered := make([]scryfall.Ruling, 0, len(rulings))
+		for _, ruling := range rulings {
+			if !ruling.PublishedAt.Before(f.since) {
+				filtered = append(filtered, ruling)
+			}
+		}
+		rulings = filtered
+	}
+
+	if f.max > 0 && len(rulings) > f.max {
+		sorted := make([]scryfall.Ruling, len(rulings))
+		copy(sorted, rulings)
+		// Scryfall returns the rulings in chronological order, but make sure
+		// of it before dropping the oldest ones
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return sorted[i].PublishedAt.Before(sorted[j].PublishedAt.Time)
+		})
+		rulings = sorted[len(sorted)-f.max:]
+	}
+
+	return rulings
+}
+
 func buildCardName(card scryfall.Card) string {
 	var sb strings.Builder
 
@@ -275,3 +396,730 @@ func buildCardFacesDescription(faces []scryfall.CardFace, rulings []scryfall.Rul
 
 	return sb.String()
 }
+
+// descriptionFields are the fields available in the "description_template"
+// option.
+type descriptionFields struct {
+	// Name of the card (or of the card face)
+	Name string
+	// Type line
+	Type string
+	// ManaCost of the card (e.g. "{2}{U}")
+	ManaCost string
+	// Text is the Oracle text
+	Text string
+	// Rulings of the card, only set with the "rulings" option
+	Rulings []string
+	// Price of the card in USD, empty if unknown
+	Price string
+	// Set code, in upper case
+	Set string
+	// SetName is the full name of the set
+	SetName string
+	// Description is the description generated without the template
+	Description string
+}
+
+// descriptionTemplate parses the "description_template" option, and returns
+// nil if it isn't set.
+func descriptionTemplate(options map[string]interface{}) (*template.Template, error) {
+	option, found := options["description_template"]
+	if !found || len(option.(string)) == 0 {
+		return nil, nil
+	}
+
+	tmpl, err := template.New("description").Parse(option.(string))
+	if err != nil {
+		return nil, fmt.Errorf("invalid description template: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+// renderDescription executes tmpl with the fields of card (or of face if it
+// isn't nil), and returns the resulting description.
+func renderDescription(
+	tmpl *template.Template,
+	card scryfall.Card,
+	face *scryfall.CardFace,
+	rulings []scryfall.Ruling,
+	description string,
+) (string, error) {
+	fields := descriptionFields{
+		Name:        card.Name,
+		Type:        card.TypeLine,
+		ManaCost:    card.ManaCost,
+		Text:        card.OracleText,
+		Price:       card.Prices.USD,
+		Set:         strings.ToUpper(card.Set),
+		SetName:     card.SetName,
+		Description: description,
+	}
+
+	if face != nil {
+		fields.Name = face.Name
+		fields.Type = face.TypeLine
+		fields.ManaCost = face.ManaCost
+		fields.Text = ""
+		if face.OracleText != nil {
+			fields.Text = *face.OracleText
+		}
+	} else if len(fields.Text) == 0 && len(card.CardFaces) > 0 {
+		// Split, flip and adventure cards only have an Oracle text for each
+		// face
+		texts := make([]string, 0, len(card.CardFaces))
+		for _, cardFace := range card.CardFaces {
+			if cardFace.OracleText != nil {
+				texts = append(texts, *cardFace.OracleText)
+			}
+		}
+		fields.Text = strings.Join(texts, "\n\n")
+	}
+
+	for _, ruling := range rulings {
+		fields.Rulings = append(fields.Rulings, ruling.Comment)
+	}
+
+	var sb strings.Builder
+
+	if err := tmpl.Execute(&sb, fields); err != nil {
+		return "", fmt.Errorf("couldn't render the description of %s: %w", card.Name, err)
+	}
+
+	return strings.TrimSpace(sb.String()), nil
+}
+
+// applyDescriptionTemplate replaces the description of cardInfo (and of its
+// alternative state for the double-faced cards) with the one rendered from
+// tmpl. The meld results keep their description.
+func applyDescriptionTemplate(tmpl *template.Template, card scryfall.Card, rulings []scryfall.Ruling, cardInfo *plugins.CardInfo) error {
+	if tmpl == nil {
+		return nil
+	}
+
+	var front *scryfall.CardFace
+	if isDoubleFaced(card) && cardInfo.AlternativeState != nil {
+		front = &card.CardFaces[0]
+
+		description, err := renderDescription(tmpl, card, &card.CardFaces[1], rulings, cardInfo.AlternativeState.Description)
+		if err != nil {
+			return err
+		}
+		cardInfo.AlternativeState.Description = description
+	}
+
+	description, err := renderDescription(tmpl, card, front, rulings, cardInfo.Description)
+	if err != nil {
+		return err
+	}
+	cardInfo.Description = description
+
+	return nil
+}
+
+// richTextColors are the BBCode colors used by the "rich_text" option for the
+// mana symbols and the type lines, chosen to be readable on the dark
+// background of the Tabletop Simulator tooltips.
+var richTextColors = map[string]string{
+	"W": "FFF6C9",
+	"U": "5AAFE8",
+	"B": "B494C9",
+	"R": "F2684A",
+	"G": "52C07A",
+	"C": "BDBDBD",
+	// Multicolored cards and hybrid symbols
+	"M": "E3C35D",
+}
+
+var manaSymbolRegex = regexp.MustCompile(`\{[^{}]+\}`)
+
+// colorKey returns the key of richTextColors matching colors, or an empty
+// string if there is no color.
+func colorKey(colors []string) string {
+	switch len(colors) {
+	case 0:
+		return ""
+	case 1:
+		return colors[0]
+	default:
+		return "M"
+	}
+}
+
+// colorManaSymbols colors the mana symbols of text (e.g. "{R}" or "{W/U}")
+// with BBCode. The other symbols (e.g. "{T}" or "{2}") are left untouched.
+func colorManaSymbols(text string) string {
+	return manaSymbolRegex.ReplaceAllStringFunc(text, func(symbol string) string {
+		var colors []string
+		for _, color := range []string{"W", "U", "B", "R", "G", "C"} {
+			if strings.Contains(symbol, color) {
+				colors = append(colors, color)
+			}
+		}
+
+		key := colorKey(colors)
+		if len(key) == 0 {
+			return symbol
+		}
+
+		return "[" + richTextColors[key] + "]" + symbol + "[-]"
+	})
+}
+
+// colorTypeLine colors the type line of a name generated by buildCardName or
+// buildCardFaceName (the last bold part of the name) with BBCode.
+func colorTypeLine(name string, colors []scryfall.Color) string {
+	names := make([]string, 0, len(colors))
+	for _, color := range colors {
+		names = append(names, string(color))
+	}
+
+	key := colorKey(names)
+	if len(key) == 0 {
+		key = "C"
+	}
+
+	start := strings.LastIndex(name, "[b]")
+	end := strings.LastIndex(name, "[/b]")
+	if start < 0 || end < start {
+		return name
+	}
+
+	return name[:start+3] + "[" + richTextColors[key] + "]" + name[start+3:end] + "[-]" + name[end:]
+}
+
+// applyRichText colors the mana symbols of the descriptions of cardInfo and
+// its alternative state, and the type lines of their names, according to the
+// colors of card.
+func applyRichText(card scryfall.Card, cardInfo *plugins.CardInfo) {
+	colors := card.Colors
+	if len(colors) == 0 && len(card.CardFaces) > 0 {
+		colors = card.CardFaces[0].Colors
+	}
+
+	cardInfo.Name = colorTypeLine(cardInfo.Name, colors)
+	cardInfo.Description = colorManaSymbols(cardInfo.Description)
+
+	if cardInfo.AlternativeState != nil {
+		if len(card.CardFaces) > 1 {
+			colors = card.CardFaces[1].Colors
+		}
+		cardInfo.AlternativeState.Name = colorTypeLine(cardInfo.AlternativeState.Name, colors)
+		cardInfo.AlternativeState.Description = colorManaSymbols(cardInfo.AlternativeState.Description)
+	}
+}
+
+func buildCardMetadata(card scryfall.Card) plugins.CardMetadata {
+	metadata := plugins.CardMetadata{
+		Name:            card.Name,
+		Type:            card.TypeLine,
+		Cost:            card.CMC,
+		Set:             card.Set,
+		CollectorNumber: card.CollectorNumber,
+	}
+
+	texts := []string{}
+	if len(card.OracleText) > 0 {
+		texts = append(texts, card.OracleText)
+	}
+	for _, face := range card.CardFaces {
+		if face.OracleText != nil && len(*face.OracleText) > 0 {
+			texts = append(texts, *face.OracleText)
+		}
+	}
+	metadata.Text = strings.Join(texts, "\n")
+
+	for format, legality := range map[string]scryfall.Legality{
+		standardFormat:  card.Legalities.Standard,
+		pioneerFormat:   card.Legalities.Pioneer,
+		modernFormat:    card.Legalities.Modern,
+		legacyFormat:    card.Legalities.Legacy,
+		vintageFormat:   card.Legalities.Vintage,
+		pauperFormat:    card.Legalities.Pauper,
+		commanderFormat: card.Legalities.Commander,
+	} {
+		if legality != scryfall.LegalityBanned && legality != scryfall.LegalityRestricted {
+			continue
+		}
+		if metadata.Legalities == nil {
+			metadata.Legalities = make(map[string]string)
+		}
+		metadata.Legalities[format] = string(legality)
+	}
+
+	colors := card.Colors
+	if len(colors) == 0 && len(card.CardFaces) > 0 {
+		// Double-faced cards only have colors on their faces
+		colors = card.CardFaces[0].Colors
+	}
+	for _, color := range colors {
+		metadata.Colors = append(metadata.Colors, string(color))
+	}
+
+	if len(card.Prices.USD) > 0 {
+		if price, err := strconv.ParseFloat(card.Prices.USD, 64); err == nil {
+			metadata.Price = price
+		}
+	} else if len(card.Prices.USDFoil) > 0 {
+		if price, err := strconv.ParseFloat(card.Prices.USDFoil, 64); err == nil {
+			metadata.Price = price
+		}
+	}
+
+	return metadata
+}
+
+// usesMechanic returns true if the Oracle text of a card of decks matches
+// regex. The maybeboards are ignored.
+// The Oracle text is read from the metadata of the cards, since their
+// description can be changed with the "description_template" option.
+func usesMechanic(decks []*plugins.Deck, regex *regexp.Regexp) bool {
+	for _, deck := range decks {
+		if plugins.IsDeckSection(deck, "Maybeboard") {
+			continue
+		}
+
+		for _, card := range deck.Cards {
+			if regex.MatchString(card.Metadata.Text) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// requiredDungeons returns the names of the dungeons used by the cards of
+// decks, based on their Oracle text.
+func requiredDungeons(decks []*plugins.Deck) []string {
+	var dungeons []string
+
+	if usesMechanic(decks, ventureRegex) {
+		dungeons = append(dungeons, ventureDungeons...)
+	}
+	if usesMechanic(decks, initiativeRegex) {
+		dungeons = append(dungeons, initiativeDungeon)
+	}
+
+	return dungeons
+}
+
+// splitConspiracies moves the Conspiracy cards of deck to a deck placed face
+// up, since they start the game in the command zone, and the ones with
+// hidden agenda to a deck placed face down. It returns the new decks.
+func splitConspiracies(deck *plugins.Deck) []*plugins.Deck {
+	var (
+		cards         []plugins.CardInfo
+		conspiracies  []plugins.CardInfo
+		hiddenAgendas []plugins.CardInfo
+	)
+
+	for _, card := range deck.Cards {
+		switch {
+		case !strings.Contains(card.Metadata.Type, "Conspiracy"):
+			cards = append(cards, card)
+		case strings.Contains(card.Metadata.Text, "Hidden agenda"):
+			hiddenAgendas = append(hiddenAgendas, card)
+		default:
+			conspiracies = append(conspiracies, card)
+		}
+	}
+
+	var decks []*plugins.Deck
+
+	for _, section := range []struct {
+		name   string
+		cards  []plugins.CardInfo
+		facing plugins.Facing
+	}{
+		{"Conspiracies", conspiracies, plugins.FacingUp},
+		{"Hidden Agendas", hiddenAgendas, plugins.FacingDown},
+	} {
+		if len(section.cards) == 0 {
+			continue
+		}
+
+		decks = append(decks, &plugins.Deck{
+			Name:     deck.Name + " - " + section.name,
+			Cards:    section.cards,
+			BackURL:  deck.BackURL,
+			CardSize: deck.CardSize,
+			Rounded:  deck.Rounded,
+			Facing:   section.facing,
+		})
+	}
+
+	if len(decks) > 0 {
+		deck.Cards = cards
+	}
+
+	return decks
+}
+
+// isDoubleFaced returns true if card has a distinct image for each of its two
+// faces (transform and modal double-faced cards, double-faced tokens, etc.),
+// in which case the back face is added as a second state of the card.
+func isDoubleFaced(card scryfall.Card) bool {
+	switch card.Layout {
+	case scryfall.LayoutTransform, scryfall.LayoutDoubleSided, scryfall.LayoutModalDFC, scryfall.LayoutDoubleFacedToken:
+		return len(card.CardFaces) == 2 && len(card.CardFaces[0].ImageURIs.PNG) > 0 && len(card.CardFaces[1].ImageURIs.PNG) > 0
+	default:
+		return false
+	}
+}
+
+// appendFinish appends the finish of a card (e.g. "Foil") to its description.
+func appendFinish(description string, finish string) string {
+	if len(description) > 0 {
+		description += "\n\n"
+	}
+
+	return description + "[i]" + finish + "[/i]"
+}
+
+// rarityFilter returns the Scryfall search filter selecting the cards matching
+// the "rarity" option, or an empty string if this option is not set.
+func rarityFilter(options map[string]interface{}) string {
+	rarity, found := options["rarity"]
+	if !found {
+		return ""
+	}
+
+	switch rarity.(string) {
+	case commonRarity, uncommonRarity, rareRarity, mythicRarity:
+		return "r:" + rarity.(string)
+	case peasantRarity:
+		return "r<=uncommon"
+	default:
+		return ""
+	}
+}
+
+// selectedBoards returns the boards chosen with the "boards" option, or all
+// of them if it is not set.
+func selectedBoards(options map[string]interface{}) (map[string]bool, error) {
+	boards := map[string]bool{
+		mainBoard:  true,
+		sideBoard:  true,
+		maybeBoard: true,
+	}
+
+	option, found := options["boards"]
+	if !found {
+		return boards, nil
+	}
+
+	selected := make(map[string]bool)
+
+	for _, board := range strings.Split(option.(string), ",") {
+		board = strings.ToLower(strings.TrimSpace(board))
+		if len(board) == 0 {
+			continue
+		}
+		if !boards[board] {
+			return nil, fmt.Errorf("invalid board %q (expected %s, %s or %s)", board, mainBoard, sideBoard, maybeBoard)
+		}
+		selected[board] = true
+	}
+
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no board selected (expected %s, %s or %s)", mainBoard, sideBoard, maybeBoard)
+	}
+
+	return selected, nil
+}
+
+// selectedQualities returns the image qualities tried for each card: the one
+// chosen with the "quality" option, followed by the qualities placed after it
+// in the "quality_fallback" chain (or the whole chain if it isn't part of it).
+func selectedQualities(options map[string]interface{}) ([]string, error) {
+	quality := MagicPlugin.AvailableOptions()["quality"].DefaultValue.(string)
+	if option, found := options["quality"]; found {
+		quality = option.(string)
+	}
+
+	chain := MagicPlugin.AvailableOptions()["quality_fallback"].DefaultValue.(string)
+	if option, found := options["quality_fallback"]; found {
+		chain = option.(string)
+	}
+
+	qualities := []string{quality}
+
+	for _, fallback := range strings.Split(chain, ",") {
+		fallback = strings.ToLower(strings.TrimSpace(fallback))
+		switch fallback {
+		case "":
+			continue
+		case string(small), string(normal), string(large), string(png):
+		default:
+			return nil, fmt.Errorf("invalid image quality %q in quality_fallback (expected %s, %s, %s or %s)", fallback, png, large, normal, small)
+		}
+
+		if fallback == quality {
+			// Only keep the qualities after the selected one
+			qualities = qualities[:1]
+			continue
+		}
+		qualities = append(qualities, fallback)
+	}
+
+	return qualities, nil
+}
+
+// formatRules are the deck construction rules of a format, checked by
+// checkFormat.
+type formatRules struct {
+	// minSize is the minimum number of cards of the main deck.
+	minSize int
+	// maxSize is the maximum number of cards of the main deck, or 0 if
+	// there is no maximum.
+	maxSize int
+	// maxSideboardSize is the maximum number of cards of the sideboard, or 0
+	// if it isn't checked.
+	maxSideboardSize int
+	// maxCopies is the number of copies allowed for each card, or 0 if
+	// there is no limit.
+	maxCopies int
+	// legality is the format of the ban list (see
+	// plugins.CardMetadata.Legalities), or an empty string if the banned
+	// cards aren't checked.
+	legality string
+}
+
+// formats maps the values of the "deck_format" option to the rules of the
+// format, checked by ValidateDecks.
+var formats = map[string]formatRules{
+	constructedFormat: {minSize: 60, maxSideboardSize: 15, maxCopies: 4},
+	limitedFormat:     {minSize: 40},
+	commanderFormat:   {minSize: 100, maxSize: 100, maxCopies: 1, legality: commanderFormat},
+	standardFormat:    {minSize: 60, maxSideboardSize: 15, maxCopies: 4, legality: standardFormat},
+	pioneerFormat:     {minSize: 60, maxSideboardSize: 15, maxCopies: 4, legality: pioneerFormat},
+	modernFormat:      {minSize: 60, maxSideboardSize: 15, maxCopies: 4, legality: modernFormat},
+	legacyFormat:      {minSize: 60, maxSideboardSize: 15, maxCopies: 4, legality: legacyFormat},
+	vintageFormat:     {minSize: 60, maxSideboardSize: 15, maxCopies: 4, legality: vintageFormat},
+	pauperFormat:      {minSize: 60, maxSideboardSize: 15, maxCopies: 4, legality: pauperFormat},
+}
+
+// suspiciousExtraCards is the number of cards above the minimum size of the
+// main deck from which countWarnings reports it, for the formats without a
+// maximum size.
+const suspiciousExtraCards = 20
+
+// suspicious returns the card counts of r reported by countWarnings, which
+// usually mean that the deck list wasn't parsed correctly: the sideboard
+// size and the banned cards aren't checked, and the main deck is only
+// reported when it is far above the minimum size of the format.
+func (r formatRules) suspicious() formatRules {
+	maxSize := r.maxSize
+	if maxSize == 0 && r.minSize > 0 {
+		maxSize = r.minSize + suspiciousExtraCards
+	}
+
+	return formatRules{minSize: r.minSize, maxSize: maxSize, maxCopies: r.maxCopies}
+}
+
+// checkFormat returns the rules broken by decks, the decks generated for a
+// single target. The sections which aren't part of the deck (e.g. the tokens
+// or the maybeboard) are ignored, and the piles of the main deck are checked
+// as a single deck.
+func checkFormat(decks []*plugins.Deck, rules formatRules) []plugins.RuleViolation {
+	var (
+		violations []plugins.RuleViolation
+		counted    []*plugins.Deck
+	)
+
+	for _, deck := range combinePiles(decks) {
+		switch {
+		case plugins.IsDeckSection(deck, "Tokens"), plugins.IsDeckSection(deck, "Dungeons"),
+			plugins.IsDeckSection(deck, "Stickers"), plugins.IsDeckSection(deck, "Contraptions"),
+			plugins.IsDeckSection(deck, "Attractions"), plugins.IsDeckSection(deck, "Commander"),
+			plugins.IsDeckSection(deck, "Conspiracies"), plugins.IsDeckSection(deck, "Hidden Agendas"),
+			plugins.IsDeckSection(deck, "Maybeboard"), plugins.IsDeckSection(deck, "Token Figurines"):
+			continue
+		case plugins.IsDeckSection(deck, "Sideboard"):
+			if rules.maxSideboardSize > 0 {
+				violations = append(violations, plugins.CheckDeckSize("mtg.sideboard-size", deck, 0, rules.maxSideboardSize)...)
+			}
+		default:
+			if rules.minSize > 0 || rules.maxSize > 0 {
+				violations = append(violations, plugins.CheckDeckSize("mtg.deck-size", deck, rules.minSize, rules.maxSize)...)
+			}
+		}
+		counted = append(counted, deck)
+	}
+
+	if len(rules.legality) > 0 {
+		violations = append(violations, checkBanned("mtg.banned", counted, rules.legality)...)
+	}
+
+	if rules.maxCopies > 0 {
+		violations = append(violations, plugins.CheckCopies("mtg.max-copies", counted, copyLimit(rules.maxCopies, rules.legality))...)
+	}
+
+	return violations
+}
+
+// checkBanned reports a violation of ruleID for each card of decks banned in
+// the format legality.
+func checkBanned(ruleID string, decks []*plugins.Deck, legality string) []plugins.RuleViolation {
+	var violations []plugins.RuleViolation
+
+	for _, deck := range decks {
+		for _, card := range deck.Cards {
+			if card.Metadata.Legalities[legality] != string(scryfall.LegalityBanned) {
+				continue
+			}
+
+			name := card.Name
+			if len(card.Metadata.Name) > 0 {
+				name = card.Metadata.Name
+			}
+			violations = append(violations, plugins.RuleViolation{
+				RuleID:  ruleID,
+				Deck:    deck.Name,
+				Card:    name,
+				Message: fmt.Sprintf("%s is banned in %s", name, legality),
+			})
+		}
+	}
+
+	return violations
+}
+
+// copyLimit returns a function to use with plugins.CheckCopies allowing limit
+// copies of each card, except the basic lands and the cards allowing any
+// number of copies, and a single copy of the cards restricted in the format
+// legality (if set).
+func copyLimit(limit int, legality string) func(card plugins.CardInfo) int {
+	return func(card plugins.CardInfo) int {
+		if strings.Contains(card.Metadata.Type, "Basic") && strings.Contains(card.Metadata.Type, "Land") {
+			return -1
+		}
+		if strings.Contains(card.Metadata.Text, "A deck can have any number of cards named") {
+			return -1
+		}
+		if len(legality) > 0 && card.Metadata.Legalities[legality] == string(scryfall.LegalityRestricted) {
+			return 1
+		}
+		return limit
+	}
+}
+
+// pileSuffix ends the name of the decks generated for the categories of the
+// main deck with the "piles" categories option (e.g. "Deck - Ramp (pile)"),
+// so that they can't be mistaken for a section of the deck (e.g. with a
+// "Tokens" category).
+const pileSuffix = " (pile)"
+
+// pileName returns the name of the deck containing the cards of category of
+// the main deck called name.
+func pileName(name, category string) string {
+	return name + " - " + category + pileSuffix
+}
+
+// mainDeckName returns the name of the main deck whose category deck
+// contains (see pileName), or the name of deck if it isn't a pile.
+func mainDeckName(deck *plugins.Deck) string {
+	if !strings.HasSuffix(deck.Name, pileSuffix) {
+		return deck.Name
+	}
+
+	// The names of the categories rarely contain " - ", unlike the names
+	// of the decks
+	name := strings.TrimSuffix(deck.Name, pileSuffix)
+	if index := strings.LastIndex(name, " - "); index >= 0 {
+		return name[:index]
+	}
+
+	return deck.Name
+}
+
+// combinePiles returns decks with the piles of each main deck (see
+// pileName) combined with it, in a deck called after the main deck, so that
+// the main deck is checked as a whole.
+func combinePiles(decks []*plugins.Deck) []*plugins.Deck {
+	var (
+		combined []*plugins.Deck
+		indexes  = make(map[string]int)
+	)
+
+	for _, deck := range decks {
+		name := mainDeckName(deck)
+
+		index, found := indexes[name]
+		if !found {
+			indexes[name] = len(combined)
+			combined = append(combined, &plugins.Deck{Name: name})
+			index = len(combined) - 1
+		}
+		combined[index].Cards = append(combined[index].Cards, deck.Cards...)
+	}
+
+	return combined
+}
+
+// countWarnings returns the suspicious card counts of the decks generated for
+// the deck called name, which usually mean that the deck list wasn't parsed
+// correctly: an empty main deck, sideboard or maybeboard and, depending on
+// the "deck_format" option, a main deck whose size is far from the one of the
+// format or cards with more copies than allowed in the main deck and
+// sideboard (see checkFormat).
+func countWarnings(decks []*plugins.Deck, name string, options map[string]interface{}) []plugins.RuleViolation {
+	var violations []plugins.RuleViolation
+
+	// The piles of the main deck are checked as a single deck
+	for _, deck := range combinePiles(decks) {
+		switch deck.Name {
+		case name, name + " - Sideboard", name + " - Maybeboard":
+		default:
+			continue
+		}
+
+		if len(deck.Cards) == 0 {
+			violations = append(violations, plugins.RuleViolation{
+				RuleID:  "mtg.empty-deck",
+				Deck:    deck.Name,
+				Message: "the deck is empty",
+			})
+		}
+	}
+
+	if format, found := options["deck_format"]; found {
+		if rules, found := formats[format.(string)]; found {
+			violations = append(violations, checkFormat(decks, rules.suspicious())...)
+		}
+	}
+
+	return violations
+}
+
+// warnCounts logs the suspicious card counts returned by countWarnings.
+func warnCounts(decks []*plugins.Deck, name string, options map[string]interface{}) {
+	for _, violation := range countWarnings(decks, name, options) {
+		log.Warn(violation.String())
+	}
+}
+
+// printingFilters returns the Scryfall search filters selecting the printings
+// matching the "artist" and "frame" options, or an empty string if these
+// options are not set.
+func printingFilters(options map[string]interface{}) string {
+	var filters []string
+
+	if artist, found := options["artist"]; found && len(strings.TrimSpace(artist.(string))) > 0 {
+		filters = append(filters, `artist:"`+strings.ReplaceAll(strings.TrimSpace(artist.(string)), `"`, "")+`"`)
+	}
+
+	if frame, found := options["frame"]; found {
+		switch frame.(string) {
+		case showcaseFrame:
+			filters = append(filters, "frame:showcase")
+		case borderlessFrame:
+			filters = append(filters, "border:borderless")
+		case extendedFrame:
+			filters = append(filters, "frame:extendedart")
+		}
+	}
+
+	return strings.Join(filters, " ")
+}
@@ -3,10 +3,13 @@ package mtg
 import (
 	"fmt"
 	"testing"
+	"time"
 	"unicode"
 
 	scryfall "github.com/BlueMonday/go-scryfall"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
 )
 
 func assertNoSpaceStartEnd(t *testing.T, description string) {
@@ -108,3 +111,500 @@ func TestBuildCardFacesDescription(t *testing.T) {
 		},
 	}, nil, false))
 }
+
+func TestBuildCardMetadataText(t *testing.T) {
+	front := "Bewitching Whispers"
+	back := "Whenever you cast a spell, venture into the dungeon."
+
+	metadata := buildCardMetadata(scryfall.Card{OracleText: "Flying"})
+	assert.Equal(t, "Flying", metadata.Text)
+
+	metadata = buildCardMetadata(scryfall.Card{CardFaces: []scryfall.CardFace{
+		{OracleText: &front},
+		{OracleText: &back},
+	}})
+	assert.Equal(t, front+"\n"+back, metadata.Text)
+}
+
+func TestBuildCardMetadataLegalities(t *testing.T) {
+	metadata := buildCardMetadata(scryfall.Card{Legalities: scryfall.Legalities{
+		Standard: scryfall.LegalityNotLegal,
+		Modern:   scryfall.LegalityLegal,
+		Legacy:   scryfall.LegalityBanned,
+		Vintage:  scryfall.LegalityRestricted,
+	}})
+	assert.Equal(t, map[string]string{legacyFormat: "banned", vintageFormat: "restricted"}, metadata.Legalities)
+
+	// Only the formats with a ban list are kept
+	metadata = buildCardMetadata(scryfall.Card{Legalities: scryfall.Legalities{Modern: scryfall.LegalityLegal}})
+	assert.Nil(t, metadata.Legalities)
+}
+
+func TestRequiredDungeons(t *testing.T) {
+	decks := []*plugins.Deck{
+		{
+			Name: "Test",
+			Cards: []plugins.CardInfo{
+				{Name: "Island", Metadata: plugins.CardMetadata{Text: "({T}: Add {U}.)"}},
+			},
+		},
+		{
+			Name: "Test - Maybeboard",
+			Cards: []plugins.CardInfo{
+				{Name: "Seasoned Dungeoneer", Metadata: plugins.CardMetadata{Text: "When Seasoned Dungeoneer enters the battlefield, you take the initiative."}},
+			},
+		},
+	}
+	assert.Empty(t, requiredDungeons(decks))
+
+	// The description can be customized, only the Oracle text is checked
+	decks[0].Cards = append(decks[0].Cards, plugins.CardInfo{
+		Name:        "Acererak the Archlich",
+		Description: "Venture into the dungeon.",
+	})
+	assert.Empty(t, requiredDungeons(decks))
+
+	decks[0].Cards = append(decks[0].Cards, plugins.CardInfo{
+		Name:        "Shessra, Death's Whisper",
+		Description: "Bewitching Whispers",
+		Metadata: plugins.CardMetadata{
+			Text: "Bewitching Whispers\nWhenever you cast a spell, venture into the dungeon.",
+		},
+	})
+	assert.Equal(t, ventureDungeons, requiredDungeons(decks))
+
+	decks[1].Name = "Test - Sideboard"
+	assert.Equal(t, append(ventureDungeons, initiativeDungeon), requiredDungeons(decks))
+}
+
+func TestAuxiliaryDecks(t *testing.T) {
+	for _, test := range []struct {
+		description string
+		section     string
+	}{
+		{"When Clown Car enters the battlefield, put a sticker on it.", "Stickers"},
+		{"{1}, {T}: Put a name sticker on target creature you control.", "Stickers"},
+		{"Whenever Steamflogger Boss attacks, assemble a Contraption.", "Contraptions"},
+		{"Contraptions you control have \"{T}: Untap target creature.\"", "Contraptions"},
+		{"Flying", ""},
+	} {
+		decks := []*plugins.Deck{
+			{
+				Name:  "Test",
+				Cards: []plugins.CardInfo{{Name: "Test", Metadata: plugins.CardMetadata{Text: test.description}}},
+			},
+		}
+
+		var sections []string
+		for _, auxiliary := range auxiliaryDecks {
+			if usesMechanic(decks, auxiliary.regex) {
+				sections = append(sections, auxiliary.section)
+			}
+		}
+
+		if len(test.section) == 0 {
+			assert.Empty(t, sections, test.description)
+		} else {
+			assert.Equal(t, []string{test.section}, sections, test.description)
+		}
+	}
+}
+
+func TestSplitConspiracies(t *testing.T) {
+	deck := &plugins.Deck{
+		Name: "Test",
+		Cards: []plugins.CardInfo{
+			{Name: "Island", Metadata: plugins.CardMetadata{Type: "Basic Land — Island"}},
+			{Name: "Backup Plan", Metadata: plugins.CardMetadata{Type: "Conspiracy"}},
+			{
+				Name: "Secret Summoning",
+				Metadata: plugins.CardMetadata{
+					Type: "Conspiracy",
+					Text: "Hidden agenda (Start the game with this conspiracy face down in the command zone and secretly choose a card name.)",
+				},
+			},
+		},
+	}
+
+	decks := splitConspiracies(deck)
+	assert.Len(t, deck.Cards, 1)
+	if assert.Len(t, decks, 2) {
+		assert.Equal(t, "Test - Conspiracies", decks[0].Name)
+		assert.Equal(t, "Backup Plan", decks[0].Cards[0].Name)
+		assert.Equal(t, plugins.FacingUp, decks[0].Facing)
+		assert.Equal(t, "Test - Hidden Agendas", decks[1].Name)
+		assert.Equal(t, "Secret Summoning", decks[1].Cards[0].Name)
+		assert.Equal(t, plugins.FacingDown, decks[1].Facing)
+	}
+
+	assert.Empty(t, splitConspiracies(deck))
+	assert.Len(t, deck.Cards, 1)
+}
+
+func TestPrintingFilters(t *testing.T) {
+	assert.Empty(t, printingFilters(map[string]interface{}{}))
+	assert.Empty(t, printingFilters(map[string]interface{}{"artist": " ", "frame": anyFrame}))
+	assert.Equal(t, `artist:"Rebecca Guay"`, printingFilters(map[string]interface{}{"artist": "Rebecca Guay"}))
+	assert.Equal(
+		t,
+		`artist:"Rebecca Guay" border:borderless`,
+		printingFilters(map[string]interface{}{"artist": `"Rebecca Guay"`, "frame": borderlessFrame}),
+	)
+	assert.Equal(t, "frame:extendedart", printingFilters(map[string]interface{}{"frame": extendedFrame}))
+}
+
+func TestSelectedBoards(t *testing.T) {
+	boards, err := selectedBoards(map[string]interface{}{})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]bool{mainBoard: true, sideBoard: true, maybeBoard: true}, boards)
+
+	boards, err = selectedBoards(map[string]interface{}{"boards": "main, Side"})
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]bool{mainBoard: true, sideBoard: true}, boards)
+
+	_, err = selectedBoards(map[string]interface{}{"boards": "main,commander"})
+	assert.NotNil(t, err)
+
+	_, err = selectedBoards(map[string]interface{}{"boards": " , "})
+	assert.NotNil(t, err)
+}
+
+func TestSelectedQualities(t *testing.T) {
+	qualities, err := selectedQualities(map[string]interface{}{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"normal", "small"}, qualities)
+
+	qualities, err = selectedQualities(map[string]interface{}{"quality": "png"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"png", "large", "normal", "small"}, qualities)
+
+	qualities, err = selectedQualities(map[string]interface{}{"quality": "png", "quality_fallback": "png, normal"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"png", "normal"}, qualities)
+
+	// The whole chain is used if it doesn't contain the selected quality
+	qualities, err = selectedQualities(map[string]interface{}{"quality": "large", "quality_fallback": "Normal,small"})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"large", "normal", "small"}, qualities)
+
+	qualities, err = selectedQualities(map[string]interface{}{"quality": "large", "quality_fallback": ""})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"large"}, qualities)
+
+	_, err = selectedQualities(map[string]interface{}{"quality_fallback": "png,huge"})
+	assert.NotNil(t, err)
+}
+
+func TestIsDoubleFaced(t *testing.T) {
+	faces := []scryfall.CardFace{
+		{Name: "Day", ImageURIs: scryfall.ImageURIs{PNG: "https://example.com/day.png"}},
+		{Name: "Night", ImageURIs: scryfall.ImageURIs{PNG: "https://example.com/night.png"}},
+	}
+
+	assert.True(t, isDoubleFaced(scryfall.Card{Layout: scryfall.LayoutDoubleFacedToken, CardFaces: faces}))
+	assert.True(t, isDoubleFaced(scryfall.Card{Layout: scryfall.LayoutTransform, CardFaces: faces}))
+	assert.False(t, isDoubleFaced(scryfall.Card{Layout: scryfall.LayoutToken}))
+	// Adventure and split cards have a single image
+	assert.False(t, isDoubleFaced(scryfall.Card{Layout: scryfall.LayoutAdventure, CardFaces: faces}))
+	assert.False(t, isDoubleFaced(scryfall.Card{
+		Layout:    scryfall.LayoutDoubleFacedToken,
+		CardFaces: []scryfall.CardFace{{Name: "Day"}, {Name: "Night"}},
+	}))
+}
+
+func TestRarityFilter(t *testing.T) {
+	assert.Empty(t, rarityFilter(map[string]interface{}{}))
+	assert.Empty(t, rarityFilter(map[string]interface{}{"rarity": anyRarity}))
+	assert.Equal(t, "r:common", rarityFilter(map[string]interface{}{"rarity": commonRarity}))
+	assert.Equal(t, "r:mythic", rarityFilter(map[string]interface{}{"rarity": mythicRarity}))
+	assert.Equal(t, "r<=uncommon", rarityFilter(map[string]interface{}{"rarity": peasantRarity}))
+}
+
+func TestAppendFinish(t *testing.T) {
+	assert.Equal(t, "[i]Foil[/i]", appendFinish("", "Foil"))
+	assert.Equal(t, "{T}: Add {C}{C}.\n\n[i]Etched[/i]", appendFinish("{T}: Add {C}{C}.", "Etched"))
+}
+
+func TestRulingsFilter(t *testing.T) {
+	ruling := func(comment string, year int) scryfall.Ruling {
+		return scryfall.Ruling{
+			Comment:     comment,
+			PublishedAt: scryfall.Date{Time: time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)},
+		}
+	}
+	rulings := []scryfall.Ruling{ruling("a", 2004), ruling("b", 2015), ruling("c", 2020)}
+
+	filter, err := parseRulingsFilter(map[string]interface{}{"rulings": false, "max_rulings": 1})
+	assert.Nil(t, err)
+	assert.Nil(t, filter)
+
+	filter, err = parseRulingsFilter(map[string]interface{}{"rulings": true})
+	assert.Nil(t, err)
+	assert.Equal(t, rulings, filter.apply(rulings))
+
+	filter, err = parseRulingsFilter(map[string]interface{}{"rulings": true, "max_rulings": 2})
+	assert.Nil(t, err)
+	assert.Equal(t, rulings[1:], filter.apply(rulings))
+
+	filter, err = parseRulingsFilter(map[string]interface{}{"rulings": true, "rulings_since": "2015-01-01", "max_rulings": 5})
+	assert.Nil(t, err)
+	assert.Equal(t, rulings[1:], filter.apply(rulings))
+
+	filter, err = parseRulingsFilter(map[string]interface{}{"rulings": true, "rulings_since": "2016-01-01", "max_rulings": 1})
+	assert.Nil(t, err)
+	assert.Equal(t, rulings[2:], filter.apply(rulings))
+
+	_, err = parseRulingsFilter(map[string]interface{}{"rulings": true, "rulings_since": "01/01/2016"})
+	assert.NotNil(t, err)
+
+	_, err = parseRulingsFilter(map[string]interface{}{"rulings": true, "max_rulings": -1})
+	assert.NotNil(t, err)
+}
+
+func TestCountWarnings(t *testing.T) {
+	decks := []*plugins.Deck{
+		{
+			Name: "Test",
+			Cards: []plugins.CardInfo{
+				{Name: "Lightning Bolt", Count: 4},
+				{Name: "Mountain", Count: 20, Metadata: plugins.CardMetadata{Type: "Basic Land — Mountain"}},
+			},
+		},
+		{
+			Name:  "Test - Sideboard",
+			Cards: []plugins.CardInfo{{Name: "Lightning Bolt", Count: 1}},
+		},
+		{Name: "Test - Maybeboard"},
+		{Name: "Test - Tokens"},
+	}
+
+	violations := countWarnings(decks, "Test", map[string]interface{}{"deck_format": anyFormat})
+	if assert.Len(t, violations, 1) {
+		assert.Equal(t, "mtg.empty-deck", violations[0].RuleID)
+		assert.Equal(t, "Test - Maybeboard", violations[0].Deck)
+	}
+
+	violations = countWarnings(decks, "Test", map[string]interface{}{"deck_format": constructedFormat})
+	var ruleIDs []string
+	for _, violation := range violations {
+		ruleIDs = append(ruleIDs, violation.RuleID)
+	}
+	assert.Equal(t, []string{"mtg.empty-deck", "mtg.deck-size", "mtg.max-copies"}, ruleIDs)
+
+	violations = countWarnings(decks[:1], "Test", map[string]interface{}{"deck_format": limitedFormat})
+	assert.Len(t, violations, 1)
+
+	// The token figurines aren't part of the deck
+	figurines := &plugins.Deck{
+		Name:  "Test - Token Figurines",
+		Cards: []plugins.CardInfo{{Name: "Soldier", Count: 5}},
+	}
+	violations = countWarnings([]*plugins.Deck{decks[0], figurines}, "Test", map[string]interface{}{"deck_format": limitedFormat})
+	assert.Len(t, violations, 1)
+}
+
+func TestMainDeckName(t *testing.T) {
+	assert.Equal(t, "Atraxa - Superfriends", mainDeckName(&plugins.Deck{Name: "Atraxa - Superfriends"}))
+	assert.Equal(t, "Atraxa - Superfriends", mainDeckName(&plugins.Deck{Name: pileName("Atraxa - Superfriends", "Ramp")}))
+	assert.Equal(t, "Atraxa - Superfriends - Sideboard", mainDeckName(&plugins.Deck{Name: "Atraxa - Superfriends - Sideboard"}))
+
+	// A category can't be mistaken for a section
+	assert.False(t, plugins.IsDeckSection(&plugins.Deck{Name: pileName("Deck", "Tokens")}, "Tokens"))
+}
+
+func TestPilesWarnings(t *testing.T) {
+	decks := []*plugins.Deck{
+		{
+			Name:  "Test",
+			Cards: []plugins.CardInfo{{Name: "Lightning Bolt", Count: 4}},
+		},
+		{
+			Name:  pileName("Test", "Lands"),
+			Cards: []plugins.CardInfo{{Name: "Mountain", Count: 56, Metadata: plugins.CardMetadata{Type: "Basic Land — Mountain"}}},
+		},
+		{
+			Name:  pileName("Test", "Tokens"),
+			Cards: []plugins.CardInfo{{Name: "Lightning Bolt", Count: 1}},
+		},
+	}
+
+	// The piles form a single main deck of 61 cards, with 5 copies of
+	// Lightning Bolt
+	violations := countWarnings(decks, "Test", map[string]interface{}{"deck_format": constructedFormat})
+	if assert.Len(t, violations, 1) {
+		assert.Equal(t, "mtg.max-copies", violations[0].RuleID)
+	}
+
+	violations = MagicPlugin.ValidateDecks(decks, nil)
+	if assert.Len(t, violations, 1) {
+		assert.Equal(t, "mtg.max-copies", violations[0].RuleID)
+		assert.Equal(t, "Test", violations[0].Deck)
+	}
+}
+
+func TestCopyLimit(t *testing.T) {
+	limit := copyLimit(4, "")
+
+	assert.Equal(t, 4, limit(plugins.CardInfo{Name: "Lightning Bolt"}))
+	assert.Equal(t, -1, limit(plugins.CardInfo{Name: "Mountain", Metadata: plugins.CardMetadata{Type: "Basic Land — Mountain"}}))
+	assert.Equal(t, -1, limit(plugins.CardInfo{
+		Name:     "Relentless Rats",
+		Metadata: plugins.CardMetadata{Text: "Relentless Rats gets +1/+1 for each other creature you control named Relentless Rats.\nA deck can have any number of cards named Relentless Rats."},
+	}))
+	// The description can be customized, only the Oracle text is checked
+	assert.Equal(t, 4, limit(plugins.CardInfo{
+		Name:        "Rat Colony",
+		Description: "A deck can have any number of cards named Rat Colony.",
+	}))
+}
+
+func TestValidateDecks(t *testing.T) {
+	decks := []*plugins.Deck{
+		{
+			Name: "Test",
+			Cards: []plugins.CardInfo{
+				{Name: "Lightning Bolt", Count: 4},
+				{Name: "Mountain", Count: 56, Metadata: plugins.CardMetadata{Type: "Basic Land — Mountain"}},
+			},
+		},
+		{
+			Name:  "Test - Sideboard",
+			Cards: []plugins.CardInfo{{Name: "Lightning Bolt", Count: 1}},
+		},
+		{
+			Name:  "Test - Tokens",
+			Cards: []plugins.CardInfo{{Name: "Goblin", Count: 8}},
+		},
+		{
+			Name:  "Test - Token Figurines",
+			Cards: []plugins.CardInfo{{Name: "Soldier", Count: 5}},
+		},
+	}
+
+	violations := MagicPlugin.ValidateDecks(decks, nil)
+	if assert.Len(t, violations, 1) {
+		assert.Equal(t, "mtg.max-copies", violations[0].RuleID)
+		assert.Equal(t, "Lightning Bolt", violations[0].Card)
+	}
+
+	// A limited deck only needs 40 cards, without any copy limit
+	limited := []*plugins.Deck{
+		{
+			Name: "Draft",
+			Cards: []plugins.CardInfo{
+				{Name: "Lightning Bolt", Count: 6},
+				{Name: "Mountain", Count: 34, Metadata: plugins.CardMetadata{Type: "Basic Land — Mountain"}},
+			},
+		},
+	}
+
+	ruleIDs := func(violations []plugins.RuleViolation) []string {
+		ids := []string{}
+		for _, violation := range violations {
+			ids = append(ids, violation.RuleID)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []string{"mtg.deck-size", "mtg.max-copies"}, ruleIDs(MagicPlugin.ValidateDecks(limited, nil)))
+	assert.Empty(t, MagicPlugin.ValidateDecks(limited, map[string]string{"deck_format": limitedFormat}))
+	assert.Equal(t, []string{"mtg.deck-size", "mtg.max-copies"}, ruleIDs(MagicPlugin.ValidateDecks(limited, map[string]string{"deck_format": commanderFormat})))
+}
+
+func TestValidateDecksBanned(t *testing.T) {
+	decks := []*plugins.Deck{
+		{
+			Name: "Test",
+			Cards: []plugins.CardInfo{
+				{Name: "Brainstorm", Count: 4, Metadata: plugins.CardMetadata{Name: "Brainstorm", Legalities: map[string]string{vintageFormat: "restricted"}}},
+				{Name: "Black Lotus", Count: 1, Metadata: plugins.CardMetadata{Name: "Black Lotus", Legalities: map[string]string{legacyFormat: "banned", vintageFormat: "restricted"}}},
+				{Name: "Island", Count: 55, Metadata: plugins.CardMetadata{Type: "Basic Land — Island"}},
+			},
+		},
+	}
+
+	// The ban lists aren't checked without a tournament format
+	assert.Empty(t, MagicPlugin.ValidateDecks(decks, nil))
+
+	violations := MagicPlugin.ValidateDecks(decks, map[string]string{"deck_format": legacyFormat})
+	if assert.Len(t, violations, 1) {
+		assert.Equal(t, "mtg.banned", violations[0].RuleID)
+		assert.Equal(t, "Black Lotus", violations[0].Card)
+		assert.Equal(t, "Test: Black Lotus is banned in legacy (mtg.banned)", violations[0].String())
+	}
+
+	// The restricted cards are limited to a single copy
+	violations = MagicPlugin.ValidateDecks(decks, map[string]string{"deck_format": vintageFormat})
+	if assert.Len(t, violations, 1) {
+		assert.Equal(t, "mtg.max-copies", violations[0].RuleID)
+		assert.Equal(t, "Brainstorm", violations[0].Card)
+	}
+}
+
+func TestApplyDescriptionTemplate(t *testing.T) {
+	tmpl, err := descriptionTemplate(map[string]interface{}{"description_template": ""})
+	assert.Nil(t, err)
+	assert.Nil(t, tmpl)
+
+	_, err = descriptionTemplate(map[string]interface{}{"description_template": "{{.Name"})
+	assert.NotNil(t, err)
+
+	tmpl, err = descriptionTemplate(map[string]interface{}{
+		"description_template": "{{.Type}} ({{.Set}}, ${{.Price}})\n{{.Text}}{{range .Rulings}}\n- {{.}}{{end}}",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	card := scryfall.Card{
+		Name:       "Lightning Bolt",
+		TypeLine:   "Instant",
+		OracleText: "Lightning Bolt deals 3 damage to any target.",
+		Set:        "m10",
+		Prices:     scryfall.Prices{USD: "1.50"},
+	}
+	cardInfo := plugins.CardInfo{Description: card.OracleText}
+	rulings := []scryfall.Ruling{{Comment: "It can target a planeswalker."}}
+
+	assert.Nil(t, applyDescriptionTemplate(tmpl, card, rulings, &cardInfo))
+	assert.Equal(t, "Instant (M10, $1.50)\nLightning Bolt deals 3 damage to any target.\n- It can target a planeswalker.", cardInfo.Description)
+
+	dayText := "Daybound"
+	nightText := "Nightbound"
+	faces := []scryfall.CardFace{
+		{Name: "Day", TypeLine: "Creature — Human", OracleText: &dayText, ImageURIs: scryfall.ImageURIs{PNG: "https://example.com/day.png"}},
+		{Name: "Night", TypeLine: "Creature — Werewolf", OracleText: &nightText, ImageURIs: scryfall.ImageURIs{PNG: "https://example.com/night.png"}},
+	}
+	card = scryfall.Card{Name: "Day // Night", Layout: scryfall.LayoutTransform, CardFaces: faces, Set: "mid"}
+	cardInfo = plugins.CardInfo{AlternativeState: &plugins.CardInfo{}}
+
+	tmpl, err = descriptionTemplate(map[string]interface{}{"description_template": "{{.Name}}: {{.Text}}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, applyDescriptionTemplate(tmpl, card, nil, &cardInfo))
+	assert.Equal(t, "Day: Daybound", cardInfo.Description)
+	assert.Equal(t, "Night: Nightbound", cardInfo.AlternativeState.Description)
+}
+
+func TestApplyRichText(t *testing.T) {
+	assert.Equal(t, "[F2684A]{R}[-], {T}: Add [E3C35D]{W/U}[-] or {2}.", colorManaSymbols("{R}, {T}: Add {W/U} or {2}."))
+
+	card := scryfall.Card{
+		Name:     "Lightning Bolt",
+		TypeLine: "Instant",
+		CMC:      1,
+		Colors:   []scryfall.Color{scryfall.ColorRed},
+	}
+	cardInfo := plugins.CardInfo{
+		Name:        buildCardName(card),
+		Description: "{R}: Lightning Bolt deals 3 damage to any target.",
+	}
+
+	applyRichText(card, &cardInfo)
+	assert.Equal(t, "Lightning Bolt\n1CMC\n[b][F2684A]Instant[-][/b]", cardInfo.Name)
+	assert.Equal(t, "[F2684A]{R}[-]: Lightning Bolt deals 3 damage to any target.", cardInfo.Description)
+
+	// Colorless cards
+	assert.Equal(t, "Sol Ring\n[b][BDBDBD]Artifact[-][/b]", colorTypeLine("Sol Ring\n[b]Artifact[/b]", nil))
+}
@@ -1,27 +1,36 @@
 package pkm
 
 import (
+	"context"
 	"fmt"
 	"time"
 
 	pokemontcgsdk "github.com/PokemonTCG/pokemon-tcg-sdk-go-v2/pkg"
 	"github.com/PokemonTCG/pokemon-tcg-sdk-go-v2/pkg/request"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
 )
 
 // See https://docs.pokemontcg.io/#documentationrate_limits
-var rateLimiter = time.NewTicker(1.4 * 1000 * time.Millisecond)
+var rateLimiter = plugins.NewRateLimiter(PokemonPlugin.PluginID(), 1400*time.Millisecond)
 
-func getCards(name string, setCode string) ([]pokemontcgsdk.PokemonCard, error) {
-	<-rateLimiter.C
+// The Pokémon TCG SDK doesn't support contexts, so ctx is only used to stop
+// waiting for the rate limiter.
+func getCards(ctx context.Context, name string, setCode string) ([]pokemontcgsdk.PokemonCard, error) {
+	if err := rateLimiter.Wait(ctx); err != nil {
+		return nil, err
+	}
 	
 	name_query := fmt.Sprintf("name:%s", name)
 	set_query := fmt.Sprintf("set.id:%s", setCode)
 	tcg := pokemontcgsdk.NewClient("")
 	
+	start := time.Now()
 	cards, err := tcg.GetCards(
 		request.Query(name_query, set_query),
 		request.PageSize(5),
 	)
+	plugins.RecordAPICall(PokemonPlugin.PluginID(), start, err)
 
 	deref := []pokemontcgsdk.PokemonCard{}
 
@@ -33,12 +42,16 @@ func getCards(name string, setCode string) ([]pokemontcgsdk.PokemonCard, error)
 }
 
 func getSets() ([]pokemontcgsdk.Set, error) {
-	<-rateLimiter.C
+	if err := rateLimiter.Wait(context.Background()); err != nil {
+		return nil, err
+	}
 	tcg := pokemontcgsdk.NewClient("")
 	
+	start := time.Now()
 	sets, err := tcg.GetSets(
 		request.PageSize(200),
 	)
+	plugins.RecordAPICall(PokemonPlugin.PluginID(), start, err)
 	deref := []pokemontcgsdk.Set{}
 
 	for _, set := range sets {
@@ -2,6 +2,7 @@ package pkm
 
 import (
 	"bufio"
+	"context"
 	"io"
 	"regexp"
 	"strconv"
@@ -94,7 +95,7 @@ func (c *CardNames) String() string {
 	return sb.String()
 }
 
-func cardNamesToDeck(cards *CardNames, name string, options map[string]interface{}) (*plugins.Deck, error) {
+func cardNamesToDeck(ctx context.Context, cards *CardNames, name string, options map[string]interface{}) (*plugins.Deck, error) {
 	deck := &plugins.Deck{
 		Name:     name,
 		BackURL:  PokemonPlugin.AvailableBacks()[plugins.DefaultBackKey].URL,
@@ -102,7 +103,14 @@ func cardNamesToDeck(cards *CardNames, name string, options map[string]interface
 		Rounded:  true,
 	}
 
-	for _, cardInfo := range cards.Names {
+	total := len(cards.Names)
+
+	for index, cardInfo := range cards.Names {
+		// Stop querying the cards if the conversion has been cancelled
+		if err := ctx.Err(); err != nil {
+			return deck, err
+		}
+
 		count := cards.Count(cardInfo.Name, cardInfo.Set)
 
 		set, found := getSetCode(cardInfo.Set)
@@ -120,7 +128,7 @@ func cardNamesToDeck(cards *CardNames, name string, options map[string]interface
 
 		log.Debugf("Querying card %s (%s)", cardInfo.Name, set)
 
-		cards, err := getCards(cardInfo.Name, set)
+		cards, err := getCards(ctx, cardInfo.Name, set)
 		if err != nil {
 			log.Errorw(
 				"Pokemon TCG SDK client error",
@@ -142,6 +150,8 @@ func cardNamesToDeck(cards *CardNames, name string, options map[string]interface
 
 		log.Debugf("API response (%d card(s)): %v", len(cards), cards)
 
+		plugins.ReportCardResolved(ctx, deck.Name, cardInfo.Name, index+1, total)
+
 		var card pokemontcgsdk.PokemonCard
 
 		for _, card = range cards {
@@ -157,13 +167,14 @@ func cardNamesToDeck(cards *CardNames, name string, options map[string]interface
 			Description: buildCardDescription(card),
 			ImageURL:    card.Images.Large,
 			Count:       count,
+			Metadata:    buildCardMetadata(card),
 		})
 	}
 
 	return deck, nil
 }
 
-func fromDeckFile(file io.Reader, name string, options map[string]string) ([]*plugins.Deck, error) {
+func fromDeckFile(ctx context.Context, file io.Reader, name string, options map[string]string) ([]*plugins.Deck, error) {
 	// Check the options
 	validatedOptions, err := PokemonPlugin.AvailableOptions().ValidateNormalize(options)
 	if err != nil {
@@ -178,7 +189,7 @@ func fromDeckFile(file io.Reader, name string, options map[string]string) ([]*pl
 	var decks []*plugins.Deck
 
 	if main != nil {
-		deck, err := cardNamesToDeck(main, name, validatedOptions)
+		deck, err := cardNamesToDeck(ctx, main, name, validatedOptions)
 		if err != nil {
 			return nil, err
 		}
@@ -235,7 +246,12 @@ func parseDeckFile(file io.Reader) (*CardNames, error) {
 				log.Errorf("Error when parsing count: %s", err)
 				continue
 			}
-			name := strings.TrimSpace(matches[nameIdx])
+			name := plugins.NormalizeName(strings.TrimSpace(matches[nameIdx]))
+			name, keep := plugins.ResolveCardName(name)
+			if !keep {
+				log.Infof("Ignoring %s", name)
+				break
+			}
 			set := strings.TrimSpace(matches[setIdx])
 			number := strings.TrimSpace(matches[numberIdx])
 
@@ -1,10 +1,16 @@
 package pkm
 
 import (
+	"context"
+	"net/http"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+	"github.com/jeandeaual/tts-deckconverter/plugins/cassette"
 )
 
 func TestParseDeckFile(t *testing.T) {
@@ -105,3 +111,37 @@ Total Cards - 34
 	assert.Equal(t, expected, main)
 	assert.Nil(t, err)
 }
+
+func TestFromDeckFile(t *testing.T) {
+	recorder := cassette.NewForTest(t, filepath.Join("testdata", "ptcgo.json"))
+
+	// The Pokémon TCG SDK creates its own client, using
+	// http.DefaultTransport
+	defaultTransport := http.DefaultTransport
+	defer func() {
+		http.DefaultTransport = defaultTransport
+	}()
+	http.DefaultTransport = recorder
+	defer plugins.SetHTTPClient(nil)
+	plugins.SetHTTPTransport(recorder)
+
+	decks, err := fromDeckFile(context.Background(), strings.NewReader(`##Pokémon - 2
+
+* 2 Furfrou KSS 32
+
+##Energy - 2
+
+* 2 Psychic Energy XYEnergy 8
+`), "Test", map[string]string{})
+	assert.Nil(t, err)
+	assert.Nil(t, recorder.Save())
+
+	if assert.Len(t, decks, 1) {
+		assert.Equal(t, "Test", decks[0].Name)
+		assert.Equal(t, 4, plugins.CountCards(decks[0]))
+		for _, card := range decks[0].Cards {
+			assert.NotEmpty(t, card.Name)
+			assert.NotEmpty(t, card.ImageURL, card.Name)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package pkm
 
 import (
+	"strings"
+
 	"github.com/jeandeaual/tts-deckconverter/plugins"
 )
 
@@ -96,6 +98,36 @@ func (p pokemonPlugin) AvailableBacks() map[string]plugins.Back {
 	}
 }
 
+// TablePresets returns the table of a match, with prize card counters.
+func (p pokemonPlugin) TablePresets() map[string]plugins.TablePreset {
+	return map[string]plugins.TablePreset{
+		"match": {
+			Description: "two players with prize card counters",
+			Players:     2,
+			Counters:    []string{"prize"},
+		},
+	}
+}
+
+// ValidateDecks checks the standard deck construction rules: exactly 60
+// cards, and up to 4 copies of each card (except the basic Energy cards).
+func (p pokemonPlugin) ValidateDecks(decks []*plugins.Deck, _ map[string]string) []plugins.RuleViolation {
+	var violations []plugins.RuleViolation
+
+	for _, deck := range decks {
+		violations = append(violations, plugins.CheckDeckSize("pkm.deck-size", deck, 60, 60)...)
+	}
+
+	violations = append(violations, plugins.CheckCopies("pkm.max-copies", decks, func(card plugins.CardInfo) int {
+		if strings.HasPrefix(card.Metadata.Type, "Energy") && strings.Contains(card.Metadata.Type, "Basic") {
+			return -1
+		}
+		return 4
+	})...)
+
+	return violations
+}
+
 // PokemonPlugin is the exported plugin for this package
 var PokemonPlugin = pokemonPlugin{
 	id:   "pkm",
@@ -5,6 +5,7 @@ import (
 	"sync"
 
 	"github.com/jeandeaual/tts-deckconverter/log"
+	"github.com/jeandeaual/tts-deckconverter/plugins"
 )
 
 type setMap struct {
@@ -40,23 +41,40 @@ func (sm *setMap) Store(key, value string) {
 var (
 	ptcgoSetToStandardSetMap *setMap
 	standardSetToPTCGOSetMap *setMap
+	// setUpMutex ensures that the sets are only retrieved once, even when
+	// several decks are parsed concurrently.
+	setUpMutex sync.Mutex
 )
 
+// setUp retrieves the sets the first time it is called (or until it
+// succeeds).
 func setUp() bool {
+	setUpMutex.Lock()
+	defer setUpMutex.Unlock()
+
+	plugins.RecordCacheLookup("pkm.sets", ptcgoSetToStandardSetMap != nil)
+
+	if ptcgoSetToStandardSetMap != nil {
+		return true
+	}
+
 	sets, err := getSets()
 	if err != nil {
 		log.Errorf("Couldn't retrieve sets: %s", err)
 		return false
 	}
 
-	ptcgoSetToStandardSetMap = newSetMap()
-	standardSetToPTCGOSetMap = newSetMap()
+	ptcgoToStandard := newSetMap()
+	standardToPTCGO := newSetMap()
 
 	for _, set := range sets {
-		ptcgoSetToStandardSetMap.Store(set.PtcgoCode, set.ID)
-		standardSetToPTCGOSetMap.Store(set.ID, set.PtcgoCode)
+		ptcgoToStandard.Store(set.PtcgoCode, set.ID)
+		standardToPTCGO.Store(set.ID, set.PtcgoCode)
 	}
 
+	ptcgoSetToStandardSetMap = ptcgoToStandard
+	standardSetToPTCGOSetMap = standardToPTCGO
+
 	return true
 }
 
@@ -65,16 +83,16 @@ func getSetCode(ptcgoSetCode string) (string, bool) {
 		ptcgoSetCode = strings.TrimSuffix(ptcgoSetCode, "Energy")
 	}
 
-	if ptcgoSetToStandardSetMap == nil {
-		setUp()
+	if !setUp() {
+		return "", false
 	}
 
 	return ptcgoSetToStandardSetMap.Load(ptcgoSetCode)
 }
 
 func getPTCGOSetCode(setCode string) (string, bool) {
-	if standardSetToPTCGOSetMap == nil {
-		setUp()
+	if !setUp() {
+		return "", false
 	}
 
 	return standardSetToPTCGOSetMap.Load(strings.ToLower(setCode))
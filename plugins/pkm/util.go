@@ -5,6 +5,8 @@ import (
 	"strings"
 
 	pokemontcgsdk "github.com/PokemonTCG/pokemon-tcg-sdk-go-v2/pkg"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
 )
 
 func formatElement(element string) string {
@@ -178,3 +180,22 @@ func buildCardDescription(card pokemontcgsdk.PokemonCard) string {
 
 	return sb.String()
 }
+
+func buildCardMetadata(card pokemontcgsdk.PokemonCard) plugins.CardMetadata {
+	metadata := plugins.CardMetadata{
+		Name:   card.Name,
+		Type:   strings.TrimSpace(card.Supertype + " " + strings.Join(card.Subtypes, " ")),
+		Colors: card.Types,
+	}
+
+	prices := card.TCGPlayer.Prices
+	if prices.Normal != nil {
+		metadata.Price = prices.Normal.Market
+	} else if prices.Holofoil != nil {
+		metadata.Price = prices.Holofoil.Market
+	} else if prices.ReverseHolofoil != nil {
+		metadata.Price = prices.ReverseHolofoil.Market
+	}
+
+	return metadata
+}
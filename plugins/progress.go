@@ -0,0 +1,55 @@
+package plugins
+
+import (
+	"context"
+)
+
+// ProgressReporter receives progress events during a conversion.
+// Its methods can be called from several goroutines.
+type ProgressReporter interface {
+	// CardResolved is called each time the information about a card has
+	// been retrieved. resolved is the number of cards of the deck retrieved
+	// so far, out of total.
+	CardResolved(deckName string, cardName string, resolved int, total int)
+	// ImageDownloaded is called each time an image has been downloaded.
+	ImageDownloaded(url string, bytes int64)
+	// FileWritten is called each time a file has been generated.
+	FileWritten(path string, bytes int64)
+}
+
+type progressReporterKey struct{}
+
+// WithProgressReporter returns a copy of ctx which reports the progress of
+// the conversions using it to reporter.
+func WithProgressReporter(ctx context.Context, reporter ProgressReporter) context.Context {
+	return context.WithValue(ctx, progressReporterKey{}, reporter)
+}
+
+func progressReporter(ctx context.Context) ProgressReporter {
+	reporter, _ := ctx.Value(progressReporterKey{}).(ProgressReporter)
+	return reporter
+}
+
+// ReportCardResolved notifies the progress reporter of ctx (if any) that a
+// card has been retrieved.
+func ReportCardResolved(ctx context.Context, deckName string, cardName string, resolved int, total int) {
+	if reporter := progressReporter(ctx); reporter != nil {
+		reporter.CardResolved(deckName, cardName, resolved, total)
+	}
+}
+
+// ReportImageDownloaded notifies the progress reporter of ctx (if any) that
+// an image has been downloaded.
+func ReportImageDownloaded(ctx context.Context, url string, bytes int64) {
+	if reporter := progressReporter(ctx); reporter != nil {
+		reporter.ImageDownloaded(url, bytes)
+	}
+}
+
+// ReportFileWritten notifies the progress reporter of ctx (if any) that a
+// file has been generated.
+func ReportFileWritten(ctx context.Context, path string, bytes int64) {
+	if reporter := progressReporter(ctx); reporter != nil {
+		reporter.FileWritten(path, bytes)
+	}
+}
@@ -0,0 +1,46 @@
+package plugins
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type recordingReporter struct {
+	cards  []string
+	images []string
+	files  []string
+	bytes  int64
+}
+
+func (r *recordingReporter) CardResolved(deckName string, cardName string, resolved int, total int) {
+	r.cards = append(r.cards, cardName)
+}
+
+func (r *recordingReporter) ImageDownloaded(url string, bytes int64) {
+	r.images = append(r.images, url)
+	r.bytes += bytes
+}
+
+func (r *recordingReporter) FileWritten(path string, bytes int64) {
+	r.files = append(r.files, path)
+	r.bytes += bytes
+}
+
+func TestProgressReporter(t *testing.T) {
+	// No reporter set, nothing should happen
+	ReportCardResolved(context.Background(), "deck", "card", 1, 1)
+
+	reporter := &recordingReporter{}
+	ctx := WithProgressReporter(context.Background(), reporter)
+
+	ReportCardResolved(ctx, "deck", "card", 1, 2)
+	ReportImageDownloaded(ctx, "https://example.com/card.png", 10)
+	ReportFileWritten(ctx, "deck.json", 5)
+
+	assert.Equal(t, []string{"card"}, reporter.cards)
+	assert.Equal(t, []string{"https://example.com/card.png"}, reporter.images)
+	assert.Equal(t, []string{"deck.json"}, reporter.files)
+	assert.Equal(t, int64(15), reporter.bytes)
+}
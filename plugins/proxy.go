@@ -0,0 +1,96 @@
+package plugins
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+var (
+	// hostProxies maps a host name to the proxy used for the requests to
+	// this host.
+	hostProxies      = make(map[string]*url.URL)
+	hostProxiesMutex sync.RWMutex
+)
+
+// ParseProxyURL parses the URL of a proxy, which needs to use the http,
+// https or socks5 scheme (e.g. "socks5://localhost:1080").
+func ParseProxyURL(rawURL string) (*url.URL, error) {
+	proxyURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL %s: %w", rawURL, err)
+	}
+
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("invalid proxy URL %s (the scheme needs to be http, https or socks5)", rawURL)
+	}
+	if len(proxyURL.Host) == 0 {
+		return nil, fmt.Errorf("invalid proxy URL %s (no host)", rawURL)
+	}
+
+	return proxyURL, nil
+}
+
+// SetHostProxy routes the requests to host (and its subdomains) through the
+// proxy located at proxyURL (see ParseProxyURL), e.g. to access a deck
+// website blocked in some regions. The other requests use the proxy set in
+// the environment (HTTP_PROXY, HTTPS_PROXY and NO_PROXY), if any.
+// The proxies are only used with the default transport of HTTPClient.
+func SetHostProxy(host, proxyURL string) error {
+	parsed, err := ParseProxyURL(proxyURL)
+	if err != nil {
+		return err
+	}
+
+	hostProxiesMutex.Lock()
+	defer hostProxiesMutex.Unlock()
+
+	hostProxies[strings.ToLower(host)] = parsed
+
+	return nil
+}
+
+// proxyForRequest returns the proxy registered with SetHostProxy for the host
+// of req, the most specific host first, or the proxy set in the environment.
+func proxyForRequest(req *http.Request) (*url.URL, error) {
+	hostProxiesMutex.RLock()
+	defer hostProxiesMutex.RUnlock()
+
+	requestHost := strings.ToLower(req.URL.Hostname())
+
+	var (
+		proxyURL  *url.URL
+		matchHost string
+	)
+
+	for host, hostProxy := range hostProxies {
+		if matchesHost(requestHost, host) && len(host) > len(matchHost) {
+			proxyURL = hostProxy
+			matchHost = host
+		}
+	}
+
+	if proxyURL != nil {
+		return proxyURL, nil
+	}
+
+	return http.ProxyFromEnvironment(req)
+}
+
+// newDefaultTransport returns a copy of http.DefaultTransport using the
+// proxies registered with SetHostProxy.
+func newDefaultTransport() http.RoundTripper {
+	transport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return http.DefaultTransport
+	}
+
+	transport = transport.Clone()
+	transport.Proxy = proxyForRequest
+
+	return transport
+}
@@ -0,0 +1,59 @@
+package plugins
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseProxyURL(t *testing.T) {
+	proxyURL, err := ParseProxyURL("socks5://localhost:1080")
+	assert.Nil(t, err)
+	assert.Equal(t, "localhost:1080", proxyURL.Host)
+
+	_, err = ParseProxyURL("http://proxy.example.com:3128")
+	assert.Nil(t, err)
+
+	_, err = ParseProxyURL("ftp://proxy.example.com")
+	assert.NotNil(t, err)
+	_, err = ParseProxyURL("localhost:1080")
+	assert.NotNil(t, err)
+}
+
+func TestProxyForRequest(t *testing.T) {
+	assert.Nil(t, SetHostProxy("blocked.example.com", "socks5://localhost:1080"))
+	assert.Nil(t, SetHostProxy("api.blocked.example.com", "http://localhost:3128"))
+	defer func() {
+		hostProxiesMutex.Lock()
+		defer hostProxiesMutex.Unlock()
+		delete(hostProxies, "blocked.example.com")
+		delete(hostProxies, "api.blocked.example.com")
+	}()
+
+	assert.NotNil(t, SetHostProxy("other.example.com", "localhost"))
+
+	for rawURL, expected := range map[string]string{
+		"https://blocked.example.com/decks/1":     "socks5://localhost:1080",
+		"https://www.blocked.example.com/decks/1": "socks5://localhost:1080",
+		"https://api.blocked.example.com/decks/1": "http://localhost:3128",
+		"https://notblocked.example.com/decks/1":  "",
+		"https://other.example.com/decks/1":       "",
+	} {
+		req, err := http.NewRequest("GET", rawURL, nil)
+		assert.Nil(t, err)
+
+		proxyURL, err := proxyForRequest(req)
+		assert.Nil(t, err)
+		if len(expected) == 0 {
+			// Depends on the environment
+			if proxyURL != nil {
+				assert.NotContains(t, proxyURL.String(), "localhost:1080")
+			}
+			continue
+		}
+		if assert.NotNil(t, proxyURL, rawURL) {
+			assert.Equal(t, expected, proxyURL.String(), rawURL)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RateLimiter ensures a minimum interval between consecutive API calls.
+type RateLimiter struct {
+	mutex    sync.Mutex
+	interval time.Duration
+	last     time.Time
+}
+
+var (
+	// rateLimiters maps a plugin ID to the rate limiter of its API.
+	rateLimiters      = make(map[string]*RateLimiter)
+	rateLimitersMutex sync.RWMutex
+)
+
+// NewRateLimiter creates a rate limiter for the API used by the plugin
+// with the given ID. interval is the default interval between API calls,
+// and can be changed with SetRateLimit.
+func NewRateLimiter(pluginID string, interval time.Duration) *RateLimiter {
+	rateLimitersMutex.Lock()
+	defer rateLimitersMutex.Unlock()
+
+	limiter := &RateLimiter{interval: interval}
+	rateLimiters[pluginID] = limiter
+
+	return limiter
+}
+
+// NewStandaloneRateLimiter creates a rate limiter which isn't associated
+// with a plugin, and thus isn't changed by SetRateLimit and
+// SetDefaultRateLimit (e.g. for the downloads from an image server).
+func NewStandaloneRateLimiter(interval time.Duration) *RateLimiter {
+	return &RateLimiter{interval: interval}
+}
+
+// Wait blocks until the next API call is allowed, or until ctx is done.
+// In the latter case, the context error is returned.
+func (r *RateLimiter) Wait(ctx context.Context) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if wait := r.interval - time.Since(r.last); wait > 0 {
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+	r.last = time.Now()
+
+	return nil
+}
+
+func (r *RateLimiter) setInterval(interval time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.interval = interval
+}
+
+// RateLimitedPlugins returns the IDs of the plugins with a rate limiter,
+// sorted.
+func RateLimitedPlugins() []string {
+	rateLimitersMutex.RLock()
+	defer rateLimitersMutex.RUnlock()
+
+	pluginIDs := make([]string, 0, len(rateLimiters))
+	for pluginID := range rateLimiters {
+		pluginIDs = append(pluginIDs, pluginID)
+	}
+	sort.Strings(pluginIDs)
+
+	return pluginIDs
+}
+
+// RateLimitInterval returns the interval between API calls for a plugin, or
+// 0 if the plugin doesn't have a rate limiter.
+func RateLimitInterval(pluginID string) time.Duration {
+	rateLimitersMutex.RLock()
+	defer rateLimitersMutex.RUnlock()
+
+	limiter, found := rateLimiters[pluginID]
+	if !found {
+		return 0
+	}
+
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	return limiter.interval
+}
+
+// SetRateLimit changes the interval between API calls for a plugin.
+func SetRateLimit(pluginID string, interval time.Duration) error {
+	if interval < 0 {
+		return fmt.Errorf("invalid rate limit for %s: %s", pluginID, interval)
+	}
+
+	rateLimitersMutex.RLock()
+	defer rateLimitersMutex.RUnlock()
+
+	limiter, found := rateLimiters[pluginID]
+	if !found {
+		return fmt.Errorf("no rate limit available for %s", pluginID)
+	}
+	limiter.setInterval(interval)
+
+	return nil
+}
+
+// SetDefaultRateLimit changes the interval between API calls for every
+// plugin.
+func SetDefaultRateLimit(interval time.Duration) error {
+	if interval < 0 {
+		return fmt.Errorf("invalid rate limit: %s", interval)
+	}
+
+	rateLimitersMutex.RLock()
+	defer rateLimitersMutex.RUnlock()
+
+	for _, limiter := range rateLimiters {
+		limiter.setInterval(interval)
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package plugins
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	limiter := NewRateLimiter("ratelimit-test", 20*time.Millisecond)
+
+	start := time.Now()
+	assert.Nil(t, limiter.Wait(context.Background()))
+	assert.Nil(t, limiter.Wait(context.Background()))
+	assert.Nil(t, limiter.Wait(context.Background()))
+	assert.True(t, time.Since(start) >= 40*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, limiter.Wait(ctx))
+
+	assert.Nil(t, SetRateLimit("ratelimit-test", time.Millisecond))
+	assert.Equal(t, time.Millisecond, RateLimitInterval("ratelimit-test"))
+	assert.NotNil(t, SetRateLimit("ratelimit-test", -time.Millisecond))
+	assert.NotNil(t, SetRateLimit("missing", time.Millisecond))
+	assert.Equal(t, time.Duration(0), RateLimitInterval("missing"))
+
+	assert.Contains(t, RateLimitedPlugins(), "ratelimit-test")
+}
+
+func TestStandaloneRateLimiter(t *testing.T) {
+	limiter := NewStandaloneRateLimiter(20 * time.Millisecond)
+	pluginCount := len(RateLimitedPlugins())
+
+	start := time.Now()
+	assert.Nil(t, limiter.Wait(context.Background()))
+	assert.Nil(t, limiter.Wait(context.Background()))
+	assert.True(t, time.Since(start) >= 20*time.Millisecond)
+
+	// The limiter isn't associated with a plugin
+	assert.Len(t, RateLimitedPlugins(), pluginCount)
+}
@@ -0,0 +1,192 @@
+package plugins
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// maxRequestRetries is the maximum number of retries of a single
+	// request.
+	maxRequestRetries = 3
+	// retryDelay is the delay before the first retry of a request, doubled
+	// after each retry.
+	retryDelay = 500 * time.Millisecond
+	// maxRetryDelay is the maximum delay before a retry, including the delay
+	// requested by the server with a Retry-After header.
+	maxRetryDelay = 10 * time.Second
+)
+
+// RetryBudget limits the total number of retries of the failed HTTP requests
+// sent by a conversion, so that an unavailable website doesn't make it retry
+// every card.
+// Its methods can be called from several goroutines.
+type RetryBudget struct {
+	mutex     sync.Mutex
+	remaining int
+	retried   int
+}
+
+// NewRetryBudget returns a budget allowing retries retries.
+func NewRetryBudget(retries int) *RetryBudget {
+	return &RetryBudget{remaining: retries}
+}
+
+// take consumes a retry, and returns false if the budget is exhausted.
+func (b *RetryBudget) take() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.remaining <= 0 {
+		return false
+	}
+	b.remaining--
+	b.retried++
+
+	return true
+}
+
+// Retried returns the number of retries consumed so far.
+func (b *RetryBudget) Retried() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	return b.retried
+}
+
+type retryBudgetKey struct{}
+
+// WithRetryBudget returns a copy of ctx whose failed HTTP requests sent
+// through HTTPClient are retried, until budget is exhausted. The requests
+// are only retried on network errors and on the server errors which are
+// usually temporary (e.g. 429 Too Many Requests or 503 Service Unavailable).
+// Only the requests using an idempotent method (e.g. GET) are retried, unless
+// their context is returned by AllowRetry.
+func WithRetryBudget(ctx context.Context, budget *RetryBudget) context.Context {
+	return context.WithValue(ctx, retryBudgetKey{}, budget)
+}
+
+func retryBudget(ctx context.Context) *RetryBudget {
+	budget, _ := ctx.Value(retryBudgetKey{}).(*RetryBudget)
+	return budget
+}
+
+type allowRetryKey struct{}
+
+// AllowRetry returns a copy of ctx whose failed HTTP requests can be retried
+// (see WithRetryBudget) whatever their method, for the requests which can be
+// safely sent several times even if their method isn't idempotent (e.g. a
+// POST request only querying data).
+// A request with side effects (e.g. posting a message) must not use it,
+// since it could be applied twice when the server fails after processing it.
+func AllowRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, allowRetryKey{}, true)
+}
+
+// retryableRequest checks if req can be sent again when it fails.
+func retryableRequest(req *http.Request) bool {
+	// Only the requests without a body, or whose body can be read again,
+	// can be sent several times
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return false
+	}
+
+	switch req.Method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		allowed, _ := req.Context().Value(allowRetryKey{}).(bool)
+		return allowed
+	}
+}
+
+// retryableStatus checks if a request which failed with status can succeed
+// when sent again.
+func retryableStatus(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryAfter returns the delay before sending the attempt-th retry of a
+// request, following the Retry-After header of resp if set.
+func retryAfter(resp *http.Response, attempt int) time.Duration {
+	delay := retryDelay << uint(attempt)
+
+	if resp != nil {
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+			delay = time.Duration(seconds) * time.Second
+		}
+	}
+
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+
+	return delay
+}
+
+// retryTransport retries the failed requests whose context has a retry
+// budget (see WithRetryBudget), if they can be sent again (see
+// retryableRequest).
+type retryTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	ctx := req.Context()
+	budget := retryBudget(ctx)
+	if budget == nil || !retryableRequest(req) {
+		return base.RoundTrip(req)
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := base.RoundTrip(req)
+		if err == nil && !retryableStatus(resp.StatusCode) {
+			return resp, nil
+		}
+		if attempt >= maxRequestRetries || ctx.Err() != nil || !budget.take() {
+			return resp, err
+		}
+
+		delay := retryAfter(resp, attempt)
+		if resp != nil {
+			// Drain the body so that the connection can be reused
+			_, _ = io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+	}
+}
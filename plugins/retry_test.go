@@ -0,0 +1,116 @@
+package plugins
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// statusTransport returns the statuses in order, then 200.
+type statusTransport struct {
+	statuses []int
+	requests int
+}
+
+func (t *statusTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	status := http.StatusOK
+	if t.requests < len(t.statuses) {
+		status = t.statuses[t.requests]
+	}
+	t.requests++
+
+	return &http.Response{
+		StatusCode: status,
+		// Retry immediately
+		Header: http.Header{"Retry-After": []string{"0"}},
+		Body:   ioutil.NopCloser(strings.NewReader("")),
+	}, nil
+}
+
+func TestRetryTransport(t *testing.T) {
+	base := &statusTransport{statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+	transport := retryTransport{base: base}
+
+	// No retry without a budget
+	req, err := http.NewRequest("GET", "https://example.com", nil)
+	assert.Nil(t, err)
+	resp, err := transport.RoundTrip(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, 1, base.requests)
+
+	budget := NewRetryBudget(2)
+	ctx := WithRetryBudget(context.Background(), budget)
+
+	base.requests = 0
+	req, err = http.NewRequestWithContext(ctx, "GET", "https://example.com", nil)
+	assert.Nil(t, err)
+	resp, err = transport.RoundTrip(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 3, base.requests)
+	assert.Equal(t, 2, budget.Retried())
+
+	// The budget is exhausted
+	base.requests = 0
+	resp, err = transport.RoundTrip(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, 1, base.requests)
+
+	// Client errors aren't retried
+	base.statuses = []int{http.StatusNotFound}
+	base.requests = 0
+	req, err = http.NewRequestWithContext(WithRetryBudget(context.Background(), NewRetryBudget(2)), "GET", "https://example.com", nil)
+	assert.Nil(t, err)
+	resp, err = transport.RoundTrip(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.Equal(t, 1, base.requests)
+}
+
+func TestRetryTransportMethods(t *testing.T) {
+	base := &statusTransport{statuses: []int{http.StatusServiceUnavailable}}
+	transport := retryTransport{base: base}
+	ctx := WithRetryBudget(context.Background(), NewRetryBudget(10))
+
+	// A POST request can have side effects, it isn't retried even if its
+	// body can be sent again
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://example.com", strings.NewReader("{}"))
+	assert.Nil(t, err)
+	resp, err := transport.RoundTrip(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, 1, base.requests)
+
+	// Unless the request allows it
+	base.requests = 0
+	req, err = http.NewRequestWithContext(AllowRetry(ctx), "POST", "https://example.com", strings.NewReader("{}"))
+	assert.Nil(t, err)
+	resp, err = transport.RoundTrip(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 2, base.requests)
+
+	// A body which can't be read again can't be sent twice
+	base.requests = 0
+	req, err = http.NewRequestWithContext(AllowRetry(ctx), "POST", "https://example.com", ioutil.NopCloser(strings.NewReader("{}")))
+	assert.Nil(t, err)
+	resp, err = transport.RoundTrip(req)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, 1, base.requests)
+}
+
+func TestRetryAfter(t *testing.T) {
+	assert.Equal(t, retryDelay, retryAfter(nil, 0))
+	assert.Equal(t, 4*retryDelay, retryAfter(nil, 2))
+	assert.Equal(t, maxRetryDelay, retryAfter(nil, 10))
+	assert.Equal(t, 2*time.Second, retryAfter(&http.Response{Header: http.Header{"Retry-After": []string{"2"}}}, 0))
+	assert.Equal(t, maxRetryDelay, retryAfter(&http.Response{Header: http.Header{"Retry-After": []string{"3600"}}}, 0))
+}
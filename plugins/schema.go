@@ -0,0 +1,69 @@
+package plugins
+
+// JSONSchemaVersion is the JSON Schema draft used by OptionsSchema.
+const JSONSchemaVersion = "http://json-schema.org/draft-07/schema#"
+
+// JSONSchema is the subset of JSON Schema needed to describe plugin options.
+type JSONSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 string                 `json:"type"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Default              interface{}            `json:"default,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	AdditionalProperties *bool                  `json:"additionalProperties,omitempty"`
+}
+
+// JSONSchema returns the JSON Schema of the option.
+func (o Option) JSONSchema() *JSONSchema {
+	schema := &JSONSchema{
+		Description: o.Description,
+		Default:     o.DefaultValue,
+	}
+
+	switch o.Type {
+	case OptionTypeBool:
+		schema.Type = "boolean"
+	case OptionTypeInt:
+		schema.Type = "integer"
+	case OptionTypeEnum:
+		schema.Type = "string"
+		schema.Enum = o.AllowedValues
+	case OptionTypeString:
+		schema.Type = "string"
+	default:
+		schema.Type = "string"
+	}
+
+	return schema
+}
+
+// JSONSchema returns the JSON Schema of an object containing the options.
+// Unknown options are rejected by ValidateNormalize, so additional
+// properties aren't allowed.
+func (o Options) JSONSchema() *JSONSchema {
+	additionalProperties := false
+
+	schema := &JSONSchema{
+		Type:                 "object",
+		Properties:           make(map[string]*JSONSchema, len(o)),
+		AdditionalProperties: &additionalProperties,
+	}
+
+	for key, option := range o {
+		schema.Properties[key] = option.JSONSchema()
+	}
+
+	return schema
+}
+
+// OptionsSchema returns the JSON Schema of the options available for plugin,
+// so that forms can be built dynamically (e.g. by a GUI or a bot).
+func OptionsSchema(plugin Plugin) *JSONSchema {
+	schema := plugin.AvailableOptions().JSONSchema()
+	schema.Schema = JSONSchemaVersion
+	schema.Title = plugin.PluginName()
+
+	return schema
+}
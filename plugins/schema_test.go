@@ -0,0 +1,52 @@
+package plugins
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOptionsJSONSchema(t *testing.T) {
+	options := Options{
+		"quality": Option{
+			Type:          OptionTypeEnum,
+			Description:   "image quality",
+			DefaultValue:  "normal",
+			AllowedValues: []string{"small", "normal", "large"},
+		},
+		"rulings": Option{
+			Type:         OptionTypeBool,
+			Description:  "add the rulings to each card description",
+			DefaultValue: false,
+		},
+		"count": Option{
+			Type:        OptionTypeInt,
+			Description: "number of cards",
+		},
+	}
+
+	data, err := json.Marshal(options.JSONSchema())
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{
+		"type": "object",
+		"properties": {
+			"quality": {
+				"description": "image quality",
+				"type": "string",
+				"enum": ["small", "normal", "large"],
+				"default": "normal"
+			},
+			"rulings": {
+				"description": "add the rulings to each card description",
+				"type": "boolean",
+				"default": false
+			},
+			"count": {
+				"description": "number of cards",
+				"type": "integer"
+			}
+		},
+		"additionalProperties": false
+	}`, string(data))
+}
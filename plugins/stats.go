@@ -0,0 +1,100 @@
+package plugins
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// DeckStats contains a summary of the contents of a deck.
+type DeckStats struct {
+	// Name of the deck.
+	Name string
+	// CardCount is the total number of cards in the deck.
+	CardCount int
+	// UniqueCount is the number of different cards in the deck.
+	UniqueCount int
+	// Curve maps a card cost to the number of cards with this cost.
+	Curve map[int]int
+	// Colors maps a color to the number of cards of this color.
+	Colors map[string]int
+	// Colorless is the number of cards without any color.
+	Colorless int
+	// Price is the total price of the deck, in USD.
+	Price float64
+	// MissingPrices is the number of cards without price information.
+	MissingPrices int
+}
+
+// CurveCosts returns the costs found in the curve, sorted.
+func (s DeckStats) CurveCosts() []int {
+	costs := make([]int, 0, len(s.Curve))
+
+	for cost := range s.Curve {
+		costs = append(costs, cost)
+	}
+	sort.Ints(costs)
+
+	return costs
+}
+
+// SortedColors returns the colors found in the deck, sorted by card count
+// (highest first).
+func (s DeckStats) SortedColors() []string {
+	colors := make([]string, 0, len(s.Colors))
+
+	for color := range s.Colors {
+		colors = append(colors, color)
+	}
+	sort.Slice(colors, func(i, j int) bool {
+		if s.Colors[colors[i]] == s.Colors[colors[j]] {
+			return colors[i] < colors[j]
+		}
+		return s.Colors[colors[i]] > s.Colors[colors[j]]
+	})
+
+	return colors
+}
+
+// ComputeStats builds the statistics of a deck.
+// Lands (cards with a type line containing "Land") are not part of the
+// curve.
+func ComputeStats(deck *Deck) DeckStats {
+	stats := DeckStats{
+		Name:   deck.Name,
+		Curve:  make(map[int]int),
+		Colors: make(map[string]int),
+	}
+
+	for _, card := range deck.Cards {
+		stats.CardCount += card.Count
+		stats.UniqueCount++
+
+		if !isLand(card.Metadata.Type) {
+			stats.Curve[int(card.Metadata.Cost)] += card.Count
+		}
+
+		if len(card.Metadata.Colors) == 0 {
+			stats.Colorless += card.Count
+		}
+		for _, color := range card.Metadata.Colors {
+			stats.Colors[color] += card.Count
+		}
+
+		if card.Metadata.Price > 0 {
+			stats.Price += card.Metadata.Price * float64(card.Count)
+		} else {
+			stats.MissingPrices += card.Count
+		}
+	}
+
+	return stats
+}
+
+func isLand(typeLine string) bool {
+	words := strings.FieldsFunc(typeLine, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '—' || r == '-' || r == '/'
+	})
+
+	return IndexOf("Land", words) >= 0
+}
@@ -0,0 +1,55 @@
+package plugins
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeStats(t *testing.T) {
+	deck := &Deck{
+		Name: "Test",
+		Cards: []CardInfo{
+			{
+				Count: 4,
+				Metadata: CardMetadata{
+					Name:   "Lightning Bolt",
+					Type:   "Instant",
+					Cost:   1,
+					Colors: []string{"R"},
+					Price:  1.5,
+				},
+			},
+			{
+				Count: 2,
+				Metadata: CardMetadata{
+					Name:   "Boros Charm",
+					Type:   "Instant",
+					Cost:   2,
+					Colors: []string{"R", "W"},
+				},
+			},
+			{
+				Count: 10,
+				Metadata: CardMetadata{
+					Name:  "Mountain",
+					Type:  "Basic Land — Mountain",
+					Price: 0.1,
+				},
+			},
+		},
+	}
+
+	stats := ComputeStats(deck)
+
+	assert.Equal(t, "Test", stats.Name)
+	assert.Equal(t, 16, stats.CardCount)
+	assert.Equal(t, 3, stats.UniqueCount)
+	assert.Equal(t, map[int]int{1: 4, 2: 2}, stats.Curve)
+	assert.Equal(t, []int{1, 2}, stats.CurveCosts())
+	assert.Equal(t, map[string]int{"R": 6, "W": 2}, stats.Colors)
+	assert.Equal(t, []string{"R", "W"}, stats.SortedColors())
+	assert.Equal(t, 10, stats.Colorless)
+	assert.InDelta(t, 7.0, stats.Price, 0.0001)
+	assert.Equal(t, 2, stats.MissingPrices)
+}
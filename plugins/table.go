@@ -0,0 +1,68 @@
+package plugins
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MinTablePlayers is the minimum number of players of a table preset.
+	MinTablePlayers = 2
+	// MaxTablePlayers is the maximum number of players of a table preset.
+	MaxTablePlayers = 4
+)
+
+// TablePreset describes a full table generated for a game: a seat for each
+// player, with a hand zone, a mat, counters and a copy of the converted
+// decks.
+type TablePreset struct {
+	// Description of the preset.
+	Description string
+	// Players is the default number of players, between MinTablePlayers and
+	// MaxTablePlayers.
+	Players int
+	// LifeTotal is the starting total of the life counter of each player
+	// (0 if the game doesn't use one). The life counter of the decks is used
+	// instead when they have one.
+	LifeTotal int
+	// Counters are the names of the counters placed next to the mat of each
+	// player (e.g. "poison").
+	Counters []string
+}
+
+// TablePresetProvider can be implemented by a plugin to generate full
+// tables for its game.
+type TablePresetProvider interface {
+	// TablePresets returns the table presets of the plugin, indexed by their
+	// name.
+	TablePresets() map[string]TablePreset
+}
+
+// ParseTablePreset parses a table preset selection with the format
+// "NAME[:PLAYERS]" (e.g. "commander:3"). The number of players is 0 if not
+// set.
+func ParseTablePreset(value string) (string, int, error) {
+	name := value
+	players := 0
+
+	if index := strings.LastIndex(value, ":"); index >= 0 {
+		name = value[:index]
+		count, err := strconv.Atoi(value[index+1:])
+		if err != nil || count < MinTablePlayers || count > MaxTablePlayers {
+			return "", 0, fmt.Errorf(
+				"invalid number of players: %s (the tables have %d to %d players)",
+				value[index+1:],
+				MinTablePlayers,
+				MaxTablePlayers,
+			)
+		}
+		players = count
+	}
+
+	if len(name) == 0 {
+		return "", 0, fmt.Errorf("invalid table preset: %s", value)
+	}
+
+	return name, players, nil
+}
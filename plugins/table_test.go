@@ -0,0 +1,24 @@
+package plugins
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTablePreset(t *testing.T) {
+	name, players, err := ParseTablePreset("duel")
+	assert.Nil(t, err)
+	assert.Equal(t, "duel", name)
+	assert.Equal(t, 0, players)
+
+	name, players, err = ParseTablePreset("commander:3")
+	assert.Nil(t, err)
+	assert.Equal(t, "commander", name)
+	assert.Equal(t, 3, players)
+
+	for _, value := range []string{"", ":2", "commander:1", "commander:5", "commander:x"} {
+		_, _, err = ParseTablePreset(value)
+		assert.NotNil(t, err, value)
+	}
+}
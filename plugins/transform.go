@@ -0,0 +1,306 @@
+package plugins
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jeandeaual/tts-deckconverter/log"
+)
+
+// Transform modifies a deck after it has been parsed, and before the
+// Tabletop Simulator files are generated.
+type Transform func(deck *Deck) error
+
+// TransformBuilder creates a deck transformation.
+type TransformBuilder struct {
+	// Description of the transformation, displayed to the user.
+	Description string
+	// Build creates the transformation from the argument set by the user
+	// (empty if none).
+	Build func(arg string) (Transform, error)
+}
+
+// Transforms maps a name to a deck transformation builder.
+// New transformations can be added to this map before calling NewTransform.
+var Transforms = map[string]TransformBuilder{
+	"strip-basic-lands": {
+		Description: "remove the basic lands (Magic)",
+		Build:       noArgument("strip-basic-lands", stripBasicLands),
+	},
+	"max-copies": {
+		Description: "limit the number of copies of each card (e.g. \"max-copies=1\")",
+		Build:       buildMaxCopies,
+	},
+	"remove": {
+		Description: "remove a card, e.g. a banned one (e.g. \"remove=Black Lotus\")",
+		Build:       buildRemove,
+	},
+	"append-description": {
+		Description: "add a line to the description of each card (e.g. \"append-description=Proxy\")",
+		Build:       buildAppendDescription,
+	},
+	"lock": {
+		Description: "lock the decks in place, for the accessories which need to stay put on the table (e.g. playmats, reference cards or zone markers)",
+		Build:       noArgument("lock", lock),
+	},
+	"freeze": {
+		Description: "lock the decks in place and prevent the players from interacting with them (only the scripts can)",
+		Build:       noArgument("freeze", freeze),
+	},
+	"card-scripts": {
+		Description: "attach a Lua script to the cards with the same name, from a folder of \"CARD NAME.lua\" files or a single one (e.g. \"card-scripts=scripts\")",
+		Build:       buildCardScripts,
+	},
+	"mystery": {
+		Description: "hide the faces of the cards behind the back image (or a placeholder image, e.g. \"mystery=https://example.com/placeholder.png\"), the faces being shown in the second state of each card",
+		Build:       buildMystery,
+	},
+}
+
+// AvailableTransforms returns the names of the deck transformations in
+// Transforms, sorted.
+func AvailableTransforms() []string {
+	names := make([]string, 0, len(Transforms))
+
+	for name := range Transforms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// NewTransform creates the transformation described by spec, using the
+// "NAME" or "NAME=ARGUMENT" format.
+func NewTransform(spec string) (Transform, error) {
+	name := spec
+	arg := ""
+
+	if kv := strings.SplitN(spec, "=", 2); len(kv) == 2 {
+		name = kv[0]
+		arg = kv[1]
+	}
+
+	builder, found := Transforms[name]
+	if !found {
+		return nil, fmt.Errorf("invalid deck transformation: %s", name)
+	}
+
+	return builder.Build(arg)
+}
+
+// ApplyTransforms runs each transformation on each deck, in order.
+// The decks left without any card are removed.
+func ApplyTransforms(decks []*Deck, transforms []Transform) ([]*Deck, error) {
+	if len(transforms) == 0 {
+		return decks, nil
+	}
+
+	result := make([]*Deck, 0, len(decks))
+
+	for _, deck := range decks {
+		for _, transform := range transforms {
+			if err := transform(deck); err != nil {
+				return nil, fmt.Errorf("couldn't transform deck %s: %w", deck.Name, err)
+			}
+		}
+
+		if len(deck.Cards) == 0 {
+			log.Infof("Deck %s is empty after its transformation, skipping it", deck.Name)
+			continue
+		}
+
+		result = append(result, deck)
+	}
+
+	return result, nil
+}
+
+func noArgument(name string, transform Transform) func(string) (Transform, error) {
+	return func(arg string) (Transform, error) {
+		if len(arg) > 0 {
+			return nil, fmt.Errorf("deck transformation %s doesn't take an argument", name)
+		}
+		return transform, nil
+	}
+}
+
+// cardName returns the name of a card without any formatting.
+func cardName(card CardInfo) string {
+	if len(card.Metadata.Name) > 0 {
+		return card.Metadata.Name
+	}
+	return card.Name
+}
+
+// filterCards removes the cards for which keep returns false.
+func filterCards(deck *Deck, keep func(CardInfo) bool) {
+	cards := deck.Cards[:0]
+
+	for _, card := range deck.Cards {
+		if keep(card) {
+			cards = append(cards, card)
+		}
+	}
+
+	deck.Cards = cards
+}
+
+func stripBasicLands(deck *Deck) error {
+	filterCards(deck, func(card CardInfo) bool {
+		return !isLand(card.Metadata.Type) || !strings.Contains(card.Metadata.Type, "Basic")
+	})
+
+	return nil
+}
+
+func lock(deck *Deck) error {
+	deck.Locked = true
+
+	return nil
+}
+
+func freeze(deck *Deck) error {
+	deck.Locked = true
+	deck.NonInteractable = true
+
+	return nil
+}
+
+func buildMaxCopies(arg string) (Transform, error) {
+	max, err := strconv.Atoi(arg)
+	if err != nil || max < 1 {
+		return nil, fmt.Errorf("invalid number of copies: %s", arg)
+	}
+
+	return func(deck *Deck) error {
+		for i := range deck.Cards {
+			if deck.Cards[i].Count > max {
+				deck.Cards[i].Count = max
+			}
+		}
+		return nil
+	}, nil
+}
+
+func buildRemove(arg string) (Transform, error) {
+	if len(arg) == 0 {
+		return nil, errors.New("no card name set")
+	}
+
+	return func(deck *Deck) error {
+		filterCards(deck, func(card CardInfo) bool {
+			return !strings.EqualFold(cardName(card), arg)
+		})
+		return nil
+	}, nil
+}
+
+func buildAppendDescription(arg string) (Transform, error) {
+	if len(arg) == 0 {
+		return nil, errors.New("no description set")
+	}
+
+	return func(deck *Deck) error {
+		for i := range deck.Cards {
+			if len(deck.Cards[i].Description) > 0 {
+				deck.Cards[i].Description += "\n\n" + arg
+			} else {
+				deck.Cards[i].Description = arg
+			}
+		}
+		return nil
+	}, nil
+}
+
+func buildMystery(arg string) (Transform, error) {
+	return func(deck *Deck) error {
+		deck.Mystery = true
+		deck.MysteryFaceURL = arg
+		return nil
+	}, nil
+}
+
+// scriptKey returns the key of a card name in the scripts loaded by
+// buildCardScripts, ignoring the case and the characters which can't be
+// used in a file name (e.g. "Fire // Ice" matches "fire ice.lua").
+func scriptKey(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`/\:*?"<>|`, r) {
+			return -1
+		}
+		return r
+	}, name)
+
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// loadCardScripts returns the Lua scripts located at path (a .lua file or a
+// folder containing them), indexed by the scriptKey of their file name.
+func loadCardScripts(path string) (map[string]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		files, err = filepath.Glob(filepath.Join(path, "*.lua"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	scripts := make(map[string]string, len(files))
+
+	for _, file := range files {
+		script, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		scripts[scriptKey(name)] = string(script)
+	}
+
+	if len(scripts) == 0 {
+		return nil, fmt.Errorf("no Lua script found in %s", path)
+	}
+
+	return scripts, nil
+}
+
+func buildCardScripts(arg string) (Transform, error) {
+	if len(arg) == 0 {
+		return nil, errors.New("no script path set")
+	}
+
+	scripts, err := loadCardScripts(arg)
+	if err != nil {
+		return nil, err
+	}
+
+	var attach func(card *CardInfo)
+	attach = func(card *CardInfo) {
+		// The names of the cards can contain formatting after the first line
+		name := strings.SplitN(cardName(*card), "\n", 2)[0]
+		if script, found := scripts[scriptKey(name)]; found {
+			card.LuaScript = script
+		}
+		if card.AlternativeState != nil {
+			attach(card.AlternativeState)
+		}
+	}
+
+	return func(deck *Deck) error {
+		for i := range deck.Cards {
+			attach(&deck.Cards[i])
+		}
+		return nil
+	}, nil
+}
@@ -0,0 +1,168 @@
+package plugins
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func transformTestDeck() *Deck {
+	return &Deck{
+		Name: "Test",
+		Cards: []CardInfo{
+			{
+				Name:  "Lightning Bolt",
+				Count: 4,
+				Metadata: CardMetadata{
+					Name: "Lightning Bolt",
+					Type: "Instant",
+				},
+			},
+			{
+				Name:        "Black Lotus",
+				Description: "Artifact",
+				Count:       1,
+				Metadata: CardMetadata{
+					Name: "Black Lotus",
+					Type: "Artifact",
+				},
+			},
+			{
+				Name:  "Mountain",
+				Count: 10,
+				Metadata: CardMetadata{
+					Name: "Mountain",
+					Type: "Basic Land — Mountain",
+				},
+			},
+		},
+	}
+}
+
+func TestNewTransform(t *testing.T) {
+	for _, spec := range []string{"strip-basic-lands", "max-copies=1", "remove=Black Lotus", "append-description=Proxy", "mystery", "mystery=https://example.com/placeholder.png", "lock", "freeze"} {
+		transform, err := NewTransform(spec)
+		assert.Nil(t, err, spec)
+		assert.NotNil(t, transform, spec)
+	}
+
+	for _, spec := range []string{"invalid", "strip-basic-lands=1", "max-copies", "max-copies=0", "remove", "append-description=", "lock=true", "card-scripts", "card-scripts=does-not-exist"} {
+		_, err := NewTransform(spec)
+		assert.NotNil(t, err, spec)
+	}
+}
+
+func TestMysteryTransform(t *testing.T) {
+	transform, err := NewTransform("mystery=https://example.com/placeholder.png")
+	assert.Nil(t, err)
+
+	deck := &Deck{Name: "Test"}
+	assert.Nil(t, transform(deck))
+	assert.True(t, deck.Mystery)
+	assert.Equal(t, "https://example.com/placeholder.png", deck.MysteryFaceURL)
+}
+
+func TestCardScriptsTransform(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scripts")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "lightning bolt.lua"), []byte("-- Bolt"), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "Insectile Aberration.lua"), []byte("-- Aberration"), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "notes.txt"), []byte("Not a script"), 0644))
+
+	transform, err := NewTransform("card-scripts=" + dir)
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	deck := transformTestDeck()
+	deck.Cards[1].AlternativeState = &CardInfo{Name: "Insectile Aberration\n[b]Creature[/b]"}
+	assert.Nil(t, transform(deck))
+	assert.Equal(t, "-- Bolt", deck.Cards[0].LuaScript)
+	assert.Empty(t, deck.Cards[1].LuaScript)
+	assert.Equal(t, "-- Aberration", deck.Cards[1].AlternativeState.LuaScript)
+	assert.Empty(t, deck.Cards[2].LuaScript)
+
+	// A single script
+	transform, err = NewTransform("card-scripts=" + filepath.Join(dir, "lightning bolt.lua"))
+	if assert.Nil(t, err) {
+		deck = transformTestDeck()
+		assert.Nil(t, transform(deck))
+		assert.Equal(t, "-- Bolt", deck.Cards[0].LuaScript)
+	}
+
+	// The characters which can't be used in a file name are ignored
+	assert.Equal(t, "fire ice", scriptKey("Fire // Ice"))
+	assert.Equal(t, "fire ice", scriptKey("fire ice"))
+}
+
+func TestLockTransforms(t *testing.T) {
+	deck := &Deck{Name: "Playmat"}
+	assert.Nil(t, lock(deck))
+	assert.True(t, deck.Locked)
+	assert.False(t, deck.NonInteractable)
+
+	deck = &Deck{Name: "Zone markers"}
+	assert.Nil(t, freeze(deck))
+	assert.True(t, deck.Locked)
+	assert.True(t, deck.NonInteractable)
+}
+
+func TestApplyTransforms(t *testing.T) {
+	var transforms []Transform
+	for _, spec := range []string{"strip-basic-lands", "max-copies=2", "remove=black lotus", "append-description=Proxy"} {
+		transform, err := NewTransform(spec)
+		assert.Nil(t, err)
+		transforms = append(transforms, transform)
+	}
+
+	decks, err := ApplyTransforms([]*Deck{transformTestDeck()}, transforms)
+	assert.Nil(t, err)
+	assert.Len(t, decks, 1)
+	assert.Equal(t, []CardInfo{
+		{
+			Name:        "Lightning Bolt",
+			Description: "Proxy",
+			Count:       2,
+			Metadata: CardMetadata{
+				Name: "Lightning Bolt",
+				Type: "Instant",
+			},
+		},
+	}, decks[0].Cards)
+
+	transform, err := NewTransform("append-description=Proxy")
+	assert.Nil(t, err)
+	decks, err = ApplyTransforms([]*Deck{transformTestDeck()}, []Transform{transform})
+	assert.Nil(t, err)
+	assert.Equal(t, "Artifact\n\nProxy", decks[0].Cards[1].Description)
+}
+
+func TestApplyTransformsEmptyDeck(t *testing.T) {
+	transform, err := NewTransform("strip-basic-lands")
+	assert.Nil(t, err)
+
+	decks, err := ApplyTransforms([]*Deck{
+		{
+			Name: "Lands",
+			Cards: []CardInfo{
+				{
+					Name:     "Island",
+					Count:    1,
+					Metadata: CardMetadata{Type: "Basic Land — Island"},
+				},
+			},
+		},
+		transformTestDeck(),
+	}, []Transform{transform})
+	assert.Nil(t, err)
+	assert.Len(t, decks, 1)
+	assert.Equal(t, "Test", decks[0].Name)
+	assert.Len(t, decks[0].Cards, 2)
+}
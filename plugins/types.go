@@ -1,6 +1,7 @@
 package plugins
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -19,6 +20,8 @@ const (
 	OptionTypeBool
 	// OptionTypeInt represents an integer option.
 	OptionTypeInt
+	// OptionTypeString represents a free text option.
+	OptionTypeString
 )
 
 // String representation of an OptionType.
@@ -30,6 +33,8 @@ func (ot OptionType) String() string {
 		return "bool"
 	case OptionTypeInt:
 		return "int"
+	case OptionTypeString:
+		return "string"
 	default:
 		return "unknown"
 	}
@@ -78,6 +83,8 @@ func (o Options) ValidateNormalize(options map[string]string) (map[string]interf
 				return output, fmt.Errorf("couldn't convert option %s value (%s) to int", key, value)
 			}
 			output[key] = parsed
+		case OptionTypeString:
+			output[key] = value
 		case OptionTypeEnum:
 			// Try to convert to int
 			if option.AllowedValues == nil {
@@ -112,7 +119,8 @@ const DefaultBackKey = "default"
 
 // FileHandler is a function used to parse a deck file for a specific file
 // extension.
-type FileHandler func(io.Reader, string, map[string]string) ([]*Deck, error)
+// The context can be used to cancel the requests sent while parsing the deck.
+type FileHandler func(context.Context, io.Reader, string, map[string]string) ([]*Deck, error)
 
 // DeckType contains a file handler and an example for a deck type.
 type DeckType struct {
@@ -128,7 +136,8 @@ type URLHandler struct {
 	// Regex used to recognize supported URLs.
 	Regex *regexp.Regexp
 	// Handler function used to parse the deck.
-	Handler func(string, map[string]string) ([]*Deck, error)
+	// The context can be used to cancel the requests sent by the handler.
+	Handler func(context.Context, string, map[string]string) ([]*Deck, error)
 }
 
 // Plugin represents a deckconverted plugin.
@@ -169,6 +178,11 @@ type TemplateInfo struct {
 	ImageURLCardIDMap map[string]int
 	// Templates is a map of template ID to template.
 	Templates map[int]*Template
+	// DeckBoxTextureURL is the URL of the texture of the deck box holding
+	// the deck (see Deck.Boxed), empty if the deck isn't boxed.
+	DeckBoxTextureURL string
+	// DeckBoxMeshURL is the URL of the model of the deck box.
+	DeckBoxMeshURL string
 }
 
 // GetAssociatedTemplate returns the template containing the image of the
@@ -193,20 +207,74 @@ func (t *TemplateInfo) GetAssociatedTemplate(cardID int) (*Template, int, error)
 // CardInfo contains the information about a card used to build a TTS deck.
 type CardInfo struct {
 	// Name of the card
-	Name string
+	Name string `json:"name"`
 	// Description of the card
-	Description string
+	Description string `json:"description,omitempty"`
 	// ImageURL is the URL of the card image
-	ImageURL string
+	ImageURL string `json:"imageURL"`
+	// FallbackImageURLs are lower-quality versions of the card image, from
+	// the largest to the smallest, downloaded instead of ImageURL when it
+	// exceeds the download budget of the templates
+	FallbackImageURLs []string `json:"fallbackImageURLs,omitempty"`
+	// ArtURL is the URL of the art of the card alone, shown by the figurines
+	// (see ContainerFigurines)
+	ArtURL string `json:"artURL,omitempty"`
 	// Count is the amount of this card in the current deck
-	Count int
+	Count int `json:"count"`
 	// AlternativeState is used for double-faced cards (transforms and melds
 	// in Magic). The back of the card will be represented as a second state
 	// of the card object.
-	AlternativeState *CardInfo
+	AlternativeState *CardInfo `json:"alternativeState,omitempty"`
 	// Oversized card
 	// Used for plane, scheme or meld results in MTG
-	Oversized bool
+	Oversized bool `json:"oversized,omitempty"`
+	// BackURL is the URL of the back of this card, replacing the back of
+	// the deck (not supported with templates)
+	BackURL string `json:"backURL,omitempty"`
+	// Sideways cards are displayed in landscape orientation in TTS
+	Sideways bool `json:"sideways,omitempty"`
+	// LuaScript is the script of the card object in TTS (e.g. to add buttons
+	// creating its tokens)
+	LuaScript string `json:"luaScript,omitempty"`
+	// Metadata contains the game information about the card which isn't
+	// used to build the TTS object
+	Metadata CardMetadata `json:"metadata"`
+}
+
+// CardMetadata contains information about a card which doesn't appear in
+// TTS, but that can be used to analyze a deck.
+type CardMetadata struct {
+	// Name is the name of the card, without any formatting
+	Name string `json:"name,omitempty"`
+	// Type is the type line of the card
+	Type string `json:"type,omitempty"`
+	// Cost is the converted cost of the card (mana value in Magic, level in
+	// Yu-Gi-Oh, etc.)
+	Cost float64 `json:"cost,omitempty"`
+	// Colors of the card
+	Colors []string `json:"colors,omitempty"`
+	// Price is the price of a single copy of the card, in USD (0 if unknown)
+	Price float64 `json:"price,omitempty"`
+	// Banned is set if the card is banned in tournaments
+	Banned bool `json:"banned,omitempty"`
+	// MaxCopies is the maximum number of copies of the card allowed in a
+	// deck by the ban list of the game (0 if the card isn't restricted)
+	MaxCopies int `json:"maxCopies,omitempty"`
+	// Legalities maps the formats of the game in which the card is banned or
+	// restricted (e.g. "modern") to "banned" or "restricted", for the games
+	// with a ban list for each format
+	Legalities map[string]string `json:"legalities,omitempty"`
+	// Set is the code of the set the card was printed in (if known)
+	Set string `json:"set,omitempty"`
+	// CollectorNumber is the number of the card in its set (if known)
+	CollectorNumber string `json:"collectorNumber,omitempty"`
+	// Text is the rules text of the card (e.g. the Oracle text in Magic),
+	// of all its faces and without any formatting, unlike the description
+	// which can be customized
+	Text string `json:"text,omitempty"`
+	// Category is the category of the card in the deck list (e.g. "Ramp"),
+	// if the deck list has category headers
+	Category string `json:"category,omitempty"`
 }
 
 // CardSize is the size format of a card
@@ -217,15 +285,256 @@ const (
 	CardSizeStandard CardSize = iota
 	// CardSizeSmall is the size of a Yu-Gi-Oh or Cardfight!! Vanguard card
 	CardSizeSmall
+	// CardSizeMini is the size of a mini card (e.g. the Arkham Horror
+	// investigator minis)
+	CardSizeMini
+	// CardSizeSquare is the size of a square card
+	CardSizeSquare
+	// CardSizeTarot is the size of a tarot card
+	CardSizeTarot
+	// CardSizeJumbo is the size of a jumbo card (3.5×5 inches)
+	CardSizeJumbo
 )
 
+// cardSizeNames are the names of the card sizes, indexed by size.
+var cardSizeNames = map[CardSize]string{
+	CardSizeStandard: "standard",
+	CardSizeSmall:    "small",
+	CardSizeMini:     "mini",
+	CardSizeSquare:   "square",
+	CardSizeTarot:    "tarot",
+	CardSizeJumbo:    "jumbo",
+}
+
+// cardSizeDimensions are the width and height of the card sizes in
+// millimeters, indexed by size.
+var cardSizeDimensions = map[CardSize][2]float64{
+	CardSizeStandard: {63.5, 88.9},
+	CardSizeSmall:    {59, 86},
+	CardSizeMini:     {41, 63},
+	CardSizeSquare:   {70, 70},
+	CardSizeTarot:    {70, 120},
+	CardSizeJumbo:    {88.9, 127},
+}
+
+// String representation of a CardSize.
+func (cs CardSize) String() string {
+	if name, found := cardSizeNames[cs]; found {
+		return name
+	}
+	return "unknown"
+}
+
+// Dimensions returns the width and height of a card of this size in
+// millimeters (the dimensions of a standard card if the size is unknown).
+func (cs CardSize) Dimensions() (width, height float64) {
+	dimensions, found := cardSizeDimensions[cs]
+	if !found {
+		dimensions = cardSizeDimensions[CardSizeStandard]
+	}
+	return dimensions[0], dimensions[1]
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (cs CardSize) MarshalText() ([]byte, error) {
+	if name, found := cardSizeNames[cs]; found {
+		return []byte(name), nil
+	}
+	return nil, fmt.Errorf("invalid card size: %d", cs)
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (cs *CardSize) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*cs = CardSizeStandard
+		return nil
+	}
+
+	for size, name := range cardSizeNames {
+		if name == string(text) {
+			*cs = size
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid card size: %s", text)
+}
+
+// Facing is the side a deck is placed on when spawned.
+type Facing int
+
+const (
+	// FacingDefault places the decks face down and the single cards face up.
+	FacingDefault Facing = iota
+	// FacingUp places the deck face up, e.g. for the cards starting the game
+	// on the table.
+	FacingUp
+	// FacingDown places the deck face down, even if it contains a single
+	// card, e.g. for the cards starting the game hidden on the table.
+	FacingDown
+)
+
+// String representation of a Facing.
+func (f Facing) String() string {
+	switch f {
+	case FacingDefault:
+		return "default"
+	case FacingUp:
+		return "up"
+	case FacingDown:
+		return "down"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (f Facing) MarshalText() ([]byte, error) {
+	switch f {
+	case FacingDefault, FacingUp, FacingDown:
+		return []byte(f.String()), nil
+	default:
+		return nil, fmt.Errorf("invalid facing: %d", f)
+	}
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (f *Facing) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "", "default":
+		*f = FacingDefault
+	case "up":
+		*f = FacingUp
+	case "down":
+		*f = FacingDown
+	default:
+		return fmt.Errorf("invalid facing: %s", text)
+	}
+
+	return nil
+}
+
+// Container is the kind of object holding the cards of a deck.
+type Container int
+
+const (
+	// ContainerDeck stacks the cards in a deck.
+	ContainerDeck Container = iota
+	// ContainerStates attaches each card as a state of a holder card, as
+	// expected by some scripted mods (e.g. for the sideboards).
+	ContainerStates
+	// ContainerBag puts the cards in a bag.
+	ContainerBag
+	// ContainerTiles lays out each card as a separate custom tile, which
+	// can't be shuffled into a deck (e.g. dungeons or oversized battlefield
+	// cards).
+	ContainerTiles
+	// ContainerBoards lays out each card as a separate custom board (e.g.
+	// playmats or game boards).
+	ContainerBoards
+	// ContainerFigurines lays out each card as a separate custom figurine
+	// (a standee showing the art of the card, or the card itself if its art
+	// isn't known), e.g. for the creature tokens.
+	ContainerFigurines
+)
+
+// String representation of a Container.
+func (c Container) String() string {
+	switch c {
+	case ContainerDeck:
+		return "deck"
+	case ContainerStates:
+		return "states"
+	case ContainerBag:
+		return "bag"
+	case ContainerTiles:
+		return "tiles"
+	case ContainerBoards:
+		return "boards"
+	case ContainerFigurines:
+		return "figurines"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.
+func (c Container) MarshalText() ([]byte, error) {
+	switch c {
+	case ContainerDeck, ContainerStates, ContainerBag, ContainerTiles, ContainerBoards, ContainerFigurines:
+		return []byte(c.String()), nil
+	default:
+		return nil, fmt.Errorf("invalid container: %d", c)
+	}
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.
+func (c *Container) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "", "deck":
+		*c = ContainerDeck
+	case "states":
+		*c = ContainerStates
+	case "bag":
+		*c = ContainerBag
+	case "tiles":
+		*c = ContainerTiles
+	case "boards":
+		*c = ContainerBoards
+	case "figurines":
+		*c = ContainerFigurines
+	default:
+		return fmt.Errorf("invalid container: %s", text)
+	}
+
+	return nil
+}
+
 // Deck contains the information about a deck used to build it in TTS.
+// See EncodeDecks for its JSON representation.
 type Deck struct {
-	Name         string
-	Cards        []CardInfo
-	BackURL      string
-	TemplateInfo *TemplateInfo
-	CardSize     CardSize
-	Rounded      bool
-	ThumbnailURL string
+	Name    string     `json:"name"`
+	Cards   []CardInfo `json:"cards"`
+	BackURL string     `json:"backURL,omitempty"`
+	// TemplateInfo is only set when generating the TTS templates, and isn't
+	// serialized.
+	TemplateInfo *TemplateInfo `json:"-"`
+	CardSize     CardSize      `json:"cardSize"`
+	Rounded      bool          `json:"rounded,omitempty"`
+	ThumbnailURL string        `json:"thumbnailURL,omitempty"`
+	Facing       Facing        `json:"facing,omitempty"`
+	// SourceURL is the URL the deck was parsed from, if any.
+	SourceURL string `json:"sourceURL,omitempty"`
+	// Author of the deck, if provided by the website.
+	Author string `json:"author,omitempty"`
+	// Mystery hides the faces of the cards, which are only shown in their
+	// second state (for hidden-information formats and puzzle decks).
+	Mystery bool `json:"mystery,omitempty"`
+	// MysteryFaceURL is the image shown instead of the faces of the cards
+	// of a mystery deck (BackURL if empty).
+	MysteryFaceURL string `json:"mysteryFaceURL,omitempty"`
+	// Locked freezes the object generated for the deck in place, for the
+	// accessories which need to stay put on the table (e.g. playmats,
+	// reference cards or zone markers).
+	Locked bool `json:"locked,omitempty"`
+	// NonInteractable prevents the players from picking up or selecting the
+	// object generated for the deck (only the scripts can interact with it).
+	NonInteractable bool `json:"nonInteractable,omitempty"`
+	// Counters are the names of the counters used by the cards (e.g. "+1/+1"
+	// or "loyalty"), for which a counter is placed next to the deck.
+	Counters []string `json:"counters,omitempty"`
+	// LifeTotal is the starting total of a scripted life counter placed next
+	// to the deck (e.g. 20), or 0 for none.
+	LifeTotal int `json:"lifeTotal,omitempty"`
+	// Container is the kind of object holding the cards of the deck in TTS
+	// (a deck by default).
+	Container Container `json:"container,omitempty"`
+	// Accessories are the objects placed next to the deck (e.g. dice).
+	Accessories []Accessory `json:"accessories,omitempty"`
+	// FeaturedArtURL is the URL of the art representing the deck (e.g. the
+	// art of its commander), shown on its deck box.
+	FeaturedArtURL string `json:"featuredArtURL,omitempty"`
+	// Boxed puts the deck in a deck box showing its name and its featured
+	// art (only when generating the templates, see TemplateInfo).
+	Boxed bool `json:"boxed,omitempty"`
 }
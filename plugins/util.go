@@ -5,6 +5,27 @@ import (
 	"runtime"
 	"strings"
 	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// typographyReplacer replaces the typographic characters frequently found in
+// the deck lists copied from websites with their ASCII equivalent.
+var typographyReplacer = strings.NewReplacer(
+	// Apostrophes
+	"\u2018", "'",
+	"\u2019", "'",
+	"\u02BC", "'",
+	"\u2032", "'",
+	// Quotation marks
+	"\u201C", "\"",
+	"\u201D", "\"",
+	// En dash, em dash and minus sign
+	"\u2013", "-",
+	"\u2014", "-",
+	"\u2212", "-",
+	// Non-breaking space
+	"\u00A0", " ",
 )
 
 // IndexOf returns the index of a string in a string slice, or -1 if not found.
@@ -45,6 +66,14 @@ func CapitalizeStrings(s []string) []string {
 	return new
 }
 
+// NormalizeName normalizes the typography of a card or deck name: the
+// accented characters are composed (NFC) and the curly apostrophes and
+// quotation marks, dashes and non-breaking spaces are replaced with their
+// ASCII equivalent.
+func NormalizeName(name string) string {
+	return typographyReplacer.Replace(norm.NFC.String(name))
+}
+
 // CheckInvalidFolderName checks whether the given path can be safely created
 // on all platforms.
 // Since the Tabletop Simulator save files can be shared with Steam Cloud on
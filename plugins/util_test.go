@@ -44,3 +44,12 @@ func TestCheckInvalidFolderName(t *testing.T) {
 		assert.Equal(t, true, CheckInvalidFolderName("/home/test/tts: folder"))
 	}
 }
+
+func TestNormalizeName(t *testing.T) {
+	assert.Equal(t, "Lim-Dûl's Vault", NormalizeName("Lim-Dûl’s Vault"))
+	// Decomposed accent
+	assert.Equal(t, "J\u00F6tun Grunt", NormalizeName("Jo\u0308tun Grunt"))
+	assert.Equal(t, "Borrowing 100,000 Arrows", NormalizeName("Borrowing 100,000 Arrows"))
+	assert.Equal(t, "\"Ach! Hans, Run!\"", NormalizeName("“Ach! Hans, Run!”"))
+	assert.Equal(t, "Mono Red - Aggro", NormalizeName("Mono Red — Aggro"))
+}
@@ -0,0 +1,146 @@
+package plugins
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RuleViolation is a deck construction rule of a game broken by a deck.
+type RuleViolation struct {
+	// RuleID identifies the rule (e.g. "mtg.max-copies").
+	RuleID string `json:"ruleID"`
+	// Deck is the name of the deck breaking the rule.
+	Deck string `json:"deck"`
+	// Card is the name of the card breaking the rule, if the rule applies
+	// to a single card.
+	Card string `json:"card,omitempty"`
+	// Message describes the violation.
+	Message string `json:"message"`
+}
+
+// String representation of a RuleViolation.
+func (v RuleViolation) String() string {
+	return fmt.Sprintf("%s: %s (%s)", v.Deck, v.Message, v.RuleID)
+}
+
+// Validator can be implemented by a plugin to check that decks follow the
+// construction rules of its game (deck size, copy limits, banned cards).
+type Validator interface {
+	// ValidateDecks checks the decks parsed from a single target by the
+	// plugin, and returns the rules they break. options are the plugin
+	// options the decks were parsed with (e.g. the format of the deck).
+	ValidateDecks(decks []*Deck, options map[string]string) []RuleViolation
+}
+
+// CountCards returns the total number of cards in a deck.
+func CountCards(deck *Deck) int {
+	count := 0
+
+	for _, card := range deck.Cards {
+		count += card.Count
+	}
+
+	return count
+}
+
+// CheckDeckSize reports a violation of ruleID if deck has less than min or
+// more than max cards. A max of 0 or less means that there is no maximum.
+func CheckDeckSize(ruleID string, deck *Deck, min, max int) []RuleViolation {
+	count := CountCards(deck)
+
+	switch {
+	case min == max && count != min:
+		return []RuleViolation{{
+			RuleID:  ruleID,
+			Deck:    deck.Name,
+			Message: fmt.Sprintf("the deck contains %d card(s) instead of %d", count, min),
+		}}
+	case count < min:
+		return []RuleViolation{{
+			RuleID:  ruleID,
+			Deck:    deck.Name,
+			Message: fmt.Sprintf("the deck contains %d card(s), less than the minimum of %d", count, min),
+		}}
+	case max > 0 && count > max:
+		return []RuleViolation{{
+			RuleID:  ruleID,
+			Deck:    deck.Name,
+			Message: fmt.Sprintf("the deck contains %d card(s), more than the maximum of %d", count, max),
+		}}
+	}
+
+	return nil
+}
+
+// CheckCopies reports a violation of ruleID for each card with more copies
+// than allowed in decks combined. maxCopies returns the number of copies
+// allowed for a card, or a negative value if any number of copies is
+// allowed. Cards are identified by their name, without any formatting.
+func CheckCopies(ruleID string, decks []*Deck, maxCopies func(CardInfo) int) []RuleViolation {
+	var (
+		names  []string
+		counts = make(map[string]int)
+		first  = make(map[string]*Deck)
+		cards  = make(map[string]CardInfo)
+	)
+
+	for _, deck := range decks {
+		for _, card := range deck.Cards {
+			name := cardName(card)
+			if _, found := counts[name]; !found {
+				names = append(names, name)
+				first[name] = deck
+				cards[name] = card
+			}
+			counts[name] += card.Count
+		}
+	}
+
+	var violations []RuleViolation
+
+	for _, name := range names {
+		max := maxCopies(cards[name])
+		if max < 0 || counts[name] <= max {
+			continue
+		}
+
+		violations = append(violations, RuleViolation{
+			RuleID:  ruleID,
+			Deck:    first[name].Name,
+			Card:    name,
+			Message: fmt.Sprintf("%d copies of %s, more than the maximum of %d", counts[name], name, max),
+		})
+	}
+
+	return violations
+}
+
+// CheckBanned reports a violation of ruleID for each card of decks marked
+// as banned in its metadata.
+func CheckBanned(ruleID string, decks []*Deck) []RuleViolation {
+	var violations []RuleViolation
+
+	for _, deck := range decks {
+		for _, card := range deck.Cards {
+			if !card.Metadata.Banned {
+				continue
+			}
+
+			name := cardName(card)
+			violations = append(violations, RuleViolation{
+				RuleID:  ruleID,
+				Deck:    deck.Name,
+				Card:    name,
+				Message: fmt.Sprintf("%s is banned", name),
+			})
+		}
+	}
+
+	return violations
+}
+
+// IsDeckSection returns true if deck is the section of a deck with the given
+// suffix (e.g. "Sideboard" for the "Name - Sideboard" deck).
+func IsDeckSection(deck *Deck, section string) bool {
+	return strings.HasSuffix(deck.Name, " - "+section)
+}
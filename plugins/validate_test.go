@@ -0,0 +1,63 @@
+package plugins
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckDeckSize(t *testing.T) {
+	deck := transformTestDeck()
+
+	assert.Empty(t, CheckDeckSize("test.deck-size", deck, 15, 15))
+	assert.Empty(t, CheckDeckSize("test.deck-size", deck, 10, 0))
+
+	violations := CheckDeckSize("test.deck-size", deck, 20, 0)
+	assert.Len(t, violations, 1)
+	assert.Equal(t, "test.deck-size", violations[0].RuleID)
+	assert.Equal(t, "Test", violations[0].Deck)
+
+	assert.Len(t, CheckDeckSize("test.deck-size", deck, 0, 10), 1)
+	assert.Len(t, CheckDeckSize("test.deck-size", deck, 60, 60), 1)
+}
+
+func TestCheckCopies(t *testing.T) {
+	side := &Deck{
+		Name: "Test - Sideboard",
+		Cards: []CardInfo{
+			{
+				Name:  "Black Lotus",
+				Count: 1,
+				Metadata: CardMetadata{
+					Name: "Black Lotus",
+				},
+			},
+		},
+	}
+
+	violations := CheckCopies("test.max-copies", []*Deck{transformTestDeck(), side}, func(card CardInfo) int {
+		if card.Name == "Mountain" {
+			return -1
+		}
+		return 1
+	})
+	assert.Len(t, violations, 2)
+	assert.Equal(t, "Lightning Bolt", violations[0].Card)
+	assert.Equal(t, "Black Lotus", violations[1].Card)
+	assert.Equal(t, "Test", violations[1].Deck)
+}
+
+func TestCheckBanned(t *testing.T) {
+	deck := transformTestDeck()
+	assert.Empty(t, CheckBanned("test.banned", []*Deck{deck}))
+
+	deck.Cards[1].Metadata.Banned = true
+	violations := CheckBanned("test.banned", []*Deck{deck})
+	assert.Len(t, violations, 1)
+	assert.Equal(t, "Black Lotus", violations[0].Card)
+}
+
+func TestIsDeckSection(t *testing.T) {
+	assert.True(t, IsDeckSection(&Deck{Name: "Test - Sideboard"}, "Sideboard"))
+	assert.False(t, IsDeckSection(&Deck{Name: "Sideboard"}, "Sideboard"))
+}
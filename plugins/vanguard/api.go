@@ -1,14 +1,21 @@
 package vanguard
 
 import (
+	"context"
 	"time"
 
+	"github.com/jeandeaual/tts-deckconverter/plugins"
 	"github.com/jeandeaual/tts-deckconverter/plugins/vanguard/cardfightwiki"
 )
 
-var rateLimiter = time.NewTicker(100 * time.Millisecond)
+var rateLimiter = plugins.NewRateLimiter(VanguardPlugin.PluginID(), 100*time.Millisecond)
 
-func getCard(name string, preferPremium bool) (cardfightwiki.Card, error) {
-	<-rateLimiter.C
-	return cardfightwiki.GetCard(name, preferPremium)
+func getCard(ctx context.Context, name string, preferPremium bool) (cardfightwiki.Card, error) {
+	if err := rateLimiter.Wait(ctx); err != nil {
+		return cardfightwiki.Card{}, err
+	}
+	start := time.Now()
+	card, err := cardfightwiki.GetCard(ctx, name, preferPremium)
+	plugins.RecordAPICall(VanguardPlugin.PluginID(), start, err)
+	return card, err
 }
@@ -1,6 +1,7 @@
 package cardfightwiki
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"strconv"
@@ -11,6 +12,7 @@ import (
 	"golang.org/x/net/html"
 
 	"github.com/jeandeaual/tts-deckconverter/log"
+	"github.com/jeandeaual/tts-deckconverter/plugins"
 )
 
 const (
@@ -81,7 +83,7 @@ func getLinkFromTag(link *html.Node, linkName string, searchURL string, linkNumb
 	return htmlquery.InnerText(hrefTag), nil
 }
 
-func search(cardName string, preferPremium bool) (string, error) {
+func search(ctx context.Context, cardName string, preferPremium bool) (string, error) {
 	parsedURL, err := url.Parse(wikiSearchURL)
 	if err != nil {
 		return "", fmt.Errorf("couldn't parse URL %s: %w", wikiSearchURL, err)
@@ -94,7 +96,7 @@ func search(cardName string, preferPremium bool) (string, error) {
 
 	log.Infof("Searching for card %s with %s", cardName, searchURL)
 
-	searchResult, err := htmlquery.LoadURL(searchURL)
+	searchResult, err := plugins.LoadURL(ctx, searchURL)
 	if err != nil {
 		return "", fmt.Errorf("couldn't query %s: %w", searchURL, err)
 	}
@@ -270,15 +272,15 @@ func getCardNames(cardPage *html.Node, cardPageURL string, card *Card) error {
 }
 
 // GetCard retrieves a card's information from https://cardfight.fandom.com/
-func GetCard(cardName string, preferPremium bool) (Card, error) {
+func GetCard(ctx context.Context, cardName string, preferPremium bool) (Card, error) {
 	var card Card
 
-	cardPageURL, err := search(cardName, preferPremium)
+	cardPageURL, err := search(ctx, cardName, preferPremium)
 	if err != nil {
 		return card, err
 	}
 
-	cardPage, err := htmlquery.LoadURL(cardPageURL)
+	cardPage, err := plugins.LoadURL(ctx, cardPageURL)
 	if err != nil {
 		return card, fmt.Errorf("couldn't query %s: %w", cardPageURL, err)
 	}
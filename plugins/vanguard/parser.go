@@ -2,6 +2,7 @@ package vanguard
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"regexp"
@@ -73,7 +74,7 @@ func (c *CardNames) String() string {
 	return sb.String()
 }
 
-func cardNamesToDeck(cards *CardNames, name string, options map[string]interface{}) (*plugins.Deck, *plugins.Deck, *plugins.Deck, error) {
+func cardNamesToDeck(ctx context.Context, cards *CardNames, name string, options map[string]interface{}) (*plugins.Deck, *plugins.Deck, *plugins.Deck, error) {
 	deck := &plugins.Deck{
 		Name:     name,
 		BackURL:  VanguardPlugin.AvailableBacks()[plugins.DefaultBackKey].URL,
@@ -100,12 +101,17 @@ func cardNamesToDeck(cards *CardNames, name string, options map[string]interface
 		preferPremium = option.(bool)
 	}
 
-	for _, cardName := range cards.Names {
+	for index, cardName := range cards.Names {
+		// Stop querying the cards if the conversion has been cancelled
+		if err := ctx.Err(); err != nil {
+			return nil, nil, nil, err
+		}
+
 		count := cards.Count(cardName)
 
 		log.Debugf("Querying card %s (prefer premium: %v)", cardName, preferPremium)
 
-		card, err := getCard(cardName, preferPremium)
+		card, err := getCard(ctx, cardName, preferPremium)
 		if err != nil {
 			log.Errorw(
 				"Cardfight!! Vanguard Wiki parsing error",
@@ -117,9 +123,23 @@ func cardNamesToDeck(cards *CardNames, name string, options map[string]interface
 
 		log.Debugf("Found card: %v", card)
 
+		plugins.ReportCardResolved(ctx, deck.Name, card.EnglishName, index+1, len(cards.Names))
+
 		cardInfo := plugins.CardInfo{
 			Description: buildCardDescription(card),
 			Count:       count,
+			Metadata: plugins.CardMetadata{
+				Name: card.EnglishName,
+				Cost: float64(card.Grade),
+			},
+		}
+		if card.Type != nil {
+			cardInfo.Metadata.Type = *card.Type
+		}
+		if card.Clan != nil {
+			cardInfo.Metadata.Colors = []string{*card.Clan}
+		} else if card.Nation != nil {
+			cardInfo.Metadata.Colors = []string{*card.Nation}
 		}
 		if cardLanguage == "en" {
 			cardInfo.Name = card.EnglishName
@@ -168,7 +188,7 @@ func cardNamesToDeck(cards *CardNames, name string, options map[string]interface
 	return deck, gdeck, tokens, nil
 }
 
-func fromDeckFile(file io.Reader, name string, options map[string]string) ([]*plugins.Deck, error) {
+func fromDeckFile(ctx context.Context, file io.Reader, name string, options map[string]string) ([]*plugins.Deck, error) {
 	// Check the options
 	validatedOptions, err := VanguardPlugin.AvailableOptions().ValidateNormalize(options)
 	if err != nil {
@@ -183,7 +203,7 @@ func fromDeckFile(file io.Reader, name string, options map[string]string) ([]*pl
 	var decks []*plugins.Deck
 
 	if main != nil {
-		deck, gdeck, tokens, err := cardNamesToDeck(main, name, validatedOptions)
+		deck, gdeck, tokens, err := cardNamesToDeck(ctx, main, name, validatedOptions)
 		if err != nil {
 			return nil, err
 		}
@@ -236,7 +256,12 @@ func parseDeckFile(file io.Reader) (*CardNames, error) {
 				log.Errorf("Error when parsing count: %s", err)
 				continue
 			}
-			name := strings.TrimSpace(matches[nameIdx])
+			name := plugins.NormalizeName(strings.TrimSpace(matches[nameIdx]))
+			name, keep := plugins.ResolveCardName(name)
+			if !keep {
+				log.Infof("Ignoring %s", name)
+				break
+			}
 
 			log.Debugw(
 				"Found card",
@@ -288,14 +313,14 @@ func init() {
 	cardCountXPath = xpath.MustCompile(`/span[contains(@class,'num')]`)
 }
 
-func handleCFVanguardLink(baseURL string, options map[string]string) ([]*plugins.Deck, error) {
+func handleCFVanguardLink(ctx context.Context, baseURL string, options map[string]string) ([]*plugins.Deck, error) {
 	// Set the card language to "ja" if not set by the user
 	if _, found := options["lang"]; !found {
 		options["lang"] = "ja"
 	}
 
 	log.Infof("Checking %s", baseURL)
-	doc, err := htmlquery.LoadURL(baseURL)
+	doc, err := plugins.LoadURL(ctx, baseURL)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't query %s: %w", baseURL, err)
 	}
@@ -319,7 +344,7 @@ func handleCFVanguardLink(baseURL string, options map[string]string) ([]*plugins
 		// Found a new deck
 		if sb.Len() > 0 {
 			var parsedDecks []*plugins.Deck
-			parsedDecks, err = fromDeckFile(strings.NewReader(sb.String()), currentDeckName, options)
+			parsedDecks, err = fromDeckFile(ctx, strings.NewReader(sb.String()), currentDeckName, options)
 			if err != nil {
 				return err
 			}
@@ -376,7 +401,7 @@ func handleCFVanguardLink(baseURL string, options map[string]string) ([]*plugins
 		}
 	}
 
-	parsedDecks, err := fromDeckFile(strings.NewReader(sb.String()), currentDeckName, options)
+	parsedDecks, err := fromDeckFile(ctx, strings.NewReader(sb.String()), currentDeckName, options)
 	if err != nil {
 		return decks, err
 	}
@@ -385,14 +410,14 @@ func handleCFVanguardLink(baseURL string, options map[string]string) ([]*plugins
 	return decks, nil
 }
 
-func handleENCFVanguardLink(baseURL string, options map[string]string) ([]*plugins.Deck, error) {
+func handleENCFVanguardLink(ctx context.Context, baseURL string, options map[string]string) ([]*plugins.Deck, error) {
 	// Set the card language to "en" if not set by the user
 	if _, found := options["lang"]; !found {
 		options["lang"] = "en"
 	}
 
 	log.Infof("Checking %s", baseURL)
-	doc, err := htmlquery.LoadURL(baseURL)
+	doc, err := plugins.LoadURL(ctx, baseURL)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't query %s: %w", baseURL, err)
 	}
@@ -416,7 +441,7 @@ func handleENCFVanguardLink(baseURL string, options map[string]string) ([]*plugi
 		// Found a new deck
 		if sb.Len() > 0 {
 			var parsedDecks []*plugins.Deck
-			parsedDecks, err = fromDeckFile(strings.NewReader(sb.String()), currentDeckName, options)
+			parsedDecks, err = fromDeckFile(ctx, strings.NewReader(sb.String()), currentDeckName, options)
 			if err != nil {
 				return err
 			}
@@ -484,7 +509,7 @@ func handleENCFVanguardLink(baseURL string, options map[string]string) ([]*plugi
 		}
 	}
 
-	parsedDecks, err := fromDeckFile(strings.NewReader(sb.String()), currentDeckName, options)
+	parsedDecks, err := fromDeckFile(ctx, strings.NewReader(sb.String()), currentDeckName, options)
 	if err != nil {
 		return decks, err
 	}
@@ -507,12 +532,12 @@ func init() {
 	amountRegexp = regexp.MustCompile(`(\d)\s*\+\s*(\d)`)
 }
 
-func handleCFVWikiLink(baseURL string, options map[string]string) ([]*plugins.Deck, error) {
+func handleCFVWikiLink(ctx context.Context, baseURL string, options map[string]string) ([]*plugins.Deck, error) {
 	// Set the vanguard first option to false, since we don't know where the first vanguard is
 	options["vanguard-first"] = "false"
 
 	log.Infof("Checking %s", baseURL)
-	doc, err := htmlquery.LoadURL(baseURL)
+	doc, err := plugins.LoadURL(ctx, baseURL)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't query %s: %w", baseURL, err)
 	}
@@ -606,5 +631,5 @@ func handleCFVWikiLink(baseURL string, options map[string]string) ([]*plugins.De
 		sb.WriteString("\n")
 	}
 
-	return fromDeckFile(strings.NewReader(sb.String()), deckName, options)
+	return fromDeckFile(ctx, strings.NewReader(sb.String()), deckName, options)
 }
@@ -1,6 +1,8 @@
 package vanguard
 
 import (
+	"context"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -8,6 +10,8 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/jeandeaual/tts-deckconverter/log"
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+	"github.com/jeandeaual/tts-deckconverter/plugins/cassette"
 )
 
 func init() {
@@ -69,3 +73,25 @@ Grade 0:
 	assert.Equal(t, expected, main)
 	assert.Nil(t, err)
 }
+
+func TestHandleCFVWikiLink(t *testing.T) {
+	recorder := cassette.NewForTest(t, filepath.Join("testdata", "wiki.json"))
+
+	defer plugins.SetHTTPClient(nil)
+	plugins.SetHTTPTransport(recorder)
+
+	decks, err := handleCFVWikiLink(context.Background(), "https://cardfight.fandom.com/wiki/Trial_Deck_1:_Blaster_Blade", map[string]string{})
+	assert.Nil(t, err)
+	assert.Nil(t, recorder.Save())
+
+	// Only the structure of the decks is checked, so that the cassette can
+	// be recorded again when the page is updated
+	if assert.NotEmpty(t, decks) {
+		assert.NotEmpty(t, decks[0].Name)
+		assert.NotEmpty(t, decks[0].Cards)
+		for _, card := range decks[0].Cards {
+			assert.NotEmpty(t, card.Name)
+			assert.NotEmpty(t, card.ImageURL, card.Name)
+		}
+	}
+}
@@ -21,6 +21,7 @@ func (p vanguardPlugin) PluginName() string {
 
 func (p vanguardPlugin) AvailableOptions() plugins.Options {
 	return plugins.Options{
+		plugins.AccessoriesOption: plugins.NewAccessoriesOption(),
 		"lang": plugins.Option{
 			Type:        plugins.OptionTypeEnum,
 			Description: "Language of the cards",
@@ -92,6 +93,51 @@ func (p vanguardPlugin) AvailableBacks() map[string]plugins.Back {
 	}
 }
 
+// Accessories returns a d6 keeping track of the damage.
+func (p vanguardPlugin) Accessories() []plugins.Accessory {
+	return []plugins.Accessory{
+		{Name: "Damage", Die: plugins.D6},
+	}
+}
+
+// TablePresets returns the table of a fight, with damage counters.
+func (p vanguardPlugin) TablePresets() map[string]plugins.TablePreset {
+	return map[string]plugins.TablePreset{
+		"fight": {
+			Description: "two players with damage counters",
+			Players:     2,
+			Counters:    []string{"damage"},
+		},
+	}
+}
+
+// ValidateDecks checks the deck construction rules: a main deck of exactly
+// 50 cards, a G deck of up to 16 cards, and up to 4 copies of each card.
+func (p vanguardPlugin) ValidateDecks(decks []*plugins.Deck, _ map[string]string) []plugins.RuleViolation {
+	var (
+		violations []plugins.RuleViolation
+		counted    []*plugins.Deck
+	)
+
+	for _, deck := range decks {
+		switch {
+		case plugins.IsDeckSection(deck, "Tokens"):
+			continue
+		case plugins.IsDeckSection(deck, "G deck"):
+			violations = append(violations, plugins.CheckDeckSize("cfv.g-deck-size", deck, 0, 16)...)
+		default:
+			violations = append(violations, plugins.CheckDeckSize("cfv.deck-size", deck, 50, 50)...)
+		}
+		counted = append(counted, deck)
+	}
+
+	violations = append(violations, plugins.CheckCopies("cfv.max-copies", counted, func(card plugins.CardInfo) int {
+		return 4
+	})...)
+
+	return violations
+}
+
 // VanguardPlugin is the exported plugin for this package
 var VanguardPlugin = vanguardPlugin{
 	id:   "cfv",
@@ -1,20 +1,32 @@
 package ygo
 
 import (
+	"context"
 	"time"
 
+	"github.com/jeandeaual/tts-deckconverter/plugins"
 	"github.com/jeandeaual/tts-deckconverter/plugins/ygo/api"
 )
 
 // See https://db.ygoprodeck.com/api-guide/
-var rateLimiter = time.NewTicker(50 * time.Millisecond)
+var rateLimiter = plugins.NewRateLimiter(YGOPlugin.PluginID(), 50*time.Millisecond)
 
-func queryID(id int64, format api.Format) (api.Data, error) {
-	<-rateLimiter.C
-	return api.QueryID(id, format)
+func queryID(ctx context.Context, id int64, format api.Format) (api.Data, error) {
+	if err := rateLimiter.Wait(ctx); err != nil {
+		return api.Data{}, err
+	}
+	start := time.Now()
+	data, err := api.QueryID(id, format, api.WithHTTPClient(plugins.HTTPClient()), api.WithContext(ctx))
+	plugins.RecordAPICall(YGOPlugin.PluginID(), start, err)
+	return data, err
 }
 
-func queryName(name string, format api.Format) (api.Data, error) {
-	<-rateLimiter.C
-	return api.QueryName(name, format)
+func queryName(ctx context.Context, name string, format api.Format) (api.Data, error) {
+	if err := rateLimiter.Wait(ctx); err != nil {
+		return api.Data{}, err
+	}
+	start := time.Now()
+	data, err := api.QueryName(name, format, api.WithHTTPClient(plugins.HTTPClient()), api.WithContext(ctx))
+	plugins.RecordAPICall(YGOPlugin.PluginID(), start, err)
+	return data, err
 }
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -325,6 +326,7 @@ const (
 type clientOptions struct {
 	baseURL string
 	client  *http.Client
+	ctx     context.Context
 }
 
 // ClientOption configures the API client.
@@ -344,6 +346,13 @@ func WithHTTPClient(client *http.Client) ClientOption {
 	}
 }
 
+// WithContext returns an option which sets the context of the request.
+func WithContext(ctx context.Context) ClientOption {
+	return func(o *clientOptions) {
+		o.ctx = ctx
+	}
+}
+
 // QueryName sends a request to the YGOProDeck API to retrieve data about a card from its name.
 func QueryName(name string, format Format, options ...ClientOption) (data Data, err error) {
 	return query("name", name, format, options...)
@@ -361,6 +370,7 @@ func query(paramName string, paramValue string, format Format, options ...Client
 		client: &http.Client{
 			Timeout: defaultTimeout,
 		},
+		ctx: context.Background(),
 	}
 	for _, option := range options {
 		option(co)
@@ -381,7 +391,7 @@ func query(paramName string, paramValue string, format Format, options ...Client
 	targetURL := url.String()
 
 	// Build the request
-	req, err := http.NewRequest("GET", targetURL, nil)
+	req, err := http.NewRequestWithContext(co.ctx, "GET", targetURL, nil)
 	if err != nil {
 		return
 	}
@@ -2,6 +2,7 @@ package ygo
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -152,7 +153,7 @@ func (c *CardNames) String() string {
 	return sb.String()
 }
 
-func cardIDsToDeck(cards *CardIDs, deckName string, format api.Format) (*plugins.Deck, []plugins.CardInfo, error) {
+func cardIDsToDeck(ctx context.Context, cards *CardIDs, deckName string, format api.Format) (*plugins.Deck, []plugins.CardInfo, error) {
 	deck := &plugins.Deck{
 		Name:     deckName,
 		BackURL:  YGOPlugin.AvailableBacks()[plugins.DefaultBackKey].URL,
@@ -161,23 +162,26 @@ func cardIDsToDeck(cards *CardIDs, deckName string, format api.Format) (*plugins
 	}
 	var tokens []plugins.CardInfo
 
-	for _, id := range cards.IDs {
+	for index, id := range cards.IDs {
 		count := cards.Count(id)
 
 		log.Debugf("Querying card ID %d", id)
 
-		resp, err := queryID(id, format)
+		resp, err := queryID(ctx, id, format)
 		if err != nil {
 			return deck, tokens, fmt.Errorf("couldn't query card ID %d (format: %s): %w", id, format, err)
 		}
 
 		log.Debugf("API response: %+v", resp)
 
+		plugins.ReportCardResolved(ctx, deck.Name, resp.Name, index+1, len(cards.IDs))
+
 		if resp.Type == api.TypeToken {
 			if len(resp.Images) == 1 {
 				tokens = append(tokens, plugins.CardInfo{
 					Name:        resp.Name,
 					Description: buildDescription(resp),
+					Metadata:    buildMetadata(resp),
 					ImageURL:    resp.Images[0].URL,
 					Count:       count,
 				})
@@ -187,6 +191,7 @@ func cardIDsToDeck(cards *CardIDs, deckName string, format api.Format) (*plugins
 					tokens = append(tokens, plugins.CardInfo{
 						Name:        resp.Name,
 						Description: buildDescription(resp),
+						Metadata:    buildMetadata(resp),
 						ImageURL:    resp.Images[i%len(resp.Images)].URL,
 						Count:       1,
 					})
@@ -196,6 +201,7 @@ func cardIDsToDeck(cards *CardIDs, deckName string, format api.Format) (*plugins
 			deck.Cards = append(deck.Cards, plugins.CardInfo{
 				Name:        resp.Name,
 				Description: buildDescription(resp),
+				Metadata:    buildMetadata(resp),
 				ImageURL:    resp.Images[0].URL,
 				Count:       count,
 			})
@@ -207,7 +213,7 @@ func cardIDsToDeck(cards *CardIDs, deckName string, format api.Format) (*plugins
 	return deck, tokens, nil
 }
 
-func cardNamesToDeck(cards *CardNames, deckName string, format api.Format) (*plugins.Deck, []plugins.CardInfo, error) {
+func cardNamesToDeck(ctx context.Context, cards *CardNames, deckName string, format api.Format) (*plugins.Deck, []plugins.CardInfo, error) {
 	deck := &plugins.Deck{
 		Name:     deckName,
 		BackURL:  YGOPlugin.AvailableBacks()[plugins.DefaultBackKey].URL,
@@ -216,23 +222,26 @@ func cardNamesToDeck(cards *CardNames, deckName string, format api.Format) (*plu
 	}
 	var tokens []plugins.CardInfo
 
-	for _, name := range cards.Names {
+	for index, name := range cards.Names {
 		count := cards.Count(name)
 
 		log.Debugf("Querying card name %s", name)
 
-		resp, err := queryName(name, format)
+		resp, err := queryName(ctx, name, format)
 		if err != nil {
 			return deck, tokens, fmt.Errorf("couldn't query card %s (format: %s): %w", name, format, err)
 		}
 
 		log.Debugf("API response: %+v", resp)
 
+		plugins.ReportCardResolved(ctx, deck.Name, resp.Name, index+1, len(cards.Names))
+
 		if resp.Type == api.TypeToken {
 			if len(resp.Images) == 1 {
 				tokens = append(tokens, plugins.CardInfo{
 					Name:        resp.Name,
 					Description: buildDescription(resp),
+					Metadata:    buildMetadata(resp),
 					ImageURL:    resp.Images[0].URL,
 					Count:       count,
 				})
@@ -242,6 +251,7 @@ func cardNamesToDeck(cards *CardNames, deckName string, format api.Format) (*plu
 					tokens = append(tokens, plugins.CardInfo{
 						Name:        resp.Name,
 						Description: buildDescription(resp),
+						Metadata:    buildMetadata(resp),
 						ImageURL:    resp.Images[i%len(resp.Images)].URL,
 						Count:       1,
 					})
@@ -251,6 +261,7 @@ func cardNamesToDeck(cards *CardNames, deckName string, format api.Format) (*plu
 			deck.Cards = append(deck.Cards, plugins.CardInfo{
 				Name:        resp.Name,
 				Description: buildDescription(resp),
+				Metadata:    buildMetadata(resp),
 				ImageURL:    resp.Images[0].URL,
 				Count:       count,
 			})
@@ -423,7 +434,12 @@ func parseDeckFile(file io.Reader) (*CardNames, *CardNames, *CardNames, error) {
 				log.Errorf("Error when parsing count: %s", err)
 				continue
 			}
-			name := strings.TrimSpace(matches[nameIdx])
+			name := plugins.NormalizeName(strings.TrimSpace(matches[nameIdx]))
+			name, keep := plugins.ResolveCardName(name)
+			if !keep {
+				log.Infof("Ignoring %s", name)
+				break
+			}
 
 			log.Debugw(
 				"Found card",
@@ -485,7 +501,7 @@ func parseDeckFile(file io.Reader) (*CardNames, *CardNames, *CardNames, error) {
 	return main, extra, side, nil
 }
 
-func fromYDKFile(file io.Reader, name string, options map[string]string) ([]*plugins.Deck, error) {
+func fromYDKFile(ctx context.Context, file io.Reader, name string, options map[string]string) ([]*plugins.Deck, error) {
 	main, extra, side, err := parseYDKFile(file)
 	if err != nil {
 		return nil, err
@@ -502,7 +518,7 @@ func fromYDKFile(file io.Reader, name string, options map[string]string) ([]*plu
 	)
 
 	if main != nil {
-		mainDeck, mainTokens, err := cardIDsToDeck(main, name, duelFormat)
+		mainDeck, mainTokens, err := cardIDsToDeck(ctx, main, name, duelFormat)
 		if err != nil {
 			return nil, err
 		}
@@ -512,7 +528,7 @@ func fromYDKFile(file io.Reader, name string, options map[string]string) ([]*plu
 	}
 
 	if extra != nil {
-		extraDeck, extraTokens, err := cardIDsToDeck(extra, name+" - Extra", duelFormat)
+		extraDeck, extraTokens, err := cardIDsToDeck(ctx, extra, name+" - Extra", duelFormat)
 		if err != nil {
 			return nil, err
 		}
@@ -522,7 +538,7 @@ func fromYDKFile(file io.Reader, name string, options map[string]string) ([]*plu
 	}
 
 	if side != nil {
-		sideDeck, sideTokens, err := cardIDsToDeck(side, name+" - Side", duelFormat)
+		sideDeck, sideTokens, err := cardIDsToDeck(ctx, side, name+" - Side", duelFormat)
 		if err != nil {
 			return nil, err
 		}
@@ -544,7 +560,7 @@ func fromYDKFile(file io.Reader, name string, options map[string]string) ([]*plu
 	return decks, nil
 }
 
-func fromDeckFile(file io.Reader, name string, options map[string]string) ([]*plugins.Deck, error) {
+func fromDeckFile(ctx context.Context, file io.Reader, name string, options map[string]string) ([]*plugins.Deck, error) {
 	main, extra, side, err := parseDeckFile(file)
 	if err != nil {
 		return nil, err
@@ -561,7 +577,7 @@ func fromDeckFile(file io.Reader, name string, options map[string]string) ([]*pl
 	)
 
 	if main != nil {
-		mainDeck, mainTokens, err := cardNamesToDeck(main, name, duelFormat)
+		mainDeck, mainTokens, err := cardNamesToDeck(ctx, main, name, duelFormat)
 		if err != nil {
 			return nil, err
 		}
@@ -571,7 +587,7 @@ func fromDeckFile(file io.Reader, name string, options map[string]string) ([]*pl
 	}
 
 	if extra != nil {
-		extraDeck, extraTokens, err := cardNamesToDeck(extra, name+" - Extra", duelFormat)
+		extraDeck, extraTokens, err := cardNamesToDeck(ctx, extra, name+" - Extra", duelFormat)
 		if err != nil {
 			return nil, err
 		}
@@ -581,7 +597,7 @@ func fromDeckFile(file io.Reader, name string, options map[string]string) ([]*pl
 	}
 
 	if side != nil {
-		sideDeck, sideTokens, err := cardNamesToDeck(side, name+" - Side", duelFormat)
+		sideDeck, sideTokens, err := cardNamesToDeck(ctx, side, name+" - Side", duelFormat)
 		if err != nil {
 			return nil, err
 		}
@@ -603,7 +619,7 @@ func fromDeckFile(file io.Reader, name string, options map[string]string) ([]*pl
 	return decks, nil
 }
 
-func handleLinkWithYDKFile(url string, doc *html.Node, titleXPath, fileXPath, baseURL string, options map[string]string) (decks []*plugins.Deck, err error) {
+func handleLinkWithYDKFile(ctx context.Context, url string, doc *html.Node, titleXPath, fileXPath, baseURL string, options map[string]string) (decks []*plugins.Deck, err error) {
 	log.Infof("Checking %s for YDK file link", url)
 
 	// Find the title
@@ -624,12 +640,12 @@ func handleLinkWithYDKFile(url string, doc *html.Node, titleXPath, fileXPath, ba
 	log.Infof("Found .ydk URL: %s", ydkURL)
 
 	// Build the request
-	req, err := http.NewRequest("GET", ydkURL, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", ydkURL, nil)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't create request for %s: %w", ydkURL, err)
 	}
 
-	client := &http.Client{}
+	client := plugins.HTTPClient()
 
 	// Send the request
 	resp, err := client.Do(req)
@@ -642,7 +658,7 @@ func handleLinkWithYDKFile(url string, doc *html.Node, titleXPath, fileXPath, ba
 		}
 	}()
 
-	return fromYDKFile(resp.Body, name, options)
+	return fromYDKFile(ctx, resp.Body, name, options)
 }
 
 var (
@@ -659,9 +675,9 @@ func init() {
 	tableRowsXPath = xpath.MustCompile(`//tr`)
 }
 
-func handleYGOWikiLink(baseURL string, options map[string]string) ([]*plugins.Deck, error) {
+func handleYGOWikiLink(ctx context.Context, baseURL string, options map[string]string) ([]*plugins.Deck, error) {
 	log.Infof("Checking %s", baseURL)
-	doc, err := htmlquery.LoadURL(baseURL)
+	doc, err := plugins.LoadURL(ctx, baseURL)
 	if err != nil {
 		return nil, fmt.Errorf("couldn't query %s: %w", baseURL, err)
 	}
@@ -745,5 +761,5 @@ func handleYGOWikiLink(baseURL string, options map[string]string) ([]*plugins.De
 		sb.WriteString("\n")
 	}
 
-	return fromDeckFile(strings.NewReader(sb.String()), deckName, options)
+	return fromDeckFile(ctx, strings.NewReader(sb.String()), deckName, options)
 }
@@ -1,6 +1,8 @@
 package ygo
 
 import (
+	"context"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -8,6 +10,8 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/jeandeaual/tts-deckconverter/log"
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+	"github.com/jeandeaual/tts-deckconverter/plugins/cassette"
 )
 
 func init() {
@@ -71,3 +75,37 @@ Extra:
 	assert.Nil(t, extra)
 	assert.Nil(t, err)
 }
+
+func TestFromYDKFile(t *testing.T) {
+	// ydk.json was written by hand, record it again with
+	// TTS_DECKCONVERTER_RECORD=1
+	recorder := cassette.NewForTest(t, filepath.Join("testdata", "ydk.json"))
+
+	defer plugins.SetHTTPClient(nil)
+	plugins.SetHTTPTransport(recorder)
+
+	decks, err := fromYDKFile(context.Background(), strings.NewReader(`#created by ...
+#main
+40640057
+40640057
+40640057
+5318639
+#extra
+1861629
+!side
+`), "Test", map[string]string{})
+	assert.Nil(t, err)
+	assert.Nil(t, recorder.Save())
+
+	assert.Len(t, decks, 2)
+	assert.Equal(t, "Test", decks[0].Name)
+	assert.Len(t, decks[0].Cards, 2)
+	assert.Equal(t, "Kuriboh", decks[0].Cards[0].Name)
+	assert.Equal(t, 3, decks[0].Cards[0].Count)
+	assert.Equal(t, "https://storage.googleapis.com/ygoprodeck.com/pics/40640057.jpg", decks[0].Cards[0].ImageURL)
+	assert.Equal(t, "Mystical Space Typhoon", decks[0].Cards[1].Name)
+	assert.Equal(t, "Test - Extra", decks[1].Name)
+	assert.Len(t, decks[1].Cards, 1)
+	assert.Equal(t, "Decode Talker", decks[1].Cards[0].Name)
+	assert.Equal(t, "Link Monster", decks[1].Cards[0].Metadata.Type)
+}
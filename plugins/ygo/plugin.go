@@ -1,6 +1,7 @@
 package ygo
 
 import (
+	"context"
 	"fmt"
 	"regexp"
 
@@ -41,8 +42,8 @@ func (p ygoPlugin) URLHandlers() []plugins.URLHandler {
 		{
 			BasePath: "https://ygoprodeck.com",
 			Regex:    regexp.MustCompile(`^https://ygoprodeck\.com/`),
-			Handler: func(url string, options map[string]string) ([]*plugins.Deck, error) {
-				doc, err := htmlquery.LoadURL(url)
+			Handler: func(ctx context.Context, url string, options map[string]string) ([]*plugins.Deck, error) {
+				doc, err := plugins.LoadURL(ctx, url)
 				if err != nil {
 					return nil, fmt.Errorf("couldn't query %s: %w", url, err)
 				}
@@ -58,7 +59,7 @@ func (p ygoPlugin) URLHandlers() []plugins.URLHandler {
 					}
 				}
 
-				return handleLinkWithYDKFile(
+				return handleLinkWithYDKFile(ctx,
 					url,
 					doc,
 					ygoproDeckTitleXPath,
@@ -71,13 +72,13 @@ func (p ygoPlugin) URLHandlers() []plugins.URLHandler {
 		{
 			BasePath: "https://yugiohtopdecks.com",
 			Regex:    regexp.MustCompile(`^https://yugiohtopdecks\.com/deck/`),
-			Handler: func(url string, options map[string]string) ([]*plugins.Deck, error) {
-				doc, err := htmlquery.LoadURL(url)
+			Handler: func(ctx context.Context, url string, options map[string]string) ([]*plugins.Deck, error) {
+				doc, err := plugins.LoadURL(ctx, url)
 				if err != nil {
 					return nil, fmt.Errorf("couldn't query %s: %w", url, err)
 				}
 
-				return handleLinkWithYDKFile(
+				return handleLinkWithYDKFile(ctx,
 					url,
 					doc,
 					yugiohTopDecksTitleXPath,
@@ -160,6 +161,52 @@ func (p ygoPlugin) AvailableBacks() map[string]plugins.Back {
 	}
 }
 
+// TablePresets returns the table of a duel, with 8000 life points.
+func (p ygoPlugin) TablePresets() map[string]plugins.TablePreset {
+	return map[string]plugins.TablePreset{
+		"duel": {
+			Description: "two players starting at 8000 life points",
+			Players:     2,
+			LifeTotal:   8000,
+		},
+	}
+}
+
+// ValidateDecks checks the deck construction rules: a main deck of 40 to 60
+// cards, extra and side decks of up to 15 cards, and up to 3 copies of each
+// card in the main, extra and side decks combined (less for the cards
+// restricted by the TCG ban list).
+func (p ygoPlugin) ValidateDecks(decks []*plugins.Deck, _ map[string]string) []plugins.RuleViolation {
+	var (
+		violations []plugins.RuleViolation
+		counted    []*plugins.Deck
+	)
+
+	for _, deck := range decks {
+		switch {
+		case plugins.IsDeckSection(deck, "Tokens"):
+			continue
+		case plugins.IsDeckSection(deck, "Extra"):
+			violations = append(violations, plugins.CheckDeckSize("ygo.extra-deck-size", deck, 0, 15)...)
+		case plugins.IsDeckSection(deck, "Side"):
+			violations = append(violations, plugins.CheckDeckSize("ygo.side-deck-size", deck, 0, 15)...)
+		default:
+			violations = append(violations, plugins.CheckDeckSize("ygo.deck-size", deck, 40, 60)...)
+		}
+		counted = append(counted, deck)
+	}
+
+	violations = append(violations, plugins.CheckBanned("ygo.banned", counted)...)
+	violations = append(violations, plugins.CheckCopies("ygo.max-copies", counted, func(card plugins.CardInfo) int {
+		if card.Metadata.MaxCopies > 0 {
+			return card.Metadata.MaxCopies
+		}
+		return 3
+	})...)
+
+	return violations
+}
+
 // YGOPlugin is the exported plugin for this module
 var YGOPlugin = ygoPlugin{
 	id:   "ygo",
@@ -4,6 +4,7 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/jeandeaual/tts-deckconverter/plugins"
 	"github.com/jeandeaual/tts-deckconverter/plugins/ygo/api"
 )
 
@@ -104,3 +105,34 @@ func buildDescription(apiResponse api.Data) string {
 
 	return sb.String()
 }
+
+func buildMetadata(apiResponse api.Data) plugins.CardMetadata {
+	metadata := plugins.CardMetadata{
+		Name: apiResponse.Name,
+		Type: string(apiResponse.Type),
+	}
+
+	if apiResponse.Level != nil {
+		metadata.Cost = float64(*apiResponse.Level)
+	}
+	if apiResponse.Attribute != nil {
+		metadata.Colors = []string{string(*apiResponse.Attribute)}
+	}
+	if len(apiResponse.Prices) > 0 {
+		if price, err := strconv.ParseFloat(apiResponse.Prices[0].TCGPlayerPrice, 64); err == nil {
+			metadata.Price = price
+		}
+	}
+	if apiResponse.BanListInfo != nil && apiResponse.BanListInfo.BanTCG != nil {
+		switch *apiResponse.BanListInfo.BanTCG {
+		case api.BanStatusBanned:
+			metadata.Banned = true
+		case api.BanStatusLimited:
+			metadata.MaxCopies = 1
+		case api.BanStatusSemiLimited:
+			metadata.MaxCopies = 2
+		}
+	}
+
+	return metadata
+}
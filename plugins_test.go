@@ -0,0 +1,134 @@
+package deckconverter
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+func TestRegisterURLHandler(t *testing.T) {
+	defaultHandlers := URLHandlers
+	defaultRegisteredHandlers := registeredURLHandlers
+	defer func() {
+		URLHandlers = defaultHandlers
+		registeredURLHandlers = defaultRegisteredHandlers
+	}()
+
+	handler := plugins.URLHandler{
+		BasePath: "https://decks.example.com",
+		Regex:    regexp.MustCompile(`^https://decks\.example\.com/`),
+		Handler: func(ctx context.Context, url string, options map[string]string) ([]*plugins.Deck, error) {
+			return []*plugins.Deck{{Name: url}}, nil
+		},
+	}
+
+	assert.NotNil(t, RegisterURLHandler("invalid", handler))
+	assert.NotNil(t, RegisterURLHandler("mtg", plugins.URLHandler{}))
+	assert.Nil(t, RegisterURLHandler("mtg", handler))
+
+	decks, err := Parse(context.Background(), "https://decks.example.com/1", "", nil)
+	assert.Nil(t, err)
+	assert.Len(t, decks, 1)
+	assert.Equal(t, "https://decks.example.com/1", decks[0].Name)
+	assert.Equal(t, "https://decks.example.com/1", decks[0].SourceURL)
+}
+
+func TestParseConcurrent(t *testing.T) {
+	defaultHandlers := URLHandlers
+	defaultRegisteredHandlers := registeredURLHandlers
+	defer func() {
+		URLHandlers = defaultHandlers
+		registeredURLHandlers = defaultRegisteredHandlers
+	}()
+
+	newHandler := func(host string) plugins.URLHandler {
+		return plugins.URLHandler{
+			BasePath: "https://" + host,
+			Regex:    regexp.MustCompile(`^https://` + regexp.QuoteMeta(host) + `/`),
+			Handler: func(ctx context.Context, url string, options map[string]string) ([]*plugins.Deck, error) {
+				return []*plugins.Deck{{Name: url}}, nil
+			},
+		}
+	}
+
+	assert.Nil(t, RegisterURLHandler("mtg", newHandler("concurrent.example.com")))
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+
+		go func(i int) {
+			defer wg.Done()
+
+			url := fmt.Sprintf("https://concurrent.example.com/%d", i)
+			decks, err := Parse(context.Background(), url, "", nil)
+			assert.Nil(t, err)
+			assert.Len(t, decks, 1)
+		}(i)
+
+		go func(i int) {
+			defer wg.Done()
+
+			assert.Nil(t, RegisterURLHandler("mtg", newHandler(fmt.Sprintf("concurrent%d.example.com", i))))
+			assert.NotEmpty(t, AvailablePlugins())
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+func TestParseSharedOptions(t *testing.T) {
+	defaultHandlers := URLHandlers
+	defaultRegisteredHandlers := registeredURLHandlers
+	defer func() {
+		URLHandlers = defaultHandlers
+		registeredURLHandlers = defaultRegisteredHandlers
+	}()
+
+	// Set a default option, like the Vanguard and Yu-Gi-Oh! handlers
+	assert.Nil(t, RegisterURLHandler("mtg", plugins.URLHandler{
+		BasePath: "https://options.example.com",
+		Regex:    regexp.MustCompile(`^https://options\.example\.com/`),
+		Handler: func(ctx context.Context, url string, options map[string]string) ([]*plugins.Deck, error) {
+			if _, found := options["lang"]; !found {
+				options["lang"] = "en"
+			}
+			return []*plugins.Deck{{Name: options["lang"]}}, nil
+		},
+	}))
+
+	decks, err := Parse(context.Background(), "https://options.example.com/1", "", nil)
+	assert.Nil(t, err)
+	if assert.Len(t, decks, 1) {
+		assert.Equal(t, "en", decks[0].Name)
+	}
+
+	options := map[string]string{"sort": "name"}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			url := fmt.Sprintf("https://options.example.com/%d", i)
+			decks, err := Parse(context.Background(), url, "", options)
+			assert.Nil(t, err)
+			assert.Len(t, decks, 1)
+		}(i)
+	}
+
+	wg.Wait()
+
+	// The options of the caller aren't changed
+	assert.Equal(t, map[string]string{"sort": "name"}, options)
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// JobStatus is the status of a conversion job.
+type JobStatus string
+
+const (
+	// JobStatusQueued is the status of a job waiting for a worker.
+	JobStatusQueued JobStatus = "queued"
+	// JobStatusRunning is the status of a job being converted.
+	JobStatusRunning JobStatus = "running"
+	// JobStatusDone is the status of a job successfully converted.
+	JobStatusDone JobStatus = "done"
+	// JobStatusFailed is the status of a job which couldn't be converted.
+	JobStatusFailed JobStatus = "failed"
+)
+
+// JobRequest is the body of a job submission.
+type JobRequest struct {
+	// Target is the URL of the deck to convert.
+	Target string `json:"target"`
+	// Mode is the plugin used to parse the deck (optional).
+	Mode string `json:"mode,omitempty"`
+	// Options are the plugin specific options.
+	Options map[string]string `json:"options,omitempty"`
+	// BackURL is a custom URL for the card backs (optional).
+	BackURL string `json:"backURL,omitempty"`
+	// Template is the ID of the uploader used to generate deck templates
+	// (optional).
+	Template string `json:"template,omitempty"`
+	// Reference is a text (e.g. the quick rules of the format) rendered on
+	// an extra card, generated as a separate object (optional, requires
+	// Template).
+	Reference string `json:"reference,omitempty"`
+	// Compact disables the indentation of the generated JSON files.
+	Compact bool `json:"compact,omitempty"`
+	// Spawn the decks in the running Tabletop Simulator instance, through
+	// its External Editor API.
+	Spawn bool `json:"spawn,omitempty"`
+}
+
+// JobProgress contains the progress of a job.
+type JobProgress struct {
+	// CardsResolved is the number of cards retrieved so far.
+	CardsResolved int `json:"cardsResolved"`
+	// ImagesDownloaded is the number of images downloaded so far.
+	ImagesDownloaded int `json:"imagesDownloaded"`
+	// BytesDownloaded is the size of the images downloaded so far.
+	BytesDownloaded int64 `json:"bytesDownloaded"`
+	// FilesWritten is the number of files generated so far.
+	FilesWritten int `json:"filesWritten"`
+}
+
+// JobInfo is the representation of a job returned by the API.
+type JobInfo struct {
+	ID       string      `json:"id"`
+	Status   JobStatus   `json:"status"`
+	Target   string      `json:"target"`
+	Created  time.Time   `json:"created"`
+	Started  *time.Time  `json:"started,omitempty"`
+	Finished *time.Time  `json:"finished,omitempty"`
+	Progress JobProgress `json:"progress"`
+	// Error is set when Status is JobStatusFailed.
+	Error string `json:"error,omitempty"`
+	// Files are the names of the generated files, which can be downloaded
+	// from /jobs/{id}/files/{name} once the job is done.
+	Files []string `json:"files,omitempty"`
+}
+
+// job is a conversion job. It implements plugins.ProgressReporter to keep
+// track of its progress.
+type job struct {
+	mutex   sync.Mutex
+	info    JobInfo
+	request JobRequest
+	dir     string
+	cancel  context.CancelFunc
+}
+
+func newJobID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// Info returns a copy of the job information.
+func (j *job) Info() JobInfo {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	info := j.info
+	info.Files = append([]string(nil), j.info.Files...)
+
+	return info
+}
+
+func (j *job) setStatus(status JobStatus, err error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	now := time.Now()
+
+	j.info.Status = status
+	switch status {
+	case JobStatusRunning:
+		j.info.Started = &now
+	case JobStatusDone, JobStatusFailed:
+		j.info.Finished = &now
+	}
+	if err != nil {
+		j.info.Error = err.Error()
+	}
+}
+
+func (j *job) setFiles(files []string) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.info.Files = files
+}
+
+// CardResolved implements plugins.ProgressReporter.
+func (j *job) CardResolved(deckName string, cardName string, resolved int, total int) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.info.Progress.CardsResolved++
+}
+
+// ImageDownloaded implements plugins.ProgressReporter.
+func (j *job) ImageDownloaded(url string, bytes int64) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.info.Progress.ImagesDownloaded++
+	j.info.Progress.BytesDownloaded += bytes
+}
+
+// FileWritten implements plugins.ProgressReporter.
+func (j *job) FileWritten(path string, bytes int64) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	j.info.Progress.FilesWritten++
+}
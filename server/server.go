@@ -0,0 +1,676 @@
+// Package server exposes the deck conversion through an HTTP API.
+//
+// Conversions can take several minutes (especially when generating deck
+// templates), so they are run asynchronously: a job is submitted with
+// POST /jobs, its status and progress are polled with GET /jobs/{id}, and
+// the generated files are downloaded with GET /jobs/{id}/files/{name} once
+// it is done. The finished jobs are removed after Options.Retention.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	dc "github.com/jeandeaual/tts-deckconverter"
+	"github.com/jeandeaual/tts-deckconverter/log"
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+	"github.com/jeandeaual/tts-deckconverter/tts"
+	"github.com/jeandeaual/tts-deckconverter/tts/upload"
+)
+
+// DefaultWorkers is the number of jobs run concurrently when
+// Options.Workers isn't set.
+const DefaultWorkers = 2
+
+// DefaultQueueSize is the maximum number of queued jobs when
+// Options.QueueSize isn't set.
+const DefaultQueueSize = 100
+
+// DefaultPruneInterval is the interval between two removals of the expired
+// jobs when Options.PruneInterval isn't set.
+const DefaultPruneInterval = time.Minute
+
+// maxRequestSize is the maximum size of a job submission body.
+const maxRequestSize = 1 << 20
+
+// jobIDRegex matches the job IDs, i.e. the names of the job folders created
+// inside the work directory.
+var jobIDRegex = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// ErrQueueFull is the error returned when too many jobs are waiting for a
+// worker.
+var ErrQueueFull = errors.New("the job queue is full")
+
+// ErrStopped is the error returned when submitting a job to a server being
+// shut down.
+var ErrStopped = errors.New("the server is stopped")
+
+// Options are the settings of a Server.
+type Options struct {
+	// WorkDir is the folder where the files of each job are generated.
+	WorkDir string
+	// Workers is the number of jobs run concurrently.
+	Workers int
+	// QueueSize is the maximum number of jobs waiting for a worker.
+	QueueSize int
+	// JobTimeout stops the jobs taking longer than this duration (no
+	// timeout if 0).
+	JobTimeout time.Duration
+	// Retention is the duration during which the finished jobs and their
+	// files are kept (forever if 0).
+	Retention time.Duration
+	// PruneInterval is the interval between two removals of the jobs older
+	// than Retention.
+	PruneInterval time.Duration
+	// OutputDir is a folder where the generated files are also copied, e.g.
+	// the Tabletop Simulator chest, so that the decks appear in the saved
+	// objects (optional).
+	OutputDir string
+	// SpawnAddress is the address of the Tabletop Simulator External Editor
+	// API, used by the jobs requesting to spawn the decks. Defaults to
+	// tts.ExternalEditorAddress.
+	SpawnAddress string
+	// AllowedOrigins are the origins allowed to call the API from a browser
+	// (e.g. "chrome-extension://<extension ID>"), or "*" to allow every
+	// origin.
+	AllowedOrigins []string
+}
+
+// Server runs the conversion jobs submitted through its HTTP handler.
+type Server struct {
+	options Options
+	queue   chan *job
+	ctx     context.Context
+	cancel  context.CancelFunc
+	// workers is used to wait for the workers to stop
+	workers sync.WaitGroup
+	// background is used to wait for the pruning goroutine to stop
+	background sync.WaitGroup
+
+	mutex   sync.RWMutex
+	jobs    map[string]*job
+	stopped bool
+}
+
+// New creates a server and starts its workers.
+// Close needs to be called to stop them.
+func New(options Options) (*Server, error) {
+	if len(options.WorkDir) == 0 {
+		return nil, errors.New("the work directory is required")
+	}
+	if options.Workers <= 0 {
+		options.Workers = DefaultWorkers
+	}
+	if options.QueueSize <= 0 {
+		options.QueueSize = DefaultQueueSize
+	}
+	if len(options.SpawnAddress) == 0 {
+		options.SpawnAddress = tts.ExternalEditorAddress
+	}
+	if options.PruneInterval <= 0 {
+		options.PruneInterval = DefaultPruneInterval
+	}
+
+	if err := os.MkdirAll(options.WorkDir, 0o755); err != nil {
+		return nil, fmt.Errorf("couldn't create the work directory: %w", err)
+	}
+
+	// The jobs are only kept in memory, so their files can't be retrieved
+	// after a restart
+	if err := removeJobDirs(options.WorkDir); err != nil {
+		return nil, fmt.Errorf("couldn't clean the work directory: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := &Server{
+		options: options,
+		queue:   make(chan *job, options.QueueSize),
+		ctx:     ctx,
+		cancel:  cancel,
+		jobs:    make(map[string]*job),
+	}
+
+	for i := 0; i < options.Workers; i++ {
+		s.workers.Add(1)
+		go s.worker()
+	}
+
+	if options.Retention > 0 {
+		s.background.Add(1)
+		go s.pruner()
+	}
+
+	return s, nil
+}
+
+// Close cancels the running jobs and waits for the workers to stop.
+// The jobs still queued are marked as failed.
+func (s *Server) Close() {
+	s.mutex.Lock()
+	s.stopped = true
+	s.mutex.Unlock()
+
+	s.cancel()
+	s.workers.Wait()
+	s.background.Wait()
+}
+
+// Shutdown stops accepting new jobs and waits for the queued and running
+// jobs to finish. If ctx is done first, the remaining jobs are cancelled
+// like with Close, and the error of ctx is returned.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mutex.Lock()
+	if !s.stopped {
+		s.stopped = true
+		// The workers exit once the queue is empty
+		close(s.queue)
+	}
+	s.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.workers.Wait()
+		close(done)
+	}()
+
+	var err error
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+		log.Warnf("Cancelling the remaining jobs: %v", err)
+	}
+
+	s.cancel()
+	<-done
+	s.background.Wait()
+
+	return err
+}
+
+// Submit queues a conversion job and returns its information.
+func (s *Server) Submit(request JobRequest) (JobInfo, error) {
+	if err := checkRequest(request); err != nil {
+		return JobInfo{}, err
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return JobInfo{}, fmt.Errorf("couldn't generate a job ID: %w", err)
+	}
+
+	j := &job{
+		info: JobInfo{
+			ID:      id,
+			Status:  JobStatusQueued,
+			Target:  request.Target,
+			Created: time.Now(),
+		},
+		request: request,
+		dir:     filepath.Join(s.options.WorkDir, id),
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.stopped {
+		return JobInfo{}, ErrStopped
+	}
+
+	select {
+	case s.queue <- j:
+	default:
+		return JobInfo{}, ErrQueueFull
+	}
+
+	s.jobs[id] = j
+
+	log.Infof("Queued job %s for %s", id, request.Target)
+
+	return j.Info(), nil
+}
+
+// Job returns the information of the job with this ID.
+func (s *Server) Job(id string) (JobInfo, bool) {
+	j, found := s.job(id)
+	if !found {
+		return JobInfo{}, false
+	}
+
+	return j.Info(), true
+}
+
+func (s *Server) job(id string) (*job, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	j, found := s.jobs[id]
+	return j, found
+}
+
+func checkRequest(request JobRequest) error {
+	if len(request.Target) == 0 {
+		return errors.New("the target is required")
+	}
+
+	// Only URLs are accepted, to prevent reading the files of the server
+	u, err := url.Parse(request.Target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("invalid target %s: only HTTP and HTTPS URLs are supported", request.Target)
+	}
+
+	if len(request.Mode) > 0 {
+		if _, found := dc.FindPlugin("", request.Mode); !found {
+			return fmt.Errorf("invalid mode: %s", request.Mode)
+		}
+	}
+
+	if len(request.Template) > 0 {
+		if _, found := upload.TemplateUploaders[request.Template]; !found {
+			return fmt.Errorf("invalid template uploader: %s", request.Template)
+		}
+	} else if len(request.Reference) > 0 {
+		return errors.New("the reference card requires a template uploader")
+	}
+
+	return nil
+}
+
+func (s *Server) worker() {
+	defer s.workers.Done()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			s.drain()
+			return
+		case j, ok := <-s.queue:
+			if !ok {
+				// Shutting down
+				return
+			}
+			s.run(j)
+		}
+	}
+}
+
+// drain marks the jobs left in the queue as failed.
+func (s *Server) drain() {
+	for {
+		select {
+		case j, ok := <-s.queue:
+			if !ok {
+				return
+			}
+			j.setStatus(JobStatusFailed, s.ctx.Err())
+		default:
+			return
+		}
+	}
+}
+
+func (s *Server) pruner() {
+	defer s.background.Done()
+
+	ticker := time.NewTicker(s.options.PruneInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.prune(time.Now().Add(-s.options.Retention))
+		}
+	}
+}
+
+// prune removes the jobs finished before limit, and their files.
+func (s *Server) prune(limit time.Time) {
+	var expired []*job
+
+	s.mutex.Lock()
+	for id, j := range s.jobs {
+		info := j.Info()
+		if info.Finished != nil && info.Finished.Before(limit) {
+			expired = append(expired, j)
+			delete(s.jobs, id)
+		}
+	}
+	s.mutex.Unlock()
+
+	for _, j := range expired {
+		log.Debugf("Removing expired job %s", j.info.ID)
+		if err := os.RemoveAll(j.dir); err != nil {
+			log.Errorf("Couldn't remove the files of job %s: %v", j.info.ID, err)
+		}
+	}
+}
+
+// removeJobDirs removes the job folders found in workDir.
+func removeJobDirs(workDir string) error {
+	infos, err := ioutil.ReadDir(workDir)
+	if err != nil {
+		return err
+	}
+
+	for _, info := range infos {
+		if info.IsDir() && jobIDRegex.MatchString(info.Name()) {
+			if err := os.RemoveAll(filepath.Join(workDir, info.Name())); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) run(j *job) {
+	// A bug in a plugin only fails its job, instead of stopping the server
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("Job %s panicked: %v\n%s", j.info.ID, r, debug.Stack())
+			j.setStatus(JobStatusFailed, fmt.Errorf("internal error: %v", r))
+		}
+	}()
+
+	ctx := s.ctx
+	if s.options.JobTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.options.JobTimeout)
+		defer cancel()
+	}
+	ctx = plugins.WithProgressReporter(ctx, j)
+
+	log.Infof("Starting job %s", j.info.ID)
+	j.setStatus(JobStatusRunning, nil)
+
+	err := s.convert(ctx, j)
+	if err != nil {
+		log.Errorf("Job %s failed: %v", j.info.ID, err)
+		j.setStatus(JobStatusFailed, err)
+		return
+	}
+
+	files, err := listFiles(j.dir)
+	if err != nil {
+		j.setStatus(JobStatusFailed, err)
+		return
+	}
+	j.setFiles(files)
+
+	if err := s.copyToOutputDir(j, files); err != nil {
+		j.setStatus(JobStatusFailed, err)
+		return
+	}
+
+	log.Infof("Job %s done", j.info.ID)
+	j.setStatus(JobStatusDone, nil)
+}
+
+func (s *Server) convert(ctx context.Context, j *job) error {
+	request := j.request
+
+	// The handlers set the default values of some options, so don't share
+	// the options of the request (which are nil if none were sent)
+	options := make(map[string]string, len(request.Options))
+	for k, v := range request.Options {
+		options[k] = v
+	}
+
+	decks, err := dc.Parse(ctx, request.Target, request.Mode, options)
+	if err != nil {
+		return fmt.Errorf("couldn't parse target: %w", err)
+	}
+
+	if err := os.MkdirAll(j.dir, 0o755); err != nil {
+		return err
+	}
+
+	decks, err = tts.AddReferenceDeck(ctx, decks, request.Reference, j.dir)
+	if err != nil {
+		return err
+	}
+
+	if len(request.Template) > 0 {
+		uploader := upload.TemplateUploaders[request.Template]
+
+		errs := tts.GenerateTemplates(ctx, [][]*plugins.Deck{decks}, j.dir, *uploader)
+		for _, err := range errs {
+			// If the template was too big to be uploaded, the user can
+			// still download it and upload it manually
+			if !errors.Is(err, upload.ErrUploadSize) {
+				return err
+			}
+		}
+	}
+
+	errs := tts.Generate(ctx, decks, request.BackURL, j.dir, !request.Compact)
+	if len(errs) > 0 {
+		return errs[0]
+	}
+
+	if request.Spawn {
+		errs := tts.Spawn(ctx, decks, request.BackURL, s.options.SpawnAddress)
+		if len(errs) > 0 {
+			return errs[0]
+		}
+	}
+
+	return nil
+}
+
+// copyToOutputDir copies the generated files to Options.OutputDir.
+func (s *Server) copyToOutputDir(j *job, files []string) error {
+	if len(s.options.OutputDir) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(s.options.OutputDir, 0o755); err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		data, err := ioutil.ReadFile(filepath.Join(j.dir, file))
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(s.options.OutputDir, file)
+		if err := ioutil.WriteFile(path, data, 0o644); err != nil {
+			return fmt.Errorf("couldn't write file %s: %w", path, err)
+		}
+
+		log.Infof("Copied %s to %s", file, s.options.OutputDir)
+	}
+
+	return nil
+}
+
+// listFiles returns the names of the files generated in dir, sorted.
+func listFiles(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0, len(infos))
+	for _, info := range infos {
+		if !info.IsDir() {
+			files = append(files, info.Name())
+		}
+	}
+	sort.Strings(files)
+
+	return files, nil
+}
+
+// Handler returns the HTTP handler of the job API.
+func (s *Server) Handler() http.Handler {
+	return http.HandlerFunc(s.serveHTTP)
+}
+
+// allowedOrigin returns true if a browser page or extension from origin can
+// call the API.
+func (s *Server) allowedOrigin(origin string) bool {
+	for _, allowed := range s.options.AllowedOrigins {
+		if allowed == "*" || allowed == origin {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if origin := r.Header.Get("Origin"); len(origin) > 0 {
+		if !s.allowedOrigin(origin) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("origin %s not allowed", origin))
+			return
+		}
+
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Access-Control-Expose-Headers", "Location")
+		w.Header().Add("Vary", "Origin")
+
+		if r.Method == http.MethodOptions {
+			// Preflight request
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+
+	path := strings.Trim(r.URL.Path, "/")
+	parts := strings.Split(path, "/")
+
+	if parts[0] != "jobs" {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case len(parts) == 1:
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w, http.MethodPost)
+			return
+		}
+		s.handleSubmit(w, r)
+	case len(parts) == 2:
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, http.MethodGet)
+			return
+		}
+		s.handleStatus(w, parts[1])
+	case len(parts) == 4 && parts[2] == "files":
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, http.MethodGet)
+			return
+		}
+		s.handleFile(w, r, parts[1], parts[3])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	var request JobRequest
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+		return
+	}
+
+	info, err := s.Submit(request)
+	if errors.Is(err, ErrQueueFull) || errors.Is(err, ErrStopped) {
+		writeError(w, http.StatusServiceUnavailable, err)
+		return
+	} else if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	w.Header().Set("Location", "/jobs/"+info.ID)
+	writeJSON(w, http.StatusAccepted, info)
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, id string) {
+	info, found := s.Job(id)
+	if !found {
+		writeError(w, http.StatusNotFound, fmt.Errorf("job %s not found", id))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, info)
+}
+
+func (s *Server) handleFile(w http.ResponseWriter, r *http.Request, id string, name string) {
+	info, found := s.Job(id)
+	if !found {
+		writeError(w, http.StatusNotFound, fmt.Errorf("job %s not found", id))
+		return
+	}
+
+	if info.Status != JobStatusDone {
+		writeError(w, http.StatusConflict, fmt.Errorf("job %s is %s", id, info.Status))
+		return
+	}
+
+	// Only serve the files listed in the job, to prevent path traversal
+	listed := false
+	for _, file := range info.Files {
+		if file == name {
+			listed = true
+			break
+		}
+	}
+	if !listed {
+		writeError(w, http.StatusNotFound, fmt.Errorf("file %s not found", name))
+		return
+	}
+
+	j, _ := s.job(id)
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	http.ServeFile(w, r, filepath.Join(j.dir, name))
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed string) {
+	w.Header().Set("Allow", allowed)
+	writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Errorf("Couldn't write the response: %v", err)
+	}
+}
@@ -0,0 +1,356 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	dc "github.com/jeandeaual/tts-deckconverter"
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+func newTestServer(t *testing.T, options Options) (*Server, *httptest.Server, func()) {
+	workDir, err := ioutil.TempDir("", "server")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options.WorkDir = workDir
+	options.Workers = 1
+
+	s, err := New(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(s.Handler())
+
+	return s, ts, func() {
+		ts.Close()
+		s.Close()
+		os.RemoveAll(workDir)
+	}
+}
+
+func submit(t *testing.T, ts *httptest.Server, request string) (*http.Response, JobInfo) {
+	resp, err := http.Post(ts.URL+"/jobs", "application/json", bytes.NewBufferString(request))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var info JobInfo
+	_ = json.NewDecoder(resp.Body).Decode(&info)
+
+	return resp, info
+}
+
+func waitForJob(t *testing.T, ts *httptest.Server, id string) JobInfo {
+	var info JobInfo
+
+	for i := 0; i < 100; i++ {
+		resp, err := http.Get(ts.URL + "/jobs/" + id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = json.NewDecoder(resp.Body).Decode(&info)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if info.Status == JobStatusDone || info.Status == JobStatusFailed {
+			return info
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	t.Fatalf("job %s didn't finish", id)
+
+	return info
+}
+
+func registerTestHandler(t *testing.T) {
+	err := dc.RegisterURLHandler("mtg", plugins.URLHandler{
+		BasePath: "https://decks.example.com",
+		Regex:    regexp.MustCompile(`^https://decks\.example\.com/`),
+		Handler: func(ctx context.Context, url string, options map[string]string) ([]*plugins.Deck, error) {
+			plugins.ReportCardResolved(ctx, "Test", "Island", 1, 1)
+			return []*plugins.Deck{
+				{
+					Name:     "Test",
+					CardSize: plugins.CardSizeStandard,
+					Cards: []plugins.CardInfo{
+						{Name: "Island", Count: 1, ImageURL: "http://127.0.0.1:1/island.png"},
+					},
+				},
+			}, nil
+		},
+	})
+	assert.Nil(t, err)
+}
+
+func TestJob(t *testing.T) {
+	defaultHandlers := dc.URLHandlers
+	defer func() {
+		dc.URLHandlers = defaultHandlers
+	}()
+
+	registerTestHandler(t)
+
+	_, ts, cleanup := newTestServer(t, Options{})
+	defer cleanup()
+
+	resp, info := submit(t, ts, `{"target": "https://decks.example.com/1"}`)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+	assert.Equal(t, "/jobs/"+info.ID, resp.Header.Get("Location"))
+	assert.NotEmpty(t, info.ID)
+
+	info = waitForJob(t, ts, info.ID)
+	assert.Equal(t, JobStatusDone, info.Status, info.Error)
+	assert.Equal(t, 1, info.Progress.CardsResolved)
+	assert.Contains(t, info.Files, "Test.json")
+
+	resp, err := http.Get(ts.URL + "/jobs/" + info.ID + "/files/Test.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var deck map[string]interface{}
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&deck))
+	assert.Contains(t, deck, "ObjectStates")
+
+	resp, err = http.Get(ts.URL + "/jobs/" + info.ID + "/files/..%2F..%2Fpasswd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestJobFailed(t *testing.T) {
+	_, ts, cleanup := newTestServer(t, Options{})
+	defer cleanup()
+
+	resp, info := submit(t, ts, `{"target": "https://unsupported.example.com/1"}`)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	info = waitForJob(t, ts, info.ID)
+	assert.Equal(t, JobStatusFailed, info.Status)
+	assert.Contains(t, info.Error, "unsupported URL")
+	assert.Empty(t, info.Files)
+
+	resp, err := http.Get(ts.URL + "/jobs/" + info.ID + "/files/Test.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+}
+
+func TestJobPanic(t *testing.T) {
+	defaultHandlers := dc.URLHandlers
+	defer func() {
+		dc.URLHandlers = defaultHandlers
+	}()
+
+	err := dc.RegisterURLHandler("mtg", plugins.URLHandler{
+		BasePath: "https://panic.example.com",
+		Regex:    regexp.MustCompile(`^https://panic\.example\.com/`),
+		Handler: func(ctx context.Context, url string, options map[string]string) ([]*plugins.Deck, error) {
+			panic("unexpected deck")
+		},
+	})
+	assert.Nil(t, err)
+
+	_, ts, cleanup := newTestServer(t, Options{})
+	defer cleanup()
+
+	_, info := submit(t, ts, `{"target": "https://panic.example.com/1"}`)
+	info = waitForJob(t, ts, info.ID)
+	assert.Equal(t, JobStatusFailed, info.Status)
+	assert.Contains(t, info.Error, "unexpected deck")
+
+	// The server keeps running the other jobs
+	_, info = submit(t, ts, `{"target": "https://unsupported.example.com/1"}`)
+	info = waitForJob(t, ts, info.ID)
+	assert.Equal(t, JobStatusFailed, info.Status)
+	assert.Contains(t, info.Error, "unsupported URL")
+}
+
+func TestInvalidRequests(t *testing.T) {
+	_, ts, cleanup := newTestServer(t, Options{})
+	defer cleanup()
+
+	for _, request := range []string{
+		`{"target": "/etc/passwd"}`,
+		`{"target": "https://decks.example.com/1", "mode": "invalid"}`,
+		`{"target": "https://decks.example.com/1", "template": "invalid"}`,
+		`{"target": "https://decks.example.com/1", "reference": "No proxies"}`,
+		`{"target": "https://decks.example.com/1", "unknown": true}`,
+		`{}`,
+		`not json`,
+	} {
+		resp, _ := submit(t, ts, request)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, request)
+	}
+
+	resp, err := http.Get(ts.URL + "/jobs/unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err = http.Get(ts.URL + "/jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestCompanion(t *testing.T) {
+	defaultHandlers := dc.URLHandlers
+	defer func() {
+		dc.URLHandlers = defaultHandlers
+	}()
+
+	registerTestHandler(t)
+
+	outputDir, err := ioutil.TempDir("", "chest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outputDir)
+
+	_, ts, cleanup := newTestServer(t, Options{
+		OutputDir:      outputDir,
+		AllowedOrigins: []string{"chrome-extension://abcdef"},
+	})
+	defer cleanup()
+
+	req, err := http.NewRequest(http.MethodOptions, ts.URL+"/jobs", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Origin", "chrome-extension://abcdef")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, "chrome-extension://abcdef", resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, resp.Header.Get("Access-Control-Allow-Methods"), "POST")
+
+	req, err = http.NewRequest(http.MethodPost, ts.URL+"/jobs", bytes.NewBufferString(`{"target": "https://decks.example.com/1"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Origin", "https://evil.example.com")
+
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	resp, info := submit(t, ts, `{"target": "https://decks.example.com/1"}`)
+	assert.Equal(t, http.StatusAccepted, resp.StatusCode)
+
+	info = waitForJob(t, ts, info.ID)
+	assert.Equal(t, JobStatusDone, info.Status, info.Error)
+
+	_, err = os.Stat(filepath.Join(outputDir, "Test.json"))
+	assert.Nil(t, err)
+}
+
+func TestShutdown(t *testing.T) {
+	defaultHandlers := dc.URLHandlers
+	defer func() {
+		dc.URLHandlers = defaultHandlers
+	}()
+
+	registerTestHandler(t)
+
+	s, ts, cleanup := newTestServer(t, Options{})
+	defer cleanup()
+
+	_, info := submit(t, ts, `{"target": "https://decks.example.com/1"}`)
+
+	assert.Nil(t, s.Shutdown(context.Background()))
+
+	info, found := s.Job(info.ID)
+	assert.True(t, found)
+	assert.Equal(t, JobStatusDone, info.Status, info.Error)
+
+	resp, _ := submit(t, ts, `{"target": "https://decks.example.com/1"}`)
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+}
+
+func TestPrune(t *testing.T) {
+	defaultHandlers := dc.URLHandlers
+	defer func() {
+		dc.URLHandlers = defaultHandlers
+	}()
+
+	registerTestHandler(t)
+
+	s, ts, cleanup := newTestServer(t, Options{})
+	defer cleanup()
+
+	_, info := submit(t, ts, `{"target": "https://decks.example.com/1"}`)
+	info = waitForJob(t, ts, info.ID)
+	assert.Equal(t, JobStatusDone, info.Status, info.Error)
+
+	dir := filepath.Join(s.options.WorkDir, info.ID)
+	_, err := os.Stat(dir)
+	assert.Nil(t, err)
+
+	s.prune(info.Created)
+	_, found := s.Job(info.ID)
+	assert.True(t, found)
+
+	s.prune(time.Now().Add(time.Minute))
+	_, found = s.Job(info.ID)
+	assert.False(t, found)
+	_, err = os.Stat(dir)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRemoveJobDirs(t *testing.T) {
+	workDir, err := ioutil.TempDir("", "server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(workDir)
+
+	jobDir := filepath.Join(workDir, "0123456789abcdef0123456789abcdef")
+	otherDir := filepath.Join(workDir, "other")
+	assert.Nil(t, os.Mkdir(jobDir, 0o755))
+	assert.Nil(t, os.Mkdir(otherDir, 0o755))
+
+	assert.Nil(t, removeJobDirs(workDir))
+
+	_, err = os.Stat(jobDir)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(otherDir)
+	assert.Nil(t, err)
+}
@@ -0,0 +1,45 @@
+package deckconverter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+// FindTablePreset returns the table preset called name of the plugin
+// pluginID.
+// An error is returned if the plugin doesn't implement
+// plugins.TablePresetProvider or doesn't have this preset.
+func FindTablePreset(pluginID, name string) (plugins.TablePreset, error) {
+	plugin, found := lookupPlugin(pluginID)
+	if !found {
+		return plugins.TablePreset{}, fmt.Errorf("plugin %s not found", pluginID)
+	}
+
+	provider, ok := plugin.(plugins.TablePresetProvider)
+	if !ok {
+		return plugins.TablePreset{}, fmt.Errorf("plugin %s doesn't have any table preset", pluginID)
+	}
+
+	presets := provider.TablePresets()
+
+	preset, found := presets[name]
+	if !found {
+		names := make([]string, 0, len(presets))
+		for presetName := range presets {
+			names = append(names, presetName)
+		}
+		sort.Strings(names)
+
+		return plugins.TablePreset{}, fmt.Errorf(
+			"invalid table preset for %s: %s (available presets are %s)",
+			pluginID,
+			name,
+			strings.Join(names, ", "),
+		)
+	}
+
+	return preset, nil
+}
@@ -0,0 +1,23 @@
+package deckconverter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindTablePreset(t *testing.T) {
+	preset, err := FindTablePreset("mtg", "commander")
+	assert.Nil(t, err)
+	assert.Equal(t, 4, preset.Players)
+	assert.Equal(t, 40, preset.LifeTotal)
+
+	_, err = FindTablePreset("mtg", "unknown")
+	assert.NotNil(t, err)
+
+	_, err = FindTablePreset("custom", "duel")
+	assert.NotNil(t, err)
+
+	_, err = FindTablePreset("unknown", "duel")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,179 @@
+package tts
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+const (
+	// maxBackRatioDifference is the maximum difference between the width /
+	// height ratios of a card back and of the cards before TTS visibly
+	// stretches it.
+	maxBackRatioDifference = 0.05
+	// minBackScale is the minimum width of a card back, relative to the
+	// width of the card faces, before it looks blurry next to them.
+	minBackScale = 0.5
+)
+
+// imageSize contains the dimensions of a remote image, or the error
+// returned when retrieving them.
+type imageSize struct {
+	width  int
+	height int
+	err    error
+}
+
+var (
+	// imageSizes caches the dimensions of the checked images, since the
+	// same backs are usually shared by several decks.
+	imageSizes      = make(map[string]imageSize)
+	imageSizesMutex sync.Mutex
+)
+
+// isRemoteURL returns true if rawURL is an HTTP or HTTPS URL.
+func isRemoteURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https")
+}
+
+// fetchImageSize returns the dimensions of the image at rawURL, only
+// downloading its header.
+func fetchImageSize(ctx context.Context, rawURL string) imageSize {
+	imageSizesMutex.Lock()
+	size, found := imageSizes[rawURL]
+	imageSizesMutex.Unlock()
+	if found {
+		return size
+	}
+
+	size = func() imageSize {
+		req, err := http.NewRequestWithContext(ctx, "GET", rawURL, nil)
+		if err != nil {
+			return imageSize{err: err}
+		}
+
+		resp, err := plugins.HTTPClient().Do(req)
+		if err != nil {
+			return imageSize{err: err}
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return imageSize{err: fmt.Errorf("status %d (%s)", resp.StatusCode, http.StatusText(resp.StatusCode))}
+		}
+
+		config, _, err := image.DecodeConfig(resp.Body)
+		if err != nil {
+			return imageSize{err: fmt.Errorf("couldn't decode the image: %w", err)}
+		}
+
+		return imageSize{width: config.Width, height: config.Height}
+	}()
+
+	if ctx.Err() != nil {
+		// Don't cache the requests cancelled by the user
+		return size
+	}
+
+	imageSizesMutex.Lock()
+	imageSizes[rawURL] = size
+	imageSizesMutex.Unlock()
+
+	return size
+}
+
+// cardRatio returns the width / height ratio of the cards of size.
+func cardRatio(size plugins.CardSize) float64 {
+	width, height := size.Dimensions()
+	return width / height
+}
+
+// checkBack checks the back of deck against its first card face: it needs
+// to be reachable, with the same ratio as the cards and a similar
+// resolution.
+func checkBack(ctx context.Context, deck *plugins.Deck) error {
+	back := fetchImageSize(ctx, deck.BackURL)
+	if back.err != nil {
+		return fmt.Errorf("back %s of deck %s can't be retrieved: %w", deck.BackURL, deck.Name, back.err)
+	}
+
+	ratio := cardRatio(deck.CardSize)
+
+	var face imageSize
+	for _, card := range deck.Cards {
+		if isRemoteURL(card.ImageURL) && !card.Sideways && !card.Oversized {
+			face = fetchImageSize(ctx, card.ImageURL)
+			break
+		}
+	}
+	if face.err == nil && face.width > 0 && face.height > 0 {
+		ratio = float64(face.width) / float64(face.height)
+	}
+
+	backRatio := float64(back.width) / float64(back.height)
+	if math.Abs(backRatio-ratio) > maxBackRatioDifference {
+		return fmt.Errorf(
+			"back %s of deck %s has a width / height ratio of %.2f instead of %.2f (%dx%d), it will be stretched",
+			deck.BackURL,
+			deck.Name,
+			backRatio,
+			ratio,
+			back.width,
+			back.height,
+		)
+	}
+
+	if face.err == nil && float64(back.width) < float64(face.width)*minBackScale {
+		return fmt.Errorf(
+			"back %s of deck %s is much smaller than the card faces (%dx%d instead of %dx%d), it will look blurry",
+			deck.BackURL,
+			deck.Name,
+			back.width,
+			back.height,
+			face.width,
+			face.height,
+		)
+	}
+
+	return nil
+}
+
+// CheckBackURLs checks the card back of each deck: it needs to be
+// reachable, with the ratio of the cards, and a resolution close to the one
+// of the card faces, otherwise it is stretched or blurry in Tabletop
+// Simulator. An error is returned for each problem found.
+// The backs in known (e.g. the backs provided by the plugins) are trusted,
+// and the results are cached, so each back is only downloaded once.
+func CheckBackURLs(ctx context.Context, decks []*plugins.Deck, known map[string]bool) []error {
+	errs := []error{}
+	checked := make(map[string]bool)
+
+	for _, deck := range decks {
+		if ctx.Err() != nil {
+			break
+		}
+		if len(deck.Cards) == 0 || known[deck.BackURL] || !isRemoteURL(deck.BackURL) {
+			continue
+		}
+
+		// The same back is only reported once, for its first deck
+		key := deck.BackURL + "\n" + deck.CardSize.String()
+		if checked[key] {
+			continue
+		}
+		checked[key] = true
+
+		if err := checkBack(ctx, deck); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
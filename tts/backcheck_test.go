@@ -0,0 +1,74 @@
+package tts
+
+import (
+	"context"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/disintegration/imaging"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+func TestCheckBackURLs(t *testing.T) {
+	sizes := map[string][2]int{
+		"/face.png":    {488, 680},
+		"/good.png":    {488, 680},
+		"/square.png":  {500, 500},
+		"/tiny.png":    {61, 85},
+		"/unknown.png": {10, 10},
+	}
+	requests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		size, found := sizes[r.URL.Path]
+		if !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = png.Encode(w, imaging.New(size[0], size[1], white))
+	}))
+	defer server.Close()
+
+	deck := func(name, back string) *plugins.Deck {
+		return &plugins.Deck{
+			Name:    name,
+			BackURL: server.URL + back,
+			Cards: []plugins.CardInfo{
+				{Name: "Card", ImageURL: server.URL + "/face.png", Count: 1},
+			},
+		}
+	}
+
+	decks := []*plugins.Deck{
+		deck("Good", "/good.png"),
+		deck("Square", "/square.png"),
+		deck("Tiny", "/tiny.png"),
+		deck("Missing", "/missing.png"),
+		deck("Known", "/unknown.png"),
+		deck("Square again", "/square.png"),
+		{Name: "Empty", BackURL: server.URL + "/missing.png"},
+	}
+
+	errs := CheckBackURLs(context.Background(), decks, map[string]bool{server.URL + "/unknown.png": true})
+	if assert.Len(t, errs, 3) {
+		assert.Contains(t, errs[0].Error(), "deck Square has a width / height ratio")
+		assert.Contains(t, errs[1].Error(), "deck Tiny is much smaller than the card faces")
+		assert.Contains(t, errs[2].Error(), "deck Missing can't be retrieved")
+	}
+
+	// The sizes are cached
+	before := requests
+	assert.Len(t, CheckBackURLs(context.Background(), decks, nil), 4)
+	assert.Equal(t, before+1, requests)
+
+	// Without a remote face, the back is compared to the size of the cards
+	small := deck("Small", "/good.png")
+	small.CardSize = plugins.CardSizeSmall
+	small.Cards[0].ImageURL = "/tmp/face.png"
+	assert.Empty(t, CheckBackURLs(context.Background(), []*plugins.Deck{small}, nil))
+}
@@ -0,0 +1,100 @@
+package tts
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// DownloadBudget limits the size of the card images downloaded to generate
+// the template sheets, e.g. on metered connections. When an image would
+// exceed the budget, its lower-quality versions (see
+// plugins.CardInfo.FallbackImageURLs) are tried instead.
+type DownloadBudget struct {
+	// Total is the maximum number of bytes downloaded during the run (0 for
+	// no limit).
+	Total int64
+	// Image is the maximum size of a single image in bytes (0 for no limit).
+	Image int64
+}
+
+var (
+	budget      DownloadBudget
+	budgetSpent int64
+	budgetMutex sync.Mutex
+	// errBudgetExceeded is returned when an image doesn't fit in the
+	// download budget.
+	errBudgetExceeded = errors.New("download budget exceeded")
+)
+
+// SetDownloadBudget changes the download budget of the card images used by
+// GenerateTemplates, and resets the number of bytes already spent.
+func SetDownloadBudget(b DownloadBudget) error {
+	if b.Total < 0 {
+		return fmt.Errorf("invalid maximum download size: %d", b.Total)
+	}
+	if b.Image < 0 {
+		return fmt.Errorf("invalid maximum image size: %d", b.Image)
+	}
+
+	budgetMutex.Lock()
+	defer budgetMutex.Unlock()
+
+	budget = b
+	budgetSpent = 0
+
+	return nil
+}
+
+// reserveBudget reserves the bytes of the download budget needed by a card
+// image of size bytes (negative if its size isn't known beforehand, in which
+// case the largest size allowed is reserved). The reservation is done at
+// once so that concurrent downloads can't overshoot the budget.
+// It returns the number of bytes reserved, which is the maximum size of the
+// image, or -1 if the download budget is unlimited. errBudgetExceeded is
+// returned, without reserving anything, if the image doesn't fit in the
+// remaining budget.
+func reserveBudget(size int64) (int64, error) {
+	budgetMutex.Lock()
+	defer budgetMutex.Unlock()
+
+	limit := int64(-1)
+	if budget.Image > 0 {
+		limit = budget.Image
+	}
+	if budget.Total > 0 {
+		remaining := budget.Total - budgetSpent
+		if remaining < 0 {
+			remaining = 0
+		}
+		if limit < 0 || remaining < limit {
+			limit = remaining
+		}
+	}
+
+	if limit < 0 {
+		return -1, nil
+	}
+	if size >= 0 {
+		if size > limit {
+			return 0, errBudgetExceeded
+		}
+		limit = size
+	}
+	budgetSpent += limit
+
+	return limit, nil
+}
+
+// refundBudget gives back the part of the reserved bytes (see reserveBudget)
+// which weren't used by the download of a card image of size bytes.
+func refundBudget(reserved int64, size int64) {
+	if reserved < 0 || size >= reserved {
+		return
+	}
+
+	budgetMutex.Lock()
+	defer budgetMutex.Unlock()
+
+	budgetSpent -= reserved - size
+}
@@ -0,0 +1,135 @@
+package tts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDownloadBudget(t *testing.T) {
+	defer func() {
+		_ = SetDownloadBudget(DownloadBudget{})
+	}()
+
+	assert.NotNil(t, SetDownloadBudget(DownloadBudget{Total: -1}))
+	assert.NotNil(t, SetDownloadBudget(DownloadBudget{Image: -1}))
+
+	assert.Nil(t, SetDownloadBudget(DownloadBudget{}))
+	reserved, err := reserveBudget(-1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(-1), reserved)
+
+	assert.Nil(t, SetDownloadBudget(DownloadBudget{Total: 100, Image: 60}))
+	// The size isn't known: the largest size allowed is reserved
+	reserved, err = reserveBudget(-1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(60), reserved)
+	refundBudget(reserved, 50)
+	_, err = reserveBudget(51)
+	assert.Equal(t, errBudgetExceeded, err)
+	reserved, err = reserveBudget(50)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(50), reserved)
+	reserved, err = reserveBudget(-1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), reserved)
+
+	// Changing the budget resets the bytes spent
+	assert.Nil(t, SetDownloadBudget(DownloadBudget{Total: 100}))
+	reserved, err = reserveBudget(-1)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(100), reserved)
+}
+
+func TestDownloadBudgetConcurrent(t *testing.T) {
+	defer func() {
+		_ = SetDownloadBudget(DownloadBudget{})
+	}()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strings.Repeat("i", 30)))
+	}))
+	defer server.Close()
+
+	tmpDir, err := ioutil.TempDir("", "budget")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(tmpDir)
+
+	assert.Nil(t, SetDownloadBudget(DownloadBudget{Total: 100}))
+
+	var (
+		wg         sync.WaitGroup
+		downloaded int32
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := downloadImageIfRequired(context.Background(), fmt.Sprintf("%s/%d.png", server.URL, i), nil, tmpDir)
+			if err == nil {
+				atomic.AddInt32(&downloaded, 1)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Only 3 images fit in the budget, even when downloaded concurrently
+	assert.Equal(t, int32(3), downloaded)
+}
+
+func TestDownloadImageFallback(t *testing.T) {
+	defer func() {
+		_ = SetDownloadBudget(DownloadBudget{})
+	}()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/large.png":
+			_, _ = w.Write([]byte(strings.Repeat("l", 100)))
+		case "/small.jpg":
+			_, _ = w.Write([]byte(strings.Repeat("s", 10)))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	tmpDir, err := ioutil.TempDir("", "budget")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(tmpDir)
+
+	ctx := context.Background()
+	fallbacks := []string{server.URL + "/small.jpg"}
+
+	assert.Nil(t, SetDownloadBudget(DownloadBudget{Image: 50}))
+	path, err := downloadImageIfRequired(ctx, server.URL+"/large.png", fallbacks, tmpDir)
+	if assert.Nil(t, err) {
+		data, err := ioutil.ReadFile(path)
+		assert.Nil(t, err)
+		assert.Equal(t, strings.Repeat("s", 10), string(data))
+	}
+
+	// Nothing fits in the remaining budget
+	assert.Nil(t, SetDownloadBudget(DownloadBudget{Total: 5}))
+	_, err = downloadImageIfRequired(ctx, server.URL+"/large.png", nil, tmpDir)
+	assert.True(t, errors.Is(err, errBudgetExceeded))
+
+	// The images already downloaded don't count
+	path, err = downloadImageIfRequired(ctx, server.URL+"/large.png", fallbacks, tmpDir)
+	assert.Nil(t, err)
+	assert.True(t, strings.HasSuffix(path, "small.jpg"))
+}
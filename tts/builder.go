@@ -1,12 +1,13 @@
 package tts
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/jeandeaual/tts-deckconverter/log"
 	"github.com/jeandeaual/tts-deckconverter/plugins"
@@ -24,21 +25,91 @@ const (
 	// to get the correct size (59×86mm)
 	smallScaleX = 59.0 / 58
 	smallScaleZ = 86.0 / 80
+	// defaultCardWidth and defaultCardHeight are the dimensions of a card
+	// with scale 1.0 in millimeters, used to scale the other card sizes
+	defaultCardWidth  = 56.0
+	defaultCardHeight = 80.0
+	// counterOffsetX is the distance between a deck and its counters
+	counterOffsetX = 3.0
+	// counterSpacing is the distance between two counters
+	counterSpacing = 1.5
+	// tileSpacing is the distance between two tiles with scale 1.0
+	tileSpacing = 2.5
+	// tileThickness is the thickness of the tiles
+	tileThickness = 0.2
+	// boardSpacing is the distance between two boards
+	boardSpacing = 25.0
+	// figurineSpacing is the distance between two figurines
+	figurineSpacing = 2.0
+	// diceSpacing is the distance between two dice
+	diceSpacing = 1.5
 )
 
-var filepathReplacer = strings.NewReplacer(
-	// Illegal on Linux/Unix and Windows
-	"/", "-",
-	// Illegal on Windows
-	"\\", "-",
-	":", "-",
-	"*", "-",
-	"?", "-",
-	"\"", "-",
-	"<", "(",
-	">", ")",
-	"|", "-",
-)
+// lifeCounterScript is the script of the life counters, formatted with the
+// starting life total. The total is kept when the game is saved.
+// Right-clicking the buttons changes the total by 5.
+const lifeCounterScript = `life = %d
+
+function onSave()
+    return JSON.encode({life = life})
+end
+
+function onLoad(saved)
+    if saved ~= nil and saved ~= "" then
+        life = JSON.decode(saved).life
+    end
+    self.createButton({click_function = "noop", function_owner = self, label = tostring(life), position = {0, 0.6, 0}, width = 0, height = 0, font_size = 400})
+    self.createButton({click_function = "decrease", function_owner = self, label = "-", position = {-0.75, 0.6, 0}, width = 200, height = 200, font_size = 200})
+    self.createButton({click_function = "increase", function_owner = self, label = "+", position = {0.75, 0.6, 0}, width = 200, height = 200, font_size = 200})
+end
+
+function noop()
+end
+
+function update(delta)
+    life = life + delta
+    self.editButton({index = 0, label = tostring(life)})
+end
+
+function decrease(_, _, alt)
+    update(alt and -5 or -1)
+end
+
+function increase(_, _, alt)
+    update(alt and 5 or 1)
+end
+`
+
+// nonInteractableScript prevents the players from interacting with an
+// object, since the interactable property isn't saved with the objects.
+const nonInteractableScript = `function onLoad()
+    self.interactable = false
+end
+`
+
+// chainedNonInteractableScript is appended to the existing script of an
+// object instead of nonInteractableScript, calling its onLoad function
+// (redefining onLoad would replace it).
+const chainedNonInteractableScript = `
+local scriptOnLoad = onLoad
+
+function onLoad(...)
+    if scriptOnLoad ~= nil then
+        scriptOnLoad(...)
+    end
+    self.interactable = false
+end
+`
+
+// addNonInteractableScript returns script with the code preventing the
+// players from interacting with the object appended.
+func addNonInteractableScript(script string) string {
+	if len(strings.TrimSpace(script)) == 0 {
+		return nonInteractableScript
+	}
+
+	return script + "\n" + chainedNonInteractableScript
+}
 
 func createDeck(deck *plugins.Deck) (SavedObject, string) {
 	object := createDefaultDeck()
@@ -66,7 +137,13 @@ func createDeck(deck *plugins.Deck) (SavedObject, string) {
 			if !deck.Rounded {
 				customDeck.Type = DeckShapeRectangle
 			}
+			if len(card.BackURL) > 0 {
+				customDeck.BackURL = card.BackURL
+			}
 		} else {
+			if len(card.BackURL) > 0 {
+				log.Warnf("The back of %s can't be changed when using templates, using the back of the deck", card.Name)
+			}
 			var (
 				templateID int
 				err        error
@@ -128,24 +205,30 @@ func createDeck(deck *plugins.Deck) (SavedObject, string) {
 		}
 	}
 
-	switch deck.CardSize {
-	case plugins.CardSizeStandard:
-		deckObject.Transform.ScaleX = standardScaleX
-		deckObject.Transform.ScaleZ = standardScaleZ
+	deckObject.Transform.ScaleX, deckObject.Transform.ScaleZ = cardScale(deck.CardSize)
 
-		if oversizedDeck {
-			deckObject.Transform.ScaleX *= standardOversizedScale
-			deckObject.Transform.ScaleY *= standardOversizedScale
-			deckObject.Transform.ScaleZ *= standardOversizedScale
-		}
-	case plugins.CardSizeSmall:
-		deckObject.Transform.ScaleX = smallScaleX
-		deckObject.Transform.ScaleZ = smallScaleZ
+	if deck.CardSize == plugins.CardSizeStandard && oversizedDeck {
+		deckObject.Transform.ScaleX *= standardOversizedScale
+		deckObject.Transform.ScaleY *= standardOversizedScale
+		deckObject.Transform.ScaleZ *= standardOversizedScale
 	}
 
 	return object, thumbnailSource
 }
 
+// cardScale returns the X and Z scales of the cards of size.
+func cardScale(size plugins.CardSize) (scaleX, scaleZ float64) {
+	switch size {
+	case plugins.CardSizeStandard:
+		return standardScaleX, standardScaleZ
+	case plugins.CardSizeSmall:
+		return smallScaleX, smallScaleZ
+	default:
+		width, height := size.Dimensions()
+		return width / defaultCardWidth, height / defaultCardHeight
+	}
+}
+
 func createCard(
 	card plugins.CardInfo,
 	count int,
@@ -227,29 +310,26 @@ func createCard(
 		customDeckID = strconv.Itoa(templateID)
 	}
 
-	scaleX := 1.0
+	scaleX, scaleZ := cardScale(cardSize)
 	scaleY := 1.0
-	scaleZ := 1.0
 
-	switch cardSize {
-	case plugins.CardSizeStandard:
-		scaleX = standardScaleX
-		scaleZ = standardScaleZ
+	if cardSize == plugins.CardSizeStandard && card.Oversized {
+		scaleX *= standardOversizedScale
+		scaleY *= standardOversizedScale
+		scaleZ *= standardOversizedScale
+	}
 
-		if card.Oversized {
-			scaleX *= standardOversizedScale
-			scaleY *= standardOversizedScale
-			scaleZ *= standardOversizedScale
-		}
-	case plugins.CardSizeSmall:
-		scaleX = smallScaleX
-		scaleZ = smallScaleZ
+	description, overflow := limitDescription(card.Description)
+	gmNotes := ""
+	if descriptionLimit.GMNotes {
+		gmNotes = overflow
 	}
 
 	return Object{
 		ObjectType:  CardCustomObject,
 		Nickname:    card.Name,
-		Description: card.Description,
+		Description: description,
+		GMNotes:     gmNotes,
 		Transform: Transform{
 			PosX:   0,
 			PosY:   0,
@@ -275,7 +355,8 @@ func createCard(
 		HideWhenFaceDown: true,
 		Hands:            true,
 		CardID:           cardID,
-		SidewaysCard:     false,
+		SidewaysCard:     card.Sideways,
+		LuaScript:        card.LuaScript,
 		CustomDeck: map[string]CustomDeck{
 			customDeckID: customDeck,
 		},
@@ -283,12 +364,9 @@ func createCard(
 	}
 }
 
-func create(deck *plugins.Deck, outputFolder string, indent bool) error {
-	var (
-		object          SavedObject
-		thumbnailSource string
-	)
-
+// createObject returns the TTS object of deck (a single card if the deck
+// contains only one card), and the URL of the image used for its thumbnail.
+func createObject(deck *plugins.Deck) (object SavedObject, thumbnailSource string) {
 	if len(deck.Cards) == 1 && deck.Cards[0].Count == 1 {
 		// Don't create a deck, only generate a single card
 		card := deck.Cards[0]
@@ -302,6 +380,9 @@ func create(deck *plugins.Deck, outputFolder string, indent bool) error {
 				BackIsHidden: true,
 				UniqueBack:   false,
 			}
+			if len(card.BackURL) > 0 {
+				customDeck.BackURL = card.BackURL
+			}
 		} else {
 			cardID, found := deck.TemplateInfo.ImageURLCardIDMap[card.ImageURL]
 			if !found {
@@ -340,6 +421,195 @@ func create(deck *plugins.Deck, outputFolder string, indent bool) error {
 		object, thumbnailSource = createDeck(deck)
 	}
 
+	if deck.Mystery {
+		face := mysteryFace(deck)
+		hideFaces(&object.ObjectStates[0], face)
+		// Don't reveal the cards with the thumbnail
+		thumbnailSource = face.FaceURL
+	}
+
+	object.ObjectStates[0].GMNotes = attribution(deck, time.Now())
+
+	switch deck.Facing {
+	case plugins.FacingUp:
+		object.ObjectStates[0].Transform.RotZ = 0
+	case plugins.FacingDown:
+		object.ObjectStates[0].Transform.RotZ = 180
+	}
+
+	applyContainer(deck, &object.ObjectStates[0])
+	placement.forDeck(deck).apply(&object.ObjectStates[0])
+
+	if isBoxed(deck) {
+		object.ObjectStates[0] = createDeckBox(deck, object.ObjectStates[0])
+	}
+
+	object.ObjectStates[0].Locked = deck.Locked
+	if deck.NonInteractable {
+		object.ObjectStates[0].LuaScript = addNonInteractableScript(object.ObjectStates[0].LuaScript)
+	}
+
+	if isLaidOut(deck) {
+		if tiles := layOut(deck, object.ObjectStates[0]); len(tiles) > 0 {
+			object.ObjectStates = tiles
+		}
+	}
+
+	if deck.LifeTotal > 0 {
+		object.ObjectStates = append(object.ObjectStates, createLifeCounter(deck.LifeTotal, object.ObjectStates[0].Transform))
+	}
+
+	for i, name := range deck.Counters {
+		if len(name) > 0 {
+			object.ObjectStates = append(object.ObjectStates, createCounter(name, i, object.ObjectStates[0].Transform))
+		}
+	}
+
+	dieIndex := 0
+	for _, accessory := range deck.Accessories {
+		count := accessory.Count
+		if count == 0 {
+			count = 1
+		}
+		for i := 0; i < count; i++ {
+			die, err := createDie(accessory, dieIndex, object.ObjectStates[0].Transform)
+			if err != nil {
+				log.Warnf("Skipping accessory of %s: %v", deck.Name, err)
+				break
+			}
+			object.ObjectStates = append(object.ObjectStates, die)
+			dieIndex++
+		}
+	}
+
+	assignGUIDs(deck, &object)
+
+	return object, thumbnailSource
+}
+
+// createCounter returns the index-th counter labeled with name (e.g.
+// "+1/+1"), placed next to the deck located at deckTransform.
+func createCounter(name string, index int, deckTransform Transform) Object {
+	transform := DefaultTransform
+	transform.PosX = deckTransform.PosX + counterOffsetX
+	transform.PosY = deckTransform.PosY
+	transform.PosZ = deckTransform.PosZ - float64(index)*counterSpacing
+	transform.RotZ = 0
+
+	return Object{
+		ObjectType:     CounterObject,
+		Nickname:       plugins.CapitalizeString(name) + " counters",
+		Transform:      transform,
+		ColorDiffuse:   DefaultColorDiffuse,
+		Grid:           true,
+		Snap:           true,
+		DragSelectable: true,
+		Autoraise:      true,
+		Sticky:         true,
+		Tooltip:        true,
+	}
+}
+
+// createLifeCounter returns a scripted life counter starting at total,
+// placed next to the deck located at deckTransform (on the other side of
+// its counters).
+func createLifeCounter(total int, deckTransform Transform) Object {
+	transform := DefaultTransform
+	transform.PosX = deckTransform.PosX - counterOffsetX
+	transform.PosY = deckTransform.PosY
+	transform.PosZ = deckTransform.PosZ
+	transform.RotY = 0
+	transform.RotZ = 0
+	transform.ScaleX = 2
+	transform.ScaleZ = 1.5
+
+	return Object{
+		ObjectType:     BlockSquareObject,
+		Nickname:       "Life counter",
+		Transform:      transform,
+		ColorDiffuse:   ColorDiffuse{Red: 1, Green: 1, Blue: 1},
+		Grid:           true,
+		Snap:           true,
+		DragSelectable: true,
+		Autoraise:      true,
+		Sticky:         true,
+		Tooltip:        true,
+		LuaScript:      fmt.Sprintf(lifeCounterScript, total),
+	}
+}
+
+// dieObjectTypes are the TTS objects of the dice, indexed by die.
+var dieObjectTypes = map[plugins.Die]ObjectType{
+	plugins.D4:  Die4Object,
+	plugins.D6:  Die6Object,
+	plugins.D8:  Die8Object,
+	plugins.D10: Die10Object,
+	plugins.D12: Die12Object,
+	plugins.D20: Die20Object,
+}
+
+// createDie returns the index-th die of the accessories of a deck, placed
+// next to the deck located at deckTransform (behind its life counter).
+func createDie(accessory plugins.Accessory, index int, deckTransform Transform) (Object, error) {
+	objectType, found := dieObjectTypes[accessory.Die]
+	if !found {
+		return Object{}, fmt.Errorf("invalid die for %s: d%d", accessory.Name, accessory.Die)
+	}
+
+	transform := DefaultTransform
+	transform.PosX = deckTransform.PosX - counterOffsetX
+	transform.PosY = deckTransform.PosY
+	transform.PosZ = deckTransform.PosZ + float64(index+1)*diceSpacing
+	transform.RotY = 0
+	transform.RotZ = 0
+
+	return Object{
+		ObjectType:     objectType,
+		Nickname:       accessory.Name + " die",
+		Transform:      transform,
+		ColorDiffuse:   ColorDiffuse{Red: 1, Green: 1, Blue: 1},
+		Grid:           true,
+		Snap:           true,
+		DragSelectable: true,
+		Autoraise:      true,
+		Sticky:         true,
+		Tooltip:        true,
+	}, nil
+}
+
+// attribution returns the source and author of deck and the conversion time,
+// so that the decks shared in TTS keep this information.
+func attribution(deck *plugins.Deck, converted time.Time) string {
+	var sb strings.Builder
+
+	if len(deck.SourceURL) > 0 {
+		sb.WriteString("Source: " + deck.SourceURL + "\n")
+	}
+	if len(deck.Author) > 0 {
+		sb.WriteString("Author: " + deck.Author + "\n")
+	}
+	sb.WriteString("Converted with tts-deckconverter on " + converted.UTC().Format(time.RFC3339))
+
+	return sb.String()
+}
+
+// create writes the saved object of deck and its thumbnail inside
+// outputFolder, and returns the GUIDs of the generated objects indexed by
+// their name.
+func create(ctx context.Context, deck *plugins.Deck, outputFolder string, indent bool) (map[string]string, error) {
+	object, thumbnailSource := createObject(deck)
+
+	if err := writeObject(ctx, deck.Name, object, thumbnailSource, outputFolder, indent); err != nil {
+		return nil, err
+	}
+
+	return guidMap(deck, object), nil
+}
+
+// writeObject writes object to a saved object file called name inside
+// outputFolder, with a thumbnail generated from thumbnailSource if not
+// empty, or to the writer set with SetOutputWriter.
+func writeObject(ctx context.Context, name string, object SavedObject, thumbnailSource, outputFolder string, indent bool) error {
 	var (
 		data []byte
 		err  error
@@ -354,33 +624,62 @@ func create(deck *plugins.Deck, outputFolder string, indent bool) error {
 		return fmt.Errorf("couldn't marshall data: %w", err)
 	}
 
-	deckName := filepathReplacer.Replace(deck.Name)
+	if w := getOutputWriter(); w != nil {
+		log.Infof("Writing %s", name)
+
+		if _, err := w.Write(append(data, '\n')); err != nil {
+			return fmt.Errorf("couldn't write %s: %w", name, err)
+		}
+
+		return nil
+	}
+
+	fileName := filepathReplacer.Replace(name)
 
-	filename := filepath.Join(outputFolder, deckName+".json")
+	filename := filepath.Join(outputFolder, fileName+".json")
 	log.Infof("Generating %s", filename)
 
-	err = ioutil.WriteFile(filename, data, 0644)
+	err = WriteFile(filename, data)
 	if err != nil {
 		return fmt.Errorf("couldn't write file %s: %w", filename, err)
 	}
 
+	plugins.ReportFileWritten(ctx, filename, int64(len(data)))
+
 	if len(thumbnailSource) > 0 {
-		err = downloadAndCreateThumbnail(thumbnailSource, filepath.Join(outputFolder, deckName+".png"))
+		err = downloadAndCreateThumbnail(ctx, thumbnailSource, filepath.Join(outputFolder, fileName+".png"))
 		if err != nil {
-			log.Error("Couldn't generate the thumbnail for %s: %v", deckName, err)
+			log.Error("Couldn't generate the thumbnail for %s: %v", fileName, err)
 		}
 	}
 
 	return nil
 }
 
-// Generate deck files inside outputFolder.
-func Generate(decks []*plugins.Deck, backURL, outputFolder string, indent bool) []error {
+// Generate deck files inside outputFolder: a file for each deck, or a single
+// file containing every deck (see SetOutputLayout and SetOutputWriter).
+// The thumbnail downloads are cancelled when ctx is done.
+func Generate(ctx context.Context, decks []*plugins.Deck, backURL, outputFolder string, indent bool) []error {
 	log.Infof("Generating %d decks in %s", len(decks), outputFolder)
 
+	start := time.Now()
 	errs := []error{}
+	guids := make(map[string]string)
+	combined := []*plugins.Deck{}
+	layout := getOutputLayout()
+	if getOutputWriter() != nil {
+		// A single JSON document is written
+		layout = OutputCombined
+	}
+
+	defer func() {
+		plugins.RecordOperation(plugins.OperationGenerate, start, firstError(errs))
+	}()
 
 	for _, deck := range decks {
+		if err := ctx.Err(); err != nil {
+			return append(errs, err)
+		}
 		if len(backURL) > 0 {
 			deck.BackURL = backURL
 		}
@@ -388,11 +687,41 @@ func Generate(decks []*plugins.Deck, backURL, outputFolder string, indent bool)
 			log.Infof("Deck %s is empty, skipping", deck.Name)
 			continue
 		}
-		err := create(deck, outputFolder, indent)
+		if layout == OutputCombined {
+			combined = append(combined, deck)
+			continue
+		}
+		deckGUIDs, err := create(ctx, deck, outputFolder, indent)
 		if err != nil {
 			errs = append(errs, fmt.Errorf("couldn't generate deck %s: %w", deck.Name, err))
+			continue
+		}
+		for name, guid := range deckGUIDs {
+			guids[name] = guid
+		}
+	}
+
+	if len(combined) > 0 {
+		deckGUIDs, err := createCombined(ctx, combined, outputFolder, indent)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("couldn't generate deck %s: %w", combined[0].Name, err))
 		}
+		for name, guid := range deckGUIDs {
+			guids[name] = guid
+		}
+	}
+
+	if err := writeGUIDMap(guids); err != nil {
+		errs = append(errs, fmt.Errorf("couldn't write the GUID map: %w", err))
 	}
 
 	return errs
 }
+
+// firstError returns the first error of errs, or nil if errs is empty.
+func firstError(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs[0]
+}
@@ -0,0 +1,475 @@
+package tts
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+func TestAttribution(t *testing.T) {
+	converted := time.Date(2021, 5, 1, 12, 30, 0, 0, time.UTC)
+
+	assert.Equal(
+		t,
+		"Converted with tts-deckconverter on 2021-05-01T12:30:00Z",
+		attribution(&plugins.Deck{Name: "Test"}, converted),
+	)
+	assert.Equal(
+		t,
+		"Source: https://www.moxfield.com/decks/test\nAuthor: Someone\nConverted with tts-deckconverter on 2021-05-01T12:30:00Z",
+		attribution(&plugins.Deck{
+			Name:      "Test",
+			SourceURL: "https://www.moxfield.com/decks/test",
+			Author:    "Someone",
+		}, converted),
+	)
+}
+
+func TestCreateObject(t *testing.T) {
+	deck := &plugins.Deck{
+		Name:      "Test",
+		SourceURL: "https://www.moxfield.com/decks/test",
+		Cards: []plugins.CardInfo{
+			{Name: "Backup Plan", ImageURL: "https://example.com/1.jpg", Count: 1},
+		},
+	}
+
+	object, _ := createObject(deck)
+	assert.Contains(t, object.ObjectStates[0].GMNotes, "Source: https://www.moxfield.com/decks/test")
+	// Single cards are face up by default
+	assert.Equal(t, 0.0, object.ObjectStates[0].Transform.RotZ)
+
+	deck.Facing = plugins.FacingDown
+	object, _ = createObject(deck)
+	assert.Equal(t, 180.0, object.ObjectStates[0].Transform.RotZ)
+
+	deck.Cards[0].Count = 2
+	deck.Facing = plugins.FacingUp
+	object, _ = createObject(deck)
+	assert.Equal(t, DeckObject, object.ObjectStates[0].ObjectType)
+	assert.Equal(t, 0.0, object.ObjectStates[0].Transform.RotZ)
+}
+
+func TestCreateObjectCardSize(t *testing.T) {
+	deck := &plugins.Deck{
+		Name: "Test",
+		Cards: []plugins.CardInfo{
+			{Name: "Roland Banks", ImageURL: "https://example.com/1.jpg", Count: 2},
+		},
+		CardSize: plugins.CardSizeMini,
+	}
+
+	object, _ := createObject(deck)
+	assert.InDelta(t, 41.0/56, object.ObjectStates[0].Transform.ScaleX, 1e-9)
+	assert.InDelta(t, 63.0/80, object.ObjectStates[0].Transform.ScaleZ, 1e-9)
+	assert.InDelta(t, 41.0/56, object.ObjectStates[0].ContainedObjects[0].Transform.ScaleX, 1e-9)
+
+	deck.CardSize = plugins.CardSizeSquare
+	object, _ = createObject(deck)
+	assert.InDelta(t, 70.0/56, object.ObjectStates[0].Transform.ScaleX, 1e-9)
+	assert.InDelta(t, 70.0/80, object.ObjectStates[0].Transform.ScaleZ, 1e-9)
+
+	// The existing sizes keep their scale
+	deck.CardSize = plugins.CardSizeSmall
+	object, _ = createObject(deck)
+	assert.Equal(t, smallScaleX, object.ObjectStates[0].Transform.ScaleX)
+	assert.Equal(t, smallScaleZ, object.ObjectStates[0].Transform.ScaleZ)
+}
+
+func TestCreateObjectCardOverrides(t *testing.T) {
+	deck := &plugins.Deck{
+		Name:    "Test",
+		BackURL: "https://example.com/back.jpg",
+		Cards: []plugins.CardInfo{
+			{Name: "Bazaar of Baghdad", ImageURL: "https://example.com/1.jpg", Count: 1, Sideways: true},
+			{Name: "Tazri's Plane", ImageURL: "https://example.com/2.jpg", Count: 1, BackURL: "https://example.com/plane.jpg"},
+		},
+	}
+
+	object, _ := createObject(deck)
+	cards := object.ObjectStates[0].ContainedObjects
+	if assert.Len(t, cards, 2) {
+		assert.True(t, cards[0].SidewaysCard)
+		assert.Equal(t, "https://example.com/back.jpg", cards[0].CustomDeck["1"].BackURL)
+		assert.False(t, cards[1].SidewaysCard)
+		assert.Equal(t, "https://example.com/plane.jpg", cards[1].CustomDeck["2"].BackURL)
+	}
+}
+
+func TestCreateObjectMystery(t *testing.T) {
+	deck := &plugins.Deck{
+		Name:    "Test",
+		BackURL: "https://example.com/back.jpg",
+		Mystery: true,
+		Cards: []plugins.CardInfo{
+			{Name: "Bazaar of Baghdad", ImageURL: "https://example.com/1.jpg", Count: 2},
+			{
+				Name:             "Delver of Secrets",
+				ImageURL:         "https://example.com/2.jpg",
+				Count:            1,
+				AlternativeState: &plugins.CardInfo{Name: "Insectile Aberration", ImageURL: "https://example.com/3.jpg"},
+			},
+		},
+	}
+
+	object, thumbnailSource := createObject(deck)
+	assert.Equal(t, "https://example.com/back.jpg", thumbnailSource)
+
+	deckObject := object.ObjectStates[0]
+	assert.Equal(t, []int{400, 400, 400}, deckObject.DeckIDs)
+	assert.Equal(t, CustomDeckMap{"4": mysteryFace(deck)}, deckObject.CustomDeck)
+
+	cards := deckObject.ContainedObjects
+	if assert.Len(t, cards, 3) {
+		assert.Empty(t, cards[0].Nickname)
+		assert.Equal(t, 400, cards[0].CardID)
+		assert.Equal(t, "https://example.com/back.jpg", cards[0].CustomDeck["4"].FaceURL)
+		assert.Equal(t, "Bazaar of Baghdad", cards[0].States["2"].Nickname)
+		assert.Equal(t, "https://example.com/1.jpg", cards[0].States["2"].CustomDeck["1"].FaceURL)
+		assert.Nil(t, cards[0].States["2"].States)
+
+		assert.Len(t, cards[2].States, 2)
+		assert.Equal(t, "Delver of Secrets", cards[2].States["2"].Nickname)
+		assert.Equal(t, "Insectile Aberration", cards[2].States["3"].Nickname)
+	}
+
+	deck.Cards = deck.Cards[:1]
+	deck.Cards[0].Count = 1
+	deck.MysteryFaceURL = "https://example.com/placeholder.jpg"
+
+	object, thumbnailSource = createObject(deck)
+	assert.Equal(t, "https://example.com/placeholder.jpg", thumbnailSource)
+	card := object.ObjectStates[0]
+	assert.Equal(t, CardCustomObject, card.ObjectType)
+	assert.Equal(t, "https://example.com/placeholder.jpg", card.CustomDeck["2"].FaceURL)
+	assert.Equal(t, "Bazaar of Baghdad", card.States["2"].Nickname)
+}
+
+func TestCreateObjectPlacement(t *testing.T) {
+	defer func() {
+		assert.Nil(t, SetPlacement(Placement{}))
+	}()
+
+	assert.NotNil(t, SetPlacement(Placement{Scale: -1}))
+	assert.Nil(t, SetPlacement(Placement{
+		Position: &[3]float64{1, 2, 3},
+		Rotation: &[3]float64{0, 90, 0},
+		Scale:    2,
+	}))
+
+	deck := &plugins.Deck{
+		Name:     "Test",
+		CardSize: plugins.CardSizeSmall,
+		Facing:   plugins.FacingDown,
+		Cards: []plugins.CardInfo{
+			{
+				Name:             "Delver of Secrets",
+				ImageURL:         "https://example.com/1.jpg",
+				Count:            2,
+				AlternativeState: &plugins.CardInfo{Name: "Insectile Aberration", ImageURL: "https://example.com/2.jpg"},
+			},
+		},
+	}
+
+	object, _ := createObject(deck)
+	transform := object.ObjectStates[0].Transform
+	assert.Equal(t, 1.0, transform.PosX)
+	assert.Equal(t, 2.0, transform.PosY)
+	assert.Equal(t, 3.0, transform.PosZ)
+	// The rotation replaces the facing of the deck
+	assert.Equal(t, 90.0, transform.RotY)
+	assert.Equal(t, 0.0, transform.RotZ)
+	assert.Equal(t, 2*smallScaleX, transform.ScaleX)
+
+	cards := object.ObjectStates[0].ContainedObjects
+	if assert.Len(t, cards, 2) {
+		assert.Equal(t, 2*smallScaleX, cards[0].Transform.ScaleX)
+		assert.Equal(t, 2*smallScaleZ, cards[0].States["2"].Transform.ScaleZ)
+	}
+
+	assert.Equal(t, [3]float64{7, 2, 3}, placement.spawnPosition(deck, 2))
+}
+
+func TestCreateObjectSectionPlacement(t *testing.T) {
+	defer func() {
+		assert.Nil(t, SetPlacement(Placement{}))
+	}()
+
+	assert.NotNil(t, SetPlacement(Placement{Sections: map[string]Placement{"sideboard": {Scale: -1}}}))
+	assert.Nil(t, SetPlacement(Placement{
+		Position: &[3]float64{1, 2, 3},
+		Rotation: &[3]float64{0, 90, 0},
+		Sections: map[string]Placement{
+			"sideboard": {Position: &[3]float64{-10, 2, 0}},
+			"tokens":    {Scale: 0.5},
+		},
+	}))
+
+	card := plugins.CardInfo{Name: "Card", ImageURL: "https://example.com/1.jpg", Count: 2}
+	main := &plugins.Deck{Name: "Test", Cards: []plugins.CardInfo{card}}
+	sideboard := &plugins.Deck{Name: "Test - Sideboard", Cards: []plugins.CardInfo{card}}
+	tokens := &plugins.Deck{Name: "Test - Tokens", Cards: []plugins.CardInfo{card}}
+
+	object, _ := createObject(main)
+	assert.Equal(t, 1.0, object.ObjectStates[0].Transform.PosX)
+	assert.Equal(t, standardScaleX, object.ObjectStates[0].Transform.ScaleX)
+
+	// The position of the section overrides the position of every deck,
+	// but not the rotation
+	object, _ = createObject(sideboard)
+	assert.Equal(t, -10.0, object.ObjectStates[0].Transform.PosX)
+	assert.Equal(t, 90.0, object.ObjectStates[0].Transform.RotY)
+	assert.Equal(t, [3]float64{-10, 2, 0}, placement.spawnPosition(sideboard, 1))
+
+	object, _ = createObject(tokens)
+	assert.Equal(t, 1.0, object.ObjectStates[0].Transform.PosX)
+	assert.Equal(t, 0.5*standardScaleX, object.ObjectStates[0].Transform.ScaleX)
+	assert.Equal(t, [3]float64{4, 2, 3}, placement.spawnPosition(tokens, 1))
+}
+
+func TestCreateObjectLocked(t *testing.T) {
+	deck := &plugins.Deck{
+		Name: "Playmat",
+		Cards: []plugins.CardInfo{
+			{Name: "Playmat", ImageURL: "https://example.com/playmat.jpg", Count: 1},
+		},
+	}
+
+	object, _ := createObject(deck)
+	assert.False(t, object.ObjectStates[0].Locked)
+	assert.Empty(t, object.ObjectStates[0].LuaScript)
+
+	deck.Locked = true
+	deck.NonInteractable = true
+	object, _ = createObject(deck)
+	assert.True(t, object.ObjectStates[0].Locked)
+	assert.Contains(t, object.ObjectStates[0].LuaScript, "self.interactable = false")
+
+	// The script of the card is kept
+	deck.Cards[0].LuaScript = "function onLoad()\n    print(\"loaded\")\nend\n"
+	object, _ = createObject(deck)
+	assert.True(t, strings.HasPrefix(object.ObjectStates[0].LuaScript, deck.Cards[0].LuaScript))
+	assert.Contains(t, object.ObjectStates[0].LuaScript, "scriptOnLoad(...)")
+}
+
+func TestCreateObjectCounters(t *testing.T) {
+	deck := &plugins.Deck{
+		Name:     "Test",
+		Counters: []string{"+1/+1", "loyalty"},
+		Cards: []plugins.CardInfo{
+			{Name: "Hardened Scales", ImageURL: "https://example.com/1.jpg", Count: 1},
+		},
+	}
+
+	object, _ := createObject(deck)
+	if assert.Len(t, object.ObjectStates, 3) {
+		assert.Equal(t, CounterObject, object.ObjectStates[1].ObjectType)
+		assert.Equal(t, "+1/+1 counters", object.ObjectStates[1].Nickname)
+		assert.Equal(t, "Loyalty counters", object.ObjectStates[2].Nickname)
+		assert.Equal(t, counterOffsetX, object.ObjectStates[2].Transform.PosX)
+		assert.Equal(t, -counterSpacing, object.ObjectStates[2].Transform.PosZ)
+	}
+
+	script, err := spawnScript(object, [3]float64{10, 2, 0})
+	assert.Nil(t, err)
+	assert.Contains(t, script, "position = {x = 10, y = 2, z = 0}")
+	assert.Contains(t, script, "position = {x = 13, y = 2, z = -1.5}")
+}
+
+func TestCreateObjectLifeCounter(t *testing.T) {
+	deck := &plugins.Deck{
+		Name:      "Test",
+		LifeTotal: 40,
+		Cards: []plugins.CardInfo{
+			{Name: "Sol Ring", ImageURL: "https://example.com/1.jpg", Count: 1},
+		},
+	}
+
+	object, _ := createObject(deck)
+	if assert.Len(t, object.ObjectStates, 2) {
+		counter := object.ObjectStates[1]
+		assert.Equal(t, BlockSquareObject, counter.ObjectType)
+		assert.Equal(t, -counterOffsetX, counter.Transform.PosX)
+		assert.Contains(t, counter.LuaScript, "life = 40\n")
+	}
+}
+
+func TestCreateObjectAccessories(t *testing.T) {
+	deck := &plugins.Deck{
+		Name: "Test",
+		Cards: []plugins.CardInfo{
+			{Name: "Blaster Blade", ImageURL: "https://example.com/1.jpg", Count: 4},
+		},
+		Accessories: []plugins.Accessory{
+			{Name: "Damage", Die: plugins.D6, Count: 2},
+			{Name: "Life", Die: plugins.D20},
+			{Name: "Invalid", Die: plugins.Die(7)},
+		},
+	}
+
+	object, _ := createObject(deck)
+	if assert.Len(t, object.ObjectStates, 4) {
+		assert.Equal(t, Die6Object, object.ObjectStates[1].ObjectType)
+		assert.Equal(t, "Damage die", object.ObjectStates[1].Nickname)
+		assert.Equal(t, -counterOffsetX, object.ObjectStates[1].Transform.PosX)
+		assert.Equal(t, Die6Object, object.ObjectStates[2].ObjectType)
+		assert.NotEqual(t, object.ObjectStates[1].GUID, object.ObjectStates[2].GUID)
+		assert.Equal(t, Die20Object, object.ObjectStates[3].ObjectType)
+		assert.Equal(t, 3*diceSpacing, object.ObjectStates[3].Transform.PosZ)
+	}
+}
+
+func TestCreateObjectContainer(t *testing.T) {
+	deck := &plugins.Deck{
+		Name:    "Test - Sideboard",
+		BackURL: "https://example.com/back.jpg",
+		Rounded: true,
+		Cards: []plugins.CardInfo{
+			{Name: "Negate", ImageURL: "https://example.com/1.jpg", Count: 2},
+			{
+				Name:             "Delver of Secrets",
+				ImageURL:         "https://example.com/2.jpg",
+				Count:            1,
+				AlternativeState: &plugins.CardInfo{Name: "Insectile Aberration", ImageURL: "https://example.com/3.jpg"},
+			},
+		},
+		Container: plugins.ContainerBag,
+	}
+
+	object, _ := createObject(deck)
+	bag := object.ObjectStates[0]
+	assert.Equal(t, BagObject, bag.ObjectType)
+	assert.Equal(t, "Test - Sideboard", bag.Nickname)
+	assert.Equal(t, 0.0, bag.Transform.RotZ)
+	if assert.Len(t, bag.ContainedObjects, 3) {
+		assert.Equal(t, "Negate", bag.ContainedObjects[0].Nickname)
+		assert.NotEmpty(t, bag.ContainedObjects[2].States)
+	}
+
+	deck.Container = plugins.ContainerStates
+	object, _ = createObject(deck)
+	holder := object.ObjectStates[0]
+	assert.Equal(t, CardCustomObject, holder.ObjectType)
+	assert.Equal(t, "Test - Sideboard", holder.Nickname)
+	assert.Equal(t, "Negate\nNegate\nDelver of Secrets", holder.Description)
+	assert.Equal(t, 400, holder.CardID)
+	assert.Equal(t, "https://example.com/back.jpg", holder.CustomDeck["4"].FaceURL)
+	if assert.Len(t, holder.States, 3) {
+		assert.Equal(t, "Negate", holder.States["2"].Nickname)
+		assert.Equal(t, "Delver of Secrets", holder.States["4"].Nickname)
+		// TTS doesn't support nested states
+		assert.Empty(t, holder.States["4"].States)
+	}
+
+	// A single card is also put in its container
+	deck.Cards = deck.Cards[:1]
+	deck.Cards[0].Count = 1
+	deck.Container = plugins.ContainerBag
+	object, _ = createObject(deck)
+	if assert.Len(t, object.ObjectStates[0].ContainedObjects, 1) {
+		assert.Equal(t, "Negate", object.ObjectStates[0].ContainedObjects[0].Nickname)
+	}
+}
+
+func TestCreateObjectTiles(t *testing.T) {
+	deck := &plugins.Deck{
+		Name:    "Dungeons",
+		BackURL: "https://example.com/back.jpg",
+		Cards: []plugins.CardInfo{
+			{Name: "Tomb of Annihilation", ImageURL: "https://example.com/1.jpg", Count: 2, Oversized: true},
+			{
+				Name:             "Undercity",
+				ImageURL:         "https://example.com/2.jpg",
+				Count:            1,
+				AlternativeState: &plugins.CardInfo{Name: "Undercity", ImageURL: "https://example.com/3.jpg"},
+			},
+		},
+		Locked:    true,
+		Container: plugins.ContainerTiles,
+	}
+
+	object, _ := createObject(deck)
+	if assert.Len(t, object.ObjectStates, 3) {
+		tile := object.ObjectStates[0]
+		assert.Equal(t, TileCustomObject, tile.ObjectType)
+		assert.Equal(t, "Tomb of Annihilation", tile.Nickname)
+		assert.True(t, tile.Locked)
+		assert.Equal(t, 0.0, tile.Transform.RotZ)
+		assert.Equal(t, standardScaleX*standardOversizedScale, tile.Transform.ScaleX)
+		assert.Equal(t, "https://example.com/1.jpg", tile.CustomImage.ImageURL)
+		assert.Equal(t, "https://example.com/back.jpg", tile.CustomImage.ImageSecondaryURL)
+		assert.NotNil(t, tile.CustomImage.CustomTile)
+		assert.Contains(t, tile.GMNotes, "Converted with tts-deckconverter")
+
+		assert.True(t, object.ObjectStates[1].Transform.PosX > tile.Transform.PosX)
+		assert.Equal(t, "https://example.com/3.jpg", object.ObjectStates[2].CustomImage.ImageSecondaryURL)
+
+		// Each tile gets its own GUID
+		assert.NotEqual(t, object.ObjectStates[0].GUID, object.ObjectStates[1].GUID)
+		assert.Equal(t, objectGUID("Dungeons - Tomb of Annihilation"), object.ObjectStates[1].GUID)
+	}
+
+	deck.Container = plugins.ContainerBoards
+	object, _ = createObject(deck)
+	if assert.Len(t, object.ObjectStates, 3) {
+		board := object.ObjectStates[0]
+		assert.Equal(t, BoardCustomObject, board.ObjectType)
+		assert.Equal(t, 1.0, board.Transform.ScaleX)
+		assert.Nil(t, board.CustomImage.CustomTile)
+		assert.Equal(t, boardSpacing, object.ObjectStates[1].Transform.PosX-board.Transform.PosX)
+	}
+}
+
+func TestCreateObjectFigurines(t *testing.T) {
+	deck := &plugins.Deck{
+		Name: "Tokens",
+		Cards: []plugins.CardInfo{
+			{Name: "Goblin", ImageURL: "https://example.com/1.jpg", ArtURL: "https://example.com/1-art.jpg", Count: 2},
+			{
+				Name:             "Incubator",
+				ImageURL:         "https://example.com/2.jpg",
+				Count:            1,
+				AlternativeState: &plugins.CardInfo{Name: "Phyrexian", ImageURL: "https://example.com/3.jpg"},
+			},
+		},
+		Facing:    plugins.FacingDown,
+		Container: plugins.ContainerFigurines,
+	}
+
+	object, _ := createObject(deck)
+	if assert.Len(t, object.ObjectStates, 3) {
+		figurine := object.ObjectStates[0]
+		assert.Equal(t, FigurineCustomObject, figurine.ObjectType)
+		assert.Equal(t, "Goblin", figurine.Nickname)
+		// The figurines stand upright, whatever the facing of the deck
+		assert.Equal(t, 0.0, figurine.Transform.RotZ)
+		assert.Equal(t, "https://example.com/1-art.jpg", figurine.CustomImage.ImageURL)
+		assert.Nil(t, figurine.CustomImage.CustomTile)
+		assert.Equal(t, figurineSpacing, object.ObjectStates[1].Transform.PosX-figurine.Transform.PosX)
+
+		// The card itself is shown when its art isn't known
+		assert.Equal(t, "https://example.com/2.jpg", object.ObjectStates[2].CustomImage.ImageURL)
+		assert.Equal(t, "https://example.com/3.jpg", object.ObjectStates[2].CustomImage.ImageSecondaryURL)
+	}
+}
+
+func TestCreateObjectCardScript(t *testing.T) {
+	deck := &plugins.Deck{
+		Name: "Test",
+		Cards: []plugins.CardInfo{
+			{Name: "Hardened Scales", ImageURL: "https://example.com/1.jpg", Count: 2, LuaScript: "-- Scales"},
+			{Name: "Sol Ring", ImageURL: "https://example.com/2.jpg", Count: 1},
+		},
+	}
+
+	object, _ := createObject(deck)
+	if assert.Len(t, object.ObjectStates[0].ContainedObjects, 3) {
+		assert.Equal(t, "-- Scales", object.ObjectStates[0].ContainedObjects[0].LuaScript)
+		assert.Equal(t, "-- Scales", object.ObjectStates[0].ContainedObjects[1].LuaScript)
+		assert.Empty(t, object.ObjectStates[0].ContainedObjects[2].LuaScript)
+	}
+}
@@ -0,0 +1,239 @@
+package tts
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/jeandeaual/tts-deckconverter/log"
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+// containedCards returns the cards of object (a deck or a single card).
+func containedCards(object Object) []Object {
+	if object.ObjectType != DeckObject {
+		return []Object{object}
+	}
+
+	return object.ContainedObjects
+}
+
+// createBag returns a bag named after deck containing the cards of object,
+// placed where object was (upright, whatever the facing of the deck).
+func createBag(deck *plugins.Deck, object Object) Object {
+	transform := DefaultTransform
+	transform.PosX = object.Transform.PosX
+	transform.PosY = object.Transform.PosY
+	transform.PosZ = object.Transform.PosZ
+	transform.RotZ = 0
+
+	return Object{
+		ObjectType:       BagObject,
+		Nickname:         deck.Name,
+		Transform:        transform,
+		ColorDiffuse:     DefaultColorDiffuse,
+		Grid:             true,
+		Snap:             true,
+		DragSelectable:   true,
+		Autoraise:        true,
+		Sticky:           true,
+		Tooltip:          true,
+		ContainedObjects: containedCards(object),
+	}
+}
+
+// createHolder returns a holder card named after deck, showing the back of
+// the deck (or its mystery face), with each card of object as one of its
+// states. The states of the cards themselves (e.g. the back faces of the
+// double-faced cards) are dropped, since TTS doesn't support nested states.
+func createHolder(deck *plugins.Deck, object Object) Object {
+	cards := containedCards(object)
+
+	id := 1
+	names := make([]string, 0, len(cards))
+	for _, card := range cards {
+		if next := nextCustomDeckID(card); next > id {
+			id = next
+		}
+		names = append(names, card.Nickname)
+	}
+
+	face := mysteryFace(deck)
+
+	holder := cards[0]
+	holder.Nickname = deck.Name
+	holder.Description = strings.Join(names, "\n")
+	holder.GMNotes = ""
+	holder.SidewaysCard = false
+	holder.CardID = 100 * id
+	holder.CustomDeck = CustomDeckMap{
+		strconv.Itoa(id): face,
+	}
+	holder.Transform = object.Transform
+	holder.States = make(map[string]Object, len(cards))
+
+	for i, card := range cards {
+		if len(card.States) > 0 {
+			log.Debugf("Dropping the states of %s in the holder of %s", card.Nickname, deck.Name)
+		}
+		card.States = nil
+		holder.States[strconv.Itoa(i+2)] = card
+	}
+
+	return holder
+}
+
+// isLaidOut checks if the cards of deck are laid out on the table as
+// separate tiles, boards or figurines, instead of being held by a container.
+func isLaidOut(deck *plugins.Deck) bool {
+	switch deck.Container {
+	case plugins.ContainerTiles, plugins.ContainerBoards, plugins.ContainerFigurines:
+		return true
+	default:
+		return false
+	}
+}
+
+// createFigurine turns tile (created for card) into a custom figurine showing
+// the art of card, or the card itself if its art isn't known. The back of the
+// figurine shows the other face of the card, if any.
+func createFigurine(tile Object, card plugins.CardInfo) Object {
+	tile.ObjectType = FigurineCustomObject
+
+	if len(card.ArtURL) > 0 {
+		tile.CustomImage.ImageURL = card.ArtURL
+	}
+
+	if card.AlternativeState != nil {
+		tile.CustomImage.ImageSecondaryURL = card.AlternativeState.ImageURL
+		if len(card.AlternativeState.ArtURL) > 0 {
+			tile.CustomImage.ImageSecondaryURL = card.AlternativeState.ArtURL
+		}
+	}
+
+	return tile
+}
+
+// createTile returns the custom tile, board or figurine (following the
+// container of deck) showing card, placed at transform.
+func createTile(deck *plugins.Deck, card plugins.CardInfo, transform Transform) Object {
+	description, overflow := limitDescription(card.Description)
+	gmNotes := ""
+	if descriptionLimit.GMNotes {
+		gmNotes = overflow
+	}
+
+	tile := Object{
+		ObjectType:     BoardCustomObject,
+		Nickname:       card.Name,
+		Description:    description,
+		GMNotes:        gmNotes,
+		Transform:      transform,
+		ColorDiffuse:   DefaultColorDiffuse,
+		Grid:           true,
+		Snap:           true,
+		DragSelectable: true,
+		Autoraise:      true,
+		Sticky:         true,
+		Tooltip:        true,
+		LuaScript:      card.LuaScript,
+		CustomImage: &CustomImage{
+			ImageURL:    card.ImageURL,
+			ImageScalar: 1,
+		},
+	}
+
+	if deck.Container == plugins.ContainerFigurines {
+		return createFigurine(tile, card)
+	}
+
+	if deck.Container == plugins.ContainerTiles {
+		tile.ObjectType = TileCustomObject
+		tile.Transform.ScaleX, tile.Transform.ScaleZ = cardScale(deck.CardSize)
+		if deck.CardSize == plugins.CardSizeStandard && card.Oversized {
+			tile.Transform.ScaleX *= standardOversizedScale
+			tile.Transform.ScaleZ *= standardOversizedScale
+		}
+
+		// The back of the tile shows the other face of the card, or the
+		// back of the deck
+		tile.CustomImage.ImageSecondaryURL = deck.BackURL
+		if len(card.BackURL) > 0 {
+			tile.CustomImage.ImageSecondaryURL = card.BackURL
+		}
+		if card.AlternativeState != nil {
+			tile.CustomImage.ImageSecondaryURL = card.AlternativeState.ImageURL
+		}
+
+		tile.CustomImage.CustomTile = &CustomTile{
+			Type:      TileShapeBox,
+			Thickness: tileThickness,
+			Stretch:   true,
+		}
+		if deck.Rounded {
+			tile.CustomImage.CustomTile.Type = TileShapeRounded
+		}
+	}
+
+	return tile
+}
+
+// layOut returns a tile, board or figurine for each copy of the cards of
+// deck, in a row starting where object (the deck or single card generated for
+// it) was, face up unless the deck is facing down (the figurines always stand
+// upright).
+func layOut(deck *plugins.Deck, object Object) []Object {
+	spacing := boardSpacing
+	switch deck.Container {
+	case plugins.ContainerFigurines:
+		spacing = figurineSpacing
+	case plugins.ContainerTiles:
+		scaleX, _ := cardScale(deck.CardSize)
+		spacing = tileSpacing * scaleX
+		for _, card := range deck.Cards {
+			if deck.CardSize == plugins.CardSizeStandard && card.Oversized {
+				spacing = tileSpacing * scaleX * standardOversizedScale
+				break
+			}
+		}
+	}
+
+	tiles := []Object{}
+	for _, card := range deck.Cards {
+		for i := 0; i < card.Count; i++ {
+			transform := object.Transform
+			transform.PosX += float64(len(tiles)) * spacing
+			transform.RotZ = 0
+			if deck.Facing == plugins.FacingDown && deck.Container != plugins.ContainerFigurines {
+				transform.RotZ = 180
+			}
+			transform.ScaleX = 1
+			transform.ScaleY = 1
+			transform.ScaleZ = 1
+
+			tile := createTile(deck, card, transform)
+			tile.Locked = object.Locked
+			if len(tile.LuaScript) == 0 {
+				tile.LuaScript = object.LuaScript
+			}
+			tiles = append(tiles, tile)
+		}
+	}
+
+	// The first tile keeps the notes of the deck (e.g. its attribution)
+	if len(tiles) > 0 {
+		tiles[0].GMNotes = object.GMNotes
+	}
+
+	return tiles
+}
+
+// applyContainer replaces object (a deck or a single card) with the
+// container of deck.
+func applyContainer(deck *plugins.Deck, object *Object) {
+	switch deck.Container {
+	case plugins.ContainerBag:
+		*object = createBag(deck, *object)
+	case plugins.ContainerStates:
+		*object = createHolder(deck, *object)
+	}
+}
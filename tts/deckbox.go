@@ -0,0 +1,362 @@
+package tts
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/disintegration/imaging"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+
+	"github.com/jeandeaual/tts-deckconverter/log"
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+	"github.com/jeandeaual/tts-deckconverter/tts/upload"
+)
+
+const (
+	// deckBoxTextureWidth is the width of the deck box textures.
+	deckBoxTextureWidth = 720
+	// deckBoxLidHeight is the height of the top part of the deck box
+	// textures, shown on the lid (with the ratio of the lid).
+	deckBoxLidHeight = 1020
+	// deckBoxBandHeight is the height of the bottom part of the deck box
+	// textures, shown on the sides and the bottom of the box.
+	deckBoxBandHeight = 340
+	// deckBoxHalfWidth, deckBoxHalfHeight and deckBoxHalfDepth are the half
+	// dimensions of the deck box model, which lies flat on the table.
+	deckBoxHalfWidth  = 1.2
+	deckBoxHalfHeight = 0.8
+	deckBoxHalfDepth  = 1.7
+	// deckBoxMeshFile is the name of the deck box model written in the
+	// output folder when no model is set with SetDeckBoxMesh.
+	deckBoxMeshFile = "Deck Box.obj"
+)
+
+var (
+	deckBoxMeshURL      string
+	deckBoxMeshURLMutex sync.Mutex
+)
+
+// SetDeckBoxMesh sets the URL of the OBJ model of the deck boxes generated
+// by GenerateTemplates (see plugins.Deck.Boxed). If empty, a box model is
+// written in the output folder and referenced by its path, which only works
+// on this computer.
+func SetDeckBoxMesh(meshURL string) {
+	deckBoxMeshURLMutex.Lock()
+	defer deckBoxMeshURLMutex.Unlock()
+
+	deckBoxMeshURL = meshURL
+}
+
+// getDeckBoxMesh returns the URL set with SetDeckBoxMesh.
+func getDeckBoxMesh() string {
+	deckBoxMeshURLMutex.Lock()
+	defer deckBoxMeshURLMutex.Unlock()
+
+	return deckBoxMeshURL
+}
+
+// isBoxed checks if the deck box of deck has been generated.
+func isBoxed(deck *plugins.Deck) bool {
+	return deck.TemplateInfo != nil && len(deck.TemplateInfo.DeckBoxTextureURL) > 0 && !isLaidOut(deck)
+}
+
+// createDeckBox returns the deck box of deck containing object (the deck or
+// single card generated for it), placed where object was.
+func createDeckBox(deck *plugins.Deck, object Object) Object {
+	transform := DefaultTransform
+	transform.PosX = object.Transform.PosX
+	transform.PosY = object.Transform.PosY
+	transform.PosZ = object.Transform.PosZ
+	transform.RotZ = 0
+
+	return Object{
+		ObjectType:       BagCustomModelObject,
+		Nickname:         deck.Name,
+		GMNotes:          object.GMNotes,
+		Transform:        transform,
+		ColorDiffuse:     DefaultColorDiffuse,
+		Grid:             true,
+		Snap:             true,
+		DragSelectable:   true,
+		Autoraise:        true,
+		Sticky:           true,
+		Tooltip:          true,
+		ContainedObjects: []Object{object},
+		CustomMesh: &CustomMesh{
+			MeshURL:       deck.TemplateInfo.DeckBoxMeshURL,
+			DiffuseURL:    deck.TemplateInfo.DeckBoxTextureURL,
+			Convex:        true,
+			MaterialIndex: MeshMaterialCardboard,
+			TypeIndex:     MeshTypeBag,
+			CastShadows:   true,
+		},
+	}
+}
+
+// deckBoxArtURL returns the URL of the art shown on the deck box of deck:
+// its featured art, or the art of its first card.
+func deckBoxArtURL(deck *plugins.Deck) string {
+	if len(deck.FeaturedArtURL) > 0 {
+		return deck.FeaturedArtURL
+	}
+
+	if len(deck.Cards) == 0 {
+		return ""
+	}
+	if len(deck.Cards[0].ArtURL) > 0 {
+		return deck.Cards[0].ArtURL
+	}
+
+	return deck.Cards[0].ImageURL
+}
+
+// generateDeckBoxes renders and uploads the textures of the deck boxes of
+// the boxed decks, and adds them to their template information.
+func generateDeckBoxes(ctx context.Context, decks []*plugins.Deck, tmpDir, outputFolder string, uploader upload.TemplateUploader) []error {
+	errs := []error{}
+	meshURL := getDeckBoxMesh()
+
+	for _, deck := range decks {
+		if !deck.Boxed {
+			continue
+		}
+		if isLaidOut(deck) {
+			log.Warnf("The cards of %s are laid out on the table, not putting them in a deck box", deck.Name)
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			return append(errs, err)
+		}
+
+		if len(meshURL) == 0 {
+			var err error
+			meshURL, err = writeDeckBoxMesh(ctx, outputFolder)
+			if err != nil {
+				return append(errs, fmt.Errorf("couldn't write the deck box model: %w", err))
+			}
+		}
+
+		textureURL, err := generateDeckBoxTexture(ctx, deck, tmpDir, outputFolder, uploader)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("couldn't generate the deck box of %s: %w", deck.Name, err))
+			continue
+		}
+
+		if deck.TemplateInfo == nil {
+			deck.TemplateInfo = &plugins.TemplateInfo{}
+		}
+		deck.TemplateInfo.DeckBoxTextureURL = textureURL
+		deck.TemplateInfo.DeckBoxMeshURL = meshURL
+	}
+
+	return errs
+}
+
+// generateDeckBoxTexture renders the texture of the deck box of deck, and
+// returns its URL once uploaded.
+func generateDeckBoxTexture(ctx context.Context, deck *plugins.Deck, tmpDir, outputFolder string, uploader upload.TemplateUploader) (string, error) {
+	var art image.Image
+
+	if artURL := deckBoxArtURL(deck); len(artURL) > 0 {
+		path, err := downloadImageIfRequired(ctx, artURL, nil, tmpDir)
+		if err == nil {
+			art, err = imaging.Open(path)
+		}
+		if err != nil {
+			// The box is still usable with its name only
+			log.Warnf("Couldn't retrieve the art of the deck box of %s: %v", deck.Name, err)
+			art = nil
+		}
+	}
+
+	texture := renderDeckBoxTexture(deck.Name, art)
+
+	textureName := FileName(deck.Name) + " - Box"
+
+	var outputPath string
+	if uploader.UploaderID() != "manual" {
+		outputPath = filepath.Join(os.TempDir(), textureName+".png")
+	} else {
+		outputPath = filepath.Join(outputFolder, textureName+".png")
+	}
+
+	err := writePartial(outputPath, func(partial string) error {
+		return imaging.Save(texture, partial)
+	})
+	if err != nil {
+		return "", err
+	}
+	reportFileWritten(ctx, outputPath)
+
+	url, err := upload.UploadContext(ctx, uploader, outputPath, textureName, plugins.HTTPClient())
+	if err != nil {
+		return "", fmt.Errorf("couldn't upload %s: %w", outputPath, err)
+	}
+
+	if uploader.UploaderID() != "manual" {
+		log.Debugf("Deleting deck box texture %s", outputPath)
+		if err := os.Remove(outputPath); err != nil {
+			log.Warnf("Couldn't remove %s: %v", outputPath, err)
+		}
+	}
+
+	return url, nil
+}
+
+// renderDeckBoxTexture returns the texture of a deck box showing name, and
+// art on its lid (if not nil). The lid takes the top part of the texture,
+// the sides and the bottom of the box the rest (see deckBoxMesh).
+func renderDeckBoxTexture(name string, art image.Image) *image.NRGBA {
+	texture := imaging.New(
+		deckBoxTextureWidth,
+		deckBoxLidHeight+deckBoxBandHeight,
+		color.NRGBA{R: 32, G: 32, B: 32, A: 255},
+	)
+
+	if art != nil {
+		lid := imaging.Fill(art, deckBoxTextureWidth, deckBoxLidHeight, imaging.Center, imaging.Lanczos)
+		draw.Draw(texture, lid.Bounds(), lid, image.Point{}, draw.Src)
+	}
+
+	// The name is written on a band at the bottom of the lid, and across
+	// the sides
+	label := renderLabel(name, deckBoxTextureWidth, deckBoxLidHeight/6)
+	band := image.Rect(0, deckBoxLidHeight-label.Bounds().Dy(), deckBoxTextureWidth, deckBoxLidHeight)
+	draw.Draw(texture, band, image.Black, image.Point{}, draw.Src)
+	draw.Draw(texture, band.Add(image.Pt((deckBoxTextureWidth-label.Bounds().Dx())/2, 0)), label, image.Point{}, draw.Src)
+
+	label = renderLabel(name, deckBoxTextureWidth, deckBoxBandHeight)
+	draw.Draw(
+		texture,
+		label.Bounds().Add(image.Pt(
+			(deckBoxTextureWidth-label.Bounds().Dx())/2,
+			deckBoxLidHeight+(deckBoxBandHeight-label.Bounds().Dy())/2,
+		)),
+		label,
+		image.Point{},
+		draw.Src,
+	)
+
+	return texture
+}
+
+// renderLabel returns text written in white on a black background, as large
+// as possible without exceeding maxWidth and maxHeight. Only the Latin-1
+// characters are supported.
+func renderLabel(text string, maxWidth, maxHeight int) *image.NRGBA {
+	face := basicfont.Face7x13
+	metrics := face.Metrics()
+	// Leave one character of margin on each side of the text
+	padding := face.Advance
+	width := font.MeasureString(face, text).Ceil() + 2*padding
+	height := (metrics.Ascent + metrics.Descent).Ceil() + face.Height/2
+
+	label := imaging.New(width, height, color.NRGBA{A: 255})
+	drawer := font.Drawer{
+		Dst:  label,
+		Src:  image.White,
+		Face: face,
+		Dot:  fixed.P(padding, face.Height/4+metrics.Ascent.Ceil()),
+	}
+	drawer.DrawString(text)
+
+	if width*maxHeight > height*maxWidth {
+		return imaging.Resize(label, maxWidth, 0, imaging.Linear)
+	}
+	return imaging.Resize(label, 0, maxHeight, imaging.Linear)
+}
+
+// writeDeckBoxMesh writes the deck box model in outputFolder, and returns its
+// path.
+func writeDeckBoxMesh(ctx context.Context, outputFolder string) (string, error) {
+	path, err := filepath.Abs(filepath.Join(outputFolder, deckBoxMeshFile))
+	if err != nil {
+		return "", err
+	}
+
+	if err := WriteFile(path, []byte(deckBoxMesh())); err != nil {
+		return "", err
+	}
+	reportFileWritten(ctx, path)
+
+	return path, nil
+}
+
+// deckBoxMesh returns the OBJ model of the deck boxes: a box lying flat on
+// the table, its lid showing the top part of the texture, and its sides and
+// bottom the bottom part (see renderDeckBoxTexture).
+func deckBoxMesh() string {
+	extents := [3]float64{deckBoxHalfWidth, deckBoxHalfHeight, deckBoxHalfDepth}
+	lidV := float64(deckBoxBandHeight) / float64(deckBoxBandHeight+deckBoxLidHeight)
+
+	faces := []struct {
+		normal [3]float64
+		// up is the direction of the top of the texture on the face
+		up  [3]float64
+		lid bool
+	}{
+		{normal: [3]float64{0, 1, 0}, up: [3]float64{0, 0, 1}, lid: true},
+		{normal: [3]float64{0, -1, 0}, up: [3]float64{0, 0, 1}},
+		{normal: [3]float64{1, 0, 0}, up: [3]float64{0, 1, 0}},
+		{normal: [3]float64{-1, 0, 0}, up: [3]float64{0, 1, 0}},
+		{normal: [3]float64{0, 0, 1}, up: [3]float64{0, 1, 0}},
+		{normal: [3]float64{0, 0, -1}, up: [3]float64{0, 1, 0}},
+	}
+
+	scale := func(v [3]float64, factor float64) [3]float64 {
+		return [3]float64{
+			v[0] * extents[0] * factor,
+			v[1] * extents[1] * factor,
+			v[2] * extents[2] * factor,
+		}
+	}
+
+	var vertices, uvs, normals, triangles strings.Builder
+
+	for i, face := range faces {
+		// The right of the face, seen from outside the box
+		right := [3]float64{
+			-face.normal[1]*face.up[2] + face.normal[2]*face.up[1],
+			-face.normal[2]*face.up[0] + face.normal[0]*face.up[2],
+			-face.normal[0]*face.up[1] + face.normal[1]*face.up[0],
+		}
+		center := scale(face.normal, 1)
+
+		// Bottom left, bottom right, top right and top left corners
+		for _, corner := range [][2]float64{{-1, -1}, {1, -1}, {1, 1}, {-1, 1}} {
+			r := scale(right, corner[0])
+			u := scale(face.up, corner[1])
+			fmt.Fprintf(&vertices, "v %g %g %g\n", center[0]+r[0]+u[0], center[1]+r[1]+u[1], center[2]+r[2]+u[2])
+		}
+
+		minV, maxV := 0.0, lidV
+		if face.lid {
+			minV, maxV = lidV, 1
+		}
+		fmt.Fprintf(&uvs, "vt 0 %g\nvt 1 %g\nvt 1 %g\nvt 0 %g\n", minV, minV, maxV, maxV)
+
+		fmt.Fprintf(&normals, "vn %g %g %g\n", face.normal[0], face.normal[1], face.normal[2])
+
+		first := 4*i + 1
+		for _, triangle := range [][3]int{{0, 1, 2}, {0, 2, 3}} {
+			triangles.WriteString("f")
+			for _, corner := range triangle {
+				fmt.Fprintf(&triangles, " %d/%d/%d", first+corner, first+corner, i+1)
+			}
+			triangles.WriteString("\n")
+		}
+	}
+
+	return "# Deck box generated by tts-deckconverter\n" +
+		vertices.String() + uvs.String() + normals.String() + triangles.String()
+}
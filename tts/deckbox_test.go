@@ -0,0 +1,126 @@
+package tts
+
+import (
+	"context"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/disintegration/imaging"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+func TestDeckBoxArtURL(t *testing.T) {
+	deck := &plugins.Deck{
+		Cards: []plugins.CardInfo{
+			{ImageURL: "https://example.com/card.jpg", ArtURL: "https://example.com/art.jpg"},
+		},
+	}
+	assert.Equal(t, "https://example.com/art.jpg", deckBoxArtURL(deck))
+
+	deck.Cards[0].ArtURL = ""
+	assert.Equal(t, "https://example.com/card.jpg", deckBoxArtURL(deck))
+
+	deck.FeaturedArtURL = "https://example.com/commander.jpg"
+	assert.Equal(t, "https://example.com/commander.jpg", deckBoxArtURL(deck))
+
+	assert.Empty(t, deckBoxArtURL(&plugins.Deck{}))
+}
+
+func TestDeckBoxMesh(t *testing.T) {
+	counts := make(map[string]int)
+	for _, line := range strings.Split(deckBoxMesh(), "\n") {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			counts[fields[0]]++
+		}
+	}
+
+	assert.Equal(t, 24, counts["v"])
+	assert.Equal(t, 24, counts["vt"])
+	assert.Equal(t, 6, counts["vn"])
+	assert.Equal(t, 12, counts["f"])
+}
+
+func TestRenderDeckBoxTexture(t *testing.T) {
+	art := imaging.New(100, 50, color.NRGBA{R: 255, A: 255})
+
+	texture := renderDeckBoxTexture("Atraxa Superfriends", art)
+	assert.Equal(t, deckBoxTextureWidth, texture.Bounds().Dx())
+	assert.Equal(t, deckBoxLidHeight+deckBoxBandHeight, texture.Bounds().Dy())
+	// The art fills the lid
+	assert.Equal(t, color.NRGBA{R: 255, A: 255}, texture.NRGBAAt(deckBoxTextureWidth/2, 10))
+
+	// The label fits in its band, whatever the length of the name
+	label := renderLabel("A", deckBoxTextureWidth, deckBoxBandHeight)
+	assert.Equal(t, deckBoxBandHeight, label.Bounds().Dy())
+	assert.True(t, label.Bounds().Dx() <= deckBoxTextureWidth)
+	label = renderLabel(strings.Repeat("A", 200), deckBoxTextureWidth, deckBoxBandHeight)
+	assert.Equal(t, deckBoxTextureWidth, label.Bounds().Dx())
+	assert.True(t, label.Bounds().Dy() <= deckBoxBandHeight)
+}
+
+func TestGenerateDeckBoxes(t *testing.T) {
+	defer SetDeckBoxMesh("")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = png.Encode(w, imaging.New(10, 15, color.NRGBA{0xff, 0, 0, 0xff}))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "deckbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	card := func(name string) plugins.CardInfo {
+		return plugins.CardInfo{Name: name, ImageURL: server.URL + "/" + name + ".png", Count: 2}
+	}
+	decks := []*plugins.Deck{
+		{Name: "Main", Cards: []plugins.CardInfo{card("a")}, FeaturedArtURL: server.URL + "/art.png", Boxed: true},
+		{Name: "Main - Sideboard", Cards: []plugins.CardInfo{card("b")}},
+	}
+
+	uploader := &countingUploader{}
+	errs := GenerateTemplates(context.Background(), [][]*plugins.Deck{decks}, dir, uploader)
+	assert.Empty(t, errs)
+	assert.Contains(t, uploader.uploads, "Main - Box")
+
+	if assert.NotNil(t, decks[0].TemplateInfo) {
+		assert.Equal(t, "file://"+filepath.Join(dir, "Main - Box.png"), decks[0].TemplateInfo.DeckBoxTextureURL)
+		assert.Equal(t, filepath.Join(dir, deckBoxMeshFile), decks[0].TemplateInfo.DeckBoxMeshURL)
+		assert.FileExists(t, filepath.Join(dir, deckBoxMeshFile))
+	}
+	if assert.NotNil(t, decks[1].TemplateInfo) {
+		assert.Empty(t, decks[1].TemplateInfo.DeckBoxTextureURL)
+	}
+
+	object, _ := createObject(decks[0])
+	if assert.Len(t, object.ObjectStates, 1) {
+		box := object.ObjectStates[0]
+		assert.Equal(t, BagCustomModelObject, box.ObjectType)
+		assert.Equal(t, "Main", box.Nickname)
+		assert.Equal(t, MeshTypeBag, box.CustomMesh.TypeIndex)
+		assert.Equal(t, decks[0].TemplateInfo.DeckBoxTextureURL, box.CustomMesh.DiffuseURL)
+		assert.Contains(t, box.GMNotes, "Converted with tts-deckconverter")
+		if assert.Len(t, box.ContainedObjects, 1) {
+			assert.Equal(t, DeckObject, box.ContainedObjects[0].ObjectType)
+		}
+	}
+
+	// The model set with SetDeckBoxMesh isn't written
+	SetDeckBoxMesh("https://example.com/box.obj")
+	assert.Nil(t, os.Remove(filepath.Join(dir, deckBoxMeshFile)))
+	errs = GenerateTemplates(context.Background(), [][]*plugins.Deck{decks}, dir, uploader)
+	assert.Empty(t, errs)
+	assert.Equal(t, "https://example.com/box.obj", decks[0].TemplateInfo.DeckBoxMeshURL)
+	assert.NoFileExists(t, filepath.Join(dir, deckBoxMeshFile))
+}
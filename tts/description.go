@@ -0,0 +1,72 @@
+package tts
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// DefaultEllipsis is appended to the truncated card descriptions, unless
+// another one is set with SetDescriptionLimit.
+const DefaultEllipsis = "…"
+
+// DescriptionLimit controls the length of the card descriptions.
+// Tabletop Simulator doesn't limit them, but the tooltips of the cards with a
+// long description (e.g. with every ruling) don't fit on the screen.
+type DescriptionLimit struct {
+	// MaxLength is the maximum number of characters of a description,
+	// ellipsis included, or 0 for no limit.
+	MaxLength int
+	// Ellipsis is appended to the truncated descriptions.
+	Ellipsis string
+	// GMNotes moves the truncated part of the descriptions to the GM notes of
+	// the cards, instead of dropping it.
+	GMNotes bool
+}
+
+var descriptionLimit = DescriptionLimit{Ellipsis: DefaultEllipsis}
+
+// SetDescriptionLimit changes the maximum length of the card descriptions
+// of the generated objects.
+func SetDescriptionLimit(limit DescriptionLimit) error {
+	if limit.MaxLength < 0 {
+		return fmt.Errorf("invalid maximum description length: %d", limit.MaxLength)
+	}
+	if limit.MaxLength > 0 && len([]rune(limit.Ellipsis)) >= limit.MaxLength {
+		return fmt.Errorf("the ellipsis (%q) must be shorter than the maximum description length", limit.Ellipsis)
+	}
+
+	descriptionLimit = limit
+
+	return nil
+}
+
+// limitDescription truncates description following descriptionLimit, and
+// returns the truncated description and the part which was removed (empty
+// if the description is short enough).
+// The description is cut at the last space before the limit when possible,
+// so that words and BBCode tags are kept whole.
+func limitDescription(description string) (string, string) {
+	runes := []rune(description)
+	maxLength := descriptionLimit.MaxLength
+
+	if maxLength == 0 || len(runes) <= maxLength {
+		return description, ""
+	}
+
+	ellipsis := []rune(descriptionLimit.Ellipsis)
+	cut := maxLength - len(ellipsis)
+
+	// Only cut at a space if it doesn't remove more than half the description
+	for i := cut; i > cut/2; i-- {
+		if unicode.IsSpace(runes[i]) {
+			cut = i
+			break
+		}
+	}
+
+	kept := strings.TrimRightFunc(string(runes[:cut]), unicode.IsSpace)
+	overflow := strings.TrimSpace(string(runes[cut:]))
+
+	return kept + string(ellipsis), overflow
+}
@@ -0,0 +1,51 @@
+package tts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+func TestLimitDescription(t *testing.T) {
+	description, overflow := limitDescription("Flying, first strike")
+	assert.Equal(t, "Flying, first strike", description)
+	assert.Empty(t, overflow)
+
+	assert.Nil(t, SetDescriptionLimit(DescriptionLimit{MaxLength: 16, Ellipsis: "..."}))
+	defer func() {
+		assert.Nil(t, SetDescriptionLimit(DescriptionLimit{Ellipsis: DefaultEllipsis}))
+	}()
+
+	description, overflow = limitDescription("Flying, first strike")
+	assert.Equal(t, "Flying, first...", description)
+	assert.Equal(t, "strike", overflow)
+
+	description, overflow = limitDescription("Flying, lifelink")
+	assert.Equal(t, "Flying, lifelink", description)
+	assert.Empty(t, overflow)
+
+	// No space in the second half of the description
+	description, overflow = limitDescription("Ééééééééééééééééééé")
+	assert.Equal(t, "Ééééééééééééé...", description)
+	assert.Equal(t, "éééééé", overflow)
+
+	assert.NotNil(t, SetDescriptionLimit(DescriptionLimit{MaxLength: -1}))
+	assert.NotNil(t, SetDescriptionLimit(DescriptionLimit{MaxLength: 3, Ellipsis: "..."}))
+}
+
+func TestDescriptionGMNotes(t *testing.T) {
+	assert.Nil(t, SetDescriptionLimit(DescriptionLimit{MaxLength: 10, Ellipsis: DefaultEllipsis, GMNotes: true}))
+	defer func() {
+		assert.Nil(t, SetDescriptionLimit(DescriptionLimit{Ellipsis: DefaultEllipsis}))
+	}()
+
+	card := createCard(plugins.CardInfo{
+		Name:        "Serra Angel",
+		Description: "Flying, vigilance",
+	}, 1, CustomDeck{}, nil, plugins.CardSizeStandard)
+
+	assert.Equal(t, "Flying,…", card.Description)
+	assert.Equal(t, "vigilance", card.GMNotes)
+}
@@ -0,0 +1,132 @@
+package tts
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+const (
+	// DefaultTemplateWorkers is the default number of card images downloaded
+	// concurrently by GenerateTemplates.
+	DefaultTemplateWorkers = 4
+	// downloadHostInterval is the minimum interval between two downloads
+	// from the same host, to stay polite to the image servers of the APIs
+	// (e.g. Scryfall or YGOPRODeck).
+	downloadHostInterval = 50 * time.Millisecond
+)
+
+var (
+	templateWorkers      = DefaultTemplateWorkers
+	templateWorkersMutex sync.Mutex
+	// hostLimiters are the rate limiters of the image downloads, indexed by
+	// host.
+	hostLimiters      = make(map[string]*plugins.RateLimiter)
+	hostLimitersMutex sync.Mutex
+)
+
+// SetTemplateWorkers changes the number of card images downloaded
+// concurrently by GenerateTemplates. The downloads from the same host are
+// still spaced out.
+func SetTemplateWorkers(workers int) error {
+	if workers < 1 {
+		return fmt.Errorf("invalid number of template workers: %d", workers)
+	}
+
+	templateWorkersMutex.Lock()
+	defer templateWorkersMutex.Unlock()
+
+	templateWorkers = workers
+
+	return nil
+}
+
+// getTemplateWorkers returns the number of card images downloaded
+// concurrently.
+func getTemplateWorkers() int {
+	templateWorkersMutex.Lock()
+	defer templateWorkersMutex.Unlock()
+
+	return templateWorkers
+}
+
+// waitForHost blocks until the next download from the host of rawURL is
+// allowed, or until ctx is done.
+func waitForHost(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+
+	hostLimitersMutex.Lock()
+	limiter, found := hostLimiters[u.Host]
+	if !found {
+		limiter = plugins.NewStandaloneRateLimiter(downloadHostInterval)
+		hostLimiters[u.Host] = limiter
+	}
+	hostLimitersMutex.Unlock()
+
+	return limiter.Wait(ctx)
+}
+
+// imageDownload is a card image downloaded by downloadImages.
+type imageDownload struct {
+	url          string
+	fallbackURLs []string
+}
+
+// downloadImages downloads the images of downloads concurrently into
+// tmpDir (see downloadImageIfRequired), and returns their paths in the same
+// order. The images appearing several times are only downloaded once.
+// The first error (following the order of downloads) is returned.
+func downloadImages(ctx context.Context, downloads []imageDownload, tmpDir string) ([]string, error) {
+	var (
+		wg        sync.WaitGroup
+		queue     = make(chan int)
+		filenames = make([]string, len(downloads))
+		errs      = make([]error, len(downloads))
+		first     = make(map[string]int)
+	)
+
+	for i := 0; i < getTemplateWorkers(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range queue {
+				download := downloads[index]
+				filenames[index], errs[index] = downloadImageIfRequired(ctx, download.url, download.fallbackURLs, tmpDir)
+			}
+		}()
+	}
+
+	for index, download := range downloads {
+		if ctx.Err() != nil {
+			break
+		}
+		if _, found := first[download.url]; found {
+			continue
+		}
+		first[download.url] = index
+		queue <- index
+	}
+	close(queue)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	for index, download := range downloads {
+		source := first[download.url]
+		if errs[source] != nil {
+			return nil, errs[source]
+		}
+		filenames[index] = filenames[source]
+	}
+
+	return filenames, nil
+}
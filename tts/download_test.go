@@ -0,0 +1,66 @@
+package tts
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetTemplateWorkers(t *testing.T) {
+	defer func() {
+		_ = SetTemplateWorkers(DefaultTemplateWorkers)
+	}()
+
+	assert.NotNil(t, SetTemplateWorkers(0))
+	assert.Equal(t, DefaultTemplateWorkers, getTemplateWorkers())
+
+	assert.Nil(t, SetTemplateWorkers(8))
+	assert.Equal(t, 8, getTemplateWorkers())
+}
+
+func TestDownloadImages(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Path == "/missing.png" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(r.URL.Path))
+	}))
+	defer server.Close()
+
+	tmpDir, err := ioutil.TempDir("", "download")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(tmpDir)
+
+	ctx := context.Background()
+	downloads := []imageDownload{
+		{url: server.URL + "/1.png"},
+		{url: server.URL + "/2.png"},
+		{url: server.URL + "/1.png"},
+		{url: server.URL + "/3.png"},
+	}
+
+	filenames, err := downloadImages(ctx, downloads, tmpDir)
+	if assert.Nil(t, err) && assert.Len(t, filenames, 4) {
+		for i, expected := range []string{"/1.png", "/2.png", "/1.png", "/3.png"} {
+			data, err := ioutil.ReadFile(filenames[i])
+			assert.Nil(t, err)
+			assert.Equal(t, expected, string(data))
+		}
+	}
+	// The duplicated image is only downloaded once
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+
+	_, err = downloadImages(ctx, append(downloads, imageDownload{url: server.URL + "/missing.png"}), tmpDir)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,133 @@
+package tts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/jeandeaual/tts-deckconverter/log"
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+// ExternalEditorAddress is the address of the External Editor API of a
+// Tabletop Simulator instance running on the same machine.
+// See https://api.tabletopsimulator.com/externaleditorapi/
+const ExternalEditorAddress = "localhost:39999"
+
+// executeLuaCodeMessageID is the ID of the External Editor API message
+// executing a Lua script in the game.
+const executeLuaCodeMessageID = 3
+
+// spawnSpacing is the distance between the spawned decks.
+const spawnSpacing = 3.0
+
+type externalEditorMessage struct {
+	MessageID int    `json:"messageID"`
+	GUID      string `json:"guid"`
+	Script    string `json:"script"`
+}
+
+// Spawn sends the decks to the Tabletop Simulator instance listening on
+// address (usually ExternalEditorAddress), which spawns them on the table
+// next to each other. A game needs to be loaded in Tabletop Simulator.
+func Spawn(ctx context.Context, decks []*plugins.Deck, backURL, address string) []error {
+	errs := []error{}
+	position := 0
+
+	for _, deck := range decks {
+		if len(backURL) > 0 {
+			deck.BackURL = backURL
+		}
+		if len(deck.Cards) == 0 {
+			log.Infof("Deck %s is empty, skipping", deck.Name)
+			continue
+		}
+
+		object, _ := createObject(deck)
+
+		script, err := spawnScript(object, placement.spawnPosition(deck, position))
+		if err != nil {
+			errs = append(errs, fmt.Errorf("couldn't spawn deck %s: %w", deck.Name, err))
+			continue
+		}
+
+		log.Infof("Spawning %s in Tabletop Simulator", deck.Name)
+
+		err = sendExternalEditorMessage(ctx, address, externalEditorMessage{
+			MessageID: executeLuaCodeMessageID,
+			// Run the script in the global context
+			GUID:   "-1",
+			Script: script,
+		})
+		if err != nil {
+			// Tabletop Simulator is probably not running, don't try to
+			// spawn the other decks
+			return append(errs, fmt.Errorf("couldn't spawn deck %s: %w", deck.Name, err))
+		}
+
+		if !placement.ownPosition(deck) {
+			position++
+		}
+	}
+
+	return errs
+}
+
+// spawnScript returns the Lua script spawning the objects of object, the
+// first one at position (X, Y and Z) on the table.
+func spawnScript(object SavedObject, position [3]float64) (string, error) {
+	var sb strings.Builder
+
+	origin := object.ObjectStates[0].Transform
+
+	for _, state := range object.ObjectStates {
+		data, err := json.Marshal(state)
+		if err != nil {
+			return "", fmt.Errorf("couldn't marshall data: %w", err)
+		}
+
+		fmt.Fprintf(
+			&sb,
+			"spawnObjectJSON({json = %s, position = {x = %g, y = %g, z = %g}})\n",
+			luaLongString(string(data)),
+			// Keep the objects next to the first one (e.g. the counters
+			// next to their deck)
+			position[0]+state.Transform.PosX-origin.PosX,
+			position[1]+state.Transform.PosY-origin.PosY,
+			position[2]+state.Transform.PosZ-origin.PosZ,
+		)
+	}
+
+	return sb.String(), nil
+}
+
+// luaLongString returns s as a Lua long string literal, which doesn't
+// require escaping its content.
+func luaLongString(s string) string {
+	level := ""
+	for strings.Contains(s, "]"+level+"]") {
+		level += "="
+	}
+
+	return "[" + level + "[" + s + "]" + level + "]"
+}
+
+func sendExternalEditorMessage(ctx context.Context, address string, message externalEditorMessage) error {
+	var dialer net.Dialer
+
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return fmt.Errorf("couldn't connect to Tabletop Simulator on %s (is a game loaded?): %w", address, err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+	}
+
+	return json.NewEncoder(conn).Encode(message)
+}
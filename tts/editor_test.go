@@ -0,0 +1,88 @@
+package tts
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+func TestLuaLongString(t *testing.T) {
+	assert.Equal(t, "[[abc]]", luaLongString("abc"))
+	assert.Equal(t, "[=[a]]b]=]", luaLongString("a]]b"))
+	assert.Equal(t, "[==[a]]b]=]c]==]", luaLongString("a]]b]=]c"))
+}
+
+func TestSpawn(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	messages := make(chan externalEditorMessage, 2)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			var message externalEditorMessage
+			if err := json.NewDecoder(conn).Decode(&message); err == nil {
+				messages <- message
+			}
+			conn.Close()
+		}
+	}()
+
+	decks := []*plugins.Deck{
+		{
+			Name: "Main",
+			Cards: []plugins.CardInfo{
+				{Name: "Island", Count: 2, ImageURL: "https://example.com/island.png"},
+			},
+		},
+		{Name: "Empty"},
+		{
+			Name: "Commander",
+			Cards: []plugins.CardInfo{
+				{Name: "Kenrith", Count: 1, ImageURL: "https://example.com/kenrith.png"},
+			},
+		},
+	}
+
+	errs := Spawn(context.Background(), decks, "https://example.com/back.png", listener.Addr().String())
+	assert.Empty(t, errs)
+
+	message := <-messages
+	assert.Equal(t, executeLuaCodeMessageID, message.MessageID)
+	assert.Equal(t, "-1", message.GUID)
+	assert.Contains(t, message.Script, "spawnObjectJSON({json = [[")
+	assert.Contains(t, message.Script, `"Nickname":"Island"`)
+	assert.Contains(t, message.Script, "https://example.com/back.png")
+	assert.Contains(t, message.Script, "position = {x = 0,")
+
+	message = <-messages
+	assert.Contains(t, message.Script, `"Nickname":"Kenrith"`)
+	assert.Contains(t, message.Script, "position = {x = 3,")
+
+	assert.Nil(t, SetPlacement(Placement{Position: &[3]float64{-10, 1, 5}}))
+	defer func() {
+		assert.Nil(t, SetPlacement(Placement{}))
+	}()
+
+	errs = Spawn(context.Background(), decks[:1], "", listener.Addr().String())
+	assert.Empty(t, errs)
+
+	message = <-messages
+	assert.Contains(t, message.Script, "position = {x = -10, y = 1, z = 5}")
+
+	listener.Close()
+
+	errs = Spawn(context.Background(), decks, "", listener.Addr().String())
+	assert.Len(t, errs, 1)
+}
@@ -0,0 +1,146 @@
+package tts
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/jeandeaual/tts-deckconverter/log"
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+// Styles of the generated file names, set with SetFileNameStyle.
+const (
+	// FileNameStyleDefault only replaces the characters which can't be used
+	// in a file name.
+	FileNameStyleDefault = "default"
+	// FileNameStyleASCII also transliterates the file names to ASCII (e.g.
+	// "Jötun Grunt" becomes "Jotun Grunt"), for the tools and file systems
+	// which don't support Unicode.
+	FileNameStyleASCII = "ascii"
+)
+
+var fileNameStyle = FileNameStyleDefault
+
+var filepathReplacer = strings.NewReplacer(
+	// Illegal on Linux/Unix and Windows
+	"/", "-",
+	// Illegal on Windows
+	"\\", "-",
+	":", "-",
+	"*", "-",
+	"?", "-",
+	"\"", "-",
+	"<", "(",
+	">", ")",
+	"|", "-",
+)
+
+// asciiReplacer transliterates the letters which aren't decomposed into an
+// ASCII letter and a diacritic.
+var asciiReplacer = strings.NewReplacer(
+	"ß", "ss",
+	"æ", "ae",
+	"Æ", "AE",
+	"œ", "oe",
+	"Œ", "OE",
+	"ø", "o",
+	"Ø", "O",
+	"ł", "l",
+	"Ł", "L",
+	"đ", "d",
+	"Đ", "D",
+	"þ", "th",
+	"Þ", "Th",
+)
+
+// windowsReservedNames are the file names which can't be used on Windows,
+// whatever their extension.
+var windowsReservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// SetFileNameStyle changes the style of the file names returned by FileName.
+func SetFileNameStyle(style string) error {
+	switch style {
+	case FileNameStyleDefault, FileNameStyleASCII:
+		fileNameStyle = style
+		return nil
+	default:
+		return errors.New("invalid file name style: " + style)
+	}
+}
+
+// FileName returns name without the characters which can't be used in a
+// file name, with a normalized typography (see plugins.NormalizeName).
+// The names reserved on Windows (e.g. "CON") are suffixed with "_", since the
+// generated files can be shared between platforms.
+func FileName(name string) string {
+	name = filepathReplacer.Replace(plugins.NormalizeName(name))
+
+	if fileNameStyle == FileNameStyleASCII {
+		name = transliterate(name)
+	}
+
+	// Windows doesn't allow file names ending with a dot or a space
+	name = strings.TrimRight(name, ". ")
+	if len(name) == 0 {
+		return "_"
+	}
+
+	if windowsReservedNames[strings.ToUpper(strings.SplitN(name, ".", 2)[0])] {
+		name += "_"
+	}
+
+	return name
+}
+
+// transliterate returns name with its letters converted to ASCII. The
+// characters which can't be converted are replaced with "_".
+func transliterate(name string) string {
+	var sb strings.Builder
+
+	for _, r := range norm.NFD.String(asciiReplacer.Replace(name)) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// Diacritic, e.g. the umlaut of "ö"
+			continue
+		case r < unicode.MaxASCII && unicode.IsPrint(r):
+			sb.WriteRune(r)
+		case unicode.IsSpace(r):
+			sb.WriteRune(' ')
+		default:
+			sb.WriteRune('_')
+		}
+	}
+
+	return sb.String()
+}
+
+// DeduplicateFileNames renames the decks whose file name (see FileName) is the
+// same as the one of a previous deck, by appending " (2)", " (3)", etc. to
+// their name, so that their files don't overwrite each other.
+// The file names are compared case-insensitively, like on Windows and macOS.
+func DeduplicateFileNames(decks []*plugins.Deck) {
+	used := make(map[string]bool, len(decks))
+
+	for _, deck := range decks {
+		name := deck.Name
+		for i := 2; used[strings.ToLower(FileName(name))]; i++ {
+			name = deck.Name + " (" + strconv.Itoa(i) + ")"
+		}
+
+		if name != deck.Name {
+			log.Warnf("Renaming deck %s to %s, since another deck has the same file name", deck.Name, name)
+			deck.Name = name
+		}
+		used[strings.ToLower(FileName(name))] = true
+	}
+}
@@ -0,0 +1,48 @@
+package tts
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+func TestFileName(t *testing.T) {
+	assert.Equal(t, "Fire-Ice", FileName("Fire/Ice"))
+	assert.Equal(t, "Deck (v2) - Sideboard", FileName("Deck <v2> - Sideboard"))
+	assert.Equal(t, "Lim-Dûl's Vault", FileName("Lim-Dûl’s Vault"))
+	assert.Equal(t, "What", FileName("What.. "))
+	assert.Equal(t, "CON_", FileName("CON"))
+	assert.Equal(t, "nul.txt_", FileName("nul.txt"))
+	assert.Equal(t, "_", FileName("..."))
+}
+
+func TestFileNameASCII(t *testing.T) {
+	assert.Nil(t, SetFileNameStyle(FileNameStyleASCII))
+	defer func() {
+		assert.Nil(t, SetFileNameStyle(FileNameStyleDefault))
+	}()
+
+	assert.Equal(t, "Lim-Dul's Vault", FileName("Lim-Dûl’s Vault"))
+	assert.Equal(t, "AEther Vial", FileName("Æther Vial"))
+	assert.Equal(t, "___", FileName("青眼の"))
+
+	assert.NotNil(t, SetFileNameStyle("unknown"))
+}
+
+func TestDeduplicateFileNames(t *testing.T) {
+	decks := []*plugins.Deck{
+		{Name: "Fire/Ice"},
+		{Name: "Fire:Ice"},
+		{Name: "fire-ice"},
+		{Name: "Other"},
+	}
+
+	DeduplicateFileNames(decks)
+
+	assert.Equal(t, "Fire/Ice", decks[0].Name)
+	assert.Equal(t, "Fire:Ice (2)", decks[1].Name)
+	assert.Equal(t, "fire-ice (3)", decks[2].Name)
+	assert.Equal(t, "Other", decks[3].Name)
+}
@@ -0,0 +1,133 @@
+package tts
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+var (
+	guidMapFile  string
+	guidMapMutex sync.Mutex
+)
+
+// SetGUIDMapFile sets the JSON file mapping the name of each object
+// generated by Generate to its GUID, so that companion Lua scripts can
+// reference the generated decks (e.g. with getObjectFromGUID). The file is
+// updated after each call to Generate, keeping the objects generated by the
+// previous calls. No file is written if path is empty.
+func SetGUIDMapFile(path string) {
+	guidMapMutex.Lock()
+	defer guidMapMutex.Unlock()
+
+	guidMapFile = path
+}
+
+// objectGUID returns the GUID of the object called name. It is derived from
+// the name, so that the objects keep the same GUID across conversions.
+func objectGUID(name string) string {
+	sum := md5.Sum([]byte(name))
+	return hex.EncodeToString(sum[:3])
+}
+
+// objectName returns the name of the index-th object generated for deck:
+// the deck itself is named after it, and the objects placed next to it (e.g.
+// the counters or the tiles) after the deck and their nickname, numbered if
+// several objects have the same nickname (e.g. "Deck - Card 2").
+func objectName(deck *plugins.Deck, object SavedObject, index int) string {
+	if index == 0 {
+		return deck.Name
+	}
+
+	nickname := object.ObjectStates[index].Nickname
+	name := deck.Name + " - " + nickname
+
+	count := 1
+	for _, state := range object.ObjectStates[1:index] {
+		if state.Nickname == nickname {
+			count++
+		}
+	}
+	if count > 1 {
+		name += " " + strconv.Itoa(count)
+	}
+
+	return name
+}
+
+// assignGUIDs sets the GUID of each object generated for deck, derived from
+// its name (see objectName).
+func assignGUIDs(deck *plugins.Deck, object *SavedObject) {
+	for i := range object.ObjectStates {
+		object.ObjectStates[i].GUID = objectGUID(objectName(deck, *object, i))
+	}
+}
+
+// guidMap returns the GUIDs of the objects generated for deck, indexed by
+// their name.
+func guidMap(deck *plugins.Deck, object SavedObject) map[string]string {
+	guids := make(map[string]string, len(object.ObjectStates))
+
+	for i, state := range object.ObjectStates {
+		guids[objectName(deck, object, i)] = state.GUID
+	}
+
+	return guids
+}
+
+// writeGUIDMap adds guids to the GUID map file set with SetGUIDMapFile, if
+// any.
+func writeGUIDMap(guids map[string]string) error {
+	guidMapMutex.Lock()
+	defer guidMapMutex.Unlock()
+
+	if len(guidMapFile) == 0 || len(guids) == 0 {
+		return nil
+	}
+
+	merged := make(map[string]string)
+
+	data, err := ioutil.ReadFile(guidMapFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &merged); err != nil {
+			return fmt.Errorf("couldn't parse the GUID map %s: %w", guidMapFile, err)
+		}
+	}
+
+	for name, guid := range guids {
+		merged[name] = guid
+	}
+
+	// Check for collisions between the names (sorted so that the error is
+	// deterministic)
+	names := make([]string, 0, len(merged))
+	for name := range merged {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	seen := make(map[string]string, len(names))
+	for _, name := range names {
+		if other, found := seen[merged[name]]; found {
+			return fmt.Errorf("%s and %s have the same GUID (%s), rename one of them", other, name, merged[name])
+		}
+		seen[merged[name]] = name
+	}
+
+	data, err = json.MarshalIndent(merged, "", "  ")
+	if err != nil {
+		return fmt.Errorf("couldn't marshall data: %w", err)
+	}
+
+	return WriteFile(guidMapFile, data)
+}
@@ -0,0 +1,63 @@
+package tts
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+func TestObjectGUID(t *testing.T) {
+	assert.Equal(t, "2b487b", objectGUID("Deck"))
+	assert.Equal(t, objectGUID("Deck"), objectGUID("Deck"))
+	assert.NotEqual(t, objectGUID("Deck"), objectGUID("Deck - Sideboard"))
+}
+
+func TestAssignGUIDs(t *testing.T) {
+	deck := &plugins.Deck{Name: "Deck"}
+	object := SavedObject{
+		ObjectStates: []Object{
+			{Nickname: "Deck"},
+			{Nickname: "Life"},
+			{Nickname: "Life"},
+		},
+	}
+
+	assignGUIDs(deck, &object)
+
+	assert.Equal(t, objectGUID("Deck"), object.ObjectStates[0].GUID)
+	assert.Equal(t, objectGUID("Deck - Life"), object.ObjectStates[1].GUID)
+	// The objects with the same nickname are numbered
+	assert.Equal(t, objectGUID("Deck - Life 2"), object.ObjectStates[2].GUID)
+	assert.Equal(t, map[string]string{
+		"Deck":          objectGUID("Deck"),
+		"Deck - Life":   objectGUID("Deck - Life"),
+		"Deck - Life 2": objectGUID("Deck - Life 2"),
+	}, guidMap(deck, object))
+}
+
+func TestWriteGUIDMap(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "guids")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "guids.json")
+	SetGUIDMapFile(path)
+	defer SetGUIDMapFile("")
+
+	assert.Nil(t, writeGUIDMap(map[string]string{"Deck": "aaaaaa"}))
+	assert.Nil(t, writeGUIDMap(map[string]string{"Other": "bbbbbb"}))
+
+	data, err := ioutil.ReadFile(path)
+	if assert.Nil(t, err) {
+		assert.JSONEq(t, `{"Deck": "aaaaaa", "Other": "bbbbbb"}`, string(data))
+	}
+
+	assert.NotNil(t, writeGUIDMap(map[string]string{"Another": "aaaaaa"}))
+}
@@ -1,15 +1,20 @@
 package tts
 
 import (
+	"context"
 	"fmt"
 	"image"
 	"image/color"
 	"io"
+	"io/ioutil"
 	"net/http"
+	"net/url"
+	"os"
 
 	"github.com/disintegration/imaging"
 
 	"github.com/jeandeaual/tts-deckconverter/log"
+	"github.com/jeandeaual/tts-deckconverter/plugins"
 )
 
 const (
@@ -24,17 +29,17 @@ var (
 	white       color.Color = color.NRGBA{0xff, 0xff, 0xff, 0xff}
 )
 
-func downloadAndCreateThumbnail(url, filename string) (err error) {
+func downloadAndCreateThumbnail(ctx context.Context, url, filename string) (err error) {
 	log.Debugf("Querying %s", url)
 
 	// Build the request
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		err = fmt.Errorf("couldn't create request for %s: %w", url, err)
 		return
 	}
 
-	client := &http.Client{}
+	client := plugins.HTTPClient()
 
 	// Send the request
 	resp, err := client.Do(req)
@@ -48,11 +53,44 @@ func downloadAndCreateThumbnail(url, filename string) (err error) {
 		}
 	}()
 
-	err = generateThumbnail(resp.Body, filename)
+	body := &countingReader{reader: resp.Body}
+
+	err = generateThumbnail(body, filename)
+	if err != nil {
+		return
+	}
+
+	plugins.RecordDownload(body.count)
+	plugins.ReportImageDownloaded(ctx, url, body.count)
+	reportFileWritten(ctx, filename)
 
 	return
 }
 
+// countingReader counts the number of bytes read from reader.
+type countingReader struct {
+	reader io.Reader
+	count  int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.count += int64(n)
+	return n, err
+}
+
+// reportFileWritten notifies the progress reporter of ctx that the file
+// located at path has been generated.
+func reportFileWritten(ctx context.Context, path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		log.Debugf("Couldn't check the size of %s: %v", path, err)
+		return
+	}
+
+	plugins.ReportFileWritten(ctx, path, info.Size())
+}
+
 func generateThumbnail(source io.Reader, filename string) error {
 	// Open the source image
 	cardThumb, err := imaging.Decode(source)
@@ -74,10 +112,38 @@ func generateThumbnail(source io.Reader, filename string) error {
 	)
 
 	// Save the resulting image as PNG
-	err = imaging.Save(background, filename)
+	err = writePartial(filename, func(partial string) error {
+		return imaging.Save(background, partial)
+	})
 	if err != nil {
 		return fmt.Errorf("failed to save image: %w", err)
 	}
 
 	return nil
 }
+
+// SaveImage writes the image located at source to path. source is either a
+// URL or a local file, e.g. a template generated with the manual uploader.
+// path is overwritten if it already exists.
+func SaveImage(ctx context.Context, source, path string) error {
+	if u, err := url.Parse(source); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return downloadFile(ctx, source, path, false)
+	}
+
+	data, err := ioutil.ReadFile(source)
+	if err != nil {
+		return err
+	}
+
+	err = WriteFile(path, data)
+	if err != nil {
+		return err
+	}
+
+	plugins.ReportFileWritten(ctx, path, int64(len(data)))
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package tts
+
+import (
+	"strconv"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+// mysteryFace returns the custom deck showing the image used instead of the
+// faces of the cards of a mystery deck.
+func mysteryFace(deck *plugins.Deck) CustomDeck {
+	faceURL := deck.MysteryFaceURL
+	if len(faceURL) == 0 {
+		faceURL = deck.BackURL
+	}
+
+	face := CustomDeck{
+		FaceURL:      faceURL,
+		BackURL:      deck.BackURL,
+		NumWidth:     1,
+		NumHeight:    1,
+		BackIsHidden: true,
+		UniqueBack:   false,
+		Type:         DeckShapeRectangleRounded,
+	}
+	if !deck.Rounded {
+		face.Type = DeckShapeRectangle
+	}
+
+	return face
+}
+
+// nextCustomDeckID returns an ID which isn't used by the custom decks of
+// object.
+func nextCustomDeckID(object Object) int {
+	next := 1
+
+	for key := range object.CustomDeck {
+		if id, err := strconv.Atoi(key); err == nil && id >= next {
+			next = id + 1
+		}
+	}
+
+	return next
+}
+
+// hideFaces replaces the faces of the cards of object (a deck or a single
+// card) with face. The actual cards are moved to the second state of each
+// card, and their other states are shifted by one.
+func hideFaces(object *Object, face CustomDeck) {
+	id := nextCustomDeckID(*object)
+
+	if object.ObjectType != DeckObject {
+		*object = hideCard(*object, id, face)
+		return
+	}
+
+	for i, card := range object.ContainedObjects {
+		object.ContainedObjects[i] = hideCard(card, id, face)
+	}
+	for i := range object.DeckIDs {
+		object.DeckIDs[i] = 100 * id
+	}
+	object.CustomDeck = CustomDeckMap{
+		strconv.Itoa(id): face,
+	}
+}
+
+// hideCard returns a copy of card showing face, with card as its second
+// state.
+func hideCard(card Object, id int, face CustomDeck) Object {
+	states := map[string]Object{}
+	for key, state := range card.States {
+		if index, err := strconv.Atoi(key); err == nil {
+			states[strconv.Itoa(index+1)] = state
+		}
+	}
+
+	hidden := card
+	hidden.Nickname = ""
+	hidden.Description = ""
+	hidden.GMNotes = ""
+	hidden.SidewaysCard = false
+	hidden.CardID = 100 * id
+	hidden.CustomDeck = CustomDeckMap{
+		strconv.Itoa(id): face,
+	}
+
+	card.States = nil
+	states["2"] = card
+	hidden.States = states
+
+	return hidden
+}
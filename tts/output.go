@@ -0,0 +1,119 @@
+package tts
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+// OutputLayout is the way Generate groups the decks into saved objects.
+type OutputLayout int
+
+const (
+	// OutputSplit generates a saved object for each deck, named after the
+	// deck (e.g. "Deck.json" and "Deck - Sideboard.json").
+	OutputSplit OutputLayout = iota
+	// OutputCombined generates a single saved object containing every deck
+	// next to each other, named after the first deck.
+	OutputCombined
+)
+
+var (
+	outputLayout      = OutputSplit
+	outputLayoutMutex sync.Mutex
+	outputWriter      io.Writer
+	outputWriterMutex sync.Mutex
+)
+
+// SetOutputLayout changes the way Generate groups the decks into saved
+// objects (OutputSplit by default).
+func SetOutputLayout(layout OutputLayout) {
+	outputLayoutMutex.Lock()
+	defer outputLayoutMutex.Unlock()
+
+	outputLayout = layout
+}
+
+func getOutputLayout() OutputLayout {
+	outputLayoutMutex.Lock()
+	defer outputLayoutMutex.Unlock()
+
+	return outputLayout
+}
+
+// SetOutputWriter makes Generate write a single saved object containing
+// every deck (see OutputCombined) to w, e.g. os.Stdout, instead of writing
+// files inside the output folder. No thumbnail is generated.
+// The files are written again if w is nil.
+func SetOutputWriter(w io.Writer) {
+	outputWriterMutex.Lock()
+	defer outputWriterMutex.Unlock()
+
+	outputWriter = w
+}
+
+func getOutputWriter() io.Writer {
+	outputWriterMutex.Lock()
+	defer outputWriterMutex.Unlock()
+
+	return outputWriter
+}
+
+// createCombined writes a single saved object containing the objects of
+// every deck, and its thumbnail, inside outputFolder. Each deck is placed on
+// the right of the previous one (unless its section has its own position, see
+// Placement.Sections), and named after its deck so that they can
+// be told apart once spawned. The saved object and its thumbnail are named
+// after the first deck.
+// It returns the GUIDs of the generated objects indexed by their name.
+func createCombined(ctx context.Context, decks []*plugins.Deck, outputFolder string, indent bool) (map[string]string, error) {
+	combined, thumbnailSource := createObject(decks[0])
+	nameDeckObject(decks[0], &combined)
+	guids := guidMap(decks[0], combined)
+
+	for _, deck := range decks[1:] {
+		object, _ := createObject(deck)
+		nameDeckObject(deck, &object)
+		for name, guid := range guidMap(deck, object) {
+			guids[name] = guid
+		}
+
+		offset := 0.0
+		if !placement.ownPosition(deck) {
+			offset = rightmostPosition(combined) + spawnSpacing - object.ObjectStates[0].Transform.PosX
+		}
+		for _, state := range object.ObjectStates {
+			state.Transform.PosX += offset
+			combined.ObjectStates = append(combined.ObjectStates, state)
+		}
+	}
+
+	if err := writeObject(ctx, decks[0].Name, combined, thumbnailSource, outputFolder, indent); err != nil {
+		return nil, err
+	}
+
+	return guids, nil
+}
+
+// nameDeckObject sets the nickname of the object of deck (its first object),
+// unless it already has one (e.g. a deck box).
+func nameDeckObject(deck *plugins.Deck, object *SavedObject) {
+	if len(object.ObjectStates[0].Nickname) == 0 {
+		object.ObjectStates[0].Nickname = deck.Name
+	}
+}
+
+// rightmostPosition returns the highest X position of the objects of object.
+func rightmostPosition(object SavedObject) float64 {
+	rightmost := object.ObjectStates[0].Transform.PosX
+
+	for _, state := range object.ObjectStates[1:] {
+		if state.Transform.PosX > rightmost {
+			rightmost = state.Transform.PosX
+		}
+	}
+
+	return rightmost
+}
@@ -0,0 +1,124 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/disintegration/imaging"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+func TestGenerateOutputLayout(t *testing.T) {
+	defer SetOutputLayout(OutputSplit)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = png.Encode(w, imaging.New(10, 15, color.NRGBA{0xff, 0, 0, 0xff}))
+	}))
+	defer server.Close()
+
+	card := func(name string) plugins.CardInfo {
+		return plugins.CardInfo{Name: name, ImageURL: server.URL + "/" + name + ".png", Count: 2}
+	}
+	decks := []*plugins.Deck{
+		{Name: "Deck", Cards: []plugins.CardInfo{card("a")}},
+		{Name: "Deck - Sideboard", Cards: []plugins.CardInfo{card("b")}},
+		{Name: "Deck - Maybeboard"},
+		{Name: "Deck - Tokens", Cards: []plugins.CardInfo{card("c")}},
+	}
+
+	generate := func(layout OutputLayout) (string, []string) {
+		dir, err := ioutil.TempDir("", "output")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		SetOutputLayout(layout)
+		errs := Generate(context.Background(), decks, "", dir, false)
+		assert.Empty(t, errs)
+
+		files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+		assert.Nil(t, err)
+		for i, file := range files {
+			files[i] = filepath.Base(file)
+		}
+
+		return dir, files
+	}
+
+	dir, files := generate(OutputSplit)
+	defer os.RemoveAll(dir)
+	assert.ElementsMatch(t, []string{"Deck.json", "Deck - Sideboard.json", "Deck - Tokens.json"}, files)
+
+	dir, files = generate(OutputCombined)
+	defer os.RemoveAll(dir)
+	assert.Equal(t, []string{"Deck.json"}, files)
+	assert.FileExists(t, filepath.Join(dir, "Deck.png"))
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "Deck.json"))
+	if !assert.Nil(t, err) {
+		return
+	}
+	var object SavedObject
+	if !assert.Nil(t, json.Unmarshal(data, &object)) {
+		return
+	}
+
+	if assert.Len(t, object.ObjectStates, 3) {
+		assert.Equal(t, "Deck", object.ObjectStates[0].Nickname)
+		assert.Equal(t, "Deck - Sideboard", object.ObjectStates[1].Nickname)
+		assert.Equal(t, "Deck - Tokens", object.ObjectStates[2].Nickname)
+		// The decks are placed next to each other
+		assert.Equal(t, object.ObjectStates[0].Transform.PosX+spawnSpacing, object.ObjectStates[1].Transform.PosX)
+		assert.Equal(t, object.ObjectStates[1].Transform.PosX+spawnSpacing, object.ObjectStates[2].Transform.PosX)
+		// Each deck keeps its GUID
+		assert.Equal(t, objectGUID("Deck - Sideboard"), object.ObjectStates[1].GUID)
+	}
+}
+
+func TestGenerateOutputWriter(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutputWriter(&buf)
+	defer SetOutputWriter(nil)
+
+	dir, err := ioutil.TempDir("", "output")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	card := func(name string) plugins.CardInfo {
+		return plugins.CardInfo{Name: name, ImageURL: "https://example.com/" + name + ".png", Count: 2}
+	}
+	decks := []*plugins.Deck{
+		{Name: "Deck", Cards: []plugins.CardInfo{card("a")}},
+		{Name: "Deck - Sideboard", Cards: []plugins.CardInfo{card("b")}},
+	}
+
+	// The decks are combined even with the split layout
+	errs := Generate(context.Background(), decks, "", dir, false)
+	assert.Empty(t, errs)
+
+	var object SavedObject
+	decoder := json.NewDecoder(&buf)
+	if assert.Nil(t, decoder.Decode(&object)) {
+		assert.Len(t, object.ObjectStates, 2)
+	}
+	// A single JSON document is written
+	assert.False(t, decoder.More())
+
+	// Neither the saved object nor its thumbnail are written to the folder
+	files, err := ioutil.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Empty(t, files)
+}
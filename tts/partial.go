@@ -0,0 +1,44 @@
+package tts
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// partialSuffix is added to the name of the files being written (before
+// their extension, since the image encoders use it to find the format).
+// They are renamed once complete, so that an interrupted conversion doesn't
+// leave a truncated saved object or template sheet behind, and the files
+// left by a killed process are clearly marked.
+const partialSuffix = ".partial"
+
+// partialPath returns the path of the temporary file used to write path.
+func partialPath(path string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + partialSuffix + ext
+}
+
+// writePartial calls write with the path of a temporary file located next to
+// path, and renames it to path if write succeeds. The temporary file is
+// removed otherwise.
+func writePartial(path string, write func(partial string) error) error {
+	partial := partialPath(path)
+
+	if err := write(partial); err != nil {
+		_ = os.Remove(partial)
+		return err
+	}
+
+	return os.Rename(partial, path)
+}
+
+// WriteFile writes data to path like ioutil.WriteFile, but through a
+// temporary file renamed once complete, so that path is never truncated if
+// the conversion is interrupted.
+func WriteFile(path string, data []byte) error {
+	return writePartial(path, func(partial string) error {
+		return ioutil.WriteFile(partial, data, 0644)
+	})
+}
@@ -0,0 +1,45 @@
+package tts
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPartialPath(t *testing.T) {
+	assert.Equal(t, filepath.Join("out", "Deck.partial.json"), partialPath(filepath.Join("out", "Deck.json")))
+	assert.Equal(t, "Deck - Template.partial.jpg", partialPath("Deck - Template.jpg"))
+}
+
+func TestWritePartial(t *testing.T) {
+	dir, err := ioutil.TempDir("", "partial")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "Deck.json")
+
+	assert.Nil(t, WriteFile(path, []byte("{}")))
+	data, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "{}", string(data))
+
+	// The previous file is kept if the new one can't be written completely
+	err = writePartial(path, func(partial string) error {
+		assert.Nil(t, ioutil.WriteFile(partial, []byte("{"), 0644))
+		return errors.New("interrupted")
+	})
+	assert.NotNil(t, err)
+	data, err = ioutil.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, "{}", string(data))
+
+	files, err := ioutil.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Len(t, files, 1)
+}
@@ -0,0 +1,141 @@
+package tts
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+// Placement sets the initial transform of the generated objects, e.g. so
+// that they spawn where the scripts of a table expect them.
+type Placement struct {
+	// Position of the objects (X, Y and Z), the center of the table if nil.
+	Position *[3]float64
+	// Rotation of the objects in degrees (X, Y and Z), replacing the facing
+	// of the decks if set.
+	Rotation *[3]float64
+	// Scale multiplies the scale of the objects (1 if 0), which depends on
+	// the size of the cards.
+	Scale float64
+	// Sections overrides the placement of the decks of a section, keyed by
+	// the section in lower case (e.g. "sideboard" for "Deck - Sideboard").
+	// Only the values set override the placement of every deck. A deck with
+	// its own position isn't placed next to the other decks.
+	Sections map[string]Placement
+}
+
+var placement = Placement{}
+
+// SetPlacement changes the initial transform of the objects generated by
+// Generate and Spawn.
+func SetPlacement(p Placement) error {
+	if p.Scale < 0 {
+		return fmt.Errorf("invalid scale: %g", p.Scale)
+	}
+	for section, sp := range p.Sections {
+		if sp.Scale < 0 {
+			return fmt.Errorf("invalid scale for %s: %g", section, sp.Scale)
+		}
+	}
+
+	placement = p
+
+	return nil
+}
+
+// section returns the placement of the section of deck (see Sections), if
+// any.
+func (p Placement) section(deck *plugins.Deck) (Placement, bool) {
+	name := strings.ToLower(deck.Name)
+
+	for section, sp := range p.Sections {
+		if strings.HasSuffix(name, " - "+section) {
+			return sp, true
+		}
+	}
+
+	return Placement{}, false
+}
+
+// forDeck returns the placement of deck, overridden by the placement of its
+// section.
+func (p Placement) forDeck(deck *plugins.Deck) Placement {
+	result := Placement{Position: p.Position, Rotation: p.Rotation, Scale: p.Scale}
+
+	if sp, found := p.section(deck); found {
+		if sp.Position != nil {
+			result.Position = sp.Position
+		}
+		if sp.Rotation != nil {
+			result.Rotation = sp.Rotation
+		}
+		if sp.Scale > 0 {
+			result.Scale = sp.Scale
+		}
+	}
+
+	return result
+}
+
+// ownPosition returns true if the section of deck has its own position, in
+// which case the deck isn't placed next to the other decks.
+func (p Placement) ownPosition(deck *plugins.Deck) bool {
+	sp, found := p.section(deck)
+
+	return found && sp.Position != nil
+}
+
+// apply changes the transform of object following p. The cards contained
+// in a deck are scaled too, so that they keep their size once drawn.
+func (p Placement) apply(object *Object) {
+	if p.Position != nil {
+		object.Transform.PosX = p.Position[0]
+		object.Transform.PosY = p.Position[1]
+		object.Transform.PosZ = p.Position[2]
+	}
+
+	if p.Rotation != nil {
+		object.Transform.RotX = p.Rotation[0]
+		object.Transform.RotY = p.Rotation[1]
+		object.Transform.RotZ = p.Rotation[2]
+	}
+
+	if p.Scale > 0 && p.Scale != 1 {
+		scaleObject(object, p.Scale)
+	}
+}
+
+// scaleObject multiplies the scale of object, of the objects it contains
+// and of its states by scale.
+func scaleObject(object *Object, scale float64) {
+	object.Transform.ScaleX *= scale
+	object.Transform.ScaleY *= scale
+	object.Transform.ScaleZ *= scale
+
+	for i := range object.ContainedObjects {
+		scaleObject(&object.ContainedObjects[i], scale)
+	}
+	for key, state := range object.States {
+		scaleObject(&state, scale)
+		object.States[key] = state
+	}
+}
+
+// spawnPosition returns the position of deck, the index-th object spawned by
+// Spawn. The objects are placed next to each other, starting from the
+// position of the placement if set, unless the section of deck has its own
+// position.
+func (p Placement) spawnPosition(deck *plugins.Deck, index int) [3]float64 {
+	if p.ownPosition(deck) {
+		return *p.forDeck(deck).Position
+	}
+
+	position := [3]float64{0, 2, 0}
+	if p.Position != nil {
+		position = *p.Position
+	}
+	position[0] += float64(index) * spawnSpacing
+
+	return position
+}
@@ -0,0 +1,202 @@
+package tts
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/disintegration/imaging"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+
+	"github.com/jeandeaual/tts-deckconverter/log"
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+const (
+	// referenceCardWidth and referenceCardHeight are the dimensions of the
+	// reference cards (the size of the PNG images of Scryfall).
+	referenceCardWidth  = 745
+	referenceCardHeight = 1040
+	// referenceCardMargin is the margin around the text of the reference
+	// cards, in characters.
+	referenceCardMargin = 2
+)
+
+var (
+	// renderedImages contains the paths of the images rendered by
+	// NewReferenceDeck. They are resized to the size of the other cards of
+	// their template, whatever their ratio.
+	renderedImages      = make(map[string]bool)
+	renderedImagesMutex sync.Mutex
+)
+
+// isRenderedImage checks if the image located at path was rendered by
+// NewReferenceDeck.
+func isRenderedImage(path string) bool {
+	renderedImagesMutex.Lock()
+	defer renderedImagesMutex.Unlock()
+
+	return renderedImages[path]
+}
+
+// referenceCellSize returns the size of the template cells of the reference
+// cards of size, when their template doesn't contain any other card: they
+// keep the width of the reference cards with the ratio of size.
+func referenceCellSize(size plugins.CardSize) (width, height int) {
+	if size == plugins.CardSizeStandard {
+		return referenceCardWidth, referenceCardHeight
+	}
+
+	cardWidth, cardHeight := size.Dimensions()
+	return referenceCardWidth, int(math.Round(referenceCardWidth * cardHeight / cardWidth))
+}
+
+// NewReferenceDeck returns a deck containing a single card showing text (e.g.
+// the quick rules of a format or the notes of a deck), so that the table has
+// a rules reference object. The image of the card is written inside
+// outputFolder, and can only be used in the templates generated by
+// GenerateTemplates. The deck uses the name, back and card size of deck.
+func NewReferenceDeck(ctx context.Context, deck *plugins.Deck, text, outputFolder string) (*plugins.Deck, error) {
+	name := deck.Name + " - Reference"
+	path, err := filepath.Abs(filepath.Join(outputFolder, FileName(name)+".png"))
+	if err != nil {
+		return nil, err
+	}
+
+	card := renderReferenceCard(deck.Name, text)
+	err = writePartial(path, func(partial string) error {
+		return imaging.Save(card, partial)
+	})
+	if err != nil {
+		return nil, err
+	}
+	reportFileWritten(ctx, path)
+
+	renderedImagesMutex.Lock()
+	renderedImages[path] = true
+	renderedImagesMutex.Unlock()
+
+	return &plugins.Deck{
+		Name: name,
+		Cards: []plugins.CardInfo{
+			{
+				Name:        name,
+				Description: text,
+				ImageURL:    path,
+				Count:       1,
+			},
+		},
+		BackURL:   deck.BackURL,
+		CardSize:  deck.CardSize,
+		Rounded:   deck.Rounded,
+		Facing:    plugins.FacingUp,
+		SourceURL: deck.SourceURL,
+		Author:    deck.Author,
+	}, nil
+}
+
+// AddReferenceDeck appends a reference deck showing text (see
+// NewReferenceDeck) to decks, using the first deck as its model. decks is
+// returned as is if text or decks are empty.
+func AddReferenceDeck(ctx context.Context, decks []*plugins.Deck, text, outputFolder string) ([]*plugins.Deck, error) {
+	if len(text) == 0 || len(decks) == 0 {
+		return decks, nil
+	}
+
+	referenceDeck, err := NewReferenceDeck(ctx, decks[0], text, outputFolder)
+	if err != nil {
+		return decks, fmt.Errorf("couldn't generate the reference card: %w", err)
+	}
+
+	return append(decks, referenceDeck), nil
+}
+
+// renderReferenceCard returns the image of a reference card showing title
+// and text. The text is written as large as possible, and truncated if it
+// doesn't fit the card even at the smallest size.
+func renderReferenceCard(title, text string) image.Image {
+	face := basicfont.Face7x13
+
+	var (
+		scale int
+		lines []string
+	)
+
+	// The text is rendered on a smaller image, enlarged afterwards
+	for scale = 3; scale >= 1; scale-- {
+		columns := referenceCardWidth/scale/face.Advance - 2*referenceCardMargin
+		rows := referenceCardHeight/scale/face.Height - 2*referenceCardMargin
+
+		lines = append([]string{title, ""}, wrapText(text, columns)...)
+		if len(lines) <= rows {
+			break
+		}
+		if scale == 1 {
+			log.Warnf("The text of the reference card %s is too long, truncating it", title)
+			lines = lines[:rows]
+		}
+	}
+	if scale == 0 {
+		scale = 1
+	}
+
+	card := imaging.New(referenceCardWidth/scale, referenceCardHeight/scale, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+	drawer := font.Drawer{
+		Dst:  card,
+		Src:  image.Black,
+		Face: face,
+	}
+
+	for i, line := range lines {
+		drawer.Dot = fixed.P(
+			referenceCardMargin*face.Advance,
+			(referenceCardMargin+i)*face.Height+face.Ascent,
+		)
+		drawer.DrawString(line)
+	}
+
+	return imaging.Resize(card, referenceCardWidth, referenceCardHeight, imaging.NearestNeighbor)
+}
+
+// wrapText splits text in lines of up to columns characters, breaking the
+// lines between words when possible.
+func wrapText(text string, columns int) []string {
+	var lines []string
+
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		line := ""
+
+		for _, word := range strings.Fields(paragraph) {
+			for len([]rune(word)) > columns {
+				if len(line) > 0 {
+					lines = append(lines, line)
+					line = ""
+				}
+				runes := []rune(word)
+				lines = append(lines, string(runes[:columns]))
+				word = string(runes[columns:])
+			}
+
+			switch {
+			case len(line) == 0:
+				line = word
+			case len([]rune(line))+1+len([]rune(word)) <= columns:
+				line += " " + word
+			default:
+				lines = append(lines, line)
+				line = word
+			}
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines
+}
@@ -0,0 +1,96 @@
+package tts
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+func TestWrapText(t *testing.T) {
+	assert.Equal(
+		t,
+		[]string{"Each player", "starts at 40", "life.", "", "abcdefghijkl", "m"},
+		wrapText("Each player starts at 40 life.\n\nabcdefghijklm", 12),
+	)
+}
+
+func TestReferenceCellSize(t *testing.T) {
+	width, height := referenceCellSize(plugins.CardSizeStandard)
+	assert.Equal(t, referenceCardWidth, width)
+	assert.Equal(t, referenceCardHeight, height)
+
+	width, height = referenceCellSize(plugins.CardSizeSquare)
+	assert.Equal(t, referenceCardWidth, width)
+	assert.Equal(t, referenceCardWidth, height)
+
+	width, height = referenceCellSize(plugins.CardSizeTarot)
+	assert.Equal(t, referenceCardWidth, width)
+	assert.Equal(t, 1277, height)
+}
+
+func TestNewReferenceDeck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reference")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	deck := &plugins.Deck{
+		Name:     "Commander",
+		BackURL:  "https://example.com/back.jpg",
+		CardSize: plugins.CardSizeStandard,
+	}
+
+	reference, err := NewReferenceDeck(context.Background(), deck, "Each player starts at 40 life.", dir)
+	assert.Nil(t, err)
+	assert.Equal(t, "Commander - Reference", reference.Name)
+	assert.Equal(t, "https://example.com/back.jpg", reference.BackURL)
+	if assert.Len(t, reference.Cards, 1) {
+		card := reference.Cards[0]
+		assert.Equal(t, "Each player starts at 40 life.", card.Description)
+		assert.Equal(t, filepath.Join(dir, "Commander - Reference.png"), card.ImageURL)
+		assert.True(t, isRenderedImage(card.ImageURL))
+
+		width, height, err := getImageSize(card.ImageURL)
+		assert.Nil(t, err)
+		assert.Equal(t, referenceCardWidth, width)
+		assert.Equal(t, referenceCardHeight, height)
+	}
+
+	// The text which doesn't fit is truncated
+	card := renderReferenceCard("Long", strings.Repeat("word ", 10000))
+	assert.Equal(t, referenceCardWidth, card.Bounds().Dx())
+	assert.Equal(t, referenceCardHeight, card.Bounds().Dy())
+}
+
+func TestAddReferenceDeck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reference")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	decks := []*plugins.Deck{{Name: "Commander"}, {Name: "Commander - Tokens"}}
+
+	// Nothing to add without any text or deck
+	result, err := AddReferenceDeck(ctx, decks, "", dir)
+	assert.Nil(t, err)
+	assert.Len(t, result, 2)
+	result, err = AddReferenceDeck(ctx, nil, "Each player starts at 40 life.", dir)
+	assert.Nil(t, err)
+	assert.Empty(t, result)
+
+	result, err = AddReferenceDeck(ctx, decks, "Each player starts at 40 life.", dir)
+	assert.Nil(t, err)
+	if assert.Len(t, result, 3) {
+		assert.Equal(t, "Commander - Reference", result[2].Name)
+	}
+}
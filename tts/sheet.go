@@ -0,0 +1,308 @@
+package tts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/disintegration/imaging"
+)
+
+// DefaultTemplateFilter is the resampling filter used to resize the card
+// images of the templates, unless another one is set with
+// SetTemplateSizing.
+const DefaultTemplateFilter = "lanczos"
+
+// TemplateFilters maps the names of the resampling filters which can be used
+// to resize the card images of the templates to their implementation.
+var TemplateFilters = map[string]imaging.ResampleFilter{
+	"lanczos":    imaging.Lanczos,
+	"catmullrom": imaging.CatmullRom,
+	"linear":     imaging.Linear,
+	"box":        imaging.Box,
+	"nearest":    imaging.NearestNeighbor,
+}
+
+// TemplateSizing controls the dimensions of the template sheets.
+// By default, the sheets are made of the card images at their original size.
+type TemplateSizing struct {
+	// MaxSize is the maximum width and height of a sheet in pixels (e.g.
+	// 4096, the largest texture size recommended by Tabletop Simulator), or
+	// 0 for no maximum. The larger sheets are scaled down, keeping their
+	// ratio.
+	MaxSize int
+	// PowerOfTwo resizes the sheets to the nearest power of two dimensions
+	// (without exceeding MaxSize), which are handled better by the GPUs.
+	// The cards are stretched, since Tabletop Simulator splits the sheets in
+	// a grid whatever their ratio.
+	PowerOfTwo bool
+	// Filter is the name of the resampling filter used to resize the card
+	// images (see TemplateFilters), DefaultTemplateFilter if empty.
+	Filter string
+}
+
+var (
+	templateSizing = TemplateSizing{}
+	templateFilter = TemplateFilters[DefaultTemplateFilter]
+	// templateSaturation is the saturation of the card images of the
+	// templates, from 0 (grayscale) to 1 (unchanged).
+	templateSaturation = 1.0
+)
+
+// AvailableTemplateFilters returns the names of the filters of
+// TemplateFilters, sorted.
+func AvailableTemplateFilters() []string {
+	names := make([]string, 0, len(TemplateFilters))
+
+	for name := range TemplateFilters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// SetTemplateSizing changes the dimensions of the template sheets generated
+// by GenerateTemplates.
+func SetTemplateSizing(sizing TemplateSizing) error {
+	if sizing.MaxSize < 0 {
+		return fmt.Errorf("invalid maximum template size: %d", sizing.MaxSize)
+	}
+
+	if len(sizing.Filter) == 0 {
+		sizing.Filter = DefaultTemplateFilter
+	}
+	filter, found := TemplateFilters[strings.ToLower(sizing.Filter)]
+	if !found {
+		return errors.New("invalid resampling filter: " + sizing.Filter)
+	}
+
+	templateSizing = sizing
+	templateFilter = filter
+
+	return nil
+}
+
+// SetTemplateSaturation changes the saturation of the card images of the
+// template sheets generated by GenerateTemplates, from 0 (grayscale) to 1
+// (unchanged), e.g. to save ink when printing playtest decks.
+func SetTemplateSaturation(saturation float64) error {
+	if saturation < 0 || saturation > 1 {
+		return fmt.Errorf("invalid template saturation: %g (needs to be between 0 and 1)", saturation)
+	}
+
+	templateSaturation = saturation
+
+	return nil
+}
+
+// sheetSize returns the dimensions of a template sheet of numCols×numRows
+// cards of cardWidth×cardHeight pixels, following templateSizing.
+func sheetSize(numCols, numRows, cardWidth, cardHeight int) (int, int) {
+	width := numCols * cardWidth
+	height := numRows * cardHeight
+	maxSize := templateSizing.MaxSize
+
+	if maxSize > 0 && (width > maxSize || height > maxSize) {
+		if width >= height {
+			height = height * maxSize / width
+			width = maxSize
+		} else {
+			width = width * maxSize / height
+			height = maxSize
+		}
+	}
+
+	if templateSizing.PowerOfTwo {
+		width = nearestPowerOfTwo(width, maxSize)
+		height = nearestPowerOfTwo(height, maxSize)
+	}
+
+	// Each card needs at least one pixel
+	if width < numCols {
+		width = numCols
+	}
+	if height < numRows {
+		height = numRows
+	}
+
+	return width, height
+}
+
+// nearestPowerOfTwo returns the power of two closest to n, which isn't
+// greater than max if max is greater than 0.
+func nearestPowerOfTwo(n, max int) int {
+	power := 1
+	for power*2 <= n {
+		power *= 2
+	}
+	if n-power > power*2-n && (max <= 0 || power*2 <= max) {
+		power *= 2
+	}
+
+	return power
+}
+
+// templateSheet is a template image composed one row of cards at a time.
+// The JPEG encoder reads the image from top to bottom, so only the decoded
+// images of the rows being encoded are kept in memory, instead of every card
+// image and the whole sheet (several GB for a large PNG-quality cube).
+type templateSheet struct {
+	// ctx stops the decoding of the card images when done.
+	ctx context.Context
+	// files are the paths of the card images, in the order of the template.
+	files []string
+	// rotated contains the paths of the landscape card images, rotated by
+	// 90° to fit the portrait cells.
+	rotated map[string]bool
+	numCols int
+	numRows int
+	width   int
+	height  int
+	// rows contains the decoded rows, indexed by row number. Two rows are
+	// kept, since the blocks encoded by the JPEG encoder can overlap two
+	// rows of cards.
+	rows map[int]*image.NRGBA
+	// watermarks contains the watermark images (see renderWatermark),
+	// indexed by cell width.
+	watermarks map[int]*image.NRGBA
+	// err is the first error encountered while loading a row, since At
+	// can't return errors.
+	err error
+}
+
+// newTemplateSheet returns a template sheet of width×height pixels, split in
+// numCols×numRows cells. The cells can differ by one pixel when the size of
+// the sheet isn't a multiple of the number of cards.
+func newTemplateSheet(ctx context.Context, files []string, numCols, numRows, width, height int) *templateSheet {
+	return &templateSheet{
+		ctx:        ctx,
+		files:      files,
+		numCols:    numCols,
+		numRows:    numRows,
+		width:      width,
+		height:     height,
+		rows:       make(map[int]*image.NRGBA),
+		watermarks: make(map[int]*image.NRGBA),
+	}
+}
+
+// watermark returns the watermark image for the cells of the given width.
+func (s *templateSheet) watermark(width int) *image.NRGBA {
+	mark, found := s.watermarks[width]
+	if !found {
+		mark = renderWatermark(width)
+		s.watermarks[width] = mark
+	}
+
+	return mark
+}
+
+// ColorModel implements the image.Image interface.
+func (s *templateSheet) ColorModel() color.Model {
+	return color.NRGBAModel
+}
+
+// Bounds implements the image.Image interface.
+func (s *templateSheet) Bounds() image.Rectangle {
+	return image.Rect(0, 0, s.width, s.height)
+}
+
+// colStart returns the abscissa of the first pixel of a column of cards.
+func (s *templateSheet) colStart(col int) int {
+	return col * s.width / s.numCols
+}
+
+// rowStart returns the ordinate of the first pixel of a row of cards.
+func (s *templateSheet) rowStart(row int) int {
+	return row * s.height / s.numRows
+}
+
+// At implements the image.Image interface, loading the row of cards
+// containing the pixel if required.
+func (s *templateSheet) At(x, y int) color.Color {
+	if !image.Pt(x, y).In(s.Bounds()) {
+		return color.NRGBA{}
+	}
+
+	index := y * s.numRows / s.height
+	for index > 0 && s.rowStart(index) > y {
+		index--
+	}
+	for index < s.numRows-1 && s.rowStart(index+1) <= y {
+		index++
+	}
+
+	row, found := s.rows[index]
+	if !found {
+		row = s.loadRow(index)
+		// Only keep the previous row
+		for loaded := range s.rows {
+			if loaded < index-1 || loaded > index {
+				delete(s.rows, loaded)
+			}
+		}
+		s.rows[index] = row
+	}
+
+	return row.NRGBAAt(x, y-s.rowStart(index))
+}
+
+// loadRow decodes the card images of a row, resized to the size of their
+// cell. The cells without any card are white, as well as the rows loaded
+// after an error or once ctx is done, so that the encoding finishes quickly.
+func (s *templateSheet) loadRow(index int) *image.NRGBA {
+	cellHeight := s.rowStart(index+1) - s.rowStart(index)
+	row := imaging.New(s.width, cellHeight, white)
+
+	if err := s.ctx.Err(); err != nil && s.err == nil {
+		s.err = err
+	}
+
+	for col := 0; col < s.numCols; col++ {
+		i := index*s.numCols + col
+		if i >= len(s.files) || s.err != nil {
+			break
+		}
+
+		cardImage, err := decodeCardImage(s.files[i])
+		if err != nil {
+			s.err = err
+			break
+		}
+		if s.rotated[s.files[i]] {
+			cardImage = imaging.Rotate90(cardImage)
+		}
+
+		cell := image.Rect(s.colStart(col), 0, s.colStart(col+1), cellHeight)
+		if cardImage.Bounds().Dx() != cell.Dx() || cardImage.Bounds().Dy() != cell.Dy() {
+			// Resize the image so it fits the template
+			cardImage = imaging.Resize(cardImage, cell.Dx(), cell.Dy(), templateFilter)
+		}
+		if templateSaturation < 1 {
+			cardImage = imaging.AdjustSaturation(cardImage, (templateSaturation-1)*100)
+		}
+
+		draw.Draw(row, cell, cardImage, cardImage.Bounds().Min, draw.Src)
+		drawWatermark(row, cell, s.watermark(cell.Dx()))
+	}
+
+	return row
+}
+
+// decodeCardImage decodes the image located at path.
+func decodeCardImage(path string) (image.Image, error) {
+	source, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+
+	return imaging.Decode(source)
+}
@@ -21,6 +21,51 @@ const (
 	CardObject ObjectType = "Card"
 	// CardCustomObject represents a custom card.
 	CardCustomObject ObjectType = "CardCustom"
+	// CounterObject represents a counter.
+	CounterObject ObjectType = "Counter"
+	// BlockSquareObject represents a square block.
+	BlockSquareObject ObjectType = "BlockSquare"
+	// BagObject represents a bag.
+	BagObject ObjectType = "Bag"
+	// HandTriggerObject represents the hand zone of a player.
+	HandTriggerObject ObjectType = "HandTrigger"
+	// TextObject represents a 3D text.
+	TextObject ObjectType = "3DText"
+	// TileCustomObject represents a custom tile.
+	TileCustomObject ObjectType = "Custom_Tile"
+	// BoardCustomObject represents a custom board.
+	BoardCustomObject ObjectType = "Custom_Board"
+	// FigurineCustomObject represents a custom figurine (standee).
+	FigurineCustomObject ObjectType = "Figurine_Custom"
+	// BagCustomModelObject represents a custom model which can contain
+	// objects.
+	BagCustomModelObject ObjectType = "Custom_Model_Bag"
+	// Die4Object represents a four-sided die.
+	Die4Object ObjectType = "Die_4"
+	// Die6Object represents a six-sided die.
+	Die6Object ObjectType = "Die_6"
+	// Die8Object represents an eight-sided die.
+	Die8Object ObjectType = "Die_8"
+	// Die10Object represents a ten-sided die.
+	Die10Object ObjectType = "Die_10"
+	// Die12Object represents a twelve-sided die.
+	Die12Object ObjectType = "Die_12"
+	// Die20Object represents a twenty-sided die.
+	Die20Object ObjectType = "Die_20"
+)
+
+// TileShape is the shape of a custom tile.
+type TileShape int
+
+const (
+	// TileShapeBox is a rectangular tile.
+	TileShapeBox TileShape = iota
+	// TileShapeHex is a hexagonal tile.
+	TileShapeHex
+	// TileShapeCircle is a round tile.
+	TileShapeCircle
+	// TileShapeRounded is a rectangular tile with rounded corners.
+	TileShapeRounded
 )
 
 // DefaultTransform is the object transform data used by default in TTS.
@@ -196,10 +241,116 @@ type Object struct {
 	// States lists the differents states of the object.
 	// See https://berserk-games.com/knowledgebase/creating-states/.
 	States map[string]Object `json:"States,omitempty"`
+	// Text is the text of a 3D text object.
+	Text *Text `json:"Text,omitempty"`
+	// FogColor is the color of the player owning the object (used by the
+	// hand zones).
+	FogColor string `json:"FogColor,omitempty"`
+	// CustomImage contains the images of a custom tile, board or figurine.
+	CustomImage *CustomImage `json:"CustomImage,omitempty"`
+	// CustomMesh contains the model of a custom model.
+	CustomMesh *CustomMesh `json:"CustomMesh,omitempty"`
 	// GUID is the Globally Unique Identifier of the object.
 	GUID string `json:"GUID"`
 }
 
+// Text is the text of a 3D text object.
+type Text struct {
+	// Text displayed.
+	Text string `json:"Text"`
+	// ColorState is the color of the text.
+	ColorState ColorDiffuse `json:"colorstate"`
+	// FontSize is the size of the text.
+	FontSize int `json:"fontSize"`
+}
+
+// MeshMaterial is the material of a custom model.
+type MeshMaterial int
+
+const (
+	// MeshMaterialPlastic is a plastic model.
+	MeshMaterialPlastic MeshMaterial = iota
+	// MeshMaterialWood is a wooden model.
+	MeshMaterialWood
+	// MeshMaterialMetal is a metal model.
+	MeshMaterialMetal
+	// MeshMaterialCardboard is a cardboard model.
+	MeshMaterialCardboard
+)
+
+// MeshType is the behavior of a custom model.
+type MeshType int
+
+const (
+	// MeshTypeGeneric is a model without any specific behavior.
+	MeshTypeGeneric MeshType = iota
+	// MeshTypeFigurine is a figurine.
+	MeshTypeFigurine
+	// MeshTypeDice is a die.
+	MeshTypeDice
+	// MeshTypeCoin is a coin.
+	MeshTypeCoin
+	// MeshTypeBoard is a board.
+	MeshTypeBoard
+	// MeshTypeChip is a stackable chip.
+	MeshTypeChip
+	// MeshTypeBag is a bag, which can contain objects.
+	MeshTypeBag
+	// MeshTypeInfiniteBag is a bag spawning copies of the object it
+	// contains.
+	MeshTypeInfiniteBag
+)
+
+// CustomImage contains the images of a custom tile, board or figurine.
+type CustomImage struct {
+	// ImageURL is the address of the top image.
+	ImageURL string `json:"ImageURL"`
+	// ImageSecondaryURL is the address of the bottom image (the top image is
+	// used if empty).
+	ImageSecondaryURL string `json:"ImageSecondaryURL"`
+	// ImageScalar is the scale of the images.
+	ImageScalar float64 `json:"ImageScalar"`
+	// WidthScale is the width of the object relative to its height (computed
+	// from the image if 0).
+	WidthScale float64 `json:"WidthScale"`
+	// CustomTile contains the shape of a custom tile.
+	CustomTile *CustomTile `json:"CustomTile,omitempty"`
+}
+
+// CustomTile contains the shape of a custom tile.
+type CustomTile struct {
+	// Type is the shape of the tile.
+	Type TileShape `json:"Type"`
+	// Thickness of the tile.
+	Thickness float64 `json:"Thickness"`
+	// Stackable is whether or not the tiles can be stacked.
+	Stackable bool `json:"Stackable"`
+	// Stretch is whether or not the image is stretched to the shape of the
+	// tile.
+	Stretch bool `json:"Stretch"`
+}
+
+// CustomMesh contains the model and the textures of a custom model.
+type CustomMesh struct {
+	// MeshURL is the address of the OBJ model.
+	MeshURL string `json:"MeshURL"`
+	// DiffuseURL is the address of the texture of the model.
+	DiffuseURL string `json:"DiffuseURL"`
+	// NormalURL is the address of the normal map of the model.
+	NormalURL string `json:"NormalURL"`
+	// ColliderURL is the address of the OBJ model used for the collisions
+	// (the model itself is used if empty).
+	ColliderURL string `json:"ColliderURL"`
+	// Convex is whether or not the collider is convex.
+	Convex bool `json:"Convex"`
+	// MaterialIndex is the material of the model.
+	MaterialIndex MeshMaterial `json:"MaterialIndex"`
+	// TypeIndex is the behavior of the model.
+	TypeIndex MeshType `json:"TypeIndex"`
+	// CastShadows is whether or not the model casts shadows.
+	CastShadows bool `json:"CastShadows"`
+}
+
 // Transform contains the position, rotation and scale data of an object.
 type Transform struct {
 	// PosX is the X position of the object.
@@ -0,0 +1,350 @@
+package tts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/jeandeaual/tts-deckconverter/log"
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+const (
+	// seatDistance is the minimum distance between the center of the table
+	// and the center of the mat of each player.
+	seatDistance = 14.0
+	// seatSpacing is the space between the mats of two players.
+	seatSpacing = 2.0
+	// matWidth and matDepth are the dimensions of the mat of each player.
+	matWidth = 20.0
+	matDepth = 8.0
+	// handDepth is the depth of the hand zone of each player, placed behind
+	// their mat.
+	handDepth = 4.0
+	// tableHeight is the height of the objects placed on the table.
+	tableHeight = 1.0
+)
+
+// seatColor is the color of a player seat in TTS, with the RGB values of
+// its mat.
+type seatColor struct {
+	name  string
+	color ColorDiffuse
+}
+
+// seatColors are the colors of the seats, in the order they are used.
+var seatColors = []seatColor{
+	{"White", ColorDiffuse{Red: 1, Green: 1, Blue: 1}},
+	{"Red", ColorDiffuse{Red: 0.856, Green: 0.1, Blue: 0.094}},
+	{"Blue", ColorDiffuse{Red: 0.118, Green: 0.53, Blue: 1}},
+	{"Green", ColorDiffuse{Red: 0.192, Green: 0.701, Blue: 0.168}},
+	{"Brown", ColorDiffuse{Red: 0.443, Green: 0.231, Blue: 0.09}},
+	{"Orange", ColorDiffuse{Red: 0.956, Green: 0.392, Blue: 0.113}},
+	{"Yellow", ColorDiffuse{Red: 0.905, Green: 0.898, Blue: 0.172}},
+	{"Teal", ColorDiffuse{Red: 0.129, Green: 0.694, Blue: 0.607}},
+	{"Purple", ColorDiffuse{Red: 0.627, Green: 0.125, Blue: 0.941}},
+	{"Pink", ColorDiffuse{Red: 0.96, Green: 0.439, Blue: 0.807}},
+}
+
+// seat places the objects of a player: its positions are relative to the
+// center of the mat of the player, facing the center of the table.
+type seat struct {
+	color seatColor
+	// angle is the rotation of the seat around the center of the table,
+	// in degrees.
+	angle float64
+	// distance is the distance between the center of the table and the
+	// center of the mat.
+	distance float64
+	// player is the name of the player sitting at the seat, if known.
+	player string
+}
+
+// newSeat returns the index-th seat of a table for players.
+func newSeat(index, players int) seat {
+	// Keep the mats from overlapping when there are many players
+	distance := float64(players) * (matWidth + seatSpacing) / (2 * math.Pi)
+	if distance < seatDistance {
+		distance = seatDistance
+	}
+
+	return seat{
+		color:    seatColors[index],
+		angle:    360 * float64(index) / float64(players),
+		distance: distance,
+	}
+}
+
+// place moves object from the position (x, y, z) of the seat to the table,
+// and rotates it with the seat.
+func (s seat) place(object *Object, x, y, z float64) {
+	z -= s.distance
+
+	radians := s.angle * math.Pi / 180
+	sin, cos := math.Sin(radians), math.Cos(radians)
+
+	object.Transform.PosX = x*cos + z*sin
+	object.Transform.PosY = y
+	object.Transform.PosZ = -x*sin + z*cos
+	object.Transform.RotY = math.Mod(object.Transform.RotY+s.angle, 360)
+}
+
+// guid returns the GUID of the object called name of the player sitting at
+// s.
+func (s seat) guid(name string) string {
+	return objectGUID(s.color.name + " - " + name)
+}
+
+// createHandZone returns the hand zone of the player sitting at s.
+func createHandZone(s seat) Object {
+	hand := Object{
+		ObjectType:   HandTriggerObject,
+		Nickname:     s.color.name + " hand",
+		Transform:    DefaultTransform,
+		ColorDiffuse: s.color.color,
+		Locked:       true,
+		FogColor:     s.color.name,
+		GUID:         s.guid("Hand"),
+	}
+	hand.Transform.RotY = 0
+	hand.Transform.RotZ = 0
+	hand.Transform.ScaleX = matWidth
+	hand.Transform.ScaleY = 5
+	hand.Transform.ScaleZ = handDepth
+	s.place(&hand, 0, tableHeight+2, -(matDepth+handDepth)/2-1)
+
+	return hand
+}
+
+// createMat returns the mat of the player sitting at s.
+func createMat(s seat) Object {
+	mat := Object{
+		ObjectType:   BlockSquareObject,
+		Nickname:     s.color.name + " mat",
+		Transform:    DefaultTransform,
+		ColorDiffuse: s.color.color,
+		Locked:       true,
+		Grid:         true,
+		Snap:         true,
+		Tooltip:      true,
+		GUID:         s.guid("Mat"),
+	}
+	mat.Transform.RotY = 0
+	mat.Transform.RotZ = 0
+	mat.Transform.ScaleX = matWidth
+	mat.Transform.ScaleY = 0.2
+	mat.Transform.ScaleZ = matDepth
+	s.place(&mat, 0, tableHeight-0.1, 0)
+
+	return mat
+}
+
+// createLabel returns the label showing the name of the player sitting at
+// s, in front of their mat.
+func createLabel(s seat) Object {
+	label := Object{
+		ObjectType: TextObject,
+		Nickname:   s.player,
+		Transform:  DefaultTransform,
+		Locked:     true,
+		Text: &Text{
+			Text:       s.player,
+			ColorState: ColorDiffuse{Red: 1, Green: 1, Blue: 1},
+			FontSize:   64,
+		},
+		GUID: s.guid("Label"),
+	}
+	label.Transform.RotX = 90
+	label.Transform.RotY = 0
+	label.Transform.RotZ = 0
+	s.place(&label, 0, tableHeight, matDepth/2+1)
+
+	return label
+}
+
+// createSeat returns the objects of the player sitting at s: the hand zone,
+// the mat (with a label if the name of the player is known), the counters
+// of preset and a copy of decks laid out on the mat.
+func createSeat(s seat, decks []*plugins.Deck, preset plugins.TablePreset) []Object {
+	objects := []Object{createHandZone(s), createMat(s)}
+	if len(s.player) > 0 {
+		objects[1].Nickname = s.player
+		objects = append(objects, createLabel(s))
+	}
+	lifeCounter := false
+
+	for i, deck := range decks {
+		if deck.LifeTotal > 0 {
+			lifeCounter = true
+		}
+
+		object, _ := createObject(deck)
+		origin := object.ObjectStates[0].Transform
+		slotX := -matWidth/2 + 2 + float64(i)*spawnSpacing
+
+		for j, state := range object.ObjectStates {
+			s.place(
+				&state,
+				slotX+state.Transform.PosX-origin.PosX,
+				tableHeight+0.5+state.Transform.PosY-origin.PosY,
+				state.Transform.PosZ-origin.PosZ,
+			)
+			state.GUID = s.guid(objectName(deck, object, j))
+			if j == 0 && len(s.player) > 0 {
+				state.Nickname = s.player + " - " + deck.Name
+			}
+			objects = append(objects, state)
+		}
+	}
+
+	// The counters are placed in the corner of the mat, on the right of the
+	// player
+	var anchor Transform
+	anchorX := matWidth/2 - counterOffsetX - 1
+	anchorZ := matDepth/2 - 1
+
+	if !lifeCounter && preset.LifeTotal > 0 {
+		counter := createLifeCounter(preset.LifeTotal, anchor)
+		s.place(&counter, anchorX+counter.Transform.PosX, tableHeight, anchorZ+counter.Transform.PosZ)
+		counter.GUID = s.guid(counter.Nickname)
+		objects = append(objects, counter)
+	}
+
+	for i, name := range preset.Counters {
+		counter := createCounter(name, i, anchor)
+		s.place(&counter, anchorX+counter.Transform.PosX, tableHeight, anchorZ+counter.Transform.PosZ)
+		counter.GUID = s.guid(counter.Nickname)
+		objects = append(objects, counter)
+	}
+
+	return objects
+}
+
+// createTable returns the save file of a table for players following
+// preset, with a copy of decks in front of each player.
+func createTable(name string, decks []*plugins.Deck, preset plugins.TablePreset, players int) SavedObject {
+	objects := []Object{}
+
+	for i := 0; i < players; i++ {
+		objects = append(objects, createSeat(newSeat(i, players), decks, preset)...)
+	}
+
+	table := createSavedObject(objects)
+	table.SaveName = name
+	table.GameMode = name
+	table.Table = "Table_Square"
+
+	return table
+}
+
+// nonEmptyDecks returns the decks containing at least one card.
+func nonEmptyDecks(decks []*plugins.Deck) []*plugins.Deck {
+	nonEmpty := make([]*plugins.Deck, 0, len(decks))
+
+	for _, deck := range decks {
+		if len(deck.Cards) > 0 {
+			nonEmpty = append(nonEmpty, deck)
+		}
+	}
+
+	return nonEmpty
+}
+
+// writeSave writes the save file save to filename.
+func writeSave(ctx context.Context, save SavedObject, filename string, indent bool) error {
+	var (
+		data []byte
+		err  error
+	)
+
+	if indent {
+		data, err = json.MarshalIndent(save, "", strings.Repeat(" ", 2))
+	} else {
+		data, err = json.Marshal(save)
+	}
+	if err != nil {
+		return fmt.Errorf("couldn't marshall data: %w", err)
+	}
+
+	err = WriteFile(filename, data)
+	if err != nil {
+		return fmt.Errorf("couldn't write file %s: %w", filename, err)
+	}
+
+	plugins.ReportFileWritten(ctx, filename, int64(len(data)))
+
+	return nil
+}
+
+// GenerateTable writes a save file inside outputFolder with a seat for each
+// player following preset (a hand zone, a mat and counters), and a copy of
+// decks on each mat. players is the number of players (the default number
+// of players of preset if 0).
+func GenerateTable(ctx context.Context, decks []*plugins.Deck, preset plugins.TablePreset, players int, outputFolder string, indent bool) error {
+	if players == 0 {
+		players = preset.Players
+	}
+	if players < plugins.MinTablePlayers || players > plugins.MaxTablePlayers || players > len(seatColors) {
+		return fmt.Errorf("invalid number of players: %d", players)
+	}
+
+	nonEmpty := nonEmptyDecks(decks)
+	if len(nonEmpty) == 0 {
+		return fmt.Errorf("no deck to place on the table")
+	}
+
+	name := nonEmpty[0].Name + " - Table"
+	filename := filepath.Join(outputFolder, filepathReplacer.Replace(name)+".json")
+	log.Infof("Generating the table of %d players in %s", players, filename)
+
+	return writeSave(ctx, createTable(name, nonEmpty, preset, players), filename, indent)
+}
+
+// EventPlayer is a player of an event, with their decks.
+type EventPlayer struct {
+	// Name of the player.
+	Name string
+	// Decks of the player.
+	Decks []*plugins.Deck
+}
+
+// createEvent returns the save file of an event called name, with the decks
+// of each player at their seat, labeled with their name.
+func createEvent(name string, players []EventPlayer) SavedObject {
+	objects := []Object{}
+
+	for i, player := range players {
+		s := newSeat(i, len(players))
+		s.player = player.Name
+		objects = append(objects, createSeat(s, player.Decks, plugins.TablePreset{})...)
+	}
+
+	event := createSavedObject(objects)
+	event.SaveName = name
+	event.GameMode = name
+	event.Table = "Table_Square"
+
+	return event
+}
+
+// GenerateEvent writes the save file of an event called name inside
+// outputFolder, with a seat for each player (a hand zone and a mat labeled
+// with their name) and their decks on their mat. The events can have up to
+// 10 players, the number of seats in TTS.
+func GenerateEvent(ctx context.Context, name string, players []EventPlayer, outputFolder string, indent bool) error {
+	if len(players) == 0 || len(players) > len(seatColors) {
+		return fmt.Errorf("invalid number of players: %d (the events have 1 to %d players)", len(players), len(seatColors))
+	}
+
+	for i, player := range players {
+		players[i].Decks = nonEmptyDecks(player.Decks)
+	}
+
+	filename := filepath.Join(outputFolder, filepathReplacer.Replace(name)+".json")
+	log.Infof("Generating the event of %d players in %s", len(players), filename)
+
+	return writeSave(ctx, createEvent(name, players), filename, indent)
+}
@@ -0,0 +1,148 @@
+package tts
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+func TestCreateTable(t *testing.T) {
+	decks := []*plugins.Deck{
+		{
+			Name:    "Deck",
+			BackURL: "https://example.com/back.png",
+			Cards: []plugins.CardInfo{
+				{Name: "Card 1", ImageURL: "https://example.com/1.png", Count: 2},
+				{Name: "Card 2", ImageURL: "https://example.com/2.png", Count: 1},
+			},
+		},
+	}
+	preset := plugins.TablePreset{
+		Players:   2,
+		LifeTotal: 20,
+		Counters:  []string{"poison"},
+	}
+
+	table := createTable("Deck - Table", decks, preset, 2)
+
+	// Hand zone, mat, deck, life counter and poison counter for each player
+	if !assert.Len(t, table.ObjectStates, 10) {
+		return
+	}
+
+	guids := make(map[string]bool)
+	hands := []string{}
+	for _, object := range table.ObjectStates {
+		assert.False(t, guids[object.GUID], "duplicate GUID %s", object.GUID)
+		guids[object.GUID] = true
+		if object.ObjectType == HandTriggerObject {
+			hands = append(hands, object.FogColor)
+		}
+	}
+	assert.Equal(t, []string{"White", "Red"}, hands)
+
+	// The players face each other
+	white, red := table.ObjectStates[2], table.ObjectStates[7]
+	assert.Equal(t, DeckObject, white.ObjectType)
+	assert.Equal(t, DeckObject, red.ObjectType)
+	assert.InDelta(t, -white.Transform.PosX, red.Transform.PosX, 1e-9)
+	assert.InDelta(t, -white.Transform.PosZ, red.Transform.PosZ, 1e-9)
+	assert.Less(t, white.Transform.PosZ, 0.0)
+	assert.InDelta(t, 180, math.Abs(red.Transform.RotY-white.Transform.RotY), 1e-9)
+	assert.Equal(t, "Life counter", table.ObjectStates[3].Nickname)
+	assert.Equal(t, "Poison counters", table.ObjectStates[4].Nickname)
+
+	// The life counter of the decks replaces the one of the preset
+	decks[0].LifeTotal = 40
+	table = createTable("Deck - Table", decks, preset, 2)
+	assert.Len(t, table.ObjectStates, 10)
+}
+
+func TestGenerateTable(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "table")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer os.RemoveAll(tmpDir)
+
+	decks := []*plugins.Deck{
+		{
+			Name: "Deck",
+			Cards: []plugins.CardInfo{
+				{Name: "Card", ImageURL: "https://example.com/1.png", Count: 1},
+			},
+		},
+	}
+	preset := plugins.TablePreset{Players: 4}
+
+	assert.NotNil(t, GenerateTable(context.Background(), decks, preset, 5, tmpDir, false))
+	assert.NotNil(t, GenerateTable(context.Background(), []*plugins.Deck{{Name: "Empty"}}, preset, 0, tmpDir, false))
+
+	if !assert.Nil(t, GenerateTable(context.Background(), decks, preset, 0, tmpDir, false)) {
+		return
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(tmpDir, "Deck - Table.json"))
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	var table SavedObject
+	if assert.Nil(t, json.Unmarshal(data, &table)) {
+		assert.Equal(t, "Deck - Table", table.SaveName)
+		// Hand zone, mat and card for each player
+		assert.Len(t, table.ObjectStates, 12)
+	}
+}
+
+func TestCreateEvent(t *testing.T) {
+	newDeck := func(name string) *plugins.Deck {
+		return &plugins.Deck{
+			Name: name,
+			Cards: []plugins.CardInfo{
+				{Name: "Card", ImageURL: "https://example.com/1.png", Count: 1},
+			},
+		}
+	}
+
+	players := []EventPlayer{}
+	for _, name := range []string{"Alice", "Bob", "Carol", "Dave", "Erin", "Frank"} {
+		players = append(players, EventPlayer{Name: name, Decks: []*plugins.Deck{newDeck("Deck")}})
+	}
+
+	event := createEvent("League", players)
+	assert.Equal(t, "League", event.SaveName)
+
+	// Hand zone, mat, label and deck for each player
+	if !assert.Len(t, event.ObjectStates, 4*len(players)) {
+		return
+	}
+
+	guids := make(map[string]bool)
+	for _, object := range event.ObjectStates {
+		assert.False(t, guids[object.GUID], "duplicate GUID %s", object.GUID)
+		guids[object.GUID] = true
+	}
+
+	mat, label, card := event.ObjectStates[1], event.ObjectStates[2], event.ObjectStates[3]
+	assert.Equal(t, "Alice", mat.Nickname)
+	assert.Equal(t, TextObject, label.ObjectType)
+	if assert.NotNil(t, label.Text) {
+		assert.Equal(t, "Alice", label.Text.Text)
+	}
+	assert.Equal(t, "Alice - Deck", card.Nickname)
+
+	// The mats don't overlap with 6 players
+	distance := math.Hypot(mat.Transform.PosX, mat.Transform.PosZ)
+	assert.Greater(t, distance, seatDistance)
+
+	assert.NotNil(t, GenerateEvent(context.Background(), "Empty", nil, "", false))
+}
@@ -1,6 +1,7 @@
 package tts
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"image"
@@ -11,6 +12,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/disintegration/imaging"
 
@@ -25,6 +27,9 @@ var (
 	maxTemplateRows  uint = 7
 	maxTemplateCount      = maxTemplateCols * maxTemplateRows
 	errAlreadyExists      = errors.New("target file already exists")
+	// maxRatioDifference is the maximum difference between the width / height
+	// ratios of the images of a template.
+	maxRatioDifference = 0.01
 )
 
 func findTemplateSize(count uint) (uint, uint, error) {
@@ -68,11 +73,25 @@ func getImageSize(filepath string) (width int, height int, err error) {
 	return image.Width, image.Height, nil
 }
 
-func downloadFile(url string, filepath string) (err error) {
-	if _, err = os.Stat(filepath); err == nil {
-		err = errAlreadyExists
-		return
+// downloadFile downloads url to filepath. If budgeted is true, the size of
+// the file is taken from the download budget, and the download is aborted
+// with errBudgetExceeded when the file doesn't fit in it.
+func downloadFile(ctx context.Context, url string, filepath string, budgeted bool) error {
+	if _, err := os.Stat(filepath); err == nil {
+		return errAlreadyExists
+	}
+
+	if err := waitForHost(ctx, url); err != nil {
+		return err
 	}
+
+	// Don't leave a truncated image if the download is interrupted
+	return writePartial(filepath, func(partial string) error {
+		return downloadToFile(ctx, url, partial, budgeted)
+	})
+}
+
+func downloadToFile(ctx context.Context, url string, filepath string, budgeted bool) (err error) {
 	output, err := os.Create(filepath)
 	if err != nil {
 		log.Errorf("Error while creating %s: %s", filepath, err)
@@ -84,7 +103,13 @@ func downloadFile(url string, filepath string) (err error) {
 		}
 	}()
 
-	resp, err := http.Get(url)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		log.Errorf("Error while creating the request for %s: %s", url, err)
+		return
+	}
+
+	resp, err := plugins.HTTPClient().Do(req)
 	if err != nil {
 		log.Errorf("Error while downloading %s: %s", url, err)
 		return
@@ -102,66 +127,104 @@ func downloadFile(url string, filepath string) (err error) {
 		return
 	}
 
-	n, err := io.Copy(output, resp.Body)
+	var body io.Reader = resp.Body
+	reserved := int64(-1)
+	if budgeted {
+		// The size isn't always known beforehand, in which case the largest
+		// size allowed is reserved
+		if reserved, err = reserveBudget(resp.ContentLength); err != nil {
+			return
+		}
+		if reserved >= 0 {
+			body = io.LimitReader(resp.Body, reserved+1)
+		}
+	}
+
+	n, err := io.Copy(output, body)
 	if err != nil {
+		refundBudget(reserved, 0)
 		log.Errorf("Error while downloading %s: %s", url, err)
 		return
 	}
+	if reserved >= 0 && n > reserved {
+		refundBudget(reserved, 0)
+		return errBudgetExceeded
+	}
+	refundBudget(reserved, n)
 
 	log.Debugf("Downloaded file %s to %s (%d bytes)", url, filepath, n)
+	plugins.RecordDownload(n)
+	plugins.ReportImageDownloaded(ctx, url, n)
+
 	return nil
 }
 
-func downloadImageIfRequired(imageURL string, tmpDir string) (string, error) {
-	var filename string
+// downloadImageIfRequired returns the path of the image located at imageURL,
+// downloading it to tmpDir if it isn't a local file. If the image doesn't fit
+// in the download budget, the first of fallbackURLs (lower-quality versions
+// of the image) which fits is downloaded instead.
+func downloadImageIfRequired(ctx context.Context, imageURL string, fallbackURLs []string, tmpDir string) (string, error) {
+	u, err := url.Parse(imageURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		// If the card image is a file, use it directly
+		return imageURL, nil
+	}
 
-	if u, err := url.Parse(imageURL); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
-		// If the card image is a URL, download it to the temporary folder
-		filename = filepath.Join(tmpDir, filepathReplacer.Replace(imageURL))
-		err = downloadFile(imageURL, filename)
-		if err != nil && err == errAlreadyExists {
-			log.Debugf("File %s already exists, reusing it (path: %s)", filename, imageURL)
+	// If the card image is a URL, download it to the temporary folder
+	for _, candidate := range append([]string{imageURL}, fallbackURLs...) {
+		filename := filepath.Join(tmpDir, filepathReplacer.Replace(candidate))
+		err = downloadFile(ctx, candidate, filename, true)
+		switch err {
+		case nil:
+			if candidate != imageURL {
+				log.Infof("Downloaded a lower-quality image to fit in the download budget: %s", candidate)
+			}
 			return filename, nil
-		} else if err != nil {
+		case errAlreadyExists:
+			log.Debugf("File %s already exists, reusing it (path: %s)", filename, candidate)
+			return filename, nil
+		case errBudgetExceeded:
+			log.Debugf("Image %s doesn't fit in the download budget", candidate)
+		default:
 			return filename, err
 		}
-	} else {
-		// If the card image is a file, use it directly
-		filename = imageURL
 	}
 
-	return filename, nil
+	return "", fmt.Errorf("couldn't download %s: %w", imageURL, errBudgetExceeded)
 }
 
-func generateTemplate(cards []plugins.CardInfo, tmpDir, outputPath string, count int) (urlIDMap map[string]int, numCols, numRows uint, err error) {
+// generateTemplate downloads the images of cards and saves them in a
+// template sheet at outputPath, starting from ID count×100. The landscape
+// images are rotated to fit the portrait cells of the sheet, and their URLs
+// are returned in sideways, so that the cards are displayed sideways in TTS.
+func generateTemplate(ctx context.Context, cards []plugins.CardInfo, cardSize plugins.CardSize, tmpDir, outputPath string, count int) (urlIDMap map[string]int, sideways map[string]bool, numCols, numRows uint, err error) {
 	idFilePathMap := make(map[int]string)
 	urlIDMap = make(map[string]int)
+	sideways = make(map[string]bool)
+	rotated := make(map[string]bool)
 
-	id := startingID * count
+	downloads := make([]imageDownload, 0, len(cards))
 	for _, card := range cards {
-		var filename string
-
-		filename, err = downloadImageIfRequired(card.ImageURL, tmpDir)
-		if err != nil {
-			return
+		downloads = append(downloads, imageDownload{url: card.ImageURL, fallbackURLs: card.FallbackImageURLs})
+		if card.AlternativeState != nil {
+			downloads = append(downloads, imageDownload{
+				url:          card.AlternativeState.ImageURL,
+				fallbackURLs: card.AlternativeState.FallbackImageURLs,
+			})
 		}
+	}
 
-		idFilePathMap[id] = filename
-		urlIDMap[card.ImageURL] = id
-
-		id++
-
-		if card.AlternativeState != nil {
-			filename, err = downloadImageIfRequired(card.AlternativeState.ImageURL, tmpDir)
-			if err != nil {
-				return
-			}
+	filenames, err := downloadImages(ctx, downloads, tmpDir)
+	if err != nil {
+		return
+	}
 
-			idFilePathMap[id] = filename
-			urlIDMap[card.AlternativeState.ImageURL] = id
+	id := startingID * count
+	for i, download := range downloads {
+		idFilePathMap[id] = filenames[i]
+		urlIDMap[download.url] = id
 
-			id++
-		}
+		id++
 	}
 
 	var (
@@ -173,17 +236,32 @@ func generateTemplate(cards []plugins.CardInfo, tmpDir, outputPath string, count
 	)
 
 	for _, filepath := range idFilePathMap {
+		if isRenderedImage(filepath) {
+			// The reference cards are resized to the size of the others
+			continue
+		}
+
 		width, height, err = getImageSize(filepath)
 		if err != nil {
 			return
 		}
 
+		if width > height {
+			// Landscape image (e.g. a Magic plane or battle), rotate it
+			// instead of squashing it in a portrait cell
+			rotated[filepath] = true
+			width, height = height, width
+		}
+
 		if maxWidth == 0 && maxHeight == 0 {
 			maxWidth = width
 			maxHeight = height
 			ratio = float64(width) / float64(height)
 		} else if width != maxWidth || height != maxHeight {
-			if float64(width)/float64(height) != ratio {
+			// The qualities of an image can have slightly different
+			// ratios (e.g. with a lower-quality image downloaded to fit
+			// in the download budget)
+			if math.Abs(float64(width)/float64(height)-ratio) > maxRatioDifference {
 				err = fmt.Errorf("the images don't all have the same ratio")
 				return
 			}
@@ -194,6 +272,11 @@ func generateTemplate(cards []plugins.CardInfo, tmpDir, outputPath string, count
 		}
 	}
 
+	if maxWidth == 0 && maxHeight == 0 {
+		// The template only contains reference cards
+		maxWidth, maxHeight = referenceCellSize(cardSize)
+	}
+
 	imageCount := len(idFilePathMap)
 
 	log.Debugw(
@@ -214,8 +297,7 @@ func generateTemplate(cards []plugins.CardInfo, tmpDir, outputPath string, count
 		)
 		return
 	}
-	templateWidth := int(numCols) * maxWidth
-	templateHeight := int(numRows) * maxHeight
+	templateWidth, templateHeight := sheetSize(int(numCols), int(numRows), maxWidth, maxHeight)
 	log.Infof(
 		"We have %d items, so create a %d×%d template (%d×%d pixels)",
 		imageCount,
@@ -225,272 +307,297 @@ func generateTemplate(cards []plugins.CardInfo, tmpDir, outputPath string, count
 		templateHeight,
 	)
 
-	template := imaging.New(templateWidth, templateHeight, white)
-	var (
-		curRow uint = 1
-		curCol uint = 1
-	)
-
+	files := make([]string, 0, imageCount)
 	for i := 0; i < imageCount; i++ {
 		filepath, found := idFilePathMap[startingID*count+i]
 		if !found {
 			err = fmt.Errorf("image for ID %d not found", startingID+i)
 			return
 		}
-		var source *os.File
-		source, err = os.Open(filepath)
-		if err != nil {
-			return
-		}
-		defer func() {
-			if cerr := source.Close(); cerr != nil && err == nil {
-				err = cerr
-			}
-		}()
+		files = append(files, filepath)
+	}
 
-		var cardImage image.Image
-		cardImage, err = imaging.Decode(source)
-		if err != nil {
-			return
-		}
+	// The card images are decoded while the template is encoded
+	template := newTemplateSheet(ctx, files, int(numCols), int(numRows), templateWidth, templateHeight)
+	template.rotated = rotated
 
-		if cardImage.Bounds().Max.X != maxWidth || cardImage.Bounds().Max.Y != maxHeight {
-			// Resize the image so it fits the template
-			cardImage = imaging.Resize(cardImage, maxWidth, maxHeight, imaging.Lanczos)
+	for imageURL, id := range urlIDMap {
+		if rotated[idFilePathMap[id]] {
+			log.Debugf("Rotating the landscape image %s", imageURL)
+			sideways[imageURL] = true
 		}
+	}
 
-		template = imaging.Paste(
-			template,
-			cardImage,
-			image.Pt((int(curCol)-1)*maxWidth, (int(curRow)-1)*maxHeight),
-		)
-
-		if curCol == numCols {
-			curCol = 1
-			curRow++
-		} else {
-			curCol++
+	// Save the resulting image
+	err = writePartial(outputPath, func(partial string) error {
+		if err := imaging.Save(template, partial, imaging.JPEGQuality(100)); err != nil {
+			return err
 		}
+		return template.err
+	})
+	if err != nil {
+		return
 	}
 
-	// Save the resulting image
-	err = imaging.Save(template, outputPath, imaging.JPEGQuality(100))
+	reportFileWritten(ctx, outputPath)
 
 	return
 }
 
-func generateTemplatesForRelatedDecks(decks []*plugins.Deck, tmpDir, outputFolder string, uploader upload.TemplateUploader) []error {
-	var (
-		urlIDMap   map[string]int
-		outputPath string
-		numCols    uint
-		numRows    uint
-		err        error
-	)
+// templateImageCount returns the number of images card takes in a template
+// (its back face included).
+func templateImageCount(card plugins.CardInfo) int {
+	if card.AlternativeState != nil {
+		return 2
+	}
+	return 1
+}
 
-	errs := []error{}
-	uniqueCards := make(map[string]struct{})
+// uniqueTemplateCards returns the cards of decks, each card image only
+// appearing once.
+func uniqueTemplateCards(decks []*plugins.Deck) []plugins.CardInfo {
+	cards := []plugins.CardInfo{}
+	seen := make(map[string]bool)
 
 	for _, deck := range decks {
 		for _, card := range deck.Cards {
-			uniqueCards[card.ImageURL] = struct{}{}
-			if card.AlternativeState != nil {
-				uniqueCards[card.AlternativeState.ImageURL] = struct{}{}
+			if seen[card.ImageURL] {
+				continue
 			}
+			seen[card.ImageURL] = true
+			cards = append(cards, card)
 		}
 	}
 
-	totalCount := len(uniqueCards)
+	return cards
+}
 
-	if totalCount > int(maxTemplateCount) {
-		totalTemplateCount := 1
-		for _, deck := range decks {
-			log.Debugw(
-				"Parsing cards to generate template(s)",
-				"card count", len(deck.Cards),
-				"cards", deck.Cards,
-			)
+// splitSharedCards returns the cards of decks appearing in several decks,
+// and the cards appearing in a single deck, for each deck.
+func splitSharedCards(decks []*plugins.Deck) ([]plugins.CardInfo, [][]plugins.CardInfo) {
+	deckCount := make(map[string]int)
+
+	for _, deck := range decks {
+		counted := make(map[string]bool)
+		for _, card := range deck.Cards {
+			if !counted[card.ImageURL] {
+				counted[card.ImageURL] = true
+				deckCount[card.ImageURL]++
+			}
+		}
+	}
 
-			// Set used for counting unique cards
-			uniqueCards = make(map[string]struct{})
-			// Set used for counting alternative state cards
-			alts := make(map[string]struct{})
+	shared := []plugins.CardInfo{}
+	exclusive := make([][]plugins.CardInfo, len(decks))
 
-			templateStarts := []int{0}
-			templateEnds := []int{}
+	for _, card := range uniqueTemplateCards(decks) {
+		if deckCount[card.ImageURL] > 1 {
+			shared = append(shared, card)
+		}
+	}
+	for i, deck := range decks {
+		for _, card := range uniqueTemplateCards([]*plugins.Deck{deck}) {
+			if deckCount[card.ImageURL] == 1 {
+				exclusive[i] = append(exclusive[i], card)
+			}
+		}
+	}
 
-			for _, card := range deck.Cards {
-				uniqueCards[card.ImageURL] = struct{}{}
-				if len(uniqueCards)%int(maxTemplateCount) == 0 {
-					if card.AlternativeState != nil {
-						alts[card.AlternativeState.ImageURL] = struct{}{}
-					}
+	return shared, exclusive
+}
 
-					log.Debugf("Cut template number %d at %d", len(templateStarts), len(uniqueCards)-len(alts))
-					log.Debugf("Found %d card(s) with an alternative state", len(alts))
+// splitTemplateCards splits cards in groups fitting in a single template.
+func splitTemplateCards(cards []plugins.CardInfo) [][]plugins.CardInfo {
+	var (
+		groups [][]plugins.CardInfo
+		group  []plugins.CardInfo
+		count  int
+	)
 
-					templateEnds = append(templateEnds, len(uniqueCards)-len(alts))
-					templateStarts = append(templateStarts, len(uniqueCards)-len(alts))
+	for _, card := range cards {
+		imageCount := templateImageCount(card)
+		if count+imageCount > int(maxTemplateCount) {
+			groups = append(groups, group)
+			group = nil
+			count = 0
+		}
+		group = append(group, card)
+		count += imageCount
+	}
+	if len(group) > 0 {
+		groups = append(groups, group)
+	}
 
-					alts = make(map[string]struct{})
-					continue
-				}
-				if card.AlternativeState != nil {
-					alts[card.AlternativeState.ImageURL] = struct{}{}
-					if len(uniqueCards)%int(maxTemplateCount) == 0 {
-						log.Debugf("Cut template number %d at %d", len(templateStarts), len(uniqueCards)-len(alts))
-						log.Debugf("Found %d card(s) with an alternative state", len(alts))
+	return groups
+}
 
-						templateEnds = append(templateEnds, len(uniqueCards)-len(alts))
-						templateStarts = append(templateStarts, len(uniqueCards)-len(alts))
+// setSideways displays the cards of deck whose image URL is in sideways
+// (the landscape images rotated in the templates) sideways.
+func setSideways(deck *plugins.Deck, sideways map[string]bool) {
+	for i := range deck.Cards {
+		card := &deck.Cards[i]
+		if sideways[card.ImageURL] {
+			card.Sideways = true
+		}
+		if card.AlternativeState != nil && sideways[card.AlternativeState.ImageURL] {
+			card.AlternativeState.Sideways = true
+		}
+	}
+}
 
-						alts = make(map[string]struct{})
-					}
-				}
-			}
+// generateTemplateSheets generates and uploads the templates of cards,
+// named after name, and adds them to the template information of decks.
+// The templates are numbered from *templateID, which is incremented for
+// each template.
+func generateTemplateSheets(ctx context.Context, name string, cards []plugins.CardInfo, decks []*plugins.Deck, templateID *int, tmpDir, outputFolder string, uploader upload.TemplateUploader) []error {
+	errs := []error{}
 
-			log.Debugf("Cut template number %d at %d", len(templateStarts), len(uniqueCards)-len(alts))
-			log.Debugf("Found %d cards with an alternative state", len(alts))
-			templateEnds = append(templateEnds, len(uniqueCards)-len(alts))
+	for i, group := range splitTemplateCards(cards) {
+		if err := ctx.Err(); err != nil {
+			return append(errs, err)
+		}
 
-			for templateCount := 0; templateCount < len(templateStarts); templateCount++ {
-				var suffix string
-				if templateCount > 0 {
-					suffix = fmt.Sprintf(" %d", templateCount+1)
-				}
-				templateName := filepathReplacer.Replace(deck.Name) + " - Template" + suffix
-
-				if uploader.UploaderID() != "manual" {
-					outputPath = filepath.Join(os.TempDir(), templateName+".jpg")
-				} else if outputFolder == "" {
-					outputPath = templateName + ".jpg"
-				} else {
-					outputPath = filepath.Join(outputFolder, templateName+".jpg")
-				}
+		var suffix string
+		if i > 0 {
+			suffix = fmt.Sprintf(" %d", i+1)
+		}
+		templateName := filepathReplacer.Replace(name) + suffix
 
-				start := templateStarts[templateCount]
-				end := templateEnds[templateCount]
-				log.Debugw(
-					"Generating new template",
-					"start", start,
-					"end", end,
-					"card count", len(deck.Cards),
-				)
-
-				urlIDMap, numCols, numRows, err = generateTemplate(
-					deck.Cards[start:end],
-					tmpDir,
-					outputPath,
-					totalTemplateCount,
-				)
-				if err != nil {
-					errs = append(errs, fmt.Errorf("couldn't save template to %s: %w", outputPath, err))
-					totalTemplateCount++
-					continue
-				}
+		var outputPath string
+		if uploader.UploaderID() != "manual" {
+			outputPath = filepath.Join(os.TempDir(), templateName+".jpg")
+		} else if outputFolder == "" {
+			outputPath = templateName + ".jpg"
+		} else {
+			outputPath = filepath.Join(outputFolder, templateName+".jpg")
+		}
 
-				var url string
-
-				url, err = uploader.Upload(outputPath, templateName, http.DefaultClient)
-				if err != nil {
-					err = fmt.Errorf(
-						"couldn't upload %s: %v\n"+
-							"Try to upload %s manually, and update the URL in the deck file(s) manually",
-						outputPath,
-						err,
-						outputPath,
-					)
-					errs = append(errs, err)
-				} else if uploader.UploaderID() != "manual" {
-					log.Debugf("Deleting template file %s", outputPath)
-					err = os.Remove(outputPath)
-					if err != nil {
-						errs = append(errs, fmt.Errorf("Couldn't remove %s: %v", outputPath, err))
-					}
-				}
+		log.Debugw(
+			"Generating new template",
+			"name", templateName,
+			"card count", len(group),
+		)
 
-				template := &plugins.Template{
-					URL:     url,
-					NumCols: int(numCols),
-					NumRows: int(numRows),
-				}
-				if deck.TemplateInfo == nil {
-					deck.TemplateInfo = &plugins.TemplateInfo{
-						ImageURLCardIDMap: urlIDMap,
-						Templates: map[int]*plugins.Template{
-							totalTemplateCount: template,
-						},
-					}
-				} else {
-					for cardURL, cardID := range urlIDMap {
-						deck.TemplateInfo.ImageURLCardIDMap[cardURL] = cardID
-					}
-					deck.TemplateInfo.Templates[totalTemplateCount] = template
-				}
-				totalTemplateCount++
+		id := *templateID
+		*templateID++
+
+		urlIDMap, sideways, numCols, numRows, err := generateTemplate(ctx, group, decks[0].CardSize, tmpDir, outputPath, id)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("couldn't save template to %s: %w", outputPath, err))
+			continue
+		}
+
+		url, err := upload.UploadContext(ctx, uploader, outputPath, templateName, plugins.HTTPClient())
+		if err != nil {
+			err = fmt.Errorf(
+				"couldn't upload %s: %v\n"+
+					"Try to upload %s manually, and update the URL in the deck file(s) manually",
+				outputPath,
+				err,
+				outputPath,
+			)
+			errs = append(errs, err)
+		} else if uploader.UploaderID() != "manual" {
+			log.Debugf("Deleting template file %s", outputPath)
+			err = os.Remove(outputPath)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("Couldn't remove %s: %v", outputPath, err))
 			}
 		}
 
-		return errs
+		template := &plugins.Template{
+			URL:     url,
+			NumCols: int(numCols),
+			NumRows: int(numRows),
+		}
+
+		for _, deck := range decks {
+			if deck.TemplateInfo == nil {
+				deck.TemplateInfo = &plugins.TemplateInfo{
+					ImageURLCardIDMap: make(map[string]int),
+					Templates:         make(map[int]*plugins.Template),
+				}
+			}
+			for cardURL, cardID := range urlIDMap {
+				deck.TemplateInfo.ImageURLCardIDMap[cardURL] = cardID
+			}
+			deck.TemplateInfo.Templates[id] = template
+			setSideways(deck, sideways)
+		}
 	}
 
-	cards := []plugins.CardInfo{}
-	templateName := ""
+	return errs
+}
+
+// groupDecksByCardSize splits decks by card size, keeping their order, since
+// the cards of different sizes can't share a template sheet.
+func groupDecksByCardSize(decks []*plugins.Deck) [][]*plugins.Deck {
+	groups := [][]*plugins.Deck{}
+	indexes := make(map[plugins.CardSize]int)
 
 	for _, deck := range decks {
-		if len(templateName) == 0 {
-			templateName = filepathReplacer.Replace(deck.Name) + " - Template"
+		index, found := indexes[deck.CardSize]
+		if !found {
+			index = len(groups)
+			indexes[deck.CardSize] = index
+			groups = append(groups, nil)
 		}
-		cards = append(cards, deck.Cards...)
+		groups[index] = append(groups[index], deck)
 	}
 
-	if uploader.UploaderID() != "manual" {
-		outputPath = filepath.Join(os.TempDir(), templateName+".jpg")
-	} else if outputFolder == "" {
-		outputPath = templateName + ".jpg"
-	} else {
-		outputPath = filepath.Join(outputFolder, templateName+".jpg")
+	return groups
+}
+
+// generateTemplatesForRelatedDecks generates the templates of decks (e.g.
+// the main deck and the sideboard, or all the decks of a batch). The decks
+// are grouped by card size, and each group gets its own templates (see
+// generateTemplatesForSameSizeDecks).
+func generateTemplatesForRelatedDecks(ctx context.Context, decks []*plugins.Deck, tmpDir, outputFolder string, uploader upload.TemplateUploader) []error {
+	errs := []error{}
+	templateID := 1
+
+	for _, deck := range decks {
+		deck.TemplateInfo = nil
 	}
 
-	log.Debug("Generating new template")
+	for _, group := range groupDecksByCardSize(decks) {
+		groupErrs := generateTemplatesForSameSizeDecks(ctx, group, &templateID, tmpDir, outputFolder, uploader)
+		errs = append(errs, groupErrs...)
+	}
 
-	urlIDMap, numCols, numRows, err = generateTemplate(cards, tmpDir, outputPath, 1)
-	if err != nil {
-		errs = append(errs, fmt.Errorf("couldn't save template to %s: %w", outputPath, err))
-		return errs
+	return errs
+}
+
+// generateTemplatesForSameSizeDecks generates the templates of decks, which
+// all have the same card size, numbered from *templateID. When the cards of
+// all the decks fit in a single template, this template is shared by all the
+// decks. Otherwise, the cards appearing in several decks are put in shared
+// templates (named after the first deck), and the other cards in the
+// templates of their deck, so that each image is only uploaded once.
+func generateTemplatesForSameSizeDecks(ctx context.Context, decks []*plugins.Deck, templateID *int, tmpDir, outputFolder string, uploader upload.TemplateUploader) []error {
+	cards := uniqueTemplateCards(decks)
+
+	imageCount := 0
+	for _, card := range cards {
+		imageCount += templateImageCount(card)
 	}
 
-	url, err := uploader.Upload(outputPath, templateName, http.DefaultClient)
-	if err != nil {
-		err = fmt.Errorf(
-			"couldn't upload %s: %v\n"+
-				"Try to upload %s manually, and update the URL in the deck file(s) manually",
-			outputPath,
-			err,
-			outputPath,
-		)
-		errs = append(errs, err)
-	} else if uploader.UploaderID() != "manual" {
-		log.Debugf("Deleting template file %s", outputPath)
-		err = os.Remove(outputPath)
-		if err != nil {
-			errs = append(errs, fmt.Errorf("Couldn't remove %s: %v", outputPath, err))
-		}
+	if imageCount <= int(maxTemplateCount) {
+		return generateTemplateSheets(ctx, decks[0].Name+" - Template", cards, decks, templateID, tmpDir, outputFolder, uploader)
 	}
 
-	template := &plugins.Template{
-		URL:     url,
-		NumCols: int(numCols),
-		NumRows: int(numRows),
+	errs := []error{}
+	shared, exclusive := splitSharedCards(decks)
+
+	if len(shared) > 0 {
+		log.Debugf("Found %d card(s) shared by several decks", len(shared))
+		sharedErrs := generateTemplateSheets(ctx, decks[0].Name+" - Shared Template", shared, decks, templateID, tmpDir, outputFolder, uploader)
+		errs = append(errs, sharedErrs...)
 	}
 
-	for _, deck := range decks {
-		deck.TemplateInfo = &plugins.TemplateInfo{
-			ImageURLCardIDMap: urlIDMap,
-			Templates: map[int]*plugins.Template{
-				1: template,
-			},
-		}
+	for i, deck := range decks {
+		deckErrs := generateTemplateSheets(ctx, deck.Name+" - Template", exclusive[i], []*plugins.Deck{deck}, templateID, tmpDir, outputFolder, uploader)
+		errs = append(errs, deckErrs...)
 	}
 
 	return errs
@@ -501,7 +608,15 @@ func generateTemplatesForRelatedDecks(decks []*plugins.Deck, tmpDir, outputFolde
 // All the images required to display a deck are ordered in several rows and
 // columns, to be later displayed by TTS when loading the deck.
 // See https://berserk-games.com/knowledgebase/custom-decks/.
-func GenerateTemplates(decks [][]*plugins.Deck, outputFolder string, uploader upload.TemplateUploader) (errs []error) {
+// The texture of the deck box of the boxed decks (see plugins.Deck.Boxed) is
+// also rendered and uploaded.
+// The generation stops as soon as ctx is done.
+func GenerateTemplates(ctx context.Context, decks [][]*plugins.Deck, outputFolder string, uploader upload.TemplateUploader) (errs []error) {
+	start := time.Now()
+	defer func() {
+		plugins.RecordOperation(plugins.OperationTemplates, start, firstError(errs))
+	}()
+
 	tmpDir, err := ioutil.TempDir("", "template")
 	if err != nil {
 		errs = append(errs, err)
@@ -516,8 +631,16 @@ func GenerateTemplates(decks [][]*plugins.Deck, outputFolder string, uploader up
 	}()
 
 	for _, relatedDecks := range decks {
-		generateErrs := generateTemplatesForRelatedDecks(relatedDecks, tmpDir, outputFolder, uploader)
+		if err := ctx.Err(); err != nil {
+			errs = append(errs, err)
+			return
+		}
+
+		generateErrs := generateTemplatesForRelatedDecks(ctx, relatedDecks, tmpDir, outputFolder, uploader)
 		errs = append(errs, generateErrs...)
+
+		boxErrs := generateDeckBoxes(ctx, relatedDecks, tmpDir, outputFolder, uploader)
+		errs = append(errs, boxErrs...)
 	}
 
 	return
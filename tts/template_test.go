@@ -1,9 +1,21 @@
 package tts
 
 import (
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/disintegration/imaging"
 	"github.com/stretchr/testify/assert"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
 )
 
 func TestFindTemplateSize(t *testing.T) {
@@ -46,3 +58,295 @@ func TestFindTemplateSize(t *testing.T) {
 	assert.Equal(t, uint(7), row)
 	assert.Nil(t, err)
 }
+
+func TestTemplateSheet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sheet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	colors := []color.NRGBA{
+		{0xff, 0, 0, 0xff},
+		{0, 0xff, 0, 0xff},
+		{0, 0, 0xff, 0xff},
+	}
+	files := make([]string, 0, len(colors))
+	for i, c := range colors {
+		files = append(files, filepath.Join(dir, string(rune('a'+i))+".png"))
+		// The last image is twice as big, and needs to be resized
+		size := 10
+		if i == len(colors)-1 {
+			size = 20
+		}
+		assert.Nil(t, imaging.Save(imaging.New(size, size*3/2, c), files[i]))
+	}
+
+	sheet := newTemplateSheet(context.Background(), files, 2, 2, 20, 30)
+	assert.Equal(t, image.Rect(0, 0, 20, 30), sheet.Bounds())
+	assert.Equal(t, colors[0], sheet.At(0, 0))
+	assert.Equal(t, colors[1], sheet.At(19, 14))
+	assert.Equal(t, colors[2], sheet.At(5, 15))
+	// The cells without any card are white
+	assert.Equal(t, white, sheet.At(15, 20))
+	// Going back to the first row reloads it
+	assert.Equal(t, colors[0], sheet.At(9, 0))
+	assert.LessOrEqual(t, len(sheet.rows), 2)
+	assert.Nil(t, sheet.err)
+
+	sheet = newTemplateSheet(context.Background(), []string{filepath.Join(dir, "missing.png")}, 1, 1, 10, 15)
+	sheet.At(0, 0)
+	assert.NotNil(t, sheet.err)
+
+	// The rows aren't decoded once the context is done
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	sheet = newTemplateSheet(ctx, files, 2, 2, 20, 30)
+	assert.Equal(t, white, sheet.At(0, 0))
+	assert.Equal(t, context.Canceled, sheet.err)
+
+	// Cells of different sizes
+	sheet = newTemplateSheet(context.Background(), files, 2, 2, 21, 31)
+	assert.Equal(t, colors[1], sheet.At(10, 0))
+	assert.Equal(t, colors[2], sheet.At(9, 15))
+	assert.Equal(t, colors[0], sheet.At(9, 14))
+}
+
+func TestTemplateSaturation(t *testing.T) {
+	defer func() {
+		assert.Nil(t, SetTemplateSaturation(1))
+	}()
+
+	assert.NotNil(t, SetTemplateSaturation(-1))
+	assert.NotNil(t, SetTemplateSaturation(2))
+
+	dir, err := ioutil.TempDir("", "sheet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "red.png")
+	assert.Nil(t, imaging.Save(imaging.New(10, 15, color.NRGBA{R: 255, A: 255}), file))
+
+	assert.Nil(t, SetTemplateSaturation(0))
+	sheet := newTemplateSheet(context.Background(), []string{file}, 1, 1, 10, 15)
+	pixel := sheet.At(5, 5).(color.NRGBA)
+	assert.Equal(t, pixel.R, pixel.G)
+	assert.Equal(t, pixel.R, pixel.B)
+}
+
+func TestSheetSize(t *testing.T) {
+	defer func() {
+		assert.Nil(t, SetTemplateSizing(TemplateSizing{}))
+	}()
+
+	width, height := sheetSize(10, 7, 745, 1040)
+	assert.Equal(t, 7450, width)
+	assert.Equal(t, 7280, height)
+
+	assert.Nil(t, SetTemplateSizing(TemplateSizing{MaxSize: 4096}))
+	width, height = sheetSize(10, 7, 745, 1040)
+	assert.Equal(t, 4096, width)
+	assert.Equal(t, 4002, height)
+	width, height = sheetSize(2, 2, 745, 1040)
+	assert.Equal(t, 1490, width)
+	assert.Equal(t, 2080, height)
+
+	assert.Nil(t, SetTemplateSizing(TemplateSizing{MaxSize: 4096, PowerOfTwo: true, Filter: "Box"}))
+	width, height = sheetSize(10, 7, 745, 1040)
+	assert.Equal(t, 4096, width)
+	assert.Equal(t, 4096, height)
+	width, height = sheetSize(2, 2, 745, 1040)
+	assert.Equal(t, 1024, width)
+	assert.Equal(t, 2048, height)
+
+	assert.NotNil(t, SetTemplateSizing(TemplateSizing{Filter: "bicubic"}))
+	assert.NotNil(t, SetTemplateSizing(TemplateSizing{MaxSize: -1}))
+}
+
+func TestNearestPowerOfTwo(t *testing.T) {
+	assert.Equal(t, 1, nearestPowerOfTwo(1, 0))
+	assert.Equal(t, 1024, nearestPowerOfTwo(1490, 0))
+	assert.Equal(t, 2048, nearestPowerOfTwo(1600, 0))
+	assert.Equal(t, 1024, nearestPowerOfTwo(1600, 2000))
+	assert.Equal(t, 8192, nearestPowerOfTwo(7450, 0))
+}
+
+func TestSplitSharedCards(t *testing.T) {
+	card := func(name string) plugins.CardInfo {
+		return plugins.CardInfo{Name: name, ImageURL: "https://example.com/" + name + ".png", Count: 1}
+	}
+	decks := []*plugins.Deck{
+		{Name: "A", Cards: []plugins.CardInfo{card("shared"), card("a"), card("a")}},
+		{Name: "B", Cards: []plugins.CardInfo{card("b"), card("shared")}},
+		{Name: "C", Cards: []plugins.CardInfo{card("c")}},
+	}
+
+	assert.Equal(t, []plugins.CardInfo{card("shared"), card("a"), card("b"), card("c")}, uniqueTemplateCards(decks))
+
+	shared, exclusive := splitSharedCards(decks)
+	assert.Equal(t, []plugins.CardInfo{card("shared")}, shared)
+	assert.Equal(t, [][]plugins.CardInfo{
+		{card("a")},
+		{card("b")},
+		{card("c")},
+	}, exclusive)
+}
+
+func TestSplitTemplateCards(t *testing.T) {
+	defer func(count uint) {
+		maxTemplateCount = count
+	}(maxTemplateCount)
+	maxTemplateCount = 3
+
+	front := plugins.CardInfo{ImageURL: "https://example.com/front.png"}
+	back := plugins.CardInfo{ImageURL: "https://example.com/back.png"}
+	dfc := plugins.CardInfo{ImageURL: "https://example.com/dfc.png", AlternativeState: &back}
+
+	assert.Equal(t, [][]plugins.CardInfo{
+		{front, dfc},
+		{dfc, front},
+		{front},
+	}, splitTemplateCards([]plugins.CardInfo{front, dfc, dfc, front, front}))
+	assert.Empty(t, splitTemplateCards(nil))
+}
+
+// countingUploader counts the uploaded templates, keeping them in place.
+type countingUploader struct {
+	uploads []string
+}
+
+func (u *countingUploader) Upload(templatePath string, templateName string, httpClient *http.Client) (string, error) {
+	u.uploads = append(u.uploads, templateName)
+	return "file://" + templatePath, nil
+}
+
+func (u *countingUploader) UploaderID() string          { return "manual" }
+func (u *countingUploader) UploaderName() string        { return "Counting" }
+func (u *countingUploader) UploaderDescription() string { return "" }
+
+func TestGenerateTemplatesSharedCards(t *testing.T) {
+	defer func(count uint) {
+		maxTemplateCount = count
+	}(maxTemplateCount)
+	maxTemplateCount = 2
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = png.Encode(w, imaging.New(10, 15, color.NRGBA{0xff, 0, 0, 0xff}))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	card := func(name string) plugins.CardInfo {
+		return plugins.CardInfo{Name: name, ImageURL: server.URL + "/" + name + ".png", Count: 1}
+	}
+	decks := []*plugins.Deck{
+		{Name: "A", Cards: []plugins.CardInfo{card("land"), card("a")}},
+		{Name: "B", Cards: []plugins.CardInfo{card("land"), card("b")}},
+	}
+
+	uploader := &countingUploader{}
+	errs := GenerateTemplates(context.Background(), [][]*plugins.Deck{decks}, dir, uploader)
+	assert.Empty(t, errs)
+
+	// The shared card is only uploaded once
+	assert.Equal(t, []string{"A - Shared Template", "A - Template", "B - Template"}, uploader.uploads)
+
+	for _, deck := range decks {
+		if !assert.NotNil(t, deck.TemplateInfo) {
+			continue
+		}
+		assert.Len(t, deck.TemplateInfo.Templates, 2)
+		for _, card := range deck.Cards {
+			template, _, err := deck.TemplateInfo.GetAssociatedTemplate(deck.TemplateInfo.ImageURLCardIDMap[card.ImageURL])
+			assert.Nil(t, err)
+			assert.NotNil(t, template)
+		}
+	}
+	assert.Equal(t,
+		decks[0].TemplateInfo.ImageURLCardIDMap[card("land").ImageURL],
+		decks[1].TemplateInfo.ImageURLCardIDMap[card("land").ImageURL],
+	)
+}
+
+func TestGenerateTemplatesSideways(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/plane.png" {
+			_ = png.Encode(w, imaging.New(15, 10, color.NRGBA{0, 0, 0xff, 0xff}))
+			return
+		}
+		_ = png.Encode(w, imaging.New(10, 15, color.NRGBA{0xff, 0, 0, 0xff}))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "sideways")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	deck := &plugins.Deck{
+		Name: "Planechase",
+		Cards: []plugins.CardInfo{
+			{Name: "Card", ImageURL: server.URL + "/card.png", Count: 1},
+			{Name: "Plane", ImageURL: server.URL + "/plane.png", Count: 1},
+		},
+	}
+
+	errs := GenerateTemplates(context.Background(), [][]*plugins.Deck{{deck}}, dir, &countingUploader{})
+	assert.Empty(t, errs)
+
+	assert.False(t, deck.Cards[0].Sideways)
+	assert.True(t, deck.Cards[1].Sideways)
+
+	// The landscape image is rotated in a portrait cell
+	sheet, err := imaging.Open(filepath.Join(dir, "Planechase - Template.jpg"))
+	if assert.Nil(t, err) {
+		assert.Equal(t, image.Rect(0, 0, 20, 15), sheet.Bounds())
+		r, g, b, _ := sheet.At(15, 7).RGBA()
+		assert.True(t, b > r && b > g, "the second cell should be blue")
+	}
+}
+
+func TestGenerateTemplatesMixedCardSizes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/mini.png" {
+			_ = png.Encode(w, imaging.New(10, 10, color.NRGBA{0, 0, 0xff, 0xff}))
+			return
+		}
+		_ = png.Encode(w, imaging.New(10, 15, color.NRGBA{0xff, 0, 0, 0xff}))
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "sizes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	decks := []*plugins.Deck{
+		{Name: "Main", Cards: []plugins.CardInfo{{Name: "Card", ImageURL: server.URL + "/card.png", Count: 1}}},
+		{
+			Name:     "Tokens",
+			Cards:    []plugins.CardInfo{{Name: "Token", ImageURL: server.URL + "/mini.png", Count: 1}},
+			CardSize: plugins.CardSizeSquare,
+		},
+		{Name: "Side", Cards: []plugins.CardInfo{{Name: "Other", ImageURL: server.URL + "/other.png", Count: 1}}},
+	}
+
+	uploader := &countingUploader{}
+	errs := GenerateTemplates(context.Background(), [][]*plugins.Deck{decks}, dir, uploader)
+	assert.Empty(t, errs)
+
+	// The images of different ratios aren't put in the same template
+	assert.Equal(t, []string{"Main - Template", "Tokens - Template"}, uploader.uploads)
+	assert.Equal(t, decks[0].TemplateInfo.Templates, decks[2].TemplateInfo.Templates)
+	assert.Len(t, decks[1].TemplateInfo.Templates, 1)
+	assert.NotContains(t, decks[1].TemplateInfo.Templates, 1)
+}
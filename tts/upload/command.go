@@ -0,0 +1,109 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strings"
+
+	"github.com/jeandeaual/tts-deckconverter/log"
+)
+
+const (
+	// CommandPathPlaceholder is replaced by the path of the template in the
+	// arguments of a CommandUploader.
+	CommandPathPlaceholder = "{path}"
+	// CommandNamePlaceholder is replaced by the name of the template in the
+	// arguments of a CommandUploader.
+	CommandNamePlaceholder = "{name}"
+)
+
+// CommandUploader uploads the templates by running an external command,
+// e.g. a script copying them to a web server. The command needs to print the
+// URL of the uploaded template on the last line of its standard output.
+type CommandUploader struct {
+	// ID of the uploader, used with "-template".
+	ID string
+	// Name of the uploader.
+	Name string
+	// Description of the uploader.
+	Description string
+	// Command to run, with its arguments. CommandPathPlaceholder and
+	// CommandNamePlaceholder are replaced in each argument.
+	Command []string
+}
+
+// Upload runs the command with the template path and name, and returns the
+// URL it printed.
+func (cu CommandUploader) Upload(templatePath string, templateName string, httpClient *http.Client) (string, error) {
+	return cu.UploadContext(context.Background(), templatePath, templateName, httpClient)
+}
+
+// UploadContext runs the command like Upload, and kills it when ctx is done.
+func (cu CommandUploader) UploadContext(ctx context.Context, templatePath string, templateName string, _ *http.Client) (string, error) {
+	if len(cu.Command) == 0 {
+		return "", errors.New("no command set")
+	}
+
+	replacer := strings.NewReplacer(
+		CommandPathPlaceholder, templatePath,
+		CommandNamePlaceholder, templateName,
+	)
+	args := make([]string, len(cu.Command))
+	for i, arg := range cu.Command {
+		args[i] = replacer.Replace(arg)
+	}
+
+	log.Infof("Uploading %s with %s", templatePath, cu.ID)
+	log.Debugf("Running %v", args)
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("command %s interrupted: %w", args[0], ctx.Err())
+		}
+		return "", fmt.Errorf("command %s failed: %w (%s)", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	templateURL := strings.TrimSpace(lines[len(lines)-1])
+
+	u, err := url.Parse(templateURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", fmt.Errorf("command %s didn't print the URL of %s (output: %q)", args[0], templatePath, templateURL)
+	}
+
+	log.Infof("Uploaded %s to %s", templatePath, templateURL)
+
+	return templateURL, nil
+}
+
+// UploaderID returns the ID of the uploading service
+func (cu CommandUploader) UploaderID() string {
+	return cu.ID
+}
+
+// UploaderName returns the name of the uploading service
+func (cu CommandUploader) UploaderName() string {
+	if len(cu.Name) == 0 {
+		return cu.ID
+	}
+	return cu.Name
+}
+
+// UploaderDescription returns the description of the uploading service
+func (cu CommandUploader) UploaderDescription() string {
+	if len(cu.Description) == 0 {
+		return fmt.Sprintf("Upload the template(s) with %s.", cu.Command[0])
+	}
+	return cu.Description
+}
@@ -0,0 +1,56 @@
+package upload
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandUpload(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires sh")
+	}
+
+	uploader := CommandUploader{
+		ID:      "test",
+		Command: []string{"sh", "-c", `echo "Uploading $0"; echo "https://example.com/$1.png"`, "{path}", "{name}"},
+	}
+
+	assert.Equal(t, "test", uploader.UploaderName())
+	assert.Equal(t, "Upload the template(s) with sh.", uploader.UploaderDescription())
+
+	url, err := uploader.Upload("test.png", "Test", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://example.com/Test.png", url)
+
+	uploader.Command = []string{"sh", "-c", "echo uploaded"}
+	_, err = uploader.Upload("test.png", "Test", nil)
+	assert.NotNil(t, err)
+
+	uploader.Command = []string{"sh", "-c", "exit 1"}
+	_, err = uploader.Upload("test.png", "Test", nil)
+	assert.NotNil(t, err)
+}
+
+func TestCommandUploadContext(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires sleep")
+	}
+
+	var uploader TemplateUploader = CommandUploader{
+		ID:      "test",
+		Command: []string{"sleep", "10"},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := UploadContext(ctx, uploader, "test.png", "Test", nil)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Less(t, int64(time.Since(start)), int64(5*time.Second))
+}
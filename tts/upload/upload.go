@@ -1,6 +1,7 @@
 package upload
 
 import (
+	"context"
 	"errors"
 	"net/http"
 )
@@ -25,6 +26,23 @@ type TemplateUploader interface {
 	UploaderDescription() string
 }
 
+// ContextUploader can be implemented by a TemplateUploader whose uploads
+// can be cancelled.
+type ContextUploader interface {
+	// UploadContext uploads a file, and stops when ctx is done
+	UploadContext(ctx context.Context, templatePath string, templateName string, httpClient *http.Client) (string, error)
+}
+
+// UploadContext uploads a file with uploader, stopping when ctx is done if
+// the uploader implements ContextUploader.
+func UploadContext(ctx context.Context, uploader TemplateUploader, templatePath string, templateName string, httpClient *http.Client) (string, error) {
+	if cu, ok := uploader.(ContextUploader); ok {
+		return cu.UploadContext(ctx, templatePath, templateName, httpClient)
+	}
+
+	return uploader.Upload(templatePath, templateName, httpClient)
+}
+
 func init() {
 	TemplateUploaders = make(map[string]*TemplateUploader)
 
@@ -40,3 +58,16 @@ func registerTemplateUploaders(uploaders ...TemplateUploader) {
 		TemplateUploaders[uploader.UploaderID()] = &uploader
 	}
 }
+
+// RegisterTemplateUploader adds an uploader to TemplateUploaders, e.g. a
+// CommandUploader defined in the configuration file. An uploader with the
+// same ID is replaced.
+func RegisterTemplateUploader(uploader TemplateUploader) error {
+	if len(uploader.UploaderID()) == 0 {
+		return errors.New("the template uploader ID is required")
+	}
+
+	registerTemplateUploaders(uploader)
+
+	return nil
+}
@@ -0,0 +1,182 @@
+package tts
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/jeandeaual/tts-deckconverter/log"
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+// urlCheckWorkers is the number of image URLs checked concurrently by
+// CheckImageURLs.
+const urlCheckWorkers = 8
+
+var (
+	// checkedURLs caches the result of the URL checks, since the same
+	// images (e.g. the card backs) are usually shared by several decks.
+	checkedURLs      = make(map[string]error)
+	checkedURLsMutex sync.Mutex
+)
+
+// imageURL is an image URL used by the saved object of a deck.
+type imageURL struct {
+	url  string
+	deck string
+}
+
+// imageURLs returns the face and back URLs used by the saved objects
+// generated for decks, without duplicates. Only the remote URLs are
+// returned, the local files (e.g. the templates generated with the manual
+// uploader) can't be loaded by the other players anyway.
+func imageURLs(decks []*plugins.Deck) []imageURL {
+	var (
+		urls  []imageURL
+		found = make(map[string]bool)
+	)
+
+	add := func(rawURL string, deck *plugins.Deck) {
+		if found[rawURL] {
+			return
+		}
+		if !isRemoteURL(rawURL) {
+			return
+		}
+		found[rawURL] = true
+		urls = append(urls, imageURL{url: rawURL, deck: deck.Name})
+	}
+
+	for _, deck := range decks {
+		if len(deck.Cards) == 0 {
+			continue
+		}
+
+		add(deck.BackURL, deck)
+		if deck.Mystery {
+			add(deck.MysteryFaceURL, deck)
+		}
+
+		if deck.TemplateInfo != nil {
+			ids := make([]int, 0, len(deck.TemplateInfo.Templates))
+			for id := range deck.TemplateInfo.Templates {
+				ids = append(ids, id)
+			}
+			sort.Ints(ids)
+			for _, id := range ids {
+				add(deck.TemplateInfo.Templates[id].URL, deck)
+			}
+			continue
+		}
+
+		for _, card := range deck.Cards {
+			add(card.ImageURL, deck)
+			add(card.BackURL, deck)
+			if card.AlternativeState != nil {
+				add(card.AlternativeState.ImageURL, deck)
+			}
+		}
+	}
+
+	return urls
+}
+
+// checkURL sends a HEAD request to rawURL using plugins.HTTPClient(), and
+// returns an error if the image can't be retrieved. Some servers don't
+// support HEAD requests, in which case a GET request is sent instead.
+func checkURL(ctx context.Context, rawURL string) error {
+	checkedURLsMutex.Lock()
+	err, found := checkedURLs[rawURL]
+	checkedURLsMutex.Unlock()
+	if found {
+		return err
+	}
+
+	status, err := requestStatus(ctx, "HEAD", rawURL)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+		status, err = requestStatus(ctx, "GET", rawURL)
+	}
+	if err == nil && (status < 200 || status > 299) {
+		err = fmt.Errorf("status %d (%s)", status, http.StatusText(status))
+	}
+
+	if ctx.Err() != nil {
+		// Don't cache the requests cancelled by the user
+		return err
+	}
+
+	checkedURLsMutex.Lock()
+	checkedURLs[rawURL] = err
+	checkedURLsMutex.Unlock()
+
+	return err
+}
+
+// requestStatus sends a request to rawURL and returns the status code of the
+// response, without reading its body.
+func requestStatus(ctx context.Context, method, rawURL string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := plugins.HTTPClient().Do(req)
+	if err != nil {
+		return 0, err
+	}
+
+	return resp.StatusCode, resp.Body.Close()
+}
+
+// CheckImageURLs checks that each face and back image used by the saved
+// objects of decks can be retrieved, using HEAD requests sent concurrently,
+// and returns an error for each dead link. This catches the missing textures
+// before loading the decks in Tabletop Simulator.
+// The results are cached, so each URL is only checked once.
+func CheckImageURLs(ctx context.Context, decks []*plugins.Deck) []error {
+	urls := imageURLs(decks)
+	if len(urls) == 0 {
+		return nil
+	}
+
+	log.Infof("Checking %d image URL(s)", len(urls))
+
+	var (
+		wg    sync.WaitGroup
+		queue = make(chan int)
+		errs  = make([]error, len(urls))
+	)
+
+	for i := 0; i < urlCheckWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range queue {
+				if err := checkURL(ctx, urls[index].url); err != nil {
+					errs[index] = fmt.Errorf("image %s of deck %s can't be retrieved: %w", urls[index].url, urls[index].deck, err)
+				}
+			}
+		}()
+	}
+
+	for index := range urls {
+		if ctx.Err() != nil {
+			break
+		}
+		queue <- index
+	}
+	close(queue)
+	wg.Wait()
+
+	// Keep the errors in the order of the decks
+	deadLinks := []error{}
+	for _, err := range errs {
+		if err != nil {
+			deadLinks = append(deadLinks, err)
+		}
+	}
+
+	return deadLinks
+}
@@ -0,0 +1,102 @@
+package tts
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+func TestImageURLs(t *testing.T) {
+	decks := []*plugins.Deck{
+		{
+			Name:    "Main",
+			BackURL: "https://example.com/back.png",
+			Cards: []plugins.CardInfo{
+				{Name: "Island", ImageURL: "https://example.com/island.png"},
+				{
+					Name:             "Delver of Secrets",
+					ImageURL:         "https://example.com/delver.png",
+					AlternativeState: &plugins.CardInfo{ImageURL: "https://example.com/insectile.png"},
+				},
+			},
+		},
+		{
+			Name:    "Templates",
+			BackURL: "https://example.com/back.png",
+			Cards:   []plugins.CardInfo{{Name: "Island", ImageURL: "https://example.com/island.png"}},
+			TemplateInfo: &plugins.TemplateInfo{
+				Templates: map[int]*plugins.Template{
+					2: {URL: "/tmp/template2.jpg"},
+					1: {URL: "https://example.com/template1.jpg"},
+				},
+			},
+		},
+		{Name: "Empty", BackURL: "https://example.com/empty.png"},
+	}
+
+	var urls []string
+	for _, u := range imageURLs(decks) {
+		urls = append(urls, u.url)
+	}
+
+	assert.Equal(t, []string{
+		"https://example.com/back.png",
+		"https://example.com/island.png",
+		"https://example.com/delver.png",
+		"https://example.com/insectile.png",
+		"https://example.com/template1.jpg",
+	}, urls)
+}
+
+func TestCheckImageURLs(t *testing.T) {
+	var (
+		requests      = make(map[string]int)
+		requestsMutex sync.Mutex
+	)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestsMutex.Lock()
+		requests[r.Method+" "+r.URL.Path]++
+		requestsMutex.Unlock()
+
+		switch r.URL.Path {
+		case "/dead.png":
+			http.NotFound(w, r)
+		case "/get-only.png":
+			if r.Method != "GET" {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		}
+	}))
+	defer ts.Close()
+
+	decks := []*plugins.Deck{
+		{
+			Name:    "Main",
+			BackURL: ts.URL + "/back.png",
+			Cards: []plugins.CardInfo{
+				{Name: "Island", ImageURL: ts.URL + "/island.png"},
+				{Name: "Swamp", ImageURL: ts.URL + "/dead.png"},
+				{Name: "Plains", ImageURL: ts.URL + "/get-only.png"},
+			},
+		},
+	}
+
+	errs := CheckImageURLs(context.Background(), decks)
+	if assert.Len(t, errs, 1) {
+		assert.Contains(t, errs[0].Error(), "/dead.png")
+		assert.Contains(t, errs[0].Error(), "404")
+	}
+	assert.Equal(t, 1, requests["GET /get-only.png"])
+
+	// The results are cached
+	errs = CheckImageURLs(context.Background(), decks)
+	assert.Len(t, errs, 1)
+	assert.Equal(t, 1, requests["HEAD /island.png"])
+}
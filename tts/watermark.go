@@ -0,0 +1,93 @@
+package tts
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/disintegration/imaging"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// DefaultWatermarkOpacity is the opacity of the watermarks, unless another
+// one is set with SetWatermark.
+const DefaultWatermarkOpacity = 0.6
+
+// Watermark is overlaid on each card image of the template sheets, e.g. to
+// mark the proxies of unreleased or gold-bordered cards.
+type Watermark struct {
+	// Text of the watermark (e.g. "PROXY"), written in black on a white band
+	// across the middle of the cards. Only the Latin-1 characters are
+	// supported. No watermark is added if empty.
+	Text string
+	// Opacity of the watermark, between 0 (excluded) and 1,
+	// DefaultWatermarkOpacity if 0.
+	Opacity float64
+}
+
+var watermark = Watermark{}
+
+// SetWatermark changes the watermark overlaid on the card images of the
+// template sheets generated by GenerateTemplates.
+func SetWatermark(w Watermark) error {
+	if w.Opacity < 0 || w.Opacity > 1 {
+		return fmt.Errorf("invalid watermark opacity: %g (needs to be between 0 and 1)", w.Opacity)
+	}
+	if w.Opacity == 0 {
+		w.Opacity = DefaultWatermarkOpacity
+	}
+
+	watermark = w
+
+	return nil
+}
+
+// renderWatermark returns the watermark image for a card of the given width,
+// or nil if no watermark is set.
+func renderWatermark(width int) *image.NRGBA {
+	if len(watermark.Text) == 0 || width <= 0 {
+		return nil
+	}
+
+	face := basicfont.Face7x13
+	metrics := face.Metrics()
+	// Leave one character of margin on each side of the text
+	padding := face.Advance
+	textWidth := font.MeasureString(face, watermark.Text).Ceil()
+	band := imaging.New(
+		textWidth+2*padding,
+		(metrics.Ascent+metrics.Descent).Ceil()+face.Height/2,
+		color.NRGBA{R: 255, G: 255, B: 255, A: 255},
+	)
+
+	drawer := font.Drawer{
+		Dst:  band,
+		Src:  image.Black,
+		Face: face,
+		Dot:  fixed.P(padding, face.Height/4+metrics.Ascent.Ceil()),
+	}
+	drawer.DrawString(watermark.Text)
+
+	mark := imaging.Resize(band, width, 0, imaging.Linear)
+	for i := 3; i < len(mark.Pix); i += 4 {
+		mark.Pix[i] = uint8(float64(mark.Pix[i]) * watermark.Opacity)
+	}
+
+	return mark
+}
+
+// drawWatermark overlays mark (see renderWatermark) across the middle of
+// cell.
+func drawWatermark(dst draw.Image, cell image.Rectangle, mark *image.NRGBA) {
+	if mark == nil {
+		return
+	}
+
+	top := cell.Min.Y + (cell.Dy()-mark.Bounds().Dy())/2
+	area := image.Rect(cell.Min.X, top, cell.Max.X, top+mark.Bounds().Dy()).Intersect(cell)
+
+	draw.Draw(dst, area, mark, image.Pt(0, area.Min.Y-top), draw.Over)
+}
@@ -0,0 +1,49 @@
+package tts
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/disintegration/imaging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetWatermark(t *testing.T) {
+	defer func() {
+		assert.Nil(t, SetWatermark(Watermark{}))
+	}()
+
+	assert.NotNil(t, SetWatermark(Watermark{Text: "PROXY", Opacity: -0.5}))
+	assert.NotNil(t, SetWatermark(Watermark{Text: "PROXY", Opacity: 1.5}))
+
+	assert.Nil(t, SetWatermark(Watermark{Text: "PROXY"}))
+	assert.Equal(t, DefaultWatermarkOpacity, watermark.Opacity)
+}
+
+func TestDrawWatermark(t *testing.T) {
+	defer func() {
+		assert.Nil(t, SetWatermark(Watermark{}))
+	}()
+
+	assert.Nil(t, renderWatermark(100))
+
+	assert.Nil(t, SetWatermark(Watermark{Text: "PROXY", Opacity: 1}))
+	mark := renderWatermark(100)
+	if !assert.NotNil(t, mark) {
+		return
+	}
+	assert.Equal(t, 100, mark.Bounds().Dx())
+
+	red := color.NRGBA{R: 255, A: 255}
+	card := imaging.New(200, 150, red)
+	cell := image.Rect(100, 0, 200, 150)
+	drawWatermark(card, cell, mark)
+
+	// The watermark is only drawn across the middle of the cell
+	assert.Equal(t, red, card.NRGBAAt(150, 0))
+	assert.Equal(t, red, card.NRGBAAt(150, 149))
+	assert.Equal(t, red, card.NRGBAAt(50, 75))
+	// The margin of the band is white
+	assert.Equal(t, color.NRGBA{R: 255, G: 255, B: 255, A: 255}, card.NRGBAAt(101, 75))
+}
@@ -0,0 +1,191 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/jeandeaual/tts-deckconverter/log"
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+const (
+	// discordMaxAttachments is the maximum number of files attached to a
+	// Discord message.
+	discordMaxAttachments = 10
+	// discordMaxContentLength is the maximum number of characters of the
+	// content of a Discord message.
+	discordMaxContentLength = 2000
+)
+
+// WebhookDeck is a deck sent to a webhook.
+type WebhookDeck struct {
+	// Name of the deck.
+	Name string `json:"name"`
+	// FileName is the name of the file generated for the deck.
+	FileName string `json:"fileName"`
+	// Object is the Tabletop Simulator saved object of the deck.
+	Object SavedObject `json:"object"`
+}
+
+// WebhookPayload is the JSON body sent to a webhook.
+type WebhookPayload struct {
+	Decks []WebhookDeck `json:"decks"`
+}
+
+// PostWebhook sends the saved objects of the decks to webhookURL.
+// Discord webhooks receive each deck as a file attachment, split into
+// several messages if there are more than 10 decks, and other webhooks
+// receive a WebhookPayload.
+func PostWebhook(ctx context.Context, decks []*plugins.Deck, backURL, webhookURL string) error {
+	payload := WebhookPayload{
+		Decks: make([]WebhookDeck, 0, len(decks)),
+	}
+
+	for _, deck := range decks {
+		if len(backURL) > 0 {
+			deck.BackURL = backURL
+		}
+		if len(deck.Cards) == 0 {
+			continue
+		}
+
+		object, _ := createObject(deck)
+
+		payload.Decks = append(payload.Decks, WebhookDeck{
+			Name:     deck.Name,
+			FileName: filepathReplacer.Replace(deck.Name) + ".json",
+			Object:   object,
+		})
+	}
+
+	log.Infof("Sending %d deck(s) to the webhook", len(payload.Decks))
+
+	if !isDiscordWebhook(webhookURL) {
+		body, contentType, err := jsonWebhookBody(payload)
+		if err != nil {
+			return fmt.Errorf("couldn't create the webhook request: %w", err)
+		}
+
+		return postWebhookBody(ctx, webhookURL, body, contentType)
+	}
+
+	for start := 0; start == 0 || start < len(payload.Decks); start += discordMaxAttachments {
+		end := start + discordMaxAttachments
+		if end > len(payload.Decks) {
+			end = len(payload.Decks)
+		}
+
+		body, contentType, err := discordWebhookBody(WebhookPayload{Decks: payload.Decks[start:end]})
+		if err != nil {
+			return fmt.Errorf("couldn't create the webhook request: %w", err)
+		}
+
+		if err := postWebhookBody(ctx, webhookURL, body, contentType); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func postWebhookBody(ctx context.Context, webhookURL string, body io.Reader, contentType string) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", webhookURL, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := plugins.HTTPClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("couldn't send the decks to the webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("the webhook returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	return nil
+}
+
+func isDiscordWebhook(webhookURL string) bool {
+	u, err := url.Parse(webhookURL)
+	if err != nil {
+		return false
+	}
+
+	host := strings.TrimPrefix(u.Hostname(), "www.")
+
+	return (host == "discord.com" || host == "discordapp.com") && strings.HasPrefix(u.Path, "/api/webhooks/")
+}
+
+func jsonWebhookBody(payload WebhookPayload) (io.Reader, string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return bytes.NewReader(data), "application/json", nil
+}
+
+// discordWebhookBody attaches the saved object of each deck as a file, since
+// a Discord message is limited to 2000 characters. The list of decks in the
+// content of the message is truncated to fit.
+// A message can't have more than 10 attachments: payload needs to be split
+// beforehand.
+// See https://discord.com/developers/docs/resources/webhook#execute-webhook
+func discordWebhookBody(payload WebhookPayload) (io.Reader, string, error) {
+	var buf bytes.Buffer
+
+	writer := multipart.NewWriter(&buf)
+
+	names := make([]string, 0, len(payload.Decks))
+	for _, deck := range payload.Decks {
+		names = append(names, deck.Name)
+	}
+
+	content := []rune("Converted " + strings.Join(names, ", "))
+	if len(content) > discordMaxContentLength {
+		content = append(content[:discordMaxContentLength-len([]rune(DefaultEllipsis))], []rune(DefaultEllipsis)...)
+	}
+
+	message, err := json.Marshal(map[string]string{
+		"content": string(content),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if err := writer.WriteField("payload_json", string(message)); err != nil {
+		return nil, "", err
+	}
+
+	for i, deck := range payload.Decks {
+		data, err := json.Marshal(deck.Object)
+		if err != nil {
+			return nil, "", err
+		}
+
+		part, err := writer.CreateFormFile(fmt.Sprintf("files[%d]", i), deck.FileName)
+		if err != nil {
+			return nil, "", err
+		}
+		if _, err := part.Write(data); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+
+	return &buf, writer.FormDataContentType(), nil
+}
@@ -0,0 +1,137 @@
+package tts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+func TestIsDiscordWebhook(t *testing.T) {
+	assert.True(t, isDiscordWebhook("https://discord.com/api/webhooks/123/abc"))
+	assert.True(t, isDiscordWebhook("https://discordapp.com/api/webhooks/123/abc"))
+	assert.False(t, isDiscordWebhook("https://discord.com/channels/123"))
+	assert.False(t, isDiscordWebhook("https://example.com/api/webhooks/123/abc"))
+}
+
+func TestPostWebhook(t *testing.T) {
+	var payload WebhookPayload
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "POST", r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&payload))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	decks := []*plugins.Deck{
+		{
+			Name: "Main/Side",
+			Cards: []plugins.CardInfo{
+				{Name: "Island", Count: 2, ImageURL: "https://example.com/island.png"},
+			},
+		},
+		{Name: "Empty"},
+	}
+
+	err := PostWebhook(context.Background(), decks, "https://example.com/back.png", ts.URL)
+	assert.Nil(t, err)
+	assert.Len(t, payload.Decks, 1)
+	assert.Equal(t, "Main/Side", payload.Decks[0].Name)
+	assert.Equal(t, "Main-Side.json", payload.Decks[0].FileName)
+	assert.Len(t, payload.Decks[0].Object.ObjectStates, 1)
+	assert.Equal(t, 2, len(payload.Decks[0].Object.ObjectStates[0].ContainedObjects))
+}
+
+func TestPostWebhookError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	err := PostWebhook(context.Background(), nil, "", ts.URL)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "invalid token")
+}
+
+func TestDiscordWebhookBody(t *testing.T) {
+	body, contentType, err := discordWebhookBody(WebhookPayload{
+		Decks: []WebhookDeck{{Name: "Main", FileName: "Main.json"}},
+	})
+	assert.Nil(t, err)
+	assert.Contains(t, contentType, "multipart/form-data")
+
+	data, err := ioutil.ReadAll(body)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `name="payload_json"`)
+	assert.Contains(t, string(data), `{"content":"Converted Main"}`)
+	assert.Contains(t, string(data), `name="files[0]"; filename="Main.json"`)
+}
+
+// redirectTransport sends the requests to target instead of their host.
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (t redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req.URL.Scheme = t.target.Scheme
+	req.URL.Host = t.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestPostDiscordWebhook(t *testing.T) {
+	var (
+		files    []int
+		contents []string
+	)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/webhooks/123/abc", r.URL.Path)
+		assert.Nil(t, r.ParseMultipartForm(1<<20))
+		files = append(files, len(r.MultipartForm.File))
+
+		var message map[string]string
+		assert.Nil(t, json.Unmarshal([]byte(r.FormValue("payload_json")), &message))
+		contents = append(contents, message["content"])
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	target, err := url.Parse(ts.URL)
+	assert.Nil(t, err)
+	defer plugins.SetHTTPClient(nil)
+	plugins.SetHTTPTransport(redirectTransport{target: target})
+
+	decks := make([]*plugins.Deck, 0, 12)
+	for i := 0; i < 12; i++ {
+		decks = append(decks, &plugins.Deck{
+			Name: fmt.Sprintf("%02d %s", i, strings.Repeat("é", 200)),
+			Cards: []plugins.CardInfo{
+				{Name: "Island", Count: 1, ImageURL: "https://example.com/island.png"},
+			},
+		})
+	}
+
+	err = PostWebhook(context.Background(), decks, "", "https://discord.com/api/webhooks/123/abc")
+	assert.Nil(t, err)
+
+	// A Discord message can't have more than 10 attachments
+	assert.Equal(t, []int{10, 2}, files)
+	if assert.Len(t, contents, 2) {
+		assert.Equal(t, discordMaxContentLength, utf8.RuneCountInString(contents[0]))
+		assert.True(t, strings.HasSuffix(contents[0], DefaultEllipsis))
+		assert.True(t, strings.HasPrefix(contents[1], "Converted 10 "))
+	}
+}
@@ -0,0 +1,72 @@
+package deckconverter
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+// FindPlugin returns the plugin which Parse uses to parse target: the plugin
+// selected with mode, or the plugin supporting the target URL or file
+// extension.
+// Like Parse, the URL handlers added with RegisterURLHandler are checked
+// before the built-in ones.
+func FindPlugin(target, mode string) (plugins.Plugin, bool) {
+	if len(mode) > 0 {
+		return lookupPlugin(mode)
+	}
+
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+
+	if u, err := url.Parse(target); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		for _, registered := range registeredURLHandlers {
+			if registered.handler.Regex.MatchString(target) {
+				plugin, found := Plugins[registered.pluginID]
+				return plugin, found
+			}
+		}
+
+		for _, pluginID := range pluginIDs {
+			plugin := Plugins[pluginID]
+			for _, handler := range plugin.URLHandlers() {
+				if handler.Regex.MatchString(target) {
+					return plugin, true
+				}
+			}
+		}
+
+		return nil, false
+	}
+
+	ext := filepath.Ext(target)
+
+	for _, pluginID := range pluginIDs {
+		plugin := Plugins[pluginID]
+		if _, found := plugin.FileExtHandlers()[ext]; found {
+			return plugin, true
+		}
+	}
+
+	return nil, false
+}
+
+// Validate checks that decks parsed with the plugin pluginID and options
+// follow the deck construction rules of its game, and returns the rules they
+// break.
+// An error is returned if the plugin doesn't implement plugins.Validator.
+func Validate(pluginID string, decks []*plugins.Deck, options map[string]string) ([]plugins.RuleViolation, error) {
+	plugin, found := lookupPlugin(pluginID)
+	if !found {
+		return nil, fmt.Errorf("plugin %s not found", pluginID)
+	}
+
+	validator, ok := plugin.(plugins.Validator)
+	if !ok {
+		return nil, fmt.Errorf("plugin %s doesn't support deck validation", pluginID)
+	}
+
+	return validator.ValidateDecks(decks, options), nil
+}
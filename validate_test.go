@@ -0,0 +1,91 @@
+package deckconverter
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/jeandeaual/tts-deckconverter/plugins"
+)
+
+func TestFindPlugin(t *testing.T) {
+	plugin, found := FindPlugin("deck.ydk", "")
+	assert.True(t, found)
+	assert.Equal(t, "ygo", plugin.PluginID())
+
+	plugin, found = FindPlugin("https://www.moxfield.com/decks/yA6HKgjJS0O1J1X17skNqQ", "")
+	assert.True(t, found)
+	assert.Equal(t, "mtg", plugin.PluginID())
+
+	plugin, found = FindPlugin("https://scryfall.com/sets/sld", "")
+	assert.True(t, found)
+	assert.Equal(t, "mtg", plugin.PluginID())
+
+	plugin, found = FindPlugin("deck.txt", "pkm")
+	assert.True(t, found)
+	assert.Equal(t, "pkm", plugin.PluginID())
+
+	_, found = FindPlugin("deck.unknown", "")
+	assert.False(t, found)
+}
+
+func TestFindPluginRegisteredURLHandler(t *testing.T) {
+	defaultHandlers := URLHandlers
+	defaultRegisteredHandlers := registeredURLHandlers
+	defer func() {
+		URLHandlers = defaultHandlers
+		registeredURLHandlers = defaultRegisteredHandlers
+	}()
+
+	handler := func(ctx context.Context, url string, options map[string]string) ([]*plugins.Deck, error) {
+		return nil, nil
+	}
+
+	_, found := FindPlugin("https://decks.example.com/1", "")
+	assert.False(t, found)
+
+	assert.Nil(t, RegisterURLHandler("ygo", plugins.URLHandler{
+		Regex:   regexp.MustCompile(`^https://decks\.example\.com/`),
+		Handler: handler,
+	}))
+
+	plugin, found := FindPlugin("https://decks.example.com/1", "")
+	assert.True(t, found)
+	assert.Equal(t, "ygo", plugin.PluginID())
+
+	// The registered handlers override the built-in ones
+	assert.Nil(t, RegisterURLHandler("pkm", plugins.URLHandler{
+		Regex:   regexp.MustCompile(`^https://www\.moxfield\.com/`),
+		Handler: handler,
+	}))
+
+	plugin, found = FindPlugin("https://www.moxfield.com/decks/yA6HKgjJS0O1J1X17skNqQ", "")
+	assert.True(t, found)
+	assert.Equal(t, "pkm", plugin.PluginID())
+}
+
+func TestValidate(t *testing.T) {
+	decks := []*plugins.Deck{
+		{
+			Name: "Test",
+			Cards: []plugins.CardInfo{
+				{Name: "Pot of Greed", Count: 3, Metadata: plugins.CardMetadata{Name: "Pot of Greed", Banned: true}},
+				{Name: "Dark Magician", Count: 37, Metadata: plugins.CardMetadata{Name: "Dark Magician"}},
+			},
+		},
+	}
+
+	violations, err := Validate("ygo", decks, nil)
+	assert.Nil(t, err)
+
+	ruleIDs := make([]string, 0, len(violations))
+	for _, violation := range violations {
+		ruleIDs = append(ruleIDs, violation.RuleID)
+	}
+	assert.Equal(t, []string{"ygo.banned", "ygo.max-copies"}, ruleIDs)
+
+	_, err = Validate("custom", decks, nil)
+	assert.NotNil(t, err)
+}